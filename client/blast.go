@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// blastChunkSize — размер одной записи в поток в режиме --blast. Крупные
+// записи снижают накладные расходы на вызов, QUIC сам режет их на пакеты.
+const blastChunkSize = 64 * 1024
+
+// blastStream открывает поток и пишет в него без пауз до отмены контекста
+func blastStream(ctx context.Context, session quic.Connection, cfg internal.TestConfig, metrics *Metrics) {
+	stream, err := session.OpenStreamSync(ctx)
+	if err != nil {
+		metrics.mu.Lock()
+		metrics.recordErrorLocked("open_stream", err)
+		metrics.mu.Unlock()
+		return
+	}
+	// Запись, ожидающая окна flow control, прерывается отменой контекста
+	stop := context.AfterFunc(ctx, func() {
+		stream.CancelWrite(0)
+	})
+	defer stop()
+	defer stream.Close()
+
+	blastWrite(ctx, stream, makePacket(blastChunkSize, cfg.Pattern), metrics)
+}
+
+// blastWrite пишет chunk в w так быстро, как позволяет w: без ограничения
+// скорости, эмуляции, FEC, номеров последовательности и замеров RTT.
+// Возвращается при отмене контекста или ошибке записи.
+func blastWrite(ctx context.Context, w io.Writer, chunk []byte, metrics *Metrics) {
+	for ctx.Err() == nil {
+		n, err := w.Write(chunk)
+		metrics.mu.Lock()
+		if len(metrics.Timestamps) == 0 {
+			// Начало отсчета для ThroughputMbps
+			metrics.Timestamps = append(metrics.Timestamps, time.Now())
+		}
+		metrics.BytesSent += n
+		if err == nil {
+			metrics.Success++
+		}
+		if metrics.HDRMetrics != nil {
+			metrics.HDRMetrics.AddBytesSent(int64(n))
+		}
+		if err != nil && ctx.Err() == nil {
+			errType := "stream_write"
+			if isStreamDataLimit(err) {
+				errType = "stream_data_limit"
+			}
+			metrics.recordErrorLocked(errType, err)
+		}
+		metrics.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// blastSummary находит плато пропускной способности по ряду throughput
+// (KB за секунду) и сравнивает потраченное время процессора с доступным.
+// quic-go упаковывает и шифрует пакеты соединения в одной горутине, поэтому
+// отправка занимает не больше ядра на соединение.
+func blastSummary(cfg internal.TestConfig, m *Metrics, cpu, wall time.Duration) internal.BlastResult {
+	m.mu.Lock()
+	samples := make([]float64, len(m.TimeSeriesThroughput))
+	for i, p := range m.TimeSeriesThroughput {
+		samples[i] = p.Value * 1024 * 8 / 1e6
+	}
+	m.mu.Unlock()
+
+	coreLimit := runtime.GOMAXPROCS(0)
+	if cfg.Connections < coreLimit {
+		coreLimit = cfg.Connections
+	}
+	return internal.AnalyzeBlast(samples, time.Second, cpu, wall, float64(coreLimit))
+}
+
+// printBlastSummary выводит итог режима --blast
+func printBlastSummary(b internal.BlastResult) {
+	fmt.Printf("Blast: максимум %.2f Mbps, устойчиво %.2f Mbps\n", b.MaxThroughputMbps, b.SustainedThroughputMbps)
+	if b.Plateaued {
+		fmt.Printf("Blast: плато с %.0f s\n", b.PlateauAtSeconds)
+	} else {
+		fmt.Println("Blast: плато не достигнуто, увеличьте --duration")
+	}
+	switch b.Bound {
+	case internal.BlastBoundCPU:
+		fmt.Printf("Blast: ограничение CPU (занято %.2f из %.0f ядер)\n", b.CPUCores, b.CPUCoreLimit)
+	case internal.BlastBoundNetwork:
+		fmt.Printf("Blast: ограничение сети (занято %.2f из %.0f ядер)\n", b.CPUCores, b.CPUCoreLimit)
+	default:
+		fmt.Println("Blast: время процессора недоступно, ограничение не определено")
+	}
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingWriter считает записи и байты
+type countingWriter struct {
+	writes atomic.Int64
+	bytes  atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes.Add(1)
+	w.bytes.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func TestBlastWriteIsNotRateLimited(t *testing.T) {
+	const window = 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	defer cancel()
+
+	// --rate 1 в обычном режиме дал бы одну запись за окно
+	w := &countingWriter{}
+	m := &Metrics{}
+	chunk := makePacket(1200, "zeroes")
+	blastWrite(ctx, w, chunk, m)
+
+	if n := w.writes.Load(); n < 100 {
+		t.Errorf("Expected blast mode to write without pauses, got %d writes in %v", n, window)
+	}
+	if m.BytesSent != int(w.bytes.Load()) || m.Success != int(w.writes.Load()) {
+		t.Errorf("Metrics disagree with the writer: %d bytes, %d writes; writer saw %d bytes, %d writes",
+			m.BytesSent, m.Success, w.bytes.Load(), w.writes.Load())
+	}
+	if len(m.Timestamps) != 1 {
+		t.Errorf("Expected a single start timestamp, got %d", len(m.Timestamps))
+	}
+}
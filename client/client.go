@@ -6,14 +6,12 @@ import (
 	"encoding/binary"
 	"fmt"
 	"log"
-	"math"
+	mrand "math/rand"
 	"net"
 	"net/http"
 	"os"
-	"os/signal"
 	"sort"
 	"sync"
-	"syscall"
 	"time"
 
 	"quic-test/internal"
@@ -21,6 +19,7 @@ import (
 	"quic-test/internal/fec"
 	"quic-test/internal/integration"
 	"quic-test/internal/metrics"
+	"quic-test/internal/pcap"
 	"quic-test/internal/pqc"
 
 	"crypto/tls"
@@ -32,6 +31,7 @@ import (
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/logging"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
 type TimePoint struct {
@@ -65,7 +65,10 @@ type Metrics struct {
 	PacketLoss             float64 // %
 	Retransmits            int
 	HandshakeTimes         []float64 // ms
+	ConnectionsConfigured  int       // соединения, заданные --connections
+	ConnectionsActive      int       // соединения с успешным handshake, еще не закрытые
 	TLSVersion             string
+	QUICVersion            string // Согласованная версия QUIC
 	CipherSuite            string
 	SessionResumptionCount int
 	ZeroRTTCount           int
@@ -74,6 +77,7 @@ type Metrics struct {
 	FlowControlEvents      int
 	KeyUpdateEvents        int
 	ErrorTypeCounts        map[string]int // error type -> count
+	ErrorAggregator        *metrics.ErrorAggregator // top-N ошибок с примерами
 	// Time series for new metrics
 	TimeSeriesPacketLoss    []TimePoint
 	TimeSeriesRetransmits   []TimePoint
@@ -82,6 +86,34 @@ type Metrics struct {
 	// HDR Histograms for precise metrics
 	HDRMetrics *metrics.HDRMetrics
 	
+	// Распределение фактически отправленных размеров пакетов
+	PacketSizes *metrics.SizeHistogram
+	
+	// Метрики по фазам теста (handshake, warmup, steady, drain)
+	Phases *metrics.PhaseTracker
+	
+	// Фактически примененная эмуляция (nil без --emulate-*)
+	Emulation *metrics.EmulationStats
+	
+	// Отправленные байты по соединениям для справедливости между ними
+	ConnThroughput *metrics.ConnectionThroughput
+	
+	// Причины закрытия установленных соединений
+	CloseReasons *metrics.CloseReasons
+	
+	// Некритичные обстоятельства прогона для отчета
+	Warnings *metrics.Warnings
+	
+	// Каждый замер RTT в CSV (nil без --raw-latency-out)
+	RawLatency *metrics.RawLatencyWriter
+	
+	// Фактические размеры буферов UDP-сокета после настройки quic-go (байт)
+	UDPRecvBuffer     int
+	UDPSendBuffer     int
+	UDPRecvDrops      int64 // датаграммы, отброшенные ядром до QUIC (переполнение буфера приема)
+	EchoReplies       int   // пакеты, RTT которых измерен по эхо сервера (--echo)
+	LocalAddrs        []string // фактические локальные адреса сокетов соединений
+	
 	// FEC Metrics
 	FECPacketsSent    int64   `json:"fec_packets_sent"`
 	FECRedundancyBytes int64   `json:"fec_redundancy_bytes"`
@@ -101,15 +133,9 @@ func (m *Metrics) ToMap() map[string]interface{} {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
-	// Вычисляем средние значения
-	var avgLatency float64
-	if len(m.Latencies) > 0 {
-		sum := 0.0
-		for _, l := range m.Latencies {
-			sum += l
-		}
-		avgLatency = sum / float64(len(m.Latencies))
-	}
+	// Вычисляем min/max/среднее/stddev латенси за один проход
+	latencyStats := metrics.Summarize(m.Latencies)
+	avgLatency := latencyStats.Mean
 	
 	var avgThroughput float64
 	if len(m.Throughput) > 0 {
@@ -126,8 +152,8 @@ func (m *Metrics) ToMap() map[string]interface{} {
 		rttP50, rttP95, rttP99 = calcPercentiles(m.Latencies)
 	}
 	
-	// Вычисляем jitter (стандартное отклонение)
-	jitter := calcJitter(m.Latencies)
+	// Jitter — стандартное отклонение латенси
+	jitter := latencyStats.StdDev
 	
 	// Вычисляем throughput в Mbps (корректная формула: bytes * 8 / duration_seconds / 1e6)
 	var throughputMbps float64
@@ -221,13 +247,16 @@ func (m *Metrics) ToMap() map[string]interface{} {
 		"RTTP95Ms": rttP95,
 		"RTTP99Ms": rttP99,
 		"RTTMinMs": minRTT,
+		"RTTMaxMs": latencyStats.Max,
 		"RTTAvgMs": avgLatency,
+		"RTTStdDevMs": latencyStats.StdDev,
 		"JitterMs": jitter,
 		"PacketLoss": m.PacketLoss,
 		"Retransmits": m.Retransmits,
 		"BufferbloatFactor": bufferbloatFactor,
 		"FairnessIndex": fairnessIndex,
 		"TLSVersion": m.TLSVersion,
+		"QUICVersion": m.QUICVersion,
 		"CipherSuite": m.CipherSuite,
 		"SessionResumptionCount": m.SessionResumptionCount,
 		"ZeroRTTCount": m.ZeroRTTCount,
@@ -249,6 +278,47 @@ func (m *Metrics) ToMap() map[string]interface{} {
 		"PQCHandshakeSize": m.PQCHandshakeSize,
 		"PQCHandshakeTime": m.PQCHandshakeTime,
 		"PQCAlgorithm": m.PQCAlgorithm,
+		"UDPRecvBuffer": m.UDPRecvBuffer,
+		"UDPSendBuffer": m.UDPSendBuffer,
+		"UDPRecvDrops": m.UDPRecvDrops,
+		"EchoReplies": m.EchoReplies,
+		"LocalAddrs": m.LocalAddrs,
+	}
+	
+	// Агрегированные ошибки: только top-N типов, чтобы отчет оставался читаемым
+	if m.ErrorAggregator != nil {
+		result["TopErrors"] = m.ErrorAggregator.TopN(topErrorsInReport)
+	}
+	if m.PacketSizes != nil {
+		result["PacketSizes"] = m.PacketSizes.Summary()
+	}
+	if warnings := m.Warnings.List(); len(warnings) > 0 {
+		result["Warnings"] = warnings
+	}
+	if m.Emulation != nil {
+		result["Emulation"] = m.Emulation.Summary()
+	}
+	if m.ConnThroughput != nil && len(m.Timestamps) > 0 {
+		// Индекс имеет смысл только для нескольких соединений на общем пути
+		if fairness := m.ConnThroughput.Summary(time.Since(m.Timestamps[0])); len(fairness.Connections) > 1 {
+			result["ConnectionFairness"] = fairness
+		}
+	}
+	if len(m.HandshakeTimes) > 0 {
+		p50, p95, p99 := calcPercentiles(m.HandshakeTimes)
+		result["HandshakeCount"] = len(m.HandshakeTimes)
+		result["HandshakeP50Ms"] = p50
+		result["HandshakeP95Ms"] = p95
+		result["HandshakeP99Ms"] = p99
+		result["HandshakeMaxMs"] = metrics.Summarize(m.HandshakeTimes).Max
+	}
+	if m.CloseReasons != nil {
+		result["CloseReasons"] = m.CloseReasons.Summary()
+	}
+	if m.Phases != nil {
+		now := time.Now()
+		result["Phases"] = m.Phases.Summary(now)
+		result["PhaseBoundaries"] = m.Phases.Boundaries(now)
 	}
 	
 	// Добавляем HDR-метрики если доступны
@@ -263,30 +333,159 @@ func (m *Metrics) ToMap() map[string]interface{} {
 	return result
 }
 
-// Run запускает клиентский тест
-func Run(cfg internal.TestConfig) {
-	ctx, cancel := context.WithCancel(context.Background())
+// topErrorsInReport — сколько типов ошибок попадает в отчет
+const topErrorsInReport = 10
+
+// recordErrorLocked учитывает ошибку в счетчиках и агрегаторе; вызывается под m.mu
+func (m *Metrics) recordErrorLocked(errType string, err error) {
+	m.Errors++
+	if m.ErrorTypeCounts == nil {
+		m.ErrorTypeCounts = map[string]int{}
+	}
+	m.ErrorTypeCounts[errType]++
+	if m.Phases != nil {
+		m.Phases.RecordError(time.Now())
+	}
+	if m.ErrorAggregator != nil {
+		detail := ""
+		if err != nil {
+			detail = err.Error()
+		}
+		m.ErrorAggregator.Record(errType, detail)
+	}
+}
+
+// warn учитывает предупреждение для отчета и выводит его при первом появлении
+func (m *Metrics) warn(code, message string) {
+	if m.Warnings.Add(code, message) {
+		fmt.Printf("⚠️  %s\n", message)
+	}
+}
+
+// warnUDPBuffers учитывает предупреждения настройки буферов сокета
+func (m *Metrics) warnUDPBuffers(warnings []string) {
+	for _, warning := range warnings {
+		m.warn(metrics.WarningUDPBuffer, warning)
+	}
+}
+
+// connectionReadyLocked отмечает завершение handshake соединения (успешное
+// или нет) для разметки фаз; вызывается под m.mu
+func (m *Metrics) connectionReadyLocked() {
+	if m.Phases != nil {
+		m.Phases.ConnectionReady(time.Now())
+	}
+}
+
+// Run запускает клиентский тест и возвращает его результат; для вызова из
+// командной строки с отчетами и кодом выхода SLA служит RunAndReport
+func Run(cfg internal.TestConfig) (*RunResult, error) {
+	return RunContext(context.Background(), cfg)
+}
+
+// RunContext выполняет тест до истечения длительности или отмены ctx и
+// возвращает результат. Отчеты не сохраняются и процесс не завершается:
+// это делает RunAndReport.
+func RunContext(parent context.Context, cfg internal.TestConfig) (*RunResult, error) {
+	return RunLive(parent, cfg, Live{})
+}
+
+// RunLive выполняет тест как RunContext и во время теста передает метрики
+// наблюдателю и берет у него скорость отправки (см. Live)
+func RunLive(parent context.Context, cfg internal.TestConfig, live Live) (*RunResult, error) {
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
-	// Graceful shutdown
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigs
-		fmt.Println("\nПолучен сигнал завершения, формируем отчет...")
-		cancel()
-	}()
+	if err := internal.ValidateTransportParams(cfg.TransportParams); err != nil {
+		return nil, err
+	}
+
+	// Адрес сервера разрешается один раз для всех соединений
+	serverAddr, err := internal.ResolveClientAddr(cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Сервер: %s (%s)\n", cfg.Addr, serverAddr)
+	if cfg.SkipVerify() {
+		fmt.Println(internal.InsecureSkipVerifyWarning)
+	}
+
+	// Передача реального файла вместо сгенерированного трафика
+	if cfg.UploadFile != "" {
+		return runUpload(ctx, cfg, serverAddr), nil
+	}
+
+	warnings := &metrics.Warnings{}
+	if warning := internal.CongestionControlWarning(cfg.CongestionControl); warning != "" {
+		warnings.Add(metrics.WarningCongestionControl, warning)
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+
+	// Короткий тест измеряет в основном handshake и slow start
+	if warning := internal.ShortDurationWarning(cfg); warning != "" {
+		warnings.Add(metrics.WarningShortDuration, warning)
+		fmt.Printf("⚠️  %s\n", warning)
+		if rampUp := internal.EstimateRampUp(cfg).Total(); cfg.AutoWarmup && cfg.Warmup == 0 && rampUp < cfg.Duration {
+			cfg.Warmup = rampUp
+			fmt.Printf("Прогрев (--auto-warmup): %v\n", cfg.Warmup.Round(time.Millisecond))
+		}
+	}
 
 	// SimpleIntegration теперь создается для каждого соединения отдельно
 	// Это необходимо для потокобезопасности при множественных соединениях
 
 	testMetrics := &Metrics{
-		HDRMetrics: metrics.NewHDRMetrics(),
+		HDRMetrics:      metrics.NewHDRMetrics(),
+		ErrorAggregator: metrics.NewErrorAggregator(0, 0),
+		PacketSizes:     metrics.NewSizeHistogram(0),
+		ConnThroughput:  metrics.NewConnectionThroughput(cfg.Connections),
+		CloseReasons:    metrics.NewCloseReasons(),
+		Warnings:        warnings,
+
+		ConnectionsConfigured: cfg.Connections,
+	}
+	if cfg.EmulateLoss > 0 || cfg.EmulateDup > 0 || cfg.EmulateReorder > 0 || cfg.EmulateLatency > 0 || cfg.EmulateJitter > 0 {
+		testMetrics.Emulation = metrics.NewEmulationStats(cfg.EmulateLoss, cfg.EmulateDup, cfg.EmulateReorder, cfg.EmulateLatency, cfg.EmulateJitter)
+	}
+	if cfg.RawLatencyOut != "" {
+		raw, err := metrics.NewRawLatencyWriter(cfg.RawLatencyOut)
+		if err != nil {
+			return nil, err
+		}
+		testMetrics.RawLatency = raw
 	}
 	var wg sync.WaitGroup
+	// Фоновые горутины теста: экспорт метрик, сбор временных рядов, ramp-up,
+	// таймер. RunLive дожидается их, прежде чем собрать результат, чтобы
+	// наблюдатель не получил метрик после возврата.
+	var background sync.WaitGroup
+	goBackground := func(f func()) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			f()
+		}()
+	}
 
 	if cfg.Prometheus {
-		go startPrometheusExporter(testMetrics)
+		reg := metrics.NewRegistry()
+		goBackground(func() { startPrometheusExporter(ctx, testMetrics, reg, metrics.WithLabels(reg, cfg.Labels)) })
+	}
+
+	// Запись трафика в pcap: один файл на все соединения
+	var pcapWriter *pcap.Writer
+	if cfg.PcapPath != "" {
+		w, err := pcap.Create(cfg.PcapPath, cfg.PcapMaxBytes)
+		if err != nil {
+			fmt.Printf("Ошибка создания pcap файла: %v\n", err)
+		} else {
+			pcapWriter = w
+			defer func() {
+				fmt.Printf("pcap: записано пакетов %d, отброшено по лимиту размера %d (%s)\n",
+					pcapWriter.Packets(), pcapWriter.Dropped(), cfg.PcapPath)
+				pcapWriter.Close()
+			}()
+		}
 	}
 	// Создаем и регистрируем глобальный SimpleIntegration ДО запуска горутин соединений
 	// Это нужно, чтобы EnhanceMetricsMap мог получить BBRv3 метрики с самого начала
@@ -296,7 +495,7 @@ func Run(cfg internal.TestConfig) {
 		logger, _ := zap.NewDevelopment()
 		globalSI = integration.NewSimpleIntegration(logger, cfg.CongestionControl)
 		if err := globalSI.Initialize(); err != nil {
-			fmt.Printf("Warning: Failed to initialize global %s integration: %v\n", cfg.CongestionControl, err)
+			testMetrics.warn(metrics.WarningCongestionControl, fmt.Sprintf("failed to initialize %s integration, falling back to the built-in cubic: %v", cfg.CongestionControl, err))
 			globalSI = nil
 		} else {
 			gmc := internal.GetGlobalMetricsCollector()
@@ -310,7 +509,7 @@ func Run(cfg internal.TestConfig) {
 		aiClient := ai.NewPredictionClient(cfg.AIServiceURL)
 		fmt.Printf("[INFO] AI Routing enabled. Connecting to %s\n", cfg.AIServiceURL)
 		
-		go func() {
+		goBackground(func() {
 			ticker := time.NewTicker(1 * time.Second)
 			defer ticker.Stop()
 			
@@ -349,12 +548,23 @@ func Run(cfg internal.TestConfig) {
 					}
 				}
 			}
-		}()
+		})
 	}
 
 	startTime := time.Now()
+	cpuStart := internal.ProcessCPUTime()
+	testMetrics.mu.Lock()
+	testMetrics.Phases = metrics.NewPhaseTracker(startTime, cfg.Connections, cfg.Warmup, cfg.Duration, cfg.Drain)
+	testMetrics.mu.Unlock()
+	goBackground(func() { runProgress(ctx, cfg, testMetrics, os.Stderr, startTime) })
+	var rate int64 = int64(cfg.Rate)
+	cfgPtr := &cfg // чтобы менять Rate по указателю
+	if cfg.Blast {
+		// В режиме --blast скорость не ограничивается: rate 0 отключает паузы
+		rate = 0
+	}
 	// Time series collector
-	go func() {
+	goBackground(func() {
 		var lastCount int
 		var lastBytes int
 		for {
@@ -376,22 +586,39 @@ func Run(cfg internal.TestConfig) {
 				bytesNow := testMetrics.BytesSent
 				throughput := float64(bytesNow-lastBytes) / 1024.0
 				testMetrics.TimeSeriesThroughput = append(testMetrics.TimeSeriesThroughput, TimePoint{Time: now, Value: throughput})
+				var snapshot LiveStats
+				if live.Stats != nil {
+					snapshot = testMetrics.liveStatsLocked(time.Since(startTime), lat, float64(bytesNow-lastBytes)*8/1e6)
+					snapshot.Rate = int(atomic.LoadInt64(&rate))
+				}
 				lastCount = len(testMetrics.Latencies)
 				lastBytes = bytesNow
 				testMetrics.mu.Unlock()
+				if live.Stats != nil {
+					live.Stats(snapshot)
+				}
 				
 				// Периодическая отправка метрик в QUIC Bottom
 				metricsMap := testMetrics.ToMap()
 				metricsMap = internal.EnhanceMetricsMap(metricsMap)
 				internal.UpdateBottomMetrics(metricsMap)
+				if live.Metrics != nil {
+					live.Metrics(metricsMap)
+				}
 			}
 		}
-	}()
+	})
 
 	// --- Ramp-up/ramp-down сценарий ---
-	var rate int64 = int64(cfg.Rate)
-	cfgPtr := &cfg // чтобы менять Rate по указателю
-	go func() {
+	goBackground(func() {
+		if cfg.Blast {
+			return
+		}
+		if live.Rate != nil {
+			// Скоростью управляет наблюдатель, ramp-up не нужен
+			followLiveRate(ctx, live.Rate, &rate)
+			return
+		}
 		minRate := int64(1)
 		maxRate := int64(cfg.Rate)
 		if maxRate < 10 {
@@ -401,20 +628,35 @@ func Run(cfg internal.TestConfig) {
 		if step < 1 {
 			step = 1
 		}
+		// Каждая ступень держится секунду или до остановки теста
+		hold := func(r int64) bool {
+			atomic.StoreInt64(&rate, r)
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(1 * time.Second):
+				return true
+			}
+		}
 		for {
 			// Ramp-up
 			for r := minRate; r <= maxRate; r += step {
-				atomic.StoreInt64(&rate, r)
-				time.Sleep(1 * time.Second)
+				if !hold(r) {
+					return
+				}
 			}
 			// Ramp-down
 			for r := maxRate; r >= minRate; r -= step {
-				atomic.StoreInt64(&rate, r)
-				time.Sleep(1 * time.Second)
+				if !hold(r) {
+					return
+				}
 			}
 		}
-	}()
+	})
 
+	// --max-connections-per-second: соединения открываются равномерно, без
+	// всплеска handshake в начале теста
+	dialLimiter := newDialLimiter(cfg.MaxConnectionsPerSecond)
 	for c := 0; c < cfg.Connections; c++ {
 		wg.Add(1)
 		go func(connID int) {
@@ -442,12 +684,21 @@ func Run(cfg internal.TestConfig) {
 					logger, _ := zap.NewDevelopment()
 					si = integration.NewSimpleIntegration(logger, cfg.CongestionControl)
 					if err := si.Initialize(); err != nil {
-						fmt.Printf("Warning: Failed to initialize %s integration for connection %d: %v\n", cfg.CongestionControl, connID, err)
+						testMetrics.warn(metrics.WarningCongestionControl, fmt.Sprintf("failed to initialize %s integration, falling back to the built-in cubic: %v", cfg.CongestionControl, err))
 						si = nil
 					}
 				}
 			}
-			clientConnection(ctx, *cfgPtr, testMetrics, connID, &rate, si)
+			if dialLimiter != nil {
+				if err := dialLimiter.Wait(ctx); err != nil {
+					// Тест остановлен до очереди этого соединения
+					testMetrics.mu.Lock()
+					testMetrics.connectionReadyLocked()
+					testMetrics.mu.Unlock()
+					return
+				}
+			}
+			clientConnection(ctx, *cfgPtr, serverAddr, testMetrics, connID, &rate, si, pcapWriter)
 			if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 				fmt.Printf("[DEBUG] Connection %d goroutine clientConnection returned\n", connID)
 			}
@@ -458,11 +709,16 @@ func Run(cfg internal.TestConfig) {
 
 	if cfg.Duration > 0 {
 		timer := time.NewTimer(cfg.Duration)
-		go func() {
-			<-timer.C
-			fmt.Println("\nТест завершен по таймеру, формируем отчет...")
-			cancel()
-		}()
+		goBackground(func() {
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				// Тест остановлен раньше
+			case <-timer.C:
+				fmt.Println("\nТест завершен по таймеру, формируем отчет...")
+				cancel()
+			}
+		})
 	}
 
 	// Добавляем таймаут для wg.Wait чтобы избежать зависаний
@@ -478,11 +734,9 @@ func Run(cfg internal.TestConfig) {
 		close(done)
 	}()
 
-	// Ждем завершения или таймаут (дополнительные 10 секунд после duration)
-	timeout := cfg.Duration + 10*time.Second
-	if cfg.Duration == 0 {
-		timeout = 120 * time.Second // default timeout
-	}
+	// Ждем завершения или таймаут (дополнительные finishGrace после
+	// duration); без --duration тест идет до отмены контекста
+	timeout := cfg.Duration + finishGrace
 	
 	if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 		fmt.Printf("[DEBUG] Waiting for connections to finish, timeout: %v\n", timeout)
@@ -494,7 +748,7 @@ func Run(cfg internal.TestConfig) {
 		if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 			fmt.Printf("[DEBUG] All connections finished normally\n")
 		}
-	case <-time.After(timeout):
+	case <-finishTimeout(cfg):
 		fmt.Printf("\n⚠️  Таймаут ожидания завершения (%v). Завершаем принудительно...\n", timeout)
 		if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 			fmt.Printf("[DEBUG] Timeout reached, canceling context...\n")
@@ -514,11 +768,54 @@ func Run(cfg internal.TestConfig) {
 		}
 	}
 
+	// Соединения завершились: останавливаем и дожидаемся фоновых горутин
+	cancel()
+	background.Wait()
+
 	// Минимальный вывод результатов
 	fmt.Printf("\nТест завершен. Обработка результатов...\n")
+	if testMetrics.RawLatency != nil {
+		// Под mu, чтобы не закрыть файл посреди записи замера
+		testMetrics.mu.Lock()
+		rows := testMetrics.RawLatency.Rows()
+		if err := testMetrics.RawLatency.Close(); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		} else {
+			fmt.Printf("Замеры RTT (%d) сохранены: %s\n", rows, cfg.RawLatencyOut)
+		}
+		testMetrics.RawLatency = nil
+		testMetrics.mu.Unlock()
+	}
 
 	// Отправляем метрики в QUIC Bottom (опционально)
 	metricsMap := testMetrics.ToMap()
+	fmt.Printf("Версии: %s\n", internal.ReportVersions(metricsMap))
+	if cfg.Blast {
+		blast := blastSummary(cfg, testMetrics, internal.ProcessCPUTime()-cpuStart, time.Since(startTime))
+		printBlastSummary(blast)
+		metricsMap["Blast"] = blast
+	}
+	
+	if cfg.UDPRecvBuffer > 0 || cfg.UDPSendBuffer > 0 {
+		fmt.Printf("UDP буферы сокета: прием %v байт, отправка %v байт\n",
+			metricsMap["UDPRecvBuffer"], metricsMap["UDPSendBuffer"])
+	}
+	if drops, _ := metricsMap["UDPRecvDrops"].(int64); drops > 0 {
+		fmt.Printf("⚠️  Ядро отбросило %d датаграмм до QUIC (переполнение буфера приема, см. --udp-recv-buffer)\n", drops)
+		warnings.Add(metrics.WarningUDPRecvDrops, fmt.Sprintf("kernel dropped %d datagrams before QUIC (receive buffer overflow, see --udp-recv-buffer)", drops))
+	}
+	if replies, _ := metricsMap["EchoReplies"].(int); cfg.Echo && replies == 0 {
+		fmt.Println("⚠️  Сервер не ответил ни на один пакет (--echo), RTT не измерен: запущен ли сервер с --echo?")
+		warnings.Add(metrics.WarningNoEchoReplies, "server answered no packet with --echo, RTT was not measured")
+	}
+	if testMetrics.Emulation != nil {
+		for _, warning := range testMetrics.Emulation.Summary().LimitWarnings() {
+			testMetrics.warn(metrics.WarningEmulation, warning)
+		}
+	}
+	if list := warnings.List(); len(list) > 0 {
+		metricsMap["Warnings"] = list
+	}
 	
 	// Enhance with BBRv3 and experimental metrics
 	metricsMap = internal.EnhanceMetricsMap(metricsMap)
@@ -533,36 +830,47 @@ func Run(cfg internal.TestConfig) {
 	// Опционально: отправка в QUIC Bottom (если нужно)
 	internal.UpdateBottomMetrics(metricsMap)
 
-	// Save report with enhanced metrics (including BBRv3)
-	err := internal.SaveReport(cfg, metricsMap)
-	if err != nil {
-		fmt.Printf("Ошибка сохранения отчета: %v\n", err)
+	result := &RunResult{
+		Config:    cfg,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+		Metrics:   metricsMap,
 	}
+	testMetrics.mu.Lock()
+	result.Success = testMetrics.Success
+	result.Errors = testMetrics.Errors
+	testMetrics.mu.Unlock()
+	if testMetrics.ErrorAggregator != nil {
+		result.TopErrors = testMetrics.ErrorAggregator.TopN(topErrorsInReport)
+	}
+	result.Warnings = warnings.List()
+	result.checkSLA()
+	return result, nil
+}
 
-	// Экспорт в Prometheus format
-	if cfg.ReportPath != "" {
-		// Создаем имя файла для Prometheus (заменяем расширение на .prom)
-		promFile := cfg.ReportPath
-		if len(promFile) > 4 && promFile[len(promFile)-5:] == ".json" {
-			promFile = promFile[:len(promFile)-5] + ".prom"
-		} else {
-			promFile = promFile + ".prom"
-		}
-		
-		if err := internal.ExportPrometheusMetrics(cfg, metricsMap, promFile); err != nil {
-			fmt.Printf("Ошибка экспорта Prometheus метрик: %v\n", err)
-		} else {
-			fmt.Printf("Prometheus метрики сохранены: %s\n", promFile)
-		}
+// clientTLSConfig возвращает TLS-конфигурацию клиента. Сертификат сервера
+// проверяется, если проверка не отключена --insecure или --no-tls; --ca-cert
+// задает доверенный CA, --cert/--key — клиентский сертификат. В режиме test
+// клиент доверяет только сертификату сервера своей пары.
+func clientTLSConfig(cfg internal.TestConfig) (*tls.Config, error) {
+	conf, err := internal.ClientTLSConfig(cfg.SkipVerify(), cfg.CACertPath, []string{"quic-test"})
+	if err != nil {
+		return nil, err
 	}
-	
-	// Проверяем SLA если настроено
-	if cfg.SlaRttP95 > 0 || cfg.SlaLoss > 0 || cfg.SlaThroughput > 0 || cfg.SlaErrors > 0 {
-		internal.ExitWithSLA(cfg, metricsMap)
+	if cfg.LoopbackCert != nil && !cfg.SkipVerify() {
+		internal.PinCertificate(conf, cfg.LoopbackCert.Leaf)
 	}
+	if cfg.CertPath != "" && cfg.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
 }
 
-func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Metrics, connID int, ratePtr *int64, si *integration.SimpleIntegration) {
+func clientConnection(ctx context.Context, cfg internal.TestConfig, serverAddr *net.UDPAddr, metrics *Metrics, connID int, ratePtr *int64, si *integration.SimpleIntegration, pcapWriter *pcap.Writer) {
 	if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 		fmt.Printf("[DEBUG] clientConnection %d: started\n", connID)
 	}
@@ -571,63 +879,74 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 			fmt.Printf("[DEBUG] clientConnection %d: returning\n", connID)
 		}
 	}()
-	var tlsConf *tls.Config
-	if cfg.CertPath != "" && cfg.KeyPath != "" {
-		cert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
-		if err != nil {
-			metrics.mu.Lock()
-			metrics.Errors++
-			if metrics.ErrorTypeCounts == nil {
-				metrics.ErrorTypeCounts = map[string]int{}
-			}
-			metrics.ErrorTypeCounts["tls_load_cert"]++
-			metrics.mu.Unlock()
-			fmt.Println("Ошибка загрузки сертификата:", err)
-			return
-		}
-		tlsConf = &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			InsecureSkipVerify: true,
-			NextProtos:         []string{"quic-test"},
-		}
-	} else {
-		// Используем единую функцию для генерации TLS конфигурации
-		tlsConf = internal.GenerateTLSConfig(cfg.NoTLS)
+	tlsConf, err := clientTLSConfig(cfg)
+	if err != nil {
+		metrics.mu.Lock()
+		metrics.recordErrorLocked("tls_load_cert", err)
+		metrics.connectionReadyLocked()
+		metrics.mu.Unlock()
+		fmt.Println("Ошибка загрузки сертификата:", err)
+		return
 	}
 
 	// Создаем отдельный UDP connection для каждого QUIC connection
 	// Это необходимо для поддержки большого количества одновременных connections
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	// (--local-addr привязывает сокеты к интерфейсу и, при одном соединении, к порту)
+	var udpConn *net.UDPConn
+	localAddr, err := internal.ParseLocalAddr(cfg.LocalAddr)
+	if err == nil {
+		udpConn, err = internal.ListenLocalUDP(localAddr)
+	}
 	if err != nil {
 		metrics.mu.Lock()
-		metrics.Errors++
-		if metrics.ErrorTypeCounts == nil {
-			metrics.ErrorTypeCounts = map[string]int{}
-		}
-		metrics.ErrorTypeCounts["udp_socket"]++
+		metrics.recordErrorLocked("udp_socket", err)
+		metrics.connectionReadyLocked()
 		metrics.mu.Unlock()
 		fmt.Printf("Ошибка создания UDP socket для connection %d: %v\n", connID, err)
 		return
 	}
 	defer udpConn.Close()
 
-	// Создаем QUIC конфигурацию с tracer для BBRv3
-	var quicConfig *quic.Config
+	// Фактический адрес сокета: без порта в --local-addr его выбирает система
+	boundAddr := udpConn.LocalAddr().String()
+	metrics.mu.Lock()
+	metrics.LocalAddrs = append(metrics.LocalAddrs, boundAddr)
+	metrics.mu.Unlock()
+	if cfg.LocalAddr != "" {
+		fmt.Printf("Соединение %d: локальный адрес %s\n", connID, boundAddr)
+	}
+	
+	// Буферы сокета (--udp-recv-buffer / --udp-send-buffer); предупреждения
+	// одинаковы для всех соединений, поэтому выводятся один раз
+	if cfg.UDPRecvBuffer > 0 || cfg.UDPSendBuffer > 0 {
+		_, warnings := internal.ApplyUDPBuffers(udpConn, cfg.UDPRecvBuffer, cfg.UDPSendBuffer)
+		metrics.warnUDPBuffers(warnings)
+	}
+	
+	// Датаграммы, отброшенные ядром до QUIC; наблюдение останавливается
+	// до закрытия сокета (defer выполняется раньше udpConn.Close)
+	stopDropWatch := internal.WatchUDPDrops(udpConn, time.Second, func(delta uint64) {
+		metrics.mu.Lock()
+		metrics.UDPRecvDrops += int64(delta)
+		metrics.mu.Unlock()
+	})
+	defer stopDropWatch()
+
+	// Создаем QUIC конфигурацию из параметров теста (с tracer для BBRv3)
+	quicConfig := internal.CreateClientQUICConfig(cfg)
 	if si != nil && cfg.CongestionControl == "bbrv3" {
 		// Создаем tracer для отслеживания реальных ACK событий
 		logger, _ := zap.NewDevelopment()
 		
-		quicConfig = &quic.Config{
-			Tracer: func(ctx context.Context, perspective logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
-				connectionIDStr := fmt.Sprintf("conn_%d_%s", connID, connID.String())
-				return integration.NewConnectionTracerForConnection(logger, si, connectionIDStr)
-			},
+		quicConfig.Tracer = func(ctx context.Context, perspective logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+			connectionIDStr := fmt.Sprintf("conn_%d_%s", connID, connID.String())
+			return integration.NewConnectionTracerForConnection(logger, si, connectionIDStr)
 		}
 	}
 	
 	// Создаем отдельный Transport для каждого connection
 	transport := &quic.Transport{
-		Conn: udpConn,
+		Conn: pcap.WrapPacketConn(udpConn, pcapWriter),
 	}
 	defer transport.Close()
 
@@ -649,7 +968,7 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 		metrics.mu.Unlock()
 	}
 	
-	session, err := transport.Dial(ctx, parseAddr(cfg.Addr), tlsConf, quicConfig)
+	session, err := dialQUIC(ctx, transport, serverAddr, tlsConf, quicConfig, cfg.HandshakeTimeout)
 	handshakeTime := time.Since(handshakeStart).Seconds() * 1000 // ms
 	
 	// Сохраняем connection для использования в tracer (если используется BBRv3)
@@ -658,18 +977,20 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 		integration.StoreConnection(connectionID, session)
 	}
 	metrics.mu.Lock()
+	metrics.connectionReadyLocked()
 	metrics.HandshakeTimes = append(metrics.HandshakeTimes, handshakeTime)
 	metrics.TimeSeriesHandshakeTime = append(metrics.TimeSeriesHandshakeTime, TimePoint{Time: time.Since(handshakeStart).Seconds(), Value: handshakeTime})
 	// Записываем handshake время в HDR-гистограммы
 	if metrics.HDRMetrics != nil {
 		metrics.HDRMetrics.RecordHandshakeTime(time.Duration(handshakeTime) * time.Millisecond)
 	}
+	// quic-go при создании Transport может увеличить буферы, поэтому
+	// фактические размеры читаются после Dial (независимо от его исхода)
+	if sizes, err := internal.ReadUDPBuffers(udpConn); err == nil {
+		metrics.UDPRecvBuffer, metrics.UDPSendBuffer = sizes.Recv, sizes.Send
+	}
 	if err != nil {
-		metrics.Errors++
-		if metrics.ErrorTypeCounts == nil {
-			metrics.ErrorTypeCounts = map[string]int{}
-		}
-		metrics.ErrorTypeCounts["quic_handshake"]++
+		metrics.recordErrorLocked(handshakeErrorType(err), err)
 		metrics.mu.Unlock()
 		fmt.Println("Ошибка соединения:", err)
 		return
@@ -677,6 +998,7 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 	// TLS negotiated params
 	state := session.ConnectionState()
 	metrics.TLSVersion = tlsVersionString(state.TLS.Version)
+	metrics.QUICVersion = state.Version.String()
 	metrics.CipherSuite = cipherSuiteString(state.TLS.CipherSuite)
 	if state.TLS.DidResume {
 		metrics.SessionResumptionCount++
@@ -686,11 +1008,17 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 	} else {
 		metrics.OneRTTCount++
 	}
+	metrics.ConnectionsActive++
 	metrics.mu.Unlock()
 	defer func() {
 		if err := session.CloseWithError(0, "client done"); err != nil {
 			fmt.Printf("Warning: failed to close session: %v\n", err)
 		}
+		metrics.mu.Lock()
+		metrics.ConnectionsActive--
+		metrics.mu.Unlock()
+		// Если соединение уже закрыто (idle timeout, сервер), причина — его ошибка
+		metrics.CloseReasons.Record(context.Cause(session.Context()))
 	}()
 
 	var wg sync.WaitGroup
@@ -709,7 +1037,11 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 			if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 				fmt.Printf("[DEBUG] Connection %d, Stream %d: goroutine started\n", connID, streamID)
 			}
-			clientStream(ctx, session, cfg, metrics, connID, streamID, ratePtr, si)
+			if cfg.Blast {
+				blastStream(ctx, session, cfg, metrics)
+			} else {
+				clientStream(ctx, session, cfg, metrics, connID, streamID, ratePtr, si)
+			}
 			if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 				fmt.Printf("[DEBUG] Connection %d, Stream %d: clientStream returned\n", connID, streamID)
 			}
@@ -729,13 +1061,11 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 		close(done)
 	}()
 	
-	streamTimeout := cfg.Duration + 10*time.Second
-	if cfg.Duration == 0 {
-		streamTimeout = 70 * time.Second
-	}
+	// Без --duration потоки ждут отмены контекста без срока
+	streamTimeout := finishTimeout(cfg)
 	
 	if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
-		fmt.Printf("[DEBUG] Connection %d: Waiting for streams, timeout: %v\n", connID, streamTimeout)
+		fmt.Printf("[DEBUG] Connection %d: Waiting for streams, timeout: %v\n", connID, cfg.Duration+finishGrace)
 	}
 	
 	select {
@@ -755,9 +1085,9 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 		case <-time.After(2 * time.Second):
 			fmt.Printf("[WARNING] Connection %d: Some streams didn't finish after context cancel\n", connID)
 		}
-	case <-time.After(streamTimeout):
+	case <-streamTimeout:
 		// Таймаут - принудительно завершаем
-		fmt.Printf("[WARNING] Connection %d streams timeout after %v, canceling context\n", connID, streamTimeout)
+		fmt.Printf("[WARNING] Connection %d streams timeout after %v, canceling context\n", connID, cfg.Duration+finishGrace)
 		if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 			fmt.Printf("[DEBUG] Connection %d: Stream timeout reached\n", connID)
 		}
@@ -769,6 +1099,15 @@ func clientConnection(ctx context.Context, cfg internal.TestConfig, metrics *Met
 	}
 }
 
+// emulatedSend — пакет, готовый к отправке с учетом эмуляции сети
+type emulatedSend struct {
+	wire       []byte // пакет в том виде, в котором пишется в поток
+	size       int    // размер пакета без длины --pattern
+	seq        int64
+	redundancy []byte // redundancy пакет FEC, отправляемый следом
+	delay      time.Duration
+}
+
 // clientStream реализует передачу данных по QUIC-стриму и сбор метрик
 func clientStream(ctx context.Context, session quic.Connection, cfg internal.TestConfig, metrics *Metrics, connID, streamID int, ratePtr *int64, si *integration.SimpleIntegration) {
 	if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
@@ -816,20 +1155,65 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 	stream, err := session.OpenStreamSync(ctx)
 	if err != nil {
 		metrics.mu.Lock()
-		metrics.Errors++
-		if metrics.ErrorTypeCounts == nil {
-			metrics.ErrorTypeCounts = map[string]int{}
-		}
-		metrics.ErrorTypeCounts["open_stream"]++
+		metrics.recordErrorLocked("open_stream", err)
 		metrics.mu.Unlock()
 		return
 	}
+	// С --echo после закрытия своей стороны поток ждет эхо последних пакетов
+	var echoDone chan struct{}
 	defer func() {
 		if err := stream.Close(); err != nil {
 			fmt.Printf("Warning: failed to close stream: %v\n", err)
 		}
+		if echoDone != nil {
+			waitEchoes(stream, echoDone)
+		}
 	}()
 
+	// Шаблоны zeroes и increment сервер проверяет: заголовок сообщает ему
+	// шаблон, и дальше пакеты идут с длиной. С --echo пакеты тоже идут с
+	// длиной, чтобы сервер ответил на каждый, а шаблон передается в
+	// заголовке эхо.
+	verifyPattern := internal.PatternVerifiable(cfg.Pattern)
+	framed := verifyPattern || cfg.Echo
+	if framed {
+		header, _ := internal.PatternHeader(cfg.Pattern)
+		headerError := "pattern_header"
+		if cfg.Echo {
+			header = internal.EchoHeader(cfg.Pattern)
+			headerError = "echo_header"
+		}
+		if _, err := stream.Write(header); err != nil {
+			metrics.mu.Lock()
+			metrics.recordErrorLocked(headerError, err)
+			metrics.mu.Unlock()
+			return
+		}
+	}
+
+	// RTT по эхо сервера заменяет оценку по эмулированной задержке
+	var echo *echoTracker
+	if cfg.Echo {
+		echo = newEchoTracker()
+		echoDone = make(chan struct{})
+		go func() {
+			defer close(echoDone)
+			echo.readAcks(stream, func(rtt time.Duration, size int) {
+				metrics.recordEchoRTT(rtt)
+				if si != nil {
+					defer func() {
+						if r := recover(); r != nil {
+							fmt.Printf("[ERROR] Panic in OnAckReceived: %v\n", r)
+						}
+					}()
+					si.OnAckReceived(session, size, rtt)
+				}
+			})
+		}()
+	}
+
+	emuRand := newEmulationRand(cfg.EmulationSeed, connID, streamID)
+
 	// Инициализация map для ошибок
 	metrics.mu.Lock()
 	if metrics.ErrorTypeCounts == nil {
@@ -837,7 +1221,13 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 	}
 	metrics.mu.Unlock()
 
-	packetSize := cfg.PacketSize
+	packetSizes := cfg.PacketSizeDistribution()
+	// Отдельная последовательность, чтобы выбор размера не коррелировал с эмуляцией потерь
+	sizeSeed := cfg.EmulationSeed
+	if sizeSeed != 0 {
+		sizeSeed = ^sizeSeed
+	}
+	sizeRand := newEmulationRand(sizeSeed, connID, streamID)
 	pattern := cfg.Pattern
 	sentPackets := 0
 	ackedPackets := 0
@@ -845,31 +1235,40 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 	outOfOrder := 0
 	var lastSeq int64 = -1
 	var seq int64
+	// Пакеты, задержанные --emulate-reorder до отправки следующего, и
+	// пакеты, которые конец теста застал в очереди отправки (unsent), так и
+	// не уходят. С эмуляцией они считаются потерянными, чтобы отправленные
+	// и потерянные пакеты сходились с принятыми решениями.
+	var held, unsent []emulatedSend
+	defer func() {
+		if n := len(held) + len(unsent); n > 0 && metrics.Emulation != nil {
+			metrics.Emulation.DropUnsent(n)
+			metrics.mu.Lock()
+			metrics.ErrorTypeCounts["emulated_loss"] += n
+			metrics.mu.Unlock()
+		}
+	}()
 	start := time.Now()
 	
-	// Таймаут для цикла отправки
-	sendTimeout := cfg.Duration
-	if sendTimeout == 0 {
-		sendTimeout = 60 * time.Second // default
-	}
-	sendDeadline := time.Now().Add(sendTimeout)
+	// Срок цикла отправки; без --duration поток шлет до отмены контекста
+	sendDeadline := sendDeadlineFor(cfg, time.Now())
 	
 	if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 		fmt.Printf("[DEBUG] Connection %d, Stream %d: sendDeadline set to %v (from now: %v)\n", 
-			connID, streamID, sendDeadline, sendTimeout)
+			connID, streamID, sendDeadline, cfg.Duration)
 	}
 	
 	iterCount := 0
 	for {
 		iterCount++
 		if cfg.CongestionControl == "bbrv3" && iterCount%1000 == 0 {
-			elapsed := time.Since(sendDeadline.Add(-sendTimeout))
+			elapsed := time.Since(start)
 			fmt.Printf("[DEBUG] Connection %d, Stream %d: iteration %d, elapsed: %v, deadline in: %v\n", 
 				connID, streamID, iterCount, elapsed, time.Until(sendDeadline))
 		}
 		
 		// Проверяем контекст и таймаут перед каждой итерацией
-		if time.Now().After(sendDeadline) {
+		if pastDeadline(sendDeadline) {
 			// Достигнут deadline отправки
 			if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 				fmt.Printf("[DEBUG] Connection %d, Stream %d: sendDeadline reached, returning\n", connID, streamID)
@@ -886,28 +1285,33 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 		}
 		
 		// Проверяем таймаут
-		if time.Now().After(sendDeadline) {
+		if pastDeadline(sendDeadline) {
 			return
 		}
 		
-		// Эмуляция задержки (с проверкой контекста и deadline)
-		if cfg.EmulateLatency > 0 {
+		// Эмуляция задержки (с проверкой контекста и deadline); --emulate-jitter
+		// меняет ее для каждого пакета
+		var delay time.Duration
+		if cfg.EmulateLatency > 0 || cfg.EmulateJitter > 0 {
+			delay = metrics.Emulation.Delay(emuRand)
 			// Проверяем deadline перед задержкой
-			if time.Now().After(sendDeadline) {
+			if pastDeadline(sendDeadline) {
 				if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 					fmt.Printf("[DEBUG] Connection %d, Stream %d: deadline reached before latency emulation, returning\n", connID, streamID)
 				}
 				return
 			}
+			delayStart := time.Now()
 			select {
 			case <-ctx.Done():
 				if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 					fmt.Printf("[DEBUG] Connection %d, Stream %d: ctx.Done() during latency emulation, returning\n", connID, streamID)
 				}
 				return
-			case <-time.After(cfg.EmulateLatency):
+			case <-time.After(delay):
+				metrics.Emulation.RecordDelay(time.Since(delayStart))
 				// Проверяем deadline после задержки
-				if time.Now().After(sendDeadline) {
+				if pastDeadline(sendDeadline) {
 					if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 						fmt.Printf("[DEBUG] Connection %d, Stream %d: deadline reached after latency emulation, returning\n", connID, streamID)
 					}
@@ -916,14 +1320,14 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 			}
 		}
 		// Эмуляция потери пакета
-		if cfg.EmulateLoss > 0 && secureFloat64() < cfg.EmulateLoss {
+		if metrics.Emulation.Drop(emuRand) {
 			metrics.mu.Lock()
 			metrics.ErrorTypeCounts["emulated_loss"]++
 			metrics.mu.Unlock()
 			continue // пропускаем отправку
 		}
 		// Формируем пакет с seq
-		buf := makePacket(packetSize, pattern)
+		buf := makePacket(packetSizes.Sample(sizeRand), pattern)
 		seq++
 		if len(buf) >= 8 {
 			for i := 0; i < 8; i++ {
@@ -947,20 +1351,43 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 			}
 		}
 		
+		wire := buf
+		if framed {
+			wire = internal.FramePatternPacket(buf)
+			if redundancyPacket != nil {
+				redundancyPacket = internal.FramePatternPacket(redundancyPacket)
+			}
+		}
+		
 		// Дублирование пакета
-		dupCount := 1
-		if cfg.EmulateDup > 0 && secureFloat64() < cfg.EmulateDup {
-			dupCount = 2
+		packet := emulatedSend{wire: wire, size: len(buf), seq: seq, redundancy: redundancyPacket, delay: delay}
+		sends := []emulatedSend{packet}
+		if metrics.Emulation.Duplicate(emuRand) {
+			sends = append(sends, packet)
 			metrics.mu.Lock()
 			metrics.ErrorTypeCounts["emulated_dup"]++
 			metrics.mu.Unlock()
 		}
-		for d := 0; d < dupCount; d++ {
+		// Перестановка: задержанный пакет уходит сразу после текущего. Пакет
+		// не ждет ничего, кроме следующего, поэтому с --emulate-latency
+		// перестановка только меняет порядок соседних пакетов.
+		if held != nil {
+			sends = append(sends, held...)
+			held = nil
+		} else if metrics.Emulation.Reorder(emuRand) {
+			held = sends
+			sends = nil
+			metrics.mu.Lock()
+			metrics.ErrorTypeCounts["emulated_reorder"]++
+			metrics.mu.Unlock()
+		}
+		for i, out := range sends {
 			// Проверяем deadline перед отправкой
-			if time.Now().After(sendDeadline) {
+			if pastDeadline(sendDeadline) {
 				if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 					fmt.Printf("[DEBUG] Connection %d, Stream %d: deadline reached before write, returning\n", connID, streamID)
 				}
+				unsent = sends[i:]
 				return
 			}
 			
@@ -970,6 +1397,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 				if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 					fmt.Printf("[DEBUG] Connection %d, Stream %d: ctx.Done() before write, returning\n", connID, streamID)
 				}
+				unsent = sends[i:]
 				return
 			default:
 			}
@@ -980,7 +1408,11 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 					fmt.Printf("[DEBUG] Connection %d, Stream %d: OnPacketSent called (packet %d)\n", 
 						connID, streamID, sentPackets)
 				}
-				si.OnPacketSent(session, len(buf), false)
+				si.OnPacketSent(session, out.size, false)
+			}
+			
+			if echo != nil {
+				echo.sent(out.seq, len(out.wire), time.Now())
 			}
 			
 			// Используем context с таймаутом для Write чтобы избежать блокировок
@@ -990,7 +1422,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 			var err error
 			
 			go func() {
-				n, err = stream.Write(buf)
+				n, err = stream.Write(out.wire)
 				writeDone <- err
 			}()
 			
@@ -999,11 +1431,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 				writeCancel()
 				// Таймаут записи - продолжаем
 				metrics.mu.Lock()
-				metrics.Errors++
-				if metrics.ErrorTypeCounts == nil {
-					metrics.ErrorTypeCounts = map[string]int{}
-				}
-				metrics.ErrorTypeCounts["stream_write_timeout"]++
+				metrics.recordErrorLocked("stream_write_timeout", writeCtx.Err())
 				metrics.mu.Unlock()
 				continue
 			case err = <-writeDone:
@@ -1012,12 +1440,16 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 			
 			// Получаем реальный RTT из Connection (используем LatestRTT если доступен)
 			// В quic-go RTT доступен через connection, но не через ConnectionState
-			// Используем эмулированную задержку + небольшая случайная вариация для реалистичности
+			// Используем эмулированную задержку + небольшая случайная вариация для реалистичности.
+			// С --echo настоящий RTT приходит с эхо и учитывается в recordEchoRTT.
 			var realRTT time.Duration
-			if cfg.EmulateLatency > 0 {
+			if cfg.EmulateJitter > 0 {
+				// Задержка этого пакета уже включает jitter
+				realRTT = out.delay
+			} else if cfg.EmulateLatency > 0 {
 				realRTT = cfg.EmulateLatency
 				// Добавляем небольшую вариацию для jitter (5-10% от базовой задержки)
-				jitter := time.Duration(float64(cfg.EmulateLatency) * 0.05 * secureFloat64())
+				jitter := time.Duration(float64(cfg.EmulateLatency) * 0.05 * emuRand())
 				realRTT += jitter
 			} else {
 				// Fallback: используем типичный RTT для локальной сети
@@ -1029,12 +1461,33 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 			
 			metrics.mu.Lock()
 			metrics.BytesSent += n
+			if metrics.ConnThroughput != nil {
+				metrics.ConnThroughput.Add(connID, n)
+			}
+			if metrics.PacketSizes != nil {
+				// Размер по --packet-size, без длины кадра --pattern и --echo
+				metrics.PacketSizes.Record(out.size)
+			}
 			metrics.Success++
-			metrics.Latencies = append(metrics.Latencies, latencyForMetrics)
+			if echo == nil {
+				metrics.Latencies = append(metrics.Latencies, latencyForMetrics)
+				if metrics.RawLatency != nil {
+					metrics.RawLatency.Record(time.Now(), realRTT)
+				}
+			}
 			metrics.Timestamps = append(metrics.Timestamps, time.Now())
+			if metrics.Phases != nil {
+				if echo == nil {
+					metrics.Phases.RecordPacket(time.Now(), n, latencyForMetrics)
+				} else {
+					metrics.Phases.RecordSent(time.Now(), n)
+				}
+			}
 			// Записываем в HDR-гистограммы
 			if metrics.HDRMetrics != nil {
-				metrics.HDRMetrics.RecordLatency(realRTT)
+				if echo == nil {
+					metrics.HDRMetrics.RecordLatency(realRTT)
+				}
 				metrics.HDRMetrics.AddBytesSent(int64(n))
 				metrics.HDRMetrics.IncrementPacketsSent()
 			}
@@ -1045,7 +1498,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 			// Уведомляем SimpleIntegration о получении ACK с реальным RTT
 			// В QUIC ACK приходит асинхронно, поэтому мы используем smoothed RTT
 			// Это приближение, но лучше чем время записи
-			if si != nil && err == nil {
+			if si != nil && err == nil && echo == nil {
 				if cfg.CongestionControl == "bbrv3" && ackedPackets%1000 == 0 {
 					fmt.Printf("[DEBUG] Connection %d, Stream %d: OnAckReceived called (packet %d, acked %d)\n", 
 						connID, streamID, sentPackets, ackedPackets)
@@ -1067,12 +1520,15 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 				}
 			}
 			if err != nil {
-				metrics.mu.Lock()
-				metrics.Errors++
-				if metrics.ErrorTypeCounts == nil {
-					metrics.ErrorTypeCounts = map[string]int{}
+				if isStreamDataLimit(err) {
+					// Сервер сбросил поток за превышение --max-stream-data: писать в него бесполезно
+					metrics.mu.Lock()
+					metrics.recordErrorLocked("stream_data_limit", err)
+					metrics.mu.Unlock()
+					return
 				}
-				metrics.ErrorTypeCounts["stream_write"]++
+				metrics.mu.Lock()
+				metrics.recordErrorLocked("stream_write", err)
 				retransmits++
 				metrics.Retransmits++
 				var se *quic.StreamError
@@ -1092,14 +1548,14 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 				metrics.mu.Unlock()
 				continue
 			}
-			if lastSeq != -1 && seq != lastSeq+1 {
+			if lastSeq != -1 && out.seq != lastSeq+1 {
 				outOfOrder++
 				metrics.mu.Lock()
 				metrics.OutOfOrderCount++
 				metrics.mu.Unlock()
 			}
 			// Отправляем redundancy пакет если он был создан
-			if redundancyPacket != nil && err == nil {
+			if redundancyPacket := out.redundancy; redundancyPacket != nil && err == nil {
 				// Отправляем redundancy пакет в отдельном write
 				redundancyCtx, redundancyCancel := context.WithTimeout(ctx, 2*time.Second)
 				redundancyDone := make(chan error, 1)
@@ -1122,7 +1578,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 				}
 			}
 			
-			lastSeq = seq
+			lastSeq = out.seq
 			metrics.mu.Lock()
 			metrics.TimeSeriesRetransmits = append(metrics.TimeSeriesRetransmits, TimePoint{Time: time.Since(start).Seconds(), Value: float64(retransmits)})
 			metrics.TimeSeriesPacketLoss = append(metrics.TimeSeriesPacketLoss, TimePoint{Time: time.Since(start).Seconds(), Value: 100 * float64(sentPackets-ackedPackets) / (float64(sentPackets) + 1e-9)})
@@ -1130,7 +1586,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 		}
 		// Пауза между пакетами (с проверкой контекста и deadline)
 		// Проверяем deadline перед паузой
-		if time.Now().After(sendDeadline) {
+		if pastDeadline(sendDeadline) {
 			if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 				fmt.Printf("[DEBUG] Connection %d, Stream %d: deadline reached before sleep, returning\n", connID, streamID)
 			}
@@ -1150,7 +1606,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 					return
 				case <-time.After(sleepDuration):
 					// Проверяем deadline после sleep
-					if time.Now().After(sendDeadline) {
+					if pastDeadline(sendDeadline) {
 						if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 							fmt.Printf("[DEBUG] Connection %d, Stream %d: deadline reached after sleep, returning\n", connID, streamID)
 						}
@@ -1167,7 +1623,7 @@ func clientStream(ctx context.Context, session quic.Connection, cfg internal.Tes
 					return
 				case <-time.After(sleepDuration):
 					// Проверяем deadline после sleep
-					if time.Now().After(sendDeadline) {
+					if pastDeadline(sendDeadline) {
 						if cfg.CongestionControl == "bbrv3" || cfg.CongestionControl == "bbrv2" {
 							fmt.Printf("[DEBUG] Connection %d, Stream %d: deadline reached after short sleep, returning\n", connID, streamID)
 						}
@@ -1194,6 +1650,15 @@ func makePacket(size int, pattern string) []byte {
 	return buf
 }
 
+// newDialLimiter ограничивает частоту открытия соединений; nil — без ограничения.
+// Burst 1: первое соединение открывается сразу, следующие — с интервалом 1/perSecond
+func newDialLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), 1)
+}
+
 // calcPercentiles вычисляет p50, p95, p99 для латенси
 func calcPercentiles(latencies []float64) (p50, p95, p99 float64) {
 	if len(latencies) == 0 {
@@ -1211,30 +1676,18 @@ func calcPercentiles(latencies []float64) (p50, p95, p99 float64) {
 	return
 }
 
-// calcJitter вычисляет стандартное отклонение латенси (jitter)
-func calcJitter(latencies []float64) float64 {
-	if len(latencies) == 0 {
-		return 0
-	}
-	mean := 0.0
-	for _, l := range latencies {
-		mean += l
-	}
-	mean /= float64(len(latencies))
-	var sum float64
-	for _, l := range latencies {
-		d := l - mean
-		sum += d * d
-	}
-	variance := sum / float64(len(latencies))
-	// Извлекаем квадратный корень для получения стандартного отклонения
-	jitter := math.Sqrt(variance)
-	return jitter
-}
-
 // printMetrics удалена - больше не используется
 
-func startPrometheusExporter(metrics *Metrics) {
+// Адрес --prometheus клиента и срок, за который остановка теста дожидается
+// идущих запросов метрик
+const (
+	prometheusAddr            = ":2112"
+	prometheusShutdownTimeout = 2 * time.Second
+)
+
+// startPrometheusExporter отдает метрики клиента на prometheusAddr до
+// отмены ctx
+func startPrometheusExporter(ctx context.Context, metrics *Metrics, reg *prometheus.Registry, gatherer prometheus.Gatherer) {
 	success := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "quic_client_success_total",
 		Help: "Total successful packets sent",
@@ -1274,6 +1727,14 @@ func startPrometheusExporter(metrics *Metrics) {
 		}
 		return sum / float64(len(metrics.Latencies))
 	})
+	udpDrops := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_client_udp_receive_drops_total",
+		Help: "Datagrams dropped by the kernel before QUIC read them (receive buffer overflow)",
+	}, func() float64 {
+		metrics.mu.Lock()
+		defer metrics.mu.Unlock()
+		return float64(metrics.UDPRecvDrops)
+	})
 	throughput := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "quic_client_throughput_kbps",
 		Help: "Current throughput in KB/s",
@@ -1290,12 +1751,51 @@ func startPrometheusExporter(metrics *Metrics) {
 		return 0
 	})
 
-	prometheus.MustRegister(success, errors, bytesSent, avgLatency, throughput)
-	http.Handle("/metrics", promhttp.Handler())
-	fmt.Println("Prometheus endpoint доступен на :2112/metrics")
-	if err := http.ListenAndServe(":2112", nil); err != nil {
+	// Фактически примененная эмуляция; сравнивается с --emulate-* в отчете
+	emulatedDrops := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "quic_client_emulated_drops_total",
+		Help: "Packets dropped by --emulate-loss",
+	}, func() float64 {
+		return float64(metrics.Emulation.Drops())
+	})
+	emulatedDups := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "quic_client_emulated_dups_total",
+		Help: "Packets duplicated by --emulate-dup",
+	}, func() float64 {
+		return float64(metrics.Emulation.Dups())
+	})
+	emulatedReorders := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "quic_client_emulated_reorders_total",
+		Help: "Packets sent after the next one by --emulate-reorder",
+	}, func() float64 {
+		return float64(metrics.Emulation.Reorders())
+	})
+	emulatedDelay := prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "quic_client_emulated_delay_seconds_sum",
+		Help: "Total delay applied by --emulate-latency and --emulate-jitter in seconds",
+	}, func() float64 {
+		return metrics.Emulation.DelaySeconds()
+	})
+
+	reg.MustRegister(success, errors, bytesSent, avgLatency, udpDrops, throughput, emulatedDrops, emulatedDups, emulatedReorders, emulatedDelay)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: prometheusAddr, Handler: mux}
+	serveDone := make(chan error, 1)
+	go func() { serveDone <- srv.ListenAndServe() }()
+	fmt.Printf("Prometheus endpoint доступен на %s/metrics\n", prometheusAddr)
+	select {
+	case err := <-serveDone:
 		log.Printf("Failed to start Prometheus server: %v", err)
+		return
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), prometheusShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Prometheus server shutdown: %v", err)
 	}
+	<-serveDone
 }
 
 // Вспомогательные функции для TLSVersion/CipherSuite
@@ -1322,6 +1822,17 @@ func cipherSuiteString(cs uint16) string {
 	}
 }
 
+// newEmulationRand возвращает источник случайных чисел для эмуляции сети.
+// При заданном seed каждый поток получает детерминированную последовательность,
+// поэтому прогоны с одинаковым seed видят одинаковую картину потерь и дублей.
+func newEmulationRand(seed int64, connID, streamID int) func() float64 {
+	if seed == 0 {
+		return secureFloat64
+	}
+	r := mrand.New(mrand.NewSource(seed ^ int64(connID)<<32 ^ int64(streamID)))
+	return r.Float64
+}
+
 // secureFloat64 генерирует криптографически стойкое случайное число от 0 до 1
 func secureFloat64() float64 {
 	b := make([]byte, 8)
@@ -1338,58 +1849,8 @@ const (
 	keyUpdateErrorCode   = 0xE // KeyUpdateError
 )
 
-// parseAddr парсит адрес в формате "host:port" и возвращает *net.UDPAddr
-func parseAddr(addr string) *net.UDPAddr {
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
-	if err != nil {
-		// Fallback на простой парсинг
-		host, port := "127.0.0.1", "9000"
-		if len(addr) > 0 {
-			parts := splitHostPort(addr)
-			if len(parts) == 2 {
-				host, port = parts[0], parts[1]
-				// Если host пустой (например, ":9000"), используем localhost
-				if host == "" {
-					host = "127.0.0.1"
-				}
-			} else if len(parts) == 1 {
-				// Только порт (например, ":9000" или "9000")
-				if parts[0] != "" {
-					port = parts[0]
-				}
-			}
-		}
-		udpAddr = &net.UDPAddr{
-			IP:   net.ParseIP(host),
-			Port: parseInt(port),
-		}
-	} else {
-		// Проверяем, что IP не пустой и не IPv6 :: (который может вызвать проблемы)
-		if udpAddr.IP == nil || udpAddr.IP.IsUnspecified() {
-			// Если IP пустой или неопределенный, используем 127.0.0.1
-			udpAddr.IP = net.ParseIP("127.0.0.1")
-		}
-	}
-	return udpAddr
-}
-
-// splitHostPort разделяет "host:port"
-func splitHostPort(addr string) []string {
-	for i := len(addr) - 1; i >= 0; i-- {
-		if addr[i] == ':' {
-			return []string{addr[:i], addr[i+1:]}
-		}
-	}
-	return []string{addr}
-}
-
-// parseInt парсит строку в int
-func parseInt(s string) int {
-	val := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] >= '0' && s[i] <= '9' {
-			val = val*10 + int(s[i]-'0')
-		}
-	}
-	return val
+// isStreamDataLimit сообщает, что сервер сбросил поток за превышение --max-stream-data
+func isStreamDataLimit(err error) bool {
+	var se *quic.StreamError
+	return errors.As(err, &se) && se.Remote && uint64(se.ErrorCode) == internal.StreamDataLimitErrorCode
 }
@@ -1,7 +1,21 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
 )
 
 // TestGenerateTestData тестирует генерацию тестовых данных
@@ -78,3 +92,193 @@ func TestTimePoint(t *testing.T) {
 		t.Errorf("TimePoint.Value = %v, want %v", tp.Value, 42.0)
 	}
 }
+
+func TestMaxConnectionsPerSecond(t *testing.T) {
+	listener, err := quic.ListenAddr("127.0.0.1:0", internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	var (
+		mu       sync.Mutex
+		accepted []time.Time
+	)
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			accepted = append(accepted, time.Now())
+			mu.Unlock()
+			go func() {
+				for {
+					str, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go io.Copy(io.Discard, str)
+				}
+			}()
+		}
+	}()
+
+	const connections, perSecond = 5, 10.0
+	cfg := internal.TestConfig{
+		Addr:                    listener.Addr().String(),
+		Connections:             connections,
+		MaxConnectionsPerSecond: perSecond,
+		Streams:                 1,
+		PacketSize:              100,
+		Rate:                    10,
+		Duration:                time.Second,
+		NoTLS:                   true,
+	}
+	result, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(accepted) != connections {
+		t.Fatalf("Expected %d connections, got %d", connections, len(accepted))
+	}
+	// Первое соединение открывается сразу, каждое следующее — не раньше чем через 1/perSecond
+	minSpan := time.Duration(float64(connections-1) / perSecond * float64(time.Second))
+	if span := accepted[connections-1].Sub(accepted[0]); span < minSpan-20*time.Millisecond {
+		t.Errorf("Expected %d connections to open over at least %v, got %v", connections, minSpan, span)
+	}
+	if count, _ := result.Metrics["HandshakeCount"].(int); count != connections {
+		t.Errorf("Expected %d handshakes in the metrics, got %v", connections, result.Metrics["HandshakeCount"])
+	}
+}
+
+func TestRawLatencyOutMatchesSamples(t *testing.T) {
+	listener, err := quic.ListenAddr("127.0.0.1:0", internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					str, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go io.Copy(io.Discard, str)
+				}
+			}()
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "rtt.csv")
+	cfg := internal.TestConfig{
+		Addr:          listener.Addr().String(),
+		Connections:   2,
+		Streams:       2,
+		PacketSize:    100,
+		Rate:          50,
+		Duration:      time.Second,
+		NoTLS:         true,
+		RawLatencyOut: path,
+	}
+	result, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, _ := result.Metrics["Latencies"].([]float64)
+	if len(samples) == 0 {
+		t.Fatal("Expected RTT samples from the run")
+	}
+	// Первая строка — заголовок
+	if len(rows)-1 != len(samples) {
+		t.Errorf("Expected %d rows of RTT samples, got %d", len(samples), len(rows)-1)
+	}
+}
+
+func TestLiveConnectionsBelowConfigured(t *testing.T) {
+	// Сервер завершает handshake только первых двух соединений
+	const configured, established = 4, 2
+	tlsConf := internal.GenerateTLSConfig(true)
+	var handshakes int32
+	tlsConf.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		if atomic.AddInt32(&handshakes, 1) > established {
+			return nil, errors.New("connection refused by test")
+		}
+		return nil, nil
+	}
+	listener, err := quic.ListenAddr("127.0.0.1:0", tlsConf, nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					str, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go io.Copy(io.Discard, str)
+				}
+			}()
+		}
+	}()
+
+	cfg := internal.TestConfig{
+		Addr:             listener.Addr().String(),
+		Connections:      configured,
+		Streams:          1,
+		PacketSize:       100,
+		Rate:             10,
+		Duration:         2 * time.Second,
+		HandshakeTimeout: time.Second,
+		NoTLS:            true,
+	}
+	var (
+		mu   sync.Mutex
+		last LiveStats
+	)
+	_, err = RunLive(context.Background(), cfg, Live{
+		Stats: func(stats LiveStats) {
+			mu.Lock()
+			last = stats
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunLive: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if last.ConfiguredConnections != configured {
+		t.Errorf("Expected %d configured connections, got %d", configured, last.ConfiguredConnections)
+	}
+	if last.Connections != established {
+		t.Errorf("Expected %d live connections, got %d", established, last.Connections)
+	}
+}
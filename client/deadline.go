@@ -0,0 +1,36 @@
+package client
+
+import (
+	"time"
+
+	"quic-test/internal"
+)
+
+// finishGrace — запас после --duration, за который соединения и потоки
+// должны завершиться, прежде чем их ожидание прерывается
+const finishGrace = 10 * time.Second
+
+// sendDeadlineFor возвращает срок отправки потока, начавшего отправку в
+// start. Без --duration срока нет (нулевое время): поток шлет пакеты до
+// отмены контекста.
+func sendDeadlineFor(cfg internal.TestConfig, start time.Time) time.Time {
+	if cfg.Duration <= 0 {
+		return time.Time{}
+	}
+	return start.Add(cfg.Duration)
+}
+
+// pastDeadline сообщает, что срок наступил; нулевой срок не наступает
+func pastDeadline(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+// finishTimeout возвращает канал, который срабатывает через finishGrace
+// после --duration, когда ожидание потоков пора прервать. Без --duration
+// канал nil: потоки ждут отмены контекста без срока.
+func finishTimeout(cfg internal.TestConfig) <-chan time.Time {
+	if cfg.Duration <= 0 {
+		return nil
+	}
+	return time.After(cfg.Duration + finishGrace)
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+// TestUnlimitedDurationHasNoDeadlines: без --duration тест идет до отмены
+// контекста — ни у отправки, ни у ожидания потоков нет срока по умолчанию
+func TestUnlimitedDurationHasNoDeadlines(t *testing.T) {
+	start := time.Now().Add(-24 * time.Hour)
+	deadline := sendDeadlineFor(internal.TestConfig{}, start)
+	if !deadline.IsZero() || pastDeadline(deadline) {
+		t.Errorf("Expected no send deadline a day into an unlimited test, got %v", deadline)
+	}
+	if finishTimeout(internal.TestConfig{}) != nil {
+		t.Error("Expected no stream wait timeout for an unlimited test")
+	}
+
+	cfg := internal.TestConfig{Duration: time.Second}
+	if deadline := sendDeadlineFor(cfg, start); !deadline.Equal(start.Add(time.Second)) || !pastDeadline(deadline) {
+		t.Errorf("Expected the send deadline one second after the start, got %v", deadline)
+	}
+	if finishTimeout(cfg) == nil {
+		t.Error("Expected a stream wait timeout with --duration")
+	}
+}
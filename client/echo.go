@@ -0,0 +1,103 @@
+package client
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// maxPendingEchoes ограничивает число пакетов, ждущих эхо в одном потоке:
+// если сервер не отвечает (запущен без --echo), память не растет весь тест
+const maxPendingEchoes = 1 << 16
+
+// echoDrainTimeout — сколько поток ждет последних эхо после отправки
+const echoDrainTimeout = 2 * time.Second
+
+// echoPending — отправленный пакет, на который еще не пришло эхо
+type echoPending struct {
+	sent time.Time
+	size int
+}
+
+// echoTracker сопоставляет эхо сервера (--echo) с отправленными пакетами
+// одного потока и считает по ним RTT
+type echoTracker struct {
+	mu      sync.Mutex
+	pending map[uint64]echoPending // по номеру пакета
+}
+
+func newEchoTracker() *echoTracker {
+	return &echoTracker{pending: make(map[uint64]echoPending)}
+}
+
+// sent запоминает время отправки пакета. Вызывается до записи в поток,
+// чтобы быстрое эхо не опередило запись. Повтор пакета (--emulate-dup)
+// время первой отправки не меняет.
+func (t *echoTracker) sent(seq int64, size int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pending[uint64(seq)]; ok || len(t.pending) >= maxPendingEchoes {
+		return
+	}
+	t.pending[uint64(seq)] = echoPending{sent: now, size: size}
+}
+
+// acked возвращает RTT и размер пакета, на который пришло эхо; ok = false
+// для неизвестного номера или повторного эхо
+func (t *echoTracker) acked(seq uint64, now time.Time) (rtt time.Duration, size int, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.pending[seq]
+	if !ok {
+		return 0, 0, false
+	}
+	delete(t.pending, seq)
+	return now.Sub(p.sent), p.size, true
+}
+
+// readAcks читает эхо из r до конца потока и передает RTT каждого
+// отправленного пакета в onRTT
+func (t *echoTracker) readAcks(r io.Reader, onRTT func(rtt time.Duration, size int)) {
+	for {
+		ack, err := internal.ReadEchoAck(r)
+		if err != nil {
+			return
+		}
+		if rtt, size, ok := t.acked(ack.Seq, time.Now()); ok {
+			onRTT(rtt, size)
+		}
+	}
+}
+
+// waitEchoes ждет, пока сервер ответит на последние пакеты и закроет свою
+// сторону потока; не дождавшись за echoDrainTimeout, перестает читать
+func waitEchoes(stream quic.Stream, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(echoDrainTimeout):
+		stream.CancelRead(0)
+		<-done
+	}
+}
+
+// recordEchoRTT учитывает RTT пакета, измеренный по эхо сервера
+func (m *Metrics) recordEchoRTT(rtt time.Duration) {
+	latencyMs := float64(rtt.Nanoseconds()) / 1e6
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.EchoReplies++
+	m.Latencies = append(m.Latencies, latencyMs)
+	if m.RawLatency != nil {
+		m.RawLatency.Record(time.Now(), rtt)
+	}
+	if m.Phases != nil {
+		m.Phases.RecordRTT(time.Now(), latencyMs)
+	}
+	if m.HDRMetrics != nil {
+		m.HDRMetrics.RecordLatency(rtt)
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+func TestEchoTrackerMatchesReplies(t *testing.T) {
+	tracker := newEchoTracker()
+	start := time.Now()
+	tracker.sent(1, 1200, start)
+	tracker.sent(2, 600, start.Add(10*time.Millisecond))
+	// Повтор (--emulate-dup) не сдвигает время первой отправки
+	tracker.sent(2, 600, start.Add(20*time.Millisecond))
+
+	var acks []byte
+	for _, seq := range []uint64{2, 1, 2, 7} {
+		acks = binaryAck(acks, seq)
+	}
+	type reply struct {
+		rtt  time.Duration
+		size int
+	}
+	var replies []reply
+	tracker.readAcks(bytes.NewReader(acks), func(rtt time.Duration, size int) {
+		replies = append(replies, reply{rtt, size})
+	})
+
+	// Повторное эхо и эхо неизвестного пакета не учитываются
+	if len(replies) != 2 || replies[0].size != 600 || replies[1].size != 1200 {
+		t.Fatalf("Expected replies to packets 2 and 1, got %+v", replies)
+	}
+	if replies[1].rtt < replies[0].rtt {
+		t.Errorf("Expected packet 1, sent first, to have the longer RTT, got %+v", replies)
+	}
+}
+
+func TestEchoTrackerIsBounded(t *testing.T) {
+	tracker := newEchoTracker()
+	now := time.Now()
+	for seq := int64(1); seq <= maxPendingEchoes+10; seq++ {
+		tracker.sent(seq, 100, now)
+	}
+	if len(tracker.pending) != maxPendingEchoes {
+		t.Errorf("Expected at most %d pending packets, got %d", maxPendingEchoes, len(tracker.pending))
+	}
+}
+
+// binaryAck добавляет к dst подтверждение пакета seq, как его пишет сервер
+func binaryAck(dst []byte, seq uint64) []byte {
+	packet := make([]byte, 8)
+	for i := range packet {
+		packet[i] = byte(seq >> (8 * i))
+	}
+	return internal.AppendEchoAck(dst, packet, time.Now())
+}
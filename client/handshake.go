@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// defaultHandshakeTimeout — HandshakeIdleTimeout quic-go по умолчанию,
+// действует без --handshake-timeout
+const defaultHandshakeTimeout = 5 * time.Second
+
+// handshakeTimeoutError — handshake не завершился за отведенное время;
+// учитывается отдельной категорией ошибок, а не как общая ошибка соединения
+type handshakeTimeoutError struct {
+	timeout    time.Duration
+	configured bool // таймаут задан через --handshake-timeout
+	err        error
+}
+
+func (e *handshakeTimeoutError) Error() string {
+	source := "--handshake-timeout"
+	if !e.configured {
+		source = "default"
+	}
+	return fmt.Sprintf("handshake timed out after %v (%s): %v", e.timeout, source, e.err)
+}
+
+func (e *handshakeTimeoutError) Unwrap() error { return e.err }
+
+// handshakeErrorType возвращает категорию ошибки handshake для ErrorTypeCounts
+func handshakeErrorType(err error) string {
+	var timeoutErr *handshakeTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return "handshake_timeout"
+	}
+	return "quic_handshake"
+}
+
+// dialQUIC устанавливает соединение, ограничивая весь handshake таймаутом
+// --handshake-timeout: quic-go отсчитывает HandshakeIdleTimeout от последнего
+// полученного пакета и допускает handshake до двух таймаутов, поэтому Dial
+// дополнительно ограничивается контекстом
+func dialQUIC(ctx context.Context, transport *quic.Transport, addr net.Addr, tlsConf *tls.Config, quicConf *quic.Config, timeout time.Duration) (quic.Connection, error) {
+	configured := timeout > 0
+	dialCtx := ctx
+	if configured {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	} else {
+		timeout = defaultHandshakeTimeout
+		if quicConf != nil && quicConf.HandshakeIdleTimeout > 0 {
+			timeout = quicConf.HandshakeIdleTimeout
+		}
+	}
+	conn, err := transport.Dial(dialCtx, addr, tlsConf, quicConf)
+	// Отмена родительского контекста (конец теста, сигнал) — не таймаут
+	if err != nil && ctx.Err() == nil && isHandshakeTimeout(err) {
+		return nil, &handshakeTimeoutError{timeout: timeout, configured: configured, err: err}
+	}
+	return conn, err
+}
+
+// isHandshakeTimeout сообщает, истек ли handshake по таймауту: контекста
+// Dial или одного из таймаутов quic-go
+func isHandshakeTimeout(err error) bool {
+	var handshakeErr *quic.HandshakeTimeoutError
+	var idleErr *quic.IdleTimeoutError
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &handshakeErr) ||
+		errors.As(err, &idleErr)
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/metrics"
+)
+
+func TestHandshakeTimeoutAgainstBlackHole(t *testing.T) {
+	// Сокет принимает Initial-пакеты и молча их отбрасывает
+	blackHole, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blackHole.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := blackHole.ReadFrom(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	const timeout = 300 * time.Millisecond
+	cfg := internal.TestConfig{
+		Connections:      1,
+		Streams:          1,
+		PacketSize:       100,
+		Rate:             50,
+		NoTLS:            true,
+		HandshakeTimeout: timeout,
+	}
+	m := &Metrics{ErrorAggregator: metrics.NewErrorAggregator(0, 0)}
+	rate := int64(cfg.Rate)
+	start := time.Now()
+	clientConnection(context.Background(), cfg, blackHole.LocalAddr().(*net.UDPAddr), m, 0, &rate, nil, nil)
+	elapsed := time.Since(start)
+
+	if elapsed < timeout || elapsed > timeout+time.Second {
+		t.Errorf("Handshake gave up after %v, want about %v", elapsed, timeout)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ErrorTypeCounts["handshake_timeout"] != 1 || m.ErrorTypeCounts["quic_handshake"] != 0 {
+		t.Errorf("Expected one handshake_timeout error, got %v", m.ErrorTypeCounts)
+	}
+	top := m.ErrorAggregator.TopN(1)
+	if len(top) != 1 || len(top[0].Samples) == 0 || !strings.Contains(top[0].Samples[0], timeout.String()) {
+		t.Errorf("Expected the configured timeout %v in the error, got %+v", timeout, top)
+	}
+}
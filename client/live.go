@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"quic-test/internal/metrics"
+)
+
+// Live связывает идущий тест с внешним наблюдателем, например с GUI
+type Live struct {
+	// Stats вызывается раз в секунду со снимком метрик
+	Stats func(LiveStats)
+	// Metrics вызывается раз в секунду с накопленными метриками в формате
+	// отчета (ToMap + EnhanceMetricsMap)
+	Metrics func(map[string]interface{})
+	// Rate возвращает нужную скорость отправки (пакетов в секунду на поток).
+	// Если задана, заменяет сценарий ramp-up/ramp-down и опрашивается раз в секунду.
+	Rate func() int
+}
+
+// LiveStats — снимок метрик идущего теста
+type LiveStats struct {
+	Elapsed     time.Duration
+	Connections int // установленные и еще открытые соединения
+	// ConfiguredConnections — заданные --connections; больше Connections,
+	// если часть соединений не установилась или уже закрылась
+	ConfiguredConnections int
+	BytesSent             int64
+	Packets               int // успешно отправленные пакеты
+	Errors                int
+	LatencyMs             float64 // средняя латенси за последнюю секунду
+	ThroughputMbps        float64 // за последнюю секунду
+	PacketLoss            float64 // доля пакетов, отброшенных эмуляцией --emulate-loss
+	Rate                  int     // текущая скорость отправки
+	// Emulation — заданные и фактические значения --emulate-*; пустая без эмуляции
+	Emulation metrics.EmulationSummary
+}
+
+// liveRateInterval — как часто опрашивается Live.Rate
+const liveRateInterval = time.Second
+
+// liveStatsLocked собирает снимок метрик; вызывается под m.mu
+func (m *Metrics) liveStatsLocked(elapsed time.Duration, latencyMs, throughputMbps float64) LiveStats {
+	stats := LiveStats{
+		Elapsed:               elapsed,
+		Connections:           m.ConnectionsActive,
+		ConfiguredConnections: m.ConnectionsConfigured,
+		BytesSent:             int64(m.BytesSent),
+		Packets:               m.Success,
+		Errors:                m.Errors,
+		LatencyMs:             latencyMs,
+		ThroughputMbps:        throughputMbps,
+		Emulation:             m.Emulation.Summary(),
+	}
+	if dropped := m.ErrorTypeCounts["emulated_loss"]; dropped > 0 {
+		stats.PacketLoss = float64(dropped) / float64(dropped+m.Success)
+	}
+	return stats
+}
+
+// followLiveRate переносит скорость наблюдателя в rate до отмены ctx
+func followLiveRate(ctx context.Context, liveRate func() int, rate *int64) {
+	ticker := time.NewTicker(liveRateInterval)
+	defer ticker.Stop()
+	for {
+		if r := liveRate(); r > 0 {
+			atomic.StoreInt64(rate, int64(r))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
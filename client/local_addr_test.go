@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// freeUDPPort возвращает свободный порт loopback
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestClientBindsLocalAddr(t *testing.T) {
+	listener, err := quic.ListenAddr("127.0.0.1:0", internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	remotes := make(chan net.Addr, 1)
+	go func() {
+		conn, err := listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		remotes <- conn.RemoteAddr()
+		for {
+			str, err := conn.AcceptStream(context.Background())
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, str)
+		}
+	}()
+
+	local := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: freeUDPPort(t)}
+	cfg := internal.TestConfig{
+		Connections: 1,
+		Streams:     1,
+		PacketSize:  100,
+		Rate:        50,
+		NoTLS:       true,
+		LocalAddr:   local.String(),
+	}
+	m := &Metrics{}
+	rate := int64(cfg.Rate)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	clientConnection(ctx, cfg, listener.Addr().(*net.UDPAddr), m, 0, &rate, nil, nil)
+
+	select {
+	case remote := <-remotes:
+		if remote.String() != local.String() {
+			t.Errorf("Expected the server to see the client at %s, got %s", local, remote)
+		}
+	default:
+		t.Fatal("Expected the client to connect")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.LocalAddrs) != 1 || m.LocalAddrs[0] != local.String() {
+		t.Errorf("Expected the bound local address %s to be reported, got %v", local, m.LocalAddrs)
+	}
+}
+
+func TestClientLocalAddrConflict(t *testing.T) {
+	taken, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	cfg := internal.TestConfig{Connections: 1, Streams: 1, PacketSize: 100, Rate: 50, LocalAddr: taken.LocalAddr().String()}
+	m := &Metrics{}
+	rate := int64(cfg.Rate)
+	clientConnection(context.Background(), cfg, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}, m, 0, &rate, nil, nil)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ErrorTypeCounts["udp_socket"] != 1 {
+		t.Fatalf("Expected a udp_socket error, got %v", m.ErrorTypeCounts)
+	}
+	if len(m.LocalAddrs) != 0 {
+		t.Errorf("Expected no bound address, got %v", m.LocalAddrs)
+	}
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/metrics"
+)
+
+// progressLine — одна строка периодической сводки (--progress-interval)
+type progressLine struct {
+	Time           time.Time         `json:"time"`
+	Msg            string            `json:"msg"`
+	ElapsedSec     float64           `json:"elapsed_s"`
+	BytesSent      metrics.ByteCount `json:"bytes_sent"`
+	BytesReceived  metrics.ByteCount `json:"bytes_received"`
+	Packets        int               `json:"packets"`
+	RTTMs          float64           `json:"rtt_ms"`
+	ThroughputKBps float64           `json:"throughput_kbps"`
+	LossPercent    float64           `json:"loss_percent"`
+}
+
+// progressPrinter считает показатели за последний интервал: RTT и throughput
+// отражают текущее состояние, а не среднее с начала теста
+type progressPrinter struct {
+	w          io.Writer
+	jsonFormat bool
+	start      time.Time
+
+	lastAt        time.Time
+	lastBytes     int
+	lastLatencies int
+}
+
+// runProgress печатает сводку каждые cfg.ProgressInterval до отмены ctx.
+// Ничего не делает без интервала или в режиме --quiet.
+func runProgress(ctx context.Context, cfg internal.TestConfig, m *Metrics, w io.Writer, start time.Time) {
+	if cfg.ProgressInterval <= 0 || cfg.Quiet {
+		return
+	}
+	p := &progressPrinter{
+		w:          w,
+		jsonFormat: cfg.LogFormat == internal.LogFormatJSON,
+		start:      start,
+		lastAt:     start,
+	}
+
+	ticker := time.NewTicker(cfg.ProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.print(p.snapshot(m, now))
+		}
+	}
+}
+
+func (p *progressPrinter) snapshot(m *Metrics, now time.Time) progressLine {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	line := progressLine{
+		Time:       now,
+		Msg:        "progress",
+		ElapsedSec: now.Sub(p.start).Seconds(),
+		BytesSent:  metrics.ByteCount(m.BytesSent),
+		Packets:    m.Success,
+	}
+	if m.HDRMetrics != nil {
+		line.BytesReceived = m.HDRMetrics.GetNetworkStats().BytesReceived
+	}
+	if recent := m.Latencies[min(p.lastLatencies, len(m.Latencies)):]; len(recent) > 0 {
+		sum := 0.0
+		for _, l := range recent {
+			sum += l
+		}
+		line.RTTMs = sum / float64(len(recent))
+	}
+	if elapsed := now.Sub(p.lastAt).Seconds(); elapsed > 0 {
+		line.ThroughputKBps = float64(m.BytesSent-p.lastBytes) / 1024.0 / elapsed
+	}
+	if n := len(m.TimeSeriesPacketLoss); n > 0 {
+		line.LossPercent = m.TimeSeriesPacketLoss[n-1].Value
+	}
+
+	p.lastAt = now
+	p.lastBytes = m.BytesSent
+	p.lastLatencies = len(m.Latencies)
+	return line
+}
+
+func (p *progressPrinter) print(line progressLine) {
+	if p.jsonFormat {
+		data, err := json.Marshal(line)
+		if err == nil {
+			fmt.Fprintln(p.w, string(data))
+		}
+		return
+	}
+	elapsed := time.Duration(line.ElapsedSec * float64(time.Second)).Round(time.Second)
+	fmt.Fprintf(p.w, "[progress] %s sent %s recv %s rtt %.2f ms throughput %.2f KB/s loss %.2f%%\n",
+		elapsed, formatBytes(int64(line.BytesSent)), formatBytes(int64(line.BytesReceived)),
+		line.RTTMs, line.ThroughputKBps, line.LossPercent)
+}
+
+// formatBytes форматирует объем в двоичных единицах
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/metrics"
+)
+
+// syncBuffer — bytes.Buffer, безопасный для записи из горутины прогресса
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+// runShortProgress запускает прогресс на ~200ms, имитируя отправку пакетов
+func runShortProgress(t *testing.T, cfg internal.TestConfig) *syncBuffer {
+	t.Helper()
+	m := &Metrics{}
+	out := &syncBuffer{}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runProgress(ctx, cfg, m, out, time.Now())
+	}()
+
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		<-ticker.C
+		m.mu.Lock()
+		m.BytesSent += 1200
+		m.Success++
+		m.Latencies = append(m.Latencies, 10)
+		m.mu.Unlock()
+	}
+	<-done
+	return out
+}
+
+func TestProgressText(t *testing.T) {
+	out := runShortProgress(t, internal.TestConfig{ProgressInterval: 50 * time.Millisecond})
+
+	lines := out.Lines()
+	if len(lines) < 3 {
+		t.Fatalf("Expected at least 3 progress lines, got %d: %q", len(lines), lines)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[progress] ") || !strings.Contains(line, "rtt 10.00 ms") {
+			t.Errorf("Unexpected progress line %q", line)
+		}
+	}
+}
+
+func TestProgressJSON(t *testing.T) {
+	out := runShortProgress(t, internal.TestConfig{ProgressInterval: 50 * time.Millisecond, LogFormat: internal.LogFormatJSON})
+
+	lines := out.Lines()
+	if len(lines) < 3 {
+		t.Fatalf("Expected at least 3 progress lines, got %d: %q", len(lines), lines)
+	}
+	var prevSent metrics.ByteCount
+	for _, line := range lines {
+		var p progressLine
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			t.Fatalf("Progress line is not JSON: %q: %v", line, err)
+		}
+		if p.Msg != "progress" || p.RTTMs != 10 || p.ThroughputKBps <= 0 {
+			t.Errorf("Unexpected progress record %+v", p)
+		}
+		if p.BytesSent <= prevSent {
+			t.Errorf("Expected bytes sent to grow, got %d after %d", p.BytesSent, prevSent)
+		}
+		prevSent = p.BytesSent
+	}
+}
+
+func TestProgressQuiet(t *testing.T) {
+	out := runShortProgress(t, internal.TestConfig{ProgressInterval: 20 * time.Millisecond, Quiet: true})
+	if lines := out.Lines(); len(lines) != 1 || lines[0] != "" {
+		t.Errorf("Expected no output with --quiet, got %q", lines)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB"}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
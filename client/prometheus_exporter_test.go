@@ -5,12 +5,13 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // createTestExporter создает экспортер с отдельным registry для тестов
 func createTestExporter() *AdvancedPrometheusExporter {
 	registry := prometheus.NewRegistry()
-	return NewAdvancedPrometheusExporterWithRegistry(registry)
+	return NewAdvancedPrometheusExporter(registry)
 }
 
 func TestNewAdvancedPrometheusExporter(t *testing.T) {
@@ -171,3 +172,22 @@ func TestGetClientMetrics(t *testing.T) {
 		t.Error("StartTime is zero")
 	}
 }
+
+func TestExportersWithSeparateRegistries(t *testing.T) {
+	first := prometheus.NewRegistry()
+	second := prometheus.NewRegistry()
+
+	// Два экспортера в одном процессе не должны паниковать при регистрации
+	exporterA := NewAdvancedPrometheusExporter(first)
+	exporterB := NewAdvancedPrometheusExporter(second)
+
+	exporterA.UpdateTestType("latency", "random")
+	exporterA.RecordTestExecution("conn1", time.Second, "success")
+
+	if got := testutil.ToFloat64(exporterA.testTypeCounters.WithLabelValues("latency", "random", "conn1")); got != 1 {
+		t.Errorf("Expected 1 test execution in first registry, got %f", got)
+	}
+	if got := testutil.CollectAndCount(exporterB.testTypeCounters); got != 0 {
+		t.Errorf("Expected no test executions in second registry, got %d", got)
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/metrics"
+)
+
+// RunResult — итог прогона клиента для использования пакета как библиотеки:
+// те же метрики, что попадают в отчет, ошибки, результат SLA и время
+type RunResult struct {
+	Config    internal.TestConfig
+	StartTime time.Time
+	EndTime   time.Time
+
+	// Metrics — карта метрик в формате отчета (ToMap + EnhanceMetricsMap)
+	Metrics map[string]interface{}
+
+	Success   int
+	Errors    int
+	TopErrors []metrics.ErrorSummary
+
+	// Warnings — некритичные обстоятельства прогона (урезанные буферы,
+	// недоступный алгоритм --cc, эмуляция у пределов, короткий тест)
+	Warnings []metrics.Warning
+
+	// SLA заполняется, только если в конфигурации заданы пороги SLA
+	SLA *SLAOutcome
+
+	// Upload заполняется при передаче файла (--upload-file)
+	Upload *internal.UploadResult
+}
+
+// SLAOutcome — результат проверки SLA
+type SLAOutcome struct {
+	Passed     bool
+	Violations []internal.SLAViolationInfo
+	ExitCode   internal.SLAExitCode
+}
+
+// Duration возвращает фактическую длительность прогона
+func (r *RunResult) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// ExitCode возвращает код завершения CLI для этого результата
+func (r *RunResult) ExitCode() internal.SLAExitCode {
+	if r.Upload != nil && !r.Upload.Verified {
+		return internal.ExitCodeCriticalFailure
+	}
+	if r.SLA != nil {
+		return r.SLA.ExitCode
+	}
+	return internal.ExitCodeSuccess
+}
+
+// checkSLA заполняет результат SLA, если пороги заданы
+func (r *RunResult) checkSLA() {
+	cfg := r.Config
+	if cfg.SlaRttP95 <= 0 && cfg.SlaLoss <= 0 && cfg.SlaThroughput <= 0 && cfg.SlaErrors <= 0 {
+		return
+	}
+	passed, violations, exitCode := internal.CheckSLA(cfg, r.Metrics)
+	r.SLA = &SLAOutcome{Passed: passed, Violations: violations, ExitCode: exitCode}
+}
+
+// RunAndReport — обертка для командной строки: запускает тест с
+// обработкой SIGINT/SIGTERM, сохраняет отчеты и завершает процесс с кодом SLA
+func RunAndReport(cfg internal.TestConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Graceful shutdown
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case <-sigs:
+			fmt.Println("\nПолучен сигнал завершения, формируем отчет...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Стоки --sink получают метрики каждую секунду и итог прогона
+	sinks := openSinks(cfg.Sinks)
+	var live Live
+	if len(sinks) > 0 {
+		live.Stats = func(stats LiveStats) {
+			sinks.Publish(sinkMetricsEvent(stats))
+		}
+	}
+
+	// Отчет --report-format jsonl пополняется каждую секунду во время теста
+	var stream *internal.ReportStream
+	if internal.IsStreamingReport(cfg.ReportFormat) {
+		var err error
+		if stream, err = internal.OpenReportStream(cfg); err != nil {
+			fmt.Printf("Ошибка сохранения отчета: %v\n", err)
+		} else {
+			live.Metrics = func(metrics map[string]interface{}) {
+				if err := stream.WriteSnapshot(metrics); err != nil {
+					fmt.Printf("Ошибка записи отчета: %v\n", err)
+				}
+			}
+		}
+	}
+
+	result, err := RunLive(ctx, cfg, live)
+	if err != nil {
+		sinks.Close()
+		if stream != nil {
+			stream.Close()
+		}
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(int(internal.ExitCodeCriticalFailure))
+	}
+
+	if stream != nil {
+		if err := stream.Finish(result.Config, result.Metrics); err != nil {
+			fmt.Println(err)
+		}
+	} else if err := internal.SaveReport(result.Config, result.Metrics); err != nil {
+		fmt.Printf("Ошибка сохранения отчета: %v\n", err)
+	}
+	if len(sinks) > 0 {
+		sinks.Publish(sinkSummaryEvent(result))
+		if err := sinks.Close(); err != nil {
+			fmt.Printf("Ошибка отправки результатов в стоки: %v\n", err)
+		}
+	}
+	if result.Upload != nil {
+		if code := result.ExitCode(); code != internal.ExitCodeSuccess {
+			os.Exit(int(code))
+		}
+		return
+	}
+
+	// Экспорт в Prometheus format
+	if cfg.ReportPath != "" {
+		// Создаем имя файла для Prometheus (заменяем расширение на .prom)
+		promFile := cfg.ReportPath
+		if len(promFile) > 4 && promFile[len(promFile)-5:] == ".json" {
+			promFile = promFile[:len(promFile)-5] + ".prom"
+		} else {
+			promFile = promFile + ".prom"
+		}
+
+		if err := internal.ExportPrometheusMetrics(result.Config, result.Metrics, promFile); err != nil {
+			fmt.Printf("Ошибка экспорта Prometheus метрик: %v\n", err)
+		} else {
+			fmt.Printf("Prometheus метрики сохранены: %s\n", promFile)
+		}
+	}
+
+	// Проверяем SLA если настроено
+	if result.SLA != nil {
+		internal.ExitWithSLA(result.Config, result.Metrics)
+	}
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/metrics"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// startDiscardServer запускает QUIC-сервер, читающий потоки в никуда, и
+// возвращает его адрес
+func startDiscardServer(t *testing.T) string {
+	t.Helper()
+	listener, err := quic.ListenAddr("127.0.0.1:0", internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					str, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go io.Copy(io.Discard, str)
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRunReturnsResult(t *testing.T) {
+	cfg := internal.TestConfig{
+		Addr:        startDiscardServer(t),
+		Connections: 1,
+		Streams:     1,
+		PacketSize:  100,
+		Rate:        50,
+		Duration:    2 * time.Second,
+		NoTLS:       true,
+		SlaErrors:   1000,
+	}
+	result, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if result.Success == 0 || result.Errors != 0 {
+		t.Errorf("Expected successful sends without errors, got %d sent, %d errors (%+v)", result.Success, result.Errors, result.TopErrors)
+	}
+	if result.Duration() < cfg.Duration {
+		t.Errorf("Expected the run to last at least %v, got %v", cfg.Duration, result.Duration())
+	}
+	if bytes, _ := result.Metrics["BytesSent"].(int); bytes < result.Success*cfg.PacketSize {
+		t.Errorf("Expected at least %d bytes in the metrics, got %v", result.Success*cfg.PacketSize, result.Metrics["BytesSent"])
+	}
+	if result.SLA == nil || !result.SLA.Passed || result.ExitCode() != internal.ExitCodeSuccess {
+		t.Errorf("Expected the SLA to pass, got %+v", result.SLA)
+	}
+}
+
+// TestPacketSizesExcludeFraming: распределение размеров сравнивается с
+// --packet-size, поэтому длина кадра проверяемого шаблона в него не входит
+func TestPacketSizesExcludeFraming(t *testing.T) {
+	result, err := Run(internal.TestConfig{
+		Addr:        startDiscardServer(t),
+		Connections: 1,
+		Streams:     1,
+		PacketSize:  200,
+		Pattern:     "zeroes",
+		Rate:        50,
+		Duration:    time.Second,
+		NoTLS:       true,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	sizes, ok := result.Metrics["PacketSizes"].(metrics.SizeSummary)
+	if !ok || sizes.Count == 0 {
+		t.Fatalf("Expected packet sizes in the metrics, got %v", result.Metrics["PacketSizes"])
+	}
+	if sizes.Min != 200 || sizes.Max != 200 {
+		t.Errorf("Expected every packet recorded at 200 bytes, got %d..%d", sizes.Min, sizes.Max)
+	}
+}
+
+// TestEmulatedPacketsAddUp: каждый пакет, прошедший решения эмуляции, с
+// дублями, либо отправлен, либо учтен потерянным — в том числе задержанный
+// перестановкой или оставшийся в очереди, когда тест кончился
+func TestEmulatedPacketsAddUp(t *testing.T) {
+	result, err := Run(internal.TestConfig{
+		Addr:           startDiscardServer(t),
+		Connections:    1,
+		Streams:        2,
+		PacketSize:     100,
+		Rate:           200,
+		Duration:       2 * time.Second, // ramp-up начинается с 1 pps
+		NoTLS:          true,
+		EmulateLoss:    0.1,
+		EmulateDup:     0.3,
+		EmulateReorder: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	emulation, ok := result.Metrics["Emulation"].(metrics.EmulationSummary)
+	if !ok || emulation.Packets == 0 {
+		t.Fatalf("Expected emulation stats in the metrics, got %v", result.Metrics["Emulation"])
+	}
+	// Запись, прерванная концом теста, учитывается ошибкой
+	if total, accounted := emulation.Packets+emulation.Dups, int64(result.Success+result.Errors)+emulation.Drops; total != accounted {
+		t.Errorf("Expected %d packets and dups to be sent or dropped, got %d sent, %d errors, %d dropped",
+			total, result.Success, result.Errors, emulation.Drops)
+	}
+}
+
+func TestRunReturnsAddressError(t *testing.T) {
+	if _, err := Run(internal.TestConfig{Addr: "no-port", Connections: 1}); err == nil {
+		t.Fatal("Expected an error for an address without a port")
+	}
+}
+
+// TestRunLiveStopsBackgroundWork: после возврата RunLive наблюдатель больше
+// не получает метрик, а порт --prometheus свободен для следующего теста
+func TestRunLiveStopsBackgroundWork(t *testing.T) {
+	probe, err := net.Listen("tcp", prometheusAddr)
+	if err != nil {
+		t.Skipf("Prometheus port is busy: %v", err)
+	}
+	probe.Close()
+
+	var returned, lateStats atomic.Bool
+	firstStats := make(chan struct{})
+	var once sync.Once
+	cfg := internal.TestConfig{
+		Addr:        startDiscardServer(t),
+		Connections: 1,
+		Streams:     1,
+		PacketSize:  100,
+		Rate:        50,
+		NoTLS:       true,
+		Prometheus:  true,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunLive(ctx, cfg, Live{Stats: func(LiveStats) {
+			if returned.Load() {
+				lateStats.Store(true)
+			}
+			once.Do(func() { close(firstStats) })
+		}})
+		returned.Store(true)
+		done <- err
+	}()
+
+	select {
+	case <-firstStats:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected live stats while the test runs")
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunLive: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected RunLive to return after the context was cancelled")
+	}
+
+	probe, err = net.Listen("tcp", prometheusAddr)
+	if err != nil {
+		t.Errorf("Expected the Prometheus port to be free after RunLive returned: %v", err)
+	} else {
+		probe.Close()
+	}
+	// Сбор метрик идет раз в секунду
+	time.Sleep(1500 * time.Millisecond)
+	if lateStats.Load() {
+		t.Error("Expected no live stats after RunLive returned")
+	}
+}
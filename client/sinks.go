@@ -0,0 +1,54 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/sink"
+)
+
+// openSinks открывает стоки --sink. Сток, который не удалось открыть,
+// пропускается с предупреждением: тест от него не зависит.
+func openSinks(specs []string) sink.Multi {
+	var sinks sink.Multi
+	for _, spec := range specs {
+		s, err := sink.Open(spec)
+		if err != nil {
+			fmt.Printf("⚠️  Сток результатов пропущен: %v\n", err)
+			continue
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks
+}
+
+// sinkMetricsEvent — периодические метрики для стоков, с теми же ключами,
+// что у метрик теста в GUI
+func sinkMetricsEvent(stats LiveStats) sink.Event {
+	return sink.Event{
+		Type: sink.EventMetrics,
+		Time: time.Now(),
+		Data: map[string]interface{}{
+			"elapsed_seconds":        stats.Elapsed.Seconds(),
+			"connections":            stats.Connections,
+			"connections_configured": stats.ConfiguredConnections,
+			"bytes_sent":             stats.BytesSent,
+			"packets_sent":           stats.Packets,
+			"errors":                 stats.Errors,
+			"latency_ms":             stats.LatencyMs,
+			"throughput_mbps":        stats.ThroughputMbps,
+			"packet_loss_ratio":      stats.PacketLoss,
+			"rate_pps":               stats.Rate,
+		},
+	}
+}
+
+// sinkSummaryEvent — итог прогона для стоков в схеме JSON-отчета
+func sinkSummaryEvent(result *RunResult) sink.Event {
+	return sink.Event{
+		Type: sink.EventSummary,
+		Time: result.EndTime,
+		Data: internal.CreateReportSchema(result.Config, result.Metrics),
+	}
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/metrics"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// runUpload передает содержимое cfg.UploadFile серверу по одному потоку QUIC
+// и проверяет контрольную сумму. Файл читается частями, поэтому его размер
+// не ограничен памятью. При несовпадении или ошибке код выхода результата —
+// ExitCodeCriticalFailure.
+func runUpload(ctx context.Context, cfg internal.TestConfig, serverAddr *net.UDPAddr) *RunResult {
+	start := time.Now()
+	result := uploadFile(ctx, cfg, serverAddr)
+
+	if result.Error != "" {
+		fmt.Printf("❌ Передача файла не удалась: %s\n", result.Error)
+	} else {
+		fmt.Printf("Передано %d байт за %.2f ms (%.2f Mbps)\n", result.Bytes, result.DurationMs, result.ThroughputMbps)
+		fmt.Printf("SHA-256: %s\n", result.Checksum)
+		if result.Verified {
+			fmt.Println("✅ Контрольная сумма подтверждена сервером")
+		} else {
+			fmt.Println("❌ Контрольная сумма на сервере не совпала")
+		}
+	}
+
+	run := &RunResult{
+		Config:    cfg,
+		StartTime: start,
+		EndTime:   time.Now(),
+		Metrics: map[string]interface{}{
+			"Success":   result.Verified,
+			"BytesSent": result.Bytes,
+			"Upload":    result,
+		},
+		Upload: &result,
+	}
+	if result.Verified {
+		run.Success = 1
+	} else {
+		run.Errors = 1
+	}
+	return run
+}
+
+// uploadFile открывает соединение с сервером и передает файл. Ошибки
+// возвращаются в поле Error результата.
+func uploadFile(ctx context.Context, cfg internal.TestConfig, serverAddr *net.UDPAddr) internal.UploadResult {
+	result := internal.UploadResult{Path: cfg.UploadFile}
+	fail := func(err error) internal.UploadResult {
+		result.Error = err.Error()
+		return result
+	}
+
+	f, err := os.Open(cfg.UploadFile)
+	if err != nil {
+		return fail(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fail(err)
+	}
+	if !info.Mode().IsRegular() {
+		return fail(fmt.Errorf("%s is not a regular file", cfg.UploadFile))
+	}
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	tlsConf, err := clientTLSConfig(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	localAddr, err := internal.ParseLocalAddr(cfg.LocalAddr)
+	if err != nil {
+		return fail(err)
+	}
+	udpConn, err := internal.ListenLocalUDP(localAddr)
+	if err != nil {
+		return fail(err)
+	}
+	defer udpConn.Close()
+	transport := &quic.Transport{Conn: udpConn}
+	defer transport.Close()
+
+	conn, err := dialQUIC(ctx, transport, serverAddr, tlsConf, internal.CreateClientQUICConfig(cfg), cfg.HandshakeTimeout)
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.CloseWithError(0, "upload done")
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	// Отмена контекста (таймаут или сигнал) прерывает передачу
+	stop := context.AfterFunc(ctx, func() {
+		stream.CancelRead(0)
+		stream.CancelWrite(0)
+	})
+	defer stop()
+
+	start := time.Now()
+	sum, verified, err := internal.SendUpload(stream, f, info.Size())
+	elapsed := time.Since(start)
+	if err != nil {
+		return fail(err)
+	}
+
+	result.Bytes = metrics.ByteCount(info.Size())
+	result.DurationMs = float64(elapsed.Nanoseconds()) / 1e6
+	if elapsed > 0 {
+		result.ThroughputMbps = float64(result.Bytes*8) / elapsed.Seconds() / 1e6
+	}
+	result.Checksum = hex.EncodeToString(sum[:])
+	result.Verified = verified
+	return result
+}
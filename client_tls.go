@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"quic-test/internal"
+)
+
+// clientTLSConfig builds the TLS config of the client-side modes from
+// --insecure, --no-tls and --ca-cert and warns when verification is off
+func clientTLSConfig(cfg internal.TestConfig, nextProtos []string) (*tls.Config, error) {
+	tlsConf, err := internal.ClientTLSConfig(cfg.SkipVerify(), cfg.CACertPath, nextProtos)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SkipVerify() {
+		fmt.Println(internal.InsecureSkipVerifyWarning)
+	}
+	return tlsConf, nil
+}
@@ -11,30 +11,82 @@ import (
 	"syscall"
 	"time"
 
+	"quic-test/internal"
 	"quic-test/internal/gui"
+	"quic-test/internal/metrics"
 )
 
 func main() {
 	var (
 		addr     = flag.String("addr", ":8080", "GUI server address")
-		apiAddr  = flag.String("api-addr", ":8081", "API server address")
+		apiAddr  = flag.String("api-addr", ":8081", "API server address (empty to disable TCP; empty by default with --api-socket)")
+		apiSock  = flag.String("api-socket", "", "Serve the API on a unix domain socket (path, or @name for abstract)")
 		certPath = flag.String("cert", "", "TLS certificate path (optional)")
 		keyPath  = flag.String("key", "", "TLS key path (optional)")
 		dev      = flag.Bool("dev", false, "Development mode (auto-reload)")
+		promURL  = flag.String("prometheus-url", "", "Prometheus exporter URL checked by /api/system/health (e.g. http://localhost:2112/metrics)")
+		byteStr  = flag.Bool("json-byte-strings", false, "Serve all byte counts as JSON strings; by default only counts above 2^53-1 are strings")
+		interval = flag.Duration("metrics-interval", time.Second, "Aggregation step of the per-test metrics history (min/max/avg per point)")
+		maxPts   = flag.Int("metrics-max-points", 3600, "Maximum history points per test; older points are down-sampled beyond it")
+		dataDir  = flag.String("data-dir", "", "Directory to persist test sessions in, so the history survives restarts (empty keeps them in memory)")
+		maxSess  = flag.Int("max-sessions", 100, "Maximum test sessions kept in memory with --data-dir; older ones stay on disk")
+		flushInt = flag.Duration("flush-interval", 10*time.Second, "With --data-dir, save running tests this often so a crash loses at most this much history (0 saves only on start, stop and end)")
+		resume   = flag.Bool("resume", false, "With --data-dir, resume the tests a restart interrupted instead of leaving them interrupted")
 	)
 	flag.Parse()
+	metrics.SetByteCountsAsStrings(*byteStr)
+	if *apiSock != "" && !flagSet("api-addr") {
+		// Serving on a socket usually means TCP is not wanted
+		*apiAddr = ""
+	}
 
 	fmt.Println("QUIC Test GUI Server")
 	fmt.Println("===================")
 	fmt.Printf("GUI Address: %s\n", *addr)
-	fmt.Printf("API Address: %s\n", *apiAddr)
+	if *apiAddr != "" {
+		fmt.Printf("API Address: %s\n", *apiAddr)
+	}
+	if *apiSock != "" {
+		fmt.Printf("API Socket: %s\n", *apiSock)
+	}
 	fmt.Printf("Development Mode: %v\n", *dev)
 
+	if *apiAddr == "" && *apiSock == "" {
+		log.Fatal("Either --api-addr or --api-socket must be set")
+	}
+
 	// Create GUI server
 	guiServer := gui.NewServer(*dev)
+	if *apiSock != "" {
+		guiServer.SetAPISocket(*apiSock)
+	} else {
+		guiServer.SetAPIAddr(*apiAddr)
+	}
 	
 	// Create API server
 	apiServer := gui.NewAPIServer()
+	apiServer.SetHealthSources(gui.HealthSources{
+		FEC:           apiServer.FECHealth,
+		Emulation:     apiServer.EmulationHealth,
+		PrometheusURL: *promURL,
+	})
+	apiServer.SetMetricsInterval(*interval, *maxPts)
+	if *dataDir != "" {
+		store, err := gui.NewFileSessionStore(*dataDir)
+		if err != nil {
+			log.Fatalf("Session store failed: %v", err)
+		}
+		if err := apiServer.SetSessionStore(store, *maxSess); err != nil {
+			log.Fatalf("Session store failed: %v", err)
+		}
+		apiServer.SetFlushInterval(*flushInt)
+		fmt.Printf("Data Directory: %s\n", *dataDir)
+		if *resume {
+			for _, id := range apiServer.ResumeInterrupted() {
+				fmt.Printf("Resumed interrupted test %s\n", id)
+			}
+		}
+	}
 
 	// Setup HTTP servers
 	guiMux := http.NewServeMux()
@@ -50,7 +102,7 @@ func main() {
 
 	apiHTTPServer := &http.Server{
 		Addr:    *apiAddr,
-		Handler: apiMux,
+		Handler: gui.CompressResponses(apiMux),
 	}
 
 	// Graceful shutdown
@@ -79,15 +131,31 @@ func main() {
 	}()
 
 	// Start servers
-	go func() {
-		fmt.Printf("Starting API server on %s\n", *apiAddr)
-		if err := apiHTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("API server failed: %v", err)
+	if *apiSock != "" {
+		// Shutdown closes the listener, which removes the socket file
+		listener, err := gui.ListenUnixSocket(*apiSock)
+		if err != nil {
+			log.Fatalf("API socket failed: %v", err)
 		}
-	}()
+		go func() {
+			fmt.Printf("Starting API server on unix socket %s\n", *apiSock)
+			if err := apiHTTPServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("API server failed: %v", err)
+			}
+		}()
+	}
+
+	if *apiAddr != "" {
+		go func() {
+			fmt.Printf("Starting API server on %s\n", *apiAddr)
+			if err := apiHTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("API server failed: %v", err)
+			}
+		}()
+	}
 
 	fmt.Printf("Starting GUI server on %s\n", *addr)
-	fmt.Printf("Open http://localhost%s in your browser\n", *addr)
+	fmt.Printf("Open %s in your browser\n", internal.LocalURL(*addr))
 	
 	var err error
 	if *certPath != "" && *keyPath != "" {
@@ -103,4 +171,15 @@ func main() {
 
 	<-ctx.Done()
 	fmt.Println("Servers stopped")
-}
\ No newline at end of file
+}
+
+// flagSet reports whether the named flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
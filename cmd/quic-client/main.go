@@ -24,12 +24,17 @@ func main() {
 	duration := flag.Duration("duration", 0, "Длительность теста (0 — до ручного завершения)")
 	packetSize := flag.Int("packet-size", 1200, "Размер пакета (байт)")
 	rate := flag.Int("rate", 100, "Частота отправки пакетов (в секунду)")
+	maxConnsPerSecond := flag.Float64("max-connections-per-second", 0, "Открывать не больше стольких новых соединений в секунду (0 — все сразу)")
 	reportPath := flag.String("report", "", "Путь к файлу для отчета (опционально)")
-	reportFormat := flag.String("report-format", "md", "Формат отчета: csv | md | json")
+	reportFormat := flag.String("report-format", "md", "Формат отчета: csv | md | json | jsonl (строка метрик каждую секунду во время теста)")
+	rawLatencyOut := flag.String("raw-latency-out", "", "CSV-файл, куда пишется каждый замер RTT с временем")
 	certPath := flag.String("cert", "", "Путь к TLS-сертификату (опционально)")
 	keyPath := flag.String("key", "", "Путь к TLS-ключу (опционально)")
 	pattern := flag.String("pattern", "random", "Шаблон данных: random | zeroes | increment")
-	noTLS := flag.Bool("no-tls", false, "Отключить TLS (для тестов)")
+	echo := flag.Bool("echo", false, "Измерять RTT по подтверждениям сервера (сервер тоже запускается с --echo)")
+	noTLS := flag.Bool("no-tls", false, "Отключить TLS (для тестов); сертификат сервера не проверяется")
+	insecure := flag.Bool("insecure", false, "Не проверять сертификат сервера (только для тестов с недоверенным сервером)")
+	caCert := flag.String("ca-cert", "", "PEM-файл CA, подписавшего сертификат сервера; доверяется вместо системных")
 	prometheus := flag.Bool("prometheus", false, "Экспортировать метрики Prometheus на /metrics")
 	emulateLoss := flag.Float64("emulate-loss", 0, "Вероятность потери пакета (0..1)")
 	emulateLatency := flag.Duration("emulate-latency", 0, "Дополнительная задержка перед отправкой пакета")
@@ -46,26 +51,39 @@ func main() {
 	}
 
 	cfg := internal.TestConfig{
-		Mode:           "client",
-		Addr:           *addr,
-		Streams:        *streams,
-		Connections:    *connections,
-		Duration:       *duration,
-		PacketSize:     *packetSize,
-		Rate:           *rate,
-		ReportPath:     *reportPath,
-		ReportFormat:   *reportFormat,
-		CertPath:       *certPath,
-		KeyPath:        *keyPath,
-		Pattern:        *pattern,
-		NoTLS:          *noTLS,
-		Prometheus:     *prometheus,
-		EmulateLoss:    *emulateLoss,
-		EmulateLatency: *emulateLatency,
-		EmulateDup:     *emulateDup,
-		PprofAddr:      *pprofAddr,
-		SlaRttP95:      *slaRttP95,
-		SlaLoss:        *slaLoss,
+		Mode:                    "client",
+		Addr:                    *addr,
+		Streams:                 *streams,
+		Connections:             *connections,
+		MaxConnectionsPerSecond: *maxConnsPerSecond,
+		Duration:                *duration,
+		PacketSize:              *packetSize,
+		Rate:                    *rate,
+		ReportPath:              *reportPath,
+		ReportFormat:            *reportFormat,
+		RawLatencyOut:           *rawLatencyOut,
+		CertPath:                *certPath,
+		KeyPath:                 *keyPath,
+		Pattern:                 *pattern,
+		Echo:                    *echo,
+		NoTLS:                   *noTLS,
+		InsecureSkipVerify:      *insecure,
+		CACertPath:              *caCert,
+		Prometheus:              *prometheus,
+		EmulateLoss:             *emulateLoss,
+		EmulateLatency:          *emulateLatency,
+		EmulateDup:              *emulateDup,
+		PprofAddr:               *pprofAddr,
+		SlaRttP95:               *slaRttP95,
+		SlaLoss:                 *slaLoss,
+	}
+	if cfg.InsecureSkipVerify && cfg.CACertPath != "" {
+		fmt.Println("Ошибка валидации: --insecure и --ca-cert взаимоисключающие")
+		os.Exit(1)
+	}
+	if cfg.MaxConnectionsPerSecond < 0 {
+		fmt.Println("Ошибка валидации: --max-connections-per-second не может быть отрицательным")
+		os.Exit(1)
 	}
 
 	fmt.Printf("Подключение к %s с %d соединениями, %d потоков на соединение\n",
@@ -88,7 +106,7 @@ func main() {
 	_ = ctx // Используем контекст для graceful shutdown
 
 	// Запуск клиента
-	client.Run(cfg)
+	client.RunAndReport(cfg)
 }
 
 // validateFlags проверяет корректность комбинаций флагов
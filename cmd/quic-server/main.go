@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"quic-test/internal"
@@ -20,9 +22,16 @@ func main() {
 	addr := flag.String("addr", ":9000", "Адрес для прослушивания")
 	certPath := flag.String("cert", "", "Путь к TLS-сертификату (опционально)")
 	keyPath := flag.String("key", "", "Путь к TLS-ключу (опционально)")
+	certHosts := flag.String("cert-hosts", strings.Join(internal.DefaultCertHosts, ","), "DNS-имена и IP самоподписанного сертификата через запятую (без --cert/--key)")
+	certValidity := flag.Duration("cert-validity", internal.DefaultCertValidity, "Срок самоподписанного сертификата")
 	noTLS := flag.Bool("no-tls", false, "Отключить TLS (для тестов)")
 	prometheus := flag.Bool("prometheus", false, "Экспортировать метрики Prometheus на /metrics")
 	pprofAddr := flag.String("pprof-addr", "", "Адрес для pprof (например, :6060)")
+	echo := flag.Bool("echo", false, "Подтверждать пакеты клиентов с --echo, чтобы они измеряли RTT")
+	acceptBacklog := flag.Int("accept-backlog", internal.DefaultAcceptBacklog, "Соединения, ждущие обработчика; лишние закрываются")
+	streamReadTimeout := flag.Duration("stream-read-timeout", 0, "Сбрасывать потоки без входящих данных дольше этого (0 — без ограничения)")
+	streamWriteTimeout := flag.Duration("stream-write-timeout", 0, "Сбрасывать потоки, ответ в которые не записан за это время (0 — без ограничения)")
+	maxActive := flag.Int("max-active-connections", 0, "Одновременно обслуживаемые соединения, остальные ждут в очереди (0 — без ограничения)")
 	flag.Parse()
 
 	// Валидация флагов
@@ -32,13 +41,21 @@ func main() {
 	}
 
 	cfg := internal.TestConfig{
-		Mode:       "server",
-		Addr:       *addr,
-		CertPath:   *certPath,
-		KeyPath:    *keyPath,
-		NoTLS:      *noTLS,
-		Prometheus: *prometheus,
-		PprofAddr:  *pprofAddr,
+		Mode:         "server",
+		Addr:         *addr,
+		CertPath:     *certPath,
+		KeyPath:      *keyPath,
+		CertHosts:    internal.SplitCertHosts(*certHosts),
+		CertValidity: *certValidity,
+		NoTLS:        *noTLS,
+		Prometheus:   *prometheus,
+		PprofAddr:    *pprofAddr,
+		Echo:         *echo,
+
+		AcceptBacklog:        *acceptBacklog,
+		MaxActiveConnections: *maxActive,
+		StreamReadTimeout:    *streamReadTimeout,
+		StreamWriteTimeout:   *streamWriteTimeout,
 	}
 
 	fmt.Printf("Запуск QUIC сервера на %s\n", cfg.Addr)
@@ -49,18 +66,24 @@ func main() {
 		fmt.Printf("pprof будет доступен на %s/debug/pprof\n", cfg.PprofAddr)
 	}
 
-	// Обработка сигналов для graceful shutdown
+	// Обработка сигналов для graceful shutdown: сервер закрывает соединения
+	// и возвращается из Run после отмены контекста
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		<-sigs
 		fmt.Println("\nПолучен сигнал завершения, остановка сервера...")
-		os.Exit(0)
+		cancel()
 	}()
 
 	// Запуск сервера
-	server.Run(cfg)
+	if err := server.Run(ctx, cfg); err != nil {
+		fmt.Printf("Ошибка запуска сервера: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 // validateFlags проверяет корректность комбинаций флагов
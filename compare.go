@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"quic-test/internal"
+	matrix "quic-test/internal/testing"
+)
+
+// defaultCompareDuration is used per run when no --duration is given
+const defaultCompareDuration = 10 * time.Second
+
+// defaultTopologyStreams is the total stream count of a topology comparison
+// when --connections × --streams gives fewer than two streams
+const defaultTopologyStreams = 8
+
+// comparison holds what every comparison run needs: the executable, a
+// scratch directory for the per-run reports and a signal-aware context
+type comparison struct {
+	cfg       internal.TestConfig
+	binary    string
+	outputDir string
+	ctx       context.Context
+	stop      context.CancelFunc
+}
+
+// newComparison prepares a comparison. Every run gets the same emulation
+// seed so that the runs face an identical loss/dup pattern.
+func newComparison(cfg internal.TestConfig, name string) (*comparison, error) {
+	if cfg.EmulationSeed == 0 {
+		cfg.EmulationSeed = time.Now().UnixNano()
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = defaultCompareDuration
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot locate executable: %w", err)
+	}
+
+	outputDir, err := os.MkdirTemp("", "quic-test-"+name+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	return &comparison{cfg: cfg, binary: binary, outputDir: outputDir, ctx: ctx, stop: stop}, nil
+}
+
+// Close stops signal handling and removes the per-run reports
+func (c *comparison) Close() {
+	c.stop()
+	os.RemoveAll(c.outputDir)
+}
+
+// saveSummary writes the JSON summary to --report (with --report-format json)
+// or to defaultPath
+func (c *comparison) saveSummary(save func(string) error, defaultPath string) int {
+	jsonPath := defaultPath
+	if c.cfg.ReportPath != "" && c.cfg.ReportFormat == "json" {
+		jsonPath = c.cfg.ReportPath
+	}
+	if err := save(jsonPath); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("JSON summary saved: %s\n", jsonPath)
+	return 0
+}
+
+// runCompareCC runs the configured scenario once per congestion control
+// algorithm and prints a ranked summary. conditions names the network
+// profile or scenario the runs are emulating, for the report.
+func runCompareCC(cfg internal.TestConfig, list, conditions string) int {
+	algorithms := matrix.ParseCCList(list)
+	if len(algorithms) == 0 {
+		fmt.Println("❌ Error: --compare-cc requires at least one algorithm")
+		return 1
+	}
+
+	c, err := newComparison(cfg, "cc-compare")
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	defer c.Close()
+
+	fmt.Printf("Comparing congestion control: %v (duration %v each, seed %d)\n", algorithms, c.cfg.Duration, c.cfg.EmulationSeed)
+
+	run := matrix.NewSubprocessCCRun(c.binary, compareRunArgs(c.cfg), c.outputDir, false)
+	summary := matrix.RunCCComparison(c.ctx, algorithms, c.cfg.EmulationSeed, run)
+	summary.Conditions = emulationConditions(c.cfg, conditions)
+	summary.PrintTable(os.Stdout)
+
+	markdownPath := "cc-compare.md"
+	if c.cfg.ReportPath != "" && c.cfg.ReportFormat == "md" {
+		markdownPath = c.cfg.ReportPath
+	}
+	if err := summary.SaveMarkdown(markdownPath); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Markdown table saved: %s\n", markdownPath)
+	return c.saveSummary(summary.SaveJSON, "cc-compare.json")
+}
+
+// emulationConditions describes the emulation every run gets, after the
+// network profile or scenario it comes from when one was given
+func emulationConditions(cfg internal.TestConfig, source string) string {
+	emulation := fmt.Sprintf("latency %v, jitter %v, loss %.2f%%, duplication %.2f%%, reordering %.2f%%",
+		cfg.EmulateLatency, cfg.EmulateJitter, cfg.EmulateLoss*100, cfg.EmulateDup*100, cfg.EmulateReorder*100)
+	if source == "" {
+		return emulation
+	}
+	return source + " (" + emulation + ")"
+}
+
+// runCompareTopology runs the configured scenario over a single reused
+// connection and over one connection per stream, with the same total
+// number of streams, and prints throughput, RTT, handshake overhead and
+// resource usage of each topology
+func runCompareTopology(cfg internal.TestConfig) int {
+	totalStreams := cfg.Connections * cfg.Streams
+	if totalStreams < 2 {
+		totalStreams = defaultTopologyStreams
+	}
+
+	c, err := newComparison(cfg, "topology-compare")
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	defer c.Close()
+
+	fmt.Printf("Comparing connection reuse with new connections: %d streams (duration %v each, seed %d)\n",
+		totalStreams, c.cfg.Duration, c.cfg.EmulationSeed)
+
+	run := matrix.NewSubprocessTopologyRun(c.binary, compareRunArgs(c.cfg), c.outputDir, false)
+	summary := matrix.RunTopologyComparison(c.ctx, matrix.TopologyPresets(totalStreams), c.cfg.EmulationSeed, run)
+	summary.PrintTable(os.Stdout)
+	return c.saveSummary(summary.SaveJSON, "topology-compare.json")
+}
+
+// runCompareProfiles runs the configured scenario once per network profile,
+// with the profile's emulation on top of the same load, and prints how
+// goodput, RTT and loss change from profile to profile
+func runCompareProfiles(cfg internal.TestConfig, list string) int {
+	profiles := matrix.ParseProfileList(list)
+	if len(profiles) == 0 {
+		fmt.Println("❌ Error: --compare-profiles requires at least one profile")
+		return 1
+	}
+
+	c, err := newComparison(cfg, "profile-compare")
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return 1
+	}
+	defer c.Close()
+
+	fmt.Printf("Comparing network profiles: %v (duration %v each, seed %d)\n", profiles, c.cfg.Duration, c.cfg.EmulationSeed)
+
+	run := matrix.NewSubprocessProfileRun(c.binary, compareRunArgs(c.cfg), c.outputDir, false)
+	summary := matrix.RunProfileComparison(c.ctx, profiles, c.cfg.EmulationSeed, run)
+	summary.PrintTable(os.Stdout)
+	return c.saveSummary(summary.SaveJSON, "profile-compare.json")
+}
+
+// compareRunArgs builds the flags for a single comparison run in test mode
+func compareRunArgs(cfg internal.TestConfig) []string {
+	args := []string{
+		"--mode", "test",
+		"--addr", cfg.Addr,
+		"--connections", strconv.Itoa(cfg.Connections),
+		"--streams", strconv.Itoa(cfg.Streams),
+		"--duration", cfg.Duration.String(),
+		"--packet-size", cfg.PacketSizeDistribution().String(),
+		"--rate", strconv.Itoa(cfg.Rate),
+		"--pattern", cfg.Pattern,
+		"--emulate-loss", strconv.FormatFloat(cfg.EmulateLoss, 'f', -1, 64),
+		"--emulate-latency", cfg.EmulateLatency.String(),
+		"--emulate-dup", strconv.FormatFloat(cfg.EmulateDup, 'f', -1, 64),
+		"--emulate-reorder", strconv.FormatFloat(cfg.EmulateReorder, 'f', -1, 64),
+		"--emulate-jitter", cfg.EmulateJitter.String(),
+		"--emulation-seed", strconv.FormatInt(cfg.EmulationSeed, 10),
+	}
+	if cfg.NoTLS {
+		args = append(args, "--no-tls")
+	}
+	if cfg.InsecureSkipVerify {
+		args = append(args, "--insecure")
+	}
+	return args
+}
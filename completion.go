@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"quic-test/internal"
+)
+
+// completionShells are the shells --completion can generate a script for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionFileFlags take a file path as their value
+var completionFileFlags = map[string]bool{
+	"cert": true, "key": true, "report": true, "pcap": true,
+	"upload-file": true, "output-file": true, "scenario-out": true,
+}
+
+// completionValues returns the fixed value sets of flags. Scenario and
+// profile names come from the registries, so new entries are completed
+// without touching this list.
+func completionValues() map[string][]string {
+	return map[string][]string{
+		"mode":            {"server", "client", "test", "http3-load", "http3-server", "masque-server", "masque-client", "stun", "observe", "record"},
+		"scenario":        internal.ListScenarios(),
+		"network-profile": internal.ListNetworkProfiles(),
+		"cc":              internal.ListCongestionControls(),
+		"pqc-algorithm":   {"ml-kem-512", "ml-kem-768", "dilithium-2", "hybrid", "baseline"},
+		"report-format":   {"csv", "md", "json", "jsonl"},
+		"pattern":         {"random", "zeroes", "increment"},
+		"log-format":      {internal.LogFormatText, internal.LogFormatJSON},
+		"request-pattern": {"sequential", "parallel", "burst"},
+		"protocol":        {"h3", "h2"},
+		"completion":      completionShells,
+	}
+}
+
+// completionFlag is a flag as seen by the completion scripts
+type completionFlag struct {
+	name      string
+	usage     string
+	takesArg  bool
+	values    []string
+	filenames bool
+}
+
+// completionFlags lists the flags of fs sorted by name
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	values := completionValues()
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		takesArg := true
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			takesArg = false
+		}
+		flags = append(flags, completionFlag{
+			name:      f.Name,
+			usage:     strings.Join(strings.Fields(f.Usage), " "),
+			takesArg:  takesArg,
+			values:    values[f.Name],
+			filenames: completionFileFlags[f.Name],
+		})
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].name < flags[j].name })
+	return flags
+}
+
+// writeCompletion writes the completion script of program for shell
+func writeCompletion(w io.Writer, shell, program string, fs *flag.FlagSet) error {
+	flags := completionFlags(fs)
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, program, flags)
+	case "zsh":
+		writeZshCompletion(w, program, flags)
+	case "fish":
+		writeFishCompletion(w, program, flags)
+	default:
+		return fmt.Errorf("unsupported shell %q (%s)", shell, strings.Join(completionShells, " | "))
+	}
+	return nil
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// completionFunc returns the name of the shell function completing program
+func completionFunc(program string) string {
+	return "_" + nonIdentifier.ReplaceAllString(program, "_")
+}
+
+func writeBashCompletion(w io.Writer, program string, flags []completionFlag) {
+	fn := completionFunc(program)
+	fmt.Fprintf(w, "# bash completion for %s\n", program)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `    case "$prev" in`)
+	var freeForm []string
+	for _, f := range flags {
+		switch {
+		case len(f.values) > 0:
+			fmt.Fprintf(w, "        -%[1]s|--%[1]s)\n", f.name)
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(f.values, " "))
+			fmt.Fprintln(w, "            return ;;")
+		case f.filenames:
+			fmt.Fprintf(w, "        -%[1]s|--%[1]s)\n", f.name)
+			fmt.Fprintln(w, `            COMPREPLY=($(compgen -f -- "$cur"))`)
+			fmt.Fprintln(w, "            return ;;")
+		case f.takesArg:
+			freeForm = append(freeForm, "-"+f.name, "--"+f.name)
+		}
+	}
+	if len(freeForm) > 0 {
+		// Free-form values (addresses, durations, numbers) have no completion
+		fmt.Fprintf(w, "        %s)\n", strings.Join(freeForm, "|"))
+		fmt.Fprintln(w, "            return ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "--" + f.name
+	}
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintln(w, "}")
+	fmt.Fprintf(w, "complete -F %s %s\n", fn, program)
+}
+
+// zshEscaper escapes a flag description for an _arguments spec in single quotes
+var zshEscaper = strings.NewReplacer(`'`, `'\''`, `\`, `\\`, `[`, `\[`, `]`, `\]`, `:`, `\:`)
+
+func writeZshCompletion(w io.Writer, program string, flags []completionFlag) {
+	fn := completionFunc(program)
+	fmt.Fprintf(w, "#compdef %s\n\n", program)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprint(w, "    _arguments")
+	for _, f := range flags {
+		spec := fmt.Sprintf("--%s[%s]", f.name, zshEscaper.Replace(f.usage))
+		switch {
+		case len(f.values) > 0:
+			spec += fmt.Sprintf(":%s:(%s)", f.name, strings.Join(f.values, " "))
+		case f.filenames:
+			spec += ":file:_files"
+		case f.takesArg:
+			spec += ":" + f.name + ": "
+		}
+		fmt.Fprintf(w, " \\\n        '%s'", spec)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	// Works both from $fpath and when sourced directly
+	fmt.Fprintf(w, "if [ \"$funcstack[1]\" = \"%s\" ]; then\n", fn)
+	fmt.Fprintf(w, "    %s \"$@\"\n", fn)
+	fmt.Fprintln(w, "else")
+	fmt.Fprintf(w, "    compdef %s %s\n", fn, program)
+	fmt.Fprintln(w, "fi")
+}
+
+// fishEscaper escapes a string for single quotes in fish
+var fishEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func writeFishCompletion(w io.Writer, program string, flags []completionFlag) {
+	fmt.Fprintf(w, "# fish completion for %s\n", program)
+	fmt.Fprintf(w, "complete -c %s -f\n", program)
+	for _, f := range flags {
+		line := fmt.Sprintf("complete -c %s -l %s -d '%s'", program, f.name, fishEscaper.Replace(f.usage))
+		switch {
+		case len(f.values) > 0:
+			line += fmt.Sprintf(" -x -a '%s'", fishEscaper.Replace(strings.Join(f.values, " ")))
+		case f.filenames:
+			line += " -r -F"
+		case f.takesArg:
+			line += " -x"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
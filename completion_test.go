@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"quic-test/internal"
+)
+
+func testCompletionFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("quic-test", flag.ContinueOnError)
+	fs.String("mode", "test", "Mode: server | client | test")
+	fs.String("addr", ":9000", "Address for connection or listening")
+	fs.String("scenario", "", "Predefined scenario")
+	fs.String("network-profile", "", "Network profile")
+	fs.String("cert", "", "Path to TLS certificate [optional]")
+	fs.Bool("no-tls", false, "Disable TLS (for 'testing')")
+	return fs
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		var buf bytes.Buffer
+		if err := writeCompletion(&buf, shell, "quic-test", testCompletionFlags()); err != nil {
+			t.Fatalf("%s: writeCompletion failed: %v", shell, err)
+		}
+		script := buf.String()
+		if script == "" {
+			t.Fatalf("%s: empty completion script", shell)
+		}
+
+		// Flag names, dynamic scenario/profile names and fixed value sets
+		want := []string{"mode", "addr", "no-tls", "cert", "http3-load"}
+		want = append(want, internal.ListScenarios()...)
+		want = append(want, internal.ListNetworkProfiles()...)
+		for _, s := range want {
+			if !strings.Contains(script, s) {
+				t.Errorf("%s: expected the script to mention %q", shell, s)
+			}
+		}
+		if !strings.Contains(script, "quic-test") {
+			t.Errorf("%s: expected the script to register the program name", shell)
+		}
+	}
+}
+
+func TestWriteCompletionEscapesDescriptions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCompletion(&buf, "zsh", "quic-test", testCompletionFlags()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `'--cert[Path to TLS certificate \[optional\]]:file:_files'`) {
+		t.Errorf("Expected an escaped file spec for --cert, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeCompletion(&buf, "fish", "quic-test", testCompletionFlags()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `-l no-tls -d 'Disable TLS (for \'testing\')'`) {
+		t.Errorf("Expected an escaped description for --no-tls, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteCompletionUnknownShell(t *testing.T) {
+	if err := writeCompletion(&bytes.Buffer{}, "tcsh", "quic-test", testCompletionFlags()); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"quic-test/internal"
+)
+
+// runEstimate prints the projected load and resource footprint of the
+// configured test without running it. It returns a non-zero exit code when
+// the plan is obviously infeasible.
+func runEstimate(cfg internal.TestConfig) int {
+	e := internal.EstimatePlan(cfg, internal.ReadSystemLimits())
+
+	fmt.Println("Test plan estimate:")
+	fmt.Printf("  Connections x streams:  %d x %d = %d streams\n", e.Connections, cfg.Streams, e.Streams)
+	fmt.Printf("  Peak rate:              %d pps per stream, %.0f pps total\n", e.StreamRate, e.PacketsPerSecond)
+	fmt.Printf("  Packet size:            %s (mean %.0f bytes)\n", cfg.PacketSizeDistribution(), e.MeanPacketSize)
+	fmt.Printf("  Peak bandwidth:         %.2f Mbit/s\n", e.PeakBandwidthBits/1e6)
+	if e.Duration > 0 {
+		fmt.Printf("  Duration:               %s\n", e.Duration)
+		fmt.Printf("  Total (upper bound):    %d packets, %s\n", e.TotalPackets, formatEstimateBytes(e.TotalBytes))
+	} else {
+		fmt.Println("  Duration:               until stopped")
+	}
+	fmt.Printf("  Goroutines:             ~%d\n", e.Goroutines)
+	fmt.Printf("  Memory:                 ~%s\n", formatEstimateBytes(e.MemoryBytes))
+	fmt.Printf("  Open files:             ~%d\n", e.OpenFiles)
+
+	for _, warning := range e.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	if !e.Feasible() {
+		for _, problem := range e.Problems {
+			fmt.Printf("❌ %s\n", problem)
+		}
+		fmt.Println("❌ Plan is not feasible")
+		return 1
+	}
+	fmt.Println("✅ Plan looks feasible")
+	return 0
+}
+
+// formatEstimateBytes formats a byte count in binary units
+func formatEstimateBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/http3"
+)
+
+// http3LoadOptions holds the flags specific to --mode http3-load
+type http3LoadOptions struct {
+	URL             string
+	Method          string
+	BodySize        int
+	Requests        int
+	RequestPattern  string
+	Protocol        string
+	ThinkTime       time.Duration
+	RampStep        int
+	RampInterval    time.Duration
+	Resumption      bool
+	SharedTransport bool
+	PreWarm         bool
+	MaxSamples      int
+	Reconnect       bool
+	SetupBudget     float64
+	Headers         headerFlags
+	BearerToken     string
+	BasicAuth       string // user:password
+	LoginURL        string
+	LoginBody       string
+	TokenField      string
+	SLAErrorRate    float64
+	SLAMinRPS       float64
+}
+
+// headerFlags collects repeated --header "Name: value" flags
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	if h == nil {
+		return ""
+	}
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if name, _, ok := strings.Cut(value, ":"); !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header %q: expected \"Name: value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// headers returns the collected headers as a map
+func (h headerFlags) headers() map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(h))
+	for _, header := range h {
+		name, value, _ := strings.Cut(header, ":")
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// authConfig builds the load tester authentication from the --auth-* flags
+func (opts http3LoadOptions) authConfig() (*http3.AuthConfig, error) {
+	if opts.BearerToken == "" && opts.BasicAuth == "" && opts.LoginURL == "" {
+		return nil, nil
+	}
+	auth := &http3.AuthConfig{BearerToken: opts.BearerToken}
+	if opts.BasicAuth != "" {
+		user, password, ok := strings.Cut(opts.BasicAuth, ":")
+		if !ok {
+			return nil, fmt.Errorf("--auth-basic must be user:password")
+		}
+		auth.BasicUser, auth.BasicPassword = user, password
+	}
+	if opts.LoginURL != "" {
+		auth.Login = &http3.LoginConfig{
+			URL:        opts.LoginURL,
+			Body:       opts.LoginBody,
+			TokenField: opts.TokenField,
+		}
+	}
+	return auth, nil
+}
+
+// runHTTP3Load runs the HTTP/3 load tester against the configured URL(s),
+// writes a report and returns the process exit code
+func runHTTP3Load(cfg internal.TestConfig, opts http3LoadOptions) int {
+	var targets []string
+	for _, target := range strings.Split(opts.URL, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Println("❌ Error: --url is required for http3-load mode")
+		return int(internal.ExitCodeCriticalFailure)
+	}
+
+	if opts.SetupBudget < 0 || opts.SetupBudget > 1 {
+		fmt.Println("❌ Error: --establishment-budget must be between 0 and 1")
+		return int(internal.ExitCodeCriticalFailure)
+	}
+
+	auth, err := opts.authConfig()
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	// ALPN is set per protocol by the transports
+	tlsConf, err := clientTLSConfig(cfg, nil)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+
+	// Without --duration the run ends when all requests are done or on Ctrl+C
+	duration := cfg.Duration
+	if duration <= 0 {
+		duration = 24 * time.Hour
+	}
+
+	loadConfig := &http3.LoadTestConfig{
+		TargetURL:                targets[0],
+		Duration:                 duration,
+		ConcurrentConnections:    cfg.Connections,
+		RequestsPerConnection:    opts.Requests,
+		RequestPattern:           opts.RequestPattern,
+		Method:                   opts.Method,
+		BodySize:                 opts.BodySize,
+		ThinkTime:                opts.ThinkTime,
+		Protocol:                 opts.Protocol,
+		RampStep:                 opts.RampStep,
+		RampInterval:             opts.RampInterval,
+		SessionResumption:        opts.Resumption,
+		SharedTransport:          opts.SharedTransport,
+		PreWarm:                  opts.PreWarm,
+		MaxSamples:               opts.MaxSamples,
+		ReconnectOnClose:         opts.Reconnect,
+		EstablishmentErrorBudget: opts.SetupBudget,
+		Headers:                  opts.Headers.headers(),
+		Auth:                     auth,
+		TLSConfig:                tlsConf,
+		SLA: http3.LoadTestSLA{
+			MaxP95ResponseTime:   cfg.SlaRttP95,
+			MaxErrorRate:         opts.SLAErrorRate,
+			MinRequestsPerSecond: opts.SLAMinRPS,
+		},
+	}
+	if len(targets) > 1 {
+		loadConfig.Targets = targets
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Running %s load test against %s (%d connections x %d requests, %s)\n",
+		loadConfig.Protocol, strings.Join(targets, ", "), loadConfig.ConcurrentConnections,
+		loadConfig.RequestsPerConnection, loadConfig.RequestPattern)
+
+	tester := http3.NewLoadTester(loadConfig)
+	defer tester.Close()
+
+	if err := tester.Start(ctx); err != nil {
+		fmt.Printf("❌ Load test failed: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+
+	results := tester.GetResults()
+	fmt.Printf("\nRequests: %d total, %d ok, %d failed (%.2f%% errors)\n",
+		results.TotalRequests, results.SuccessfulRequests, results.FailedRequests, results.ErrorRate*100)
+	fmt.Printf("Throughput: %.2f req/s, latency avg %.2f ms, p95 %.2f ms, p99 %.2f ms\n",
+		results.RequestsPerSecond, results.AvgResponseTime, results.P95ResponseTime, results.P99ResponseTime)
+	fmt.Printf("Latency min %.2f ms, max %.2f ms, stddev %.2f ms\n",
+		results.MinResponseTime, results.MaxResponseTime, results.StdDevResponseTime)
+	fmt.Printf("Time to first byte: avg %.2f ms, p50 %.2f ms, p95 %.2f ms, p99 %.2f ms\n",
+		results.AvgTTFB, results.P50TTFB, results.P95TTFB, results.P99TTFB)
+	if results.SampledPercentiles {
+		fmt.Printf("Percentiles estimated from a sample of %d response times (--max-samples)\n", opts.MaxSamples)
+	}
+	if results.SetupFailures > 0 {
+		fmt.Printf("Connection setups: %d failed of %d\n", results.SetupFailures, results.SetupAttempts)
+	}
+	if results.EstablishmentAborted {
+		fmt.Printf("⚠️  Aborted: connection setup failures exceeded %.0f%%; max sustainable connections: %d\n",
+			opts.SetupBudget*100, results.MaxSustainableConnections)
+	}
+	if results.TokenRefreshes > 0 {
+		fmt.Printf("Auth: token refreshed %d times after 401 responses\n", results.TokenRefreshes)
+	}
+	if w := results.Warmup; w != nil {
+		fmt.Printf("Warm-up: %d connections in %.2f ms (handshake avg %.2f ms, max %.2f ms), %d of %d requests failed\n",
+			w.Connections, w.Duration, w.AvgHandshakeTime, w.MaxHandshakeTime, w.Failures, w.Requests)
+	}
+	if cm := results.ConnectionMetrics; cm != nil {
+		fmt.Printf("Connections: %d opened, %d requests reused an open connection\n",
+			cm.ConnectionsCreated, cm.ConnectionsReused)
+		fmt.Printf("Connection setup: avg %.2f ms (DNS %.2f ms, TLS handshake %.2f ms)\n",
+			cm.AvgConnectionTime, cm.DNSLookupTime, cm.TLSHandshakeTime)
+		fmt.Printf("Handshakes: %d full (avg %.2f ms), %d resumed (avg %.2f ms)\n",
+			cm.FullHandshakes, cm.AvgFullHandshakeTime, cm.ResumedHandshakes, cm.AvgResumedHandshakeTime)
+		if cm.GoAways > 0 || cm.ServerCloses > 0 {
+			fmt.Printf("Server shutdowns: %d GOAWAY, %d other closes, %d reconnects\n",
+				cm.GoAways, cm.ServerCloses, cm.Reconnects)
+		}
+	}
+
+	if err := http3.SaveReport(results, cfg.ReportPath, cfg.ReportFormat); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+	}
+
+	// An aborted run keeps its status in the report but still fails its SLA
+	if tester.SLAFailed() || len(results.SLAViolations) > 0 {
+		fmt.Println("\n❌ SLA checks failed:")
+		for _, v := range results.SLAViolations {
+			fmt.Printf("  - %s\n", v)
+		}
+		return int(internal.ExitCodeSLAFailure)
+	}
+	return int(internal.ExitCodeSuccess)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestRunHTTP3Load(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	// httptest provides a self-signed certificate for the HTTP/3 listener
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	h3Server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(tlsServer.TLS.Clone()),
+	}
+	go h3Server.Serve(udpConn)
+	defer func() {
+		h3Server.Close()
+		udpConn.Close()
+	}()
+
+	// The load tester verifies certificates, so trust httptest's through --ca-cert
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "load.json")
+	cfg := internal.TestConfig{
+		Connections:  2,
+		Duration:     30 * time.Second,
+		ReportPath:   reportPath,
+		ReportFormat: "json",
+		CACertPath:   caPath,
+	}
+	opts := http3LoadOptions{
+		URL:            fmt.Sprintf("https://%s/", udpConn.LocalAddr()),
+		Method:         "GET",
+		Requests:       5,
+		RequestPattern: "sequential",
+		Protocol:       "h3",
+	}
+
+	if code := runHTTP3Load(cfg, opts); code != int(internal.ExitCodeSuccess) {
+		t.Fatalf("Expected exit code %d, got %d", internal.ExitCodeSuccess, code)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Report was not written: %v", err)
+	}
+	var report struct {
+		TotalRequests      int64  `json:"total_requests"`
+		SuccessfulRequests int64  `json:"successful_requests"`
+		Protocol           string `json:"protocol"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if report.TotalRequests != 10 || report.SuccessfulRequests != 10 {
+		t.Errorf("Expected 10 successful requests, got %d total / %d ok", report.TotalRequests, report.SuccessfulRequests)
+	}
+	if report.Protocol != "h3" {
+		t.Errorf("Expected protocol h3, got %q", report.Protocol)
+	}
+
+	// An unreachable SLA must produce the SLA failure exit code
+	opts.SLAMinRPS = 1e9
+	if code := runHTTP3Load(cfg, opts); code != int(internal.ExitCodeSLAFailure) {
+		t.Errorf("Expected exit code %d on SLA failure, got %d", internal.ExitCodeSLAFailure, code)
+	}
+}
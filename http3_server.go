@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"quic-test/internal"
+	"quic-test/internal/http3"
+)
+
+// runHTTP3Server serves synthetic HTTP/3 responses on --addr until ctx is
+// cancelled, so that --mode http3-load can run without an external target,
+// and returns the process exit code
+func runHTTP3Server(ctx context.Context, cfg internal.TestConfig, responses []http3.SyntheticResponse) int {
+	tlsConf := internal.GenerateTLSConfig(false)
+	if cfg.CertPath != "" && cfg.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+		if err != nil {
+			fmt.Printf("❌ Error: failed to load certificate: %v\n", err)
+			return int(internal.ExitCodeCriticalFailure)
+		}
+		tlsConf = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	server, err := http3.ListenTestServer(http3.TestServerConfig{
+		Addr:       cfg.Addr,
+		TLSConfig:  tlsConf,
+		QUICConfig: internal.CreateQUICConfig(cfg),
+		Responses:  responses,
+	})
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	if len(responses) == 0 {
+		responses = []http3.SyntheticResponse{http3.DefaultSyntheticResponse}
+	}
+	fmt.Printf("Serving synthetic HTTP/3 responses on https://%s/\n", server.Addr())
+	for _, resp := range responses {
+		fmt.Printf("  %s\n", resp)
+	}
+
+	err = server.Serve(ctx)
+	fmt.Printf("Served %s\n", server.Stats())
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	return int(internal.ExitCodeSuccess)
+}
@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DefaultPort — порт, подставляемый в --addr без порта
+const DefaultPort = "9000"
+
+// NormalizeAddr проверяет значение --addr и приводит его к виду host:port.
+// Без порта подставляется DefaultPort, IPv6-адрес заключается в квадратные
+// скобки, адрес из одного порта ("9000") означает все интерфейсы. IPv6-адрес
+// с портом должен быть в скобках: "::1:9000" читается как IPv6-адрес.
+func NormalizeAddr(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return "", fmt.Errorf("invalid address %q: address is empty", addr)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port, err = splitPortless(addr)
+		if err != nil {
+			return "", fmt.Errorf("invalid address %q: %v", addr, err)
+		}
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return "", fmt.Errorf("invalid address %q: port %q is not a number in 0..65535", addr, port)
+	}
+	if host != "" && net.ParseIP(host) == nil && !isHostname(host) {
+		return "", fmt.Errorf("invalid address %q: %q is neither an IP address nor a host name", addr, host)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// splitPortless разбирает адрес без порта: хост, IPv6 в скобках или без них,
+// либо один номер порта
+func splitPortless(addr string) (host, port string, err error) {
+	switch {
+	case strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]"):
+		host = addr[1 : len(addr)-1]
+		if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+			return "", "", fmt.Errorf("%q is not an IPv6 address", host)
+		}
+		return host, DefaultPort, nil
+	case net.ParseIP(addr) != nil:
+		return addr, DefaultPort, nil
+	case isPort(addr):
+		return "", addr, nil
+	case !strings.ContainsAny(addr, ":[]"):
+		return addr, DefaultPort, nil
+	}
+	return "", "", fmt.Errorf("expected host:port, [ipv6]:port, host or port")
+}
+
+func isPort(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isHostname проверяет синтаксис имени хоста (метки из букв, цифр, '-' и '_')
+func isHostname(host string) bool {
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ResolveClientAddr проверяет адрес сервера и разрешает имя хоста в IP.
+// Адрес без хоста или с неопределенным IP (":9000", "0.0.0.0:9000")
+// указывает на сервер на этой же машине.
+func ResolveClientAddr(addr string) (*net.UDPAddr, error) {
+	normalized, err := NormalizeAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", normalized)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve address %q: %w", addr, err)
+	}
+	if udpAddr.Port == 0 {
+		return nil, fmt.Errorf("invalid address %q: the server port must not be 0", addr)
+	}
+	if udpAddr.IP == nil || udpAddr.IP.IsUnspecified() {
+		if udpAddr.IP != nil && udpAddr.IP.To4() == nil {
+			udpAddr.IP = net.IPv6loopback
+		} else {
+			udpAddr.IP = net.IPv4(127, 0, 0, 1)
+		}
+	}
+	return udpAddr, nil
+}
+
+// localDialAddr возвращает адрес, по которому клиент на этой же машине
+// подключается к серверу, слушающему listenAddr: пустой или неопределенный
+// хост заменяется на loopback того же семейства. Имена хостов не
+// разрешаются.
+func localDialAddr(listenAddr string) string {
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return listenAddr
+	}
+	switch ip := net.ParseIP(host); {
+	case host == "":
+		host = "127.0.0.1"
+	case ip == nil || !ip.IsUnspecified():
+		return listenAddr
+	case ip.To4() != nil:
+		host = "127.0.0.1"
+	default:
+		host = "::1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// ParseLocalAddr проверяет значение --local-addr: IP-адрес локального
+// интерфейса с портом или без него ("10.0.0.2", "10.0.0.2:5000", ":5000",
+// "[fe80::1%eth0]:5000"). Без порта или с портом 0 порт выбирает система.
+// Пустой адрес означает любой интерфейс и возвращает nil.
+func ParseLocalAddr(addr string) (*net.UDPAddr, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return nil, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		if isPort(addr) {
+			host, port = "", addr
+		} else {
+			host, port = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]"), "0"
+		}
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return nil, fmt.Errorf("invalid local address %q: port %q is not a number in 0..65535", addr, port)
+	}
+	local := &net.UDPAddr{Port: n}
+	if host != "" {
+		ipStr, zone, _ := strings.Cut(host, "%")
+		if local.IP = net.ParseIP(ipStr); local.IP == nil {
+			return nil, fmt.Errorf("invalid local address %q: %q is not an IP address", addr, host)
+		}
+		local.Zone = zone
+	}
+	return local, nil
+}
+
+// ListenLocalUDP открывает UDP-сокет клиента на local (nil — любой
+// интерфейс, порт выбирает система). Занятый адрес и адрес, которого нет
+// на интерфейсах машины, возвращают понятную ошибку.
+func ListenLocalUDP(local *net.UDPAddr) (*net.UDPConn, error) {
+	if local == nil {
+		local = &net.UDPAddr{IP: net.IPv4zero, Port: 0}
+	}
+	conn, err := net.ListenUDP("udp", local)
+	switch {
+	case err == nil:
+		return conn, nil
+	case errors.Is(err, syscall.EADDRINUSE):
+		return nil, fmt.Errorf("local address %s is already in use: %w", local, err)
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return nil, fmt.Errorf("local address %s is not assigned to any interface: %w", local, err)
+	}
+	return nil, fmt.Errorf("cannot bind local address %s: %w", local, err)
+}
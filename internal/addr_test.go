@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		// Корректные host:port
+		{"127.0.0.1:4433", "127.0.0.1:4433"},
+		{"example.com:443", "example.com:443"},
+		{":9000", ":9000"},
+		{" localhost:9000 ", "localhost:9000"},
+		// Без порта
+		{"localhost", "localhost:" + DefaultPort},
+		{"10.0.0.1", "10.0.0.1:" + DefaultPort},
+		{"4433", ":4433"},
+		// IPv6
+		{"[::1]:4433", "[::1]:4433"},
+		{"[::1]", "[::1]:" + DefaultPort},
+		{"::1", "[::1]:" + DefaultPort},
+		{"fe80::1", "[fe80::1]:" + DefaultPort},
+		{"[10.0.0.1]:9000", "10.0.0.1:9000"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeAddr(tt.addr)
+		if err != nil {
+			t.Errorf("NormalizeAddr(%q) failed: %v", tt.addr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeAddrMalformed(t *testing.T) {
+	for _, addr := range []string{
+		"",
+		"localhost:",
+		"localhost:http",
+		"localhost:70000",
+		"host name:9000",
+		"-bad-.example:9000",
+		"[::1",
+		"1.2.3.4:5:6",
+	} {
+		_, err := NormalizeAddr(addr)
+		if err == nil {
+			t.Errorf("NormalizeAddr(%q) succeeded, expected an error", addr)
+			continue
+		}
+		// Сообщение называет исходный адрес
+		if !strings.Contains(err.Error(), "\""+strings.TrimSpace(addr)+"\"") {
+			t.Errorf("Expected the error for %q to name the address, got %v", addr, err)
+		}
+	}
+}
+
+func TestResolveClientAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{":9000", "127.0.0.1:9000"},
+		{"0.0.0.0:9000", "127.0.0.1:9000"},
+		{"[::]:9000", "[::1]:9000"},
+		{"127.0.0.1", "127.0.0.1:" + DefaultPort},
+		{"::1", "[::1]:" + DefaultPort},
+	}
+	for _, tt := range tests {
+		got, err := ResolveClientAddr(tt.addr)
+		if err != nil {
+			t.Errorf("ResolveClientAddr(%q) failed: %v", tt.addr, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ResolveClientAddr(%q) = %s, want %s", tt.addr, got, tt.want)
+		}
+	}
+
+	if _, err := ResolveClientAddr("127.0.0.1:0"); err == nil {
+		t.Error("Expected an error for server port 0")
+	}
+}
+
+func TestLocalDialAddr(t *testing.T) {
+	tests := []struct {
+		listen string
+		want   string
+	}{
+		{":9000", "127.0.0.1:9000"},
+		{"0.0.0.0:9000", "127.0.0.1:9000"},
+		{"[::]:9000", "[::1]:9000"},
+		{"127.0.0.1:4433", "127.0.0.1:4433"},
+		{"[::1]:4433", "[::1]:4433"},
+		{"localhost:9000", "localhost:9000"},
+	}
+	for _, tt := range tests {
+		if got := localDialAddr(tt.listen); got != tt.want {
+			t.Errorf("localDialAddr(%q) = %q, want %q", tt.listen, got, tt.want)
+		}
+	}
+}
+
+func TestParseLocalAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"", "<nil>"},
+		{"127.0.0.1", "127.0.0.1:0"},
+		{"127.0.0.1:5000", "127.0.0.1:5000"},
+		{":5000", ":5000"},
+		{"5000", ":5000"},
+		{"::1", "[::1]:0"},
+		{"[::1]", "[::1]:0"},
+		{"[fe80::1%eth0]:5000", "[fe80::1%eth0]:5000"},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocalAddr(tt.addr)
+		if err != nil {
+			t.Errorf("ParseLocalAddr(%q) failed: %v", tt.addr, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseLocalAddr(%q) = %s, want %s", tt.addr, got, tt.want)
+		}
+	}
+
+	for _, addr := range []string{"localhost:5000", "10.0.0.1:70000", "10.0.0.1:http", "10.0.0"} {
+		if _, err := ParseLocalAddr(addr); err == nil {
+			t.Errorf("ParseLocalAddr(%q) succeeded, expected an error", addr)
+		}
+	}
+}
+
+func TestListenLocalUDPErrors(t *testing.T) {
+	taken, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	_, err = ListenLocalUDP(taken.LocalAddr().(*net.UDPAddr))
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("Expected an address in use error, got %v", err)
+	}
+	// TEST-NET-1 не назначен интерфейсам
+	_, err = ListenLocalUDP(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1)})
+	if err == nil || !strings.Contains(err.Error(), "not assigned") {
+		t.Errorf("Expected an address not available error, got %v", err)
+	}
+}
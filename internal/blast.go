@@ -0,0 +1,103 @@
+package internal
+
+import "time"
+
+// Ограничение итога режима --blast
+const (
+	BlastBoundCPU     = "cpu"     // процесс упирается в процессор
+	BlastBoundNetwork = "network" // процессор свободен, предел задает сеть
+	BlastBoundUnknown = "unknown" // время процессора недоступно на этой платформе
+)
+
+const (
+	// blastPlateauWindow — число интервалов скользящего среднего при поиске плато
+	blastPlateauWindow = 3
+	// blastPlateauTolerance — насколько окно может быть ниже пикового, оставаясь на плато
+	blastPlateauTolerance = 0.10
+	// blastCPUBoundShare — доля доступных ядер, при которой прогон считается упертым в CPU
+	blastCPUBoundShare = 0.85
+)
+
+// BlastResult — итог режима --blast: предельная пропускная способность
+// без ограничения скорости и момент, когда она перестала расти
+type BlastResult struct {
+	MaxThroughputMbps       float64 `json:"max_throughput_mbps"`       // лучший интервал
+	SustainedThroughputMbps float64 `json:"sustained_throughput_mbps"` // среднее от выхода на плато до конца
+	PlateauAtSeconds        float64 `json:"plateau_at_seconds"`        // время выхода на плато от начала теста
+	Plateaued               bool    `json:"plateaued"`                 // false — скорость росла до конца теста
+	CPUSeconds              float64 `json:"cpu_seconds"`               // user + system за время теста
+	WallSeconds             float64 `json:"wall_seconds"`
+	CPUCores                float64 `json:"cpu_cores"`      // в среднем занято ядер
+	CPUCoreLimit            float64 `json:"cpu_core_limit"` // сколько ядер прогон может занять
+	Bound                   string  `json:"bound"`          // cpu | network | unknown
+}
+
+// AnalyzeBlast находит плато пропускной способности по ряду замеров
+// (Mbps за каждый interval) и определяет, что ограничило скорость.
+// Плато начинается с первого окна из blastPlateauWindow замеров, среднее
+// которого и первый замер не ниже пикового окна больше чем на
+// blastPlateauTolerance.
+// Прогон упирается в CPU, если занято не меньше blastCPUBoundShare от
+// coreLimit ядер; cpu == 0 означает, что время процессора неизвестно.
+func AnalyzeBlast(samples []float64, interval, cpu, wall time.Duration, coreLimit float64) BlastResult {
+	result := BlastResult{
+		CPUSeconds:   cpu.Seconds(),
+		WallSeconds:  wall.Seconds(),
+		CPUCoreLimit: coreLimit,
+		Bound:        BlastBoundUnknown,
+	}
+	if cpu > 0 && wall > 0 {
+		result.CPUCores = cpu.Seconds() / wall.Seconds()
+		result.Bound = BlastBoundNetwork
+		if result.CPUCores >= blastCPUBoundShare*coreLimit {
+			result.Bound = BlastBoundCPU
+		}
+	}
+
+	for _, s := range samples {
+		if s > result.MaxThroughputMbps {
+			result.MaxThroughputMbps = s
+		}
+	}
+	if len(samples) < blastPlateauWindow {
+		// Слишком короткий ряд, чтобы отличить плато от разгона
+		result.SustainedThroughputMbps = mean(samples)
+		return result
+	}
+
+	windows := make([]float64, len(samples)-blastPlateauWindow+1)
+	var peak float64
+	for i := range windows {
+		windows[i] = mean(samples[i : i+blastPlateauWindow])
+		if windows[i] > peak {
+			peak = windows[i]
+		}
+	}
+	// Не найдено — скорость росла до конца, берется последнее окно
+	plateau := len(windows) - 1
+	threshold := (1 - blastPlateauTolerance) * peak
+	for i, w := range windows {
+		// Первый замер окна тоже должен дотягивать до плато, иначе
+		// среднее окна захватит хвост разгона
+		if w >= threshold && samples[i] >= threshold {
+			plateau = i
+			break
+		}
+	}
+	result.PlateauAtSeconds = float64(plateau) * interval.Seconds()
+	result.SustainedThroughputMbps = mean(samples[plateau:])
+	// Плато в последнем окне не отличить от продолжающегося роста
+	result.Plateaued = plateau < len(windows)-1
+	return result
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeBlastPlateau(t *testing.T) {
+	// Разгон 3 секунды, затем около 900 Mbps
+	samples := []float64{100, 400, 700, 880, 910, 900, 905, 895, 900}
+	b := AnalyzeBlast(samples, time.Second, 0, 9*time.Second, 4)
+
+	if !b.Plateaued {
+		t.Fatal("Expected a plateau")
+	}
+	if b.PlateauAtSeconds != 3 {
+		t.Errorf("Expected the plateau at 3s, got %.0fs", b.PlateauAtSeconds)
+	}
+	if b.MaxThroughputMbps != 910 {
+		t.Errorf("Expected max 910 Mbps, got %.2f", b.MaxThroughputMbps)
+	}
+	if math.Abs(b.SustainedThroughputMbps-898.33) > 0.01 {
+		t.Errorf("Expected sustained ~898.33 Mbps, got %.2f", b.SustainedThroughputMbps)
+	}
+	if b.Bound != BlastBoundUnknown {
+		t.Errorf("Expected an unknown bound without CPU time, got %s", b.Bound)
+	}
+}
+
+func TestAnalyzeBlastStillGrowing(t *testing.T) {
+	b := AnalyzeBlast([]float64{100, 200, 300, 400, 500, 600}, time.Second, 0, 6*time.Second, 1)
+	if b.Plateaued {
+		t.Errorf("Expected no plateau while throughput keeps growing, got one at %.0fs", b.PlateauAtSeconds)
+	}
+
+	// Слишком короткий ряд
+	b = AnalyzeBlast([]float64{500, 600}, time.Second, 0, 2*time.Second, 1)
+	if b.Plateaued || b.SustainedThroughputMbps != 550 {
+		t.Errorf("Expected no plateau and the mean as sustained throughput, got %+v", b)
+	}
+}
+
+func TestAnalyzeBlastBound(t *testing.T) {
+	tests := []struct {
+		cpu       time.Duration
+		coreLimit float64
+		want      string
+	}{
+		{cpu: 19 * time.Second, coreLimit: 2, want: BlastBoundCPU},    // 1.9 из 2 ядер
+		{cpu: 3 * time.Second, coreLimit: 2, want: BlastBoundNetwork}, // 0.3 из 2 ядер
+		{cpu: 9 * time.Second, coreLimit: 1, want: BlastBoundCPU},     // 0.9 из 1 ядра
+	}
+	for _, tt := range tests {
+		b := AnalyzeBlast(nil, time.Second, tt.cpu, 10*time.Second, tt.coreLimit)
+		if b.Bound != tt.want {
+			t.Errorf("cpu %v, %v cores: expected %s, got %s (%.2f cores)", tt.cpu, tt.coreLimit, tt.want, b.Bound, b.CPUCores)
+		}
+	}
+}
@@ -77,7 +77,7 @@ type MetricsResponse struct {
 func NewBottomBridge(apiURL string, interval time.Duration) *BottomBridge {
 	return &BottomBridge{
 		apiURL:   apiURL,
-		client:   &http.Client{Timeout: 5 * time.Second},
+		client:   LocalHTTPClient(5 * time.Second),
 		enabled:  true,
 		interval: interval,
 	}
@@ -8,6 +8,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"quic-test/client"
@@ -203,9 +205,10 @@ func runServer(args []string) error {
 		Prometheus:   getBool(config, "prometheus"),
 	}
 	
-	// Запускаем сервер
-	server.Run(cfg)
-	return nil
+	// Запускаем сервер до SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.Run(ctx, cfg)
 }
 
 // runClient запускает клиент
@@ -230,7 +233,7 @@ func runClient(args []string) error {
 	}
 	
 	// Запускаем клиент
-	client.Run(cfg)
+	client.RunAndReport(cfg)
 	return nil
 }
 
@@ -254,11 +257,27 @@ func runTest(args []string) error {
 		Duration:    30 * time.Second, // По умолчанию 30 секунд
 	}
 	
-	// Запускаем тест (сервер + клиент)
-	// TODO: реализовать одновременный запуск сервера и клиента
-	// Пока запускаем только клиент, предполагая что сервер уже работает
-	client.Run(cfg)
-	return nil
+	// Клиент проверяет сервер по сертификату, созданному для этой пары
+	if err := cfg.PrepareLoopbackTLS(); err != nil {
+		return err
+	}
+	
+	// Запускаем сервер в этом же процессе; ошибка прослушивания завершает тест
+	ctx, stopServer := context.WithCancel(context.Background())
+	defer stopServer()
+	serverDone := make(chan error, 1)
+	go func() {
+		serverDone <- server.Run(ctx, cfg.ServerConfig())
+	}()
+	select {
+	case err := <-serverDone:
+		return err
+	case <-time.After(time.Second):
+	}
+	
+	client.RunAndReport(cfg.ClientConfig())
+	stopServer()
+	return <-serverDone
 }
 
 // runDashboard запускает dashboard
@@ -1,7 +1,11 @@
 package internal
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -9,32 +13,78 @@ import (
 type TestConfig struct {
 	Mode         string        // Режим работы: server | client | test
 	Addr         string        // Адрес для подключения или прослушивания
+	LocalAddr    string        // Клиент: локальный адрес UDP-сокета (пусто — любой интерфейс и порт)
 	Streams      int           // Количество потоков на соединение
 	Connections  int           // Количество соединений
+	MaxConnectionsPerSecond float64 // Клиент: не открывать новые соединения чаще (0 — все сразу)
 	Duration     time.Duration // Длительность теста
-	PacketSize   int           // Размер пакета (байт)
+	Warmup       time.Duration // Фаза прогрева после handshake всех соединений (0 — без прогрева)
+	Drain        time.Duration // Фаза завершения в конце теста (0 — без нее)
+	MinDurationFactor float64  // Во сколько раз тест должен быть длиннее handshake и slow start (0 — по умолчанию)
+	AutoWarmup   bool          // Для короткого теста без --warmup прогревать на время handshake и slow start
+	PacketSize   int           // Размер пакета (байт); при распределении — максимальный
+	PacketSizes  PacketSizeSpec // Распределение размеров пакетов (пустое — фиксированный PacketSize)
 	Rate         int           // Частота отправки пакетов (в секунду)
+	Blast        bool          // Отправка без ограничения скорости и проверок для поиска предела канала
 	ReportPath   string        // Путь к файлу для отчета
-	ReportFormat string        // Формат отчета: csv | md | json
+	ReportFormat string        // Формат отчета: csv | md | json | jsonl
+	ReportCompress bool        // Сжимать файл отчета gzip (к имени добавляется .gz)
+	RawLatencyOut string       // Клиент: CSV, куда пишется каждый замер RTT (пусто — не писать)
 	CertPath     string        // Путь к TLS-сертификату
 	KeyPath      string        // Путь к TLS-ключу
+	CertHosts    []string      // Сервер: SAN самоподписанного сертификата без --cert/--key (пусто — DefaultCertHosts)
+	CertValidity time.Duration // Сервер: срок самоподписанного сертификата (0 — DefaultCertValidity)
 	Pattern      string        // Шаблон данных: random | zeroes | increment
+	Echo         bool          // Сервер подтверждает каждый пакет, клиент считает по подтверждениям RTT (--echo)
 	NoTLS        bool          // Отключить TLS
+	InsecureSkipVerify bool    // Не проверять сертификат сервера (--insecure)
+	CACertPath   string        // PEM с CA, которому клиент доверяет вместо системных (--ca-cert)
+	LoopbackCert *tls.Certificate `json:"-"` // Режим test: сертификат сервера пары, которому доверяет ее клиент (см. PrepareLoopbackTLS)
 	Prometheus   bool          // Экспортировать метрики Prometheus
 
 	// --- Эмуляция плохих сетей ---
 	EmulateLoss    float64       // вероятность потери пакета (0..1)
 	EmulateLatency time.Duration // дополнительная задержка
 	EmulateDup     float64       // вероятность дублирования пакета (0..1)
+	EmulateReorder float64       // вероятность задержать пакет и отправить его после следующего (0..1)
+	EmulateJitter  time.Duration // случайное отклонение ± от EmulateLatency, равномерное для каждого пакета
+	EmulationSeed  int64         // seed генератора эмуляции (0 — случайный); одинаковый seed дает одинаковую картину потерь
+
+	// --- Вывод ---
+	ProgressInterval time.Duration // Интервал однострочной сводки прогресса в stderr (0 — выключено)
+	Quiet            bool          // Не печатать периодический прогресс
+	LogFormat        string        // Формат строк прогресса: text | json
+	Sinks            []string      // Стоки метрик и итога прогона: stdout, file:PATH, http://URL (--sink)
+	Labels           RunLabels     // Метки прогона для метрик Prometheus и отчета (--label)
 
 	// --- Профилирование и мониторинг ---
-	PprofAddr string // Адрес для pprof (например, :6060)
+	PprofAddr    string // Адрес для pprof (например, :6060)
+	PcapPath     string // Файл для записи UDP-датаграмм в формате pcap
+	PcapMaxBytes int64  // Максимальный размер pcap-файла (0 — без ограничения)
+
+	// --- UDP сокеты ---
+	UDPRecvBuffer int // SO_RCVBUF сокетов клиента и сервера, байт (0 — системное значение)
+	UDPSendBuffer int // SO_SNDBUF сокетов клиента и сервера, байт (0 — системное значение)
+
+	// --- Передача файла ---
+	UploadFile string // Клиент: файл, передаваемый серверу вместо сгенерированных пакетов
+	OutputFile string // Сервер: куда записывать принятый файл (пусто — не сохранять)
+
+	// --- Прием соединений на сервере ---
+	AcceptBacklog        int // Очередь принятых соединений, ждущих обработчика (0 — DefaultAcceptBacklog); лишние закрываются
+	MaxActiveConnections int // Одновременно обслуживаемые соединения, остальные ждут в очереди (0 — без ограничения)
+	StreamReadTimeout    time.Duration // Поток без входящих данных дольше этого сбрасывается сервером (0 — без ограничения)
+	StreamWriteTimeout   time.Duration // Запись сервера в поток, не завершившаяся за это время, сбрасывает его (0 — без ограничения)
 
 	// --- SLA проверки ---
 	SlaRttP95     time.Duration // SLA: максимальный RTT p95
 	SlaLoss       float64       // SLA: максимальная потеря пакетов
 	SlaThroughput float64       // SLA: минимальная пропускная способность (KB/s)
 	SlaErrors     int64         // SLA: максимальное количество ошибок
+
+	// --- Оценка стабильности ---
+	StabilityRTTCoV        float64 // Порог коэффициента вариации RTT (0 — по умолчанию)
+	StabilityThroughputCoV float64 // Порог коэффициента вариации throughput (0 — по умолчанию)
 	
 	// --- QUIC тюнинг ---
 	CongestionControl string        // Алгоритм управления перегрузкой: cubic, bbr, reno
@@ -48,6 +98,7 @@ type TestConfig struct {
 	EnableDatagrams   bool          // Включить datagrams
 	MaxIncomingStreams int64        // Максимальное количество входящих потоков
 	MaxIncomingUniStreams int64     // Максимальное количество входящих unidirectional потоков
+	TransportParams   []string      // --transport-param key=value поверх остальных настроек (см. TransportParamNames)
 	
 	// --- FEC (Forward Error Correction) ---
 	FECEnabled    bool    // Включить Forward Error Correction
@@ -62,66 +113,268 @@ type TestConfig struct {
 	AIServiceURL string // URL сервиса прогнозирования (например, http://localhost:5000)
 }
 
-// Validate проверяет корректность конфигурации
+// Форматы вывода прогресса (--log-format)
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// validCongestionControls — поддерживаемые алгоритмы управления перегрузкой
+var validCongestionControls = map[string]bool{
+	"cubic": true, "bbr": true, "bbrv2": true, "bbrv3": true, "reno": true,
+}
+
+// IsValidCongestionControl проверяет, поддерживается ли алгоритм управления перегрузкой
+func IsValidCongestionControl(name string) bool {
+	return validCongestionControls[name]
+}
+
+// ListCongestionControls возвращает поддерживаемые алгоритмы управления перегрузкой
+func ListCongestionControls() []string {
+	names := make([]string, 0, len(validCongestionControls))
+	for name := range validCongestionControls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PacketSizeDistribution возвращает распределение размеров пакетов;
+// без явного распределения все пакеты имеют размер PacketSize
+func (cfg *TestConfig) PacketSizeDistribution() PacketSizeSpec {
+	if cfg.PacketSizes.Kind != "" {
+		return cfg.PacketSizes
+	}
+	return FixedPacketSize(cfg.PacketSize)
+}
+
+// StabilityThresholds возвращает пороги оценки стабильности канала
+func (cfg *TestConfig) StabilityThresholds() StabilityThresholds {
+	return StabilityThresholds{
+		MaxRTTCoV:        cfg.StabilityRTTCoV,
+		MaxThroughputCoV: cfg.StabilityThroughputCoV,
+	}.withDefaults()
+}
+
+// ServerConfig возвращает конфигурацию серверной стороны режима test:
+// сервер слушает cfg.Addr, клиентские параметры ему не нужны
+func (cfg *TestConfig) ServerConfig() TestConfig {
+	server := *cfg
+	server.Mode = "server"
+	// Захват клиента уже содержит оба направления loopback-трафика
+	server.PcapPath = ""
+	server.UploadFile = ""
+	server.Blast = false
+	return server
+}
+
+// ClientConfig возвращает конфигурацию клиентской стороны режима test:
+// клиент подключается к серверу, слушающему cfg.Addr на этой же машине
+func (cfg *TestConfig) ClientConfig() TestConfig {
+	client := *cfg
+	client.Mode = "client"
+	client.Addr = localDialAddr(cfg.Addr)
+	client.OutputFile = ""
+	return client
+}
+
+// PrepareLoopbackTLS генерирует для режима test один самоподписанный
+// сертификат на пару сервер-клиент: сервер использует его, а клиент доверяет
+// именно ему, не отключая проверку. С --cert/--key или --no-tls ничего не
+// меняется.
+func (cfg *TestConfig) PrepareLoopbackTLS() error {
+	if cfg.NoTLS || cfg.LoopbackCert != nil || (cfg.CertPath != "" && cfg.KeyPath != "") {
+		return nil
+	}
+	cert, err := GenerateSelfSignedCert(cfg.CertHosts, cfg.CertValidity)
+	if err != nil {
+		return fmt.Errorf("generate test mode certificate: %w", err)
+	}
+	cfg.LoopbackCert = &cert
+	return nil
+}
+
+// SkipVerify сообщает, отключена ли проверка сертификата сервера: явно
+// через --insecure или режимом --no-tls, где сервер использует
+// самоподписанный сертификат
+func (cfg *TestConfig) SkipVerify() bool {
+	return cfg.InsecureSkipVerify || cfg.NoTLS
+}
+
+// DefaultAcceptBacklog — размер очереди приема соединений по умолчанию
+const DefaultAcceptBacklog = 128
+
+// Допустимый размер пакета (--packet-size)
+const (
+	MinPacketSize = 64
+	MaxPacketSize = 65535
+)
+
+// Допустимая избыточность FEC (--fec-rate), когда FEC включен
+const (
+	MinFECRedundancy = 0.05
+	MaxFECRedundancy = 0.20
+)
+
+// Validate проверяет корректность конфигурации. Проверяются все поля сразу:
+// ошибка объединяет все найденные проблемы (см. ValidationErrors).
 func (cfg *TestConfig) Validate() error {
+	var errs []error
+	fail := func(message string) {
+		errs = append(errs, errors.New(message))
+	}
+	
 	if cfg.Connections <= 0 {
-		return errors.New("connections must be positive")
+		fail("connections must be positive")
 	}
 	if cfg.Streams <= 0 {
-		return errors.New("streams must be positive")
+		fail("streams must be positive")
+	}
+	// Нулевая длительность — тест до ручной остановки
+	if cfg.Duration < 0 {
+		fail("duration must not be negative")
+	}
+	if cfg.MaxConnectionsPerSecond < 0 {
+		fail("max connections per second must not be negative")
 	}
-	if cfg.Duration <= 0 {
-		return errors.New("duration must be positive")
+	if cfg.Warmup < 0 || cfg.Drain < 0 {
+		fail("warmup and drain must not be negative")
 	}
-	if cfg.PacketSize <= 0 {
-		return errors.New("packet size must be positive")
+	if cfg.MinDurationFactor < 0 {
+		fail("min duration factor must be non-negative")
+	}
+	if cfg.Drain > 0 && (cfg.Duration == 0 || cfg.Drain >= cfg.Duration) {
+		fail("drain must be shorter than duration")
+	}
+	if cfg.PacketSize < MinPacketSize || cfg.PacketSize > MaxPacketSize {
+		errs = append(errs, fmt.Errorf("packet size must be between %d and %d", MinPacketSize, MaxPacketSize))
+	}
+	if cfg.PacketSizes.Kind != "" {
+		if err := cfg.PacketSizes.Validate(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 	if cfg.Rate <= 0 {
-		return errors.New("rate must be positive")
+		fail("rate must be positive")
+	}
+	if cfg.Blast && cfg.UploadFile != "" {
+		fail("blast mode and upload file are mutually exclusive")
+	}
+	if cfg.Blast && cfg.Echo {
+		fail("blast mode and echo are mutually exclusive")
+	}
+	if cfg.InsecureSkipVerify && cfg.CACertPath != "" {
+		fail("insecure and CA certificate are mutually exclusive")
+	}
+	if local, err := ParseLocalAddr(cfg.LocalAddr); err != nil {
+		errs = append(errs, err)
+	} else if local != nil && local.Port != 0 && cfg.Connections > 1 {
+		// Каждое соединение открывает свой сокет, второе не сможет занять порт
+		fail("local address with a fixed port allows only one connection")
 	}
 	if cfg.EmulateLoss < 0 || cfg.EmulateLoss > 1 {
-		return errors.New("emulate loss must be between 0 and 1")
+		fail("emulate loss must be between 0 and 1")
 	}
 	if cfg.EmulateDup < 0 || cfg.EmulateDup > 1 {
-		return errors.New("emulate dup must be between 0 and 1")
+		fail("emulate dup must be between 0 and 1")
+	}
+	if cfg.EmulateReorder < 0 || cfg.EmulateReorder > 1 {
+		fail("emulate reorder must be between 0 and 1")
+	}
+	if cfg.EmulateJitter < 0 {
+		fail("emulate jitter must be non-negative")
 	}
 	if cfg.SlaLoss < 0 || cfg.SlaLoss > 1 {
-		return errors.New("SLA loss must be between 0 and 1")
+		fail("SLA loss must be between 0 and 1")
+	}
+	if cfg.ProgressInterval < 0 {
+		fail("progress interval must be non-negative")
+	}
+	if cfg.LogFormat != "" && cfg.LogFormat != LogFormatText && cfg.LogFormat != LogFormatJSON {
+		fail("log format must be one of: text, json")
+	}
+	if err := ValidateRunLabels(cfg.Labels); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.CertValidity < 0 {
+		fail("certificate validity must be non-negative")
+	}
+	for _, host := range cfg.CertHosts {
+		if strings.TrimSpace(host) == "" {
+			fail("certificate hosts must not be empty")
+			break
+		}
+	}
+	if cfg.AcceptBacklog < 0 {
+		fail("accept backlog must be non-negative")
+	}
+	if cfg.MaxActiveConnections < 0 {
+		fail("max active connections must be non-negative")
+	}
+	if cfg.StreamReadTimeout < 0 {
+		fail("stream read timeout must be non-negative")
+	}
+	if cfg.StreamWriteTimeout < 0 {
+		fail("stream write timeout must be non-negative")
+	}
+	if cfg.ReportCompress && IsStreamingReport(cfg.ReportFormat) {
+		fail("report compression is not supported for the jsonl report format")
+	}
+	if cfg.UDPRecvBuffer < 0 || cfg.UDPSendBuffer < 0 {
+		fail("UDP buffer sizes must be non-negative")
+	}
+	if cfg.StabilityRTTCoV < 0 || cfg.StabilityThroughputCoV < 0 {
+		fail("stability thresholds must be non-negative")
 	}
 	
 	// Валидация QUIC параметров
-	validCC := map[string]bool{
-		"cubic": true, "bbr": true, "bbrv2": true, "bbrv3": true, "reno": true,
-	}
-	if cfg.CongestionControl != "" && !validCC[cfg.CongestionControl] {
-		return errors.New("congestion control must be one of: cubic, bbr, bbrv2, bbrv3, reno")
+	if cfg.CongestionControl != "" && !IsValidCongestionControl(cfg.CongestionControl) {
+		fail("congestion control must be one of: cubic, bbr, bbrv2, bbrv3, reno")
 	}
 	if cfg.MaxIdleTimeout < 0 {
-		return errors.New("max idle timeout must be non-negative")
+		fail("max idle timeout must be non-negative")
 	}
 	if cfg.HandshakeTimeout < 0 {
-		return errors.New("handshake timeout must be non-negative")
+		fail("handshake timeout must be non-negative")
 	}
 	if cfg.KeepAlive < 0 {
-		return errors.New("keep alive must be non-negative")
+		fail("keep alive must be non-negative")
 	}
 	if cfg.MaxStreams < 0 {
-		return errors.New("max streams must be non-negative")
+		fail("max streams must be non-negative")
 	}
 	if cfg.MaxStreamData < 0 {
-		return errors.New("max stream data must be non-negative")
+		fail("max stream data must be non-negative")
 	}
 	if cfg.MaxIncomingStreams < 0 {
-		return errors.New("max incoming streams must be non-negative")
+		fail("max incoming streams must be non-negative")
 	}
 	if cfg.MaxIncomingUniStreams < 0 {
-		return errors.New("max incoming uni streams must be non-negative")
+		fail("max incoming uni streams must be non-negative")
+	}
+	if err := ValidateTransportParams(cfg.TransportParams); err != nil {
+		errs = append(errs, err)
 	}
 	
 	// Валидация FEC параметров
-	if cfg.FECRedundancy < 0 || cfg.FECRedundancy > 1 {
-		return errors.New("FEC redundancy must be between 0 and 1")
+	if cfg.FECEnabled {
+		if cfg.FECRedundancy < MinFECRedundancy || cfg.FECRedundancy > MaxFECRedundancy {
+			errs = append(errs, fmt.Errorf("FEC redundancy must be between %.2f and %.2f", MinFECRedundancy, MaxFECRedundancy))
+		}
+	} else if cfg.FECRedundancy < 0 || cfg.FECRedundancy > 1 {
+		fail("FEC redundancy must be between 0 and 1")
 	}
 	
-	return nil
+	return errors.Join(errs...)
+}
+
+// ValidationErrors возвращает по отдельности проблемы, найденные Validate
+func ValidationErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
 }
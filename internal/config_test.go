@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -76,6 +77,211 @@ func TestTestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "drain longer than duration",
+			config: TestConfig{
+				Mode:        "test",
+				Addr:        ":9000",
+				Connections: 1,
+				Streams:     1,
+				Duration:    time.Second,
+				Drain:       2 * time.Second, // Invalid
+				PacketSize:  1024,
+				Rate:        100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative UDP buffer",
+			config: TestConfig{
+				Mode:          "test",
+				Addr:          ":9000",
+				Connections:   1,
+				Streams:       1,
+				Duration:      time.Second,
+				PacketSize:    1024,
+				Rate:          100,
+				UDPRecvBuffer: -1, // Invalid
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative stability threshold",
+			config: TestConfig{
+				Mode:            "test",
+				Addr:            ":9000",
+				Connections:     1,
+				Streams:         1,
+				Duration:        time.Second,
+				PacketSize:      1024,
+				Rate:            100,
+				StabilityRTTCoV: -0.1, // Invalid
+			},
+			wantErr: true,
+		},
+		{
+			name: "unlimited duration",
+			config: TestConfig{
+				Mode:        "test",
+				Addr:        ":9000",
+				Connections: 1,
+				Streams:     1,
+				Duration:    0, // До ручной остановки
+				PacketSize:  1024,
+				Rate:        100,
+			},
+			wantErr: false,
+		},
+		{
+			name: "drain without duration",
+			config: TestConfig{
+				Mode:        "test",
+				Addr:        ":9000",
+				Connections: 1,
+				Streams:     1,
+				Drain:       time.Second, // Invalid
+				PacketSize:  1024,
+				Rate:        100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative duration",
+			config: TestConfig{
+				Mode:        "test",
+				Addr:        ":9000",
+				Connections: 1,
+				Streams:     1,
+				Duration:    -time.Second, // Invalid
+				PacketSize:  1024,
+				Rate:        100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "blast with upload file",
+			config: TestConfig{
+				Mode:        "client",
+				Addr:        ":9000",
+				Connections: 1,
+				Streams:     1,
+				PacketSize:  1024,
+				Rate:        100,
+				Blast:       true,
+				UploadFile:  "data.bin", // Invalid
+			},
+			wantErr: true,
+		},
+		{
+			name: "blast with echo",
+			config: TestConfig{
+				Mode:        "client",
+				Addr:        ":9000",
+				Connections: 1,
+				Streams:     1,
+				PacketSize:  1024,
+				Rate:        100,
+				Blast:       true,
+				Echo:        true, // Invalid
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative accept backlog",
+			config: TestConfig{
+				Mode:          "server",
+				Addr:          ":9000",
+				Connections:   1,
+				Streams:       1,
+				PacketSize:    1024,
+				Rate:          100,
+				AcceptBacklog: -1, // Invalid
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative certificate validity",
+			config: TestConfig{
+				Mode:         "server",
+				Addr:         ":9000",
+				Connections:  1,
+				Streams:      1,
+				PacketSize:   1024,
+				Rate:         100,
+				CertValidity: -time.Hour, // Invalid
+			},
+			wantErr: true,
+		},
+		{
+			name: "local address with port and several connections",
+			config: TestConfig{
+				Mode:        "client",
+				Addr:        ":9000",
+				LocalAddr:   "127.0.0.1:5000",
+				Connections: 2, // Invalid
+				Streams:     1,
+				PacketSize:  1024,
+				Rate:        100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "local address without port and several connections",
+			config: TestConfig{
+				Mode:        "client",
+				Addr:        ":9000",
+				LocalAddr:   "127.0.0.1",
+				Connections: 2,
+				Streams:     1,
+				PacketSize:  1024,
+				Rate:        100,
+			},
+			wantErr: false,
+		},
+		{
+			name: "packet size below minimum",
+			config: TestConfig{
+				Connections: 1,
+				Streams:     1,
+				PacketSize:  32, // Invalid
+				Rate:        100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "packet size above maximum",
+			config: TestConfig{
+				Connections: 1,
+				Streams:     1,
+				PacketSize:  70000, // Invalid
+				Rate:        100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "FEC redundancy out of range",
+			config: TestConfig{
+				Connections:   1,
+				Streams:       1,
+				PacketSize:    1200,
+				Rate:          100,
+				FECEnabled:    true,
+				FECRedundancy: 0.5, // Invalid
+			},
+			wantErr: true,
+		},
+		{
+			name: "FEC redundancy in range",
+			config: TestConfig{
+				Connections:   1,
+				Streams:       1,
+				PacketSize:    1200,
+				Rate:          100,
+				FECEnabled:    true,
+				FECRedundancy: 0.05,
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,6 +294,30 @@ func TestTestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestTestConfig_ValidateReportsAllProblems(t *testing.T) {
+	config := TestConfig{
+		Connections:       0,
+		Streams:           1,
+		PacketSize:        10,
+		Rate:              100,
+		EmulateLoss:       2,
+		CongestionControl: "vegas",
+	}
+
+	problems := ValidationErrors(config.Validate())
+	if len(problems) != 4 {
+		t.Fatalf("Expected 4 problems, got %d: %v", len(problems), problems)
+	}
+	for i, want := range []string{"connections", "packet size", "emulate loss", "congestion control"} {
+		if !strings.Contains(problems[i].Error(), want) {
+			t.Errorf("Problem %d: expected %q, got %q", i, want, problems[i])
+		}
+	}
+	if ValidationErrors(nil) != nil {
+		t.Error("Expected no problems for a nil error")
+	}
+}
+
 func TestTestConfig_DefaultValues(t *testing.T) {
 	config := TestConfig{}
 
@@ -113,3 +343,54 @@ func TestTestConfig_DefaultValues(t *testing.T) {
 		t.Errorf("Valid config should not have errors: %v", err)
 	}
 }
+
+func TestTestConfig_DerivedRoleConfigs(t *testing.T) {
+	base := TestConfig{
+		Mode:        "test",
+		Addr:        ":9000",
+		Connections: 2,
+		Streams:     4,
+		Duration:    time.Second,
+		PacketSize:  1200,
+		Rate:        100,
+		PcapPath:    "capture.pcap",
+		UploadFile:  "in.bin",
+		OutputFile:  "out.bin",
+	}
+
+	server := base.ServerConfig()
+	if server.Mode != "server" {
+		t.Errorf("Expected server mode, got %q", server.Mode)
+	}
+	if server.Addr != ":9000" {
+		t.Errorf("Expected server to listen on :9000, got %q", server.Addr)
+	}
+	if server.PcapPath != "" || server.UploadFile != "" {
+		t.Errorf("Expected client-side fields to be cleared, got pcap %q upload %q", server.PcapPath, server.UploadFile)
+	}
+	if server.OutputFile != "out.bin" {
+		t.Errorf("Expected server to keep the output file, got %q", server.OutputFile)
+	}
+
+	client := base.ClientConfig()
+	if client.Mode != "client" {
+		t.Errorf("Expected client mode, got %q", client.Mode)
+	}
+	if client.Addr != "127.0.0.1:9000" {
+		t.Errorf("Expected client to dial 127.0.0.1:9000, got %q", client.Addr)
+	}
+	if client.OutputFile != "" {
+		t.Errorf("Expected server-side output file to be cleared, got %q", client.OutputFile)
+	}
+	if client.PcapPath != "capture.pcap" || client.UploadFile != "in.bin" {
+		t.Errorf("Expected client to keep pcap and upload file, got %q %q", client.PcapPath, client.UploadFile)
+	}
+	if client.Connections != 2 || client.Streams != 4 {
+		t.Errorf("Expected shared load parameters to be kept, got %d×%d", client.Connections, client.Streams)
+	}
+
+	// Базовая конфигурация не меняется
+	if base.Mode != "test" || base.Addr != ":9000" {
+		t.Errorf("Expected the base config to stay unchanged, got %q %q", base.Mode, base.Addr)
+	}
+}
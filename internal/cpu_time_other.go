@@ -0,0 +1,10 @@
+//go:build !unix
+
+package internal
+
+import "time"
+
+// ProcessCPUTime не поддерживается на этой платформе: время неизвестно
+func ProcessCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package internal
+
+import (
+	"syscall"
+	"time"
+)
+
+// ProcessCPUTime возвращает время процессора (user + system), потребленное
+// процессом с момента запуска
+func ProcessCPUTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
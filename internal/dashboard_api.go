@@ -179,7 +179,7 @@ func (api *DashboardAPI) ReportHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"config":  api.state.TestConfig,
-			"metrics": api.state.Metrics,
+			"metrics": JSONSafeValue(api.state.Metrics),
 			"timestamp": api.state.LastUpdate,
 		})
 	case "csv":
@@ -203,7 +203,7 @@ func (api *DashboardAPI) MetricsHandler(w http.ResponseWriter, r *http.Request)
 	defer api.state.mu.RUnlock()
 	
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(api.state.Metrics)
+	json.NewEncoder(w).Encode(JSONSafeValue(api.state.Metrics))
 }
 
 // UpdateMetrics обновляет метрики
@@ -125,7 +125,7 @@ func (m *SSEManager) SSEServerHandler(w http.ResponseWriter, r *http.Request) {
 func (m *SSEManager) BroadcastMetrics(metrics map[string]interface{}) {
 	message := map[string]interface{}{
 		"type":      "metrics",
-		"data":      metrics,
+		"data":      JSONSafeValue(metrics),
 		"timestamp": time.Now().Unix(),
 	}
 	m.Broadcast(message)
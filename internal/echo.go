@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Эхо пакетов (--echo) для измерения настоящего RTT.
+//
+// Поток клиента с --echo начинается с заголовка, а пакеты в нем идут с
+// длиной, как в потоке с проверяемым шаблоном:
+//
+//	"ECHOSEQ1" | шаблон (1 байт, 0 — не проверять) | (длина (uint32, big endian) | пакет)...
+//
+// Сервер с --echo отвечает на каждый пакет в том же потоке подтверждением:
+//
+//	номер пакета (uint64, little endian) | время сервера (int64, нс Unix, little endian)
+//
+// Клиент сопоставляет номер с временем отправки пакета и получает RTT.
+// Сервер без --echo такие потоки только считает. Пакеты теста начинаются с
+// номера 1, шаблон — с "PATTERN1", загрузка — с "QTUPLOAD", поэтому
+// заголовок ни с чем не путается.
+const echoMagic = "ECHOSEQ1"
+
+const (
+	echoHeaderSize = len(echoMagic) + 1
+	// EchoAckSize — размер подтверждения сервера
+	EchoAckSize = 16
+)
+
+// EchoAck — подтверждение сервером принятого пакета
+type EchoAck struct {
+	Seq        uint64    // номер пакета
+	ServerTime time.Time // когда сервер принял пакет, по часам сервера
+}
+
+// IsEchoPrefix сообщает, что начало потока совпадает с заголовком эхо
+// (prefix может быть короче заголовка)
+func IsEchoPrefix(prefix []byte) bool {
+	if len(prefix) == 0 {
+		return false
+	}
+	if len(prefix) > len(echoMagic) {
+		prefix = prefix[:len(echoMagic)]
+	}
+	return bytes.HasPrefix([]byte(echoMagic), prefix)
+}
+
+// EchoHeader возвращает заголовок потока с эхо. Шаблон, который сервер
+// может проверить, передается в заголовке; остальные не проверяются.
+func EchoHeader(pattern string) []byte {
+	return append([]byte(echoMagic), patternCodes[pattern])
+}
+
+// ReadEchoHeader читает заголовок из r и возвращает проверяемый шаблон
+// потока (пусто — без проверки)
+func ReadEchoHeader(r io.Reader) (string, error) {
+	header := make([]byte, echoHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", fmt.Errorf("failed to read echo header: %w", err)
+	}
+	if string(header[:len(echoMagic)]) != echoMagic {
+		return "", errors.New("not an echo stream")
+	}
+	code := header[len(echoMagic)]
+	if code == 0 {
+		return "", nil
+	}
+	for pattern, c := range patternCodes {
+		if c == code {
+			return pattern, nil
+		}
+	}
+	return "", fmt.Errorf("unknown pattern code %d", code)
+}
+
+// PacketSeq возвращает номер пакета теста из его первых байт
+func PacketSeq(packet []byte) uint64 {
+	var seq [patternSeqSize]byte
+	copy(seq[:], packet)
+	return binary.LittleEndian.Uint64(seq[:])
+}
+
+// AppendEchoAck добавляет к dst подтверждение пакета packet
+func AppendEchoAck(dst, packet []byte, now time.Time) []byte {
+	dst = binary.LittleEndian.AppendUint64(dst, PacketSeq(packet))
+	return binary.LittleEndian.AppendUint64(dst, uint64(now.UnixNano()))
+}
+
+// ReadEchoAck читает следующее подтверждение из r
+func ReadEchoAck(r io.Reader) (EchoAck, error) {
+	var buf [EchoAckSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return EchoAck{}, err
+	}
+	return EchoAck{
+		Seq:        binary.LittleEndian.Uint64(buf[:8]),
+		ServerTime: time.Unix(0, int64(binary.LittleEndian.Uint64(buf[8:]))),
+	}, nil
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEchoHeader(t *testing.T) {
+	for _, pattern := range []string{"", "random", "zeroes", "increment"} {
+		header := EchoHeader(pattern)
+		if !IsEchoPrefix(header[:3]) || !IsEchoPrefix(append(header, 1, 2, 3)) {
+			t.Errorf("%q: expected the header to be recognized", pattern)
+		}
+		got, err := ReadEchoHeader(bytes.NewReader(header))
+		if err != nil {
+			t.Fatalf("%q: %v", pattern, err)
+		}
+		// random не проверяется, как и поток без шаблона
+		want := pattern
+		if !PatternVerifiable(pattern) {
+			want = ""
+		}
+		if got != want {
+			t.Errorf("%q: expected pattern %q, got %q", pattern, want, got)
+		}
+	}
+
+	for _, prefix := range []string{"PATTERN1", "QTUPLOAD", "\x01\x00\x00"} {
+		if IsEchoPrefix([]byte(prefix)) {
+			t.Errorf("%q: expected no echo header", prefix)
+		}
+	}
+	if _, err := ReadEchoHeader(bytes.NewReader([]byte("ECHOSEQ1\x07"))); err == nil {
+		t.Error("Expected an error for an unknown pattern code")
+	}
+}
+
+func TestEchoAckRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+	var buf []byte
+	for seq := uint64(1); seq <= 3; seq++ {
+		buf = AppendEchoAck(buf, patternPacket("increment", seq, 1200), now)
+	}
+	if len(buf) != 3*EchoAckSize {
+		t.Fatalf("Expected %d bytes, got %d", 3*EchoAckSize, len(buf))
+	}
+
+	r := bytes.NewReader(buf)
+	for seq := uint64(1); seq <= 3; seq++ {
+		ack, err := ReadEchoAck(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ack.Seq != seq || !ack.ServerTime.Equal(now) {
+			t.Errorf("Expected packet %d at %v, got %+v", seq, now, ack)
+		}
+	}
+	if _, err := ReadEchoAck(r); err == nil {
+		t.Error("Expected an error at the end of the stream")
+	}
+}
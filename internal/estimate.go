@@ -0,0 +1,237 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Приближенная модель ресурсов клиента для --estimate
+const (
+	// estimateSampleBytes — латенси (float64) и отметка времени (time.Time),
+	// которые клиент хранит на каждый пакет
+	estimateSampleBytes = 8 + 24
+	// estimateSliceGrowth — запас на удвоение слайсов при росте
+	estimateSliceGrowth = 2
+	// estimateGoroutineBytes — начальный стек горутины
+	estimateGoroutineBytes = 8 << 10
+	// estimateConnectionBytes — буферы и состояние quic-go на соединение
+	estimateConnectionBytes = 256 << 10
+	// estimateQUICGoroutines — служебные горутины quic-go на соединение
+	estimateQUICGoroutines = 3
+	// estimateBaseGoroutines — горутины клиента вне соединений (ramp, прогресс, сигналы)
+	estimateBaseGoroutines = 8
+	// estimateBaseFiles — дескрипторы вне соединений (stdio, логи, pprof, prometheus)
+	estimateBaseFiles = 16
+	// estimateRampMinRate — ramp-up клиента идет до 100 pps, если --rate меньше 10
+	estimateRampMinRate = 10
+	estimateRampDefault = 100
+	// maxPacedStreamRate — выше этой частоты пауза между пакетами (< 100 мкс)
+	// уже не выдерживается sleep-пейсингом потока
+	maxPacedStreamRate = 10000
+	// maxFeasibleBandwidth — 100 Гбит/с, больше любой массовой сетевой карты
+	maxFeasibleBandwidth = 100e9
+)
+
+// Модель выхода на установившийся режим для проверки коротких тестов
+const (
+	// DefaultMinDurationFactor — во сколько раз тест должен быть длиннее
+	// handshake и slow start, чтобы они не определяли результат
+	DefaultMinDurationFactor = 10
+	// estimateDefaultRTT — RTT, если --emulate-latency не задан
+	estimateDefaultRTT = 20 * time.Millisecond
+	// estimateInitialWindow — начальное окно перегрузки quic-go (32 пакета по 1252 байта)
+	estimateInitialWindow = 32 * 1252
+)
+
+// RampUpEstimate — оценка времени до установившегося режима: 1-RTT
+// handshake и удвоение окна за RTT до BDP (произведения полосы на задержку)
+type RampUpEstimate struct {
+	RTT       time.Duration
+	BDPBytes  float64
+	Handshake time.Duration
+	SlowStart time.Duration
+}
+
+// Total возвращает суммарное время handshake и slow start
+func (r RampUpEstimate) Total() time.Duration {
+	return r.Handshake + r.SlowStart
+}
+
+// EstimateRampUp оценивает выход на режим по пиковой нагрузке теста;
+// RTT берется из --emulate-latency
+func EstimateRampUp(cfg TestConfig) RampUpEstimate {
+	rtt := cfg.EmulateLatency
+	if rtt <= 0 {
+		rtt = estimateDefaultRTT
+	}
+	streamRate := peakStreamRate(cfg.Rate)
+	bandwidth := float64(cfg.Connections*cfg.Streams) * float64(streamRate) * cfg.PacketSizeDistribution().Mean()
+	r := RampUpEstimate{
+		RTT:       rtt,
+		BDPBytes:  bandwidth * rtt.Seconds(),
+		Handshake: rtt,
+	}
+	if r.BDPBytes > estimateInitialWindow {
+		rounds := math.Ceil(math.Log2(r.BDPBytes / estimateInitialWindow))
+		r.SlowStart = time.Duration(rounds) * rtt
+	}
+	return r
+}
+
+// MinMeaningfulDuration возвращает минимальную длительность, при которой
+// handshake и slow start не доминируют в результате
+func MinMeaningfulDuration(cfg TestConfig) time.Duration {
+	factor := cfg.MinDurationFactor
+	if factor == 0 {
+		factor = DefaultMinDurationFactor
+	}
+	return time.Duration(float64(EstimateRampUp(cfg).Total()) * factor)
+}
+
+// ShortDurationWarning возвращает предупреждение, если тест короче
+// MinMeaningfulDuration; "" — длительность достаточна или не ограничена
+func ShortDurationWarning(cfg TestConfig) string {
+	minDuration := MinMeaningfulDuration(cfg)
+	if cfg.Duration <= 0 || cfg.Duration >= minDuration {
+		return ""
+	}
+	r := EstimateRampUp(cfg)
+	return fmt.Sprintf("duration %v is short: handshake and slow start take about %v (RTT %v, BDP %.1f MB), so results will be dominated by ramp-up; use at least %v or --warmup/--auto-warmup",
+		cfg.Duration, r.Total().Round(time.Millisecond), r.RTT, r.BDPBytes/1e6, minDuration.Round(time.Second))
+}
+
+// SystemLimits — ограничения ОС, с которыми сравнивается план (0 — неизвестно)
+type SystemLimits struct {
+	MaxOpenFiles uint64 // мягкий лимит RLIMIT_NOFILE
+	MemoryBytes  uint64 // объем физической памяти
+}
+
+// PlanEstimate — прогноз нагрузки и ресурсов теста до его запуска
+type PlanEstimate struct {
+	Connections       int
+	Streams           int           // потоков всего
+	StreamRate        int           // пиковая частота пакетов одного потока
+	PacketsPerSecond  float64       // пиковая суммарная частота пакетов
+	MeanPacketSize    float64       // байт
+	PeakBandwidthBits float64       // бит/с
+	Duration          time.Duration // 0 — до ручной остановки
+	TotalPackets      int64         // верхняя оценка; 0 при неограниченной длительности
+	TotalBytes        int64         // верхняя оценка; 0 при неограниченной длительности
+	Goroutines        int
+	MemoryBytes       int64    // пиковая память клиента (приближенно)
+	OpenFiles         int      // дескрипторы: UDP-сокет на соединение и служебные
+	Warnings          []string // план выполним, но стоит проверить
+	Problems          []string // план заведомо невыполним
+}
+
+// Feasible сообщает, что в плане нет заведомо невыполнимых параметров
+func (e PlanEstimate) Feasible() bool {
+	return len(e.Problems) == 0
+}
+
+// peakStreamRate возвращает пиковую частоту пакетов потока с учетом ramp-up клиента
+func peakStreamRate(rate int) int {
+	if rate < estimateRampMinRate {
+		return estimateRampDefault
+	}
+	return rate
+}
+
+// EstimatePlan рассчитывает нагрузку теста connections × streams × rate ×
+// packet-size × duration и проверяет ее на выполнимость. Байты и пакеты —
+// верхняя оценка при пиковой частоте всю длительность теста.
+func EstimatePlan(cfg TestConfig, limits SystemLimits) PlanEstimate {
+	e := PlanEstimate{
+		Connections:    cfg.Connections,
+		Streams:        cfg.Connections * cfg.Streams,
+		StreamRate:     peakStreamRate(cfg.Rate),
+		MeanPacketSize: cfg.PacketSizeDistribution().Mean(),
+		Duration:       cfg.Duration,
+	}
+	e.PacketsPerSecond = float64(e.Streams) * float64(e.StreamRate)
+	e.PeakBandwidthBits = e.PacketsPerSecond * e.MeanPacketSize * 8
+	e.Goroutines = estimateBaseGoroutines + e.Connections*(1+estimateQUICGoroutines) + e.Streams
+	e.OpenFiles = estimateBaseFiles + e.Connections
+
+	memory := int64(e.Goroutines)*estimateGoroutineBytes + int64(e.Connections)*estimateConnectionBytes
+	if cfg.Duration > 0 {
+		e.TotalPackets = int64(e.PacketsPerSecond * cfg.Duration.Seconds())
+		e.TotalBytes = int64(float64(e.TotalPackets) * e.MeanPacketSize)
+		memory += e.TotalPackets * estimateSampleBytes * estimateSliceGrowth
+		if warning := ShortDurationWarning(cfg); warning != "" {
+			e.Warnings = append(e.Warnings, warning)
+		}
+	} else {
+		e.Warnings = append(e.Warnings, "duration 0 runs until stopped: totals are unbounded and per-packet samples grow memory without limit")
+	}
+	e.MemoryBytes = memory
+
+	if err := cfg.PacketSizeDistribution().Validate(); err != nil {
+		e.Problems = append(e.Problems, fmt.Sprintf("invalid packet size: %v", err))
+	}
+	if e.Connections <= 0 || cfg.Streams <= 0 {
+		e.Problems = append(e.Problems, "connections and streams must be positive")
+	}
+	if cfg.Rate < estimateRampMinRate {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("--rate %d is below %d: the client ramps each stream up to %d pps instead",
+			cfg.Rate, estimateRampMinRate, estimateRampDefault))
+	}
+	if e.StreamRate > maxPacedStreamRate {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("%d pps per stream needs a %v gap between packets; sleep-based pacing will fall short above %d pps",
+			e.StreamRate, time.Second/time.Duration(e.StreamRate), maxPacedStreamRate))
+	}
+	if e.PeakBandwidthBits > maxFeasibleBandwidth {
+		e.Problems = append(e.Problems, fmt.Sprintf("peak bandwidth %.1f Gbit/s exceeds %.0f Gbit/s",
+			e.PeakBandwidthBits/1e9, maxFeasibleBandwidth/1e9))
+	}
+	if limits.MaxOpenFiles > 0 && uint64(e.OpenFiles) > limits.MaxOpenFiles {
+		e.Problems = append(e.Problems, fmt.Sprintf("%d connections need about %d open files, the limit is %d (ulimit -n)",
+			e.Connections, e.OpenFiles, limits.MaxOpenFiles))
+	}
+	if limits.MemoryBytes > 0 {
+		switch {
+		case uint64(e.MemoryBytes) > limits.MemoryBytes:
+			e.Problems = append(e.Problems, fmt.Sprintf("estimated memory %d MiB exceeds physical memory %d MiB",
+				e.MemoryBytes>>20, limits.MemoryBytes>>20))
+		case uint64(e.MemoryBytes) > limits.MemoryBytes/2:
+			e.Warnings = append(e.Warnings, fmt.Sprintf("estimated memory %d MiB is more than half of physical memory %d MiB",
+				e.MemoryBytes>>20, limits.MemoryBytes>>20))
+		}
+	}
+	return e
+}
+
+// ReadSystemLimits читает ограничения ОС; недоступные остаются нулевыми
+func ReadSystemLimits() SystemLimits {
+	return SystemLimits{
+		MaxOpenFiles: maxOpenFiles(),
+		MemoryBytes:  physicalMemory(),
+	}
+}
+
+// physicalMemory возвращает MemTotal из /proc/meminfo (только Linux)
+func physicalMemory() uint64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb << 10
+		}
+	}
+	return 0
+}
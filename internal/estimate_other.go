@@ -0,0 +1,8 @@
+//go:build !unix
+
+package internal
+
+// maxOpenFiles не поддерживается на этой платформе: лимит неизвестен
+func maxOpenFiles() uint64 {
+	return 0
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimatePlanMath(t *testing.T) {
+	cfg := TestConfig{
+		Connections: 4,
+		Streams:     2,
+		Rate:        500,
+		PacketSize:  1200,
+		Duration:    30 * time.Second,
+	}
+	e := EstimatePlan(cfg, SystemLimits{})
+
+	// 4 × 2 потоков × 500 pps = 4000 pps; 4000 × 1200 байт × 8 = 38.4 Мбит/с
+	if e.Streams != 8 || e.PacketsPerSecond != 4000 {
+		t.Errorf("Expected 8 streams at 4000 pps, got %d at %.0f", e.Streams, e.PacketsPerSecond)
+	}
+	if e.PeakBandwidthBits != 38_400_000 {
+		t.Errorf("Expected 38.4 Mbit/s, got %.0f bit/s", e.PeakBandwidthBits)
+	}
+	if e.TotalPackets != 120_000 || e.TotalBytes != 144_000_000 {
+		t.Errorf("Expected 120000 packets and 144000000 bytes, got %d and %d", e.TotalPackets, e.TotalBytes)
+	}
+	if !e.Feasible() || len(e.Warnings) != 0 {
+		t.Errorf("Expected a feasible plan without warnings, got problems %v, warnings %v", e.Problems, e.Warnings)
+	}
+}
+
+func TestEstimatePlanUsesMeanPacketSizeAndRamp(t *testing.T) {
+	cfg := TestConfig{
+		Connections: 1,
+		Streams:     1,
+		Rate:        5, // ниже порога ramp-up: клиент разгоняется до 100 pps
+		PacketSizes: PacketSizeSpec{Kind: PacketSizeBimodal, Min: 100, Max: 1100, Ratio: 0.5},
+		Duration:    10 * time.Second,
+	}
+	e := EstimatePlan(cfg, SystemLimits{})
+
+	if e.StreamRate != 100 || e.MeanPacketSize != 600 {
+		t.Fatalf("Expected 100 pps of 600-byte packets, got %d pps of %.0f bytes", e.StreamRate, e.MeanPacketSize)
+	}
+	if e.TotalPackets != 1000 || e.TotalBytes != 600_000 || e.PeakBandwidthBits != 480_000 {
+		t.Errorf("Unexpected totals: %d packets, %d bytes, %.0f bit/s", e.TotalPackets, e.TotalBytes, e.PeakBandwidthBits)
+	}
+	if len(e.Warnings) != 1 {
+		t.Errorf("Expected a warning about the ramp-up rate, got %v", e.Warnings)
+	}
+}
+
+func TestEstimatePlanInfeasible(t *testing.T) {
+	cfg := TestConfig{
+		Connections: 1000,
+		Streams:     10,
+		Rate:        100000,
+		PacketSize:  1200,
+		Duration:    time.Hour,
+	}
+	e := EstimatePlan(cfg, SystemLimits{MaxOpenFiles: 256, MemoryBytes: 1 << 30})
+
+	// Полоса, дескрипторы и память превышают лимиты
+	if e.Feasible() || len(e.Problems) != 3 {
+		t.Errorf("Expected 3 problems, got %v", e.Problems)
+	}
+
+	cfg.Duration = 0
+	if e := EstimatePlan(cfg, SystemLimits{}); e.TotalBytes != 0 || e.TotalPackets != 0 {
+		t.Errorf("Expected no totals for an unlimited test, got %d packets, %d bytes", e.TotalPackets, e.TotalBytes)
+	}
+}
+
+func TestShortDurationWarningHighBDP(t *testing.T) {
+	// 10 потоков × 10000 pps × 1200 байт ≈ 1.15 Гбит/с при RTT 100 мс: BDP 14.4 МБ
+	cfg := TestConfig{
+		Connections:    1,
+		Streams:        10,
+		Rate:           10000,
+		PacketSize:     1200,
+		Duration:       2 * time.Second,
+		EmulateLatency: 100 * time.Millisecond,
+	}
+	r := EstimateRampUp(cfg)
+	// Handshake за 1 RTT и 9 удвоений окна с 40 КБ до 14.4 МБ
+	if r.Handshake != 100*time.Millisecond || r.SlowStart != 900*time.Millisecond {
+		t.Errorf("Expected 100ms handshake and 900ms slow start, got %+v", r)
+	}
+
+	warning := ShortDurationWarning(cfg)
+	if warning == "" || !strings.Contains(warning, "use at least 10s") {
+		t.Errorf("Expected a warning advising at least 10s, got %q", warning)
+	}
+	if e := EstimatePlan(cfg, SystemLimits{}); !containsString(e.Warnings, warning) {
+		t.Errorf("Expected --estimate to include the warning, got %v", e.Warnings)
+	}
+
+	// Порог настраивается
+	cfg.MinDurationFactor = 2
+	if warning := ShortDurationWarning(cfg); warning != "" {
+		t.Errorf("Expected no warning for a 2s run with factor 2, got %q", warning)
+	}
+	cfg.MinDurationFactor = 0
+	cfg.Duration = 30 * time.Second
+	if warning := ShortDurationWarning(cfg); warning != "" {
+		t.Errorf("Expected no warning for a 30s run, got %q", warning)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
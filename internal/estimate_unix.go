@@ -0,0 +1,14 @@
+//go:build unix
+
+package internal
+
+import "syscall"
+
+// maxOpenFiles возвращает мягкий лимит открытых файлов процесса
+func maxOpenFiles() uint64 {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0
+	}
+	return uint64(limit.Cur)
+}
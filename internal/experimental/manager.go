@@ -72,6 +72,9 @@ type ExperimentalConfig struct {
 	// Наблюдаемость
 	EnableTracing    bool
 	MetricsInterval  time.Duration
+	
+	// Registry для Prometheus метрик (nil - prometheus.DefaultRegisterer)
+	Registerer prometheus.Registerer
 }
 
 // NewExperimentalManager создает новый экспериментальный менеджер
@@ -322,7 +325,11 @@ func (em *ExperimentalManager) initializeComponents(ctx context.Context) error {
 	em.ccManager = NewCongestionControlManager(em.logger, em.config.CongestionControl)
 	
 	// Prometheus метрики
-	em.prometheusMetrics = metrics.NewPrometheusMetrics(prometheus.DefaultRegisterer)
+	reg := em.config.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	em.prometheusMetrics = metrics.NewPrometheusMetrics(reg)
 	
 	// CC Integration для метрик
 	var ccMgr *CongestionControlManager
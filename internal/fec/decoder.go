@@ -189,19 +189,24 @@ func (d *FECDecoder) AddRedundancyPacket(redundancyPacket []byte) (bool, []Recov
 	
 	// Пытаемся восстановить недостающие пакеты
 	if group.received < group.packetCount {
+		// Запоминаем недостающие пакеты до восстановления: tryRecover
+		// помечает восстановленный пакет как присутствующий
+		var missing []uint64
+		for packetID := uint64(0); packetID < uint64(group.packetCount); packetID++ {
+			if !group.present[packetID] {
+				missing = append(missing, packetID)
+			}
+		}
 		recovered := d.tryRecover(group)
 		if recovered {
 			// Возвращаем список восстановленных пакетов
 			var recoveredList []Recovered
-			for packetID := uint64(0); packetID < uint64(group.packetCount); packetID++ {
-				if !group.present[packetID] {
-					// Это восстановленный пакет
-					if data, exists := group.packets[packetID]; exists {
-						recoveredList = append(recoveredList, Recovered{
-							PacketID: packetID,
-							Data:     data,
-						})
-					}
+			for _, packetID := range missing {
+				if data, exists := group.packets[packetID]; exists {
+					recoveredList = append(recoveredList, Recovered{
+						PacketID: packetID,
+						Data:     data,
+					})
 				}
 			}
 			return true, recoveredList
@@ -295,6 +300,21 @@ func (d *FECDecoder) GetMetrics() *FECDecoderMetrics {
 	return &metrics
 }
 
+// BufferedBytes возвращает объем данных, удерживаемых открытыми группами
+func (d *FECDecoder) BufferedBytes() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	var total int64
+	for _, group := range d.groups {
+		for _, sym := range group.packets {
+			total += int64(len(sym))
+		}
+		total += int64(len(group.redundancy))
+	}
+	return total
+}
+
 // ResetMetrics сбрасывает метрики
 func (d *FECDecoder) ResetMetrics() {
 	d.mu.Lock()
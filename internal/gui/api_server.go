@@ -2,6 +2,7 @@ package gui
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -14,6 +15,7 @@ import (
 // APIServer handles REST API requests
 type APIServer struct {
 	testManager *TestManager
+	health      *healthMonitor
 }
 
 // APIResponse represents a standard API response
@@ -21,6 +23,7 @@ type APIResponse struct {
 	Success   bool        `json:"success"`
 	Data      interface{} `json:"data,omitempty"`
 	Error     string      `json:"error,omitempty"`
+	Errors    []string    `json:"errors,omitempty"` // every problem of an invalid test config
 	Timestamp time.Time   `json:"timestamp"`
 }
 
@@ -28,6 +31,7 @@ type APIResponse struct {
 func NewAPIServer() *APIServer {
 	return &APIServer{
 		testManager: NewTestManager(),
+		health:      newHealthMonitor(),
 	}
 }
 
@@ -35,12 +39,14 @@ func NewAPIServer() *APIServer {
 func (api *APIServer) RegisterRoutes(mux *http.ServeMux) {
 	// Test management
 	mux.HandleFunc("/api/tests", api.handleTests)
+	mux.HandleFunc("/api/tests/compare", api.handleCompareTests)
 	mux.HandleFunc("/api/tests/", api.handleTestByID)
 	
 	// Metrics
 	mux.HandleFunc("/api/metrics/current", api.handleCurrentMetrics)
 	mux.HandleFunc("/api/metrics/history", api.handleHistoricalMetrics)
 	mux.HandleFunc("/api/metrics/prometheus", api.handlePrometheusMetrics)
+	mux.HandleFunc("/api/metrics/schema", api.handleMetricsSchema)
 	
 	// Configuration
 	mux.HandleFunc("/api/config/presets", api.handleConfigPresets)
@@ -69,6 +75,14 @@ func (api *APIServer) handleTests(w http.ResponseWriter, r *http.Request) {
 // handleTestByID handles /api/tests/{id} endpoint
 func (api *APIServer) handleTestByID(w http.ResponseWriter, r *http.Request) {
 	testID := strings.TrimPrefix(r.URL.Path, "/api/tests/")
+	if id, ok := strings.CutSuffix(testID, "/logs"); ok && id != "" {
+		api.handleTestLogs(w, r, id)
+		return
+	}
+	if id, ok := strings.CutSuffix(testID, "/resume"); ok && id != "" {
+		api.handleResumeTest(w, r, id)
+		return
+	}
 	if testID == "" {
 		api.sendError(w, "Test ID required", http.StatusBadRequest)
 		return
@@ -79,6 +93,8 @@ func (api *APIServer) handleTestByID(w http.ResponseWriter, r *http.Request) {
 		api.handleGetTest(w, r, testID)
 	case "DELETE":
 		api.handleStopTest(w, r, testID)
+	case "PATCH":
+		api.handleUpdateTest(w, r, testID)
 	default:
 		api.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -108,12 +124,20 @@ func (api *APIServer) handleListTests(w http.ResponseWriter, r *http.Request) {
 	// Get all tests
 	allTests := api.testManager.GetAllTests()
 	
-	// Filter by status if specified
+	// Filter by status if specified; any change, including one that moves a
+	// test out of the filter, updates the collection's Last-Modified
 	var filteredTests []*TestSession
+	var lastModified time.Time
+	var counts TestStatusCounts
 	for _, test := range allTests {
-		if status == "" || test.Status == status {
+		testStatus := test.GetStatus()
+		counts.add(testStatus)
+		if status == "" || testStatus == status {
 			filteredTests = append(filteredTests, test)
 		}
+		if updated := test.LastModified(); updated.After(lastModified) {
+			lastModified = updated
+		}
 	}
 	
 	// Apply pagination
@@ -131,18 +155,46 @@ func (api *APIServer) handleListTests(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	response := struct {
-		Tests  []*TestSession `json:"tests"`
-		Total  int            `json:"total"`
-		Limit  int            `json:"limit"`
-		Offset int            `json:"offset"`
+		Tests   []*TestSession   `json:"tests"`
+		Total   int              `json:"total"`
+		Limit   int              `json:"limit"`
+		Offset  int              `json:"offset"`
+		HasMore bool             `json:"has_more"`
+		Counts  TestStatusCounts `json:"counts"` // across all tests, ignoring the status filter
 	}{
-		Tests:  filteredTests,
-		Total:  total,
-		Limit:  limit,
-		Offset: offset,
+		Tests:   filteredTests,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: offset+limit < total,
+		Counts:  counts,
 	}
 	
-	api.sendSuccess(w, response)
+	api.sendCacheable(w, r, response, lastModified)
+}
+
+// TestStatusCounts is the number of tests in each status
+type TestStatusCounts struct {
+	Running     int `json:"running"`
+	Completed   int `json:"completed"`
+	Failed      int `json:"failed"`
+	Stopped     int `json:"stopped"`
+	Interrupted int `json:"interrupted"`
+}
+
+func (c *TestStatusCounts) add(status string) {
+	switch status {
+	case "running":
+		c.Running++
+	case "completed":
+		c.Completed++
+	case "failed":
+		c.Failed++
+	case "stopped":
+		c.Stopped++
+	case "interrupted":
+		c.Interrupted++
+	}
 }
 
 // handleCreateTest creates a new test
@@ -162,7 +214,7 @@ func (api *APIServer) handleCreateTest(w http.ResponseWriter, r *http.Request) {
 	
 	// Validate configuration
 	if err := config.Validate(); err != nil {
-		api.sendError(w, "Invalid configuration: "+err.Error(), http.StatusBadRequest)
+		api.sendValidationError(w, err)
 		return
 	}
 	
@@ -243,11 +295,16 @@ func (api *APIServer) parseTestConfig(raw map[string]interface{}) (*internal.Tes
 	if v, ok := raw["prometheus"].(bool); ok {
 		config.Prometheus = v
 	}
+	if v, ok := raw["echo"].(bool); ok {
+		config.Echo = v
+	}
 	if v, ok := raw["fec_enabled"].(bool); ok {
 		config.FECEnabled = v
 	}
 	if v, ok := raw["fec_redundancy"].(float64); ok {
 		config.FECRedundancy = v
+	} else if config.FECEnabled {
+		config.FECRedundancy = 0.10 // default value
 	}
 	if v, ok := raw["pqc_enabled"].(bool); ok {
 		config.PQCEnabled = v
@@ -255,9 +312,28 @@ func (api *APIServer) parseTestConfig(raw map[string]interface{}) (*internal.Tes
 	if v, ok := raw["congestion_control"].(string); ok {
 		config.CongestionControl = v
 	}
-	
-	// Parse duration fields
-	if v, ok := raw["duration"].(string); ok {
+	// A client test verifies the server's certificate, as on the command line
+	if v, ok := raw["insecure"].(bool); ok {
+		config.InsecureSkipVerify = v
+	}
+	if v, ok := raw["ca_cert"].(string); ok {
+		config.CACertPath = v
+	}
+	if v, ok := raw["labels"].(map[string]interface{}); ok {
+		config.Labels = make(internal.RunLabels, len(v))
+		for name, value := range v {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid value of label %s: must be a string", name)
+			}
+			config.Labels[name] = s
+		}
+	}
+
+	// Parse duration fields. A missing duration defaults to 60s; "unlimited":
+	// true or an explicit zero duration runs the test until it is stopped,
+	// as --duration 0 does on the command line.
+	if v, ok := raw["duration"].(string); ok && v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			config.Duration = d
 		} else {
@@ -269,6 +345,9 @@ func (api *APIServer) parseTestConfig(raw map[string]interface{}) (*internal.Tes
 	} else {
 		config.Duration = 60 * time.Second // default 60 seconds
 	}
+	if v, ok := raw["unlimited"].(bool); ok && v {
+		config.Duration = 0
+	}
 	
 	if v, ok := raw["emulate_latency"].(string); ok && v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
@@ -285,6 +364,16 @@ func (api *APIServer) parseTestConfig(raw map[string]interface{}) (*internal.Tes
 	if v, ok := raw["emulate_dup"].(float64); ok {
 		config.EmulateDup = v
 	}
+	if v, ok := raw["emulate_reorder"].(float64); ok {
+		config.EmulateReorder = v
+	}
+	if v, ok := raw["emulate_jitter"].(string); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.EmulateJitter = d
+		} else {
+			return nil, fmt.Errorf("invalid emulate_jitter format: %s", v)
+		}
+	}
 	
 	return config, nil
 }
@@ -297,7 +386,7 @@ func (api *APIServer) handleGetTest(w http.ResponseWriter, r *http.Request, test
 		return
 	}
 	
-	api.sendSuccess(w, session)
+	api.sendCacheable(w, r, session, session.LastModified())
 }
 
 // handleStopTest stops a test
@@ -312,6 +401,65 @@ func (api *APIServer) handleStopTest(w http.ResponseWriter, r *http.Request, tes
 	})
 }
 
+// handleResumeTest continues a test interrupted by a restart
+func (api *APIServer) handleResumeTest(w http.ResponseWriter, r *http.Request, testID string) {
+	if r.Method != "POST" {
+		api.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if api.testManager.GetTest(testID) == nil {
+		api.sendError(w, "Test not found", http.StatusNotFound)
+		return
+	}
+	
+	if err := api.testManager.ResumeTest(testID); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errTestNotInterrupted) {
+			status = http.StatusConflict
+		}
+		api.sendError(w, err.Error(), status)
+		return
+	}
+	
+	api.sendSuccess(w, map[string]string{
+		"message": "Test resumed",
+	})
+}
+
+// handleUpdateTest changes a running test; only {"rate": N} is supported
+func (api *APIServer) handleUpdateTest(w http.ResponseWriter, r *http.Request, testID string) {
+	if api.testManager.GetTest(testID) == nil {
+		api.sendError(w, "Test not found", http.StatusNotFound)
+		return
+	}
+	
+	var update struct {
+		Rate *float64 `json:"rate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		api.sendError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if update.Rate == nil || *update.Rate != float64(int(*update.Rate)) {
+		api.sendError(w, "rate must be an integer number of packets per second", http.StatusBadRequest)
+		return
+	}
+	
+	if err := api.testManager.SetRate(testID, int(*update.Rate)); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errTestNotRunning) {
+			status = http.StatusConflict
+		}
+		api.sendError(w, err.Error(), status)
+		return
+	}
+	
+	api.sendSuccess(w, map[string]interface{}{
+		"message": "Rate updated",
+		"rate":    int(*update.Rate),
+	})
+}
+
 // handleCurrentMetrics gets current aggregated metrics
 func (api *APIServer) handleCurrentMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -327,7 +475,7 @@ func (api *APIServer) handleCurrentMetrics(w http.ResponseWriter, r *http.Reques
 		"total_connections": 0,
 		"avg_latency_ms":   0.0,
 		"total_throughput_mbps": 0.0,
-		"avg_packet_loss":  0.0,
+		"avg_packet_loss_ratio": 0.0,
 		"total_errors":     0,
 	}
 	
@@ -337,7 +485,7 @@ func (api *APIServer) handleCurrentMetrics(w http.ResponseWriter, r *http.Reques
 	lossSum := 0.0
 	
 	for _, test := range activeTests {
-		if test.Status == "running" {
+		if test.GetStatus() == "running" {
 			activeCount++
 			metrics := test.GetMetrics()
 			
@@ -353,7 +501,7 @@ func (api *APIServer) handleCurrentMetrics(w http.ResponseWriter, r *http.Reques
 				throughputSum += throughput
 			}
 			
-			if loss, ok := metrics["packet_loss"].(float64); ok {
+			if loss, ok := metrics["packet_loss_ratio"].(float64); ok {
 				lossSum += loss
 			}
 			
@@ -367,7 +515,7 @@ func (api *APIServer) handleCurrentMetrics(w http.ResponseWriter, r *http.Reques
 	
 	if activeCount > 0 {
 		aggregatedMetrics["avg_latency_ms"] = latencySum / float64(activeCount)
-		aggregatedMetrics["avg_packet_loss"] = lossSum / float64(activeCount)
+		aggregatedMetrics["avg_packet_loss_ratio"] = lossSum / float64(activeCount)
 	}
 	
 	aggregatedMetrics["total_throughput_mbps"] = throughputSum
@@ -375,55 +523,60 @@ func (api *APIServer) handleCurrentMetrics(w http.ResponseWriter, r *http.Reques
 	api.sendSuccess(w, aggregatedMetrics)
 }
 
-// handleHistoricalMetrics gets historical metrics
+// handleHistoricalMetrics returns the recorded metric history of a test,
+// limited to start_time..end_time and averaged over interval-long windows
 func (api *APIServer) handleHistoricalMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		api.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 	
-	if r.Method != "GET" {
-		api.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	query := r.URL.Query()
+	testID := query.Get("test_id")
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "5s"
+	}
+	if testID == "" {
+		api.sendError(w, "test_id is required", http.StatusBadRequest)
 		return
 	}
+	step, err := time.ParseDuration(interval)
+	if err != nil || step <= 0 {
+		api.sendError(w, "Invalid interval: "+interval, http.StatusBadRequest)
+		return
+	}
+	var from, to time.Time
+	for _, bound := range []struct {
+		name string
+		t    *time.Time
+	}{{"start_time", &from}, {"end_time", &to}} {
+		if v := query.Get(bound.name); v != "" {
+			if *bound.t, err = time.Parse(time.RFC3339, v); err != nil {
+				api.sendError(w, "Invalid "+bound.name+": "+v, http.StatusBadRequest)
+				return
+			}
+		}
+	}
 	
-	testID := r.URL.Query().Get("test_id")
-	_ = r.URL.Query().Get("start_time") // startTimeStr - unused for now
-	_ = r.URL.Query().Get("end_time")   // endTimeStr - unused for now
-	interval := r.URL.Query().Get("interval")
-	
-	if interval == "" {
-		interval = "5s"
+	session := api.testManager.GetTest(testID)
+	if session == nil {
+		api.sendError(w, "Test not found", http.StatusNotFound)
+		return
 	}
 	
-	// For now, return placeholder data
-	// In a real implementation, this would query a time-series database
-	historicalData := map[string]interface{}{
+	response := map[string]interface{}{
 		"test_id":  testID,
 		"interval": interval,
-		"metrics": []map[string]interface{}{
-			{
-				"timestamp":      time.Now().Add(-60 * time.Second),
-				"latency_ms":     45.2,
-				"throughput_mbps": 125.8,
-				"packet_loss":    0.01,
-			},
-			{
-				"timestamp":      time.Now().Add(-30 * time.Second),
-				"latency_ms":     47.1,
-				"throughput_mbps": 128.3,
-				"packet_loss":    0.008,
-			},
-			{
-				"timestamp":      time.Now(),
-				"latency_ms":     44.8,
-				"throughput_mbps": 131.2,
-				"packet_loss":    0.012,
-			},
-		},
-	}
-	
-	api.sendSuccess(w, historicalData)
+		"metrics":  downsampleHistory(historyBetween(session.GetHistory(), from, to), step),
+	}
+	if !from.IsZero() {
+		response["start_time"] = from
+	}
+	if !to.IsZero() {
+		response["end_time"] = to
+	}
+	api.sendSuccess(w, response)
 }
 
 // handlePrometheusMetrics returns metrics in Prometheus format
@@ -445,7 +598,7 @@ func (api *APIServer) handlePrometheusMetrics(w http.ResponseWriter, r *http.Req
 	
 	activeCount := 0
 	for _, test := range activeTests {
-		if test.Status == "running" {
+		if test.GetStatus() == "running" {
 			activeCount++
 		}
 	}
@@ -454,7 +607,7 @@ func (api *APIServer) handlePrometheusMetrics(w http.ResponseWriter, r *http.Req
 	
 	// Add per-test metrics
 	for _, test := range activeTests {
-		if test.Status == "running" {
+		if test.GetStatus() == "running" {
 			testMetrics := test.GetMetrics()
 			
 			if latency, ok := testMetrics["latency_ms"].(float64); ok {
@@ -465,8 +618,8 @@ func (api *APIServer) handlePrometheusMetrics(w http.ResponseWriter, r *http.Req
 				metrics = append(metrics, fmt.Sprintf("quic_test_throughput_mbps{test_id=\"%s\"} %.2f", test.ID, throughput))
 			}
 			
-			if loss, ok := testMetrics["packet_loss"].(float64); ok {
-				metrics = append(metrics, fmt.Sprintf("quic_test_packet_loss{test_id=\"%s\"} %.4f", test.ID, loss))
+			if loss, ok := testMetrics["packet_loss_ratio"].(float64); ok {
+				metrics = append(metrics, fmt.Sprintf("quic_test_packet_loss_ratio{test_id=\"%s\"} %.4f", test.ID, loss))
 			}
 		}
 	}
@@ -521,13 +674,11 @@ func (api *APIServer) handleHealthCheck(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	
+	status, checks := api.health.check()
 	health := map[string]interface{}{
-		"status":    "healthy",
+		"status":    status,
 		"timestamp": time.Now(),
-		"checks": map[string]string{
-			"api_server":   "ok",
-			"test_manager": "ok",
-		},
+		"checks":    checks,
 	}
 	
 	api.sendSuccess(w, health)
@@ -568,4 +719,26 @@ func (api *APIServer) sendError(w http.ResponseWriter, message string, statusCod
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
+}
+
+// sendValidationError reports all problems found by TestConfig.Validate, so
+// that the GUI can show them at once
+func (api *APIServer) sendValidationError(w http.ResponseWriter, err error) {
+	problems := internal.ValidationErrors(err)
+	response := APIResponse{
+		Success:   false,
+		Error:     fmt.Sprintf("Invalid configuration: %d problems", len(problems)),
+		Errors:    make([]string, len(problems)),
+		Timestamp: time.Now(),
+	}
+	if len(problems) == 1 {
+		response.Error = "Invalid configuration: " + problems[0].Error()
+	}
+	for i, problem := range problems {
+		response.Errors[i] = problem.Error()
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
 }
\ No newline at end of file
@@ -0,0 +1,287 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/server"
+)
+
+// startQUICServer runs a QUIC server with a self-signed certificate for the
+// duration of the test and returns its address
+func startQUICServer(t *testing.T) string {
+	t.Helper()
+	free, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.LocalAddr().String()
+	free.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := server.RunWithStats(ctx, internal.TestConfig{Addr: addr}, nil); err != nil {
+			t.Errorf("Server failed: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return addr
+}
+
+// createTest starts a test through POST /api/tests and returns its ID
+func createTest(t *testing.T, api *APIServer, body string) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/tests", strings.NewReader(body)))
+
+	response := struct {
+		Success bool         `json:"success"`
+		Error   string       `json:"error"`
+		Data    *TestSession `json:"data"`
+	}{}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Success {
+		t.Fatalf("Expected the test to start, got %d: %s", rec.Code, response.Error)
+	}
+	return response.Data.ID
+}
+
+// testStatus returns the status of a test under its lock
+func testStatus(api *APIServer, id string) string {
+	session := api.testManager.GetTest(id)
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	return session.Status
+}
+
+func TestParseTestConfigDuration(t *testing.T) {
+	api := NewAPIServer()
+	for name, tc := range map[string]struct {
+		raw  map[string]interface{}
+		want time.Duration
+	}{
+		"missing":        {map[string]interface{}{}, 60 * time.Second},
+		"empty":          {map[string]interface{}{"duration": ""}, 60 * time.Second},
+		"bounded":        {map[string]interface{}{"duration": "5s"}, 5 * time.Second},
+		"zero string":    {map[string]interface{}{"duration": "0s"}, 0},
+		"zero number":    {map[string]interface{}{"duration": float64(0)}, 0},
+		"unlimited flag": {map[string]interface{}{"duration": "5s", "unlimited": true}, 0},
+	} {
+		config, err := api.parseTestConfig(tc.raw)
+		if err != nil {
+			t.Fatalf("%s: parseTestConfig failed: %v", name, err)
+		}
+		if config.Duration != tc.want {
+			t.Errorf("%s: expected duration %v, got %v", name, tc.want, config.Duration)
+		}
+		if err := config.Validate(); err != nil {
+			t.Errorf("%s: expected a valid config, got %v", name, err)
+		}
+	}
+}
+
+func TestCreateTestReportsAllConfigProblems(t *testing.T) {
+	api := NewAPIServer()
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	body := `{"mode": "client", "connections": 0, "packet_size": 10, "fec_enabled": true, "fec_redundancy": 0.5}`
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/tests", strings.NewReader(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response APIResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Errors) != 3 {
+		t.Errorf("Expected all 3 problems at once, got %q", response.Errors)
+	}
+	if len(api.testManager.GetAllTests()) != 0 {
+		t.Error("Expected no test to start")
+	}
+}
+
+func TestBoundedTestCompletesViaAPI(t *testing.T) {
+	api := NewAPIServer()
+	id := createTest(t, api, `{"mode": "client", "duration": "1s"}`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testStatus(api, id) == "running" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the bounded test to complete")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if status := testStatus(api, id); status != "completed" {
+		t.Errorf("Expected status completed, got %s", status)
+	}
+}
+
+func TestUnlimitedTestRunsUntilStopped(t *testing.T) {
+	api := NewAPIServer()
+	id := createTest(t, api, `{"mode": "client", "unlimited": true}`)
+
+	// Past the first metrics tick the test must still be running
+	time.Sleep(1500 * time.Millisecond)
+	if status := testStatus(api, id); status != "running" {
+		t.Fatalf("Expected the unlimited test to keep running, got %s", status)
+	}
+
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/tests/"+id, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the stop to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if status := testStatus(api, id); status != "stopped" {
+		t.Errorf("Expected status stopped, got %s", status)
+	}
+}
+
+func TestServerListenFailureFailsTest(t *testing.T) {
+	taken, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	api := NewAPIServer()
+	id := createTest(t, api, fmt.Sprintf(`{"mode": "server", "addr": %q, "duration": "5s"}`, taken.LocalAddr()))
+
+	// The GUI process survives and the test reports the listen error
+	deadline := time.Now().Add(3 * time.Second)
+	for testStatus(api, id) == "running" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the server test to fail")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	session := api.testManager.GetTest(id)
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	if session.Status != "failed" {
+		t.Fatalf("Expected status failed, got %s", session.Status)
+	}
+	if last := session.Logs[len(session.Logs)-1]; !strings.Contains(last, "Server failed") {
+		t.Errorf("Expected the listen error in the logs, got %q", last)
+	}
+}
+
+// waitForThroughput waits for a metrics update that measured a whole second
+// at the given send rate and returns its throughput. The first update at a
+// new rate may still cover packets sent at the old one.
+func waitForThroughput(t *testing.T, api *APIServer, id string, rate int) float64 {
+	t.Helper()
+	session := api.testManager.GetTest(id)
+	firstSeen := -1.0
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		metrics := session.GetMetrics()
+		if metrics["rate_pps"] == rate {
+			elapsed := metrics["elapsed_seconds"].(float64)
+			if firstSeen < 0 {
+				firstSeen = elapsed
+			} else if elapsed > firstSeen {
+				return metrics["throughput_mbps"].(float64)
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Expected a metrics update at rate %d", rate)
+	return 0
+}
+
+func TestChangeRateMidRun(t *testing.T) {
+	api := NewAPIServer()
+	addr := startQUICServer(t)
+	id := createTest(t, api, fmt.Sprintf(`{"mode": "client", "addr": %q, "insecure": true, "unlimited": true, "rate": 100}`, addr))
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	patch := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("PATCH", "/api/tests/"+id, strings.NewReader(body)))
+		return rec
+	}
+
+	before := waitForThroughput(t, api, id, 100)
+	if rec := patch(`{"rate": 1000}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the rate change to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	after := waitForThroughput(t, api, id, 1000)
+	// Ten times the rate with at most ±10% noise on each side
+	if after < before*5 {
+		t.Errorf("Expected throughput to follow the rate, got %.1f Mbps before and %.1f after", before, after)
+	}
+
+	session := api.testManager.GetTest(id)
+	session.mu.RLock()
+	changes := append([]RateChange(nil), session.RateChanges...)
+	history := append([]MetricSample(nil), session.History...)
+	session.mu.RUnlock()
+	if len(changes) != 1 || changes[0].From != 100 || changes[0].To != 1000 {
+		t.Errorf("Expected one change from 100 to 1000, got %+v", changes)
+	}
+	if last := history[len(history)-1]; last.RatePps != 1000 {
+		t.Errorf("Expected the history to record the new rate, got %+v", last)
+	}
+
+	if rec := patch(`{"rate": 0}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a zero rate to be rejected, got %d", rec.Code)
+	}
+	if err := api.testManager.StopTest(id); err != nil {
+		t.Fatal(err)
+	}
+	if rec := patch(`{"rate": 500}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected a rate change on a stopped test to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestListTestsCountsByStatus(t *testing.T) {
+	api := NewAPIServer()
+	for i, status := range []string{"completed", "completed", "completed", "failed", "stopped", "running"} {
+		id := fmt.Sprintf("t%d", i)
+		addFinishedTest(api, id, 10)
+		api.testManager.activeTests[id].Status = status
+	}
+
+	var list struct {
+		Total   int              `json:"total"`
+		HasMore bool             `json:"has_more"`
+		Counts  TestStatusCounts `json:"counts"`
+	}
+	// Counts ignore the status filter
+	if code := getAPI(t, api, "/api/tests?status=completed&limit=2", &list); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	want := TestStatusCounts{Running: 1, Completed: 3, Failed: 1, Stopped: 1}
+	if list.Counts != want {
+		t.Errorf("Expected counts %+v, got %+v", want, list.Counts)
+	}
+	if list.Total != 3 || !list.HasMore {
+		t.Errorf("Expected 3 completed tests with more to fetch, got total %d, has_more %v", list.Total, list.HasMore)
+	}
+
+	if getAPI(t, api, "/api/tests?status=completed&limit=2&offset=2", &list); list.HasMore {
+		t.Error("Expected no more tests on the last page")
+	}
+}
@@ -0,0 +1,65 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ListenUnixSocket opens a unix domain socket listener for the API server.
+// Paths starting with "@" are treated as Linux abstract sockets and never
+// touch the filesystem. A stale socket file left behind by a crashed process
+// is removed before listening; the listener unlinks the file again on Close.
+func ListenUnixSocket(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty unix socket path")
+	}
+
+	if !isAbstractSocket(path) {
+		if info, err := os.Stat(path); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("refusing to replace non-socket file: %s", path)
+			}
+			if err := os.Remove(path); err != nil {
+				return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+			}
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %s: %w", path, err)
+	}
+
+	if !isAbstractSocket(path) {
+		// The control plane must not be reachable by other local users
+		if err := os.Chmod(path, 0600); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+		}
+	}
+
+	return listener, nil
+}
+
+// NewUnixSocketClient returns an HTTP client that sends every request to the
+// given unix socket regardless of the host in the request URL.
+func NewUnixSocketClient(path string, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+func isAbstractSocket(path string) bool {
+	return strings.HasPrefix(path, "@")
+}
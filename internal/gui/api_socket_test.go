@@ -0,0 +1,113 @@
+package gui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func startUnixAPIServer(t *testing.T) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := ListenUnixSocket(socketPath)
+	if err != nil {
+		t.Fatalf("ListenUnixSocket failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewAPIServer().RegisterRoutes(mux)
+	httpServer := &http.Server{Handler: mux}
+	go httpServer.Serve(listener)
+	t.Cleanup(func() { httpServer.Close() })
+
+	return socketPath
+}
+
+func TestAPIOverUnixSocket(t *testing.T) {
+	socketPath := startUnixAPIServer(t)
+
+	client := NewUnixSocketClient(socketPath, 5*time.Second)
+	resp, err := client.Get("http://unix/api/system/health")
+	if err != nil {
+		t.Fatalf("Request over unix socket failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var apiResponse APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !apiResponse.Success {
+		t.Errorf("Expected success response, got error: %s", apiResponse.Error)
+	}
+}
+
+func TestGUIProxyOverUnixSocket(t *testing.T) {
+	socketPath := startUnixAPIServer(t)
+
+	guiServer := NewServer(false)
+	guiServer.SetAPISocket(socketPath)
+
+	req := httptest.NewRequest("GET", "/api/tests", nil)
+	rr := httptest.NewRecorder()
+	guiServer.handleAPIProxy(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected proxied status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestGUIProxyReusesAPITransport(t *testing.T) {
+	socketPath := startUnixAPIServer(t)
+
+	guiServer := NewServer(false)
+	guiServer.SetAPISocket(socketPath)
+
+	first := guiServer.apiClient(5 * time.Second)
+	second := guiServer.apiClient(30 * time.Second)
+	if first.Transport != second.Transport {
+		t.Error("Expected proxied requests to share one transport")
+	}
+	if first.Timeout != 5*time.Second || second.Timeout != 30*time.Second {
+		t.Errorf("Expected per-request timeouts, got %v and %v", first.Timeout, second.Timeout)
+	}
+
+	guiServer.SetAPIAddr("127.0.0.1:8081")
+	if guiServer.apiClient(5*time.Second).Transport == first.Transport {
+		t.Error("Expected SetAPIAddr to replace the unix socket transport")
+	}
+}
+
+func TestListenUnixSocketCleanup(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "api.sock")
+
+	listener, err := ListenUnixSocket(socketPath)
+	if err != nil {
+		t.Fatalf("ListenUnixSocket failed: %v", err)
+	}
+	if _, err := os.Stat(socketPath); err != nil {
+		t.Fatalf("Expected socket file to exist: %v", err)
+	}
+
+	listener.Close()
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file to be removed on close, stat err: %v", err)
+	}
+
+	// A regular file at the socket path must not be clobbered
+	if err := os.WriteFile(socketPath, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenUnixSocket(socketPath); err == nil {
+		t.Error("Expected error when socket path is a regular file")
+	}
+}
@@ -0,0 +1,187 @@
+package gui
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TestSummary holds the averaged metrics of one test in a comparison
+type TestSummary struct {
+	ID                string    `json:"id"`
+	Status            string    `json:"status"`
+	Mode              string    `json:"mode"`
+	StartTime         time.Time `json:"start_time"`
+	DurationSeconds   float64   `json:"duration_seconds"`
+	Samples           int       `json:"samples"`
+	AvgLatencyMs      float64   `json:"avg_latency_ms"`
+	AvgThroughputMbps float64   `json:"avg_throughput_mbps"`
+	AvgPacketLoss     float64   `json:"avg_packet_loss_ratio"`
+}
+
+// MetricDelta is the difference of one metric between a test and the baseline
+type MetricDelta struct {
+	TestID       string   `json:"test_id"`
+	Metric       string   `json:"metric"`
+	Baseline     float64  `json:"baseline"`
+	Value        float64  `json:"value"`
+	Delta        float64  `json:"delta"`
+	DeltaPercent *float64 `json:"delta_percent,omitempty"` // Omitted when the baseline is zero
+}
+
+// TestComparison is the response of /api/tests/compare. The first requested
+// test is the baseline for all deltas.
+type TestComparison struct {
+	Baseline string        `json:"baseline"`
+	Tests    []TestSummary `json:"tests"`
+	Deltas   []MetricDelta `json:"deltas"`
+}
+
+// parseTestIDs splits a comma-separated list of test IDs, dropping blanks and
+// duplicates while keeping the order
+func parseTestIDs(raw string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(raw, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// handleCompareTests compares two or more finished tests: GET /api/tests/compare?ids=a,b
+func (api *APIServer) handleCompareTests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		api.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ids := parseTestIDs(r.URL.Query().Get("ids"))
+	if len(ids) < 2 {
+		api.sendError(w, "At least two test IDs required", http.StatusBadRequest)
+		return
+	}
+
+	summaries := make([]TestSummary, 0, len(ids))
+	for _, id := range ids {
+		session := api.testManager.GetTest(id)
+		if session == nil {
+			api.sendError(w, "Test not found: "+id, http.StatusNotFound)
+			return
+		}
+		summary := summarizeTest(session)
+		if summary.Status == "running" {
+			api.sendError(w, "Test is still running: "+id, http.StatusConflict)
+			return
+		}
+		summaries = append(summaries, summary)
+	}
+
+	api.sendSuccess(w, compareSummaries(summaries))
+}
+
+// summarizeTest averages the metric history of a test
+func summarizeTest(session *TestSession) TestSummary {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	summary := TestSummary{
+		ID:        session.ID,
+		Status:    session.Status,
+		Mode:      session.Config.Mode,
+		StartTime: session.StartTime,
+	}
+	if session.EndTime != nil {
+		summary.DurationSeconds = session.elapsedLocked(*session.EndTime).Seconds()
+	}
+
+	// Aggregated points count with the weight of their raw samples
+	for _, sample := range session.History {
+		w := float64(sample.weight())
+		summary.Samples += sample.weight()
+		summary.AvgLatencyMs += sample.LatencyMs * w
+		summary.AvgThroughputMbps += sample.ThroughputMbps * w
+		summary.AvgPacketLoss += sample.PacketLoss * w
+	}
+	if n := float64(summary.Samples); n > 0 {
+		summary.AvgLatencyMs /= n
+		summary.AvgThroughputMbps /= n
+		summary.AvgPacketLoss /= n
+	}
+
+	return summary
+}
+
+// compareSummaries builds the delta table of every test against the first one
+func compareSummaries(summaries []TestSummary) TestComparison {
+	comparison := TestComparison{
+		Baseline: summaries[0].ID,
+		Tests:    summaries,
+		Deltas:   []MetricDelta{},
+	}
+
+	metrics := []struct {
+		name  string
+		value func(TestSummary) float64
+	}{
+		{"avg_latency_ms", func(s TestSummary) float64 { return s.AvgLatencyMs }},
+		{"avg_throughput_mbps", func(s TestSummary) float64 { return s.AvgThroughputMbps }},
+		{"avg_packet_loss_ratio", func(s TestSummary) float64 { return s.AvgPacketLoss }},
+		{"duration_seconds", func(s TestSummary) float64 { return s.DurationSeconds }},
+	}
+
+	baseline := summaries[0]
+	for _, test := range summaries[1:] {
+		for _, metric := range metrics {
+			base, value := metric.value(baseline), metric.value(test)
+			delta := MetricDelta{
+				TestID:   test.ID,
+				Metric:   metric.name,
+				Baseline: base,
+				Value:    value,
+				Delta:    value - base,
+			}
+			if base != 0 {
+				percent := (value - base) / base * 100
+				delta.DeltaPercent = &percent
+			}
+			comparison.Deltas = append(comparison.Deltas, delta)
+		}
+	}
+
+	return comparison
+}
+
+// downsampleHistory merges the samples in every step-long window of elapsed
+// time into one point at the window's first sample, as mergeSamples does
+func downsampleHistory(history []MetricSample, step time.Duration) []MetricSample {
+	result := make([]MetricSample, 0, len(history))
+	lastWindow := int64(-1)
+	for _, sample := range history {
+		window := int64(sample.ElapsedSeconds / step.Seconds())
+		if window == lastWindow {
+			result[len(result)-1] = mergeSamples(result[len(result)-1], sample)
+			continue
+		}
+		lastWindow = window
+		result = append(result, sample)
+	}
+	return result
+}
+
+// historyBetween keeps the samples taken within [from, to]; a zero bound
+// leaves that side open
+func historyBetween(history []MetricSample, from, to time.Time) []MetricSample {
+	result := make([]MetricSample, 0, len(history))
+	for _, sample := range history {
+		if (!from.IsZero() && sample.Timestamp.Before(from)) || (!to.IsZero() && sample.Timestamp.After(to)) {
+			continue
+		}
+		result = append(result, sample)
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+package gui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+// addFinishedTest registers a completed test with one sample per second
+func addFinishedTest(api *APIServer, id string, latencies ...float64) {
+	start := time.Now().Add(-time.Hour)
+	end := start.Add(time.Duration(len(latencies)) * time.Second)
+	session := &TestSession{
+		ID:        id,
+		Config:    internal.TestConfig{Mode: "client"},
+		Status:    "completed",
+		StartTime: start,
+		EndTime:   &end,
+		Metrics:   make(map[string]interface{}),
+	}
+	for i, latency := range latencies {
+		elapsed := time.Duration(i+1) * time.Second
+		session.History = append(session.History, MetricSample{
+			Timestamp:      start.Add(elapsed),
+			ElapsedSeconds: elapsed.Seconds(),
+			LatencyMs:      latency,
+			ThroughputMbps: 100,
+		})
+	}
+	api.testManager.activeTests[id] = session
+}
+
+func getAPI(t *testing.T, api *APIServer, url string, data interface{}) int {
+	t.Helper()
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+
+	response := struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode %s: %v", url, err)
+	}
+	if response.Success && data != nil {
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			t.Fatalf("Failed to decode data of %s: %v", url, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestCompareTests(t *testing.T) {
+	api := NewAPIServer()
+	addFinishedTest(api, "base", 40, 60)
+	addFinishedTest(api, "short", 25)
+	addFinishedTest(api, "long", 50, 50, 50, 50)
+
+	var comparison TestComparison
+	if code := getAPI(t, api, "/api/tests/compare?ids=base,short,long,short", &comparison); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if comparison.Baseline != "base" || len(comparison.Tests) != 3 {
+		t.Fatalf("Expected baseline and 3 deduplicated tests, got %+v", comparison)
+	}
+	if comparison.Tests[2].DurationSeconds != 4 || comparison.Tests[2].Samples != 4 {
+		t.Errorf("Unexpected summary of the long test: %+v", comparison.Tests[2])
+	}
+
+	deltas := make(map[string]MetricDelta)
+	for _, d := range comparison.Deltas {
+		deltas[d.TestID+"/"+d.Metric] = d
+	}
+	if len(deltas) != 8 {
+		t.Fatalf("Expected 4 metrics for each of 2 tests, got %+v", comparison.Deltas)
+	}
+	latency := deltas["short/avg_latency_ms"]
+	if latency.Baseline != 50 || latency.Value != 25 || latency.Delta != -25 ||
+		latency.DeltaPercent == nil || *latency.DeltaPercent != -50 {
+		t.Errorf("Unexpected latency delta: %+v", latency)
+	}
+	if loss := deltas["long/avg_packet_loss_ratio"]; loss.DeltaPercent != nil {
+		t.Errorf("Expected no percentage against a zero baseline, got %v", *loss.DeltaPercent)
+	}
+}
+
+func TestCompareTestsRejectsInvalidSelection(t *testing.T) {
+	api := NewAPIServer()
+	addFinishedTest(api, "a", 10)
+	addFinishedTest(api, "b", 20)
+	api.testManager.activeTests["b"].Status = "running"
+
+	for url, want := range map[string]int{
+		"/api/tests/compare?ids=a":         http.StatusBadRequest,
+		"/api/tests/compare?ids=a,a":       http.StatusBadRequest,
+		"/api/tests/compare?ids=a,missing": http.StatusNotFound,
+		"/api/tests/compare?ids=a,b":       http.StatusConflict,
+	} {
+		if code := getAPI(t, api, url, nil); code != want {
+			t.Errorf("%s: expected status %d, got %d", url, want, code)
+		}
+	}
+}
+
+func TestHistoricalMetricsForTest(t *testing.T) {
+	api := NewAPIServer()
+	addFinishedTest(api, "a", 10, 20, 30, 40, 50)
+
+	var history struct {
+		Metrics []MetricSample `json:"metrics"`
+	}
+	if code := getAPI(t, api, "/api/metrics/history?test_id=a&interval=2s", &history); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	// Samples at 1..5s fall into the 2s windows [0,2), [2,4), [4,6)
+	var got []float64
+	for _, sample := range history.Metrics {
+		got = append(got, sample.ElapsedSeconds)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 4 {
+		t.Fatalf("Expected samples at 1s, 2s and 4s, got %v", got)
+	}
+	// Each window averages its samples
+	for i, want := range []float64{10, 25, 45} {
+		if history.Metrics[i].LatencyMs != want {
+			t.Errorf("Window %d: expected average latency %v, got %v", i, want, history.Metrics[i].LatencyMs)
+		}
+	}
+	if history.Metrics[1].Count != 2 || history.Metrics[1].LatencyMaxMs != 30 {
+		t.Errorf("Expected the second window to merge 2 samples up to 30 ms, got %+v", history.Metrics[1])
+	}
+
+	// Only samples within the bounds are returned
+	samples := api.testManager.GetTest("a").GetHistory()
+	from := samples[1].Timestamp.UTC().Format(time.RFC3339Nano)
+	to := samples[2].Timestamp.UTC().Format(time.RFC3339Nano)
+	url := "/api/metrics/history?test_id=a&interval=1s&start_time=" + from + "&end_time=" + to
+	if code := getAPI(t, api, url, &history); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(history.Metrics) != 2 || history.Metrics[0].LatencyMs != 20 || history.Metrics[1].LatencyMs != 30 {
+		t.Errorf("Expected the samples at 2s and 3s, got %+v", history.Metrics)
+	}
+
+	for url, want := range map[string]int{
+		"/api/metrics/history?test_id=missing":                http.StatusNotFound,
+		"/api/metrics/history":                                http.StatusBadRequest,
+		"/api/metrics/history?test_id=a&start_time=yesterday": http.StatusBadRequest,
+		"/api/metrics/history?test_id=a&interval=-1s":         http.StatusBadRequest,
+	} {
+		if code := getAPI(t, api, url, nil); code != want {
+			t.Errorf("%s: expected status %d, got %d", url, want, code)
+		}
+	}
+}
+
+func TestUpdateMetricsRecordsHistory(t *testing.T) {
+	session := &TestSession{StartTime: time.Now(), Metrics: make(map[string]interface{})}
+	session.updateMetrics(map[string]interface{}{"connections": 0, "uptime_seconds": 1.0})
+	session.updateMetrics(map[string]interface{}{"latency_ms": 12.5, "throughput_mbps": 80.0, "packet_loss_ratio": 0.02})
+
+	history := session.GetHistory()
+	if len(history) != 1 {
+		t.Fatalf("Expected only the client update in the history, got %+v", history)
+	}
+	if history[0].LatencyMs != 12.5 || history[0].ThroughputMbps != 80 || history[0].PacketLoss != 0.02 {
+		t.Errorf("Unexpected sample: %+v", history[0])
+	}
+}
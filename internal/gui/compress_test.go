@@ -0,0 +1,101 @@
+package gui
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func compressedAPI() http.Handler {
+	mux := http.NewServeMux()
+	NewAPIServer().RegisterRoutes(mux)
+	return CompressResponses(mux)
+}
+
+func TestCompressResponsesGzipRoundTrip(t *testing.T) {
+	handler := compressedAPI()
+
+	plain := httptest.NewRecorder()
+	handler.ServeHTTP(plain, httptest.NewRequest("GET", "/api/metrics/schema", nil))
+	if enc := plain.Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("Expected an uncompressed response without Accept-Encoding, got %q", enc)
+	}
+
+	req := httptest.NewRequest("GET", "/api/metrics/schema", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if enc := rec.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("Expected Content-Encoding gzip, got %q", enc)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected the JSON content type to be kept, got %q", ct)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Response is not gzip: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to decompress the response: %v", err)
+	}
+
+	var compressed, uncompressed APIResponse
+	if err := json.Unmarshal(body, &compressed); err != nil {
+		t.Fatalf("Decompressed body is not JSON: %v", err)
+	}
+	if err := json.Unmarshal(plain.Body.Bytes(), &uncompressed); err != nil {
+		t.Fatal(err)
+	}
+	if !compressed.Success {
+		t.Error("Expected a successful response")
+	}
+	a, _ := json.Marshal(compressed.Data)
+	b, _ := json.Marshal(uncompressed.Data)
+	if string(a) != string(b) {
+		t.Error("Compressed and plain responses carry different data")
+	}
+}
+
+func TestCompressResponsesDeflate(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/system/health", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0, deflate")
+	rec := httptest.NewRecorder()
+	compressedAPI().ServeHTTP(rec, req)
+
+	if enc := rec.Header().Get("Content-Encoding"); enc != "deflate" {
+		t.Fatalf("Expected Content-Encoding deflate, got %q", enc)
+	}
+	zr, err := zlib.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Response is not deflate: %v", err)
+	}
+	var resp APIResponse
+	if err := json.NewDecoder(zr).Decode(&resp); err != nil {
+		t.Fatalf("Decompressed body is not JSON: %v", err)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"br":                "",
+		"gzip":              "gzip",
+		"deflate, gzip":     "gzip",
+		"GZIP;q=0.5":        "gzip",
+		"gzip;q=0":          "",
+		"gzip;q=0, deflate": "deflate",
+		"*":                 "gzip",
+		"identity, deflate": "deflate",
+	}
+	for header, want := range tests {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
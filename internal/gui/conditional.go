@@ -0,0 +1,57 @@
+package gui
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sendCacheable sends data like sendSuccess, adding an ETag computed from
+// the serialized data and, when known, a Last-Modified. A request whose
+// If-None-Match matches the ETag gets 304 Not Modified without a body, so
+// dashboards polling an unchanged resource cost neither bandwidth nor
+// re-encoding on the client.
+//
+// The ETag is weak: the same data is equivalent whether it is sent plain or
+// compressed. The response timestamp is not part of it, since it changes on
+// every request.
+func (api *APIServer) sendCacheable(w http.ResponseWriter, r *http.Request, data interface{}, lastModified time.Time) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		api.sendError(w, "Failed to encode response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(payload)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "no-cache") // cache, but revalidate on every poll
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	api.sendSuccess(w, json.RawMessage(payload))
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. As
+// required for If-None-Match, weak and strong tags compare equal.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package gui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// conditionalGet requests url through the compressing API handler, as the
+// dashboard does, optionally with If-None-Match
+func conditionalGet(t *testing.T, handler http.Handler, url, etag string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPollingUnchangedTestsReturnsNotModified(t *testing.T) {
+	api := NewAPIServer()
+	addFinishedTest(api, "a", 10, 11)
+	addFinishedTest(api, "b", 20, 21)
+	api.testManager.activeTests["b"].updateMetrics(map[string]interface{}{"connections": 1})
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	handler := CompressResponses(mux)
+
+	for _, url := range []string{"/api/tests", "/api/tests?status=completed", "/api/tests/b"} {
+		first := conditionalGet(t, handler, url, "")
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: expected 200 with an ETag, got %d %q", url, first.Code, etag)
+		}
+		lastModified, err := http.ParseTime(first.Header().Get("Last-Modified"))
+		if err != nil || time.Since(lastModified) > time.Minute {
+			t.Errorf("%s: expected Last-Modified of the latest update, got %q", url, first.Header().Get("Last-Modified"))
+		}
+
+		// Repeated polls of unchanged data are answered without a body,
+		// although the response envelope carries a fresh timestamp
+		for i := 0; i < 3; i++ {
+			rec := conditionalGet(t, handler, url, etag)
+			if rec.Code != http.StatusNotModified {
+				t.Fatalf("%s: expected 304 on poll %d, got %d", url, i+1, rec.Code)
+			}
+			if rec.Body.Len() != 0 || rec.Header().Get("Content-Encoding") != "" {
+				t.Errorf("%s: expected an empty, unencoded 304, got %d bytes (%q)", url, rec.Body.Len(), rec.Header().Get("Content-Encoding"))
+			}
+			if rec.Header().Get("ETag") != etag {
+				t.Errorf("%s: expected the 304 to repeat the ETag %s, got %s", url, etag, rec.Header().Get("ETag"))
+			}
+		}
+		if rec := conditionalGet(t, handler, url, `"other", `+etag); rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 when the ETag is one of several, got %d", url, rec.Code)
+		}
+	}
+
+	// A changed test invalidates both its own ETag and the collection's
+	before := conditionalGet(t, handler, "/api/tests", "").Header().Get("ETag")
+	beforeTest := conditionalGet(t, handler, "/api/tests/b", "").Header().Get("ETag")
+	api.testManager.activeTests["b"].updateMetrics(map[string]interface{}{"connections": 2})
+	if rec := conditionalGet(t, handler, "/api/tests", before); rec.Code != http.StatusOK || rec.Header().Get("ETag") == before {
+		t.Errorf("Expected 200 with a new ETag after a change, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := conditionalGet(t, handler, "/api/tests/b", beforeTest); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for the changed test, got %d", rec.Code)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	for header, want := range map[string]bool{
+		"":               false,
+		`W/"abc"`:        true,
+		`"abc"`:          true,
+		`"x", W/"abc"`:   true,
+		"*":              true,
+		`"abcd"`:         false,
+		`W/"ab", "abc "`: false,
+	} {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// TestServingTestsWhileMetricsUpdate: a test is encoded from a copy taken
+// under its lock, so serving it races neither the metrics nor the logs
+// its run keeps writing
+func TestServingTestsWhileMetricsUpdate(t *testing.T) {
+	api := NewAPIServer()
+	addFinishedTest(api, "a", 10)
+	session := api.testManager.activeTests["a"]
+	session.Status = "running"
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			session.updateMetrics(map[string]interface{}{fmt.Sprintf("metric_%d", i%64): i})
+			session.addLogSafe(fmt.Sprintf("update %d", i))
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		for _, url := range []string{"/api/tests/a", "/api/tests"} {
+			if rec := conditionalGet(t, mux, url, ""); rec.Code != http.StatusOK {
+				t.Fatalf("Expected %s to be served, got %d", url, rec.Code)
+			}
+		}
+	}
+	close(stop)
+	<-done
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TestSession
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != "a" || decoded.Status != "running" || len(decoded.Metrics) == 0 || len(decoded.Logs) == 0 {
+		t.Errorf("Expected the session fields in its JSON, got %s", data)
+	}
+}
@@ -0,0 +1,98 @@
+package gui
+
+import (
+	"time"
+
+	"quic-test/internal"
+)
+
+// Sources of the final metrics
+const (
+	finalFromResult  = "client_result" // the client's own measurements of the whole run
+	finalFromHistory = "history"       // the per-interval history up to the end
+)
+
+// FinalMetrics summarizes a finished test. A client run that completed in
+// one piece is summarized from the client's result; a stopped or resumed
+// run, or one without a client, from the metric history over the period
+// it actually ran, so it still has usable results.
+type FinalMetrics struct {
+	DurationSeconds   float64 `json:"duration_seconds"`
+	Samples           int     `json:"samples"`
+	AvgLatencyMs      float64 `json:"avg_latency_ms"`
+	MinLatencyMs      float64 `json:"min_latency_ms"`
+	MaxLatencyMs      float64 `json:"max_latency_ms"`
+	AvgThroughputMbps float64 `json:"avg_throughput_mbps"`
+	AvgPacketLoss     float64 `json:"avg_packet_loss_ratio"`
+	Partial           bool    `json:"partial"` // Stopped before the configured duration
+	Source            string  `json:"source"`  // client_result or history
+}
+
+// finalizeLocked computes the final metrics and the report. The caller
+// holds ts.mu and has set EndTime.
+func (ts *TestSession) finalizeLocked() {
+	end := time.Now()
+	if ts.EndTime != nil {
+		end = *ts.EndTime
+	}
+
+	final := &FinalMetrics{
+		DurationSeconds: ts.elapsedLocked(end).Seconds(),
+		Partial:         ts.Status == "stopped",
+	}
+	latencies := make([]float64, 0, len(ts.History))
+	var latencySum, throughputSum, lossSum float64
+	for _, sample := range ts.History {
+		// A sample can land between the stop and the end of the run
+		if sample.Timestamp.After(end) {
+			continue
+		}
+		if len(latencies) == 0 || sample.latencyMin() < final.MinLatencyMs {
+			final.MinLatencyMs = sample.latencyMin()
+		}
+		if sample.latencyMax() > final.MaxLatencyMs {
+			final.MaxLatencyMs = sample.latencyMax()
+		}
+		// Aggregated points count with the weight of their raw samples
+		w := float64(sample.weight())
+		latencies = append(latencies, sample.LatencyMs)
+		final.Samples += sample.weight()
+		latencySum += sample.LatencyMs * w
+		throughputSum += sample.ThroughputMbps * w
+		lossSum += sample.PacketLoss * w
+	}
+	if final.Samples > 0 {
+		n := float64(final.Samples)
+		final.AvgLatencyMs = latencySum / n
+		final.AvgThroughputMbps = throughputSum / n
+		final.AvgPacketLoss = lossSum / n
+	}
+	ts.FinalMetrics = final
+
+	var report internal.ReportSchema
+	if ts.result != nil && ts.Status == "completed" && len(ts.Resumes) == 0 {
+		// The client measured every packet of the run, not one point a second
+		report = internal.CreateReportSchema(ts.Config, ts.result.Metrics)
+		// Success in the client's metrics is the count of delivered packets
+		report.Metrics.Success = ts.result.Success > 0
+		final.Source = finalFromResult
+		final.AvgLatencyMs = report.Metrics.Latency.Average
+		final.MinLatencyMs = report.Metrics.Latency.Min
+		final.MaxLatencyMs = report.Metrics.Latency.Max
+		final.AvgThroughputMbps = report.Metrics.ThroughputMbps
+		final.AvgPacketLoss = report.Metrics.PacketLoss / 100
+	} else {
+		final.Source = finalFromHistory
+		report = internal.CreateReportSchema(ts.Config, map[string]interface{}{
+			"Success":        final.Samples > 0,
+			"Latencies":      latencies,
+			"ThroughputMbps": final.AvgThroughputMbps,
+			"PacketLoss":     final.AvgPacketLoss,
+		})
+	}
+	report.Metadata["partial"] = final.Partial
+	report.Metadata["elapsed_seconds"] = final.DurationSeconds
+	report.Metadata["final_metrics_source"] = final.Source
+	ts.Report = &report
+	ts.updated = time.Now()
+}
@@ -0,0 +1,101 @@
+package gui
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+func TestStoppedTestHasFinalMetricsAndReport(t *testing.T) {
+	api := NewAPIServer()
+	addr := startQUICServer(t)
+	id := createTest(t, api, fmt.Sprintf(`{"mode": "client", "addr": %q, "insecure": true, "unlimited": true}`, addr))
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+
+	// Past the first metrics tick there is history to aggregate
+	time.Sleep(1500 * time.Millisecond)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/tests/"+id, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the stop to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Finalization runs when the test goroutine notices the stop
+	var session *TestSession
+	deadline := time.Now().Add(2 * time.Second)
+	for session == nil || session.FinalMetrics == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the stopped test to be finalized")
+		}
+		time.Sleep(50 * time.Millisecond)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tests/"+id, nil))
+		response := struct {
+			Data *TestSession `json:"data"`
+		}{}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		session = response.Data
+	}
+
+	final := session.FinalMetrics
+	if session.Status != "stopped" || !final.Partial {
+		t.Errorf("Expected a partial result of a stopped test, got status %s, %+v", session.Status, final)
+	}
+	if final.Samples < 1 || final.AvgThroughputMbps <= 0 || final.AvgLatencyMs <= 0 {
+		t.Errorf("Expected aggregated metrics over the elapsed period, got %+v", final)
+	}
+	if final.DurationSeconds < 1.5 {
+		t.Errorf("Expected the elapsed period of at least 1.5s, got %.2fs", final.DurationSeconds)
+	}
+	if session.Report == nil {
+		t.Fatal("Expected a report for the stopped test")
+	}
+	if session.Report.Metrics.ThroughputMbps != final.AvgThroughputMbps || math.Abs(session.Report.Metrics.Latency.Average-final.AvgLatencyMs) > 1e-9 {
+		t.Errorf("Report does not match the final metrics: %+v vs %+v", session.Report.Metrics, final)
+	}
+	if final.Source != finalFromHistory {
+		t.Errorf("Expected a stopped test to be summarized from its history, got %s", final.Source)
+	}
+	if session.Report.Metadata["partial"] != true {
+		t.Errorf("Expected the report to be marked partial, got %v", session.Report.Metadata)
+	}
+}
+
+func TestCompletedTestReportsClientResult(t *testing.T) {
+	tm := NewTestManager()
+	addr := startQUICServer(t)
+	session := tm.StartTest(internal.TestConfig{Mode: "client", Addr: addr, InsecureSkipVerify: true, Duration: 1500 * time.Millisecond, Rate: 100, PacketSize: 1200, Connections: 1, Streams: 1})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		session.mu.RLock()
+		final, report, result := session.FinalMetrics, session.Report, session.result
+		session.mu.RUnlock()
+		if final != nil {
+			if final.Source != finalFromResult || result == nil {
+				t.Fatalf("Expected a completed test to be summarized from the client result, got %+v", final)
+			}
+			m := report.Metrics
+			if !m.Success || fmt.Sprint(m.BytesSent) != fmt.Sprint(result.Metrics["BytesSent"]) || m.BytesSent == 0 {
+				t.Errorf("Expected the report to carry the client's byte count, got success=%v bytes=%d (result %v)", m.Success, m.BytesSent, result.Metrics["BytesSent"])
+			}
+			if m.Latency.P95 <= 0 || final.AvgLatencyMs != m.Latency.Average || final.AvgThroughputMbps != m.ThroughputMbps {
+				t.Errorf("Expected the final metrics to come from the client's measurements, got %+v vs %+v", final, m.Latency)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the test to complete")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,224 @@
+package gui
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"quic-test/internal"
+)
+
+// Sub-check results reported by /api/system/health. A degraded result is
+// followed by the reason, e.g. "degraded: 2048 open FEC groups".
+const (
+	checkOK       = "ok"
+	checkDisabled = "disabled" // the feature is not active in this process
+	checkDegraded = "degraded"
+)
+
+const (
+	// fecOpenGroupsDegraded is the number of open FEC groups treated as a
+	// leak. Healthy decoders complete or expire groups within seconds, so
+	// only a handful are open per stream.
+	fecOpenGroupsDegraded = 1024
+	// fecMemoryDegraded is the FEC decoder memory treated as a leak
+	fecMemoryDegraded = 64 << 20
+	// fecGrowthChecks is how many consecutive health checks open FEC groups
+	// may grow before the growth is treated as unbounded
+	fecGrowthChecks = 5
+
+	// emulationMinPackets is how many packets the emulation must have seen
+	// before its applied drop rate is compared with the configured one
+	emulationMinPackets = 1000
+	// emulationLossTolerance is the allowed absolute difference between the
+	// applied and the configured drop rate
+	emulationLossTolerance = 0.05
+
+	// prometheusCheckTimeout bounds the exporter reachability check
+	prometheusCheckTimeout = 2 * time.Second
+)
+
+// FECHealthStats is the state of the FEC decoders in use
+type FECHealthStats struct {
+	OpenGroups  int64 // groups waiting for packets or repair data
+	MemoryBytes int64 // data held by the open groups
+}
+
+// EmulationHealthStats is the state of network emulation
+type EmulationHealthStats struct {
+	ConfiguredLoss float64 // drop probability, 0..1
+	Packets        int64   // packets that went through the emulation
+	Dropped        int64
+	Duplicated     int64
+}
+
+// HealthSources are the live components inspected by /api/system/health.
+// A nil source or an empty URL reports the sub-check as disabled.
+type HealthSources struct {
+	FEC           func() FECHealthStats
+	Emulation     func() EmulationHealthStats
+	PrometheusURL string // exporter /metrics URL checked for reachability
+}
+
+// healthMonitor runs the sub-checks and keeps the history needed to spot
+// FEC groups that grow without bound
+type healthMonitor struct {
+	mu             sync.Mutex
+	sources        HealthSources
+	client         *http.Client
+	lastFECGroups  int64
+	fecGrowthCount int
+}
+
+func newHealthMonitor() *healthMonitor {
+	return &healthMonitor{client: internal.LocalHTTPClient(prometheusCheckTimeout)}
+}
+
+// SetHealthSources sets the components inspected by /api/system/health
+func (api *APIServer) SetHealthSources(sources HealthSources) {
+	api.health.mu.Lock()
+	defer api.health.mu.Unlock()
+	api.health.sources = sources
+	api.health.lastFECGroups, api.health.fecGrowthCount = 0, 0
+}
+
+// FECHealth sums the FEC decoder state of the servers of running tests
+func (tm *TestManager) FECHealth() FECHealthStats {
+	var total FECHealthStats
+	for _, session := range tm.GetAllTests() {
+		session.mu.RLock()
+		if session.Status == "running" {
+			total.OpenGroups += session.fecHealth.OpenGroups
+			total.MemoryBytes += session.fecHealth.MemoryBytes
+		}
+		session.mu.RUnlock()
+	}
+	return total
+}
+
+// EmulationHealth sums the emulation of the clients of running tests. The
+// configured loss is averaged over their packets, so it compares with the
+// summed drops.
+func (tm *TestManager) EmulationHealth() EmulationHealthStats {
+	var total EmulationHealthStats
+	var configuredDrops float64
+	for _, session := range tm.GetAllTests() {
+		session.mu.RLock()
+		if session.Status == "running" {
+			stats := session.emulationHealth
+			total.Packets += stats.Packets
+			total.Dropped += stats.Dropped
+			total.Duplicated += stats.Duplicated
+			configuredDrops += stats.ConfiguredLoss * float64(stats.Packets)
+		}
+		session.mu.RUnlock()
+	}
+	if total.Packets > 0 {
+		total.ConfiguredLoss = configuredDrops / float64(total.Packets)
+	}
+	return total
+}
+
+// FECHealth is the FEC health source of the tests run by the API server
+func (api *APIServer) FECHealth() FECHealthStats {
+	return api.testManager.FECHealth()
+}
+
+// EmulationHealth is the emulation health source of the tests run by the
+// API server
+func (api *APIServer) EmulationHealth() EmulationHealthStats {
+	return api.testManager.EmulationHealth()
+}
+
+// setFECHealth records the FEC decoder state of the test's server
+func (ts *TestSession) setFECHealth(stats FECHealthStats) {
+	ts.mu.Lock()
+	ts.fecHealth = stats
+	ts.mu.Unlock()
+}
+
+// setEmulationHealth records the emulation state of the test's client
+func (ts *TestSession) setEmulationHealth(stats EmulationHealthStats) {
+	ts.mu.Lock()
+	ts.emulationHealth = stats
+	ts.mu.Unlock()
+}
+
+// check runs all sub-checks. The overall status is "degraded" when any
+// sub-check is degraded.
+func (h *healthMonitor) check() (string, map[string]string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	checks := map[string]string{
+		"api_server":   checkOK,
+		"test_manager": checkOK,
+		"fec_decoder":  h.checkFEC(),
+		"emulation":    h.checkEmulation(),
+		"prometheus":   h.checkPrometheus(),
+	}
+	status := "healthy"
+	for _, result := range checks {
+		if result != checkOK && result != checkDisabled {
+			status = checkDegraded
+		}
+	}
+	return status, checks
+}
+
+func (h *healthMonitor) checkFEC() string {
+	if h.sources.FEC == nil {
+		return checkDisabled
+	}
+	stats := h.sources.FEC()
+
+	if stats.OpenGroups > h.lastFECGroups {
+		h.fecGrowthCount++
+	} else {
+		h.fecGrowthCount = 0
+	}
+	h.lastFECGroups = stats.OpenGroups
+
+	switch {
+	case stats.OpenGroups >= fecOpenGroupsDegraded:
+		return fmt.Sprintf("%s: %d open FEC groups", checkDegraded, stats.OpenGroups)
+	case stats.MemoryBytes >= fecMemoryDegraded:
+		return fmt.Sprintf("%s: FEC decoders hold %d bytes", checkDegraded, stats.MemoryBytes)
+	case h.fecGrowthCount >= fecGrowthChecks:
+		return fmt.Sprintf("%s: open FEC groups grew for %d checks in a row (%d now)", checkDegraded, h.fecGrowthCount, stats.OpenGroups)
+	}
+	return checkOK
+}
+
+func (h *healthMonitor) checkEmulation() string {
+	if h.sources.Emulation == nil {
+		return checkDisabled
+	}
+	stats := h.sources.Emulation()
+	if stats.Packets < emulationMinPackets {
+		return checkOK
+	}
+	applied := float64(stats.Dropped) / float64(stats.Packets)
+	if math.Abs(applied-stats.ConfiguredLoss) > emulationLossTolerance {
+		return fmt.Sprintf("%s: emulation drops %.1f%% of packets, configured %.1f%%",
+			checkDegraded, applied*100, stats.ConfiguredLoss*100)
+	}
+	return checkOK
+}
+
+func (h *healthMonitor) checkPrometheus() string {
+	if h.sources.PrometheusURL == "" {
+		return checkDisabled
+	}
+	resp, err := h.client.Get(h.sources.PrometheusURL)
+	if err != nil {
+		return fmt.Sprintf("%s: exporter unreachable: %v", checkDegraded, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("%s: exporter answered %s", checkDegraded, resp.Status)
+	}
+	return checkOK
+}
@@ -0,0 +1,177 @@
+package gui
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"quic-test/internal/fec"
+)
+
+// getHealth calls /api/system/health and returns its status and checks
+func getHealth(t *testing.T, api *APIServer) (string, map[string]string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/system/health", nil))
+
+	response := struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Status string            `json:"status"`
+			Checks map[string]string `json:"checks"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || !response.Success {
+		t.Fatalf("Expected a successful response, got %d", rec.Code)
+	}
+	return response.Data.Status, response.Data.Checks
+}
+
+// decoderSource reports the state of a single FEC decoder
+func decoderSource(d *fec.FECDecoder) func() FECHealthStats {
+	return func() FECHealthStats {
+		return FECHealthStats{
+			OpenGroups:  d.GetMetrics().GroupsActive,
+			MemoryBytes: d.BufferedBytes(),
+		}
+	}
+}
+
+func TestHealthWithoutSources(t *testing.T) {
+	status, checks := getHealth(t, NewAPIServer())
+	if status != "healthy" {
+		t.Errorf("Expected healthy, got %q", status)
+	}
+	for _, name := range []string{"fec_decoder", "emulation", "prometheus"} {
+		if checks[name] != checkDisabled {
+			t.Errorf("Expected %s to be disabled, got %q", name, checks[name])
+		}
+	}
+	if checks["api_server"] != checkOK || checks["test_manager"] != checkOK {
+		t.Errorf("Expected the API checks to be ok, got %v", checks)
+	}
+}
+
+func TestHealthDegradesOnFECLeak(t *testing.T) {
+	api := NewAPIServer()
+	decoder := fec.NewFECDecoder()
+	api.SetHealthSources(HealthSources{FEC: decoderSource(decoder)})
+
+	// A complete group is recovered or expired, only a few stay open
+	for id := uint64(0); id < 4; id++ {
+		decoder.AddPacket(make([]byte, 100), id, 0)
+	}
+	if status, checks := getHealth(t, api); status != "healthy" || checks["fec_decoder"] != checkOK {
+		t.Fatalf("Expected healthy FEC, got %q %v", status, checks)
+	}
+
+	// Simulated leak: every group gets one packet and never completes
+	for group := uint64(1); group <= fecOpenGroupsDegraded; group++ {
+		decoder.AddPacket(make([]byte, 100), 0, group)
+	}
+	status, checks := getHealth(t, api)
+	if status != checkDegraded {
+		t.Errorf("Expected degraded, got %q", status)
+	}
+	if !strings.HasPrefix(checks["fec_decoder"], checkDegraded+":") {
+		t.Errorf("Expected fec_decoder to be degraded, got %q", checks["fec_decoder"])
+	}
+	if checks["api_server"] != checkOK {
+		t.Errorf("Expected the other checks to stay ok, got %v", checks)
+	}
+}
+
+func TestHealthDegradesOnSteadyFECGrowth(t *testing.T) {
+	api := NewAPIServer()
+	decoder := fec.NewFECDecoder()
+	api.SetHealthSources(HealthSources{FEC: decoderSource(decoder)})
+
+	// Far below the open group limit, but growing on every check
+	var checks map[string]string
+	for group := uint64(0); group < fecGrowthChecks; group++ {
+		decoder.AddPacket(make([]byte, 100), 0, group)
+		_, checks = getHealth(t, api)
+	}
+	if !strings.HasPrefix(checks["fec_decoder"], checkDegraded+":") {
+		t.Errorf("Expected steady growth to degrade fec_decoder, got %q", checks["fec_decoder"])
+	}
+
+	// Growth stopped
+	if _, checks = getHealth(t, api); checks["fec_decoder"] != checkOK {
+		t.Errorf("Expected fec_decoder to recover, got %q", checks["fec_decoder"])
+	}
+}
+
+func TestHealthEmulationAndPrometheus(t *testing.T) {
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# metrics\n"))
+	}))
+	defer exporter.Close()
+
+	emulation := EmulationHealthStats{ConfiguredLoss: 0.05, Packets: 10000, Dropped: 510}
+	api := NewAPIServer()
+	api.SetHealthSources(HealthSources{
+		Emulation:     func() EmulationHealthStats { return emulation },
+		PrometheusURL: exporter.URL,
+	})
+	if status, checks := getHealth(t, api); status != "healthy" {
+		t.Errorf("Expected healthy, got %q %v", status, checks)
+	}
+
+	// Emulation drops far more than configured, exporter is gone
+	emulation.Dropped = 4000
+	exporter.Close()
+	status, checks := getHealth(t, api)
+	if status != checkDegraded {
+		t.Errorf("Expected degraded, got %q", status)
+	}
+	for _, name := range []string{"emulation", "prometheus"} {
+		if !strings.HasPrefix(checks[name], checkDegraded+":") {
+			t.Errorf("Expected %s to be degraded, got %q", name, checks[name])
+		}
+	}
+}
+
+func TestHealthSourcesFollowRunningTests(t *testing.T) {
+	api := NewAPIServer()
+	api.SetHealthSources(HealthSources{FEC: api.FECHealth, Emulation: api.EmulationHealth})
+	for id, status := range map[string]string{"a": "running", "b": "running", "c": "completed"} {
+		api.testManager.activeTests[id] = &TestSession{ID: id, Status: status, Metrics: make(map[string]interface{})}
+	}
+	if status, checks := getHealth(t, api); status != "healthy" {
+		t.Fatalf("Expected healthy without FEC or emulation traffic, got %q %v", status, checks)
+	}
+
+	half := fecOpenGroupsDegraded / 2
+	api.testManager.activeTests["a"].setFECHealth(FECHealthStats{OpenGroups: int64(half)})
+	api.testManager.activeTests["b"].setFECHealth(FECHealthStats{OpenGroups: int64(half)})
+	// A finished test no longer holds decoders
+	api.testManager.activeTests["c"].setFECHealth(FECHealthStats{OpenGroups: fecOpenGroupsDegraded})
+	if got := api.FECHealth().OpenGroups; got != int64(2*half) {
+		t.Errorf("Expected the open groups of the running tests, got %d", got)
+	}
+
+	// 1% configured on one client and 9% on the other, 5% applied overall
+	api.testManager.activeTests["a"].setEmulationHealth(EmulationHealthStats{ConfiguredLoss: 0.01, Packets: 1000, Dropped: 10})
+	api.testManager.activeTests["b"].setEmulationHealth(EmulationHealthStats{ConfiguredLoss: 0.09, Packets: 1000, Dropped: 90})
+	emulation := api.EmulationHealth()
+	if emulation.Packets != 2000 || emulation.Dropped != 100 || math.Abs(emulation.ConfiguredLoss-0.05) > 1e-9 {
+		t.Errorf("Unexpected emulation totals: %+v", emulation)
+	}
+
+	status, checks := getHealth(t, api)
+	if status != checkDegraded || !strings.HasPrefix(checks["fec_decoder"], checkDegraded+":") {
+		t.Errorf("Expected the running tests' FEC groups to degrade health, got %q %v", status, checks)
+	}
+	if checks["emulation"] != checkOK {
+		t.Errorf("Expected emulation to be ok, got %q", checks["emulation"])
+	}
+}
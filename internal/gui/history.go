@@ -0,0 +1,119 @@
+package gui
+
+import "time"
+
+// defaultMetricsInterval is the default aggregation step of the history
+const defaultMetricsInterval = time.Second
+
+// SetMetricsInterval sets the history aggregation step and the point cap of
+// tests started afterwards. Samples within one step are merged into one
+// point; when a history exceeds maxPoints, neighbouring points are merged
+// and the step doubles, so any run stays within maxPoints. Zero values keep
+// the defaults of one second and maxMetricHistory points.
+func (tm *TestManager) SetMetricsInterval(interval time.Duration, maxPoints int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.metricsInterval = interval
+	tm.maxHistoryPoints = maxPoints
+}
+
+// SetMetricsInterval sets the history aggregation of tests started through the API
+func (api *APIServer) SetMetricsInterval(interval time.Duration, maxPoints int) {
+	api.testManager.SetMetricsInterval(interval, maxPoints)
+}
+
+// weight returns the number of raw samples a point stands for
+func (s MetricSample) weight() int {
+	if s.Count < 1 {
+		return 1
+	}
+	return s.Count
+}
+
+// mergeSamples folds b into a: averages are weighted by the raw sample
+// counts, extremes are kept, and the point stays at a's time
+func mergeSamples(a, b MetricSample) MetricSample {
+	wa, wb := float64(a.weight()), float64(b.weight())
+	n := wa + wb
+	merged := a
+	merged.Count = a.weight() + b.weight()
+	merged.LatencyMs = (a.LatencyMs*wa + b.LatencyMs*wb) / n
+	merged.ThroughputMbps = (a.ThroughputMbps*wa + b.ThroughputMbps*wb) / n
+	merged.PacketLoss = (a.PacketLoss*wa + b.PacketLoss*wb) / n
+	merged.RatePps = (a.RatePps*wa + b.RatePps*wb) / n
+	merged.LatencyMinMs = min(a.latencyMin(), b.latencyMin())
+	merged.LatencyMaxMs = max(a.latencyMax(), b.latencyMax())
+	merged.ThroughputMinMbps = min(a.throughputMin(), b.throughputMin())
+	merged.ThroughputMaxMbps = max(a.throughputMax(), b.throughputMax())
+	return merged
+}
+
+// Raw samples may carry only the value; it is then both extremes
+func (s MetricSample) latencyMin() float64 {
+	if s.Count < 2 {
+		return s.LatencyMs
+	}
+	return s.LatencyMinMs
+}
+
+func (s MetricSample) latencyMax() float64 {
+	if s.Count < 2 {
+		return s.LatencyMs
+	}
+	return s.LatencyMaxMs
+}
+
+func (s MetricSample) throughputMin() float64 {
+	if s.Count < 2 {
+		return s.ThroughputMbps
+	}
+	return s.ThroughputMinMbps
+}
+
+func (s MetricSample) throughputMax() float64 {
+	if s.Count < 2 {
+		return s.ThroughputMbps
+	}
+	return s.ThroughputMaxMbps
+}
+
+// recordSampleLocked adds a raw sample to the history, merging it into the
+// last point while both fall into the same step. The caller holds ts.mu.
+func (ts *TestSession) recordSampleLocked(sample MetricSample) {
+	if ts.historyInterval <= 0 {
+		ts.historyInterval = defaultMetricsInterval
+	}
+	maxPoints := ts.maxHistoryPoints
+	if maxPoints <= 0 {
+		maxPoints = maxMetricHistory
+	}
+
+	sample.Count = 1
+	sample.LatencyMinMs, sample.LatencyMaxMs = sample.LatencyMs, sample.LatencyMs
+	sample.ThroughputMinMbps, sample.ThroughputMaxMbps = sample.ThroughputMbps, sample.ThroughputMbps
+
+	step := ts.historyInterval.Seconds()
+	if n := len(ts.History); n > 0 && int64(ts.History[n-1].ElapsedSeconds/step) == int64(sample.ElapsedSeconds/step) {
+		ts.History[n-1] = mergeSamples(ts.History[n-1], sample)
+		return
+	}
+	ts.History = append(ts.History, sample)
+	if len(ts.History) > maxPoints {
+		ts.History = compactHistory(ts.History)
+		ts.historyInterval *= 2
+	}
+}
+
+// compactHistory halves the history by merging neighbouring points
+func compactHistory(history []MetricSample) []MetricSample {
+	compacted := make([]MetricSample, 0, (len(history)+1)/2)
+	for i := 0; i < len(history); i += 2 {
+		if i+1 < len(history) {
+			compacted = append(compacted, mergeSamples(history[i], history[i+1]))
+		} else {
+			compacted = append(compacted, history[i])
+		}
+	}
+	return compacted
+}
@@ -0,0 +1,78 @@
+package gui
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLongRunHistoryStaysUnderPointCap(t *testing.T) {
+	tm := NewTestManager()
+	tm.SetMetricsInterval(time.Second, 100)
+	session := &TestSession{
+		StartTime:        time.Now(),
+		historyInterval:  tm.metricsInterval,
+		maxHistoryPoints: tm.maxHistoryPoints,
+	}
+
+	// Ten hours of one sample per second with a single latency spike
+	const samples = 10 * 3600
+	var latencySum float64
+	for i := 0; i < samples; i++ {
+		latency := 40 + float64(i%10)
+		if i == samples/2 {
+			latency = 900
+		}
+		latencySum += latency
+		session.recordSampleLocked(MetricSample{
+			Timestamp:      session.StartTime.Add(time.Duration(i) * time.Second),
+			ElapsedSeconds: float64(i),
+			LatencyMs:      latency,
+			ThroughputMbps: 100,
+		})
+		if len(session.History) > 100 {
+			t.Fatalf("History grew to %d points after %d samples", len(session.History), i+1)
+		}
+	}
+
+	var count int
+	var weightedSum, maxLatency float64
+	minLatency := math.Inf(1)
+	for _, point := range session.History {
+		count += point.Count
+		weightedSum += point.LatencyMs * float64(point.Count)
+		minLatency = math.Min(minLatency, point.LatencyMinMs)
+		maxLatency = math.Max(maxLatency, point.LatencyMaxMs)
+		if point.ThroughputMinMbps != 100 || point.ThroughputMaxMbps != 100 {
+			t.Fatalf("Constant throughput changed by down-sampling: %+v", point)
+		}
+	}
+	if count != samples {
+		t.Errorf("Down-sampled points cover %d samples, want %d", count, samples)
+	}
+	if math.Abs(weightedSum/samples-latencySum/samples) > 1e-6 {
+		t.Errorf("Mean latency changed from %.4f to %.4f", latencySum/samples, weightedSum/samples)
+	}
+	if minLatency != 40 || maxLatency != 900 {
+		t.Errorf("Expected extremes 40 and 900 ms to survive, got %.0f and %.0f", minLatency, maxLatency)
+	}
+	if first := session.History[0]; first.ElapsedSeconds != 0 {
+		t.Errorf("Expected the history to still start at the test start, got %+v", first)
+	}
+}
+
+func TestHistoryAggregatesWithinInterval(t *testing.T) {
+	session := &TestSession{historyInterval: 5 * time.Second}
+	for i, latency := range []float64{10, 20, 30, 40, 50, 60} {
+		session.recordSampleLocked(MetricSample{ElapsedSeconds: float64(i), LatencyMs: latency})
+	}
+
+	// Seconds 0-4 fall into the first 5s point, second 5 starts the next one
+	if len(session.History) != 2 {
+		t.Fatalf("Expected 2 points, got %+v", session.History)
+	}
+	first := session.History[0]
+	if first.Count != 5 || first.LatencyMs != 30 || first.LatencyMinMs != 10 || first.LatencyMaxMs != 50 {
+		t.Errorf("Unexpected first point %+v", first)
+	}
+}
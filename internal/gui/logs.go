@@ -0,0 +1,112 @@
+package gui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxLogEntries is the number of log entries a session keeps
+const maxLogEntries = 100
+
+// Log levels of session log entries
+const (
+	LogLevelInfo  = "info"
+	LogLevelError = "error"
+)
+
+// LogEntry is the structured form of a session log line. Logs keeps the
+// same entries as "[15:04:05] message" strings for older API clients.
+type LogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+}
+
+// String formats the entry the way it appears in Logs
+func (e LogEntry) String() string {
+	return fmt.Sprintf("[%s] %s", e.Timestamp.Format("15:04:05"), e.Message)
+}
+
+// addLogLevel adds a log entry at level; the caller holds ts.mu
+func (ts *TestSession) addLogLevel(level, message string) {
+	entry := LogEntry{Timestamp: time.Now(), Level: level, Message: message}
+	ts.LogEntries = append(ts.LogEntries, entry)
+	ts.Logs = append(ts.Logs, entry.String())
+	ts.updated = entry.Timestamp
+
+	// Keep only the last maxLogEntries entries
+	if len(ts.LogEntries) > maxLogEntries {
+		ts.LogEntries = ts.LogEntries[len(ts.LogEntries)-maxLogEntries:]
+	}
+	if len(ts.Logs) > maxLogEntries {
+		ts.Logs = ts.Logs[len(ts.Logs)-maxLogEntries:]
+	}
+}
+
+// GetLogEntries returns a copy of the log entries written after since (all
+// of them when since is zero), limited to the last tail entries when tail
+// is positive
+func (ts *TestSession) GetLogEntries(since time.Time, tail int) []LogEntry {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	entries := ts.LogEntries
+	if !since.IsZero() {
+		first := len(entries)
+		for i, entry := range entries {
+			if entry.Timestamp.After(since) {
+				first = i
+				break
+			}
+		}
+		entries = entries[first:]
+	}
+	if tail > 0 && len(entries) > tail {
+		entries = entries[len(entries)-tail:]
+	}
+
+	logs := make([]LogEntry, len(entries))
+	copy(logs, entries)
+	return logs
+}
+
+// handleTestLogs serves GET /api/tests/{id}/logs. ?tail=N returns only the
+// last N entries and ?since=<RFC3339> only the entries written after that
+// time, so a poller can fetch just the entries it has not seen yet.
+func (api *APIServer) handleTestLogs(w http.ResponseWriter, r *http.Request, testID string) {
+	if r.Method != "GET" {
+		api.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	session := api.testManager.GetTest(testID)
+	if session == nil {
+		api.sendError(w, "Test not found", http.StatusNotFound)
+		return
+	}
+
+	tail := 0
+	if v := r.URL.Query().Get("tail"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			api.sendError(w, "Invalid tail: "+v, http.StatusBadRequest)
+			return
+		}
+		tail = parsed
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			api.sendError(w, "Invalid since, expected an RFC3339 timestamp: "+v, http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	api.sendCacheable(w, r, map[string]interface{}{
+		"test_id": testID,
+		"logs":    session.GetLogEntries(since, tail),
+	}, session.LastModified())
+}
@@ -0,0 +1,68 @@
+package gui
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTestLogsTailAndSince(t *testing.T) {
+	api := NewAPIServer()
+	addFinishedTest(api, "a", 10)
+	session := api.testManager.activeTests["a"]
+	for i := 0; i < maxLogEntries+5; i++ {
+		session.addLogSafe(fmt.Sprintf("entry %d", i))
+	}
+	session.addLogLevel(LogLevelError, "Client failed: boom")
+
+	var all struct {
+		TestID string     `json:"test_id"`
+		Logs   []LogEntry `json:"logs"`
+	}
+	if code := getAPI(t, api, "/api/tests/a/logs", &all); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if all.TestID != "a" || len(all.Logs) != maxLogEntries {
+		t.Fatalf("Expected the last %d entries of test a, got %d of %q", maxLogEntries, len(all.Logs), all.TestID)
+	}
+	last := all.Logs[len(all.Logs)-1]
+	if last.Level != LogLevelError || last.Message != "Client failed: boom" {
+		t.Errorf("Expected the failure as the last entry, got %+v", last)
+	}
+	if session.Logs[len(session.Logs)-1] != last.String() {
+		t.Errorf("Expected logs to keep the same entry as text, got %q", session.Logs[len(session.Logs)-1])
+	}
+
+	var tail struct {
+		Logs []LogEntry `json:"logs"`
+	}
+	getAPI(t, api, "/api/tests/a/logs?tail=3", &tail)
+	if len(tail.Logs) != 3 || tail.Logs[0].Message != all.Logs[maxLogEntries-3].Message {
+		t.Errorf("Expected the last 3 entries, got %+v", tail.Logs)
+	}
+
+	// A poller passes the timestamp of the last entry it has seen
+	seen := all.Logs[maxLogEntries-2].Timestamp.Format(time.RFC3339Nano)
+	var since struct {
+		Logs []LogEntry `json:"logs"`
+	}
+	getAPI(t, api, "/api/tests/a/logs?since="+url.QueryEscape(seen), &since)
+	if len(since.Logs) != 1 || since.Logs[0].Message != last.Message {
+		t.Errorf("Expected only the entry after %s, got %+v", seen, since.Logs)
+	}
+	getAPI(t, api, "/api/tests/a/logs?since="+url.QueryEscape(last.Timestamp.Format(time.RFC3339Nano)), &since)
+	if len(since.Logs) != 0 {
+		t.Errorf("Expected no entries after the last one, got %+v", since.Logs)
+	}
+
+	for _, query := range []string{"tail=-1", "tail=x", "since=15:04:05"} {
+		if code := getAPI(t, api, "/api/tests/a/logs?"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, code)
+		}
+	}
+	if code := getAPI(t, api, "/api/tests/missing/logs", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown test, got %d", code)
+	}
+}
@@ -0,0 +1,169 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// errTestNotInterrupted is returned for resuming a test that was not cut off
+var errTestNotInterrupted = errors.New("test is not interrupted")
+
+// ResumeEvent is one continuation of a test cut off by a restart
+type ResumeEvent struct {
+	InterruptedAt  time.Time `json:"interrupted_at"` // Last save before the restart
+	ResumedAt      time.Time `json:"resumed_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"` // Run time before the resume, without downtime
+}
+
+// cumulativeMetrics are the counters a resumed run continues from the
+// persisted values instead of starting again from zero
+var cumulativeMetrics = []string{
+	"bytes_sent", "packets_sent", "errors", "elapsed_seconds",
+	"bytes_received", "server_connections", "server_streams", "server_errors",
+	"corrupted_packets", "uptime_seconds",
+}
+
+// ResumeTest continues an interrupted test toward its configured duration.
+// The downtime is not counted: elapsed times and the history continue
+// where the run was cut off, and counters add up with the persisted ones.
+func (tm *TestManager) ResumeTest(testID string) error {
+	tm.mu.RLock()
+	session, exists := tm.activeTests[testID]
+	tm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("test not found: %s", testID)
+	}
+
+	session.mu.Lock()
+	if session.Status != "interrupted" {
+		session.mu.Unlock()
+		return fmt.Errorf("%w: %s", errTestNotInterrupted, testID)
+	}
+	interruptedAt := session.updated
+	if session.EndTime != nil {
+		interruptedAt = *session.EndTime
+	}
+	elapsed := session.elapsedLocked(interruptedAt)
+	if session.Config.Duration > 0 && elapsed >= session.Config.Duration {
+		session.mu.Unlock()
+		return fmt.Errorf("test %s had already reached its duration", testID)
+	}
+
+	now := time.Now()
+	session.Resumes = append(session.Resumes, ResumeEvent{
+		InterruptedAt:  interruptedAt,
+		ResumedAt:      now,
+		ElapsedSeconds: elapsed.Seconds(),
+	})
+	session.metricsBase = make(map[string]float64)
+	for _, key := range cumulativeMetrics {
+		if value, ok := metricFloat(session.Metrics[key]); ok {
+			session.metricsBase[key] = value
+		}
+	}
+	session.Status = "running"
+	session.EndTime = nil
+	session.FinalMetrics = nil
+	session.Report = nil
+	if session.Config.Duration > 0 {
+		session.addLog(fmt.Sprintf("Test resumed after an interruption at %s of run time, %s remaining",
+			elapsed.Round(time.Second), (session.Config.Duration - elapsed).Round(time.Second)))
+	} else {
+		session.addLog(fmt.Sprintf("Test resumed after an interruption at %s of run time", elapsed.Round(time.Second)))
+	}
+	session.mu.Unlock()
+
+	tm.persist(session)
+	go tm.runTest(session)
+	return nil
+}
+
+// ResumeInterrupted resumes every interrupted test and returns their IDs.
+// Tests that cannot be resumed are logged and left interrupted.
+func (tm *TestManager) ResumeInterrupted() []string {
+	var resumed []string
+	for _, session := range tm.GetAllTests() {
+		session.mu.RLock()
+		interrupted := session.Status == "interrupted"
+		session.mu.RUnlock()
+		if !interrupted {
+			continue
+		}
+		if err := tm.ResumeTest(session.ID); err != nil {
+			session.mu.Lock()
+			session.addLogLevel(LogLevelError, fmt.Sprintf("Resume failed: %v", err))
+			session.mu.Unlock()
+			continue
+		}
+		resumed = append(resumed, session.ID)
+	}
+	return resumed
+}
+
+// ResumeInterrupted resumes the interrupted tests of the API server
+func (api *APIServer) ResumeInterrupted() []string {
+	return api.testManager.ResumeInterrupted()
+}
+
+// pausedLocked returns the downtime between interruptions and resumes.
+// The caller holds ts.mu.
+func (ts *TestSession) pausedLocked() time.Duration {
+	var paused time.Duration
+	for _, r := range ts.Resumes {
+		paused += r.ResumedAt.Sub(r.InterruptedAt)
+	}
+	return paused
+}
+
+// elapsedLocked returns the run time of the test at t, without the
+// downtime of interruptions. The caller holds ts.mu.
+func (ts *TestSession) elapsedLocked(t time.Time) time.Duration {
+	return t.Sub(ts.StartTime) - ts.pausedLocked()
+}
+
+// remainingLocked returns the duration the current run has to go, zero for
+// an unlimited test. The caller holds ts.mu.
+func (ts *TestSession) remainingLocked() time.Duration {
+	if ts.Config.Duration <= 0 || len(ts.Resumes) == 0 {
+		return ts.Config.Duration
+	}
+	return ts.Config.Duration - time.Duration(ts.Resumes[len(ts.Resumes)-1].ElapsedSeconds*float64(time.Second))
+}
+
+// metricFloat reads a metric value as saved live or loaded from the store,
+// where numbers are float64 and large counters strings
+func metricFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// continueMetric adds the persisted value of a cumulative counter to the
+// value of the resumed run. The caller holds ts.mu.
+func (ts *TestSession) continueMetric(key string, value interface{}) interface{} {
+	base, ok := ts.metricsBase[key]
+	if !ok {
+		return value
+	}
+	switch v := value.(type) {
+	case int:
+		return v + int(base)
+	case int64:
+		return v + int64(base)
+	case float64:
+		return v + base
+	}
+	return value
+}
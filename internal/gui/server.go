@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"quic-test/client"
 	"quic-test/internal"
 )
 
@@ -19,24 +20,46 @@ type Server struct {
 	templates   *template.Template
 	devMode     bool
 	testManager *TestManager
+	apiBaseURL  string // Base URL of the API server used by the proxy
+	apiSocket   string // Unix socket of the API server (overrides apiBaseURL)
+	apiHTTP     *http.Client // Reused for every proxied request, so connections are kept alive
 	mu          sync.RWMutex
 }
 
 // TestManager manages running tests
 type TestManager struct {
-	activeTests map[string]*TestSession
-	mu          sync.RWMutex
+	activeTests      map[string]*TestSession
+	metricsInterval  time.Duration // History aggregation step of new tests
+	maxHistoryPoints int           // History point cap of new tests
+	store            SessionStore  // Persists sessions; nil keeps them in memory only
+	flushInterval    time.Duration // Snapshot interval of running tests in store; zero or less disables
+	maxSessions      int           // Sessions kept in memory when store is set
+	mu               sync.RWMutex
 }
 
 // TestSession represents an active test session
 type TestSession struct {
 	ID          string                 `json:"id"`
 	Config      internal.TestConfig    `json:"config"`
-	Status      string                 `json:"status"` // "running", "completed", "failed"
+	Status      string                 `json:"status"` // "running", "completed", "stopped", "failed", "interrupted"
 	StartTime   time.Time              `json:"start_time"`
 	EndTime     *time.Time             `json:"end_time,omitempty"`
 	Metrics     map[string]interface{} `json:"metrics"`
 	Logs        []string               `json:"logs"`
+	LogEntries  []LogEntry             `json:"-"` // Served by /api/tests/{id}/logs
+	Rate        int                    `json:"rate"`                   // Current send rate, changed live with PATCH /api/tests/{id}
+	RateChanges []RateChange           `json:"rate_changes,omitempty"` // Live rate changes in order
+	Resumes     []ResumeEvent          `json:"resumes,omitempty"`      // Continuations after interruptions, in order
+	History     []MetricSample         `json:"-"` // Served by /api/metrics/history
+	FinalMetrics *FinalMetrics         `json:"final_metrics,omitempty"` // Set when a test completes or is stopped
+	Report      *internal.ReportSchema `json:"report,omitempty"`
+	updated     time.Time              // Last change of status, metrics or logs
+	historyInterval  time.Duration     // Current aggregation step, doubles on compaction
+	metricsBase map[string]float64     // Counters of the run before the last resume
+	result      *client.RunResult      // Result of the client run, when it ended without an error
+	fecHealth   FECHealthStats         // Live FEC decoder state of the test's server
+	emulationHealth EmulationHealthStats // Live emulation state of the test's client
+	maxHistoryPoints int
 	mu          sync.RWMutex
 }
 
@@ -45,16 +68,64 @@ func NewServer(devMode bool) *Server {
 	server := &Server{
 		devMode:     devMode,
 		testManager: NewTestManager(),
+		apiBaseURL:  "http://localhost:8081",
+		apiHTTP:     internal.LocalHTTPClient(0),
 	}
 	
 	server.loadTemplates()
 	return server
 }
 
+// SetAPIAddr points the GUI proxy at the API server's TCP address. A
+// wildcard host is reached through localhost and IPv6 hosts are bracketed.
+func (s *Server) SetAPIAddr(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.apiBaseURL = internal.LocalURL(addr)
+	s.apiSocket = ""
+	s.apiHTTP = internal.LocalHTTPClient(0)
+}
+
+// SetAPISocket points the GUI proxy at an API server listening on a unix socket
+func (s *Server) SetAPISocket(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.apiSocket = path
+	s.apiHTTP = NewUnixSocketClient(path, 0)
+}
+
+// apiURL builds an API server URL for the given path
+func (s *Server) apiURL(path string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.apiSocket != "" {
+		// Host is ignored by the unix socket dialer
+		return "http://unix" + path
+	}
+	return s.apiBaseURL + path
+}
+
+// apiClient returns an HTTP client able to reach the API server; localhost
+// is tried over both IPv6 and IPv4 whatever the resolver returns. The
+// client shares the transport built by SetAPIAddr or SetAPISocket and only
+// differs in its timeout.
+func (s *Server) apiClient(timeout time.Duration) *http.Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	client := *s.apiHTTP
+	client.Timeout = timeout
+	return &client
+}
+
 // NewTestManager creates a new test manager
 func NewTestManager() *TestManager {
 	return &TestManager{
-		activeTests: make(map[string]*TestSession),
+		activeTests:   make(map[string]*TestSession),
+		flushInterval: defaultFlushInterval,
 	}
 }
 
@@ -106,6 +177,7 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/test/new", s.handleNewTest)
 	mux.HandleFunc("/test/", s.handleTestDetails)
 	mux.HandleFunc("/tests", s.handleTestList)
+	mux.HandleFunc("/tests/compare", s.handleTestCompare)
 	mux.HandleFunc("/docs", s.handleDocs)
 	mux.HandleFunc("/api-docs", s.handleAPIDocs)
 	
@@ -163,8 +235,8 @@ func (s *Server) handleTestDetails(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Get test data from API server
-	apiURL := fmt.Sprintf("http://localhost:8081/api/tests/%s", testID)
-	resp, err := http.Get(apiURL)
+	apiURL := s.apiURL(fmt.Sprintf("/api/tests/%s", testID))
+	resp, err := s.apiClient(5 * time.Second).Get(apiURL)
 	if err != nil {
 		http.Error(w, "Failed to fetch test data", http.StatusInternalServerError)
 		return
@@ -210,8 +282,8 @@ func (s *Server) handleTestDetails(w http.ResponseWriter, r *http.Request) {
 // handleTestList serves the test list page
 func (s *Server) handleTestList(w http.ResponseWriter, r *http.Request) {
 	// Get test data from API server
-	apiURL := "http://localhost:8081/api/tests"
-	resp, err := http.Get(apiURL)
+	apiURL := s.apiURL("/api/tests")
+	resp, err := s.apiClient(5 * time.Second).Get(apiURL)
 	if err != nil {
 		http.Error(w, "Failed to fetch test data", http.StatusInternalServerError)
 		return
@@ -251,6 +323,19 @@ func (s *Server) handleTestList(w http.ResponseWriter, r *http.Request) {
 	s.renderTemplate(w, "test-list.html", data)
 }
 
+// handleTestCompare serves the comparison page for the tests in ?ids=a,b,...
+func (s *Server) handleTestCompare(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Title string
+		IDs   []string
+	}{
+		Title: "Compare Tests",
+		IDs:   parseTestIDs(r.URL.Query().Get("ids")),
+	}
+	
+	s.renderTemplate(w, "test-compare.html", data)
+}
+
 // handleDocs serves the documentation page
 func (s *Server) handleDocs(w http.ResponseWriter, r *http.Request) {
 	data := struct {
@@ -302,7 +387,7 @@ func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
 // handleAPIProxy proxies API requests to the API server
 func (s *Server) handleAPIProxy(w http.ResponseWriter, r *http.Request) {
 	// Create proxy URL to API server
-	apiURL := "http://localhost:8081" + r.URL.Path
+	apiURL := s.apiURL(r.URL.Path)
 	if r.URL.RawQuery != "" {
 		apiURL += "?" + r.URL.RawQuery
 	}
@@ -327,9 +412,7 @@ func (s *Server) handleAPIProxy(w http.ResponseWriter, r *http.Request) {
 		timeout = 30 * time.Second // Longer timeout for stop operations
 	}
 	
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	client := s.apiClient(timeout)
 	
 	resp, err := client.Do(proxyReq)
 	if err != nil {
@@ -381,6 +464,8 @@ func (s *Server) renderSimpleHTML(w http.ResponseWriter, name string, data inter
 		s.renderTestDetailsHTML(w, data)
 	case "test-list.html":
 		s.renderTestListHTML(w, data)
+	case "test-compare.html":
+		s.renderTestCompareHTML(w, data)
 	case "docs.html":
 		s.renderDocsHTML(w, data)
 	case "api-docs.html":
@@ -0,0 +1,340 @@
+package gui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultMaxSessions is the default number of sessions kept in memory when
+// a session store is set
+const defaultMaxSessions = 100
+
+// defaultFlushInterval is how often a running test is saved by default
+const defaultFlushInterval = 10 * time.Second
+
+// SessionStore persists test sessions so the test history survives restarts
+type SessionStore interface {
+	// Save writes the current state of a session, replacing an earlier save
+	Save(session *TestSession) error
+	// Load returns all saved sessions, oldest first
+	Load() ([]*TestSession, error)
+}
+
+// storedSession is the on-disk form of a session; the metric history and
+// the structured logs are not part of the API representation of a session,
+// so they are stored beside it
+type storedSession struct {
+	Session         *TestSession   `json:"session"`
+	Updated         time.Time      `json:"updated"` // Last change before the save, when a restart interrupted a running test
+	History         []MetricSample `json:"history,omitempty"`
+	HistoryInterval time.Duration  `json:"history_interval,omitempty"`
+	LogEntries      []LogEntry     `json:"log_entries,omitempty"`
+}
+
+// FileSessionStore keeps each session as <id>.json in a directory. Files
+// are replaced atomically, so a crash mid-write leaves the previous save.
+type FileSessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileSessionStore creates a store in dir, creating the directory if needed
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// path returns the file of a session; IDs never name a path outside dir
+func (s *FileSessionStore) path(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid session id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// Save writes the session to a temporary file and renames it over the
+// previous save. Saves are serialized and each encodes a copy of the session
+// taken under its read lock, so a later save never loses to an earlier one.
+func (s *FileSessionStore) Save(session *TestSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session = session.snapshot()
+	data, err := json.MarshalIndent(storedSession{
+		Session:         session,
+		Updated:         session.updated,
+		History:         session.History,
+		HistoryInterval: session.historyInterval,
+		LogEntries:      session.LogEntries,
+	}, "", "  ")
+	id := session.ID
+	if err != nil {
+		return fmt.Errorf("failed to encode session %s: %w", id, err)
+	}
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, "."+id+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save session %s: %w", id, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save session %s: %w", id, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save session %s: %w", id, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save session %s: %w", id, err)
+	}
+	return nil
+}
+
+// Load reads all sessions in the directory. Unreadable, corrupted or
+// partial files are skipped with a warning.
+func (s *FileSessionStore) Load() ([]*TestSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	sessions := make([]*TestSession, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			log.Printf("Warning: skipping session file %s: %v", name, err)
+			continue
+		}
+		var stored storedSession
+		if err := json.Unmarshal(data, &stored); err != nil {
+			log.Printf("Warning: skipping corrupted session file %s: %v", name, err)
+			continue
+		}
+		session := stored.Session
+		if session == nil || session.ID+".json" != name {
+			log.Printf("Warning: skipping session file %s: no session with a matching id", name)
+			continue
+		}
+		if session.Metrics == nil {
+			session.Metrics = make(map[string]interface{})
+		}
+		session.History = stored.History
+		session.historyInterval = stored.HistoryInterval
+		session.LogEntries = stored.LogEntries
+		session.updated = stored.Updated
+		if session.updated.IsZero() {
+			session.updated = session.StartTime
+			if session.EndTime != nil {
+				session.updated = *session.EndTime
+			}
+		}
+		sessions = append(sessions, session)
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// sortSessions orders sessions oldest first, by ID within the same start time
+func sortSessions(sessions []*TestSession) {
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].StartTime.Equal(sessions[j].StartTime) {
+			return sessions[i].StartTime.Before(sessions[j].StartTime)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+}
+
+// SetSessionStore makes the manager save sessions to store and loads the
+// saved ones, keeping the latest maxSessions in memory (zero keeps
+// defaultMaxSessions). Sessions saved as running were cut off by a restart
+// and are loaded as interrupted, ending at their last save; ResumeTest
+// continues them.
+func (tm *TestManager) SetSessionStore(store SessionStore, maxSessions int) error {
+	sessions, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+
+	tm.mu.Lock()
+	tm.store = store
+	tm.maxSessions = maxSessions
+	var interrupted []*TestSession
+	for _, session := range sessions {
+		if session.Status == "running" {
+			session.Status = "interrupted"
+			end := session.updated
+			session.EndTime = &end
+			session.addLogLevel(LogLevelError, "Test interrupted by a GUI restart")
+			interrupted = append(interrupted, session)
+		}
+		tm.activeTests[session.ID] = session
+	}
+	tm.evictLocked()
+	tm.mu.Unlock()
+
+	for _, session := range interrupted {
+		tm.persist(session)
+	}
+	return nil
+}
+
+// SetSessionStore makes the API server persist its tests to store
+func (api *APIServer) SetSessionStore(store SessionStore, maxSessions int) error {
+	return api.testManager.SetSessionStore(store, maxSessions)
+}
+
+// evictLocked drops the oldest finished sessions from memory beyond
+// maxSessions; they stay in the store. Running tests are never dropped.
+// The caller holds tm.mu.
+func (tm *TestManager) evictLocked() {
+	if tm.store == nil || len(tm.activeTests) <= tm.maxSessions {
+		return
+	}
+	sessions := make([]*TestSession, 0, len(tm.activeTests))
+	for _, session := range tm.activeTests {
+		sessions = append(sessions, session)
+	}
+	sortSessions(sessions)
+	excess := len(sessions) - tm.maxSessions
+	for _, session := range sessions {
+		if excess == 0 {
+			break
+		}
+		session.mu.RLock()
+		running := session.Status == "running"
+		session.mu.RUnlock()
+		if !running {
+			delete(tm.activeTests, session.ID)
+			excess--
+		}
+	}
+}
+
+// SetFlushInterval sets how often running tests are saved to the store, so
+// a crash loses at most one interval of their history. Zero or less saves
+// them only on start, stop and end.
+func (tm *TestManager) SetFlushInterval(interval time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.flushInterval = interval
+}
+
+// SetFlushInterval sets how often the API server saves its running tests
+func (api *APIServer) SetFlushInterval(interval time.Duration) {
+	api.testManager.SetFlushInterval(interval)
+}
+
+// flushLoop saves a snapshot of a running session every flush interval
+// until ctx is done. Saves run in the background on a copy, so neither the
+// test nor metric updates wait for the disk; a tick that finds the previous
+// save still running is skipped. flushLoop returns after the last save.
+func (tm *TestManager) flushLoop(ctx context.Context, session *TestSession) {
+	tm.mu.RLock()
+	store, interval := tm.store, tm.flushInterval
+	tm.mu.RUnlock()
+	if store == nil || interval <= 0 {
+		return
+	}
+
+	var saving atomic.Bool
+	var saves sync.WaitGroup
+	defer saves.Wait()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !saving.CompareAndSwap(false, true) {
+			continue
+		}
+		snapshot := session.snapshot()
+		saves.Add(1)
+		go func() {
+			defer saves.Done()
+			defer saving.Store(false)
+			if err := store.Save(snapshot); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}()
+	}
+}
+
+// snapshot copies what the store saves and the API serves of a session, so
+// the copy can be encoded without holding the session's lock
+func (ts *TestSession) snapshot() *TestSession {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	return &TestSession{
+		ID:              ts.ID,
+		Config:          ts.Config,
+		Status:          ts.Status,
+		StartTime:       ts.StartTime,
+		EndTime:         ts.EndTime,
+		Metrics:         maps.Clone(ts.Metrics),
+		Logs:            slices.Clone(ts.Logs),
+		LogEntries:      slices.Clone(ts.LogEntries),
+		Rate:            ts.Rate,
+		RateChanges:     slices.Clone(ts.RateChanges),
+		Resumes:         slices.Clone(ts.Resumes),
+		History:         slices.Clone(ts.History),
+		FinalMetrics:    ts.FinalMetrics,
+		Report:          ts.Report,
+		updated:         ts.updated,
+		historyInterval: ts.historyInterval,
+	}
+}
+
+// sessionJSON is TestSession without its MarshalJSON method
+type sessionJSON TestSession
+
+// MarshalJSON encodes a snapshot of the session, so a session can be served
+// while its test updates the metrics and logs. The caller must not hold
+// ts.mu.
+func (ts *TestSession) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*sessionJSON)(ts.snapshot()))
+}
+
+// persist saves a session to the store, if any; a failed save is logged and
+// does not affect the test
+func (tm *TestManager) persist(session *TestSession) {
+	tm.mu.RLock()
+	store := tm.store
+	tm.mu.RUnlock()
+	if store == nil {
+		return
+	}
+	if err := store.Save(session); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
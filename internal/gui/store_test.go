@@ -0,0 +1,277 @@
+package gui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+func TestSessionsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := NewTestManager()
+	if err := tm.SetSessionStore(store, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startQUICServer(t)
+	session := tm.StartTest(internal.TestConfig{Mode: "client", Addr: addr, InsecureSkipVerify: true, Rate: 100, PacketSize: 1200, Connections: 1, Streams: 1})
+	time.Sleep(1200 * time.Millisecond)
+	if err := tm.StopTest(session.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The final save lands when the test goroutine finalizes the stop
+	var loaded *TestSession
+	deadline := time.Now().Add(2 * time.Second)
+	for loaded == nil || loaded.FinalMetrics == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the finalized test to be saved")
+		}
+		time.Sleep(50 * time.Millisecond)
+		restarted := NewTestManager()
+		if err := restarted.SetSessionStore(store, 0); err != nil {
+			t.Fatal(err)
+		}
+		loaded = restarted.GetTest(session.ID)
+	}
+
+	if loaded.Status != "stopped" || loaded.Config.Rate != 100 || loaded.Report == nil {
+		t.Errorf("Expected the stopped test with its config and report, got %+v", loaded)
+	}
+	if len(loaded.GetHistory()) == 0 {
+		t.Error("Expected the metric history to be restored")
+	}
+	if entries := loaded.GetLogEntries(time.Time{}, 0); len(entries) != len(loaded.Logs) {
+		t.Errorf("Expected the structured logs to be restored, got %d entries for %d logs", len(entries), len(loaded.Logs))
+	}
+}
+
+func TestSessionStoreSkipsCorruptedFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSessionStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Minute)
+	if err := store.Save(&TestSession{ID: "test_1", Status: "completed", StartTime: start}); err != nil {
+		t.Fatal(err)
+	}
+	// Left running by a process that exited mid-test
+	if err := store.Save(&TestSession{ID: "test_2", Status: "running", StartTime: start.Add(time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"test_3.json": `{"session": {"id": "test_3", "status": "compl`, // Partial write
+		"test_4.json": `not json`,
+		"test_5.json": `{"session": {"id": "test_6"}}`, // ID does not match the file
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tm := NewTestManager()
+	if err := tm.SetSessionStore(store, 0); err != nil {
+		t.Fatalf("Expected corrupted files to be skipped, got %v", err)
+	}
+	tests := tm.GetAllTests()
+	if len(tests) != 2 || tests[0].ID != "test_1" || tests[1].ID != "test_2" {
+		t.Fatalf("Expected the two valid sessions, got %d", len(tests))
+	}
+	if tests[1].Status != "interrupted" || tests[1].EndTime == nil {
+		t.Errorf("Expected a session left running to load as interrupted, got %s", tests[1].Status)
+	}
+}
+
+func TestSessionStoreKeepsLatestInMemory(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Hour)
+	for i, id := range []string{"test_1", "test_2", "test_3"} {
+		if err := store.Save(&TestSession{ID: id, Status: "completed", StartTime: start.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tm := NewTestManager()
+	if err := tm.SetSessionStore(store, 2); err != nil {
+		t.Fatal(err)
+	}
+	if tm.GetTest("test_1") != nil || tm.GetTest("test_2") == nil || tm.GetTest("test_3") == nil {
+		t.Errorf("Expected only the latest two sessions in memory, got %d", tm.GetTotalTestCount())
+	}
+	if sessions, _ := store.Load(); len(sessions) != 3 {
+		t.Errorf("Expected evicted sessions to stay on disk, got %d", len(sessions))
+	}
+}
+
+func TestSessionStoreConcurrentSaves(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &TestSession{ID: "test_1", Status: "running", StartTime: time.Now(), Metrics: map[string]interface{}{}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session.addLogSafe("tick")
+			if err := store.Save(session); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sessions, err := store.Load()
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("Expected one intact session, got %d (%v)", len(sessions), err)
+	}
+	if got := len(sessions[0].Logs); got != 20 {
+		t.Errorf("Expected the last save to hold all 20 log entries, got %d", got)
+	}
+}
+
+func TestResumeInterruptedTest(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startQUICServer(t)
+
+	// A run of 3s cut off after 1s: its last save is all that is left
+	start := time.Now().Add(-time.Minute)
+	partial := &TestSession{
+		ID:        "test_1",
+		Config:    internal.TestConfig{Mode: "client", Addr: addr, InsecureSkipVerify: true, Duration: 3 * time.Second, Rate: 100, PacketSize: 1200, Connections: 1, Streams: 1},
+		Status:    "running",
+		StartTime: start,
+		Rate:      100,
+		Metrics:   map[string]interface{}{"bytes_sent": int64(50000), "packets_sent": int64(40), "elapsed_seconds": 1.0},
+		History:   []MetricSample{{Timestamp: start.Add(time.Second), ElapsedSeconds: 1, LatencyMs: 2, ThroughputMbps: 1, Count: 1}},
+		updated:   start.Add(time.Second),
+	}
+	partial.historyInterval = time.Second
+	if err := store.Save(partial); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restarted manager finds the test interrupted and resumes it
+	tm := NewTestManager()
+	if err := tm.SetSessionStore(store, 0); err != nil {
+		t.Fatal(err)
+	}
+	if session := tm.GetTest("test_1"); session == nil || session.Status != "interrupted" {
+		t.Fatalf("Expected the test to load as interrupted, got %+v", session)
+	}
+	if resumed := tm.ResumeInterrupted(); len(resumed) != 1 || resumed[0] != "test_1" {
+		t.Fatalf("Expected test_1 to be resumed, got %v", resumed)
+	}
+	if err := tm.ResumeTest("test_1"); err == nil {
+		t.Error("Expected a running test not to be resumed again")
+	}
+
+	var session *TestSession
+	deadline := time.Now().Add(10 * time.Second)
+	for session == nil || session.FinalMetrics == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the resumed test to complete")
+		}
+		time.Sleep(100 * time.Millisecond)
+		// Reload what the store has, as after another restart
+		restarted := NewTestManager()
+		if err := restarted.SetSessionStore(store, 0); err != nil {
+			t.Fatal(err)
+		}
+		session = restarted.GetTest("test_1")
+	}
+
+	if session.Status != "completed" || len(session.Resumes) != 1 {
+		t.Fatalf("Expected a completed test with one resume, got %s with %+v", session.Status, session.Resumes)
+	}
+	// The run continued for the remaining 2s, without the minute of downtime
+	if d := session.FinalMetrics.DurationSeconds; d < 2.5 || d > 5 {
+		t.Errorf("Expected about 3s of run time, got %.2fs", d)
+	}
+	history := session.GetHistory()
+	if len(history) < 2 || history[0].ElapsedSeconds != 1 {
+		t.Fatalf("Expected the saved point followed by new ones, got %+v", history)
+	}
+	for _, sample := range history[1:] {
+		if sample.ElapsedSeconds < 1 || sample.ElapsedSeconds > 5 {
+			t.Errorf("Expected new points to continue after 1s of run time, got %.2fs", sample.ElapsedSeconds)
+		}
+	}
+	sent, _ := metricFloat(session.Metrics["bytes_sent"])
+	if sent <= 50000 {
+		t.Errorf("Expected bytes_sent to add up with the saved 50000, got %v", session.Metrics["bytes_sent"])
+	}
+	var logged bool
+	for _, line := range session.Logs {
+		logged = logged || strings.Contains(line, "Test resumed")
+	}
+	if !logged {
+		t.Errorf("Expected the resume in the logs, got %v", session.Logs)
+	}
+}
+
+func TestRunningTestIsFlushedPeriodically(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := NewTestManager()
+	if err := tm.SetSessionStore(store, 0); err != nil {
+		t.Fatal(err)
+	}
+	tm.SetFlushInterval(200 * time.Millisecond)
+
+	addr := startQUICServer(t)
+	session := tm.StartTest(internal.TestConfig{Mode: "client", Addr: addr, InsecureSkipVerify: true, Rate: 100, PacketSize: 1200, Connections: 1, Streams: 1})
+	// The final save has to land before the directory is removed
+	t.Cleanup(func() {
+		tm.StopTest(session.ID)
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if sessions, _ := store.Load(); len(sessions) == 1 && sessions[0].FinalMetrics != nil {
+				break
+			}
+		}
+	})
+
+	// Without a stop or an end, only the periodic saves reach the disk: this
+	// is what a crash would leave behind
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sessions, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) == 1 && len(sessions[0].History) >= 2 {
+			saved := sessions[0]
+			if saved.Status != "running" {
+				t.Errorf("Expected a snapshot of the running test, got %s", saved.Status)
+			}
+			if sent, _ := metricFloat(saved.Metrics["bytes_sent"]); sent <= 0 {
+				t.Errorf("Expected the snapshot to hold the live metrics, got %v", saved.Metrics)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a snapshot with intermediate history on disk, got %d sessions", len(sessions))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -1,6 +1,7 @@
 package gui
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -182,7 +183,7 @@ func (s *Server) renderNewTestHTML(w http.ResponseWriter, data interface{}) {
                     </div>
                     <div class="form-group">
                         <label for="duration">Duration</label>
-                        <input type="text" id="duration" name="duration" value="60s" placeholder="e.g., 60s, 5m">
+                        <input type="text" id="duration" name="duration" value="60s" placeholder="e.g., 60s, 5m; 0 runs until stopped">
                     </div>
                     <div class="form-group">
                         <label for="connections">Connections</label>
@@ -345,7 +346,9 @@ func (s *Server) renderTestListHTML(w http.ResponseWriter, data interface{}) {
                         <option value="completed">Completed</option>
                         <option value="failed">Failed</option>
                         <option value="stopped">Stopped</option>
+                        <option value="interrupted">Interrupted</option>
                     </select>
+                    <button id="compare-btn" class="btn btn-primary" disabled>Compare selected</button>
                 </div>
             </div>
 
@@ -356,6 +359,24 @@ func (s *Server) renderTestListHTML(w http.ResponseWriter, data interface{}) {
     </main>
 
     <script>
+        // Finished tests ticked for comparison, kept across refreshes
+        const selectedTests = new Set();
+        
+        function updateCompareButton() {
+            const button = document.getElementById('compare-btn');
+            button.disabled = selectedTests.size < 2;
+            button.textContent = 'Compare selected' + (selectedTests.size > 0 ? ' (' + selectedTests.size + ')' : '');
+        }
+        
+        function toggleCompare(checkbox) {
+            if (checkbox.checked) {
+                selectedTests.add(checkbox.value);
+            } else {
+                selectedTests.delete(checkbox.value);
+            }
+            updateCompareButton();
+        }
+        
         function loadTestList() {
             fetch('/api/tests')
                 .then(response => response.json())
@@ -378,9 +399,15 @@ func (s *Server) renderTestListHTML(w http.ResponseWriter, data interface{}) {
                             Math.round((new Date(test.end_time) - new Date(test.start_time)) / 1000) + 's' : 
                             'Running';
                         
+                        // Running tests have no complete history to compare yet
+                        const compareBox = test.status === 'running' ? '' :
+                            '<input type="checkbox" class="compare-select" value="' + test.id + '"' +
+                            (selectedTests.has(test.id) ? ' checked' : '') +
+                            ' onchange="toggleCompare(this)" title="Select for comparison"> ';
+                        
                         return '<div class="test-item">' +
                             '<div class="test-header">' +
-                            '<h3><a href="/test/' + test.id + '">' + test.id + '</a></h3>' +
+                            '<h3>' + compareBox + '<a href="/test/' + test.id + '">' + test.id + '</a></h3>' +
                             '<span class="test-status status-' + test.status + '">' + test.status + '</span>' +
                             '</div>' +
                             '<div class="test-details">' +
@@ -405,6 +432,12 @@ func (s *Server) renderTestListHTML(w http.ResponseWriter, data interface{}) {
         // Auto-refresh every 5 seconds
         setInterval(loadTestList, 5000);
         
+        document.getElementById('compare-btn').addEventListener('click', () => {
+            if (selectedTests.size >= 2) {
+                window.location.href = '/tests/compare?ids=' + Array.from(selectedTests).map(encodeURIComponent).join(',');
+            }
+        });
+        
         // Search and filter functionality
         document.getElementById('search-tests').addEventListener('input', filterTests);
         document.getElementById('filter-status').addEventListener('change', filterTests);
@@ -499,7 +532,7 @@ func (s *Server) renderDocsHTML(w http.ResponseWriter, data interface{}) {
                     
                     <h3>Basic Parameters</h3>
                     <ul>
-                        <li><strong>Duration:</strong> How long to run the test (e.g., 60s, 5m, 1h)</li>
+                        <li><strong>Duration:</strong> How long to run the test (e.g., 60s, 5m, 1h); 0 runs the test until it is stopped</li>
                         <li><strong>Connections:</strong> Number of parallel QUIC connections</li>
                         <li><strong>Streams:</strong> Number of streams per connection</li>
                         <li><strong>Packet Rate:</strong> Packets per second to send</li>
@@ -713,12 +746,14 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
   "fec_enabled": false,
   "fec_redundancy": 0.10
 }</code></pre>
+                    <p><code>duration</code> defaults to 60s when omitted. A zero duration (<code>0</code> or <code>"0s"</code>)
+                    or <code>"unlimited": true</code> runs the test until it is stopped with <code>DELETE /api/tests/{id}</code>.</p>
                     
                     <h4>Response</h4>
                     <pre><code>{
   "success": true,
   "data": {
-    "id": "test_1704110400",
+    "id": "test_1704110400000000000_1",
     "status": "running",
     "start_time": "2024-01-01T12:00:00Z",
     "config": { ... }
@@ -736,13 +771,13 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
                     <pre><code>{
   "success": true,
   "data": {
-    "id": "test_1704110400",
+    "id": "test_1704110400000000000_1",
     "status": "running",
     "start_time": "2024-01-01T12:00:00Z",
     "metrics": {
       "latency_ms": 45.2,
       "throughput_mbps": 125.8,
-      "packet_loss": 0.01,
+      "packet_loss_ratio": 0.01,
       "connections": 2
     },
     "logs": [
@@ -752,6 +787,34 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
   }
 }</code></pre>
                     
+                    <h3>Get Test Logs</h3>
+                    <div class="api-endpoint">
+                        <div class="method get">GET</div>
+                        <div class="path">/api/tests/{id}/logs</div>
+                    </div>
+                    <p>Retrieve only the logs of a test, as structured entries. A session keeps its last 100 entries.</p>
+                    
+                    <h4>Query Parameters</h4>
+                    <ul>
+                        <li><code>tail</code> - Return only the last N entries</li>
+                        <li><code>since</code> - Return only entries written after an RFC3339 timestamp, e.g. the <code>timestamp</code> of the last entry already seen</li>
+                    </ul>
+                    
+                    <h4>Response</h4>
+                    <pre><code>{
+  "success": true,
+  "data": {
+    "test_id": "test_1704110400000000000_1",
+    "logs": [
+      {
+        "timestamp": "2024-01-01T12:00:02.123456789Z",
+        "level": "info",
+        "message": "Server started, beginning client test"
+      }
+    ]
+  }
+}</code></pre>
+                    
                     <h3>Stop Test</h3>
                     <div class="api-endpoint">
                         <div class="method delete">DELETE</div>
@@ -767,6 +830,25 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
   }
 }</code></pre>
                     
+                    <h3>Resume Test</h3>
+                    <div class="api-endpoint">
+                        <div class="method post">POST</div>
+                        <div class="path">/api/tests/{id}/resume</div>
+                    </div>
+                    <p>Continue a test that a GUI restart interrupted, toward its original duration. Counters and the history continue from the saved values. A test that is not interrupted returns 409.</p>
+                    
+                    <h3>Change Send Rate</h3>
+                    <div class="api-endpoint">
+                        <div class="method patch">PATCH</div>
+                        <div class="path">/api/tests/{id}</div>
+                    </div>
+                    <p>Change the send rate of a running client or integrated test without restarting it. Every change is added to the test logs and to <code>rate_changes</code>, and history points carry the <code>rate_pps</code> in effect. A test that is no longer running returns 409.</p>
+                    
+                    <h4>Request Body</h4>
+                    <pre><code>{
+  "rate": 500
+}</code></pre>
+                    
                     <h3>List Tests</h3>
                     <div class="api-endpoint">
                         <div class="method get">GET</div>
@@ -776,10 +858,22 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
                     
                     <h4>Query Parameters</h4>
                     <ul>
-                        <li><code>status</code> - Filter by status (running, completed, failed, stopped)</li>
+                        <li><code>status</code> - Filter by status (running, completed, failed, stopped, interrupted)</li>
                         <li><code>limit</code> - Maximum number of results (default: 50)</li>
                         <li><code>offset</code> - Number of results to skip (default: 0)</li>
                     </ul>
+                    
+                    <h3>Compare Tests</h3>
+                    <div class="api-endpoint">
+                        <div class="method get">GET</div>
+                        <div class="path">/api/tests/compare</div>
+                    </div>
+                    <p>Compare two or more finished tests. Returns averaged metrics per test and deltas against the first test (the baseline).</p>
+                    
+                    <h4>Query Parameters</h4>
+                    <ul>
+                        <li><code>ids</code> - Comma-separated test IDs, at least two; running tests are rejected</li>
+                    </ul>
                 </section>
 
                 <section id="metrics-api">
@@ -801,7 +895,7 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
                     
                     <h4>Query Parameters</h4>
                     <ul>
-                        <li><code>test_id</code> - Specific test ID</li>
+                        <li><code>test_id</code> - Specific test ID; samples carry <code>elapsed_seconds</code> since the test start</li>
                         <li><code>start_time</code> - Start time (ISO 8601)</li>
                         <li><code>end_time</code> - End time (ISO 8601)</li>
                         <li><code>interval</code> - Data interval (1s, 5s, 1m, etc.)</li>
@@ -813,6 +907,14 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
                         <div class="path">/api/metrics/prometheus</div>
                     </div>
                     <p>Get metrics in Prometheus format for scraping.</p>
+                    
+                    <h3>Metrics Schema</h3>
+                    <div class="api-endpoint">
+                        <div class="method get">GET</div>
+                        <div class="path">/api/metrics/schema</div>
+                    </div>
+                    <p>Get the unit and description of every numeric metric field. Field names carry their unit as a suffix:
+                    <code>_ms</code>, <code>_seconds</code>, <code>_mbps</code>, <code>_ratio</code> (fraction 0..1) and <code>_percent</code> (0..100); unsuffixed fields are counts.</p>
                 </section>
 
                 <section id="websocket-api">
@@ -825,12 +927,12 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
                     <h3>Message Format</h3>
                     <pre><code>{
   "type": "metrics_update",
-  "test_id": "test_1704110400",
+  "test_id": "test_1704110400000000000_1",
   "timestamp": "2024-01-01T12:00:00Z",
   "data": {
     "latency_ms": 45.2,
     "throughput_mbps": 125.8,
-    "packet_loss": 0.01
+    "packet_loss_ratio": 0.01
   }
 }</code></pre>
                 </section>
@@ -849,7 +951,7 @@ func (s *Server) renderAPIDocsHTML(w http.ResponseWriter, data interface{}) {
   }'</code></pre>
                     
                     <h3>Monitor Test Progress</h3>
-                    <pre><code>curl http://localhost:8081/api/tests/test_1704110400</code></pre>
+                    <pre><code>curl http://localhost:8081/api/tests/test_1704110400000000000_1</code></pre>
                     
                     <h3>Get Prometheus Metrics</h3>
                     <pre><code>curl http://localhost:8081/api/metrics/prometheus</code></pre>
@@ -1012,6 +1114,12 @@ func (s *Server) renderTestDetailsHTML(w http.ResponseWriter, data interface{})
                 </div>
             </div>
 
+            <div class="metrics-card" id="rate-control" style="display: none;">
+                <h3>Send Rate</h3>
+                <input type="range" id="rate-slider" min="1" max="10000" step="1">
+                <span id="rate-value"></span>
+            </div>
+
             <div class="logs-card">
                 <h3>Test Logs</h3>
                 <div class="logs-container" id="test-logs">
@@ -1024,6 +1132,36 @@ func (s *Server) renderTestDetailsHTML(w http.ResponseWriter, data interface{})
     <script>
         const testId = '%s';
         let refreshInterval;
+        let lastLogTime = '';
+
+        // Only the log entries written since the previous poll are fetched
+        function updateTestLogs() {
+            let url = '/api/tests/' + testId + '/logs';
+            if (lastLogTime) {
+                url += '?since=' + encodeURIComponent(lastLogTime);
+            }
+            fetch(url)
+                .then(response => response.json())
+                .then(data => {
+                    if (!data.success || data.data.logs.length === 0) {
+                        return;
+                    }
+                    const container = document.getElementById('test-logs');
+                    if (!lastLogTime) {
+                        container.innerHTML = '';
+                    }
+                    data.data.logs.forEach(log => {
+                        const entry = document.createElement('div');
+                        entry.className = 'log-entry log-' + log.level;
+                        entry.textContent = '[' + new Date(log.timestamp).toTimeString().slice(0, 8) + '] ' + log.message;
+                        container.appendChild(entry);
+                    });
+                    lastLogTime = data.data.logs[data.data.logs.length - 1].timestamp;
+                })
+                .catch(error => {
+                    console.error('Failed to update test logs:', error);
+                });
+        }
 
         function updateTestDetails() {
             fetch('/api/tests/' + testId)
@@ -1045,6 +1183,21 @@ func (s *Server) renderTestDetailsHTML(w http.ResponseWriter, data interface{})
                             stopBtn.style.display = 'none';
                         }
                         
+                        // The send rate can change only while a client test runs;
+                        // the slider is not reset while it is being dragged
+                        const rateControl = document.getElementById('rate-control');
+                        const rateSlider = document.getElementById('rate-slider');
+                        if (test.status === 'running' && test.config.Mode !== 'server') {
+                            rateControl.style.display = 'block';
+                            if (!rateDragging) {
+                                rateSlider.max = Math.max(10000, test.rate * 10);
+                                rateSlider.value = test.rate;
+                                document.getElementById('rate-value').textContent = test.rate + ' pps';
+                            }
+                        } else {
+                            rateControl.style.display = 'none';
+                        }
+                        
                         // Update metrics
                         if (test.metrics) {
                             document.getElementById('metric-latency').textContent = 
@@ -1052,7 +1205,7 @@ func (s *Server) renderTestDetailsHTML(w http.ResponseWriter, data interface{})
                             document.getElementById('metric-throughput').textContent = 
                                 test.metrics.throughput_mbps ? test.metrics.throughput_mbps.toFixed(1) + ' Mbps' : 'N/A';
                             document.getElementById('metric-packet-loss').textContent = 
-                                test.metrics.packet_loss ? (test.metrics.packet_loss * 100).toFixed(2) + '%%' : 'N/A';
+                                test.metrics.packet_loss_ratio ? (test.metrics.packet_loss_ratio * 100).toFixed(2) + '%%' : 'N/A';
                             document.getElementById('metric-connections').textContent = 
                                 test.metrics.connections || '0';
                             document.getElementById('metric-elapsed').textContent = 
@@ -1060,12 +1213,7 @@ func (s *Server) renderTestDetailsHTML(w http.ResponseWriter, data interface{})
                         }
                         
                         // Update logs
-                        if (test.logs && test.logs.length > 0) {
-                            const logsHtml = test.logs.map(log => 
-                                '<div class="log-entry">' + log + '</div>'
-                            ).join('');
-                            document.getElementById('test-logs').innerHTML = logsHtml;
-                        }
+                        updateTestLogs();
                         
                         // Stop auto-refresh if test is completed
                         if (test.status !== 'running' && refreshInterval) {
@@ -1097,9 +1245,38 @@ func (s *Server) renderTestDetailsHTML(w http.ResponseWriter, data interface{})
             }
         }
 
+        let rateDragging = false;
+
+        function setRate(rate) {
+            fetch('/api/tests/' + testId, {
+                method: 'PATCH',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ rate: rate })
+            })
+                .then(response => response.json())
+                .then(result => {
+                    rateDragging = false;
+                    if (!result.success) {
+                        alert('Failed to change rate: ' + (result.error || 'Unknown error'));
+                    }
+                    updateTestDetails();
+                })
+                .catch(error => {
+                    rateDragging = false;
+                    console.error('Failed to change rate:', error);
+                });
+        }
+
         // Event listeners
         document.getElementById('refresh-btn').addEventListener('click', updateTestDetails);
         document.getElementById('stop-btn').addEventListener('click', stopTest);
+        document.getElementById('rate-slider').addEventListener('input', event => {
+            rateDragging = true;
+            document.getElementById('rate-value').textContent = event.target.value + ' pps';
+        });
+        document.getElementById('rate-slider').addEventListener('change', event => {
+            setRate(parseInt(event.target.value, 10));
+        });
 
         // Initial load and auto-refresh
         updateTestDetails();
@@ -1132,4 +1309,211 @@ func (s *Server) renderTestDetailsHTML(w http.ResponseWriter, data interface{})
 		d.Session.ID)
 	
 	w.Write([]byte(html))
-}
\ No newline at end of file
+}
+
+// renderTestCompareHTML renders the page comparing two or more finished tests
+func (s *Server) renderTestCompareHTML(w http.ResponseWriter, data interface{}) {
+	d := data.(struct {
+		Title string
+		IDs   []string
+	})
+	
+	// json.Marshal escapes <, > and &, so the IDs are safe inside <script>
+	ids, _ := json.Marshal(d.IDs)
+	
+	html := fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>%s - QUIC Test Suite</title>
+    <link rel="stylesheet" href="/static/css/style.css">
+    <style>
+        .compare-chart { margin-bottom: 24px; }
+        .compare-chart svg { width: 100%%; height: 240px; background: #fff; border: 1px solid #ddd; }
+        .compare-legend span { display: inline-block; margin-right: 16px; }
+        .compare-legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; }
+        .delta-better { color: #2e7d32; }
+        .delta-worse { color: #c62828; }
+    </style>
+</head>
+<body>
+    <nav class="navbar">
+        <div class="nav-brand">
+            <h1>QUIC Test Suite</h1>
+        </div>
+        <div class="nav-links">
+            <a href="/">Dashboard</a>
+            <a href="/test/new">New Test</a>
+            <a href="/tests" class="active">Test History</a>
+            <a href="/docs">Documentation</a>
+            <a href="/api-docs">API Docs</a>
+        </div>
+    </nav>
+
+    <main class="container">
+        <div class="page-header">
+            <h2>Compare Tests</h2>
+            <p>Time series are aligned on elapsed time since each test started</p>
+            <div class="test-actions">
+                <a href="/tests" class="btn btn-secondary">Back to List</a>
+            </div>
+        </div>
+
+        <div id="compare-error" class="error-message" style="display: none;"></div>
+        <div id="compare-legend" class="compare-legend"></div>
+        <div id="compare-charts"></div>
+
+        <h3>Summary</h3>
+        <div id="compare-summary"><p>Loading comparison...</p></div>
+
+        <h3>Delta vs. baseline</h3>
+        <div id="compare-deltas"></div>
+    </main>
+
+    <script>
+        const testIds = %s;
+        const colors = ['#1f77b4', '#ff7f0e', '#2ca02c', '#d62728', '#9467bd', '#8c564b', '#e377c2', '#7f7f7f'];
+        const charts = [
+            { key: 'latency_ms', title: 'Latency (ms)' },
+            { key: 'throughput_mbps', title: 'Throughput (Mbps)' },
+            { key: 'packet_loss_ratio', title: 'Packet Loss (ratio)' }
+        ];
+        // Whether a higher value of the metric is an improvement
+        const higherIsBetter = { avg_throughput_mbps: true };
+
+        function showError(message) {
+            const el = document.getElementById('compare-error');
+            el.textContent = message;
+            el.style.display = 'block';
+            document.getElementById('compare-summary').innerHTML = '';
+        }
+
+        function escapeHTML(text) {
+            const div = document.createElement('div');
+            div.textContent = text;
+            return div.innerHTML;
+        }
+
+        function fetchJSON(url) {
+            return fetch(url)
+                .then(response => response.json())
+                .then(result => {
+                    if (!result.success) {
+                        throw new Error(result.error || 'Unknown error');
+                    }
+                    return result.data;
+                });
+        }
+
+        // renderChart overlays one line per test; the x axis is elapsed seconds
+        // so tests of different durations share the same scale
+        function renderChart(chart, histories) {
+            const width = 800, height = 240, pad = 40;
+            let maxX = 0, maxY = 0;
+            histories.forEach(h => h.metrics.forEach(p => {
+                maxX = Math.max(maxX, p.elapsed_seconds);
+                maxY = Math.max(maxY, p[chart.key]);
+            }));
+            maxX = maxX || 1;
+            maxY = maxY * 1.1 || 1;
+
+            const x = v => pad + (v / maxX) * (width - 2 * pad);
+            const y = v => height - pad - (v / maxY) * (height - 2 * pad);
+
+            let svg = '<svg viewBox="0 0 ' + width + ' ' + height + '" preserveAspectRatio="none">';
+            svg += '<line x1="' + pad + '" y1="' + (height - pad) + '" x2="' + (width - pad) + '" y2="' + (height - pad) + '" stroke="#999"/>';
+            svg += '<line x1="' + pad + '" y1="' + pad + '" x2="' + pad + '" y2="' + (height - pad) + '" stroke="#999"/>';
+            svg += '<text x="' + (width - pad) + '" y="' + (height - pad + 16) + '" font-size="11" text-anchor="end">' + maxX.toFixed(0) + 's</text>';
+            svg += '<text x="' + pad + '" y="' + (height - pad + 16) + '" font-size="11">0s</text>';
+            svg += '<text x="' + (pad - 4) + '" y="' + (pad + 4) + '" font-size="11" text-anchor="end">' + maxY.toFixed(2) + '</text>';
+
+            histories.forEach((h, i) => {
+                if (h.metrics.length === 0) {
+                    return;
+                }
+                const points = h.metrics.map(p => x(p.elapsed_seconds).toFixed(1) + ',' + y(p[chart.key]).toFixed(1)).join(' ');
+                svg += '<polyline fill="none" stroke-width="2" stroke="' + colors[i %% colors.length] + '" points="' + points + '"/>';
+            });
+            svg += '</svg>';
+
+            return '<div class="compare-chart"><h3>' + chart.title + '</h3>' + svg + '</div>';
+        }
+
+        function renderSummary(comparison) {
+            const rows = comparison.tests.map((t, i) =>
+                '<tr>' +
+                '<td><i style="display:inline-block;width:12px;height:12px;background:' + colors[i %% colors.length] + '"></i> ' +
+                '<a href="/test/' + encodeURIComponent(t.id) + '">' + escapeHTML(t.id) + '</a>' +
+                (t.id === comparison.baseline ? ' (baseline)' : '') + '</td>' +
+                '<td>' + escapeHTML(t.status) + '</td>' +
+                '<td>' + t.duration_seconds.toFixed(1) + 's</td>' +
+                '<td>' + t.samples + '</td>' +
+                '<td>' + t.avg_latency_ms.toFixed(2) + '</td>' +
+                '<td>' + t.avg_throughput_mbps.toFixed(2) + '</td>' +
+                '<td>' + t.avg_packet_loss_ratio.toFixed(4) + '</td>' +
+                '</tr>').join('');
+
+            document.getElementById('compare-summary').innerHTML =
+                '<table class="data-table"><thead><tr><th>Test</th><th>Status</th><th>Duration</th><th>Samples</th>' +
+                '<th>Avg Latency (ms)</th><th>Avg Throughput (Mbps)</th><th>Avg Packet Loss (ratio)</th></tr></thead>' +
+                '<tbody>' + rows + '</tbody></table>';
+        }
+
+        function renderDeltas(comparison) {
+            const rows = comparison.deltas.map(d => {
+                let cls = '';
+                if (d.delta !== 0 && d.metric !== 'duration_seconds') {
+                    const improved = (d.delta > 0) === !!higherIsBetter[d.metric];
+                    cls = improved ? 'delta-better' : 'delta-worse';
+                }
+                const percent = d.delta_percent === undefined ? 'n/a' :
+                    (d.delta_percent > 0 ? '+' : '') + d.delta_percent.toFixed(1) + '%%';
+                return '<tr>' +
+                    '<td>' + escapeHTML(d.test_id) + '</td>' +
+                    '<td>' + escapeHTML(d.metric) + '</td>' +
+                    '<td>' + d.baseline.toFixed(4) + '</td>' +
+                    '<td>' + d.value.toFixed(4) + '</td>' +
+                    '<td class="' + cls + '">' + (d.delta > 0 ? '+' : '') + d.delta.toFixed(4) + '</td>' +
+                    '<td class="' + cls + '">' + percent + '</td>' +
+                    '</tr>';
+            }).join('');
+
+            document.getElementById('compare-deltas').innerHTML =
+                '<table class="data-table"><thead><tr><th>Test</th><th>Metric</th><th>Baseline</th><th>Value</th>' +
+                '<th>Delta</th><th>Delta %%</th></tr></thead><tbody>' + rows + '</tbody></table>';
+        }
+
+        function loadComparison() {
+            if (testIds.length < 2) {
+                showError('Select at least two completed tests on the Test History page.');
+                return;
+            }
+
+            const query = testIds.map(encodeURIComponent).join(',');
+            const comparison = fetchJSON('/api/tests/compare?ids=' + query);
+            const histories = Promise.all(testIds.map(id =>
+                fetchJSON('/api/metrics/history?interval=1s&test_id=' + encodeURIComponent(id))));
+
+            Promise.all([comparison, histories])
+                .then(([comparison, histories]) => {
+                    document.getElementById('compare-legend').innerHTML = testIds.map((id, i) =>
+                        '<span><i style="background:' + colors[i %% colors.length] + '"></i>' + escapeHTML(id) + '</span>').join('');
+                    document.getElementById('compare-charts').innerHTML =
+                        charts.map(chart => renderChart(chart, histories)).join('');
+                    renderSummary(comparison);
+                    renderDeltas(comparison);
+                })
+                .catch(error => {
+                    console.error('Failed to load comparison:', error);
+                    showError('Failed to load comparison: ' + error.message);
+                });
+        }
+
+        loadComparison();
+    </script>
+</body>
+</html>`, d.Title, ids)
+	
+	w.Write([]byte(html))
+}
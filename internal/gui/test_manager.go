@@ -2,19 +2,62 @@ package gui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"sync/atomic"
 	"time"
 
+	"quic-test/client"
 	"quic-test/internal"
+	"quic-test/server"
 )
 
+// maxMetricHistory is the default cap on the points of a test's history
+const maxMetricHistory = 3600
+
+// MetricSample is one point of a test's metric history. A point may
+// aggregate several raw samples: values are then averages over Count
+// samples and the min/max fields keep their extremes.
+type MetricSample struct {
+	Timestamp         time.Time `json:"timestamp"`
+	ElapsedSeconds    float64   `json:"elapsed_seconds"` // Since the test start, used to align tests
+	LatencyMs         float64   `json:"latency_ms"`
+	ThroughputMbps    float64   `json:"throughput_mbps"`
+	PacketLoss        float64   `json:"packet_loss_ratio"` // Fraction of lost packets, 0..1
+	Count             int       `json:"count"`
+	LatencyMinMs      float64   `json:"latency_min_ms"`
+	LatencyMaxMs      float64   `json:"latency_max_ms"`
+	ThroughputMinMbps float64   `json:"throughput_min_mbps"`
+	ThroughputMaxMbps float64   `json:"throughput_max_mbps"`
+	RatePps           float64   `json:"rate_pps"` // Send rate in packets per second
+}
+
+// RateChange is a live change of a running test's send rate
+type RateChange struct {
+	Timestamp      time.Time `json:"timestamp"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	From           int       `json:"from"`
+	To             int       `json:"to"`
+}
+
+// errTestNotRunning is returned for changes to a test that has ended
+var errTestNotRunning = errors.New("test is not running")
+
+// testSeq numbers the tests started by this process
+var testSeq atomic.Uint64
+
+// newTestID returns a unique test ID. The sequence number keeps tests
+// started within the same clock tick apart.
+func newTestID() string {
+	return fmt.Sprintf("test_%d_%d", time.Now().UnixNano(), testSeq.Add(1))
+}
+
 // StartTest starts a new test session
 func (tm *TestManager) StartTest(config internal.TestConfig) *TestSession {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
 	
-	// Generate unique test ID
-	testID := fmt.Sprintf("test_%d", time.Now().Unix())
+	testID := newTestID()
 	
 	session := &TestSession{
 		ID:        testID,
@@ -23,9 +66,17 @@ func (tm *TestManager) StartTest(config internal.TestConfig) *TestSession {
 		StartTime: time.Now(),
 		Metrics:   make(map[string]interface{}),
 		Logs:      make([]string, 0),
+		Rate:      config.Rate,
 	}
+	session.updated = session.StartTime
+	session.historyInterval = tm.metricsInterval
+	session.maxHistoryPoints = tm.maxHistoryPoints
 	
 	tm.activeTests[testID] = session
+	tm.evictLocked()
+	tm.mu.Unlock()
+	
+	tm.persist(session)
 	
 	// Start test in background
 	go tm.runTest(session)
@@ -44,9 +95,8 @@ func (tm *TestManager) StopTest(testID string) error {
 	}
 	
 	session.mu.Lock()
-	defer session.mu.Unlock()
-	
 	if session.Status != "running" {
+		session.mu.Unlock()
 		return fmt.Errorf("test is not running: %s", testID)
 	}
 	
@@ -54,10 +104,55 @@ func (tm *TestManager) StopTest(testID string) error {
 	now := time.Now()
 	session.EndTime = &now
 	session.addLog("Test stopped by user")
+	session.mu.Unlock()
+	
+	tm.persist(session)
+	return nil
+}
+
+// SetRate changes the send rate of a running client or integrated test
+// without restarting it. The change is logged and kept in RateChanges.
+func (tm *TestManager) SetRate(testID string, rate int) error {
+	tm.mu.RLock()
+	session, exists := tm.activeTests[testID]
+	tm.mu.RUnlock()
+	
+	if !exists {
+		return fmt.Errorf("test not found: %s", testID)
+	}
+	if rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	
+	if session.Status != "running" {
+		return fmt.Errorf("%w: %s", errTestNotRunning, testID)
+	}
+	if session.Config.Mode == "server" {
+		return errors.New("a server test has no send rate")
+	}
 	
+	now := time.Now()
+	session.RateChanges = append(session.RateChanges, RateChange{
+		Timestamp:      now,
+		ElapsedSeconds: session.elapsedLocked(now).Seconds(),
+		From:           session.Rate,
+		To:             rate,
+	})
+	session.addLog(fmt.Sprintf("Send rate changed from %d to %d pps", session.Rate, rate))
+	session.Rate = rate
 	return nil
 }
 
+// currentRate returns the send rate the test should use now
+func (ts *TestSession) currentRate() int {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.Rate
+}
+
 // GetTest retrieves a test session by ID
 func (tm *TestManager) GetTest(testID string) *TestSession {
 	tm.mu.RLock()
@@ -66,7 +161,8 @@ func (tm *TestManager) GetTest(testID string) *TestSession {
 	return tm.activeTests[testID]
 }
 
-// GetAllTests returns all test sessions
+// GetAllTests returns all test sessions, oldest first, so that listings and
+// their ETags are stable between requests
 func (tm *TestManager) GetAllTests() []*TestSession {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
@@ -75,6 +171,7 @@ func (tm *TestManager) GetAllTests() []*TestSession {
 	for _, session := range tm.activeTests {
 		tests = append(tests, session)
 	}
+	sortSessions(tests)
 	
 	return tests
 }
@@ -106,13 +203,15 @@ func (tm *TestManager) GetTotalTestCount() int {
 
 // runTest executes a test session
 func (tm *TestManager) runTest(session *TestSession) {
+	// Deferred first, so the final state is saved after a panic too
+	defer tm.persist(session)
 	defer func() {
 		if r := recover(); r != nil {
 			session.mu.Lock()
 			session.Status = "failed"
 			now := time.Now()
 			session.EndTime = &now
-			session.addLog(fmt.Sprintf("Test failed with panic: %v", r))
+			session.addLogLevel(LogLevelError, fmt.Sprintf("Test failed with panic: %v", r))
 			session.mu.Unlock()
 		}
 	}()
@@ -123,9 +222,30 @@ func (tm *TestManager) runTest(session *TestSession) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	
-	// Monitor for stop requests
+	// Periodic saves stop before the final one, so none can overwrite it
+	flushCtx, stopFlush := context.WithCancel(context.Background())
+	flushDone := make(chan struct{})
 	go func() {
+		defer close(flushDone)
+		tm.flushLoop(flushCtx, session)
+	}()
+	defer func() {
+		stopFlush()
+		<-flushDone
+	}()
+	
+	// Monitor for stop requests until the test ends; a test with zero
+	// duration is unlimited and ends only here
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
 		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			
 			session.mu.RLock()
 			status := session.Status
 			session.mu.RUnlock()
@@ -134,15 +254,13 @@ func (tm *TestManager) runTest(session *TestSession) {
 				cancel()
 				return
 			}
-			
-			time.Sleep(100 * time.Millisecond)
 		}
 	}()
 	
 	// Run the actual test based on mode
 	switch session.Config.Mode {
 	case "server":
-		tm.runServerTest(ctx, session)
+		tm.runServerTest(ctx, session, nil)
 	case "client":
 		tm.runClientTest(ctx, session)
 	case "test":
@@ -152,12 +270,13 @@ func (tm *TestManager) runTest(session *TestSession) {
 		session.Status = "failed"
 		now := time.Now()
 		session.EndTime = &now
-		session.addLog(fmt.Sprintf("Unknown test mode: %s", session.Config.Mode))
+		session.addLogLevel(LogLevelError, fmt.Sprintf("Unknown test mode: %s", session.Config.Mode))
 		session.mu.Unlock()
 		return
 	}
 	
-	// Mark test as completed if not already stopped/failed
+	// Mark test as completed if not already stopped/failed; completed and
+	// stopped tests both get final metrics and a report
 	session.mu.Lock()
 	if session.Status == "running" {
 		session.Status = "completed"
@@ -165,15 +284,42 @@ func (tm *TestManager) runTest(session *TestSession) {
 		session.EndTime = &now
 		session.addLog("Test completed successfully")
 	}
+	if session.Status == "completed" || session.Status == "stopped" {
+		session.finalizeLocked()
+		session.addLog("Final metrics and report computed")
+	}
 	session.mu.Unlock()
 }
 
-// runServerTest runs a server-only test
-func (tm *TestManager) runServerTest(ctx context.Context, session *TestSession) {
+// runServerTest runs the QUIC server until the test's duration is reached
+// or, with zero duration, until it is stopped or ctx is cancelled. The server's own metrics are
+// fed into the session every second.
+func (tm *TestManager) runServerTest(ctx context.Context, session *TestSession, ready func()) {
 	session.addLogSafe("Starting QUIC server")
 	
-	// This would integrate with the actual server implementation
-	// For now, simulate server operation
+	// The server stops with ctx; a listen failure fails the test instead of
+	// taking the GUI process down
+	serverCtx, stopServer := context.WithCancel(ctx)
+	hooks := server.Hooks{
+		Stats: func(stats server.Stats) {
+			session.updateMetrics(serverMetricsMap(stats))
+			session.setFECHealth(FECHealthStats{OpenGroups: stats.FECOpenGroups, MemoryBytes: stats.FECBufferedBytes})
+		},
+	}
+	if ready != nil {
+		hooks.Ready = func(net.Addr) { ready() }
+	}
+	var serverErr error
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		serverErr = server.RunWithHooks(serverCtx, session.Config.ServerConfig(), hooks)
+	}()
+	defer func() {
+		stopServer()
+		<-serverDone
+	}()
+	
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 	
@@ -182,51 +328,100 @@ func (tm *TestManager) runServerTest(ctx context.Context, session *TestSession)
 		case <-ctx.Done():
 			session.addLogSafe("Server test stopped")
 			return
+		case <-serverDone:
+			if serverErr != nil {
+				session.mu.Lock()
+				session.Status = "failed"
+				now := time.Now()
+				session.EndTime = &now
+				session.addLogLevel(LogLevelError, fmt.Sprintf("Server failed: %v", serverErr))
+				session.mu.Unlock()
+			}
+			return
 		case <-ticker.C:
-			// Update metrics (simulated)
-			session.updateMetrics(map[string]interface{}{
-				"connections": 0,
-				"bytes_received": 0,
-				"uptime": time.Since(session.StartTime).Seconds(),
-			})
+			// In an integrated test the server runs as long as the client
+			session.mu.RLock()
+			done := session.Config.Mode == "server" && session.Config.Duration > 0 && session.elapsedLocked(time.Now()) >= session.Config.Duration
+			session.mu.RUnlock()
+			if done {
+				session.addLogSafe("Test duration reached")
+				return
+			}
 		}
 	}
 }
 
-// runClientTest runs a client-only test
+// serverMetricsMap converts server stats to session metrics. The names
+// differ from the client's, so both sides of an integrated test are kept.
+func serverMetricsMap(stats server.Stats) map[string]interface{} {
+	return map[string]interface{}{
+		"server_connections": stats.Connections,
+		"server_streams":     stats.Streams,
+		"bytes_received":     stats.Bytes,
+		"server_errors":      stats.Errors,
+		"corrupted_packets":  stats.Pattern.Corrupted,
+		"uptime_seconds":     stats.Uptime.Seconds(),
+	}
+}
+
+// runClientTest runs the QUIC client until its duration is reached or it
+// is stopped. The client's metrics are fed into the session every second,
+// and the client follows the send rate that PATCH /api/tests/{id} sets.
 func (tm *TestManager) runClientTest(ctx context.Context, session *TestSession) {
 	session.addLogSafe("Starting QUIC client test")
 	
-	// This would integrate with the actual client implementation
-	// For now, simulate client operation
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	session.mu.RLock()
+	cfg := session.Config.ClientConfig()
+	// A resumed test runs for what is left of its duration
+	cfg.Duration = session.remainingLocked()
+	session.mu.RUnlock()
+	result, err := client.RunLive(ctx, cfg, client.Live{
+		Stats: func(stats client.LiveStats) {
+			session.updateMetrics(clientMetricsMap(stats))
+			session.setEmulationHealth(EmulationHealthStats{
+				ConfiguredLoss: stats.Emulation.ConfiguredLoss,
+				Packets:        stats.Emulation.Packets,
+				Dropped:        stats.Emulation.Drops,
+				Duplicated:     stats.Emulation.Dups,
+			})
+		},
+		Rate: session.currentRate,
+	})
+	if err != nil {
+		session.mu.Lock()
+		session.Status = "failed"
+		now := time.Now()
+		session.EndTime = &now
+		session.addLogLevel(LogLevelError, fmt.Sprintf("Client failed: %v", err))
+		session.mu.Unlock()
+		return
+	}
 	
-	startTime := time.Now()
+	session.mu.Lock()
+	session.result = result
+	session.mu.Unlock()
 	
-	for {
-		select {
-		case <-ctx.Done():
-			session.addLogSafe("Client test stopped")
-			return
-		case <-ticker.C:
-			elapsed := time.Since(startTime)
-			
-			// Check if duration limit reached
-			if session.Config.Duration > 0 && elapsed >= session.Config.Duration {
-				session.addLogSafe("Test duration reached")
-				return
-			}
-			
-			// Update metrics (simulated)
-			session.updateMetrics(map[string]interface{}{
-				"latency_ms": 50.0 + (10.0 * (0.5 - float64(time.Now().UnixNano()%1000)/1000.0)),
-				"throughput_mbps": 100.0 + (20.0 * (0.5 - float64(time.Now().UnixNano()%1000)/1000.0)),
-				"packet_loss": 0.01,
-				"connections": session.Config.Connections,
-				"elapsed_seconds": elapsed.Seconds(),
-			})
-		}
+	if ctx.Err() != nil {
+		session.addLogSafe("Client test stopped")
+	} else {
+		session.addLogSafe("Test duration reached")
+	}
+	session.addLogSafe(fmt.Sprintf("Client sent %d packets with %d errors", result.Success, result.Errors))
+}
+
+// clientMetricsMap converts live client stats to session metrics
+func clientMetricsMap(stats client.LiveStats) map[string]interface{} {
+	return map[string]interface{}{
+		"latency_ms":        stats.LatencyMs,
+		"throughput_mbps":   stats.ThroughputMbps,
+		"packet_loss_ratio": stats.PacketLoss,
+		"connections":       stats.Connections,
+		"connections_configured": stats.ConfiguredConnections,
+		"bytes_sent":        stats.BytesSent,
+		"packets_sent":      stats.Packets,
+		"errors":            stats.Errors,
+		"elapsed_seconds":   stats.Elapsed.Seconds(),
+		"rate_pps":          stats.Rate,
 	}
 }
 
@@ -234,21 +429,44 @@ func (tm *TestManager) runClientTest(ctx context.Context, session *TestSession)
 func (tm *TestManager) runIntegratedTest(ctx context.Context, session *TestSession) {
 	session.addLogSafe("Starting integrated test (server + client)")
 	
-	// Start server in background
+	// The client trusts the certificate generated for the integrated server
+	session.mu.Lock()
+	err := session.Config.PrepareLoopbackTLS()
+	if err != nil {
+		session.Status = "failed"
+		now := time.Now()
+		session.EndTime = &now
+		session.addLogLevel(LogLevelError, fmt.Sprintf("Test failed: %v", err))
+	}
+	session.mu.Unlock()
+	if err != nil {
+		return
+	}
+	
+	// Start server in background; it runs as long as the client does
+	serverCtx, stopServer := context.WithCancel(ctx)
+	defer stopServer()
 	serverDone := make(chan struct{})
+	serverReady := make(chan struct{})
 	go func() {
 		defer close(serverDone)
-		tm.runServerTest(ctx, session)
+		tm.runServerTest(serverCtx, session, func() { close(serverReady) })
 	}()
 	
-	// Wait a bit for server to start
-	time.Sleep(2 * time.Second)
-	session.addLogSafe("Server started, beginning client test")
-	
-	// Run client test
-	tm.runClientTest(ctx, session)
+	// The client starts once the server accepts connections; there is no
+	// client test if the server failed
+	select {
+	case <-ctx.Done():
+	case <-serverDone:
+	case <-serverReady:
+		session.addLogSafe("Server started, beginning client test")
+		
+		// Run client test
+		tm.runClientTest(ctx, session)
+	}
 	
 	// Wait for server to finish
+	stopServer()
 	<-serverDone
 	session.addLogSafe("Integrated test completed")
 }
@@ -257,14 +475,7 @@ func (tm *TestManager) runIntegratedTest(ctx context.Context, session *TestSessi
 func (ts *TestSession) addLog(message string) {
 	// Note: This method assumes the caller already holds the mutex
 	// If called without mutex, it should be called as addLogSafe
-	timestamp := time.Now().Format("15:04:05")
-	logEntry := fmt.Sprintf("[%s] %s", timestamp, message)
-	ts.Logs = append(ts.Logs, logEntry)
-	
-	// Keep only last 100 log entries
-	if len(ts.Logs) > 100 {
-		ts.Logs = ts.Logs[len(ts.Logs)-100:]
-	}
+	ts.addLogLevel(LogLevelInfo, message)
 }
 
 // addLogSafe adds a log entry with mutex protection
@@ -278,9 +489,39 @@ func (ts *TestSession) updateMetrics(metrics map[string]interface{}) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 	
+	// The map is served as is, so large counters are stored JSON-safe
 	for key, value := range metrics {
-		ts.Metrics[key] = value
+		ts.Metrics[key] = internal.JSONSafeValue(ts.continueMetric(key, value))
+	}
+	ts.updated = time.Now()
+	
+	// Only client updates carry traffic metrics worth keeping in the history
+	if _, ok := metrics["latency_ms"]; !ok {
+		return
+	}
+	now := time.Now()
+	sample := MetricSample{
+		Timestamp:      now,
+		ElapsedSeconds: ts.elapsedLocked(now).Seconds(),
+	}
+	sample.LatencyMs, _ = metrics["latency_ms"].(float64)
+	sample.ThroughputMbps, _ = metrics["throughput_mbps"].(float64)
+	sample.PacketLoss, _ = metrics["packet_loss_ratio"].(float64)
+	if rate, ok := metrics["rate_pps"].(int); ok {
+		sample.RatePps = float64(rate)
 	}
+	ts.recordSampleLocked(sample)
+}
+
+// GetHistory returns a copy of the metric history
+func (ts *TestSession) GetHistory() []MetricSample {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	
+	history := make([]MetricSample, len(ts.History))
+	copy(history, ts.History)
+	
+	return history
 }
 
 // GetMetrics returns a copy of current metrics
@@ -296,6 +537,22 @@ func (ts *TestSession) GetMetrics() map[string]interface{} {
 	return metrics
 }
 
+// GetStatus returns the session status
+func (ts *TestSession) GetStatus() string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	
+	return ts.Status
+}
+
+// LastModified returns when the session status, metrics or logs last changed
+func (ts *TestSession) LastModified() time.Time {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	
+	return ts.updated
+}
+
 // GetLogs returns a copy of current logs
 func (ts *TestSession) GetLogs() []string {
 	ts.mu.RLock()
@@ -0,0 +1,124 @@
+package gui
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+func TestIntegratedTestRunsRealQUIC(t *testing.T) {
+	free, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.LocalAddr().(*net.UDPAddr)
+	free.Close()
+
+	tm := NewTestManager()
+	session := tm.StartTest(internal.TestConfig{Mode: "test", Addr: addr.String(), Rate: 100, PacketSize: 1200, Connections: 1, Streams: 2})
+
+	// Both sides report what actually went over the wire
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		metrics := session.GetMetrics()
+		sent, _ := metrics["bytes_sent"].(int64)
+		received, _ := metrics["bytes_received"].(int64)
+		if sent > 0 && received > 0 && metrics["connections"] == 1 && metrics["server_connections"] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected real client and server metrics, got %v", metrics)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := tm.StopTest(session.ID); err != nil {
+		t.Fatal(err)
+	}
+	// The stop cancels the server too, which frees its port
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.ListenUDP("udp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the stopped test to close the server: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	if session.Status != "stopped" {
+		t.Errorf("Expected status stopped, got %s", session.Status)
+	}
+}
+
+func TestIntegratedClientStartsWhenServerIsReady(t *testing.T) {
+	free, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.LocalAddr().String()
+	free.Close()
+
+	tm := NewTestManager()
+	session := tm.StartTest(internal.TestConfig{Mode: "test", Addr: addr, Rate: 100, PacketSize: 1200, Connections: 1, Streams: 1})
+	defer tm.StopTest(session.ID)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for session.GetMetrics()["connections"] != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the client to connect, got %v", session.GetMetrics())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// The client starts on the server's ready signal, not after a fixed wait
+	var started, clientStarted time.Time
+	for _, entry := range session.GetLogEntries(time.Time{}, 0) {
+		switch entry.Message {
+		case "Starting integrated test (server + client)":
+			started = entry.Timestamp
+		case "Server started, beginning client test":
+			clientStarted = entry.Timestamp
+		}
+	}
+	if started.IsZero() || clientStarted.IsZero() {
+		t.Fatalf("Expected the start logs, got %v", session.GetLogs())
+	}
+	if wait := clientStarted.Sub(started); wait > time.Second {
+		t.Errorf("Expected the client to start as soon as the server was ready, waited %v", wait)
+	}
+}
+
+func TestTestIDsAreUnique(t *testing.T) {
+	// Tests started in a burst, including from several goroutines, fall
+	// within the same second and often the same clock tick
+	const workers, perWorker = 8, 100
+	ids := make(chan string, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ids <- newTestID()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("Duplicate test ID %s", id)
+		}
+		seen[id] = true
+	}
+}
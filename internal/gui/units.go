@@ -0,0 +1,104 @@
+package gui
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Units of numeric metric fields in API responses
+const (
+	UnitMilliseconds = "ms"
+	UnitSeconds      = "s"
+	UnitMbps         = "Mbps"
+	UnitRatio        = "ratio"   // Fraction in 0..1, never a percentage
+	UnitPercent      = "percent" // 0..100
+	UnitBytes        = "bytes"
+	UnitCount        = "count"
+	UnitPps          = "pps" // Packets per second
+)
+
+// MetricField describes one numeric field of the metrics responses
+type MetricField struct {
+	Unit        string `json:"unit"`
+	Description string `json:"description"`
+}
+
+// metricFields is the canonical schema of the numeric fields returned by
+// the test and metrics endpoints. Field names carry their unit as a suffix
+// (see unitFromName); unsuffixed names are plain counts.
+var metricFields = map[string]MetricField{
+	// Test metrics (/api/tests/{id}, /api/metrics/history)
+	"latency_ms":             {UnitMilliseconds, "Round-trip latency"},
+	"throughput_mbps":        {UnitMbps, "Throughput"},
+	"packet_loss_ratio":      {UnitRatio, "Fraction of packets lost"},
+	"elapsed_seconds":        {UnitSeconds, "Time since the test start"},
+	"uptime_seconds":         {UnitSeconds, "Time the server has been running"},
+	"connections":            {UnitCount, "Client connections established and still open"},
+	"connections_configured": {UnitCount, "Client connections the test was configured with"},
+	"bytes_sent":             {UnitBytes, "Bytes sent by the client"},
+	"packets_sent":           {UnitCount, "Packets sent by the client"},
+	"errors":                 {UnitCount, "Client errors"},
+	"rate_pps":               {UnitPps, "Send rate of the client"},
+	"server_connections":     {UnitCount, "Connections accepted by the server"},
+	"server_streams":         {UnitCount, "Streams opened on the server"},
+	"bytes_received":         {UnitBytes, "Bytes received by the server"},
+	"server_errors":          {UnitCount, "Server errors"},
+	"corrupted_packets":      {UnitCount, "Packets that failed --pattern verification on the server"},
+
+	// Aggregated history points (/api/metrics/history)
+	"count":               {UnitCount, "Raw samples merged into the point"},
+	"latency_min_ms":      {UnitMilliseconds, "Lowest latency within the point"},
+	"latency_max_ms":      {UnitMilliseconds, "Highest latency within the point"},
+	"throughput_min_mbps": {UnitMbps, "Lowest throughput within the point"},
+	"throughput_max_mbps": {UnitMbps, "Highest throughput within the point"},
+
+	// Aggregated metrics (/api/metrics/current)
+	"active_tests":          {UnitCount, "Running tests"},
+	"total_connections":     {UnitCount, "Connections of all running tests"},
+	"avg_latency_ms":        {UnitMilliseconds, "Mean latency"},
+	"total_throughput_mbps": {UnitMbps, "Sum of the throughput of all running tests"},
+	"avg_packet_loss_ratio": {UnitRatio, "Mean fraction of packets lost"},
+	"total_errors":          {UnitCount, "Errors of all running tests"},
+
+	// Test comparison (/api/tests/compare)
+	"duration_seconds":    {UnitSeconds, "Test duration"},
+	"samples":             {UnitCount, "Metric samples of the test"},
+	"avg_throughput_mbps": {UnitMbps, "Mean throughput"},
+	"delta_percent":       {UnitPercent, "Relative difference to the baseline"},
+}
+
+// unitSuffixes maps field name suffixes to the unit they declare
+var unitSuffixes = []struct {
+	suffix string
+	unit   string
+}{
+	{"_ms", UnitMilliseconds},
+	{"_seconds", UnitSeconds},
+	{"_mbps", UnitMbps},
+	{"_ratio", UnitRatio},
+	{"_percent", UnitPercent},
+	{"_pps", UnitPps},
+}
+
+// unitFromName returns the unit implied by a metric field name
+func unitFromName(name string) string {
+	for _, s := range unitSuffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return s.unit
+		}
+	}
+	if strings.HasPrefix(name, "bytes_") || strings.HasSuffix(name, "_bytes") {
+		return UnitBytes
+	}
+	return UnitCount
+}
+
+// handleMetricsSchema returns the units and descriptions of the metric fields
+func (api *APIServer) handleMetricsSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		api.sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	api.sendSuccess(w, metricFields)
+}
@@ -0,0 +1,131 @@
+package gui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// numericFields collects the names of all numeric fields in a decoded JSON value
+func numericFields(v interface{}, fields map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if _, ok := value.(float64); ok {
+				fields[key] = true
+			}
+			numericFields(value, fields)
+		}
+	case []interface{}:
+		for _, item := range v {
+			numericFields(item, fields)
+		}
+	}
+}
+
+// checkFields asserts that every field is in the canonical schema with the
+// unit its name declares
+func checkFields(t *testing.T, endpoint string, fields map[string]bool) {
+	t.Helper()
+	if len(fields) == 0 {
+		t.Errorf("%s: expected numeric metric fields", endpoint)
+	}
+	for name := range fields {
+		field, ok := metricFields[name]
+		if !ok {
+			t.Errorf("%s: field %q is missing from the metrics schema", endpoint, name)
+			continue
+		}
+		if unit := unitFromName(name); field.Unit != unit {
+			t.Errorf("%s: field %q is declared in %s, its name implies %s", endpoint, name, field.Unit, unit)
+		}
+	}
+}
+
+func TestMetricsSchemaMatchesFieldNames(t *testing.T) {
+	for name, field := range metricFields {
+		if unit := unitFromName(name); field.Unit != unit {
+			t.Errorf("Field %q is declared in %s, its name implies %s", name, field.Unit, unit)
+		}
+		if field.Description == "" {
+			t.Errorf("Field %q has no description", name)
+		}
+	}
+
+	var schema map[string]MetricField
+	if code := getAPI(t, NewAPIServer(), "/api/metrics/schema", &schema); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(schema) != len(metricFields) || schema["packet_loss_ratio"].Unit != UnitRatio {
+		t.Errorf("Unexpected schema response: %+v", schema)
+	}
+}
+
+func TestMetricFieldUnitsConsistentAcrossEndpoints(t *testing.T) {
+	api := NewAPIServer()
+	addFinishedTest(api, "a", 10, 20)
+	addFinishedTest(api, "b", 30)
+
+	// A running client test and a running server test with live metrics
+	addFinishedTest(api, "client", 15)
+	client := api.testManager.activeTests["client"]
+	client.Status = "running"
+	client.updateMetrics(map[string]interface{}{
+		"latency_ms": 15.0, "throughput_mbps": 90.0, "packet_loss_ratio": 0.01,
+		"connections": 2, "elapsed_seconds": 1.0,
+	})
+	addFinishedTest(api, "server")
+	server := api.testManager.activeTests["server"]
+	server.Status = "running"
+	server.updateMetrics(map[string]interface{}{"connections": 0, "bytes_received": 0, "uptime_seconds": 1.0})
+
+	for _, endpoint := range []string{
+		"/api/metrics/current",
+		"/api/metrics/history?test_id=a",
+		"/api/tests/client",
+		"/api/tests/server",
+	} {
+		var data map[string]interface{}
+		if code := getAPI(t, api, endpoint, &data); code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", endpoint, code)
+		}
+		// Test details carry the configuration next to the metrics
+		if metrics, ok := data["metrics"]; ok {
+			data = map[string]interface{}{"metrics": metrics}
+		}
+		fields := make(map[string]bool)
+		numericFields(data, fields)
+		checkFields(t, endpoint, fields)
+	}
+
+	// Deltas are in the unit of the metric they compare
+	var comparison struct {
+		Tests  []interface{} `json:"tests"`
+		Deltas []MetricDelta `json:"deltas"`
+	}
+	if code := getAPI(t, api, "/api/tests/compare?ids=a,b", &comparison); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	fields := map[string]bool{"delta_percent": true}
+	numericFields(comparison.Tests, fields)
+	for _, delta := range comparison.Deltas {
+		fields[delta.Metric] = true
+	}
+	checkFields(t, "/api/tests/compare", fields)
+
+	// Prometheus metric names follow the same suffixes
+	mux := http.NewServeMux()
+	api.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/metrics/prometheus", nil))
+	fields = make(map[string]bool)
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := strings.TrimPrefix(strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0], "quic_test_")
+		fields[name] = true
+	}
+	checkFields(t, "/api/metrics/prometheus", fields)
+}
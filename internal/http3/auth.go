@@ -0,0 +1,215 @@
+package http3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// TokenPlaceholder in a header value is replaced with the token obtained by
+// the login request, e.g. "Authorization: Bearer {{token}}"
+const TokenPlaceholder = "{{token}}"
+
+// defaultTokenField is the JSON field of the login response holding the token
+const defaultTokenField = "token"
+
+// maxLoginResponseSize bounds how much of a login response is read
+const maxLoginResponseSize = 1 << 20
+
+// ErrLoginFailed is returned when the login request does not yield a token
+var ErrLoginFailed = errors.New("login failed")
+
+// AuthConfig configures authentication of load test requests. BearerToken
+// and BasicUser set a static Authorization header. Login obtains a token
+// before the test starts and again whenever a request is rejected with 401.
+// Secrets are kept out of the JSON report.
+type AuthConfig struct {
+	BearerToken   string       `json:"-"`
+	BasicUser     string       `json:"basic_user,omitempty"`
+	BasicPassword string       `json:"-"`
+	Login         *LoginConfig `json:"login,omitempty"`
+}
+
+// LoginConfig describes the pre-flight login request. Its token is sent in
+// headers containing TokenPlaceholder, or as a bearer token if none does.
+type LoginConfig struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method,omitempty"`       // POST by default
+	Body        string            `json:"-"`                      // may hold credentials
+	ContentType string            `json:"content_type,omitempty"` // application/json by default
+	Headers     map[string]string `json:"-"`
+
+	// TokenField is the JSON field of the response holding the token, with
+	// dots for nested objects ("token" by default). TokenHeader takes the
+	// token from a response header instead.
+	TokenField  string `json:"token_field,omitempty"`
+	TokenHeader string `json:"token_header,omitempty"`
+}
+
+// authState holds the current login token. A generation number identifies
+// the token a request was sent with, so that concurrent 401s trigger a
+// single refresh instead of one login per request.
+type authState struct {
+	mu         sync.RWMutex
+	token      string
+	generation int64
+
+	refreshMu sync.Mutex // serializes logins
+}
+
+// current returns the token and its generation
+func (a *authState) current() (string, int64) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.token, a.generation
+}
+
+// hasTokenPlaceholder reports whether any configured header takes the token
+func (lt *LoadTester) hasTokenPlaceholder() bool {
+	for _, value := range lt.config.Headers {
+		if strings.Contains(value, TokenPlaceholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAuth sets authentication headers on req and returns the generation of
+// the login token used (zero without a login)
+func (lt *LoadTester) applyAuth(req *http.Request) int64 {
+	auth := lt.config.Auth
+	if auth == nil {
+		return 0
+	}
+	if auth.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+auth.BearerToken)
+	}
+	if auth.BasicUser != "" {
+		req.SetBasicAuth(auth.BasicUser, auth.BasicPassword)
+	}
+	if auth.Login == nil {
+		return 0
+	}
+
+	token, generation := lt.auth.current()
+	if !lt.hasTokenPlaceholder() {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return generation
+}
+
+// headerValue substitutes the login token into a configured header value
+func (lt *LoadTester) headerValue(value string) string {
+	if !strings.Contains(value, TokenPlaceholder) {
+		return value
+	}
+	token, _ := lt.auth.current()
+	return strings.ReplaceAll(value, TokenPlaceholder, token)
+}
+
+// login performs the login request and stores the token it returns
+func (lt *LoadTester) login(ctx context.Context) error {
+	cfg := lt.config.Auth.Login
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	var body io.Reader
+	if cfg.Body != "" {
+		body = strings.NewReader(cfg.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, cfg.URL, body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+	if body != nil {
+		contentType := cfg.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := lt.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLoginResponseSize))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: status %d", ErrLoginFailed, resp.StatusCode)
+	}
+
+	token, err := extractToken(cfg, resp.Header, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+
+	lt.auth.mu.Lock()
+	lt.auth.token = token
+	lt.auth.generation++
+	lt.auth.mu.Unlock()
+	return nil
+}
+
+// refreshToken logs in again after a request sent with the given token
+// generation was rejected. If another request already refreshed the token
+// meanwhile, the new token is used without logging in again.
+func (lt *LoadTester) refreshToken(ctx context.Context, generation int64) error {
+	lt.auth.refreshMu.Lock()
+	defer lt.auth.refreshMu.Unlock()
+
+	if _, current := lt.auth.current(); current != generation {
+		return nil
+	}
+	if err := lt.login(ctx); err != nil {
+		return err
+	}
+	atomic.AddInt64(&lt.results.TokenRefreshes, 1)
+	return nil
+}
+
+// extractToken takes the token from the login response header or JSON body
+func extractToken(cfg *LoginConfig, header http.Header, body []byte) (string, error) {
+	if cfg.TokenHeader != "" {
+		token := strings.TrimSpace(strings.TrimPrefix(header.Get(cfg.TokenHeader), "Bearer "))
+		if token == "" {
+			return "", fmt.Errorf("no token in response header %q", cfg.TokenHeader)
+		}
+		return token, nil
+	}
+
+	field := cfg.TokenField
+	if field == "" {
+		field = defaultTokenField
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", fmt.Errorf("invalid login response: %v", err)
+	}
+	for _, key := range strings.Split(field, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("no field %q in login response", field)
+		}
+		value = obj[key]
+	}
+	token, ok := value.(string)
+	if !ok || token == "" {
+		return "", fmt.Errorf("no string field %q in login response", field)
+	}
+	return token, nil
+}
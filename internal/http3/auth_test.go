@@ -0,0 +1,134 @@
+package http3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAuthServer issues tokens on POST /login and accepts each token for a
+// limited number of requests, after which it answers 401 until a new login
+type fakeAuthServer struct {
+	mu        sync.Mutex
+	token     string
+	remaining int
+	logins    int
+	accepted  int
+	perToken  int
+}
+
+func (s *fakeAuthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/login" {
+		var creds struct{ User, Password string }
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&creds) != nil ||
+			creds.User != "load" || creds.Password != "secret" {
+			http.Error(w, "bad credentials", http.StatusForbidden)
+			return
+		}
+		s.logins++
+		s.token = fmt.Sprintf("token-%d", s.logins)
+		s.remaining = s.perToken
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]string{"access_token": s.token},
+		})
+		return
+	}
+
+	if s.token == "" || r.Header.Get("X-Auth-Token") != s.token || s.remaining == 0 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.remaining--
+	s.accepted++
+	w.Write([]byte("ok"))
+}
+
+func authLoadConfig(url string, body string) *LoadTestConfig {
+	return &LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             url + "api",
+		Duration:              30 * time.Second,
+		ConcurrentConnections: 2,
+		RequestsPerConnection: 10,
+		RequestPattern:        "sequential",
+		Timeout:               5 * time.Second,
+		Headers:               map[string]string{"X-Auth-Token": TokenPlaceholder},
+		Auth: &AuthConfig{
+			Login: &LoginConfig{
+				URL:        url + "login",
+				Body:       body,
+				TokenField: "data.access_token",
+			},
+		},
+	}
+}
+
+func TestLoginTokenRefreshedOn401(t *testing.T) {
+	auth := &fakeAuthServer{perToken: 5}
+	url := startDualStackServer(t, auth)
+
+	tester := NewLoadTester(authLoadConfig(url, `{"user":"load","password":"secret"}`))
+	defer tester.Close()
+
+	if err := tester.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	results := tester.GetResults()
+	if results.FailedRequests != 0 || results.StatusCodes["200"] != 20 {
+		t.Fatalf("Expected all 20 requests to be authorized, got status codes %v, %d failed",
+			results.StatusCodes, results.FailedRequests)
+	}
+
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	// 20 requests at 5 per token need the pre-flight login and 3 refreshes
+	if auth.logins != 4 || results.TokenRefreshes != 3 {
+		t.Errorf("Expected 4 logins (3 refreshes), got %d logins, %d refreshes", auth.logins, results.TokenRefreshes)
+	}
+	if auth.accepted != 20 {
+		t.Errorf("Expected the server to accept 20 requests, got %d", auth.accepted)
+	}
+}
+
+func TestLoginFailureStopsTest(t *testing.T) {
+	auth := &fakeAuthServer{perToken: 5}
+	url := startDualStackServer(t, auth)
+
+	tester := NewLoadTester(authLoadConfig(url, `{"user":"load","password":"wrong"}`))
+	defer tester.Close()
+
+	err := tester.Start(context.Background())
+	if !errors.Is(err, ErrLoginFailed) {
+		t.Fatalf("Expected a login error, got %v", err)
+	}
+	if results := tester.GetResults(); results.Status != "failed" || results.TotalRequests != 0 {
+		t.Errorf("Expected no requests after a failed login, got status %q, %d requests",
+			results.Status, results.TotalRequests)
+	}
+}
+
+func TestStaticAuthHeaders(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.test/", nil)
+	tester := &LoadTester{config: &LoadTestConfig{Auth: &AuthConfig{BasicUser: "user", BasicPassword: "pass"}}}
+	if generation := tester.applyAuth(req); generation != 0 {
+		t.Errorf("Expected no login token, got generation %d", generation)
+	}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("Expected basic auth user:pass, got %q:%q", user, pass)
+	}
+
+	tester.config.Auth = &AuthConfig{BearerToken: "abc"}
+	tester.applyAuth(req)
+	if got := req.Header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("Expected bearer token, got %q", got)
+	}
+}
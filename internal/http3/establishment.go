@@ -0,0 +1,36 @@
+package http3
+
+// A connection worker's first request is its connection setup: it pays for
+// the dial and handshake, or finds the pool unable to give it a connection.
+// Setup failures are counted apart from errors on established connections so
+// that a capacity test can stop ramping once the server stops accepting.
+
+// recordSetup accounts a setup result and aborts the run when setup failures
+// exceed the establishment error budget. The number of workers that did
+// establish is reported as the maximum sustainable connection count.
+// Called with lt.results.mu held.
+func (lt *LoadTester) recordSetup(result *RequestResult) {
+	r := lt.results
+	r.SetupAttempts++
+	if result.Error != nil {
+		r.SetupFailures++
+	}
+
+	budget := lt.config.EstablishmentErrorBudget
+	if budget <= 0 || r.EstablishmentAborted {
+		return
+	}
+	// Wait for a full ramp step so that a single early failure does not abort
+	if r.SetupAttempts < int64(max(lt.config.RampStep, 1)) {
+		return
+	}
+	if float64(r.SetupFailures)/float64(r.SetupAttempts) <= budget {
+		return
+	}
+
+	r.EstablishmentAborted = true
+	r.MaxSustainableConnections = int(r.SetupAttempts - r.SetupFailures)
+	if lt.abort != nil {
+		lt.abort()
+	}
+}
@@ -0,0 +1,86 @@
+package http3
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// capacityTransport accepts the first limit connection setups and refuses
+// every request after that, like a server that ran out of capacity
+type capacityTransport struct {
+	limit int64
+	calls int64
+}
+
+func (c *capacityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt64(&c.calls, 1) > c.limit {
+		return nil, &net.OpError{Op: "dial", Net: "udp", Err: io.ErrUnexpectedEOF}
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("ok")),
+		Request:    req,
+	}, nil
+}
+
+func runCapacityTest(t *testing.T, budget float64) *LoadTestResults {
+	t.Helper()
+	tester := NewLoadTester(&LoadTestConfig{
+		TargetURL:                "https://capacity.test/",
+		Duration:                 10 * time.Second,
+		ConcurrentConnections:    20,
+		RequestsPerConnection:    1,
+		RequestPattern:           "sequential",
+		RampStep:                 2,
+		RampInterval:             20 * time.Millisecond,
+		EstablishmentErrorBudget: budget,
+		SharedTransport:          true, // the capacity transport replaces the shared one
+	})
+	defer tester.Close()
+	tester.client.Transport = &capacityTransport{limit: 6}
+
+	if err := tester.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return tester.GetResults()
+}
+
+func TestEstablishmentBudgetAbortsRamp(t *testing.T) {
+	start := time.Now()
+	results := runCapacityTest(t, 0.2)
+
+	if !results.EstablishmentAborted || results.Status != "aborted" {
+		t.Fatalf("Expected the run to abort, got status %q", results.Status)
+	}
+	if results.MaxSustainableConnections != 6 {
+		t.Errorf("Expected a ceiling of 6 connections, got %d", results.MaxSustainableConnections)
+	}
+	// Two failed setups out of eight exceed the 20% budget; the ramp to 20
+	// connections (~200ms) must not run to completion
+	if results.SetupAttempts >= 20 {
+		t.Errorf("Expected the ramp to stop early, got %d setups", results.SetupAttempts)
+	}
+	if results.SetupFailures == 0 || results.SetupFailures > results.SetupAttempts {
+		t.Errorf("Unexpected setup accounting: %d failures of %d", results.SetupFailures, results.SetupAttempts)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Aborted run took %v", elapsed)
+	}
+}
+
+func TestEstablishmentBudgetDisabled(t *testing.T) {
+	results := runCapacityTest(t, 0)
+
+	if results.EstablishmentAborted || results.Status != "completed" {
+		t.Fatalf("Expected the run to complete without a budget, got status %q", results.Status)
+	}
+	if results.SetupAttempts != 20 || results.SetupFailures != 14 {
+		t.Errorf("Expected 14 of 20 setups to fail, got %d of %d", results.SetupFailures, results.SetupAttempts)
+	}
+}
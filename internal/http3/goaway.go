@@ -0,0 +1,44 @@
+package http3
+
+import (
+	"errors"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Server-initiated connection close kinds
+const (
+	closeKindGoAway = "goaway"       // graceful shutdown (H3_NO_ERROR)
+	closeKindServer = "server_close" // any other application close from the server
+)
+
+// serverCloseKind reports whether err means the server closed the connection.
+// The quic-go client skips GOAWAY frames on the control stream, so a graceful
+// shutdown is recognized by the CONNECTION_CLOSE with H3_NO_ERROR that follows it.
+func serverCloseKind(err error) (string, bool) {
+	var appErr *quic.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.Remote {
+		return "", false
+	}
+	if appErr.ErrorCode == quic.ApplicationErrorCode(http3.ErrCodeNoError) {
+		return closeKindGoAway, true
+	}
+	return closeKindServer, true
+}
+
+// recordServerClose accounts a server-initiated close and whether the
+// request was retried on a new connection
+func (cm *ConnectionMetrics) recordServerClose(kind string, reconnected bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if kind == closeKindGoAway {
+		cm.GoAways++
+	} else {
+		cm.ServerCloses++
+	}
+	if reconnected {
+		cm.Reconnects++
+	}
+}
@@ -0,0 +1,119 @@
+package http3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// trackingListener remembers accepted connections so the test can shut
+// them down from the server side
+type trackingListener struct {
+	*quic.EarlyListener
+
+	mu    sync.Mutex
+	conns []quic.EarlyConnection
+}
+
+func (l *trackingListener) Accept(ctx context.Context) (quic.EarlyConnection, error) {
+	conn, err := l.EarlyListener.Accept(ctx)
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+// goAway closes all connections the way a gracefully shutting down server does
+func (l *trackingListener) goAway() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "shutting down")
+	}
+	l.conns = nil
+}
+
+// startGoAwayServer serves HTTP/3 and shuts every connection down after the
+// third request
+func startGoAwayServer(t *testing.T) string {
+	t.Helper()
+
+	var ln *trackingListener
+	var served int64
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+		if atomic.AddInt64(&served, 1) == 3 {
+			time.AfterFunc(20*time.Millisecond, ln.goAway)
+		}
+	})
+
+	tlsServer := httptest.NewTLSServer(handler)
+	t.Cleanup(tlsServer.Close)
+
+	quicLn, err := quic.ListenAddrEarly("127.0.0.1:0", http3.ConfigureTLSConfig(tlsServer.TLS.Clone()), nil)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ln = &trackingListener{EarlyListener: quicLn}
+
+	server := &http3.Server{Handler: handler}
+	go server.ServeListener(ln)
+	t.Cleanup(func() {
+		server.Close()
+		quicLn.Close()
+	})
+
+	return fmt.Sprintf("https://%s/", quicLn.Addr())
+}
+
+func TestServerGoAway(t *testing.T) {
+	for _, reconnect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reconnect=%v", reconnect), func(t *testing.T) {
+			tester := NewLoadTester(&LoadTestConfig{
+				TLSConfig:             insecureTLSConfig(),
+				TargetURL:             startGoAwayServer(t),
+				Duration:              30 * time.Second,
+				ConcurrentConnections: 1,
+				RequestsPerConnection: 6,
+				RequestPattern:        "sequential",
+				ThinkTime:             100 * time.Millisecond,
+				Timeout:               5 * time.Second,
+				ReconnectOnClose:      reconnect,
+			})
+			defer tester.Close()
+
+			if err := tester.Start(context.Background()); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			results := tester.GetResults()
+			cm := results.ConnectionMetrics
+
+			if cm.GoAways != 1 || cm.ServerCloses != 0 {
+				t.Fatalf("Expected exactly one GOAWAY, got %d goaways / %d other closes (errors: %v)",
+					cm.GoAways, cm.ServerCloses, results.Errors)
+			}
+
+			if reconnect {
+				if cm.Reconnects != 1 || results.SuccessfulRequests != 6 {
+					t.Errorf("Expected 1 reconnect and 6 successful requests, got %d / %d (errors: %v)",
+						cm.Reconnects, results.SuccessfulRequests, results.Errors)
+				}
+			} else {
+				if cm.Reconnects != 0 || results.Errors[closeKindGoAway] != 1 || results.SuccessfulRequests != 5 {
+					t.Errorf("Expected one goaway error and 5 successful requests, got %d reconnects, errors %v, %d ok",
+						cm.Reconnects, results.Errors, results.SuccessfulRequests)
+				}
+			}
+		})
+	}
+}
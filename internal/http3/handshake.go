@@ -0,0 +1,148 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// dialQUIC dials a QUIC connection for the HTTP/3 round tripper and records
+// how long the handshake took and whether the TLS session was resumed. The
+// address is resolved here, so that the lookup can be traced.
+func (lt *LoadTester) dialQUIC(ctx context.Context, addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
+	start := time.Now()
+	trace := httptrace.ContextClientTrace(ctx)
+	udpAddr, err := resolveUDPAddr(addr, trace)
+	if err != nil {
+		return nil, err
+	}
+
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+	conn, err := quic.DialAddrEarly(ctx, udpAddr.String(), tlsConf, quicConf)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-conn.HandshakeComplete():
+	case <-conn.Context().Done():
+		// The round tripper reports the close reason on first use
+		return conn, nil
+	case <-ctx.Done():
+		conn.CloseWithError(0, "")
+		return nil, ctx.Err()
+	}
+
+	state := conn.ConnectionState().TLS
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		trace.TLSHandshakeDone(state, nil)
+	}
+	lt.recordHandshake(time.Since(start), state.DidResume)
+	recordDial(ctx, time.Since(start))
+	return conn, nil
+}
+
+// resolveUDPAddr resolves addr and reports the lookup to trace unless the
+// host is an IP address
+func resolveUDPAddr(addr string, trace *httptrace.ClientTrace) (*net.UDPAddr, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil || trace == nil {
+		return net.ResolveUDPAddr("udp", addr)
+	}
+
+	if trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if trace.DNSDone != nil {
+		info := httptrace.DNSDoneInfo{Err: err}
+		if err == nil {
+			info.Addrs = []net.IPAddr{{IP: udpAddr.IP, Zone: udpAddr.Zone}}
+		}
+		trace.DNSDone(info)
+	}
+	return udpAddr, err
+}
+
+// dialTLS returns the TCP+TLS dialer for the h2 baseline. The recorded time
+// includes the TCP connect, which QUIC folds into its handshake anyway. The
+// TCP dialer reports the lookup to the request trace; the handshake is done
+// here, so that it can be traced as well.
+func (lt *LoadTester) dialTLS(tlsConf *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		rawConn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		config := tlsConf
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		conn := tls.Client(rawConn, config)
+		trace := httptrace.ContextClientTrace(ctx)
+		if trace != nil && trace.TLSHandshakeStart != nil {
+			trace.TLSHandshakeStart()
+		}
+		err = conn.HandshakeContext(ctx)
+		if trace != nil && trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(conn.ConnectionState(), err)
+		}
+		if err != nil {
+			rawConn.Close()
+			return nil, err
+		}
+
+		lt.recordHandshake(time.Since(start), conn.ConnectionState().DidResume)
+		recordDial(ctx, time.Since(start))
+		return conn, nil
+	}
+}
+
+// recordHandshake accounts a completed handshake to the warm-up while it
+// runs and to the connection metrics otherwise
+func (lt *LoadTester) recordHandshake(d time.Duration, resumed bool) {
+	if atomic.LoadInt32(&lt.warming) == 1 {
+		lt.results.Warmup.recordHandshake(d)
+		return
+	}
+	lt.results.ConnectionMetrics.recordHandshake(d, resumed)
+}
+
+// recordReuse accounts a request served on an existing connection
+func (cm *ConnectionMetrics) recordReuse() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.ConnectionsReused++
+}
+
+// recordHandshake accounts a completed handshake and updates the averages
+func (cm *ConnectionMetrics) recordHandshake(d time.Duration, resumed bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.ConnectionsCreated++
+
+	ms := float64(d.Nanoseconds()) / 1e6
+	if resumed {
+		cm.ResumedHandshakes++
+		cm.AvgResumedHandshakeTime += (ms - cm.AvgResumedHandshakeTime) / float64(cm.ResumedHandshakes)
+	} else {
+		cm.FullHandshakes++
+		cm.AvgFullHandshakeTime += (ms - cm.AvgFullHandshakeTime) / float64(cm.FullHandshakes)
+	}
+
+	if cm.FullHandshakes > 0 && cm.ResumedHandshakes > 0 {
+		cm.ResumptionSavings = cm.AvgFullHandshakeTime - cm.AvgResumedHandshakeTime
+	}
+}
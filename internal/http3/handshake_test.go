@@ -0,0 +1,58 @@
+package http3
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+func TestSessionResumption(t *testing.T) {
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	run := func(resumption bool) *ConnectionMetrics {
+		// A short idle timeout and a longer think time force a new QUIC
+		// connection for every request
+		tester := NewLoadTester(&LoadTestConfig{
+			TLSConfig:             insecureTLSConfig(),
+			TargetURL:             url,
+			Duration:              30 * time.Second,
+			ConcurrentConnections: 1,
+			RequestsPerConnection: 3,
+			RequestPattern:        "sequential",
+			ThinkTime:             500 * time.Millisecond,
+			Timeout:               5 * time.Second,
+			QuicConfig:            &quic.Config{MaxIdleTimeout: 100 * time.Millisecond},
+			SessionResumption:     resumption,
+		})
+		defer tester.Close()
+
+		if err := tester.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		results := tester.GetResults()
+		if results.SuccessfulRequests != 3 {
+			t.Fatalf("Expected 3 successful requests, got %d (errors: %v)", results.SuccessfulRequests, results.Errors)
+		}
+		return results.ConnectionMetrics
+	}
+
+	enabled := run(true)
+	if enabled.FullHandshakes != 1 || enabled.ResumedHandshakes != 2 {
+		t.Errorf("With resumption expected 1 full and 2 resumed handshakes, got %d full / %d resumed",
+			enabled.FullHandshakes, enabled.ResumedHandshakes)
+	}
+	if enabled.AvgResumedHandshakeTime <= 0 {
+		t.Errorf("Expected a positive resumed handshake time, got %f", enabled.AvgResumedHandshakeTime)
+	}
+
+	disabled := run(false)
+	if disabled.FullHandshakes != 3 || disabled.ResumedHandshakes != 0 {
+		t.Errorf("Without resumption expected 3 full handshakes, got %d full / %d resumed",
+			disabled.FullHandshakes, disabled.ResumedHandshakes)
+	}
+}
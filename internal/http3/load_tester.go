@@ -3,23 +3,42 @@ package http3
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"quic-test/internal/metrics"
+
+	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
 )
 
+// topErrorsInResults limits how many error types are listed in the results
+const topErrorsInResults = 10
+
 // LoadTester performs HTTP/3 load testing
 type LoadTester struct {
 	config  *LoadTestConfig
 	results *LoadTestResults
-	client  *http.Client
+	client  *http.Client // shared by all workers with SharedTransport; also used to log in
 	mu      sync.RWMutex
+	
+	protocol      string
+	tlsConfig     *tls.Config
+	workerClients map[int]*http.Client // per connection worker unless SharedTransport
+	clientsMu     sync.Mutex
+	
+	connectionsLaunched int64 // number of connection workers started so far
+	abort               context.CancelFunc // stops the running test early
+	auth                authState          // login token, see AuthConfig
+	warming             int32              // 1 while PreWarm establishes connections
 }
 
 // LoadTestConfig holds HTTP/3 load test configuration
@@ -34,15 +53,65 @@ type LoadTestConfig struct {
 	BodySize               int               `json:"body_size"`
 	ThinkTime              time.Duration     `json:"think_time"`
 	TLSConfig              *tls.Config       `json:"-"`
+	QuicConfig             *quic.Config      `json:"-"`
 	FollowRedirects        bool              `json:"follow_redirects"`
 	Timeout                time.Duration     `json:"timeout"`
 	UserAgent              string            `json:"user_agent"`
+	Auth                   *AuthConfig       `json:"auth,omitempty"`
+	Protocol               string            `json:"protocol,omitempty"` // "h3" (default) or "h2" for a TCP baseline
+	
+	// SessionResumption shares a TLS session cache across all connections so
+	// reconnects resume instead of doing a full handshake, like a browser does
+	SessionResumption      bool              `json:"session_resumption,omitempty"`
+	
+	// PreWarm establishes the connections before the measured phase, so that
+	// measured requests exclude the handshake (see WarmupResults)
+	PreWarm                bool              `json:"pre_warm,omitempty"`
+	
+	// MaxSamples bounds memory for long runs: beyond it response times
+	// (overall, per target and per ramp level) are reservoir-sampled and
+	// percentiles become estimates. Zero keeps all.
+	MaxSamples             int               `json:"max_samples,omitempty"`
+	
+	// SharedTransport sends the requests of all connection workers through
+	// one transport, so they multiplex over a single connection per host.
+	// By default every worker has its own transport and thus its own
+	// connection, and ConcurrentConnections connections are opened.
+	SharedTransport        bool              `json:"shared_transport,omitempty"`
+	
+	// ReconnectOnClose retries a request once on a new connection when the
+	// server shut the previous one down (GOAWAY or CONNECTION_CLOSE)
+	ReconnectOnClose       bool              `json:"reconnect_on_close,omitempty"`
+	
+	// Connection ramp: start with RampStep connections and add RampStep more
+	// every RampInterval until ConcurrentConnections is reached. Disabled when zero.
+	RampStep               int               `json:"ramp_step,omitempty"`
+	RampInterval           time.Duration     `json:"ramp_interval,omitempty"`
+	
+	// EstablishmentErrorBudget aborts the run once more than this fraction
+	// (0..1) of connection setups fail. Zero disables the check.
+	EstablishmentErrorBudget float64         `json:"establishment_error_budget,omitempty"`
+	
+	// Multi-target runs spread requests round-robin over Targets (TargetURL
+	// is used when empty) and report statistics per target
+	Targets                []string          `json:"targets,omitempty"`
+	
+	// SLA applies to the aggregate and to every target unless overridden in TargetSLAs
+	SLA                    LoadTestSLA            `json:"sla,omitempty"`
+	TargetSLAs             map[string]LoadTestSLA `json:"target_slas,omitempty"`
 }
 
+// Supported load test protocols
+const (
+	ProtocolHTTP3 = "h3"
+	ProtocolHTTP2 = "h2"
+)
+
 // LoadTestResults holds HTTP/3 load test results
 type LoadTestResults struct {
 	LoadTestID         string                 `json:"load_test_id"`
 	Status             string                 `json:"status"` // "running", "completed", "failed"
+	Protocol           string                 `json:"protocol"`
 	CreatedAt          time.Time              `json:"created_at"`
 	StartedAt          *time.Time             `json:"started_at,omitempty"`
 	CompletedAt        *time.Time             `json:"completed_at,omitempty"`
@@ -56,27 +125,92 @@ type LoadTestResults struct {
 	P50ResponseTime    float64                `json:"p50_response_time_ms"`
 	P95ResponseTime    float64                `json:"p95_response_time_ms"`
 	P99ResponseTime    float64                `json:"p99_response_time_ms"`
+	MinResponseTime    float64                `json:"min_response_time_ms"`
+	MaxResponseTime    float64                `json:"max_response_time_ms"`
+	StdDevResponseTime float64                `json:"stddev_response_time_ms"`
+	FirstResponseTime  float64                `json:"first_response_time_ms"` // Includes connection setup
+	
+	// Time to first byte: until the response headers arrive, before the body
+	// is read. TTFB vs total time separates server processing from transfer.
+	AvgTTFB            float64                `json:"avg_ttfb_ms"`
+	P50TTFB            float64                `json:"p50_ttfb_ms"`
+	P95TTFB            float64                `json:"p95_ttfb_ms"`
+	P99TTFB            float64                `json:"p99_ttfb_ms"`
+	
 	RequestsPerSecond  float64                `json:"requests_per_second"`
-	BytesTransferred   int64                  `json:"bytes_transferred"`
+	BytesTransferred   metrics.ByteCount      `json:"bytes_transferred"`
 	ErrorRate          float64                `json:"error_rate"`
+	SampledPercentiles bool                   `json:"sampled_percentiles,omitempty"` // estimated from a reservoir sample
+	ConcurrencyLevels  []ConcurrencyLevel     `json:"concurrency_levels,omitempty"` // Latency vs concurrency during a ramp
+	TargetResults      []TargetResults        `json:"target_results,omitempty"`
+	SLAViolations      []SLAViolation         `json:"sla_violations,omitempty"`
 	StatusCodes        map[string]int64       `json:"status_codes"`
 	Errors             map[string]int64       `json:"errors"`
+	TopErrors          []metrics.ErrorSummary `json:"top_errors,omitempty"`
+	
+	// Connection setups (each worker's first request), apart from in-flight errors
+	SetupAttempts             int64 `json:"setup_attempts"`
+	SetupFailures             int64 `json:"setup_failures"`
+	EstablishmentAborted      bool  `json:"establishment_aborted,omitempty"`
+	MaxSustainableConnections int   `json:"max_sustainable_connections,omitempty"`
+	
+	// Connections established before the measured phase (PreWarm)
+	Warmup             *WarmupResults         `json:"warmup,omitempty"`
+	
+	// Logins repeated after a request was rejected with 401
+	TokenRefreshes     int64                  `json:"token_refreshes,omitempty"`
 	
 	// Detailed metrics
 	ResponseTimes      []float64              `json:"-"` // Not exported in JSON
+	responseTimes      *metrics.Reservoir
+	ttfbTimes          *metrics.Reservoir
+	firstRequestStart  time.Time
+	concurrencyTimes   map[int]*metrics.Reservoir // concurrency level -> response times
+	targets            map[string]*targetAccumulator
 	ConnectionMetrics  *ConnectionMetrics     `json:"connection_metrics"`
 	
-	mu sync.RWMutex
+	errorAggregator *metrics.ErrorAggregator
+	mu              sync.RWMutex
+}
+
+// ConcurrencyLevel holds latency statistics for requests issued while a
+// given number of connections was running
+type ConcurrencyLevel struct {
+	Connections     int     `json:"connections"`
+	Requests        int64   `json:"requests"`
+	AvgResponseTime float64 `json:"avg_response_time_ms"`
+	P50ResponseTime float64 `json:"p50_response_time_ms"`
+	P95ResponseTime float64 `json:"p95_response_time_ms"`
+	// Estimated from a reservoir sample of MaxSamples response times
+	SampledPercentiles bool `json:"sampled_percentiles,omitempty"`
 }
 
 // ConnectionMetrics holds connection-level metrics
 type ConnectionMetrics struct {
-	ConnectionsCreated   int64   `json:"connections_created"`
-	ConnectionsReused    int64   `json:"connections_reused"`
+	ConnectionsCreated   int64   `json:"connections_created"` // handshakes completed outside the warm-up
+	ConnectionsReused    int64   `json:"connections_reused"`  // requests served on an already open connection
 	ConnectionsFailed    int64   `json:"connections_failed"`
+	
+	// Averages over the requests that opened a connection, did a TLS (with
+	// HTTP/3: QUIC) handshake or looked up a name, see requestTrace
 	AvgConnectionTime    float64 `json:"avg_connection_time_ms"`
 	TLSHandshakeTime     float64 `json:"avg_tls_handshake_time_ms"`
 	DNSLookupTime        float64 `json:"avg_dns_lookup_time_ms"`
+	timedConnections     int64
+	timedTLSHandshakes   int64
+	timedDNSLookups      int64
+	
+	// Handshakes observed by the dialer, split by TLS session resumption
+	FullHandshakes          int64   `json:"full_handshakes"`
+	ResumedHandshakes       int64   `json:"resumed_handshakes"`
+	AvgFullHandshakeTime    float64 `json:"avg_full_handshake_time_ms"`
+	AvgResumedHandshakeTime float64 `json:"avg_resumed_handshake_time_ms"`
+	ResumptionSavings       float64 `json:"resumption_savings_ms"` // full minus resumed average
+	
+	// Server-initiated connection shutdowns seen by requests
+	GoAways                 int64   `json:"goaways"`
+	ServerCloses            int64   `json:"server_closes"`
+	Reconnects              int64   `json:"reconnects"`
 	
 	mu sync.RWMutex
 }
@@ -84,6 +218,7 @@ type ConnectionMetrics struct {
 // RequestResult holds individual request result
 type RequestResult struct {
 	StartTime      time.Time
+	FirstByteTime  time.Time // response headers received
 	EndTime        time.Time
 	StatusCode     int
 	ResponseSize   int64
@@ -91,60 +226,135 @@ type RequestResult struct {
 	ConnectionTime time.Duration
 	DNSTime        time.Duration
 	TLSTime        time.Duration
+	Concurrency    int // connections running when the request started
+	Target         string
+	Setup          bool // first request of a connection worker
 }
 
 // NewLoadTester creates a new HTTP/3 load tester
 func NewLoadTester(config *LoadTestConfig) *LoadTester {
 	loadTestID := fmt.Sprintf("http3_load_%d", time.Now().Unix())
 	
+	protocol := config.Protocol
+	if protocol == "" {
+		protocol = ProtocolHTTP3
+	}
+	
 	results := &LoadTestResults{
 		LoadTestID:        loadTestID,
 		Status:            "created",
+		Protocol:          protocol,
 		CreatedAt:         time.Now(),
 		Config:            config,
 		StatusCodes:       make(map[string]int64),
 		Errors:            make(map[string]int64),
+		errorAggregator:   metrics.NewErrorAggregator(0, 0),
 		ResponseTimes:     make([]float64, 0),
+		responseTimes:     metrics.NewReservoir(config.MaxSamples, time.Now().UnixNano()),
+		ttfbTimes:         metrics.NewReservoir(config.MaxSamples, time.Now().UnixNano()+1),
+		concurrencyTimes:  make(map[int]*metrics.Reservoir),
+		targets:           make(map[string]*targetAccumulator),
 		ConnectionMetrics: &ConnectionMetrics{},
 	}
 	
-	// Configure HTTP/3 client
+	// Configure client transport
+	// Without a TLS config the server certificate is verified against the
+	// system roots; skipping verification has to be asked for explicitly
 	tlsConfig := config.TLSConfig
 	if tlsConfig == nil {
-		tlsConfig = &tls.Config{
-			InsecureSkipVerify: true, // For testing
-		}
+		tlsConfig = &tls.Config{}
+	}
+	if config.SessionResumption {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
 	}
 	
-	roundTripper := &http3.RoundTripper{
-		TLSClientConfig: tlsConfig,
+	lt := &LoadTester{
+		config:        config,
+		results:       results,
+		protocol:      protocol,
+		tlsConfig:     tlsConfig,
+		workerClients: make(map[int]*http.Client),
 	}
+	lt.client = lt.newClient()
 	
-	timeout := config.Timeout
+	return lt
+}
+
+// newClient creates an HTTP client with its own transport
+func (lt *LoadTester) newClient() *http.Client {
+	timeout := lt.config.Timeout
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 	
 	client := &http.Client{
-		Transport: roundTripper,
+		Transport: lt.newRoundTripper(lt.protocol, lt.tlsConfig),
 		Timeout:   timeout,
 	}
 	
-	if !config.FollowRedirects {
+	if !lt.config.FollowRedirects {
 		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}
 	}
+	return client
+}
+
+// clientFor returns the client of a connection worker: the shared one with
+// SharedTransport, otherwise one created on the worker's first request
+func (lt *LoadTester) clientFor(connID int) *http.Client {
+	if lt.config.SharedTransport {
+		return lt.client
+	}
+	
+	lt.clientsMu.Lock()
+	defer lt.clientsMu.Unlock()
+	client := lt.workerClients[connID]
+	if client == nil {
+		client = lt.newClient()
+		lt.workerClients[connID] = client
+	}
+	return client
+}
+
+// newRoundTripper creates the transport for the requested protocol. The h2
+// transport is a standard TCP+TLS client used as a baseline for HTTP/3.
+// Both dial through the tester so that handshakes are recorded.
+func (lt *LoadTester) newRoundTripper(protocol string, tlsConfig *tls.Config) http.RoundTripper {
+	if protocol == ProtocolHTTP2 {
+		h2TLS := tlsConfig.Clone()
+		h2TLS.NextProtos = []string{"h2"}
+		return &http.Transport{
+			TLSClientConfig:   h2TLS,
+			DialTLSContext:    lt.dialTLS(h2TLS),
+			ForceAttemptHTTP2: true,
+		}
+	}
 	
-	return &LoadTester{
-		config:  config,
-		results: results,
-		client:  client,
+	return &http3.RoundTripper{
+		TLSClientConfig: tlsConfig,
+		QuicConfig:      lt.config.QuicConfig,
+		Dial:            lt.dialQUIC,
 	}
 }
 
-// Start starts the load test
+// Start starts the load test. With a login configured the token is obtained
+// first, and the test does not start if the login fails.
 func (lt *LoadTester) Start(ctx context.Context) error {
+	if lt.config.Auth != nil && lt.config.Auth.Login != nil {
+		if err := lt.login(ctx); err != nil {
+			lt.results.mu.Lock()
+			lt.results.Status = "failed"
+			lt.results.mu.Unlock()
+			return fmt.Errorf("pre-flight login: %w", err)
+		}
+	}
+	
+	if lt.config.PreWarm {
+		lt.warmUp(ctx)
+	}
+	
 	lt.results.mu.Lock()
 	lt.results.Status = "running"
 	now := time.Now()
@@ -161,24 +371,57 @@ func (lt *LoadTester) Start(ctx context.Context) error {
 
 // runLoadTest executes the load test
 func (lt *LoadTester) runLoadTest(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lt.abort = cancel
+	
 	var wg sync.WaitGroup
 	resultsChan := make(chan *RequestResult, lt.config.ConcurrentConnections*lt.config.RequestsPerConnection)
 	
 	// Start result collector
-	go lt.collectResults(ctx, resultsChan)
+	collectorDone := make(chan struct{})
+	go func() {
+		defer close(collectorDone)
+		lt.collectResults(resultsChan)
+	}()
+	
+	// Start concurrent connections, optionally ramping them up over time
+	launch := func(count int) {
+		for ; count > 0; count-- {
+			connID := int(atomic.LoadInt64(&lt.connectionsLaunched))
+			if connID >= lt.config.ConcurrentConnections {
+				return
+			}
+			wg.Add(1)
+			atomic.AddInt64(&lt.connectionsLaunched, 1)
+			go func(connID int) {
+				defer wg.Done()
+				lt.runConnection(ctx, connID, resultsChan)
+			}(connID)
+		}
+	}
 	
-	// Start concurrent connections
-	for i := 0; i < lt.config.ConcurrentConnections; i++ {
-		wg.Add(1)
-		go func(connID int) {
-			defer wg.Done()
-			lt.runConnection(ctx, connID, resultsChan)
-		}(i)
+	if lt.config.RampStep > 0 && lt.config.RampInterval > 0 {
+		launch(lt.config.RampStep)
+		ticker := time.NewTicker(lt.config.RampInterval)
+	ramp:
+		for lt.ConnectionsLaunched() < lt.config.ConcurrentConnections {
+			select {
+			case <-ctx.Done():
+				break ramp
+			case <-ticker.C:
+				launch(lt.config.RampStep)
+			}
+		}
+		ticker.Stop()
+	} else {
+		launch(lt.config.ConcurrentConnections)
 	}
 	
 	// Wait for all connections to complete
 	wg.Wait()
 	close(resultsChan)
+	<-collectorDone
 	
 	// Finalize results
 	lt.finalizeResults()
@@ -186,6 +429,11 @@ func (lt *LoadTester) runLoadTest(ctx context.Context) error {
 	return nil
 }
 
+// ConnectionsLaunched returns how many connection workers have been started
+func (lt *LoadTester) ConnectionsLaunched() int {
+	return int(atomic.LoadInt64(&lt.connectionsLaunched))
+}
+
 // runConnection runs requests for a single connection
 func (lt *LoadTester) runConnection(ctx context.Context, connID int, resultsChan chan<- *RequestResult) {
 	switch lt.config.RequestPattern {
@@ -282,10 +530,21 @@ func (lt *LoadTester) runBurstRequests(ctx context.Context, connID int, resultsC
 	}
 }
 
+// targetURL picks the endpoint for a request, rotating through Targets
+func (lt *LoadTester) targetURL(connID, reqID int) string {
+	if len(lt.config.Targets) == 0 {
+		return lt.config.TargetURL
+	}
+	return lt.config.Targets[(connID+reqID)%len(lt.config.Targets)]
+}
+
 // executeRequest executes a single HTTP request
 func (lt *LoadTester) executeRequest(ctx context.Context, connID, reqID int) *RequestResult {
 	result := &RequestResult{
-		StartTime: time.Now(),
+		StartTime:   time.Now(),
+		Concurrency: lt.ConnectionsLaunched(),
+		Target:      lt.targetURL(connID, reqID),
+		Setup:       reqID == 0,
 	}
 	
 	// Create request
@@ -294,41 +553,37 @@ func (lt *LoadTester) executeRequest(ctx context.Context, connID, reqID int) *Re
 		method = "GET"
 	}
 	
-	var body io.Reader
-	if lt.config.BodySize > 0 {
-		body = strings.NewReader(strings.Repeat("x", lt.config.BodySize))
+	// The HTTP/2 transport reports the first response byte; the HTTP/3 one
+	// does not call trace hooks and returns as soon as the headers arrive.
+	// Connection timings are reported by the dialers, see requestTrace.
+	ctx, trace := withRequestTrace(ctx)
+	
+	// Execute request; a connection shut down by the server is reported
+	// separately and, if configured, the request is retried once
+	client := lt.clientFor(connID)
+	resp, err := lt.doRequest(ctx, client, method, result.Target)
+	if kind, ok := serverCloseKind(err); ok {
+		lt.results.ConnectionMetrics.recordServerClose(kind, lt.config.ReconnectOnClose)
+		if lt.config.ReconnectOnClose {
+			resp, err = lt.doRequest(ctx, client, method, result.Target)
+		}
 	}
 	
-	req, err := http.NewRequestWithContext(ctx, method, lt.config.TargetURL, body)
 	if err != nil {
 		result.EndTime = time.Now()
 		result.Error = err
-		return result
-	}
-	
-	// Set headers
-	userAgent := lt.config.UserAgent
-	if userAgent == "" {
-		userAgent = "QUIC-Test-Suite/1.0"
-	}
-	req.Header.Set("User-Agent", userAgent)
-	
-	for key, value := range lt.config.Headers {
-		req.Header.Set(key, value)
-	}
-	
-	// Execute request
-	resp, err := lt.client.Do(req)
-	result.EndTime = time.Now()
-	
-	if err != nil {
-		result.Error = err
+		trace.apply(result)
 		return result
 	}
 	defer resp.Body.Close()
+	result.FirstByteTime = time.Now()
+	if reused := trace.apply(result); reused {
+		lt.results.ConnectionMetrics.recordReuse()
+	}
 	
 	// Read response body
 	bodyBytes, err := io.ReadAll(resp.Body)
+	result.EndTime = time.Now()
 	if err != nil {
 		result.Error = err
 		return result
@@ -340,19 +595,63 @@ func (lt *LoadTester) executeRequest(ctx context.Context, connID, reqID int) *Re
 	return result
 }
 
+// doRequest sends a single request. A 401 to a request carrying a login
+// token refreshes the token and repeats the request once.
+func (lt *LoadTester) doRequest(ctx context.Context, client *http.Client, method, target string) (*http.Response, error) {
+	req, generation, err := lt.newRequest(ctx, method, target)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || generation == 0 {
+		return resp, err
+	}
+	
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err := lt.refreshToken(ctx, generation); err != nil {
+		return nil, err
+	}
+	if req, _, err = lt.newRequest(ctx, method, target); err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// newRequest builds a request with the configured headers and authentication;
+// it also returns the generation of the login token used
+func (lt *LoadTester) newRequest(ctx context.Context, method, target string) (*http.Request, int64, error) {
+	var body io.Reader
+	if lt.config.BodySize > 0 {
+		body = strings.NewReader(strings.Repeat("x", lt.config.BodySize))
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	// Set headers
+	userAgent := lt.config.UserAgent
+	if userAgent == "" {
+		userAgent = "QUIC-Test-Suite/1.0"
+	}
+	req.Header.Set("User-Agent", userAgent)
+	generation := lt.applyAuth(req)
+	
+	for key, value := range lt.config.Headers {
+		req.Header.Set(key, lt.headerValue(value))
+	}
+	
+	return req, generation, nil
+}
+
 // collectResults collects and processes request results
-func (lt *LoadTester) collectResults(ctx context.Context, resultsChan <-chan *RequestResult) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case result, ok := <-resultsChan:
-			if !ok {
-				return
-			}
-			
-			lt.processResult(result)
-		}
+func (lt *LoadTester) collectResults(resultsChan <-chan *RequestResult) {
+	// Drain until the producers close the channel, even if the test context
+	// was cancelled, so that no produced result goes uncounted
+	for result := range resultsChan {
+		lt.processResult(result)
 	}
 }
 
@@ -362,18 +661,33 @@ func (lt *LoadTester) processResult(result *RequestResult) {
 	defer lt.results.mu.Unlock()
 	
 	atomic.AddInt64(&lt.results.TotalRequests, 1)
+	if result.Setup {
+		lt.recordSetup(result)
+	}
+	lt.results.ConnectionMetrics.recordTimings(result)
+	
+	var target *targetAccumulator
+	if len(lt.config.Targets) > 0 {
+		target = lt.results.targets[result.Target]
+		if target == nil {
+			target = &targetAccumulator{times: metrics.NewReservoir(lt.config.MaxSamples, time.Now().UnixNano())}
+			lt.results.targets[result.Target] = target
+		}
+		target.total++
+	}
 	
 	if result.Error != nil {
 		atomic.AddInt64(&lt.results.FailedRequests, 1)
 		
-		errorType := "unknown"
-		if result.Error != nil {
-			errorType = result.Error.Error()
+		// Raw error strings are unbounded (addresses, stream IDs), so group
+		// them by type and keep only a few samples
+		lt.results.errorAggregator.RecordAt(classifyError(result.Error), result.Error.Error(), result.EndTime)
+		if target != nil {
+			target.failed++
 		}
-		lt.results.Errors[errorType]++
 	} else {
 		atomic.AddInt64(&lt.results.SuccessfulRequests, 1)
-		atomic.AddInt64(&lt.results.BytesTransferred, result.ResponseSize)
+		atomic.AddInt64((*int64)(&lt.results.BytesTransferred), result.ResponseSize)
 		
 		// Record status code
 		statusCode := fmt.Sprintf("%d", result.StatusCode)
@@ -381,7 +695,27 @@ func (lt *LoadTester) processResult(result *RequestResult) {
 		
 		// Record response time
 		responseTime := float64(result.EndTime.Sub(result.StartTime).Nanoseconds()) / 1e6
-		lt.results.ResponseTimes = append(lt.results.ResponseTimes, responseTime)
+		lt.results.responseTimes.Add(responseTime)
+		lt.results.ResponseTimes = lt.results.responseTimes.Values()
+		lt.results.ttfbTimes.Add(float64(result.FirstByteTime.Sub(result.StartTime).Nanoseconds()) / 1e6)
+		
+		if target != nil {
+			target.times.Add(responseTime)
+		}
+		if lt.config.RampStep > 0 {
+			times := lt.results.concurrencyTimes[result.Concurrency]
+			if times == nil {
+				times = metrics.NewReservoir(lt.config.MaxSamples, time.Now().UnixNano())
+				lt.results.concurrencyTimes[result.Concurrency] = times
+			}
+			times.Add(responseTime)
+		}
+		
+		// The earliest request pays for connection establishment
+		if lt.results.firstRequestStart.IsZero() || result.StartTime.Before(lt.results.firstRequestStart) {
+			lt.results.firstRequestStart = result.StartTime
+			lt.results.FirstResponseTime = responseTime
+		}
 	}
 }
 
@@ -393,38 +727,44 @@ func (lt *LoadTester) finalizeResults() {
 	now := time.Now()
 	lt.results.CompletedAt = &now
 	lt.results.Status = "completed"
+	if lt.results.EstablishmentAborted {
+		lt.results.Status = "aborted"
+	}
 	
 	// Calculate response time statistics
 	if len(lt.results.ResponseTimes) > 0 {
-		// Sort response times for percentile calculation
-		times := make([]float64, len(lt.results.ResponseTimes))
-		copy(times, lt.results.ResponseTimes)
+		// Sort a copy of the response times for percentile calculation
+		times := append([]float64(nil), lt.results.ResponseTimes...)
+		sort.Float64s(times)
 		
-		// Simple sort (for production, use a more efficient algorithm)
-		for i := 0; i < len(times); i++ {
-			for j := i + 1; j < len(times); j++ {
-				if times[i] > times[j] {
-					times[i], times[j] = times[j], times[i]
-				}
-			}
-		}
+		// Calculate average, min, max and standard deviation in one pass
+		stats := metrics.Summarize(times)
+		lt.results.AvgResponseTime = stats.Mean
+		lt.results.MinResponseTime = stats.Min
+		lt.results.MaxResponseTime = stats.Max
+		lt.results.StdDevResponseTime = stats.StdDev
 		
-		// Calculate average
-		sum := 0.0
-		for _, t := range times {
-			sum += t
-		}
-		lt.results.AvgResponseTime = sum / float64(len(times))
-		
-		// Calculate percentiles
-		lt.results.P50ResponseTime = times[len(times)*50/100]
-		lt.results.P95ResponseTime = times[len(times)*95/100]
-		lt.results.P99ResponseTime = times[len(times)*99/100]
+		lt.results.P50ResponseTime = percentileOf(times, 50)
+		lt.results.P95ResponseTime = percentileOf(times, 95)
+		lt.results.P99ResponseTime = percentileOf(times, 99)
+	}
+	
+	if ttfb := lt.results.ttfbTimes.Values(); len(ttfb) > 0 {
+		sorted := append([]float64(nil), ttfb...)
+		sort.Float64s(sorted)
+		lt.results.AvgTTFB = metrics.Summarize(sorted).Mean
+		lt.results.P50TTFB = percentileOf(sorted, 50)
+		lt.results.P95TTFB = percentileOf(sorted, 95)
+		lt.results.P99TTFB = percentileOf(sorted, 99)
 	}
 	
+	lt.results.SampledPercentiles = lt.results.responseTimes.Sampled()
+	lt.results.ConcurrencyLevels = buildConcurrencyLevels(lt.results.concurrencyTimes)
+	
 	// Calculate requests per second
+	var duration float64
 	if lt.results.StartedAt != nil && lt.results.CompletedAt != nil {
-		duration := lt.results.CompletedAt.Sub(*lt.results.StartedAt).Seconds()
+		duration = lt.results.CompletedAt.Sub(*lt.results.StartedAt).Seconds()
 		if duration > 0 {
 			lt.results.RequestsPerSecond = float64(lt.results.TotalRequests) / duration
 		}
@@ -434,6 +774,94 @@ func (lt *LoadTester) finalizeResults() {
 	if lt.results.TotalRequests > 0 {
 		lt.results.ErrorRate = float64(lt.results.FailedRequests) / float64(lt.results.TotalRequests)
 	}
+	
+	if len(lt.results.targets) > 0 {
+		lt.results.TargetResults = buildTargetResults(lt.results.targets, duration)
+	}
+	lt.evaluateSLA()
+	
+	lt.results.Errors = lt.results.errorAggregator.Counts()
+	lt.results.TopErrors = lt.results.errorAggregator.TopN(topErrorsInResults)
+}
+
+// buildConcurrencyLevels summarizes response times per concurrency level
+func buildConcurrencyLevels(timesByLevel map[int]*metrics.Reservoir) []ConcurrencyLevel {
+	levels := make([]ConcurrencyLevel, 0, len(timesByLevel))
+	for connections, times := range timesByLevel {
+		if len(times.Values()) == 0 {
+			continue
+		}
+		sorted := append([]float64(nil), times.Values()...)
+		sort.Float64s(sorted)
+		
+		sum := 0.0
+		for _, t := range sorted {
+			sum += t
+		}
+		levels = append(levels, ConcurrencyLevel{
+			Connections:        connections,
+			Requests:           times.Count(),
+			AvgResponseTime:    sum / float64(len(sorted)),
+			P50ResponseTime:    percentileOf(sorted, 50),
+			P95ResponseTime:    percentileOf(sorted, 95),
+			SampledPercentiles: times.Sampled(),
+		})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Connections < levels[j].Connections
+	})
+	return levels
+}
+
+// percentileOf returns the p-th percentile of an ascending slice
+func percentileOf(sorted []float64, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := len(sorted) * p / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// classifyError maps a request error to a bounded set of error types
+func classifyError(err error) string {
+	var idleErr *quic.IdleTimeoutError
+	var handshakeErr *quic.HandshakeTimeoutError
+	var appErr *quic.ApplicationError
+	var transportErr *quic.TransportError
+	var netErr net.Error
+	
+	switch {
+	case errors.Is(err, ErrLoginFailed):
+		return "auth_failed"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &idleErr):
+		return "idle_timeout"
+	case errors.As(err, &handshakeErr):
+		return "handshake_timeout"
+	case errors.As(err, &appErr):
+		if kind, ok := serverCloseKind(err); ok {
+			return kind
+		}
+		return "application_error"
+	case errors.As(err, &transportErr):
+		return "transport_error"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return "unexpected_eof"
+	}
+	
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return "net_" + opErr.Op
+	}
+	return "unknown"
 }
 
 // GetResults returns the current test results
@@ -442,10 +870,58 @@ func (lt *LoadTester) GetResults() *LoadTestResults {
 	defer lt.results.mu.RUnlock()
 	
 	// Return a copy (without response times array for performance)
-	results := *lt.results
-	results.ResponseTimes = nil
+	r := lt.results
+	return &LoadTestResults{
+		LoadTestID:                r.LoadTestID,
+		Status:                    r.Status,
+		Protocol:                  r.Protocol,
+		CreatedAt:                 r.CreatedAt,
+		StartedAt:                 r.StartedAt,
+		CompletedAt:               r.CompletedAt,
+		Config:                    r.Config,
+		TotalRequests:             r.TotalRequests,
+		SuccessfulRequests:        r.SuccessfulRequests,
+		FailedRequests:            r.FailedRequests,
+		AvgResponseTime:           r.AvgResponseTime,
+		P50ResponseTime:           r.P50ResponseTime,
+		P95ResponseTime:           r.P95ResponseTime,
+		P99ResponseTime:           r.P99ResponseTime,
+		MinResponseTime:           r.MinResponseTime,
+		MaxResponseTime:           r.MaxResponseTime,
+		StdDevResponseTime:        r.StdDevResponseTime,
+		FirstResponseTime:         r.FirstResponseTime,
+		AvgTTFB:                   r.AvgTTFB,
+		P50TTFB:                   r.P50TTFB,
+		P95TTFB:                   r.P95TTFB,
+		P99TTFB:                   r.P99TTFB,
+		RequestsPerSecond:         r.RequestsPerSecond,
+		BytesTransferred:          r.BytesTransferred,
+		ErrorRate:                 r.ErrorRate,
+		SampledPercentiles:        r.SampledPercentiles,
+		ConcurrencyLevels:         r.ConcurrencyLevels,
+		TargetResults:             r.TargetResults,
+		SLAViolations:             r.SLAViolations,
+		StatusCodes:               r.StatusCodes,
+		Errors:                    r.errorAggregator.Counts(),
+		TopErrors:                 r.errorAggregator.TopN(topErrorsInResults),
+		SetupAttempts:             r.SetupAttempts,
+		SetupFailures:             r.SetupFailures,
+		EstablishmentAborted:      r.EstablishmentAborted,
+		MaxSustainableConnections: r.MaxSustainableConnections,
+		Warmup:                    r.Warmup,
+		TokenRefreshes:            atomic.LoadInt64(&r.TokenRefreshes),
+		ConnectionMetrics:         r.ConnectionMetrics,
+		errorAggregator:           r.errorAggregator,
+	}
+}
+
+// ResponseTimeSample returns a copy of the retained response times (ms) for
+// external analysis; a reservoir sample when MaxSamples was exceeded
+func (lt *LoadTester) ResponseTimeSample() []float64 {
+	lt.results.mu.RLock()
+	defer lt.results.mu.RUnlock()
 	
-	return &results
+	return append([]float64(nil), lt.results.ResponseTimes...)
 }
 
 // Stop stops the load test
@@ -462,8 +938,21 @@ func (lt *LoadTester) Stop() {
 
 // Close cleans up resources
 func (lt *LoadTester) Close() error {
-	if transport, ok := lt.client.Transport.(*http3.RoundTripper); ok {
-		return transport.Close()
+	lt.clientsMu.Lock()
+	clients := []*http.Client{lt.client}
+	for _, client := range lt.workerClients {
+		clients = append(clients, client)
 	}
-	return nil
+	lt.clientsMu.Unlock()
+	
+	var errs []error
+	for _, client := range clients {
+		switch transport := client.Transport.(type) {
+		case *http3.RoundTripper:
+			errs = append(errs, transport.Close())
+		case *http.Transport:
+			transport.CloseIdleConnections()
+		}
+	}
+	return errors.Join(errs...)
 }
\ No newline at end of file
@@ -0,0 +1,347 @@
+package http3
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestConnectionRamp(t *testing.T) {
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+
+	tester := NewLoadTester(&LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             url,
+		Duration:              30 * time.Second,
+		ConcurrentConnections: 4,
+		RequestsPerConnection: 40,
+		RequestPattern:        "sequential",
+		Timeout:               5 * time.Second,
+		RampStep:              1,
+		RampInterval:          100 * time.Millisecond,
+	})
+	defer tester.Close()
+
+	// Sample the number of running connections while the test ramps up
+	var samples []int
+	var mu sync.Mutex
+	stop := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				samples = append(samples, tester.ConnectionsLaunched())
+				mu.Unlock()
+			}
+		}
+	}()
+
+	start := time.Now()
+	if err := tester.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	close(stop)
+	<-sampled
+
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Ramp to 4 connections should take at least 300ms, took %v", elapsed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(samples) == 0 || samples[0] > 2 {
+		t.Fatalf("Expected the ramp to start with few connections, samples: %v", samples)
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i] < samples[i-1] {
+			t.Fatalf("Connection count decreased: %v", samples)
+		}
+	}
+	if last := samples[len(samples)-1]; last != 4 {
+		t.Errorf("Expected to reach 4 connections, got %d", last)
+	}
+
+	results := tester.GetResults()
+	if len(results.ConcurrencyLevels) != 4 {
+		t.Fatalf("Expected latency for 4 concurrency levels, got %+v", results.ConcurrencyLevels)
+	}
+	var total int64
+	for i, level := range results.ConcurrencyLevels {
+		if level.Connections != i+1 || level.Requests == 0 || level.AvgResponseTime <= 0 {
+			t.Errorf("Unexpected level %d: %+v", i, level)
+		}
+		total += level.Requests
+	}
+	if total != results.SuccessfulRequests {
+		t.Errorf("Levels account for %d requests, expected %d", total, results.SuccessfulRequests)
+	}
+}
+
+func TestCancelMidRunCountsAllResults(t *testing.T) {
+	const connections = 8
+
+	var inFlight int64
+	release := make(chan struct{})
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&inFlight, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() { close(release) })
+
+	tester := NewLoadTester(&LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             url,
+		Duration:              30 * time.Second,
+		ConcurrentConnections: connections,
+		RequestsPerConnection: 10,
+		RequestPattern:        "sequential",
+		Timeout:               30 * time.Second,
+	})
+	defer tester.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- tester.Start(ctx) }()
+
+	// Cancel once every connection is blocked in a request: all of them
+	// then produce their (failed) result after the context is done
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadInt64(&inFlight) < connections {
+		if time.Now().After(deadline) {
+			t.Fatalf("Only %d of %d requests reached the server", atomic.LoadInt64(&inFlight), connections)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	results := tester.GetResults()
+	if results.TotalRequests != connections || results.FailedRequests != connections {
+		t.Errorf("Expected %d cancelled requests to be counted, got %d total / %d failed",
+			connections, results.TotalRequests, results.FailedRequests)
+	}
+}
+
+func TestMaxSamplesBoundsResponseTimes(t *testing.T) {
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	tester := NewLoadTester(&LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             url,
+		Duration:              30 * time.Second,
+		ConcurrentConnections: 2,
+		RequestsPerConnection: 20,
+		RequestPattern:        "sequential",
+		Timeout:               5 * time.Second,
+		MaxSamples:            10,
+	})
+	defer tester.Close()
+
+	if err := tester.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	results := tester.GetResults()
+	if results.SuccessfulRequests != 40 {
+		t.Fatalf("Expected 40 successful requests, got %d", results.SuccessfulRequests)
+	}
+	if sample := tester.ResponseTimeSample(); len(sample) != 10 {
+		t.Errorf("Expected 10 retained samples, got %d", len(sample))
+	}
+	if !results.SampledPercentiles || results.P95ResponseTime <= 0 {
+		t.Errorf("Expected sampled positive percentiles, got sampled=%v p95=%f", results.SampledPercentiles, results.P95ResponseTime)
+	}
+}
+
+func TestResponseTimeStatsFromKnownSamples(t *testing.T) {
+	tester := NewLoadTester(&LoadTestConfig{TargetURL: "https://localhost/", MaxSamples: 100})
+	defer tester.Close()
+
+	start := time.Now()
+	for _, ms := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		tester.processResult(&RequestResult{
+			StartTime:  start,
+			EndTime:    start.Add(time.Duration(ms * float64(time.Millisecond))),
+			StatusCode: http.StatusOK,
+		})
+	}
+	tester.finalizeResults()
+
+	results := tester.GetResults()
+	if results.MinResponseTime != 2 || results.MaxResponseTime != 9 {
+		t.Errorf("Expected min 2 ms and max 9 ms, got %f and %f", results.MinResponseTime, results.MaxResponseTime)
+	}
+	if results.AvgResponseTime != 5 || results.StdDevResponseTime != 2 {
+		t.Errorf("Expected avg 5 ms and stddev 2 ms, got %f and %f", results.AvgResponseTime, results.StdDevResponseTime)
+	}
+}
+
+func TestMaxSamplesBoundsTargetAndRampTimes(t *testing.T) {
+	tester := NewLoadTester(&LoadTestConfig{
+		TargetURL:  "https://localhost/",
+		Targets:    []string{"https://localhost/a"},
+		RampStep:   1,
+		MaxSamples: 10,
+	})
+	defer tester.Close()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		tester.processResult(&RequestResult{
+			StartTime:   start,
+			EndTime:     start.Add(time.Duration(i+1) * time.Millisecond),
+			StatusCode:  http.StatusOK,
+			Concurrency: 1,
+			Target:      "https://localhost/a",
+		})
+	}
+	if n := len(tester.results.targets["https://localhost/a"].times.Values()); n != 10 {
+		t.Errorf("Expected 10 retained target samples, got %d", n)
+	}
+	if n := len(tester.results.concurrencyTimes[1].Values()); n != 10 {
+		t.Errorf("Expected 10 retained ramp samples, got %d", n)
+	}
+	tester.finalizeResults()
+
+	results := tester.GetResults()
+	if len(results.TargetResults) != 1 || !results.TargetResults[0].SampledPercentiles || results.TargetResults[0].TotalRequests != 100 {
+		t.Errorf("Expected sampled percentiles over 100 target requests, got %+v", results.TargetResults)
+	}
+	if len(results.ConcurrencyLevels) != 1 || !results.ConcurrencyLevels[0].SampledPercentiles || results.ConcurrencyLevels[0].Requests != 100 {
+		t.Errorf("Expected sampled percentiles over 100 ramp requests, got %+v", results.ConcurrencyLevels)
+	}
+}
+
+func TestPercentilesOfFewSamples(t *testing.T) {
+	for _, tc := range []struct {
+		samples []float64
+		p50     float64
+		p99     float64
+	}{
+		{nil, 0, 0},
+		{[]float64{3}, 3, 3},
+		{[]float64{5, 1}, 5, 5},
+		{[]float64{9, 1, 5}, 5, 9},
+	} {
+		tester := NewLoadTester(&LoadTestConfig{TargetURL: "https://localhost/"})
+		tester.results.ResponseTimes = tc.samples
+		tester.finalizeResults()
+		tester.Close()
+
+		results := tester.GetResults()
+		if results.P50ResponseTime != tc.p50 || results.P99ResponseTime != tc.p99 {
+			t.Errorf("Samples %v: expected p50 %v and p99 %v, got %v and %v",
+				tc.samples, tc.p50, tc.p99, results.P50ResponseTime, results.P99ResponseTime)
+		}
+	}
+}
+
+// BenchmarkFinalizeResults measures the statistics of a long high-rate run.
+// At this size the quadratic sort used before needed some 5*10^11
+// comparisons.
+func BenchmarkFinalizeResults(b *testing.B) {
+	const samples = 1_000_000
+	rng := rand.New(rand.NewSource(1))
+	times := make([]float64, samples)
+	for i := range times {
+		times[i] = rng.ExpFloat64() * 20
+	}
+
+	tester := NewLoadTester(&LoadTestConfig{TargetURL: "https://localhost/"})
+	defer tester.Close()
+	tester.results.ResponseTimes = times
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tester.finalizeResults()
+	}
+}
+
+func TestTransportPerWorkerOrShared(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	quicLn, err := quic.ListenAddrEarly("127.0.0.1:0", http3.ConfigureTLSConfig(tlsServer.TLS.Clone()), nil)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ln := &trackingListener{EarlyListener: quicLn}
+	server := &http3.Server{Handler: handler}
+	go server.ServeListener(ln)
+	defer func() {
+		server.Close()
+		quicLn.Close()
+	}()
+
+	run := func(shared bool) (accepted int, cm *ConnectionMetrics) {
+		ln.mu.Lock()
+		ln.conns = nil
+		ln.mu.Unlock()
+
+		tester := NewLoadTester(&LoadTestConfig{
+			TLSConfig:             insecureTLSConfig(),
+			TargetURL:             fmt.Sprintf("https://%s/", quicLn.Addr()),
+			Duration:              10 * time.Second,
+			ConcurrentConnections: 4,
+			RequestsPerConnection: 5,
+			RequestPattern:        "sequential",
+			Timeout:               5 * time.Second,
+			SharedTransport:       shared,
+		})
+		defer tester.Close()
+
+		if err := tester.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		results := tester.GetResults()
+		if results.SuccessfulRequests != 20 {
+			t.Fatalf("Expected 20 successful requests, got %d (errors: %v)", results.SuccessfulRequests, results.Errors)
+		}
+		ln.mu.Lock()
+		defer ln.mu.Unlock()
+		return len(ln.conns), results.ConnectionMetrics
+	}
+
+	// Every worker opens its own connection and reuses it for the rest
+	accepted, cm := run(false)
+	if accepted != 4 || cm.ConnectionsCreated != 4 || cm.ConnectionsReused != 16 {
+		t.Errorf("Per-worker transports: expected 4 connections and 16 reuses, got %d accepted, %d created, %d reused",
+			accepted, cm.ConnectionsCreated, cm.ConnectionsReused)
+	}
+
+	// All workers multiplex over one connection
+	accepted, cm = run(true)
+	if accepted != 1 || cm.ConnectionsCreated != 1 || cm.ConnectionsReused != 19 {
+		t.Errorf("Shared transport: expected 1 connection and 19 reuses, got %d accepted, %d created, %d reused",
+			accepted, cm.ConnectionsCreated, cm.ConnectionsReused)
+	}
+}
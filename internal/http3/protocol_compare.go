@@ -0,0 +1,92 @@
+package http3
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ProtocolComparison holds the results of running the same workload over
+// HTTP/2 (TCP) and HTTP/3 (QUIC)
+type ProtocolComparison struct {
+	H2    *LoadTestResults `json:"h2"`
+	H3    *LoadTestResults `json:"h3"`
+	Delta ProtocolDelta    `json:"delta"`
+}
+
+// ProtocolDelta is HTTP/3 relative to HTTP/2. Negative latency deltas and
+// positive throughput deltas mean HTTP/3 performed better.
+type ProtocolDelta struct {
+	AvgResponseTimeMs    float64 `json:"avg_response_time_ms"`
+	AvgResponseTimePct   float64 `json:"avg_response_time_pct"`
+	P95ResponseTimeMs    float64 `json:"p95_response_time_ms"`
+	P95ResponseTimePct   float64 `json:"p95_response_time_pct"`
+	FirstResponseTimeMs  float64 `json:"first_response_time_ms"`
+	FirstResponseTimePct float64 `json:"first_response_time_pct"`
+	RequestsPerSecond    float64 `json:"requests_per_second"`
+	RequestsPerSecondPct float64 `json:"requests_per_second_pct"`
+	ErrorRate            float64 `json:"error_rate"`
+}
+
+// CompareProtocols runs the workload from config over h2 and then h3
+// against the same target URL and reports the difference
+func CompareProtocols(ctx context.Context, config LoadTestConfig) (*ProtocolComparison, error) {
+	comparison := &ProtocolComparison{}
+
+	for _, protocol := range []string{ProtocolHTTP2, ProtocolHTTP3} {
+		cfg := config
+		cfg.Protocol = protocol
+
+		tester := NewLoadTester(&cfg)
+		err := tester.Start(ctx)
+		tester.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s run failed: %w", protocol, err)
+		}
+
+		if protocol == ProtocolHTTP2 {
+			comparison.H2 = tester.GetResults()
+		} else {
+			comparison.H3 = tester.GetResults()
+		}
+	}
+
+	comparison.Delta = computeProtocolDelta(comparison.H2, comparison.H3)
+	return comparison, nil
+}
+
+func computeProtocolDelta(h2, h3 *LoadTestResults) ProtocolDelta {
+	return ProtocolDelta{
+		AvgResponseTimeMs:    h3.AvgResponseTime - h2.AvgResponseTime,
+		AvgResponseTimePct:   percentChange(h2.AvgResponseTime, h3.AvgResponseTime),
+		P95ResponseTimeMs:    h3.P95ResponseTime - h2.P95ResponseTime,
+		P95ResponseTimePct:   percentChange(h2.P95ResponseTime, h3.P95ResponseTime),
+		FirstResponseTimeMs:  h3.FirstResponseTime - h2.FirstResponseTime,
+		FirstResponseTimePct: percentChange(h2.FirstResponseTime, h3.FirstResponseTime),
+		RequestsPerSecond:    h3.RequestsPerSecond - h2.RequestsPerSecond,
+		RequestsPerSecondPct: percentChange(h2.RequestsPerSecond, h3.RequestsPerSecond),
+		ErrorRate:            h3.ErrorRate - h2.ErrorRate,
+	}
+}
+
+func percentChange(base, value float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (value - base) / base * 100
+}
+
+// PrintSummary writes a side-by-side comparison table
+func (c *ProtocolComparison) PrintSummary(w io.Writer) {
+	fmt.Fprintf(w, "\nHTTP/2 vs HTTP/3 Comparison\n")
+	fmt.Fprintf(w, "===========================\n")
+	fmt.Fprintf(w, "%-22s %12s %12s %18s\n", "Metric", "h2", "h3", "Delta (h3-h2)")
+	row := func(name string, h2, h3, delta, pct float64, unit string) {
+		fmt.Fprintf(w, "%-22s %9.2f %-2s %9.2f %-2s %+9.2f %-2s (%+.1f%%)\n", name, h2, unit, h3, unit, delta, unit, pct)
+	}
+	row("Avg response time", c.H2.AvgResponseTime, c.H3.AvgResponseTime, c.Delta.AvgResponseTimeMs, c.Delta.AvgResponseTimePct, "ms")
+	row("P95 response time", c.H2.P95ResponseTime, c.H3.P95ResponseTime, c.Delta.P95ResponseTimeMs, c.Delta.P95ResponseTimePct, "ms")
+	row("Connection setup", c.H2.FirstResponseTime, c.H3.FirstResponseTime, c.Delta.FirstResponseTimeMs, c.Delta.FirstResponseTimePct, "ms")
+	row("Requests/sec", c.H2.RequestsPerSecond, c.H3.RequestsPerSecond, c.Delta.RequestsPerSecond, c.Delta.RequestsPerSecondPct, "")
+	fmt.Fprintf(w, "%-22s %11.2f%% %11.2f%% %+17.2f%%\n", "Error rate", c.H2.ErrorRate*100, c.H3.ErrorRate*100, c.Delta.ErrorRate*100)
+}
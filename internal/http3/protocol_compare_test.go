@@ -0,0 +1,100 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// insecureTLSConfig skips verification of the httptest certificates; the
+// load tester verifies server certificates unless told otherwise
+func insecureTLSConfig() *tls.Config {
+	return &tls.Config{InsecureSkipVerify: true}
+}
+
+// startDualStackServer serves the same handler over HTTP/2 (TCP) and HTTP/3
+// (UDP) on one port and returns the shared URL
+func startDualStackServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	tcpServer := httptest.NewUnstartedServer(handler)
+	tcpServer.EnableHTTP2 = true
+	tcpServer.StartTLS()
+	t.Cleanup(tcpServer.Close)
+
+	port := tcpServer.Listener.Addr().(*net.TCPAddr).Port
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP port %d: %v", port, err)
+	}
+
+	h3Server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(tcpServer.TLS.Clone()),
+	}
+	go h3Server.Serve(udpConn)
+	t.Cleanup(func() {
+		h3Server.Close()
+		udpConn.Close()
+	})
+
+	return fmt.Sprintf("https://127.0.0.1:%d/", port)
+}
+
+func TestCompareProtocols(t *testing.T) {
+	protocols := make(chan string, 100)
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protocols <- r.Proto
+		w.Write([]byte("hello"))
+	}))
+
+	config := LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             url,
+		Duration:              30 * time.Second,
+		ConcurrentConnections: 2,
+		RequestsPerConnection: 5,
+		RequestPattern:        "sequential",
+		Timeout:               5 * time.Second,
+	}
+
+	comparison, err := CompareProtocols(context.Background(), config)
+	if err != nil {
+		t.Fatalf("CompareProtocols failed: %v", err)
+	}
+	close(protocols)
+
+	seen := make(map[string]int)
+	for proto := range protocols {
+		seen[proto]++
+	}
+	if seen["HTTP/2.0"] != 10 || seen["HTTP/3.0"] != 10 {
+		t.Errorf("Expected 10 requests per protocol, got %v", seen)
+	}
+
+	for name, results := range map[string]*LoadTestResults{"h2": comparison.H2, "h3": comparison.H3} {
+		if results.Protocol != name {
+			t.Errorf("Expected protocol %s, got %s", name, results.Protocol)
+		}
+		if results.SuccessfulRequests != 10 || results.FailedRequests != 0 {
+			t.Errorf("%s: expected 10 successful requests, got %d ok / %d failed (errors: %v)",
+				name, results.SuccessfulRequests, results.FailedRequests, results.Errors)
+		}
+		if results.AvgResponseTime <= 0 || results.FirstResponseTime <= 0 {
+			t.Errorf("%s: expected positive response times, got avg=%f first=%f",
+				name, results.AvgResponseTime, results.FirstResponseTime)
+		}
+	}
+
+	want := comparison.H3.AvgResponseTime - comparison.H2.AvgResponseTime
+	if comparison.Delta.AvgResponseTimeMs != want {
+		t.Errorf("Expected avg delta %f, got %f", want, comparison.Delta.AvgResponseTimeMs)
+	}
+}
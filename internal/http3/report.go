@@ -0,0 +1,171 @@
+package http3
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SaveReport writes load test results in the given format (json, md or csv),
+// matching the report formats of the main test suite
+func SaveReport(results *LoadTestResults, path, format string) error {
+	format = strings.ToLower(format)
+	if format == "" {
+		format = "md"
+	}
+	if path == "" {
+		path = fmt.Sprintf("report.%s", format)
+	}
+
+	var data []byte
+	switch format {
+	case "json":
+		var err error
+		data, err = json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(reportRows(results)); err != nil {
+			return fmt.Errorf("failed to write csv report: %w", err)
+		}
+		data = buf.Bytes()
+	default:
+		data = []byte(reportMarkdown(results))
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	return nil
+}
+
+// reportRows flattens the headline metrics into metric/value rows
+func reportRows(r *LoadTestResults) [][]string {
+	rows := [][]string{
+		{"metric", "value"},
+		{"protocol", r.Protocol},
+		{"status", r.Status},
+		{"total_requests", fmt.Sprintf("%d", r.TotalRequests)},
+		{"successful_requests", fmt.Sprintf("%d", r.SuccessfulRequests)},
+		{"failed_requests", fmt.Sprintf("%d", r.FailedRequests)},
+		{"requests_per_second", fmt.Sprintf("%.2f", r.RequestsPerSecond)},
+		{"avg_response_time_ms", fmt.Sprintf("%.2f", r.AvgResponseTime)},
+		{"p50_response_time_ms", fmt.Sprintf("%.2f", r.P50ResponseTime)},
+		{"p95_response_time_ms", fmt.Sprintf("%.2f", r.P95ResponseTime)},
+		{"p99_response_time_ms", fmt.Sprintf("%.2f", r.P99ResponseTime)},
+		{"min_response_time_ms", fmt.Sprintf("%.2f", r.MinResponseTime)},
+		{"max_response_time_ms", fmt.Sprintf("%.2f", r.MaxResponseTime)},
+		{"stddev_response_time_ms", fmt.Sprintf("%.2f", r.StdDevResponseTime)},
+		{"first_response_time_ms", fmt.Sprintf("%.2f", r.FirstResponseTime)},
+		{"avg_ttfb_ms", fmt.Sprintf("%.2f", r.AvgTTFB)},
+		{"p50_ttfb_ms", fmt.Sprintf("%.2f", r.P50TTFB)},
+		{"p95_ttfb_ms", fmt.Sprintf("%.2f", r.P95TTFB)},
+		{"p99_ttfb_ms", fmt.Sprintf("%.2f", r.P99TTFB)},
+		{"bytes_transferred", fmt.Sprintf("%d", r.BytesTransferred)},
+		{"error_rate", fmt.Sprintf("%.4f", r.ErrorRate)},
+		{"sampled_percentiles", fmt.Sprintf("%t", r.SampledPercentiles)},
+		{"setup_attempts", fmt.Sprintf("%d", r.SetupAttempts)},
+		{"setup_failures", fmt.Sprintf("%d", r.SetupFailures)},
+	}
+	if r.EstablishmentAborted {
+		rows = append(rows, []string{"max_sustainable_connections", fmt.Sprintf("%d", r.MaxSustainableConnections)})
+	}
+	if w := r.Warmup; w != nil {
+		rows = append(rows,
+			[]string{"warmup_connections", fmt.Sprintf("%d", w.Connections)},
+			[]string{"warmup_failures", fmt.Sprintf("%d", w.Failures)},
+			[]string{"warmup_avg_handshake_time_ms", fmt.Sprintf("%.2f", w.AvgHandshakeTime)},
+			[]string{"warmup_max_handshake_time_ms", fmt.Sprintf("%.2f", w.MaxHandshakeTime)},
+		)
+	}
+	if cm := r.ConnectionMetrics; cm != nil {
+		rows = append(rows,
+			[]string{"connections_created", fmt.Sprintf("%d", cm.ConnectionsCreated)},
+			[]string{"connections_reused", fmt.Sprintf("%d", cm.ConnectionsReused)},
+			[]string{"avg_connection_time_ms", fmt.Sprintf("%.2f", cm.AvgConnectionTime)},
+			[]string{"avg_dns_lookup_time_ms", fmt.Sprintf("%.2f", cm.DNSLookupTime)},
+			[]string{"avg_tls_handshake_time_ms", fmt.Sprintf("%.2f", cm.TLSHandshakeTime)},
+			[]string{"full_handshakes", fmt.Sprintf("%d", cm.FullHandshakes)},
+			[]string{"resumed_handshakes", fmt.Sprintf("%d", cm.ResumedHandshakes)},
+			[]string{"avg_full_handshake_time_ms", fmt.Sprintf("%.2f", cm.AvgFullHandshakeTime)},
+			[]string{"avg_resumed_handshake_time_ms", fmt.Sprintf("%.2f", cm.AvgResumedHandshakeTime)},
+			[]string{"goaways", fmt.Sprintf("%d", cm.GoAways)},
+			[]string{"server_closes", fmt.Sprintf("%d", cm.ServerCloses)},
+			[]string{"reconnects", fmt.Sprintf("%d", cm.Reconnects)},
+		)
+	}
+	for _, v := range r.SLAViolations {
+		rows = append(rows, []string{"sla_violation", v.String()})
+	}
+	return rows
+}
+
+func reportMarkdown(r *LoadTestResults) string {
+	var buf bytes.Buffer
+
+	buf.WriteString("# HTTP Load Test Report\n\n")
+	if r.Config != nil {
+		fmt.Fprintf(&buf, "**Target:** %s  \n", r.Config.TargetURL)
+		if len(r.Config.Targets) > 0 {
+			fmt.Fprintf(&buf, "**Targets:** %s  \n", strings.Join(r.Config.Targets, ", "))
+		}
+		fmt.Fprintf(&buf, "**Connections:** %d, **Requests/connection:** %d, **Pattern:** %s\n\n",
+			r.Config.ConcurrentConnections, r.Config.RequestsPerConnection, r.Config.RequestPattern)
+	}
+
+	buf.WriteString("| Metric | Value |\n|---|---|\n")
+	for _, row := range reportRows(r)[1:] {
+		if row[0] == "sla_violation" {
+			continue
+		}
+		fmt.Fprintf(&buf, "| %s | %s |\n", row[0], row[1])
+	}
+
+	if len(r.StatusCodes) > 0 {
+		buf.WriteString("\n## Status Codes\n\n| Code | Count |\n|---|---|\n")
+		for code, count := range r.StatusCodes {
+			fmt.Fprintf(&buf, "| %s | %d |\n", code, count)
+		}
+	}
+
+	if len(r.TopErrors) > 0 {
+		buf.WriteString("\n## Errors\n\n| Type | Count | Example |\n|---|---|---|\n")
+		for _, e := range r.TopErrors {
+			sample := ""
+			if len(e.Samples) > 0 {
+				sample = strings.ReplaceAll(e.Samples[0], "|", "\\|")
+			}
+			fmt.Fprintf(&buf, "| %s | %d | %s |\n", e.Type, e.Count, sample)
+		}
+	}
+
+	if len(r.TargetResults) > 0 {
+		buf.WriteString("\n## Targets\n\n| Target | Requests | Failed | Avg (ms) | P95 (ms) | RPS |\n|---|---|---|---|---|---|\n")
+		for _, t := range r.TargetResults {
+			fmt.Fprintf(&buf, "| %s | %d | %d | %.2f | %.2f | %.2f |\n",
+				t.Target, t.TotalRequests, t.FailedRequests, t.AvgResponseTime, t.P95ResponseTime, t.RequestsPerSecond)
+		}
+	}
+
+	if len(r.ConcurrencyLevels) > 0 {
+		buf.WriteString("\n## Latency vs Concurrency\n\n| Connections | Requests | Avg (ms) | P95 (ms) |\n|---|---|---|---|\n")
+		for _, l := range r.ConcurrencyLevels {
+			fmt.Fprintf(&buf, "| %d | %d | %.2f | %.2f |\n", l.Connections, l.Requests, l.AvgResponseTime, l.P95ResponseTime)
+		}
+	}
+
+	if len(r.SLAViolations) > 0 {
+		buf.WriteString("\n## SLA Violations\n\n")
+		for _, v := range r.SLAViolations {
+			fmt.Fprintf(&buf, "- %s\n", v)
+		}
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,142 @@
+package http3
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"quic-test/internal/metrics"
+)
+
+// StatusSLAFailed marks a completed load test that violated its SLA
+const StatusSLAFailed = "sla_failed"
+
+// LoadTestSLA holds the thresholds a load test must meet. Zero disables a check.
+type LoadTestSLA struct {
+	MaxP95ResponseTime   time.Duration `json:"max_p95_response_time,omitempty"`
+	MaxErrorRate         float64       `json:"max_error_rate,omitempty"` // 0..1
+	MinRequestsPerSecond float64       `json:"min_requests_per_second,omitempty"`
+}
+
+// Enabled reports whether any threshold is set
+func (s LoadTestSLA) Enabled() bool {
+	return s.MaxP95ResponseTime > 0 || s.MaxErrorRate > 0 || s.MinRequestsPerSecond > 0
+}
+
+// SLAViolation describes a single failed SLA check
+type SLAViolation struct {
+	Target    string  `json:"target,omitempty"` // empty for the aggregate over all targets
+	Metric    string  `json:"metric"`
+	Threshold float64 `json:"threshold"`
+	Actual    float64 `json:"actual"`
+}
+
+func (v SLAViolation) String() string {
+	scope := "overall"
+	if v.Target != "" {
+		scope = v.Target
+	}
+	return fmt.Sprintf("%s: %s = %.4g (threshold %.4g)", scope, v.Metric, v.Actual, v.Threshold)
+}
+
+// TargetResults holds statistics for one endpoint of a multi-target run
+type TargetResults struct {
+	Target             string  `json:"target"`
+	TotalRequests      int64   `json:"total_requests"`
+	SuccessfulRequests int64   `json:"successful_requests"`
+	FailedRequests     int64   `json:"failed_requests"`
+	AvgResponseTime    float64 `json:"avg_response_time_ms"`
+	P95ResponseTime    float64 `json:"p95_response_time_ms"`
+	RequestsPerSecond  float64 `json:"requests_per_second"`
+	ErrorRate          float64 `json:"error_rate"`
+	// Estimated from a reservoir sample of MaxSamples response times
+	SampledPercentiles bool `json:"sampled_percentiles,omitempty"`
+}
+
+// targetAccumulator collects raw per-target data until finalization
+type targetAccumulator struct {
+	total, failed int64
+	times         *metrics.Reservoir // bounded by MaxSamples
+}
+
+// checkSLA evaluates sla against the given statistics
+func checkSLA(sla LoadTestSLA, target string, p95, errorRate, rps float64) []SLAViolation {
+	var violations []SLAViolation
+
+	if sla.MaxP95ResponseTime > 0 {
+		limit := float64(sla.MaxP95ResponseTime) / float64(time.Millisecond)
+		if p95 > limit {
+			violations = append(violations, SLAViolation{Target: target, Metric: "p95_response_time_ms", Threshold: limit, Actual: p95})
+		}
+	}
+	if sla.MaxErrorRate > 0 && errorRate > sla.MaxErrorRate {
+		violations = append(violations, SLAViolation{Target: target, Metric: "error_rate", Threshold: sla.MaxErrorRate, Actual: errorRate})
+	}
+	if sla.MinRequestsPerSecond > 0 && rps < sla.MinRequestsPerSecond {
+		violations = append(violations, SLAViolation{Target: target, Metric: "requests_per_second", Threshold: sla.MinRequestsPerSecond, Actual: rps})
+	}
+
+	return violations
+}
+
+// buildTargetResults summarizes per-target data over the given run duration
+func buildTargetResults(accumulators map[string]*targetAccumulator, duration float64) []TargetResults {
+	results := make([]TargetResults, 0, len(accumulators))
+	for target, acc := range accumulators {
+		tr := TargetResults{
+			Target:             target,
+			TotalRequests:      acc.total,
+			FailedRequests:     acc.failed,
+			SuccessfulRequests: acc.total - acc.failed,
+		}
+		if len(acc.times.Values()) > 0 {
+			sorted := append([]float64(nil), acc.times.Values()...)
+			sort.Float64s(sorted)
+			sum := 0.0
+			for _, t := range sorted {
+				sum += t
+			}
+			tr.AvgResponseTime = sum / float64(len(sorted))
+			tr.P95ResponseTime = percentileOf(sorted, 95)
+			tr.SampledPercentiles = acc.times.Sampled()
+		}
+		if duration > 0 {
+			tr.RequestsPerSecond = float64(acc.total) / duration
+		}
+		if acc.total > 0 {
+			tr.ErrorRate = float64(acc.failed) / float64(acc.total)
+		}
+		results = append(results, tr)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Target < results[j].Target
+	})
+	return results
+}
+
+// evaluateSLA checks the aggregate and per-target SLAs; called with results
+// locked. Only a completed run becomes sla_failed: an aborted or stopped run
+// keeps its status, with the violations still listed.
+func (lt *LoadTester) evaluateSLA() {
+	r := lt.results
+	r.SLAViolations = checkSLA(lt.config.SLA, "", r.P95ResponseTime, r.ErrorRate, r.RequestsPerSecond)
+
+	for _, tr := range r.TargetResults {
+		sla := lt.config.SLA
+		if override, ok := lt.config.TargetSLAs[tr.Target]; ok {
+			sla = override
+		}
+		r.SLAViolations = append(r.SLAViolations, checkSLA(sla, tr.Target, tr.P95ResponseTime, tr.ErrorRate, tr.RequestsPerSecond)...)
+	}
+
+	if len(r.SLAViolations) > 0 && r.Status == "completed" {
+		r.Status = StatusSLAFailed
+	}
+}
+
+// SLAFailed reports whether the finished test violated its SLA
+func (lt *LoadTester) SLAFailed() bool {
+	lt.results.mu.RLock()
+	defer lt.results.mu.RUnlock()
+	return lt.results.Status == StatusSLAFailed
+}
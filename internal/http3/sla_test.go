@@ -0,0 +1,123 @@
+package http3
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func runSLATest(t *testing.T, config LoadTestConfig) (*LoadTester, *LoadTestResults) {
+	t.Helper()
+
+	tester := NewLoadTester(&config)
+	defer tester.Close()
+	if err := tester.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return tester, tester.GetResults()
+}
+
+func TestLoadTestSLAPass(t *testing.T) {
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	tester, results := runSLATest(t, LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             url,
+		Duration:              30 * time.Second,
+		ConcurrentConnections: 2,
+		RequestsPerConnection: 10,
+		Timeout:               5 * time.Second,
+		SLA: LoadTestSLA{
+			MaxP95ResponseTime:   5 * time.Second,
+			MaxErrorRate:         0.01,
+			MinRequestsPerSecond: 1,
+		},
+	})
+
+	if tester.SLAFailed() || results.Status != "completed" {
+		t.Errorf("Expected SLA to pass, status %s, violations %v", results.Status, results.SLAViolations)
+	}
+}
+
+func TestLoadTestSLAFailPerTarget(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("slow"))
+	})
+	url := startDualStackServer(t, mux)
+
+	// The handshakes are kept out of the measured requests and the fast
+	// endpoint gets a wide margin, so only /slow can push a p95 over budget
+	tester, results := runSLATest(t, LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		Targets:               []string{url + "fast", url + "slow"},
+		Duration:              30 * time.Second,
+		ConcurrentConnections: 2,
+		RequestsPerConnection: 6,
+		Timeout:               5 * time.Second,
+		PreWarm:               true,
+		SLA:                   LoadTestSLA{MaxP95ResponseTime: 150 * time.Millisecond},
+		TargetSLAs: map[string]LoadTestSLA{
+			// The aggregate still fails, but the slow endpoint has its own budget
+			url + "slow": {MaxP95ResponseTime: 5 * time.Second},
+		},
+	})
+
+	if !tester.SLAFailed() || results.Status != StatusSLAFailed {
+		t.Fatalf("Expected status %s, got %s", StatusSLAFailed, results.Status)
+	}
+	if len(results.TargetResults) != 2 {
+		t.Fatalf("Expected results for 2 targets, got %+v", results.TargetResults)
+	}
+	for _, tr := range results.TargetResults {
+		if tr.TotalRequests != 6 || tr.FailedRequests != 0 {
+			t.Errorf("Target %s: expected 6 successful requests, got %+v", tr.Target, tr)
+		}
+	}
+
+	if len(results.SLAViolations) != 1 {
+		t.Fatalf("Expected exactly the aggregate p95 violation, got %v", results.SLAViolations)
+	}
+	if v := results.SLAViolations[0]; v.Target != "" || v.Metric != "p95_response_time_ms" {
+		t.Errorf("Unexpected violation: %s", v)
+	}
+}
+
+func TestEvaluateSLAKeepsAbortedStatus(t *testing.T) {
+	tester := &LoadTester{
+		config:  &LoadTestConfig{SLA: LoadTestSLA{MaxP95ResponseTime: 50 * time.Millisecond}},
+		results: &LoadTestResults{Status: "aborted", P95ResponseTime: 120},
+	}
+	tester.evaluateSLA()
+
+	if tester.results.Status != "aborted" {
+		t.Errorf("Expected an aborted run to stay aborted, got %s", tester.results.Status)
+	}
+	if len(tester.results.SLAViolations) != 1 {
+		t.Errorf("Expected the violation to be reported anyway, got %v", tester.results.SLAViolations)
+	}
+	if tester.SLAFailed() {
+		t.Error("Expected SLAFailed to be false for an aborted run")
+	}
+}
+
+func TestCheckSLA(t *testing.T) {
+	sla := LoadTestSLA{MaxP95ResponseTime: 100 * time.Millisecond, MaxErrorRate: 0.05, MinRequestsPerSecond: 10}
+
+	if v := checkSLA(sla, "", 80, 0.01, 20); len(v) != 0 {
+		t.Errorf("Expected no violations, got %v", v)
+	}
+	if v := checkSLA(sla, "t", 120, 0.10, 5); len(v) != 3 {
+		t.Errorf("Expected 3 violations, got %v", v)
+	}
+	if v := checkSLA(LoadTestSLA{}, "", 1e9, 1, 0); len(v) != 0 {
+		t.Errorf("Disabled SLA must not report violations, got %v", v)
+	}
+}
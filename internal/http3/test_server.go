@@ -0,0 +1,302 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// SyntheticResponse is one kind of response served by the test server
+type SyntheticResponse struct {
+	Status int           // HTTP status code
+	Size   int           // body size in bytes
+	Delay  time.Duration // pause before the response headers
+	Weight int           // share of requests relative to the other responses
+}
+
+// DefaultSyntheticResponse is served when no responses are configured
+var DefaultSyntheticResponse = SyntheticResponse{Status: http.StatusOK, Size: 1024, Weight: 1}
+
+// maxSyntheticBodySize bounds the body of a single response
+const maxSyntheticBodySize = 1 << 30
+
+// ParseSyntheticResponse parses a response spec such as
+// "status=503,size=0,delay=50ms,weight=1". Omitted keys keep the values of
+// DefaultSyntheticResponse.
+func ParseSyntheticResponse(spec string) (SyntheticResponse, error) {
+	resp := DefaultSyntheticResponse
+	for _, field := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return resp, fmt.Errorf("response %q: expected key=value, got %q", spec, field)
+		}
+		var err error
+		switch key {
+		case "status":
+			resp.Status, err = strconv.Atoi(value)
+		case "size":
+			resp.Size, err = strconv.Atoi(value)
+		case "delay":
+			resp.Delay, err = time.ParseDuration(value)
+		case "weight":
+			resp.Weight, err = strconv.Atoi(value)
+		default:
+			return resp, fmt.Errorf("response %q: unknown key %q, expected status, size, delay or weight", spec, key)
+		}
+		if err != nil {
+			return resp, fmt.Errorf("response %q: invalid %s: %w", spec, key, err)
+		}
+	}
+	if err := resp.validate(); err != nil {
+		return resp, fmt.Errorf("response %q: %w", spec, err)
+	}
+	return resp, nil
+}
+
+func (r SyntheticResponse) validate() error {
+	if r.Status < 100 || r.Status > 999 {
+		return fmt.Errorf("status %d is not a valid HTTP status code", r.Status)
+	}
+	if r.Size < 0 || r.Size > maxSyntheticBodySize {
+		return fmt.Errorf("size must be between 0 and %d", maxSyntheticBodySize)
+	}
+	if r.Delay < 0 {
+		return errors.New("delay must be non-negative")
+	}
+	if r.Weight <= 0 {
+		return errors.New("weight must be positive")
+	}
+	return nil
+}
+
+func (r SyntheticResponse) String() string {
+	return fmt.Sprintf("status=%d,size=%d,delay=%v,weight=%d", r.Status, r.Size, r.Delay, r.Weight)
+}
+
+// SyntheticResponseFlags collects repeated --response flags and rejects
+// invalid specs when they are parsed
+type SyntheticResponseFlags []SyntheticResponse
+
+func (f *SyntheticResponseFlags) String() string {
+	if f == nil {
+		return ""
+	}
+	specs := make([]string, len(*f))
+	for i, resp := range *f {
+		specs[i] = resp.String()
+	}
+	return strings.Join(specs, " ")
+}
+
+func (f *SyntheticResponseFlags) Set(value string) error {
+	resp, err := ParseSyntheticResponse(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, resp)
+	return nil
+}
+
+// TestServerConfig configures the built-in HTTP/3 test server
+type TestServerConfig struct {
+	Addr       string
+	TLSConfig  *tls.Config // the h3 ALPN is added by the server
+	QUICConfig *quic.Config
+	// Responses are picked at random in proportion to their weights;
+	// empty serves DefaultSyntheticResponse
+	Responses []SyntheticResponse
+}
+
+// TestServerStats counts the requests served
+type TestServerStats struct {
+	Requests    int64
+	StatusCodes map[int]int64
+}
+
+// TestServer serves synthetic responses over HTTP/3, so that the load tester
+// can be exercised without an external target. Every request gets one of the
+// configured responses; the query parameters status, size and delay override
+// it for a single request.
+type TestServer struct {
+	responses   []SyntheticResponse
+	totalWeight int
+	conn        net.PacketConn
+	server      *http3.Server
+
+	mu    sync.Mutex
+	stats TestServerStats
+}
+
+// ListenTestServer validates the responses and binds the UDP socket; the
+// server starts answering with Serve
+func ListenTestServer(config TestServerConfig) (*TestServer, error) {
+	responses := config.Responses
+	if len(responses) == 0 {
+		responses = []SyntheticResponse{DefaultSyntheticResponse}
+	}
+	s := &TestServer{
+		responses: responses,
+		stats:     TestServerStats{StatusCodes: make(map[int]int64)},
+	}
+	for _, resp := range responses {
+		if err := resp.validate(); err != nil {
+			return nil, fmt.Errorf("response %s: %w", resp, err)
+		}
+		s.totalWeight += resp.Weight
+	}
+	if config.TLSConfig == nil {
+		return nil, errors.New("test server requires a TLS config")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", config.Addr, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", config.Addr, err)
+	}
+	s.conn = conn
+	s.server = &http3.Server{
+		Handler:    s,
+		TLSConfig:  http3.ConfigureTLSConfig(config.TLSConfig.Clone()),
+		QuicConfig: config.QUICConfig,
+	}
+	return s, nil
+}
+
+// Addr returns the bound UDP address
+func (s *TestServer) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Serve answers requests until ctx is cancelled. It returns nil after a
+// cancellation and the listener error otherwise.
+func (s *TestServer) Serve(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() { done <- s.server.Serve(s.conn) }()
+	select {
+	case <-ctx.Done():
+		s.server.Close()
+		s.conn.Close()
+		<-done
+		return nil
+	case err := <-done:
+		s.conn.Close()
+		return fmt.Errorf("HTTP/3 test server failed: %w", err)
+	}
+}
+
+// Stats returns a copy of the request counters
+func (s *TestServer) Stats() TestServerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := TestServerStats{
+		Requests:    s.stats.Requests,
+		StatusCodes: make(map[int]int64, len(s.stats.StatusCodes)),
+	}
+	for code, count := range s.stats.StatusCodes {
+		stats.StatusCodes[code] = count
+	}
+	return stats
+}
+
+// String describes the counters in one line, status codes in order
+func (st TestServerStats) String() string {
+	codes := make([]int, 0, len(st.StatusCodes))
+	for code := range st.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%d: %d", code, st.StatusCodes[code])
+	}
+	return fmt.Sprintf("%d requests (%s)", st.Requests, strings.Join(parts, ", "))
+}
+
+func (s *TestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.responseFor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.count(http.StatusBadRequest)
+		return
+	}
+	if resp.Delay > 0 {
+		timer := time.NewTimer(resp.Delay)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(resp.Size))
+	w.WriteHeader(resp.Status)
+	s.count(resp.Status)
+
+	chunk := make([]byte, min(resp.Size, 32<<10))
+	for left := resp.Size; left > 0; left -= len(chunk) {
+		if left < len(chunk) {
+			chunk = chunk[:left]
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+	}
+}
+
+// responseFor picks the response for a request: a weighted random one with
+// the overrides of the query parameters applied
+func (s *TestServer) responseFor(r *http.Request) (SyntheticResponse, error) {
+	resp := s.responses[0]
+	if len(s.responses) > 1 {
+		n := rand.Intn(s.totalWeight)
+		for _, candidate := range s.responses {
+			if n < candidate.Weight {
+				resp = candidate
+				break
+			}
+			n -= candidate.Weight
+		}
+	}
+
+	query := r.URL.Query()
+	var err error
+	if v := query.Get("status"); v != "" {
+		if resp.Status, err = strconv.Atoi(v); err != nil {
+			return resp, fmt.Errorf("invalid status: %w", err)
+		}
+	}
+	if v := query.Get("size"); v != "" {
+		if resp.Size, err = strconv.Atoi(v); err != nil {
+			return resp, fmt.Errorf("invalid size: %w", err)
+		}
+	}
+	if v := query.Get("delay"); v != "" {
+		if resp.Delay, err = time.ParseDuration(v); err != nil {
+			return resp, fmt.Errorf("invalid delay: %w", err)
+		}
+	}
+	return resp, resp.validate()
+}
+
+func (s *TestServer) count(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Requests++
+	s.stats.StatusCodes[status]++
+}
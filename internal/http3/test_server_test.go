@@ -0,0 +1,114 @@
+package http3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// startTestServer runs the built-in test server on a free local port and
+// returns it with its URL
+func startTestServer(t *testing.T, responses ...SyntheticResponse) (*TestServer, string) {
+	t.Helper()
+	server, err := ListenTestServer(TestServerConfig{
+		Addr:      "127.0.0.1:0",
+		TLSConfig: internal.GenerateTLSConfig(false),
+		Responses: responses,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return server, fmt.Sprintf("https://%s/", server.Addr())
+}
+
+func TestLoadTesterAgainstTestServer(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	server, url := startTestServer(t,
+		SyntheticResponse{Status: http.StatusOK, Size: 4096, Delay: delay, Weight: 3},
+		SyntheticResponse{Status: http.StatusServiceUnavailable, Size: 0, Delay: delay, Weight: 1},
+	)
+
+	tester := NewLoadTester(&LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             url,
+		Duration:              30 * time.Second,
+		ConcurrentConnections: 2,
+		RequestsPerConnection: 40,
+		RequestPattern:        "sequential",
+		Timeout:               5 * time.Second,
+	})
+	defer tester.Close()
+	if err := tester.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	results := tester.GetResults()
+
+	if results.TotalRequests != 80 {
+		t.Fatalf("Expected 80 requests, got %d", results.TotalRequests)
+	}
+	ok, unavailable := results.StatusCodes["200"], results.StatusCodes["503"]
+	if ok+unavailable != 80 || ok == 0 || unavailable == 0 || ok < unavailable {
+		t.Errorf("Expected mostly 200 and some 503 responses, got %v", results.StatusCodes)
+	}
+	if results.MinResponseTime < float64(delay.Milliseconds()) {
+		t.Errorf("Expected every response to take at least %v, got min %.2f ms", delay, results.MinResponseTime)
+	}
+	if stats := server.Stats(); stats.Requests != 80 || stats.StatusCodes[http.StatusOK] != ok {
+		t.Errorf("Expected the server to count the same responses, got %s", stats)
+	}
+}
+
+func TestTestServerQueryOverrides(t *testing.T) {
+	_, url := startTestServer(t)
+
+	client := &http.Client{Transport: &http3.RoundTripper{TLSClientConfig: insecureTLSConfig()}}
+	defer client.Transport.(*http3.RoundTripper).Close()
+	resp, err := client.Get(url + "?status=404&size=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || resp.ContentLength != 10 {
+		t.Errorf("Expected a 404 with 10 bytes, got %d with %d", resp.StatusCode, resp.ContentLength)
+	}
+
+	resp, err = client.Get(url + "?delay=bogus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid override, got %d", resp.StatusCode)
+	}
+}
+
+func TestParseSyntheticResponse(t *testing.T) {
+	resp, err := ParseSyntheticResponse("status=503, delay=50ms,weight=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SyntheticResponse{Status: 503, Size: DefaultSyntheticResponse.Size, Delay: 50 * time.Millisecond, Weight: 2}
+	if resp != want {
+		t.Errorf("Expected %+v, got %+v", want, resp)
+	}
+	for _, spec := range []string{"status", "status=abc", "status=42", "size=-1", "weight=0", "delay=-1s", "color=red"} {
+		if _, err := ParseSyntheticResponse(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// The HTTP/2 transport reports DNS lookups and the first response byte
+// through httptrace itself. The HTTP/3 transport calls no trace hooks, so its
+// dialer reports the lookup, and both dialers report the TLS handshake. With
+// HTTP/3 the TLS handshake is carried in the QUIC handshake and cannot be
+// timed apart from it: TLSTime is the whole QUIC handshake. ConnectionTime
+// is how long the dialer took, lookup and handshake included.
+
+// requestTrace collects the connection timings of one request
+type requestTrace struct {
+	mu        sync.Mutex
+	dialed    bool // the request opened a new connection
+	dnsStart  time.Time
+	tlsStart  time.Time
+	dns       time.Duration
+	tls       time.Duration
+	dial      time.Duration
+	firstByte time.Time
+}
+
+// requestTraceKey is the context key of the request trace
+type requestTraceKey struct{}
+
+// withRequestTrace returns a context that records the connection timings of
+// its request. Both transports dial with the context of the request that
+// needed the connection; requests that waited for a dial started by another
+// request reuse that connection.
+func withRequestTrace(ctx context.Context) (context.Context, *requestTrace) {
+	t := &requestTrace{}
+	ctx = context.WithValue(ctx, requestTraceKey{}, t)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.dns = time.Since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsStart = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.tls = time.Since(t.tlsStart)
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.firstByte = time.Now()
+			t.mu.Unlock()
+		},
+	}), t
+}
+
+// recordDial marks the request of ctx as having opened a new connection in d
+func recordDial(ctx context.Context, d time.Duration) {
+	if t, ok := ctx.Value(requestTraceKey{}).(*requestTrace); ok {
+		t.mu.Lock()
+		t.dialed = true
+		t.dial = d
+		t.mu.Unlock()
+	}
+}
+
+// apply copies the timings into result and reports whether the request
+// reused an open connection
+func (t *requestTrace) apply(result *RequestResult) (reused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result.DNSTime = t.dns
+	result.TLSTime = t.tls
+	result.ConnectionTime = t.dial
+	if !t.firstByte.IsZero() {
+		result.FirstByteTime = t.firstByte
+	}
+	return !t.dialed
+}
+
+// recordTimings accounts the connection timings of a request in the averages;
+// requests that did not open a connection or look up a name are left out
+func (cm *ConnectionMetrics) recordTimings(result *RequestResult) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	average := func(avg *float64, n *int64, d time.Duration) {
+		if d <= 0 {
+			return
+		}
+		*n++
+		*avg += (float64(d.Nanoseconds())/1e6 - *avg) / float64(*n)
+	}
+	average(&cm.AvgConnectionTime, &cm.timedConnections, result.ConnectionTime)
+	average(&cm.TLSHandshakeTime, &cm.timedTLSHandshakes, result.TLSTime)
+	average(&cm.DNSLookupTime, &cm.timedDNSLookups, result.DNSTime)
+}
@@ -0,0 +1,51 @@
+package http3
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectionTimings(t *testing.T) {
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	// A host name makes the dialers look it up
+	url = strings.Replace(url, "127.0.0.1", "localhost", 1)
+
+	for _, protocol := range []string{ProtocolHTTP3, ProtocolHTTP2} {
+		tester := NewLoadTester(&LoadTestConfig{
+			TLSConfig:             insecureTLSConfig(),
+			TargetURL:             url,
+			Duration:              10 * time.Second,
+			ConcurrentConnections: 2,
+			RequestsPerConnection: 3,
+			RequestPattern:        "sequential",
+			Timeout:               5 * time.Second,
+			Protocol:              protocol,
+		})
+		if err := tester.Start(context.Background()); err != nil {
+			t.Fatalf("%s: Start failed: %v", protocol, err)
+		}
+		results := tester.GetResults()
+		tester.Close()
+
+		if results.SuccessfulRequests != 6 {
+			t.Fatalf("%s: expected 6 successful requests, got %d (errors: %v)", protocol, results.SuccessfulRequests, results.Errors)
+		}
+		cm := results.ConnectionMetrics
+		if cm.timedConnections != 2 || cm.timedTLSHandshakes != 2 {
+			t.Errorf("%s: expected the timings of 2 connections, got %d connections and %d handshakes",
+				protocol, cm.timedConnections, cm.timedTLSHandshakes)
+		}
+		if cm.DNSLookupTime <= 0 || cm.TLSHandshakeTime <= 0 {
+			t.Errorf("%s: expected positive DNS and TLS times, got %.3f ms and %.3f ms", protocol, cm.DNSLookupTime, cm.TLSHandshakeTime)
+		}
+		if cm.AvgConnectionTime < cm.TLSHandshakeTime {
+			t.Errorf("%s: expected the connection time %.3f ms to include the handshake %.3f ms",
+				protocol, cm.AvgConnectionTime, cm.TLSHandshakeTime)
+		}
+	}
+}
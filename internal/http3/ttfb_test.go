@@ -0,0 +1,48 @@
+package http3
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTTFBExcludesBodyTransfer(t *testing.T) {
+	const bodyDelay = 100 * time.Millisecond
+	url := startDualStackServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(bodyDelay)
+		w.Write([]byte("body"))
+	}))
+
+	for _, protocol := range []string{ProtocolHTTP3, ProtocolHTTP2} {
+		tester := NewLoadTester(&LoadTestConfig{
+			TLSConfig:             insecureTLSConfig(),
+			TargetURL:             url,
+			Duration:              10 * time.Second,
+			ConcurrentConnections: 1,
+			RequestsPerConnection: 5,
+			RequestPattern:        "sequential",
+			Timeout:               5 * time.Second,
+			Protocol:              protocol,
+		})
+		if err := tester.Start(context.Background()); err != nil {
+			t.Fatalf("%s: Start failed: %v", protocol, err)
+		}
+		results := tester.GetResults()
+		tester.Close()
+
+		if results.SuccessfulRequests != 5 {
+			t.Fatalf("%s: expected 5 successful requests, got %d", protocol, results.SuccessfulRequests)
+		}
+		// The body arrives bodyDelay after the headers
+		if results.P50TTFB <= 0 || results.P50TTFB > results.P50ResponseTime-50 {
+			t.Errorf("%s: expected TTFB well below the total time, got p50 TTFB %.2f ms and total %.2f ms",
+				protocol, results.P50TTFB, results.P50ResponseTime)
+		}
+		if results.AvgTTFB > results.AvgResponseTime || results.P99TTFB < results.P50TTFB {
+			t.Errorf("%s: inconsistent TTFB statistics: %+v", protocol, results)
+		}
+	}
+}
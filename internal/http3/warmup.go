@@ -0,0 +1,90 @@
+package http3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Without a warm-up the first request of every connection worker pays for
+// the dial and handshake, so the measured latency mixes setup with
+// steady-state requests. With PreWarm the connections are established by
+// HEAD requests before the measured phase starts, and their handshakes are
+// reported in Warmup instead of ConnectionMetrics. With SharedTransport the
+// workers share one connection to a host, so Connections may be lower than
+// Requests.
+
+// WarmupResults describes the connections established before the measured phase
+type WarmupResults struct {
+	Requests         int64   `json:"requests"` // one per connection worker
+	Failures         int64   `json:"failures"`
+	Connections      int64   `json:"connections"` // handshakes completed while warming
+	AvgHandshakeTime float64 `json:"avg_handshake_time_ms"`
+	MaxHandshakeTime float64 `json:"max_handshake_time_ms"`
+	Duration         float64 `json:"duration_ms"`
+
+	mu sync.Mutex
+}
+
+// warmUp sends one HEAD request per connection worker to the target of its
+// first request, so that the pool holds their connections when the measured
+// phase starts. The status of the responses does not matter.
+func (lt *LoadTester) warmUp(ctx context.Context) {
+	warmup := &WarmupResults{}
+	lt.results.mu.Lock()
+	lt.results.Warmup = warmup
+	lt.results.mu.Unlock()
+
+	start := time.Now()
+	atomic.StoreInt32(&lt.warming, 1)
+	defer atomic.StoreInt32(&lt.warming, 0)
+
+	var wg sync.WaitGroup
+	for connID := 0; connID < lt.config.ConcurrentConnections; connID++ {
+		wg.Add(1)
+		go func(connID int) {
+			defer wg.Done()
+			err := lt.warmRequest(ctx, lt.clientFor(connID), lt.targetURL(connID, 0))
+			warmup.mu.Lock()
+			warmup.Requests++
+			if err != nil {
+				warmup.Failures++
+			}
+			warmup.mu.Unlock()
+		}(connID)
+	}
+	wg.Wait()
+
+	warmup.mu.Lock()
+	warmup.Duration = float64(time.Since(start).Nanoseconds()) / 1e6
+	warmup.mu.Unlock()
+}
+
+// warmRequest establishes the connection of client to target with a HEAD request
+func (lt *LoadTester) warmRequest(ctx context.Context, client *http.Client, target string) error {
+	req, _, err := lt.newRequest(ctx, http.MethodHead, target)
+	if err != nil {
+		return err
+	}
+	req.Body, req.ContentLength = nil, 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// recordHandshake accounts a handshake completed while warming up
+func (w *WarmupResults) recordHandshake(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ms := float64(d.Nanoseconds()) / 1e6
+	w.Connections++
+	w.AvgHandshakeTime += (ms - w.AvgHandshakeTime) / float64(w.Connections)
+	w.MaxHandshakeTime = max(w.MaxHandshakeTime, ms)
+}
@@ -0,0 +1,79 @@
+package http3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestPreWarmEstablishesConnectionsFirst(t *testing.T) {
+	var ln *trackingListener
+	var once sync.Once
+	connsAtFirstRequest := -1
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			once.Do(func() {
+				ln.mu.Lock()
+				connsAtFirstRequest = len(ln.conns)
+				ln.mu.Unlock()
+			})
+		}
+		w.Write([]byte("ok"))
+	})
+
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	quicLn, err := quic.ListenAddrEarly("127.0.0.1:0", http3.ConfigureTLSConfig(tlsServer.TLS.Clone()), nil)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ln = &trackingListener{EarlyListener: quicLn}
+	server := &http3.Server{Handler: handler}
+	go server.ServeListener(ln)
+	defer func() {
+		server.Close()
+		quicLn.Close()
+	}()
+
+	tester := NewLoadTester(&LoadTestConfig{
+		TLSConfig:             insecureTLSConfig(),
+		TargetURL:             fmt.Sprintf("https://%s/", quicLn.Addr()),
+		Duration:              10 * time.Second,
+		ConcurrentConnections: 4,
+		RequestsPerConnection: 5,
+		RequestPattern:        "sequential",
+		Timeout:               5 * time.Second,
+		PreWarm:               true,
+	})
+	defer tester.Close()
+
+	if err := tester.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	results := tester.GetResults()
+
+	if connsAtFirstRequest < 1 {
+		t.Errorf("Expected a connection to exist before the first measured request, got %d", connsAtFirstRequest)
+	}
+	warmup := results.Warmup
+	if warmup == nil || warmup.Requests != 4 || warmup.Failures != 0 {
+		t.Fatalf("Expected 4 successful warm-up requests, got %+v", warmup)
+	}
+	if warmup.Connections == 0 || warmup.AvgHandshakeTime <= 0 || warmup.MaxHandshakeTime < warmup.AvgHandshakeTime {
+		t.Errorf("Expected the warm-up handshakes to be reported, got %+v", warmup)
+	}
+	// The measured phase reuses the warm connections
+	if cm := results.ConnectionMetrics; cm.FullHandshakes != 0 || cm.ResumedHandshakes != 0 {
+		t.Errorf("Expected no handshakes in the measured phase, got %d full, %d resumed", cm.FullHandshakes, cm.ResumedHandshakes)
+	}
+	if results.SuccessfulRequests != 20 {
+		t.Errorf("Expected 20 measured requests, got %d", results.SuccessfulRequests)
+	}
+}
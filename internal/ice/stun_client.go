@@ -0,0 +1,227 @@
+package ice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/pion/stun"
+
+	"quic-test/internal/metrics"
+)
+
+// DefaultSTUNTimeout — сколько ждать ответа на один Binding Request
+const DefaultSTUNTimeout = 3 * time.Second
+
+// BindingResult — итог одного Binding Request
+type BindingResult struct {
+	Server string
+	Local  *net.UDPAddr // адрес сокета клиента (базовый адрес кандидата)
+	Mapped *net.UDPAddr // XOR-MAPPED-ADDRESS: адрес клиента, каким его видит сервер
+	RTT    time.Duration
+}
+
+// Candidate описывает найденный server-reflexive кандидат
+func (r BindingResult) Candidate() string {
+	return fmt.Sprintf("srflx %s (base %s)", r.Mapped, r.Local)
+}
+
+// BehindNAT сообщает, отличается ли адрес, видимый серверу, от адреса сокета
+func (r BindingResult) BehindNAT() bool {
+	return !r.Mapped.IP.Equal(r.Local.IP) || r.Mapped.Port != r.Local.Port
+}
+
+// STUNClient отправляет Binding Request на STUN-сервер (RFC 8489) с одного
+// UDP-сокета, поэтому повторные запросы проверяют и стабильность отображения NAT
+type STUNClient struct {
+	server string
+	conn   *net.UDPConn
+	buf    []byte
+}
+
+// DialSTUN открывает UDP-сокет к STUN-серверу (host:port)
+func DialSTUN(server string) (*STUNClient, error) {
+	addr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STUN server %q: %w", server, err)
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach STUN server %s: %w", server, err)
+	}
+	return &STUNClient{server: server, conn: conn, buf: make([]byte, 1500)}, nil
+}
+
+// Binding отправляет один Binding Request и ждет ответа с тем же
+// transaction ID не дольше timeout (0 — DefaultSTUNTimeout)
+func (c *STUNClient) Binding(ctx context.Context, timeout time.Duration) (BindingResult, error) {
+	if timeout <= 0 {
+		timeout = DefaultSTUNTimeout
+	}
+	request, err := stun.Build(stun.TransactionID, stun.BindingRequest, stun.Fingerprint)
+	if err != nil {
+		return BindingResult{}, fmt.Errorf("failed to build Binding Request: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { c.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	start := time.Now()
+	if _, err := c.conn.Write(request.Raw); err != nil {
+		return BindingResult{}, fmt.Errorf("failed to send Binding Request to %s: %w", c.server, err)
+	}
+	for {
+		n, err := c.conn.Read(c.buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return BindingResult{}, ctx.Err()
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return BindingResult{}, fmt.Errorf("no Binding response from %s within %v", c.server, timeout)
+			}
+			return BindingResult{}, fmt.Errorf("failed to receive Binding response from %s: %w", c.server, err)
+		}
+		rtt := time.Since(start)
+
+		response := &stun.Message{Raw: append([]byte(nil), c.buf[:n]...)}
+		if err := response.Decode(); err != nil || response.TransactionID != request.TransactionID {
+			// Не STUN или ответ на более ранний запрос, ответ еще может прийти
+			continue
+		}
+		return c.bindingResult(response, rtt)
+	}
+}
+
+// bindingResult извлекает отображенный адрес из ответа сервера
+func (c *STUNClient) bindingResult(response *stun.Message, rtt time.Duration) (BindingResult, error) {
+	switch response.Type {
+	case stun.BindingSuccess:
+	case stun.BindingError:
+		var code stun.ErrorCodeAttribute
+		if err := code.GetFrom(response); err == nil {
+			return BindingResult{}, fmt.Errorf("STUN server %s answered error %d: %s", c.server, code.Code, code.Reason)
+		}
+		return BindingResult{}, fmt.Errorf("STUN server %s answered an error", c.server)
+	default:
+		return BindingResult{}, fmt.Errorf("unexpected STUN message %s from %s", response.Type, c.server)
+	}
+
+	result := BindingResult{Server: c.server, Local: c.conn.LocalAddr().(*net.UDPAddr), RTT: rtt}
+	var xorAddr stun.XORMappedAddress
+	if err := xorAddr.GetFrom(response); err == nil {
+		result.Mapped = &net.UDPAddr{IP: xorAddr.IP, Port: xorAddr.Port}
+		return result, nil
+	}
+	// Серверы по RFC 3489 отвечают только MAPPED-ADDRESS
+	var addr stun.MappedAddress
+	if err := addr.GetFrom(response); err != nil {
+		return BindingResult{}, fmt.Errorf("Binding response from %s has no XOR-MAPPED-ADDRESS", c.server)
+	}
+	result.Mapped = &net.UDPAddr{IP: addr.IP, Port: addr.Port}
+	return result, nil
+}
+
+// Close закрывает сокет клиента
+func (c *STUNClient) Close() error {
+	return c.conn.Close()
+}
+
+// STUNCheckConfig настраивает серию проверок STUN-сервера
+type STUNCheckConfig struct {
+	Server   string        // host:port STUN-сервера
+	Count    int           // число Binding Request
+	Interval time.Duration // пауза между запросами
+	Timeout  time.Duration // ожидание одного ответа; 0 — DefaultSTUNTimeout
+}
+
+// STUNCheckResult — итог серии проверок
+type STUNCheckResult struct {
+	Attempts  int
+	Successes int
+	RTTs      []float64 // мс успешных запросов
+	// Mapped — отображенные адреса по порядку, повторы подряд не
+	// записываются; больше одного — NAT менял отображение сокета
+	Mapped    []string
+	Last      BindingResult // последний успешный ответ
+	Errors    map[string]int
+	LastError error
+}
+
+// SuccessRate — доля запросов, получивших ответ
+func (r STUNCheckResult) SuccessRate() float64 {
+	if r.Attempts == 0 {
+		return 0
+	}
+	return float64(r.Successes) / float64(r.Attempts)
+}
+
+// STUNRTTStats — сводка RTT в мс
+type STUNRTTStats struct {
+	metrics.SampleStats
+	P50, P95, P99 float64
+}
+
+// RTT — сводка RTT успешных запросов
+func (r STUNCheckResult) RTT() STUNRTTStats {
+	stats := STUNRTTStats{SampleStats: metrics.Summarize(r.RTTs)}
+	if len(r.RTTs) == 0 {
+		return stats
+	}
+	sorted := append([]float64(nil), r.RTTs...)
+	sort.Float64s(sorted)
+	at := func(q float64) float64 { return sorted[int(q*float64(len(sorted)-1))] }
+	stats.P50, stats.P95, stats.P99 = at(0.50), at(0.95), at(0.99)
+	return stats
+}
+
+// RunSTUNCheck отправляет config.Count запросов Binding с одного сокета и
+// собирает долю ответов, RTT и отображенные адреса. Ошибка возвращается,
+// только если сокет открыть не удалось; отмена ctx завершает серию досрочно
+func RunSTUNCheck(ctx context.Context, config STUNCheckConfig) (STUNCheckResult, error) {
+	if config.Count <= 0 {
+		return STUNCheckResult{}, errors.New("STUN check count must be positive")
+	}
+	client, err := DialSTUN(config.Server)
+	if err != nil {
+		return STUNCheckResult{}, err
+	}
+	defer client.Close()
+
+	result := STUNCheckResult{Errors: make(map[string]int)}
+	for i := 0; i < config.Count && ctx.Err() == nil; i++ {
+		if i > 0 && config.Interval > 0 {
+			select {
+			case <-time.After(config.Interval):
+			case <-ctx.Done():
+				return result, nil
+			}
+		}
+		result.Attempts++
+		binding, err := client.Binding(ctx, config.Timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				result.Attempts--
+				break
+			}
+			result.Errors[err.Error()]++
+			result.LastError = err
+			continue
+		}
+		result.Successes++
+		result.RTTs = append(result.RTTs, float64(binding.RTT.Microseconds())/1000)
+		result.Last = binding
+		if mapped := binding.Mapped.String(); len(result.Mapped) == 0 || result.Mapped[len(result.Mapped)-1] != mapped {
+			result.Mapped = append(result.Mapped, mapped)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package ice
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// serveSTUN отвечает на Binding Request адресом отправителя; answer решает,
+// отвечать ли на очередной запрос
+func serveSTUN(t *testing.T, answer func(n int) bool) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for n := 0; ; n++ {
+			size, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			request := &stun.Message{Raw: append([]byte(nil), buf[:size]...)}
+			if request.Decode() != nil || request.Type != stun.BindingRequest || !answer(n) {
+				continue
+			}
+			addr := from.(*net.UDPAddr)
+			response := stun.MustBuild(request, stun.BindingSuccess,
+				&stun.XORMappedAddress{IP: addr.IP, Port: addr.Port}, stun.Fingerprint)
+			conn.WriteTo(response.Raw, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestSTUNBinding(t *testing.T) {
+	server := serveSTUN(t, func(int) bool { return true })
+	client, err := DialSTUN(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	result, err := client.Binding(context.Background(), time.Second)
+	if err != nil {
+		t.Fatalf("Binding: %v", err)
+	}
+	if result.Mapped.String() != result.Local.String() {
+		t.Errorf("Expected the loopback socket address %s as mapped address, got %s", result.Local, result.Mapped)
+	}
+	if result.BehindNAT() {
+		t.Error("Expected no NAT over loopback")
+	}
+	if result.RTT <= 0 || !strings.HasPrefix(result.Candidate(), "srflx ") {
+		t.Errorf("Unexpected result %+v (%s)", result, result.Candidate())
+	}
+}
+
+func TestRunSTUNCheckCountsTimeouts(t *testing.T) {
+	// Каждый третий запрос остается без ответа
+	server := serveSTUN(t, func(n int) bool { return n%3 != 2 })
+	result, err := RunSTUNCheck(context.Background(), STUNCheckConfig{
+		Server:  server,
+		Count:   6,
+		Timeout: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Attempts != 6 || result.Successes != 4 {
+		t.Fatalf("Expected 4 of 6 answered, got %d of %d (%v)", result.Successes, result.Attempts, result.Errors)
+	}
+	if rate := result.SuccessRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("Expected a success rate of 2/3, got %.3f", rate)
+	}
+	if len(result.RTTs) != 4 || result.RTT().Count != 4 {
+		t.Errorf("Expected 4 RTT samples, got %v", result.RTTs)
+	}
+	if len(result.Mapped) != 1 {
+		t.Errorf("Expected a stable mapping, got %v", result.Mapped)
+	}
+	if result.LastError == nil || len(result.Errors) != 1 {
+		t.Errorf("Expected the timeouts to be recorded, got %v", result.Errors)
+	}
+}
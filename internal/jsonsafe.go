@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+
+	"quic-test/internal/metrics"
+)
+
+// JSONSafeValue возвращает значение, которое JavaScript прочитает без потерь:
+// целые за пределами безопасного диапазона заменяются десятичной строкой,
+// карты и срезы обходятся рекурсивно. Счетчики байт в картах (см.
+// isByteCountKey) при metrics.SetByteCountsAsStrings(true) становятся
+// строками всегда, как metrics.ByteCount. Нужна для map[string]interface{},
+// которые отдаются в API как есть.
+func JSONSafeValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return safeInt64(int64(x), v)
+	case int64:
+		return safeInt64(x, v)
+	case uint64:
+		if x > metrics.MaxSafeJSONInteger {
+			return strconv.FormatUint(x, 10)
+		}
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for key, value := range x {
+			if isByteCountKey(key) {
+				out[key] = byteCountValue(value)
+				continue
+			}
+			out[key] = JSONSafeValue(value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, value := range x {
+			out[i] = JSONSafeValue(value)
+		}
+		return out
+	}
+	return v
+}
+
+// isByteCountKey сообщает, что ключ карты метрик — счетчик байт: bytes,
+// bytes_* и *_bytes, как в API GUI, или Bytes* в метриках клиента
+func isByteCountKey(key string) bool {
+	return key == "bytes" || strings.HasPrefix(key, "bytes_") ||
+		strings.HasSuffix(key, "_bytes") || strings.HasPrefix(key, "Bytes")
+}
+
+// byteCountValue записывает целый счетчик байт строкой, если так велит
+// --json-byte-strings, и как JSONSafeValue иначе
+func byteCountValue(v interface{}) interface{} {
+	if !metrics.ByteCountsAsStrings() {
+		return JSONSafeValue(v)
+	}
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case metrics.ByteCount:
+		return strconv.FormatInt(int64(x), 10)
+	}
+	return JSONSafeValue(v)
+}
+
+func safeInt64(x int64, v interface{}) interface{} {
+	if metrics.IsSafeJSONInteger(x) {
+		return v
+	}
+	return strconv.FormatInt(x, 10)
+}
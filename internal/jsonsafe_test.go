@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"quic-test/internal/metrics"
+)
+
+func TestReportByteCountsRoundTrip(t *testing.T) {
+	const large = int64(1<<53 + 7)
+	cfg := TestConfig{Mode: "client", Addr: "127.0.0.1:9000", Connections: 1, Streams: 1, Duration: time.Second}
+	report := CreateReportSchema(cfg, map[string]interface{}{
+		"BytesSent":     large,
+		"BytesReceived": int64(1500),
+	})
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded ReportSchema
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Metrics.BytesSent != metrics.ByteCount(large) {
+		t.Errorf("Expected bytes_sent %d, got %d", large, decoded.Metrics.BytesSent)
+	}
+	if decoded.Metrics.BytesReceived != 1500 {
+		t.Errorf("Expected bytes_received 1500, got %d", decoded.Metrics.BytesReceived)
+	}
+
+	// Так отчет читает JavaScript: большое значение — строка, малое — число
+	var generic struct {
+		Metrics map[string]interface{} `json:"metrics"`
+	}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := generic.Metrics["bytes_sent"].(string); !ok || v != "9007199254740999" {
+		t.Errorf("Expected bytes_sent as the string \"9007199254740999\", got %#v", generic.Metrics["bytes_sent"])
+	}
+	if v, ok := generic.Metrics["bytes_received"].(float64); !ok || v != 1500 {
+		t.Errorf("Expected bytes_received as the number 1500, got %#v", generic.Metrics["bytes_received"])
+	}
+}
+
+func TestJSONSafeValue(t *testing.T) {
+	large := int64(1<<53 + 1)
+	safe := JSONSafeValue(map[string]interface{}{
+		"bytes_received": large,
+		"connections":    2,
+		"latency_ms":     12.5,
+		"targets":        []interface{}{map[string]interface{}{"bytes": uint64(large)}},
+	}).(map[string]interface{})
+
+	if safe["bytes_received"] != "9007199254740993" {
+		t.Errorf("Expected the large counter as a string, got %#v", safe["bytes_received"])
+	}
+	if safe["connections"] != 2 || safe["latency_ms"] != 12.5 {
+		t.Errorf("Expected small values to stay as they are, got %v", safe)
+	}
+	nested := safe["targets"].([]interface{})[0].(map[string]interface{})
+	if nested["bytes"] != "9007199254740993" {
+		t.Errorf("Expected nested counters to be converted, got %#v", nested["bytes"])
+	}
+}
+
+func TestJSONSafeValueByteCountsAsStrings(t *testing.T) {
+	metrics.SetByteCountsAsStrings(true)
+	t.Cleanup(func() { metrics.SetByteCountsAsStrings(false) })
+
+	safe := JSONSafeValue(map[string]interface{}{
+		"bytes_sent":    int64(1500),
+		"BytesReceived": 42,
+		"buffer_bytes":  uint64(7),
+		"packets_sent":  int64(10),
+		"targets":       []interface{}{map[string]interface{}{"bytes": 3}},
+	}).(map[string]interface{})
+
+	for key, want := range map[string]string{"bytes_sent": "1500", "BytesReceived": "42", "buffer_bytes": "7"} {
+		if safe[key] != want {
+			t.Errorf("Expected %s as the string %q, got %#v", key, want, safe[key])
+		}
+	}
+	if safe["packets_sent"] != int64(10) {
+		t.Errorf("Expected other counters to stay numbers, got %#v", safe["packets_sent"])
+	}
+	nested := safe["targets"].([]interface{})[0].(map[string]interface{})
+	if nested["bytes"] != "3" {
+		t.Errorf("Expected nested byte counts as strings, got %#v", nested["bytes"])
+	}
+}
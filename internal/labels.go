@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения меток --label. Метки добавляются к каждой серии Prometheus,
+// поэтому их число и длина значений ограничены.
+const (
+	MaxRunLabels           = 10
+	MaxRunLabelValueLength = 128
+)
+
+// labelNamePattern — допустимое имя метки Prometheus
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// RunLabels — метки прогона key=value, которые отличают его метрики и отчет
+// от других экземпляров и нагрузок (--label, повторяемый)
+type RunLabels map[string]string
+
+func (l *RunLabels) String() string {
+	if l == nil {
+		return ""
+	}
+	return FormatRunLabels(*l)
+}
+
+// Set разбирает одну метку key=value и сразу отвергает ошибочную
+func (l *RunLabels) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("label %q: expected key=value", value)
+	}
+	if _, exists := (*l)[name]; exists {
+		return fmt.Errorf("label %q: set more than once", name)
+	}
+	labels := RunLabels{name: val}
+	for k, v := range *l {
+		labels[k] = v
+	}
+	if err := ValidateRunLabels(labels); err != nil {
+		return err
+	}
+	*l = labels
+	return nil
+}
+
+// ValidateRunLabels проверяет имена и значения меток и их число
+func ValidateRunLabels(labels map[string]string) error {
+	if len(labels) > MaxRunLabels {
+		return fmt.Errorf("at most %d labels allowed, got %d", MaxRunLabels, len(labels))
+	}
+	for _, name := range sortedLabelNames(labels) {
+		value := labels[name]
+		switch {
+		case !labelNamePattern.MatchString(name):
+			return fmt.Errorf("label name %q: must match [a-zA-Z_][a-zA-Z0-9_]*", name)
+		case strings.HasPrefix(name, "__"):
+			return fmt.Errorf("label name %q: names starting with __ are reserved", name)
+		case value == "":
+			return fmt.Errorf("label %q: value must not be empty", name)
+		case len(value) > MaxRunLabelValueLength:
+			return fmt.Errorf("label %q: value longer than %d bytes", name, MaxRunLabelValueLength)
+		case !utf8.ValidString(value):
+			return fmt.Errorf("label %q: value must be valid UTF-8", name)
+		}
+	}
+	return nil
+}
+
+// FormatRunLabels возвращает метки как key=value через запятую в порядке имен
+func FormatRunLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, name := range sortedLabelNames(labels) {
+		pairs = append(pairs, name+"="+labels[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func sortedLabelNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLabelsFlag(t *testing.T) {
+	var labels RunLabels
+	for _, value := range []string{"workload=checkout", "region=eu-west-1"} {
+		if err := labels.Set(value); err != nil {
+			t.Fatalf("%s: %v", value, err)
+		}
+	}
+	if got := labels.String(); got != "region=eu-west-1,workload=checkout" {
+		t.Errorf("Expected labels sorted by name, got %q", got)
+	}
+
+	for _, value := range []string{
+		"workload=again", // повтор
+		"noequals",       // без значения
+		"empty=",         // пустое значение
+		"1abc=x",         // недопустимое имя
+		"bad-name=x",     // недопустимое имя
+		"__name__=x",     // зарезервированное имя
+		"long=" + strings.Repeat("v", MaxRunLabelValueLength+1),
+	} {
+		if err := labels.Set(value); err == nil {
+			t.Errorf("%.40s: expected an error", value)
+		}
+	}
+	if len(labels) != 2 {
+		t.Errorf("Expected rejected labels not to be added, got %v", labels)
+	}
+}
+
+func TestRunLabelsCardinalityBound(t *testing.T) {
+	var labels RunLabels
+	for i := 0; i < MaxRunLabels; i++ {
+		if err := labels.Set(fmt.Sprintf("l%d=v", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := labels.Set("one_more=v"); err == nil {
+		t.Errorf("Expected more than %d labels to be rejected", MaxRunLabels)
+	}
+
+	cfg := TestConfig{Connections: 1, Streams: 1, PacketSize: 1200, Rate: 1, Labels: RunLabels{"bad name": "x"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected Validate to reject an invalid label")
+	}
+}
+
+func TestRunLabelsInReportAndPromFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := TestConfig{
+		Mode:              "client",
+		CongestionControl: "bbrv3",
+		ReportPath:        filepath.Join(dir, "report.json"),
+		ReportFormat:      "json",
+		Labels:            RunLabels{"workload": `say "hi"`, "cc": "ignored"},
+	}
+	metrics := map[string]interface{}{"Success": 1, "BytesSent": 1200}
+	if err := SaveReport(cfg, metrics); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cfg.ReportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report ReportSchema
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	labels, _ := report.Metadata["labels"].(map[string]interface{})
+	if labels["workload"] != `say "hi"` {
+		t.Errorf("Expected the labels in the report metadata, got %v", report.Metadata["labels"])
+	}
+
+	promFile := filepath.Join(dir, "report.prom")
+	if err := ExportPrometheusMetrics(cfg, metrics, promFile); err != nil {
+		t.Fatal(err)
+	}
+	prom, err := os.ReadFile(promFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `quic_test_bytes_sent_total{cc="bbrv3",workload="say \"hi\""} 1200`
+	if !strings.Contains(string(prom), want) {
+		t.Errorf("Expected %s in:\n%s", want, prom)
+	}
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lookupHost разрешает имя хоста; в тестах подменяется, чтобы localhost
+// разрешался только в одно семейство адресов
+var lookupHost = net.DefaultResolver.LookupHost
+
+// loopbackHosts — адреса, по которым пробуется localhost, если резолвер их не вернул
+var loopbackHosts = []string{"::1", "127.0.0.1"}
+
+// DialLocal подключается как net.Dialer, но для localhost пробует оба
+// loopback-адреса, а не только те, что вернул резолвер. На системах, где
+// localhost разрешается только в ::1 (или только в 127.0.0.1), клиент иначе
+// не находит сервер, слушающий loopback другого семейства.
+func DialLocal(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || !strings.EqualFold(host, "localhost") {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	candidates, _ := lookupHost(ctx, host)
+	for _, loopback := range loopbackHosts {
+		if !containsHost(candidates, loopback) {
+			candidates = append(candidates, loopback)
+		}
+	}
+	var firstErr error
+	for _, candidate := range candidates {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(candidate, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+func containsHost(hosts []string, host string) bool {
+	ip := net.ParseIP(host)
+	for _, h := range hosts {
+		if h == host || (ip != nil && ip.Equal(net.ParseIP(h))) {
+			return true
+		}
+	}
+	return false
+}
+
+// localTransport — общий транспорт клиентов LocalHTTPClient, чтобы они
+// переиспользовали соединения, а не открывали новые на каждый вызов
+var localTransport = sync.OnceValue(func() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = DialLocal
+	return transport
+})
+
+// localClients кэширует клиентов LocalHTTPClient по таймауту
+var localClients sync.Map // time.Duration -> *http.Client
+
+// LocalHTTPClient возвращает HTTP-клиент, подключающийся через DialLocal.
+// Клиенты с одним таймаутом общие, поэтому менять их нельзя.
+func LocalHTTPClient(timeout time.Duration) *http.Client {
+	if client, ok := localClients.Load(timeout); ok {
+		return client.(*http.Client)
+	}
+	client, _ := localClients.LoadOrStore(timeout, &http.Client{Timeout: timeout, Transport: localTransport()})
+	return client.(*http.Client)
+}
+
+// LocalURL возвращает http-URL сервера на этой машине, слушающего listenAddr.
+// Пустой или неопределенный хост (":8081", "0.0.0.0:8081", "[::]:8081")
+// заменяется на localhost, который DialLocal пробует в обоих семействах;
+// IPv6-адрес заключается в квадратные скобки.
+func LocalURL(listenAddr string) string {
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return "http://" + listenAddr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
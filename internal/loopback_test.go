@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// resolveLocalhostTo подменяет резолвер так, что localhost разрешается только в addrs
+func resolveLocalhostTo(t *testing.T, addrs ...string) {
+	t.Helper()
+	orig := lookupHost
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		if host == "localhost" {
+			return addrs, nil
+		}
+		return orig(ctx, host)
+	}
+	t.Cleanup(func() { lookupHost = orig })
+}
+
+// startLoopbackServer запускает HTTP-сервер на loopback-адресе listenHost и
+// возвращает его адрес в виде localhost:port
+func startLoopbackServer(t *testing.T, listenHost string, handler http.Handler) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", net.JoinHostPort(listenHost, "0"))
+	if err != nil {
+		t.Skipf("loopback %s unavailable: %v", listenHost, err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return net.JoinHostPort("localhost", strconv.Itoa(listener.Addr().(*net.TCPAddr).Port))
+}
+
+func TestLocalHTTPClientCrossFamily(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tc := range []struct {
+		name     string
+		resolves string
+		listen   string
+	}{
+		// localhost разрешается в ::1, а сервер слушает только IPv4
+		{"ipv6 localhost, ipv4 server", "::1", "127.0.0.1"},
+		// localhost разрешается в 127.0.0.1, а сервер слушает только IPv6
+		{"ipv4 localhost, ipv6 server", "127.0.0.1", "::1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolveLocalhostTo(t, tc.resolves)
+			addr := startLoopbackServer(t, tc.listen, ok)
+
+			resp, err := LocalHTTPClient(2 * time.Second).Get("http://" + addr + "/health")
+			if err != nil {
+				t.Fatalf("Expected to reach %s listening on %s, got %v", addr, tc.listen, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestLocalHTTPClientShared(t *testing.T) {
+	if LocalHTTPClient(2*time.Second) != LocalHTTPClient(2*time.Second) {
+		t.Error("Expected one client per timeout")
+	}
+	short, long := LocalHTTPClient(time.Second), LocalHTTPClient(time.Minute)
+	if short.Timeout != time.Second || long.Timeout != time.Minute {
+		t.Errorf("Expected the requested timeouts, got %v and %v", short.Timeout, long.Timeout)
+	}
+	if short.Transport != long.Transport {
+		t.Error("Expected clients to share one transport")
+	}
+}
+
+func TestBottomBridgeIPv6Localhost(t *testing.T) {
+	resolveLocalhostTo(t, "::1")
+	addr := startLoopbackServer(t, "127.0.0.1", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	bridge := NewBottomBridge("http://"+addr, time.Second)
+	if err := bridge.CheckHealth(); err != nil {
+		t.Errorf("Expected the bridge to reach QUIC Bottom on %s, got %v", addr, err)
+	}
+}
+
+func TestLocalURL(t *testing.T) {
+	tests := []struct {
+		listen string
+		want   string
+	}{
+		{":8081", "http://localhost:8081"},
+		{"0.0.0.0:8081", "http://localhost:8081"},
+		{"[::]:8081", "http://localhost:8081"},
+		{"[::1]:8081", "http://[::1]:8081"},
+		{"127.0.0.1:8081", "http://127.0.0.1:8081"},
+		{"localhost:8081", "http://localhost:8081"},
+	}
+	for _, tt := range tests {
+		if got := LocalURL(tt.listen); got != tt.want {
+			t.Errorf("LocalURL(%q) = %q, want %q", tt.listen, got, tt.want)
+		}
+	}
+}
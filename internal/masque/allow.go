@@ -0,0 +1,126 @@
+package masque
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// AllowPublic — правило --masque-allow, пускающее к любому публичному
+// адресу: не loopback, не частному, не link-local, не multicast
+const AllowPublic = "public"
+
+// TargetRule — цели, к которым прокси открывает туннели: публичные адреса
+// (Public) или сеть Prefix; Port, если не 0, ограничивает правило одним портом
+type TargetRule struct {
+	Public bool
+	Prefix netip.Prefix
+	Port   uint16
+}
+
+// DefaultTargetRules — правила без --masque-allow: только публичные адреса,
+// чтобы прокси не открывал клиентам loopback и внутреннюю сеть своей машины
+var DefaultTargetRules = []TargetRule{{Public: true}}
+
+// ParseTargetRule разбирает правило --masque-allow: "public", адрес или
+// сеть CIDR, с портом или без: "10.0.0.0/8", "192.0.2.1:9001",
+// "127.0.0.0/8:9001", "[::1]:9001", "[fd00::/8]:9001"
+func ParseTargetRule(s string) (TargetRule, error) {
+	s = strings.TrimSpace(s)
+	if s == AllowPublic {
+		return TargetRule{Public: true}, nil
+	}
+	network, port := s, ""
+	if host, p, err := net.SplitHostPort(s); err == nil {
+		network, port = host, p
+	}
+
+	var rule TargetRule
+	if port != "" {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || p == 0 {
+			return TargetRule{}, fmt.Errorf("invalid port in target rule %q", s)
+		}
+		rule.Port = uint16(p)
+	}
+	if strings.Contains(network, "/") {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return TargetRule{}, fmt.Errorf("invalid network in target rule %q: %w", s, err)
+		}
+		rule.Prefix = prefix.Masked()
+		return rule, nil
+	}
+	addr, err := netip.ParseAddr(network)
+	if err != nil {
+		return TargetRule{}, fmt.Errorf("invalid address in target rule %q: %w", s, err)
+	}
+	rule.Prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+	return rule, nil
+}
+
+// ParseTargetRules разбирает список правил --masque-allow через запятую;
+// пустой список дает DefaultTargetRules
+func ParseTargetRules(list string) ([]TargetRule, error) {
+	var rules []TargetRule
+	for _, s := range strings.Split(list, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		rule, err := ParseTargetRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return DefaultTargetRules, nil
+	}
+	return rules, nil
+}
+
+// Allows сообщает, что правило пускает к target
+func (r TargetRule) Allows(target netip.AddrPort) bool {
+	if r.Port != 0 && r.Port != target.Port() {
+		return false
+	}
+	addr := target.Addr().Unmap()
+	if r.Public {
+		return isPublicAddr(addr)
+	}
+	return r.Prefix.Contains(addr)
+}
+
+func (r TargetRule) String() string {
+	s := AllowPublic
+	if !r.Public {
+		s = r.Prefix.String()
+		if r.Prefix.IsSingleIP() {
+			s = r.Prefix.Addr().String()
+		}
+	}
+	if r.Port == 0 {
+		return s
+	}
+	if r.Prefix.IsSingleIP() && r.Prefix.Addr().Is6() {
+		return "[" + s + "]:" + strconv.Itoa(int(r.Port))
+	}
+	return s + ":" + strconv.Itoa(int(r.Port))
+}
+
+// isPublicAddr сообщает, что адрес глобальный и не из частных сетей
+func isPublicAddr(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// allowedTarget сообщает, что хотя бы одно из правил пускает к target
+func allowedTarget(rules []TargetRule, target netip.AddrPort) bool {
+	for _, rule := range rules {
+		if rule.Allows(target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package masque
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// Константы CONNECT-UDP (RFC 9298) поверх HTTP/3 с HTTP Datagrams (RFC 9297)
+const (
+	ProtocolConnectUDP = "connect-udp" // :protocol расширенного CONNECT
+
+	// ConnectUDPPathPrefix — начало пути шаблона URI по умолчанию
+	// /.well-known/masque/udp/{target_host}/{target_port}/
+	ConnectUDPPathPrefix = "/.well-known/masque/udp/"
+
+	// MaxTunnelPayload — UDP-нагрузка, которая помещается в одну HTTP-датаграмму
+	// с учетом заголовков QUIC DATAGRAM и идентификаторов потока и контекста
+	MaxTunnelPayload = 1180
+
+	settingsEnableConnectProtocol = 0x08 // SETTINGS_ENABLE_CONNECT_PROTOCOL (RFC 9220)
+	contextIDUDPPayload           = 0    // Context ID датаграмм с UDP-нагрузкой
+	capsuleProtocolHeader         = "Capsule-Protocol"
+)
+
+var errShortDatagram = errors.New("masque: datagram too short")
+
+// ConnectUDPPath возвращает путь запроса CONNECT-UDP к target (host:port)
+func ConnectUDPPath(target string) (string, error) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	if host == "" {
+		return "", fmt.Errorf("invalid target %q: empty host", target)
+	}
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return "", fmt.Errorf("invalid target %q: bad port", target)
+	}
+	// Двоеточия IPv6 кодируются, как требует RFC 9298
+	return ConnectUDPPathPrefix + strings.ReplaceAll(url.PathEscape(host), ":", "%3A") + "/" + port + "/", nil
+}
+
+// parseConnectUDPPath извлекает host:port цели из экранированного пути запроса
+func parseConnectUDPPath(escapedPath string) (string, error) {
+	rest, ok := strings.CutPrefix(escapedPath, ConnectUDPPathPrefix)
+	if !ok {
+		return "", fmt.Errorf("path %q does not match %s{host}/{port}/", escapedPath, ConnectUDPPathPrefix)
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", fmt.Errorf("path %q does not match %s{host}/{port}/", escapedPath, ConnectUDPPathPrefix)
+	}
+	host, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid target host: %w", err)
+	}
+	port, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil || port == 0 {
+		return "", fmt.Errorf("invalid target port %q", parts[1])
+	}
+	return net.JoinHostPort(host, parts[1]), nil
+}
+
+// appendUDPDatagram собирает HTTP-датаграмму: quarter stream ID потока
+// CONNECT, Context ID 0 и UDP-нагрузку
+func appendUDPDatagram(b []byte, streamID uint64, payload []byte) []byte {
+	b = quicvarint.Append(b, streamID/4)
+	b = quicvarint.Append(b, contextIDUDPPayload)
+	return append(b, payload...)
+}
+
+// parseUDPDatagram разбирает HTTP-датаграмму на ID потока CONNECT и
+// UDP-нагрузку; ok=false для датаграмм с другим Context ID, которые
+// по RFC 9298 отбрасываются
+func parseUDPDatagram(b []byte) (streamID uint64, payload []byte, ok bool, err error) {
+	r := bytes.NewReader(b)
+	quarterID, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, nil, false, errShortDatagram
+	}
+	contextID, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, nil, false, errShortDatagram
+	}
+	return quarterID * 4, b[len(b)-r.Len():], contextID == contextIDUDPPayload, nil
+}
@@ -0,0 +1,24 @@
+package masque
+
+import "testing"
+
+func TestConnectUDPPath(t *testing.T) {
+	for _, target := range []string{"192.0.2.1:443", "[2001:db8::1]:53", "example.com:4433"} {
+		path, err := ConnectUDPPath(target)
+		if err != nil {
+			t.Fatalf("ConnectUDPPath(%q): %v", target, err)
+		}
+		got, err := parseConnectUDPPath(path)
+		if err != nil || got != target {
+			t.Errorf("Expected %q to round-trip through %q, got %q (%v)", target, path, got, err)
+		}
+	}
+	if path, _ := ConnectUDPPath("[2001:db8::1]:53"); path != "/.well-known/masque/udp/2001%3Adb8%3A%3A1/53/" {
+		t.Errorf("Expected the colons of an IPv6 target to be escaped, got %q", path)
+	}
+	for _, target := range []string{"example.com", ":53", "example.com:0", "example.com:70000"} {
+		if _, err := ConnectUDPPath(target); err == nil {
+			t.Errorf("Expected an error for target %q", target)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package masque
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"quic-test/internal/metrics"
+)
+
+// echoHeaderSize — номер и время отправки в начале каждой датаграммы теста
+const echoHeaderSize = 16
+
+// EchoTestConfig настраивает замер через UDP-цель, возвращающую датаграммы
+type EchoTestConfig struct {
+	PacketSize int           // размер UDP-нагрузки, не меньше 16 байт
+	Rate       int           // датаграмм в секунду
+	Duration   time.Duration // 0 — до отмены ctx
+	// Wait — сколько ждать ответов на последние датаграммы после отправки
+	Wait time.Duration
+}
+
+// EchoTestResult — итог замера
+type EchoTestResult struct {
+	Sent          int64
+	Received      int64
+	BytesSent     int64
+	BytesReceived int64
+	SendErrors    int64
+	RTTs          []float64 // мс
+	Elapsed       time.Duration
+	LastError     error // последняя ошибка отправки
+}
+
+// LossRatio — доля датаграмм без ответа
+func (r EchoTestResult) LossRatio() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Sent-r.Received) / float64(r.Sent)
+}
+
+// RTTStats — сводка RTT ответов в мс
+type RTTStats struct {
+	metrics.SampleStats
+	P50, P95, P99 float64
+}
+
+// RTT — сводка RTT ответов
+func (r EchoTestResult) RTT() RTTStats {
+	stats := RTTStats{SampleStats: metrics.Summarize(r.RTTs)}
+	if len(r.RTTs) == 0 {
+		return stats
+	}
+	sorted := append([]float64(nil), r.RTTs...)
+	sort.Float64s(sorted)
+	at := func(q float64) float64 { return sorted[int(q*float64(len(sorted)-1))] }
+	stats.P50, stats.P95, stats.P99 = at(0.50), at(0.95), at(0.99)
+	return stats
+}
+
+// RunEchoTest отправляет по conn датаграммы с номером и временем отправки
+// с заданной частотой и считает RTT по вернувшимся. Каждая датаграмма
+// учитывается один раз, дубликаты ответов игнорируются.
+func RunEchoTest(ctx context.Context, conn DatagramConn, config EchoTestConfig) (EchoTestResult, error) {
+	if config.PacketSize < echoHeaderSize {
+		return EchoTestResult{}, fmt.Errorf("packet size must be at least %d bytes", echoHeaderSize)
+	}
+	if config.Rate <= 0 {
+		return EchoTestResult{}, errors.New("rate must be positive")
+	}
+
+	var (
+		mu     sync.Mutex
+		result EchoTestResult
+		acked  = make(map[uint64]bool)
+	)
+	recvCtx, stopRecv := context.WithCancel(context.Background())
+	recvDone := make(chan struct{})
+	go func() {
+		defer close(recvDone)
+		for {
+			payload, err := conn.Receive(recvCtx)
+			if err != nil {
+				return
+			}
+			if len(payload) < echoHeaderSize {
+				continue
+			}
+			seq := binary.BigEndian.Uint64(payload)
+			sent := time.Unix(0, int64(binary.BigEndian.Uint64(payload[8:])))
+			mu.Lock()
+			if seq < uint64(result.Sent) && !acked[seq] {
+				acked[seq] = true
+				result.Received++
+				result.BytesReceived += int64(len(payload))
+				result.RTTs = append(result.RTTs, float64(time.Since(sent).Microseconds())/1000)
+			}
+			mu.Unlock()
+		}
+	}()
+
+	sendCtx := ctx
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		sendCtx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+	payload := make([]byte, config.PacketSize)
+	ticker := time.NewTicker(time.Second / time.Duration(config.Rate))
+	defer ticker.Stop()
+	start := time.Now()
+	for seq := uint64(0); sendCtx.Err() == nil; seq++ {
+		binary.BigEndian.PutUint64(payload, seq)
+		binary.BigEndian.PutUint64(payload[8:], uint64(time.Now().UnixNano()))
+		mu.Lock()
+		result.Sent++
+		mu.Unlock()
+		err := conn.Send(payload)
+		mu.Lock()
+		if err != nil {
+			result.SendErrors++
+			result.LastError = err
+		} else {
+			result.BytesSent += int64(len(payload))
+		}
+		mu.Unlock()
+
+		select {
+		case <-sendCtx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	// Ответы на последние датаграммы еще в пути
+	if config.Wait > 0 {
+		timer := time.NewTimer(config.Wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	stopRecv()
+	<-recvDone
+
+	mu.Lock()
+	defer mu.Unlock()
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+// udpConn — прямой UDP-сокет к цели, база для сравнения с туннелем
+type udpConn struct {
+	conn *net.UDPConn
+	buf  []byte
+}
+
+// DialUDP открывает прямой UDP-сокет к target (host:port)
+func DialUDP(target string) (DatagramConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", target, err)
+	}
+	return &udpConn{conn: conn, buf: make([]byte, 64*1024)}, nil
+}
+
+func (c *udpConn) Send(payload []byte) error {
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// Receive читает следующую датаграмму; отмена ctx прерывает ожидание.
+// Буфер результата переиспользуется следующим вызовом.
+func (c *udpConn) Receive(ctx context.Context) ([]byte, error) {
+	stop := context.AfterFunc(ctx, func() { c.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+	for {
+		n, err := c.conn.Read(c.buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				continue
+			}
+			return nil, err
+		}
+		return c.buf[:n], nil
+	}
+}
+
+func (c *udpConn) Close() error {
+	return c.conn.Close()
+}
+
+// ServeUDPEcho возвращает каждую датаграмму отправителю до отмены ctx;
+// служит целью для замеров через прокси
+func ServeUDPEcho(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	buf := make([]byte, 64*1024)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("UDP echo failed: %w", err)
+		}
+		conn.WriteTo(buf[:n], addr)
+	}
+}
@@ -0,0 +1,325 @@
+package masque
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// ProxyConfig настраивает прокси CONNECT-UDP
+type ProxyConfig struct {
+	Addr       string
+	TLSConfig  *tls.Config // ALPN h3 добавляет прокси
+	QUICConfig *quic.Config
+	// Allow — цели, к которым разрешены туннели; пустой — DefaultTargetRules.
+	// Цель проверяется после разрешения имени, по адресу, к которому
+	// прокси действительно подключается.
+	Allow []TargetRule
+}
+
+// ProxyStats — счетчики прокси; "to target" — датаграммы клиентов,
+// отправленные цели, "from target" — ответы цели, отправленные клиентам
+type ProxyStats struct {
+	Sessions            int64 // принятые запросы CONNECT-UDP
+	ActiveSessions      int64
+	RejectedSessions    int64 // некорректные запросы, недоступные и запрещенные цели
+	DeniedSessions      int64 // цели вне Allow, часть RejectedSessions
+	DatagramsToTarget   int64
+	DatagramsFromTarget int64
+	BytesToTarget       int64 // UDP-нагрузка без заголовков HTTP-датаграмм
+	BytesFromTarget     int64
+	DroppedDatagrams    int64 // без сессии, с чужим Context ID или не отправленные
+}
+
+func (st ProxyStats) String() string {
+	return fmt.Sprintf("%d sessions (%d rejected, %d denied), to target: %d datagrams / %d bytes, from target: %d datagrams / %d bytes, dropped: %d",
+		st.Sessions, st.RejectedSessions, st.DeniedSessions, st.DatagramsToTarget, st.BytesToTarget,
+		st.DatagramsFromTarget, st.BytesFromTarget, st.DroppedDatagrams)
+}
+
+// Proxy — прокси CONNECT-UDP (RFC 9298) поверх HTTP/3. Каждый запрос
+// открывает UDP-сокет к цели из пути запроса; датаграммы клиента
+// пересылаются цели, ответы цели — обратно в HTTP-датаграммах. Цели вне
+// ProxyConfig.Allow отклоняются с 403.
+type Proxy struct {
+	conn   net.PacketConn
+	server *http3.Server
+	allow  []TargetRule
+
+	mu sync.Mutex
+	// Сессии по ID потока CONNECT для каждого QUIC-соединения; на
+	// соединение работает один цикл приема датаграмм
+	conns map[quic.Connection]map[uint64]*proxySession
+	stats ProxyStats
+}
+
+// proxySession — один туннель к цели
+type proxySession struct {
+	streamID uint64
+	target   *net.UDPConn
+}
+
+// ListenProxy привязывает UDP-сокет прокси; запросы обслуживает Serve
+func ListenProxy(config ProxyConfig) (*Proxy, error) {
+	if config.TLSConfig == nil {
+		return nil, errors.New("MASQUE proxy requires a TLS config")
+	}
+	addr, err := net.ResolveUDPAddr("udp", config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", config.Addr, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", config.Addr, err)
+	}
+	allow := config.Allow
+	if len(allow) == 0 {
+		allow = DefaultTargetRules
+	}
+	p := &Proxy{
+		conn:  conn,
+		allow: allow,
+		conns: make(map[quic.Connection]map[uint64]*proxySession),
+	}
+	p.server = &http3.Server{
+		Handler:         p,
+		TLSConfig:       http3.ConfigureTLSConfig(config.TLSConfig.Clone()),
+		QuicConfig:      config.QUICConfig,
+		EnableDatagrams: true,
+		AdditionalSettings: map[uint64]uint64{
+			settingsEnableConnectProtocol: 1,
+		},
+	}
+	return p, nil
+}
+
+// Addr возвращает адрес UDP-сокета прокси
+func (p *Proxy) Addr() net.Addr {
+	return p.conn.LocalAddr()
+}
+
+// Serve обслуживает запросы до отмены ctx. После отмены возвращает nil,
+// иначе — ошибку сокета.
+func (p *Proxy) Serve(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() { done <- p.server.Serve(p.conn) }()
+	select {
+	case <-ctx.Done():
+		p.server.Close()
+		p.conn.Close()
+		<-done
+		return nil
+	case err := <-done:
+		p.conn.Close()
+		return fmt.Errorf("MASQUE proxy failed: %w", err)
+	}
+}
+
+// Stats возвращает копию счетчиков
+func (p *Proxy) Stats() ProxyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect || r.Proto != ProtocolConnectUDP {
+		p.reject(w, http.StatusBadRequest, "expected an extended CONNECT with :protocol connect-udp")
+		return
+	}
+	target, err := parseConnectUDPPath(r.URL.EscapedPath())
+	if err != nil {
+		p.reject(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	streamer, ok := r.Body.(http3.HTTPStreamer)
+	if !ok {
+		p.reject(w, http.StatusBadRequest, "CONNECT-UDP requires HTTP/3")
+		return
+	}
+	hijacker, ok := w.(http3.Hijacker)
+	if !ok {
+		p.reject(w, http.StatusBadRequest, "CONNECT-UDP requires HTTP/3")
+		return
+	}
+	conn, ok := hijacker.StreamCreator().(quic.Connection)
+	if !ok || !conn.ConnectionState().SupportsDatagrams {
+		p.reject(w, http.StatusBadRequest, "CONNECT-UDP requires HTTP/3 datagrams")
+		return
+	}
+
+	targetAddr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		p.reject(w, http.StatusBadGateway, fmt.Sprintf("cannot resolve %s: %v", target, err))
+		return
+	}
+	if !allowedTarget(p.allow, targetAddr.AddrPort()) {
+		p.mu.Lock()
+		p.stats.DeniedSessions++
+		p.mu.Unlock()
+		p.reject(w, http.StatusForbidden, fmt.Sprintf("target %s is not allowed by the proxy", targetAddr))
+		return
+	}
+	udpConn, err := net.DialUDP("udp", nil, targetAddr)
+	if err != nil {
+		p.reject(w, http.StatusBadGateway, fmt.Sprintf("cannot reach %s: %v", target, err))
+		return
+	}
+
+	str := streamer.HTTPStream()
+	session := &proxySession{streamID: uint64(str.StreamID()), target: udpConn}
+
+	p.mu.Lock()
+	p.stats.Sessions++
+	p.stats.ActiveSessions++
+	newConn := p.conns[conn] == nil
+	if newConn {
+		p.conns[conn] = make(map[uint64]*proxySession)
+	}
+	p.conns[conn][session.streamID] = session
+	p.mu.Unlock()
+	if newConn {
+		go p.serveConn(conn)
+	}
+
+	// Заголовки уходят сразу: обработчик работает все время жизни туннеля
+	w.Header().Set(capsuleProtocolHeader, "?1")
+	w.WriteHeader(http.StatusOK)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	go p.forwardFromTarget(conn, session)
+
+	// Клиент закрывает туннель, закрывая поток CONNECT; капсулы на потоке
+	// не используются и отбрасываются
+	streamClosed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, str)
+		close(streamClosed)
+	}()
+	select {
+	case <-conn.Context().Done():
+	case <-streamClosed:
+	}
+
+	p.mu.Lock()
+	if sessions := p.conns[conn]; sessions != nil {
+		delete(sessions, session.streamID)
+	}
+	p.stats.ActiveSessions--
+	p.mu.Unlock()
+	udpConn.Close()
+	str.Close()
+}
+
+// Collectors возвращает счетчики прокси для Prometheus; значения читаются
+// при каждом сборе
+func (p *Proxy) Collectors() []prometheus.Collector {
+	stat := func(name, help string, labels prometheus.Labels, value func(ProxyStats) int64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        name,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 { return float64(value(p.Stats())) })
+	}
+	toTarget := prometheus.Labels{"direction": "to_target"}
+	fromTarget := prometheus.Labels{"direction": "from_target"}
+	return []prometheus.Collector{
+		stat("quic_masque_sessions_total", "CONNECT-UDP requests that became tunnels", nil,
+			func(st ProxyStats) int64 { return st.Sessions }),
+		stat("quic_masque_active_sessions", "Open CONNECT-UDP tunnels", nil,
+			func(st ProxyStats) int64 { return st.ActiveSessions }),
+		stat("quic_masque_rejected_sessions_total", "CONNECT-UDP requests rejected: invalid, unreachable or denied targets", nil,
+			func(st ProxyStats) int64 { return st.RejectedSessions }),
+		stat("quic_masque_denied_sessions_total", "CONNECT-UDP requests for targets outside --masque-allow", nil,
+			func(st ProxyStats) int64 { return st.DeniedSessions }),
+		stat("quic_masque_datagrams_total", "Forwarded datagrams by direction (to_target, from_target)", toTarget,
+			func(st ProxyStats) int64 { return st.DatagramsToTarget }),
+		stat("quic_masque_datagrams_total", "Forwarded datagrams by direction (to_target, from_target)", fromTarget,
+			func(st ProxyStats) int64 { return st.DatagramsFromTarget }),
+		stat("quic_masque_bytes_total", "Forwarded UDP payload bytes by direction (to_target, from_target)", toTarget,
+			func(st ProxyStats) int64 { return st.BytesToTarget }),
+		stat("quic_masque_bytes_total", "Forwarded UDP payload bytes by direction (to_target, from_target)", fromTarget,
+			func(st ProxyStats) int64 { return st.BytesFromTarget }),
+		stat("quic_masque_dropped_datagrams_total", "Datagrams without a session, with an unknown context ID or not sent", nil,
+			func(st ProxyStats) int64 { return st.DroppedDatagrams }),
+	}
+}
+
+// reject отвечает ошибкой на запрос, который не стал туннелем
+func (p *Proxy) reject(w http.ResponseWriter, status int, msg string) {
+	p.mu.Lock()
+	p.stats.RejectedSessions++
+	p.mu.Unlock()
+	http.Error(w, msg, status)
+}
+
+// serveConn пересылает датаграммы соединения целям их сессий и забывает
+// соединение после его закрытия
+func (p *Proxy) serveConn(conn quic.Connection) {
+	for {
+		msg, err := conn.ReceiveDatagram(conn.Context())
+		if err != nil {
+			break
+		}
+		streamID, payload, ok, err := parseUDPDatagram(msg)
+		var session *proxySession
+		if err == nil && ok {
+			p.mu.Lock()
+			session = p.conns[conn][streamID]
+			p.mu.Unlock()
+		}
+		if session != nil {
+			_, err = session.target.Write(payload)
+		}
+		p.mu.Lock()
+		if session == nil || err != nil {
+			p.stats.DroppedDatagrams++
+		} else {
+			p.stats.DatagramsToTarget++
+			p.stats.BytesToTarget += int64(len(payload))
+		}
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	delete(p.conns, conn)
+	p.mu.Unlock()
+}
+
+// forwardFromTarget отправляет ответы цели клиенту, пока сокет не закрыт
+func (p *Proxy) forwardFromTarget(conn quic.Connection, session *proxySession) {
+	buf := make([]byte, 64*1024)
+	var msg []byte
+	for {
+		n, err := session.target.Read(buf)
+		if err != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				// ICMP port unreachable от цели не закрывает туннель
+				continue
+			}
+			return
+		}
+		msg = appendUDPDatagram(msg[:0], session.streamID, buf[:n])
+		err = conn.SendDatagram(msg)
+		p.mu.Lock()
+		if err != nil {
+			p.stats.DroppedDatagrams++
+		} else {
+			p.stats.DatagramsFromTarget++
+			p.stats.BytesFromTarget += int64(n)
+		}
+		p.mu.Unlock()
+	}
+}
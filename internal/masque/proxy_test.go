@@ -0,0 +1,229 @@
+package masque_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/masque"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// startProxy запускает прокси и UDP-эхо на loopback и возвращает их адреса
+// и TLS-конфигурацию клиента, доверяющую сертификату прокси. Эхо
+// разрешено как цель, если allow не задан.
+func startProxy(t *testing.T, allow ...masque.TargetRule) (*masque.Proxy, string, *tls.Config) {
+	t.Helper()
+	cert, err := internal.GenerateSelfSignedCert(nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allow == nil {
+		rule, err := masque.ParseTargetRule(echo.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		allow = []masque.TargetRule{rule}
+	}
+	proxy, err := masque.ListenProxy(masque.ProxyConfig{
+		Addr:      "127.0.0.1:0",
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		Allow:     allow,
+	})
+	if err != nil {
+		echo.Close()
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{}, 2)
+	go func() { proxy.Serve(ctx); done <- struct{}{} }()
+	go func() { masque.ServeUDPEcho(ctx, echo); done <- struct{}{} }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		<-done
+	})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert.Leaf)
+	return proxy, echo.LocalAddr().String(), &tls.Config{RootCAs: pool, ServerName: "localhost"}
+}
+
+func TestProxyForwardsDatagrams(t *testing.T) {
+	proxy, target, tlsConf := startProxy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tunnel, err := masque.DialUDPTunnel(ctx, masque.UDPTunnelConfig{
+		ProxyAddr: proxy.Addr().String(),
+		Target:    target,
+		TLSConfig: tlsConf,
+	})
+	if err != nil {
+		t.Fatalf("masque.DialUDPTunnel: %v", err)
+	}
+	defer tunnel.Close()
+
+	result, err := masque.RunEchoTest(ctx, tunnel, masque.EchoTestConfig{
+		PacketSize: masque.MaxTunnelPayload,
+		Rate:       100,
+		Duration:   500 * time.Millisecond,
+		Wait:       500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("masque.RunEchoTest: %v", err)
+	}
+	if result.Sent == 0 || result.SendErrors != 0 {
+		t.Fatalf("Expected datagrams sent without errors, got %d sent, %d errors (%v)", result.Sent, result.SendErrors, result.LastError)
+	}
+	if result.Received != result.Sent {
+		t.Errorf("Expected all %d datagrams echoed over loopback, got %d", result.Sent, result.Received)
+	}
+	if len(result.RTTs) != int(result.Received) {
+		t.Errorf("Expected one RTT per echoed datagram, got %d for %d", len(result.RTTs), result.Received)
+	}
+
+	stats := proxy.Stats()
+	if stats.Sessions != 1 || stats.ActiveSessions != 1 {
+		t.Errorf("Expected one active session, got %+v", stats)
+	}
+	if stats.DatagramsToTarget != result.Sent || stats.BytesToTarget != result.BytesSent {
+		t.Errorf("Expected %d datagrams / %d bytes to the target, got %d / %d",
+			result.Sent, result.BytesSent, stats.DatagramsToTarget, stats.BytesToTarget)
+	}
+	if stats.DatagramsFromTarget != result.Received || stats.BytesFromTarget != result.BytesReceived {
+		t.Errorf("Expected %d datagrams / %d bytes from the target, got %d / %d",
+			result.Received, result.BytesReceived, stats.DatagramsFromTarget, stats.BytesFromTarget)
+	}
+
+	if err := tunnel.Send(make([]byte, masque.MaxTunnelPayload+1)); err == nil {
+		t.Error("Expected an error for a payload larger than one HTTP datagram")
+	}
+}
+
+func TestProxyRejectsInvalidTarget(t *testing.T) {
+	proxy, _, tlsConf := startProxy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// Имя не разрешается, прокси отвечает 502
+	_, err := masque.DialUDPTunnel(ctx, masque.UDPTunnelConfig{
+		ProxyAddr: proxy.Addr().String(),
+		Target:    "host.invalid:53",
+		TLSConfig: tlsConf,
+	})
+	if err == nil {
+		t.Fatal("Expected CONNECT-UDP to an unresolvable target to fail")
+	}
+	if stats := proxy.Stats(); stats.RejectedSessions != 1 || stats.Sessions != 0 {
+		t.Errorf("Expected one rejected request and no sessions, got %+v", stats)
+	}
+}
+
+func TestProxyDeniesPrivateTargetsByDefault(t *testing.T) {
+	proxy, target, tlsConf := startProxy(t, masque.DefaultTargetRules...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// Эхо слушает loopback, а по умолчанию разрешены только публичные адреса
+	_, err := masque.DialUDPTunnel(ctx, masque.UDPTunnelConfig{
+		ProxyAddr: proxy.Addr().String(),
+		Target:    target,
+		TLSConfig: tlsConf,
+	})
+	if err == nil {
+		t.Fatal("Expected CONNECT-UDP to a loopback target to be denied")
+	}
+	if !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected 403 Forbidden, got %v", err)
+	}
+	if stats := proxy.Stats(); stats.DeniedSessions != 1 || stats.RejectedSessions != 1 || stats.Sessions != 0 {
+		t.Errorf("Expected one denied request and no sessions, got %+v", stats)
+	}
+}
+
+func TestTargetRules(t *testing.T) {
+	rules, err := masque.ParseTargetRules("public, 10.0.0.0/8, 127.0.0.0/8:9001, [::1]:9001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for target, want := range map[string]bool{
+		"192.0.2.1:53":        true,  // Публичный (документационная сеть считается глобальной)
+		"10.1.2.3:53":         true,  // Разрешенная частная сеть, любой порт
+		"192.168.1.1:53":      false, // Частная сеть вне правил
+		"127.0.0.1:9001":      true,
+		"127.0.0.1:9002":      false, // Loopback только на порту эха
+		"[::1]:9001":          true,
+		"[::ffff:10.0.0.1]:1": true, // IPv4-mapped сравнивается как IPv4
+		"[fe80::1]:53":        false,
+		"0.0.0.0:9001":        false,
+	} {
+		addr := netip.MustParseAddrPort(target)
+		allowed := false
+		for _, rule := range rules {
+			allowed = allowed || rule.Allows(addr)
+		}
+		if allowed != want {
+			t.Errorf("%s: expected allowed=%v", target, want)
+		}
+	}
+
+	if defaults, err := masque.ParseTargetRules(""); err != nil || len(defaults) != 1 || !defaults[0].Public {
+		t.Errorf("Expected the public default for an empty list, got %v, %v", defaults, err)
+	}
+	for _, bad := range []string{"10.0.0.0/33", "host.example", "127.0.0.1:0", "127.0.0.1:70000"} {
+		if _, err := masque.ParseTargetRule(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestProxyCollectors(t *testing.T) {
+	proxy, target, tlsConf := startProxy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tunnel, err := masque.DialUDPTunnel(ctx, masque.UDPTunnelConfig{
+		ProxyAddr: proxy.Addr().String(),
+		Target:    target,
+		TLSConfig: tlsConf,
+	})
+	if err != nil {
+		t.Fatalf("masque.DialUDPTunnel: %v", err)
+	}
+	defer tunnel.Close()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(proxy.Collectors()...)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range m.GetLabel() {
+				name += "/" + label.GetValue()
+			}
+			values[name] = m.GetGauge().GetValue()
+		}
+	}
+	if values["quic_masque_sessions_total"] != 1 || values["quic_masque_active_sessions"] != 1 {
+		t.Errorf("Expected one active session in the gauges, got %v", values)
+	}
+	if _, ok := values["quic_masque_datagrams_total/from_target"]; !ok {
+		t.Errorf("Expected datagram gauges by direction, got %v", values)
+	}
+}
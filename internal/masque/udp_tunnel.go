@@ -0,0 +1,138 @@
+package masque
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// DatagramConn — канал датаграмм к UDP-цели: туннель CONNECT-UDP или прямой
+// UDP-сокет для сравнения
+type DatagramConn interface {
+	Send(payload []byte) error
+	Receive(ctx context.Context) ([]byte, error)
+	Close() error
+}
+
+// UDPTunnelConfig настраивает туннель CONNECT-UDP
+type UDPTunnelConfig struct {
+	ProxyAddr  string      // host:port прокси
+	Target     string      // host:port UDP-цели за прокси
+	TLSConfig  *tls.Config // ALPN h3 добавляет туннель
+	QUICConfig *quic.Config
+}
+
+// UDPTunnel — клиентская сторона туннеля CONNECT-UDP (RFC 9298)
+type UDPTunnel struct {
+	roundTripper *http3.RoundTripper
+	body         interface{ Close() error }
+	str          http3.Stream
+	conn         quic.Connection
+	streamID     uint64
+	msg          []byte
+
+	// SetupTime — от начала handshake с прокси до ответа 200 на CONNECT
+	SetupTime time.Duration
+}
+
+var _ DatagramConn = (*UDPTunnel)(nil)
+
+// DialUDPTunnel открывает QUIC-соединение с прокси и туннель к цели
+func DialUDPTunnel(ctx context.Context, config UDPTunnelConfig) (*UDPTunnel, error) {
+	path, err := ConnectUDPPath(config.Target)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf := config.TLSConfig
+	if tlsConf == nil {
+		tlsConf = &tls.Config{}
+	}
+	tlsConf = tlsConf.Clone()
+	tlsConf.NextProtos = []string{http3.NextProtoH3}
+
+	quicConf := config.QUICConfig
+	if quicConf != nil && len(quicConf.Versions) > 1 {
+		// HTTP/3 дозванивается только с одной версией QUIC
+		quicConf = quicConf.Clone()
+		quicConf.Versions = quicConf.Versions[:1]
+	}
+	rt := &http3.RoundTripper{
+		TLSClientConfig: tlsConf,
+		QuicConfig:      quicConf,
+		EnableDatagrams: true,
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodConnect, "https://"+config.ProxyAddr+path, nil)
+	if err != nil {
+		rt.Close()
+		return nil, fmt.Errorf("failed to create CONNECT-UDP request: %w", err)
+	}
+	req.Proto = ProtocolConnectUDP
+	req.Header.Set(capsuleProtocolHeader, "?1")
+
+	start := time.Now()
+	// Поток CONNECT остается открытым все время жизни туннеля
+	resp, err := rt.RoundTripOpt(req, http3.RoundTripOpt{DontCloseRequestStream: true})
+	if err != nil {
+		rt.Close()
+		return nil, fmt.Errorf("CONNECT-UDP to %s via %s failed: %w", config.Target, config.ProxyAddr, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		rt.Close()
+		return nil, fmt.Errorf("CONNECT-UDP to %s via %s: proxy answered %s", config.Target, config.ProxyAddr, resp.Status)
+	}
+
+	t := &UDPTunnel{roundTripper: rt, body: resp.Body, SetupTime: time.Since(start)}
+	streamer, ok := resp.Body.(http3.HTTPStreamer)
+	hijacker, ok2 := resp.Body.(http3.Hijacker)
+	if ok && ok2 {
+		t.str = streamer.HTTPStream()
+		t.conn, ok = hijacker.StreamCreator().(quic.Connection)
+	}
+	if !ok || !ok2 || !t.conn.ConnectionState().SupportsDatagrams {
+		t.Close()
+		return nil, errors.New("CONNECT-UDP requires HTTP/3 datagrams, which the proxy does not support")
+	}
+	t.streamID = uint64(t.str.StreamID())
+	return t, nil
+}
+
+// Send отправляет UDP-нагрузку цели в одной HTTP-датаграмме; вызовы Send
+// не должны выполняться параллельно
+func (t *UDPTunnel) Send(payload []byte) error {
+	if len(payload) > MaxTunnelPayload {
+		return fmt.Errorf("payload of %d bytes exceeds the %d bytes that fit into one HTTP datagram", len(payload), MaxTunnelPayload)
+	}
+	t.msg = appendUDPDatagram(t.msg[:0], t.streamID, payload)
+	return t.conn.SendDatagram(t.msg)
+}
+
+// Receive возвращает следующую UDP-нагрузку от цели; датаграммы с другим
+// Context ID пропускаются
+func (t *UDPTunnel) Receive(ctx context.Context) ([]byte, error) {
+	for {
+		msg, err := t.conn.ReceiveDatagram(ctx)
+		if err != nil {
+			return nil, err
+		}
+		streamID, payload, ok, err := parseUDPDatagram(msg)
+		if err == nil && ok && streamID == t.streamID {
+			return payload, nil
+		}
+	}
+}
+
+// Close закрывает туннель и соединение с прокси
+func (t *UDPTunnel) Close() error {
+	if t.str != nil {
+		t.str.Close()
+	}
+	t.body.Close()
+	return t.roundTripper.Close()
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// MaxSafeJSONInteger — наибольшее целое, которое JavaScript (Number, float64)
+// представляет точно: 2^53 - 1
+const MaxSafeJSONInteger = 1<<53 - 1
+
+// byteCountsAsStrings включает запись всех ByteCount строками
+var byteCountsAsStrings atomic.Bool
+
+// SetByteCountsAsStrings переключает запись счетчиков байт в JSON: false —
+// числом, пока значение в безопасном диапазоне, и строкой за его пределами;
+// true — всегда строкой, чтобы потребителю не нужно было проверять тип.
+func SetByteCountsAsStrings(enabled bool) {
+	byteCountsAsStrings.Store(enabled)
+}
+
+// ByteCountsAsStrings сообщает, включена ли запись всех счетчиков байт строками
+func ByteCountsAsStrings() bool {
+	return byteCountsAsStrings.Load()
+}
+
+// ByteCount — счетчик байт в отчетах и ответах API. В JSON записывается
+// числом, если JavaScript прочитает его без потерь, иначе десятичной строкой
+// ("9007199254740993"); читается из обоих видов.
+type ByteCount int64
+
+// MarshalJSON реализует json.Marshaler
+func (c ByteCount) MarshalJSON() ([]byte, error) {
+	s := strconv.FormatInt(int64(c), 10)
+	if byteCountsAsStrings.Load() || !IsSafeJSONInteger(int64(c)) {
+		return []byte(`"` + s + `"`), nil
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalJSON реализует json.Unmarshaler
+func (c *ByteCount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid byte count %s: %w", data, err)
+	}
+	*c = ByteCount(v)
+	return nil
+}
+
+// IsSafeJSONInteger сообщает, что JavaScript прочитает v без потерь
+func IsSafeJSONInteger(v int64) bool {
+	return v >= -MaxSafeJSONInteger && v <= MaxSafeJSONInteger
+}
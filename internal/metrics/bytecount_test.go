@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestByteCountJSON(t *testing.T) {
+	type payload struct {
+		Bytes ByteCount `json:"bytes"`
+	}
+	// 2^53 + 1 — первое целое, которое float64 не представляет
+	const large = ByteCount(1<<53 + 1)
+
+	for _, tc := range []struct {
+		value   ByteCount
+		strings bool
+		want    string
+	}{
+		{1024, false, `{"bytes":1024}`},
+		{MaxSafeJSONInteger, false, `{"bytes":9007199254740991}`},
+		{large, false, `{"bytes":"9007199254740993"}`},
+		{-large, false, `{"bytes":"-9007199254740993"}`},
+		{1024, true, `{"bytes":"1024"}`},
+	} {
+		SetByteCountsAsStrings(tc.strings)
+		data, err := json.Marshal(payload{tc.value})
+		SetByteCountsAsStrings(false)
+		if err != nil {
+			t.Fatalf("Marshal(%d) failed: %v", tc.value, err)
+		}
+		if string(data) != tc.want {
+			t.Errorf("Expected %s, got %s", tc.want, data)
+		}
+
+		var got payload
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+		}
+		if got.Bytes != tc.value {
+			t.Errorf("Expected %d after a round trip, got %d", tc.value, got.Bytes)
+		}
+	}
+
+	// Через float64 (как в JavaScript) большое значение искажается
+	var generic map[string]float64
+	if err := json.Unmarshal([]byte(`{"bytes":9007199254740993}`), &generic); err != nil {
+		t.Fatal(err)
+	}
+	if ByteCount(generic["bytes"]) == large {
+		t.Error("Expected a float64 to lose precision above 2^53")
+	}
+
+	var p payload
+	if err := json.Unmarshal([]byte(`{"bytes":"12kb"}`), &p); err == nil {
+		t.Error("Expected error for a non-numeric byte count")
+	}
+}
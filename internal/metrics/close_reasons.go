@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// Причины закрытия соединения
+const (
+	CloseReasonAppClose           = "app_close"           // приложение закрыло соединение с кодом 0
+	CloseReasonAppError           = "app_error"           // приложение закрыло соединение с кодом ошибки
+	CloseReasonIdleTimeout        = "idle_timeout"        // истек idle timeout
+	CloseReasonHandshakeTimeout   = "handshake_timeout"   // handshake не завершился вовремя
+	CloseReasonStatelessReset     = "stateless_reset"     // сброс соединения через stateless reset
+	CloseReasonTransportError     = "transport_error"     // ошибка транспорта QUIC
+	CloseReasonVersionNegotiation = "version_negotiation" // не удалось согласовать версию
+	CloseReasonOther              = "other"               // любая другая ошибка
+)
+
+// CloseReasonNames перечисляет все причины в порядке вывода
+var CloseReasonNames = []string{
+	CloseReasonAppClose,
+	CloseReasonAppError,
+	CloseReasonIdleTimeout,
+	CloseReasonHandshakeTimeout,
+	CloseReasonStatelessReset,
+	CloseReasonTransportError,
+	CloseReasonVersionNegotiation,
+	CloseReasonOther,
+}
+
+// CloseReasonSummary — число соединений, закрытых по одной причине
+type CloseReasonSummary struct {
+	Reason string           `json:"reason"`
+	Count  int64            `json:"count"`
+	Remote int64            `json:"remote"`          // из них закрыто другой стороной
+	Codes  map[string]int64 `json:"codes,omitempty"` // коды ошибок приложения или транспорта
+}
+
+// ClassifyClose определяет причину закрытия соединения по ошибке из
+// context.Cause(conn.Context()), код ошибки (пустой, если его нет) и
+// закрыла ли соединение другая сторона
+func ClassifyClose(err error) (reason, code string, remote bool) {
+	var (
+		appErr         *quic.ApplicationError
+		transportErr   *quic.TransportError
+		idleErr        *quic.IdleTimeoutError
+		handshakeErr   *quic.HandshakeTimeoutError
+		statelessReset *quic.StatelessResetError
+		versionErr     *quic.VersionNegotiationError
+	)
+	switch {
+	case errors.As(err, &appErr):
+		if appErr.ErrorCode == 0 {
+			return CloseReasonAppClose, "", appErr.Remote
+		}
+		return CloseReasonAppError, fmt.Sprintf("%#x", uint64(appErr.ErrorCode)), appErr.Remote
+	case errors.As(err, &idleErr):
+		return CloseReasonIdleTimeout, "", false
+	case errors.As(err, &handshakeErr):
+		return CloseReasonHandshakeTimeout, "", false
+	case errors.As(err, &statelessReset):
+		return CloseReasonStatelessReset, "", true
+	case errors.As(err, &transportErr):
+		return CloseReasonTransportError, transportErr.ErrorCode.String(), transportErr.Remote
+	case errors.As(err, &versionErr):
+		return CloseReasonVersionNegotiation, "", true
+	}
+	return CloseReasonOther, "", false
+}
+
+// CloseReasons считает закрытые соединения по причинам; безопасен для
+// конкурентного использования
+type CloseReasons struct {
+	mu      sync.Mutex
+	entries map[string]*CloseReasonSummary
+}
+
+// NewCloseReasons создает пустое распределение причин закрытия
+func NewCloseReasons() *CloseReasons {
+	return &CloseReasons{entries: make(map[string]*CloseReasonSummary)}
+}
+
+// Record учитывает закрытие соединения с ошибкой err
+func (c *CloseReasons) Record(err error) {
+	if c == nil {
+		return
+	}
+	reason, code, remote := ClassifyClose(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[reason]
+	if !ok {
+		entry = &CloseReasonSummary{Reason: reason}
+		c.entries[reason] = entry
+	}
+	entry.Count++
+	if remote {
+		entry.Remote++
+	}
+	if code != "" {
+		if entry.Codes == nil {
+			entry.Codes = make(map[string]int64)
+		}
+		entry.Codes[code]++
+	}
+}
+
+// Count возвращает число соединений, закрытых по причине reason
+func (c *CloseReasons) Count(reason string) int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[reason]; ok {
+		return entry.Count
+	}
+	return 0
+}
+
+// Summary возвращает распределение по причинам, самые частые первыми
+func (c *CloseReasons) Summary() []CloseReasonSummary {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make([]CloseReasonSummary, 0, len(c.entries))
+	for _, entry := range c.entries {
+		summary := *entry
+		if entry.Codes != nil {
+			summary.Codes = make(map[string]int64, len(entry.Codes))
+			for code, n := range entry.Codes {
+				summary.Codes[code] = n
+			}
+		}
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// closePair устанавливает соединение через loopback и возвращает обе его
+// стороны: клиентскую и серверную
+func closePair(t *testing.T, idleTimeout time.Duration) (client, server quic.Connection) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverTLS := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"close-test"},
+	}
+	quicConf := &quic.Config{MaxIdleTimeout: idleTimeout}
+
+	listener, err := quic.ListenAddr("127.0.0.1:0", serverTLS, quicConf)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err = quic.DialAddr(ctx, listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"close-test"}}, quicConf)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	server, err = listener.Accept(ctx)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	return client, server
+}
+
+// closeCause ждет закрытия соединения и возвращает его ошибку
+func closeCause(t *testing.T, conn quic.Connection) error {
+	t.Helper()
+	select {
+	case <-conn.Context().Done():
+		return context.Cause(conn.Context())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Connection did not close")
+		return nil
+	}
+}
+
+func TestCloseReasonsIdleTimeout(t *testing.T) {
+	// Без keep-alive и без трафика обе стороны закрывают соединение по idle timeout
+	client, server := closePair(t, 200*time.Millisecond)
+	reasons := NewCloseReasons()
+	reasons.Record(closeCause(t, client))
+	reasons.Record(closeCause(t, server))
+
+	if got := reasons.Count(CloseReasonIdleTimeout); got != 2 {
+		t.Errorf("Expected both sides to close by idle timeout, got %+v", reasons.Summary())
+	}
+}
+
+func TestCloseReasonsAppClose(t *testing.T) {
+	client, server := closePair(t, 5*time.Second)
+	if err := client.CloseWithError(0, "done"); err != nil {
+		t.Fatal(err)
+	}
+	reason, code, remote := ClassifyClose(closeCause(t, client))
+	if reason != CloseReasonAppClose || code != "" || remote {
+		t.Errorf("Expected a local app close on the client, got %s %q remote=%v", reason, code, remote)
+	}
+	reason, code, remote = ClassifyClose(closeCause(t, server))
+	if reason != CloseReasonAppClose || code != "" || !remote {
+		t.Errorf("Expected a remote app close on the server, got %s %q remote=%v", reason, code, remote)
+	}
+
+	client, server = closePair(t, 5*time.Second)
+	if err := server.CloseWithError(0x42, "failed"); err != nil {
+		t.Fatal(err)
+	}
+	reasons := NewCloseReasons()
+	reasons.Record(closeCause(t, client))
+	reasons.Record(closeCause(t, server))
+	summary := reasons.Summary()
+	if len(summary) != 1 || summary[0].Reason != CloseReasonAppError || summary[0].Count != 2 ||
+		summary[0].Remote != 1 || summary[0].Codes["0x42"] != 2 {
+		t.Errorf("Expected two app_error closes with code 0x42, one of them remote, got %+v", summary)
+	}
+}
+
+func TestClassifyCloseOther(t *testing.T) {
+	if reason, _, _ := ClassifyClose(errors.New("boom")); reason != CloseReasonOther {
+		t.Errorf("Expected %s for an unknown error, got %s", CloseReasonOther, reason)
+	}
+	if reason, _, _ := ClassifyClose(&quic.StatelessResetError{}); reason != CloseReasonStatelessReset {
+		t.Errorf("Expected %s, got %s", CloseReasonStatelessReset, reason)
+	}
+}
@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// EmulationSummary сравнивает заданные параметры эмуляции с фактически
+// примененными: так проверяется сам слой эмуляции
+type EmulationSummary struct {
+	Packets             int64   `json:"packets"` // пакеты, прошедшие решение о потере
+	Drops               int64   `json:"drops"`
+	Dups                int64   `json:"dups"`
+	Reorders            int64   `json:"reorders"`
+	DelaySecondsSum     float64 `json:"delay_seconds_sum"`
+	ConfiguredLoss      float64 `json:"configured_loss"`
+	AppliedLoss         float64 `json:"applied_loss"`
+	ConfiguredDup       float64 `json:"configured_dup"`
+	AppliedDup          float64 `json:"applied_dup"` // доля дублей среди не потерянных пакетов
+	ConfiguredReorder   float64 `json:"configured_reorder"`
+	AppliedReorder      float64 `json:"applied_reorder"` // доля задержанных пакетов среди принявших решение
+	ConfiguredLatencyMs float64 `json:"configured_latency_ms"`
+	AppliedLatencyMs    float64 `json:"applied_latency_ms"` // средняя фактическая задержка
+	ConfiguredJitterMs  float64 `json:"configured_jitter_ms"`
+	// Среднее отклонение задержки от --emulate-latency по модулю; для
+	// равномерного распределения около половины заданного jitter
+	AppliedJitterMs float64 `json:"applied_jitter_ms"`
+}
+
+// EmulationStats принимает решения эмуляции с заданными вероятностями и
+// считает примененные события. Методы безопасны для nil: без эмуляции
+// решения всегда отрицательные и ничего не учитывается
+type EmulationStats struct {
+	loss, dup, reorder float64
+	latency, jitter    time.Duration
+
+	packets, drops    atomic.Int64
+	sent, dups        atomic.Int64
+	reorderable, held atomic.Int64
+	delays            atomic.Int64
+	delayNanos        atomic.Int64
+	deviationNanos    atomic.Int64
+}
+
+// NewEmulationStats создает счетчики для --emulate-loss, --emulate-dup,
+// --emulate-reorder, --emulate-latency и --emulate-jitter
+func NewEmulationStats(loss, dup, reorder float64, latency, jitter time.Duration) *EmulationStats {
+	return &EmulationStats{loss: loss, dup: dup, reorder: reorder, latency: latency, jitter: jitter}
+}
+
+// Drop решает, потерять ли пакет; rnd вызывается только при ненулевой
+// вероятности, чтобы не сдвигать последовательность --emulation-seed
+func (s *EmulationStats) Drop(rnd func() float64) bool {
+	if s == nil {
+		return false
+	}
+	s.packets.Add(1)
+	if s.loss > 0 && rnd() < s.loss {
+		s.drops.Add(1)
+		return true
+	}
+	return false
+}
+
+// Duplicate решает, отправить ли не потерянный пакет дважды
+func (s *EmulationStats) Duplicate(rnd func() float64) bool {
+	if s == nil {
+		return false
+	}
+	s.sent.Add(1)
+	if s.dup > 0 && rnd() < s.dup {
+		s.dups.Add(1)
+		return true
+	}
+	return false
+}
+
+// Reorder решает, задержать ли пакет до отправки следующего
+func (s *EmulationStats) Reorder(rnd func() float64) bool {
+	if s == nil {
+		return false
+	}
+	s.reorderable.Add(1)
+	if s.reorder > 0 && rnd() < s.reorder {
+		s.held.Add(1)
+		return true
+	}
+	return false
+}
+
+// DropUnsent учитывает как потерю n пакетов, которые прошли решения
+// эмуляции, но не были отправлены: тест кончился, пока пакет был задержан
+// Reorder или ждал в очереди отправки. Перестановка остается учтенной,
+// чтобы счетчик Reorders не убывал
+func (s *EmulationStats) DropUnsent(n int) {
+	if s == nil {
+		return
+	}
+	s.drops.Add(int64(n))
+}
+
+// Delay возвращает задержку пакета: --emulate-latency плюс равномерно
+// распределенное отклонение в пределах ±--emulate-jitter, но не меньше нуля
+func (s *EmulationStats) Delay(rnd func() float64) time.Duration {
+	if s == nil {
+		return 0
+	}
+	d := s.latency
+	if s.jitter > 0 {
+		d += time.Duration((2*rnd() - 1) * float64(s.jitter))
+	}
+	return max(d, 0)
+}
+
+// RecordDelay учитывает фактически выдержанную задержку
+func (s *EmulationStats) RecordDelay(d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.delays.Add(1)
+	s.delayNanos.Add(int64(d))
+	deviation := d - s.latency
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	s.deviationNanos.Add(int64(deviation))
+}
+
+// Drops возвращает число эмулированных потерь
+func (s *EmulationStats) Drops() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.drops.Load()
+}
+
+// Dups возвращает число эмулированных дублей
+func (s *EmulationStats) Dups() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.dups.Load()
+}
+
+// Reorders возвращает число пакетов, переставленных с последующим
+func (s *EmulationStats) Reorders() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.held.Load()
+}
+
+// DelaySeconds возвращает суммарную эмулированную задержку в секундах
+func (s *EmulationStats) DelaySeconds() float64 {
+	if s == nil {
+		return 0
+	}
+	return time.Duration(s.delayNanos.Load()).Seconds()
+}
+
+// Summary возвращает заданные и фактические значения эмуляции
+func (s *EmulationStats) Summary() EmulationSummary {
+	if s == nil {
+		return EmulationSummary{}
+	}
+	summary := EmulationSummary{
+		Packets:             s.packets.Load(),
+		Drops:               s.drops.Load(),
+		Dups:                s.dups.Load(),
+		Reorders:            s.held.Load(),
+		DelaySecondsSum:     s.DelaySeconds(),
+		ConfiguredLoss:      s.loss,
+		ConfiguredDup:       s.dup,
+		ConfiguredReorder:   s.reorder,
+		ConfiguredLatencyMs: float64(s.latency) / float64(time.Millisecond),
+		ConfiguredJitterMs:  float64(s.jitter) / float64(time.Millisecond),
+	}
+	if summary.Packets > 0 {
+		summary.AppliedLoss = float64(summary.Drops) / float64(summary.Packets)
+	}
+	if sent := s.sent.Load(); sent > 0 {
+		summary.AppliedDup = float64(summary.Dups) / float64(sent)
+	}
+	if reorderable := s.reorderable.Load(); reorderable > 0 {
+		summary.AppliedReorder = float64(summary.Reorders) / float64(reorderable)
+	}
+	if delays := s.delays.Load(); delays > 0 {
+		summary.AppliedLatencyMs = float64(s.delayNanos.Load()) / float64(delays) / float64(time.Millisecond)
+		summary.AppliedJitterMs = float64(s.deviationNanos.Load()) / float64(delays) / float64(time.Millisecond)
+	}
+	return summary
+}
+
+// Пороги, после которых эмуляция искажает результат сама по себе
+const (
+	emulationHeavyLoss    = 0.5 // при такой потере соединения QUIC почти не живут
+	emulationDelayOverrun = 1.2 // фактическая задержка больше заданной в столько раз
+	emulationDelaySlackMs = 1.0 // допуск на точность таймеров, мс
+)
+
+// LimitWarnings возвращает предупреждения о работе эмуляции у своих
+// пределов: потеря, при которой измеряется в основном восстановление, и
+// задержка, которую отправитель не успевает выдерживать
+func (s EmulationSummary) LimitWarnings() []string {
+	var warnings []string
+	if s.ConfiguredLoss >= emulationHeavyLoss {
+		warnings = append(warnings, fmt.Sprintf("emulated loss %.0f%% is near the point where QUIC connections stall; results mostly reflect loss recovery",
+			s.ConfiguredLoss*100))
+	}
+	if s.ConfiguredLatencyMs > 0 && s.AppliedLatencyMs > s.ConfiguredLatencyMs*emulationDelayOverrun+emulationDelaySlackMs {
+		warnings = append(warnings, fmt.Sprintf("emulated delay averaged %.2f ms against %.2f ms configured: the sender cannot keep up with the emulation timers at this rate",
+			s.AppliedLatencyMs, s.ConfiguredLatencyMs))
+	}
+	return warnings
+}
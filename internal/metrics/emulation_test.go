@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestEmulationDropRateConverges(t *testing.T) {
+	const loss, dup = 0.05, 0.02
+	s := NewEmulationStats(loss, dup, 0, 10*time.Millisecond, 0)
+	rnd := rand.New(rand.NewSource(1)).Float64
+	const packets = 200000
+	for i := 0; i < packets; i++ {
+		if !s.Drop(rnd) {
+			s.Duplicate(rnd)
+		}
+	}
+	s.RecordDelay(9 * time.Millisecond)
+	s.RecordDelay(11 * time.Millisecond)
+
+	summary := s.Summary()
+	if summary.Packets != packets {
+		t.Fatalf("Expected %d packets, got %d", packets, summary.Packets)
+	}
+	// Пять стандартных отклонений биномиального распределения
+	if tol := 5 * math.Sqrt(loss*(1-loss)/packets); math.Abs(summary.AppliedLoss-loss) > tol {
+		t.Errorf("Applied loss %.4f did not converge to %.4f (±%.4f)", summary.AppliedLoss, loss, tol)
+	}
+	sent := float64(packets - summary.Drops)
+	if tol := 5 * math.Sqrt(dup*(1-dup)/sent); math.Abs(summary.AppliedDup-dup) > tol {
+		t.Errorf("Applied dup %.4f did not converge to %.4f (±%.4f)", summary.AppliedDup, dup, tol)
+	}
+	if summary.AppliedLatencyMs != 10 || summary.DelaySecondsSum != 0.02 {
+		t.Errorf("Expected 10 ms average over 0.02 s of delay, got %+v", summary)
+	}
+	if summary.ConfiguredLoss != loss || summary.ConfiguredDup != dup || summary.ConfiguredLatencyMs != 10 {
+		t.Errorf("Configured values not echoed: %+v", summary)
+	}
+}
+
+func TestEmulationReorderAndJitter(t *testing.T) {
+	const reorder = 0.1
+	const latency, jitter = 20 * time.Millisecond, 5 * time.Millisecond
+	s := NewEmulationStats(0, 0, reorder, latency, jitter)
+	rnd := rand.New(rand.NewSource(1)).Float64
+	const packets = 100000
+	for i := 0; i < packets; i++ {
+		s.Reorder(rnd)
+		d := s.Delay(rnd)
+		if d < latency-jitter || d > latency+jitter {
+			t.Fatalf("Delay %v outside %v ± %v", d, latency, jitter)
+		}
+		s.RecordDelay(d)
+	}
+
+	summary := s.Summary()
+	if tol := 5 * math.Sqrt(reorder*(1-reorder)/packets); math.Abs(summary.AppliedReorder-reorder) > tol {
+		t.Errorf("Applied reorder %.4f did not converge to %.4f (±%.4f)", summary.AppliedReorder, reorder, tol)
+	}
+	// Равномерное отклонение: в среднем 0, по модулю половина jitter
+	if math.Abs(summary.AppliedLatencyMs-20) > 0.1 || math.Abs(summary.AppliedJitterMs-2.5) > 0.1 {
+		t.Errorf("Expected 20 ms average delay deviating by 2.5 ms, got %+v", summary)
+	}
+	if summary.ConfiguredReorder != reorder || summary.ConfiguredJitterMs != 5 {
+		t.Errorf("Configured values not echoed: %+v", summary)
+	}
+
+	// Отклонение больше задержки не делает ее отрицательной
+	short := NewEmulationStats(0, 0, 0, time.Millisecond, 10*time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		if d := short.Delay(rnd); d < 0 {
+			t.Fatalf("Negative delay %v", d)
+		}
+	}
+}
+
+func TestEmulationDropUnsent(t *testing.T) {
+	s := NewEmulationStats(0, 0, 1, 0, 0)
+	always := func() float64 { return 0 }
+	s.Drop(always)
+	s.Duplicate(always)
+	if !s.Reorder(always) {
+		t.Fatal("Expected the packet to be held")
+	}
+	s.DropUnsent(1)
+
+	summary := s.Summary()
+	if summary.Drops != 1 || summary.Reorders != 1 || summary.AppliedLoss != 1 {
+		t.Errorf("Expected the held packet counted as lost, got %+v", summary)
+	}
+}
+
+func TestEmulationStatsNil(t *testing.T) {
+	var s *EmulationStats
+	never := func() float64 { t.Fatal("rnd called without emulation"); return 0 }
+	if s.Drop(never) || s.Duplicate(never) || s.Reorder(never) || s.Delay(never) != 0 {
+		t.Error("Expected no emulation decisions on nil stats")
+	}
+	s.RecordDelay(time.Second)
+	s.DropUnsent(1)
+	if s.Drops() != 0 || s.Dups() != 0 || s.Reorders() != 0 || s.DelaySeconds() != 0 {
+		t.Error("Expected zero counters on nil stats")
+	}
+}
+
+func TestEmulationLimitWarnings(t *testing.T) {
+	if w := (EmulationSummary{ConfiguredLoss: 0.05, ConfiguredLatencyMs: 10, AppliedLatencyMs: 10.4}).LimitWarnings(); len(w) != 0 {
+		t.Errorf("Expected no warnings within limits, got %v", w)
+	}
+	if w := (EmulationSummary{ConfiguredLoss: 0.6}).LimitWarnings(); len(w) != 1 {
+		t.Errorf("Expected a heavy loss warning, got %v", w)
+	}
+	if w := (EmulationSummary{ConfiguredLatencyMs: 1, AppliedLatencyMs: 5}).LimitWarnings(); len(w) != 1 {
+		t.Errorf("Expected a delay overrun warning, got %v", w)
+	}
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OverflowErrorType собирает ошибки новых типов после достижения лимита
+const OverflowErrorType = "other"
+
+const (
+	defaultMaxErrorTypes   = 100
+	defaultMaxErrorSamples = 3
+)
+
+// ErrorSummary описывает агрегированную информацию об одном типе ошибок
+type ErrorSummary struct {
+	Type      string    `json:"type"`
+	Count     int64     `json:"count"`
+	Samples   []string  `json:"samples,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ErrorAggregator группирует ошибки по типу с ограничением на число типов
+// и примеров, чтобы отчеты оставались читаемыми при массовых сбоях
+type ErrorAggregator struct {
+	mu         sync.Mutex
+	maxTypes   int
+	maxSamples int
+	entries    map[string]*ErrorSummary
+	total      int64
+}
+
+// NewErrorAggregator создает агрегатор; нулевые значения заменяются умолчаниями
+func NewErrorAggregator(maxTypes, maxSamples int) *ErrorAggregator {
+	if maxTypes <= 0 {
+		maxTypes = defaultMaxErrorTypes
+	}
+	if maxSamples <= 0 {
+		maxSamples = defaultMaxErrorSamples
+	}
+	return &ErrorAggregator{
+		maxTypes:   maxTypes,
+		maxSamples: maxSamples,
+		entries:    make(map[string]*ErrorSummary),
+	}
+}
+
+// Record учитывает ошибку заданного типа с текстом-примером
+func (a *ErrorAggregator) Record(errType, detail string) {
+	a.RecordAt(errType, detail, time.Now())
+}
+
+// RecordAt учитывает ошибку с явной временной меткой
+func (a *ErrorAggregator) RecordAt(errType, detail string, at time.Time) {
+	if errType == "" {
+		errType = "unknown"
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.total++
+
+	entry, ok := a.entries[errType]
+	if !ok {
+		// При достижении лимита (с учетом слота под overflow) новые типы
+		// сливаются в общий bucket
+		if len(a.entries) >= a.maxTypes-1 && errType != OverflowErrorType {
+			entry = a.entries[OverflowErrorType]
+			if entry == nil {
+				entry = &ErrorSummary{Type: OverflowErrorType, FirstSeen: at}
+				a.entries[OverflowErrorType] = entry
+			}
+			// В overflow сохраняем исходный тип, чтобы пример был информативным
+			detail = errType + ": " + detail
+		} else {
+			entry = &ErrorSummary{Type: errType, FirstSeen: at}
+			a.entries[errType] = entry
+		}
+	}
+
+	entry.Count++
+	if at.Before(entry.FirstSeen) {
+		entry.FirstSeen = at
+	}
+	if at.After(entry.LastSeen) {
+		entry.LastSeen = at
+	}
+	if detail != "" && len(entry.Samples) < a.maxSamples && !containsString(entry.Samples, detail) {
+		entry.Samples = append(entry.Samples, detail)
+	}
+}
+
+// TopN возвращает n самых частых типов ошибок (n <= 0 — все типы)
+func (a *ErrorAggregator) TopN(n int) []ErrorSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	result := make([]ErrorSummary, 0, len(a.entries))
+	for _, entry := range a.entries {
+		summary := *entry
+		summary.Samples = append([]string(nil), entry.Samples...)
+		result = append(result, summary)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Type < result[j].Type
+	})
+
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// Counts возвращает количество ошибок по типам
+func (a *ErrorAggregator) Counts() map[string]int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	counts := make(map[string]int64, len(a.entries))
+	for errType, entry := range a.entries {
+		counts[errType] = entry.Count
+	}
+	return counts
+}
+
+// Total возвращает общее число учтенных ошибок
+func (a *ErrorAggregator) Total() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.total
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestErrorAggregatorTopN(t *testing.T) {
+	agg := NewErrorAggregator(50, 2)
+	base := time.Unix(1700000000, 0)
+
+	// Много уникальных строк ошибок, но всего три типа с разной частотой
+	for i := 0; i < 1000; i++ {
+		agg.RecordAt("timeout", fmt.Sprintf("read udp 10.0.0.1:%d: i/o timeout", 10000+i), base.Add(time.Duration(i)*time.Second))
+	}
+	for i := 0; i < 500; i++ {
+		agg.RecordAt("stream_write", fmt.Sprintf("stream %d canceled", i), base.Add(time.Duration(i)*time.Second))
+	}
+	for i := 0; i < 10; i++ {
+		agg.RecordAt("quic_handshake", fmt.Sprintf("handshake %d failed", i), base)
+	}
+
+	top := agg.TopN(2)
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(top))
+	}
+	if top[0].Type != "timeout" || top[0].Count != 1000 {
+		t.Errorf("Expected timeout x1000 first, got %s x%d", top[0].Type, top[0].Count)
+	}
+	if top[1].Type != "stream_write" || top[1].Count != 500 {
+		t.Errorf("Expected stream_write x500 second, got %s x%d", top[1].Type, top[1].Count)
+	}
+	if len(top[0].Samples) != 2 {
+		t.Errorf("Expected samples capped at 2, got %d", len(top[0].Samples))
+	}
+	if !top[0].FirstSeen.Equal(base) {
+		t.Errorf("Unexpected FirstSeen: %v", top[0].FirstSeen)
+	}
+	if want := base.Add(999 * time.Second); !top[0].LastSeen.Equal(want) {
+		t.Errorf("Expected LastSeen %v, got %v", want, top[0].LastSeen)
+	}
+	if agg.Total() != 1510 {
+		t.Errorf("Expected total 1510, got %d", agg.Total())
+	}
+}
+
+func TestErrorAggregatorCapsTypes(t *testing.T) {
+	agg := NewErrorAggregator(10, 3)
+
+	for i := 0; i < 10000; i++ {
+		msg := fmt.Sprintf("error #%d", i)
+		agg.Record(msg, msg)
+	}
+
+	counts := agg.Counts()
+	if len(counts) != 10 {
+		t.Fatalf("Expected map capped at 10 types, got %d", len(counts))
+	}
+	if counts[OverflowErrorType] != 10000-9 {
+		t.Errorf("Expected %d errors in overflow bucket, got %d", 10000-9, counts[OverflowErrorType])
+	}
+
+	top := agg.TopN(1)
+	if top[0].Type != OverflowErrorType {
+		t.Errorf("Expected overflow bucket to be the top entry, got %s", top[0].Type)
+	}
+	if len(top[0].Samples) != 3 {
+		t.Errorf("Expected 3 samples in overflow bucket, got %d", len(top[0].Samples))
+	}
+
+	var sum int64
+	for _, c := range counts {
+		sum += c
+	}
+	if sum != agg.Total() {
+		t.Errorf("Counts sum %d does not match total %d", sum, agg.Total())
+	}
+}
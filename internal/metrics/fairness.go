@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"quic-test/internal/congestion"
+)
+
+// ConnectionShare — доля полосы одного соединения
+type ConnectionShare struct {
+	ID    int       `json:"id"`
+	Bytes ByteCount `json:"bytes"`
+	Mbps  float64   `json:"mbps"`
+	Share float64   `json:"share"` // доля от суммарной полосы всех соединений
+}
+
+// ConnectionFairness описывает, насколько поровну соединения на общем пути
+// делят полосу: при идеальном разделении индекс равен 1, а при захвате
+// полосы одним из n соединений — 1/n
+type ConnectionFairness struct {
+	Index       float64           `json:"index"` // Jain's fairness index по пропускной способности соединений
+	MinShare    float64           `json:"min_share"`
+	MaxShare    float64           `json:"max_share"`
+	Connections []ConnectionShare `json:"connections"`
+}
+
+// ConnectionThroughput считает отправленные байты по соединениям
+type ConnectionThroughput struct {
+	mu    sync.Mutex
+	bytes map[int]int64
+}
+
+// NewConnectionThroughput создает счетчики для connections соединений;
+// соединение, не отправившее ничего, входит в индекс с нулем
+func NewConnectionThroughput(connections int) *ConnectionThroughput {
+	c := &ConnectionThroughput{bytes: make(map[int]int64, connections)}
+	for id := 0; id < connections; id++ {
+		c.bytes[id] = 0
+	}
+	return c
+}
+
+// Add учитывает n байт, отправленных соединением connID
+func (c *ConnectionThroughput) Add(connID, n int) {
+	c.mu.Lock()
+	c.bytes[connID] += int64(n)
+	c.mu.Unlock()
+}
+
+// Summary возвращает пропускную способность соединений за elapsed и индекс
+// справедливости по ней; соединения упорядочены по ID
+func (c *ConnectionThroughput) Summary(elapsed time.Duration) ConnectionFairness {
+	c.mu.Lock()
+	ids := make([]int, 0, len(c.bytes))
+	for id := range c.bytes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	shares := make([]ConnectionShare, len(ids))
+	for i, id := range ids {
+		shares[i] = ConnectionShare{ID: id, Bytes: ByteCount(c.bytes[id])}
+	}
+	c.mu.Unlock()
+
+	// Все соединения измеряются за одно время, поэтому без elapsed доли
+	// и индекс считаются по байтам
+	throughputs := make([]float64, len(shares))
+	for i := range shares {
+		throughputs[i] = float64(shares[i].Bytes)
+		if elapsed > 0 {
+			shares[i].Mbps = throughputs[i] * 8 / elapsed.Seconds() / 1e6
+			throughputs[i] = shares[i].Mbps
+		}
+	}
+	return fairness(shares, throughputs)
+}
+
+// FairnessFromThroughputs вычисляет индекс и доли по пропускной способности
+// соединений (Мбит/с); ID соединения — его индекс в срезе
+func FairnessFromThroughputs(mbps []float64) ConnectionFairness {
+	shares := make([]ConnectionShare, len(mbps))
+	for i, v := range mbps {
+		shares[i] = ConnectionShare{ID: i, Mbps: v}
+	}
+	return fairness(shares, mbps)
+}
+
+// fairness заполняет доли shares по throughputs и вычисляет индекс Jain
+func fairness(shares []ConnectionShare, throughputs []float64) ConnectionFairness {
+	f := ConnectionFairness{Connections: shares}
+	var total float64
+	for _, t := range throughputs {
+		total += t
+	}
+	if total <= 0 {
+		return f
+	}
+	for i := range shares {
+		shares[i].Share = throughputs[i] / total
+		if i == 0 || shares[i].Share < f.MinShare {
+			f.MinShare = shares[i].Share
+		}
+		if shares[i].Share > f.MaxShare {
+			f.MaxShare = shares[i].Share
+		}
+	}
+	f.Index = congestion.JainFairnessIndex(throughputs)
+	return f
+}
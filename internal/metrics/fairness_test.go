@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCrossConnectionFairness(t *testing.T) {
+	tests := []struct {
+		name      string
+		mbps      []float64
+		wantIndex float64
+		wantMin   float64
+		wantMax   float64
+	}{
+		{"equal shares", []float64{25, 25, 25, 25}, 1, 0.25, 0.25},
+		{"one connection takes all", []float64{100, 0, 0, 0}, 0.25, 0, 1},
+		// (100)^2 / (4 × (40² + 30² + 20² + 10²)) = 10000 / 12000
+		{"skewed", []float64{40, 30, 20, 10}, 10000.0 / 12000, 0.1, 0.4},
+		{"single connection", []float64{80}, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := FairnessFromThroughputs(tt.mbps)
+			if math.Abs(f.Index-tt.wantIndex) > 1e-9 {
+				t.Errorf("Index = %.4f, want %.4f", f.Index, tt.wantIndex)
+			}
+			if math.Abs(f.MinShare-tt.wantMin) > 1e-9 || math.Abs(f.MaxShare-tt.wantMax) > 1e-9 {
+				t.Errorf("Shares in [%.2f, %.2f], want [%.2f, %.2f]", f.MinShare, f.MaxShare, tt.wantMin, tt.wantMax)
+			}
+			if len(f.Connections) != len(tt.mbps) {
+				t.Errorf("Expected %d connections, got %d", len(tt.mbps), len(f.Connections))
+			}
+		})
+	}
+}
+
+func TestConnectionThroughputSummary(t *testing.T) {
+	c := NewConnectionThroughput(3)
+	c.Add(0, 750_000)
+	c.Add(1, 250_000)
+	c.Add(0, 250_000)
+	// Соединение 2 ничего не отправило и все равно входит в индекс
+
+	f := c.Summary(time.Second)
+	if len(f.Connections) != 3 {
+		t.Fatalf("Expected 3 connections, got %+v", f.Connections)
+	}
+	if f.Connections[0].Bytes != 1_000_000 || f.Connections[0].Mbps != 8 {
+		t.Errorf("Expected 1 MB at 8 Mbit/s on connection 0, got %+v", f.Connections[0])
+	}
+	if f.Connections[2].Share != 0 || f.MinShare != 0 || f.MaxShare != 0.8 {
+		t.Errorf("Unexpected shares: %+v", f)
+	}
+	// (8 + 2)^2 / (3 × (64 + 4)) = 100 / 204
+	if math.Abs(f.Index-100.0/204) > 1e-9 {
+		t.Errorf("Index = %.4f, want %.4f", f.Index, 100.0/204)
+	}
+}
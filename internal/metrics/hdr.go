@@ -259,8 +259,8 @@ func (h *HDRMetrics) GetNetworkStats() NetworkStats {
 		PacketsReceived: h.packetsReceived,
 		PacketsLost:     h.packetsSent - h.packetsReceived,
 		LossPercent:     lossPercent,
-		BytesSent:       h.bytesSent,
-		BytesReceived:   h.bytesReceived,
+		BytesSent:       ByteCount(h.bytesSent),
+		BytesReceived:   ByteCount(h.bytesReceived),
 		Retransmits:     h.retransmits,
 		Errors:          h.errors,
 	}
@@ -405,8 +405,8 @@ type NetworkStats struct {
 	PacketsReceived int64   `json:"packets_received"`
 	PacketsLost     int64   `json:"packets_lost"`
 	LossPercent     float64 `json:"loss_percent"`
-	BytesSent       int64   `json:"bytes_sent"`
-	BytesReceived   int64   `json:"bytes_received"`
+	BytesSent       ByteCount `json:"bytes_sent"`
+	BytesReceived   ByteCount `json:"bytes_received"`
 	Retransmits     int64   `json:"retransmits"`
 	Errors          int64   `json:"errors"`
 }
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// WithLabels возвращает gatherer, который добавляет labels ко всем сериям g,
+// включая метрики Go runtime и процесса. Собственная метка серии с тем же
+// именем сохраняется. Без меток возвращает g.
+func WithLabels(g prometheus.Gatherer, labels map[string]string) prometheus.Gatherer {
+	if len(labels) == 0 {
+		return g
+	}
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, family := range families {
+			for _, metric := range family.Metric {
+				metric.Label = addLabels(metric.Label, labels)
+			}
+		}
+		return families, err
+	})
+}
+
+// addLabels дополняет пары метками, которых в них нет, и сортирует по имени,
+// как того требует формат экспорта
+func addLabels(pairs []*dto.LabelPair, labels map[string]string) []*dto.LabelPair {
+	own := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		own[pair.GetName()] = true
+	}
+	for name, value := range labels {
+		if !own[name] {
+			pairs = append(pairs, &dto.LabelPair{Name: &name, Value: &value})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].GetName() < pairs[j].GetName()
+	})
+	return pairs
+}
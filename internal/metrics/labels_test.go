@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestWithLabelsOnExportedSeries(t *testing.T) {
+	reg := NewRegistry()
+	results := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_results_total",
+		Help: "Results",
+	}, []string{"result", "workload"})
+	results.WithLabelValues("ok", "own").Add(3)
+	reg.MustRegister(results)
+
+	labels := map[string]string{"workload": "checkout", "tenant": "a"}
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(WithLabels(reg, labels), promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	text := string(body)
+
+	if !strings.Contains(text, `test_results_total{result="ok",tenant="a",workload="own"} 3`) {
+		t.Errorf("Expected the run labels beside the series' own labels, got:\n%s", grep(text, "test_results_total"))
+	}
+	// Runtime and process series are labeled too
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "go_goroutines") && !strings.Contains(line, `tenant="a",workload="checkout"`) {
+			t.Errorf("Expected the run labels on %q", line)
+		}
+	}
+	if strings.Count(text, `tenant="a"`) < 2 {
+		t.Errorf("Expected the labels on every series, got:\n%s", text)
+	}
+}
+
+func TestWithLabelsWithoutLabels(t *testing.T) {
+	reg := NewRegistry()
+	if WithLabels(reg, nil) != prometheus.Gatherer(reg) {
+		t.Error("Expected the registry itself when there are no labels")
+	}
+}
+
+// grep returns the lines of text containing s
+func grep(text, s string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, s) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,234 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Фазы клиентского теста в порядке следования
+const (
+	PhaseHandshake = "handshake" // до завершения handshake всех соединений
+	PhaseWarmup    = "warmup"    // прогрев после handshake (slow start)
+	PhaseSteady    = "steady"    // установившийся режим
+	PhaseDrain     = "drain"     // завершение в конце теста
+)
+
+// PhaseBoundary отмечает начало фазы на временных рядах (секунды с начала теста)
+type PhaseBoundary struct {
+	Phase string  `json:"phase"`
+	Time  float64 `json:"time"`
+}
+
+// PhaseStats — метрики, собранные за одну фазу
+type PhaseStats struct {
+	Phase          string    `json:"phase"`
+	Start          float64   `json:"start"` // секунды с начала теста
+	End            float64   `json:"end"`
+	Packets        int64     `json:"packets"`
+	Errors         int64     `json:"errors"`
+	BytesSent      ByteCount `json:"bytes_sent"`
+	AvgRTTMs       float64   `json:"avg_rtt_ms"`
+	P95RTTMs       float64   `json:"p95_rtt_ms"`
+	ThroughputKBps float64   `json:"throughput_kbps"`
+}
+
+type phaseAccumulator struct {
+	packets, errors, bytes int64
+	rtts                   []float64
+}
+
+// PhaseTracker относит пакеты и ошибки к фазе по времени их регистрации.
+// Handshake длится, пока все соединения не завершат handshake (успешно или
+// нет), затем warmup длительностью warmup, steady и, за drain до конца
+// теста, drain.
+type PhaseTracker struct {
+	mu          sync.Mutex
+	start       time.Time
+	connections int
+	warmup      time.Duration
+	drainStart  time.Duration // 0 — без фазы drain
+
+	ready         int
+	handshakeEnd  time.Duration
+	handshakeDone bool
+
+	phases map[string]*phaseAccumulator
+}
+
+// NewPhaseTracker создает трекер фаз. Фаза drain появляется, только если
+// известна длительность теста (duration > 0) и drain меньше нее.
+func NewPhaseTracker(start time.Time, connections int, warmup, duration, drain time.Duration) *PhaseTracker {
+	t := &PhaseTracker{
+		start:       start,
+		connections: connections,
+		warmup:      warmup,
+		phases:      make(map[string]*phaseAccumulator),
+	}
+	if duration > 0 && drain > 0 && drain < duration {
+		t.drainStart = duration - drain
+	}
+	if connections <= 0 {
+		t.handshakeDone = true
+	}
+	return t
+}
+
+// ConnectionReady отмечает завершение handshake одного соединения
+func (t *PhaseTracker) ConnectionReady(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ready++
+	if !t.handshakeDone && t.ready >= t.connections {
+		t.handshakeDone = true
+		t.handshakeEnd = now.Sub(t.start)
+	}
+}
+
+// PhaseAt возвращает фазу, к которой относится момент now
+func (t *PhaseTracker) PhaseAt(now time.Time) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.phaseAtLocked(now.Sub(t.start))
+}
+
+func (t *PhaseTracker) phaseAtLocked(elapsed time.Duration) string {
+	switch {
+	case t.drainStart > 0 && elapsed >= t.drainStart:
+		return PhaseDrain
+	case !t.handshakeDone || elapsed < t.handshakeEnd:
+		return PhaseHandshake
+	case elapsed < t.handshakeEnd+t.warmup:
+		return PhaseWarmup
+	default:
+		return PhaseSteady
+	}
+}
+
+func (t *PhaseTracker) accumulatorLocked(now time.Time) *phaseAccumulator {
+	phase := t.phaseAtLocked(now.Sub(t.start))
+	acc := t.phases[phase]
+	if acc == nil {
+		acc = &phaseAccumulator{}
+		t.phases[phase] = acc
+	}
+	return acc
+}
+
+// RecordPacket учитывает отправленный пакет и его RTT
+func (t *PhaseTracker) RecordPacket(now time.Time, bytes int, rttMs float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	acc := t.accumulatorLocked(now)
+	acc.packets++
+	acc.bytes += int64(bytes)
+	acc.rtts = append(acc.rtts, rttMs)
+}
+
+// RecordSent учитывает отправленный пакет, RTT которого станет известен
+// позже (см. RecordRTT)
+func (t *PhaseTracker) RecordSent(now time.Time, bytes int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	acc := t.accumulatorLocked(now)
+	acc.packets++
+	acc.bytes += int64(bytes)
+}
+
+// RecordRTT учитывает RTT пакета, измеренный в момент now
+func (t *PhaseTracker) RecordRTT(now time.Time, rttMs float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	acc := t.accumulatorLocked(now)
+	acc.rtts = append(acc.rtts, rttMs)
+}
+
+// RecordError учитывает ошибку
+func (t *PhaseTracker) RecordError(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.accumulatorLocked(now).errors++
+}
+
+// intervalsLocked возвращает границы фаз [start, end) в секундах на момент now
+func (t *PhaseTracker) intervalsLocked(now time.Time) []PhaseStats {
+	end := now.Sub(t.start)
+	drainStart := end
+	if t.drainStart > 0 && t.drainStart < end {
+		drainStart = t.drainStart
+	}
+	handshakeEnd := drainStart
+	if t.handshakeDone && t.handshakeEnd < drainStart {
+		handshakeEnd = t.handshakeEnd
+	}
+	warmupEnd := handshakeEnd + t.warmup
+	if warmupEnd > drainStart {
+		warmupEnd = drainStart
+	}
+
+	bounds := []struct {
+		phase      string
+		start, end time.Duration
+	}{
+		{PhaseHandshake, 0, handshakeEnd},
+		{PhaseWarmup, handshakeEnd, warmupEnd},
+		{PhaseSteady, warmupEnd, drainStart},
+		{PhaseDrain, drainStart, end},
+	}
+	intervals := make([]PhaseStats, 0, len(bounds))
+	for _, b := range bounds {
+		if b.end <= b.start && t.phases[b.phase] == nil {
+			continue
+		}
+		intervals = append(intervals, PhaseStats{Phase: b.phase, Start: b.start.Seconds(), End: b.end.Seconds()})
+	}
+	return intervals
+}
+
+// Boundaries возвращает начала фаз для разметки временных рядов
+func (t *PhaseTracker) Boundaries(now time.Time) []PhaseBoundary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	intervals := t.intervalsLocked(now)
+	boundaries := make([]PhaseBoundary, len(intervals))
+	for i, in := range intervals {
+		boundaries[i] = PhaseBoundary{Phase: in.Phase, Time: in.Start}
+	}
+	return boundaries
+}
+
+// Summary возвращает метрики по фазам на момент now
+func (t *PhaseTracker) Summary(now time.Time) []PhaseStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.intervalsLocked(now)
+	for i := range stats {
+		acc := t.phases[stats[i].Phase]
+		if acc == nil {
+			continue
+		}
+		s := &stats[i]
+		s.Packets, s.Errors, s.BytesSent = acc.packets, acc.errors, ByteCount(acc.bytes)
+		if len(acc.rtts) > 0 {
+			sorted := append([]float64(nil), acc.rtts...)
+			sort.Float64s(sorted)
+			sum := 0.0
+			for _, rtt := range sorted {
+				sum += rtt
+			}
+			s.AvgRTTMs = sum / float64(len(sorted))
+			s.P95RTTMs = sorted[min(len(sorted)*95/100, len(sorted)-1)]
+		}
+		if d := s.End - s.Start; d > 0 {
+			s.ThroughputKBps = float64(acc.bytes) / 1024.0 / d
+		}
+	}
+	return stats
+}
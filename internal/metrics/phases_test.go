@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPhaseTrackerAttribution(t *testing.T) {
+	start := time.Unix(1000, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	// 2 соединения, прогрев 1с, тест 10с, drain 2с
+	tracker := NewPhaseTracker(start, 2, time.Second, 10*time.Second, 2*time.Second)
+
+	tracker.RecordError(at(100))
+	tracker.ConnectionReady(at(200))
+	tracker.RecordPacket(at(300), 100, 50) // второе соединение еще в handshake
+	tracker.ConnectionReady(at(500))
+
+	tracker.RecordPacket(at(600), 200, 40)
+	tracker.RecordPacket(at(1400), 200, 30)
+	tracker.RecordPacket(at(1500), 1000, 10) // warmup закончился в 1.5с
+	tracker.RecordPacket(at(7999), 1000, 10)
+	tracker.RecordPacket(at(8000), 500, 20)
+	tracker.RecordError(at(9000))
+
+	for ms, want := range map[int]string{
+		0: PhaseHandshake, 499: PhaseHandshake, 500: PhaseWarmup, 1499: PhaseWarmup,
+		1500: PhaseSteady, 7999: PhaseSteady, 8000: PhaseDrain, 12000: PhaseDrain,
+	} {
+		if got := tracker.PhaseAt(at(ms)); got != want {
+			t.Errorf("PhaseAt(%dms) = %s, want %s", ms, got, want)
+		}
+	}
+
+	stats := tracker.Summary(at(10000))
+	want := []PhaseStats{
+		{Phase: PhaseHandshake, Start: 0, End: 0.5, Packets: 1, Errors: 1, BytesSent: 100, AvgRTTMs: 50},
+		{Phase: PhaseWarmup, Start: 0.5, End: 1.5, Packets: 2, BytesSent: 400, AvgRTTMs: 35},
+		{Phase: PhaseSteady, Start: 1.5, End: 8, Packets: 2, BytesSent: 2000, AvgRTTMs: 10},
+		{Phase: PhaseDrain, Start: 8, End: 10, Packets: 1, Errors: 1, BytesSent: 500, AvgRTTMs: 20},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("Expected %d phases, got %+v", len(want), stats)
+	}
+	for i, w := range want {
+		s := stats[i]
+		if s.Phase != w.Phase || s.Start != w.Start || s.End != w.End || s.Packets != w.Packets ||
+			s.Errors != w.Errors || s.BytesSent != w.BytesSent || s.AvgRTTMs != w.AvgRTTMs {
+			t.Errorf("Phase %d: got %+v, want %+v", i, s, w)
+		}
+	}
+	// Throughput считается по длительности фазы
+	if got, want := stats[1].ThroughputKBps, 400/1024.0; got != want {
+		t.Errorf("Expected warmup throughput %.3f KB/s, got %.3f", want, got)
+	}
+	if got, want := stats[2].ThroughputKBps, 2000/1024.0/6.5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected steady throughput %.3f KB/s, got %.3f", want, got)
+	}
+
+	boundaries := tracker.Boundaries(at(10000))
+	wantBoundaries := []PhaseBoundary{{PhaseHandshake, 0}, {PhaseWarmup, 0.5}, {PhaseSteady, 1.5}, {PhaseDrain, 8}}
+	if len(boundaries) != len(wantBoundaries) {
+		t.Fatalf("Expected boundaries %+v, got %+v", wantBoundaries, boundaries)
+	}
+	for i := range wantBoundaries {
+		if boundaries[i] != wantBoundaries[i] {
+			t.Errorf("Boundary %d: got %+v, want %+v", i, boundaries[i], wantBoundaries[i])
+		}
+	}
+}
+
+func TestPhaseTrackerWithoutDrainOrWarmup(t *testing.T) {
+	start := time.Unix(1000, 0)
+	// Длительность не задана: drain невозможен
+	tracker := NewPhaseTracker(start, 1, 0, 0, time.Second)
+
+	tracker.RecordPacket(start.Add(10*time.Millisecond), 100, 5)
+	if got := tracker.PhaseAt(start.Add(time.Hour)); got != PhaseHandshake {
+		t.Errorf("Expected handshake until the connection is ready, got %s", got)
+	}
+	tracker.ConnectionReady(start.Add(100 * time.Millisecond))
+	tracker.RecordPacket(start.Add(time.Hour), 100, 5)
+
+	stats := tracker.Summary(start.Add(2 * time.Hour))
+	if len(stats) != 2 || stats[0].Phase != PhaseHandshake || stats[1].Phase != PhaseSteady {
+		t.Fatalf("Expected handshake and steady phases only, got %+v", stats)
+	}
+	if stats[1].Start != 0.1 || stats[1].Packets != 1 {
+		t.Errorf("Unexpected steady phase %+v", stats[1])
+	}
+}
@@ -155,3 +155,18 @@ func TestPrometheusMetricsInvalidTypes(t *testing.T) {
 	// Если мы дошли до этого места, значит паники не было
 	t.Log("Successfully handled all method calls without panic")
 }
+
+func TestNewRegistryIsolation(t *testing.T) {
+	// Каждый registry принимает свой набор метрик без конфликтов имен
+	first := NewPrometheusMetrics(NewRegistry())
+	second := NewPrometheusMetrics(NewRegistry())
+
+	first.AddBytesReceived(2048)
+
+	if testutil.ToFloat64(first.BytesReceived) != 2048 {
+		t.Errorf("Expected bytes received to be 2048, got %f", testutil.ToFloat64(first.BytesReceived))
+	}
+	if testutil.ToFloat64(second.BytesReceived) != 0 {
+		t.Errorf("Expected bytes received to be 0, got %f", testutil.ToFloat64(second.BytesReceived))
+	}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rawLatencyHeader — заголовок CSV с сырыми RTT
+var rawLatencyHeader = []string{"timestamp", "rtt_ms"}
+
+// RawLatencyWriter пишет каждый замер RTT строкой CSV по мере поступления.
+// Замеры не хранятся в памяти и не зависят от расчета процентилей, поэтому
+// файл подходит для анализа распределения в pandas или R.
+type RawLatencyWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	csv  *csv.Writer
+	rows int64
+	err  error // первая ошибка записи
+}
+
+// NewRawLatencyWriter создает файл path и пишет заголовок
+func NewRawLatencyWriter(path string) (*RawLatencyWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raw latency file: %w", err)
+	}
+	// csv.Writer буферизует строки и пишет их в файл блоками
+	w := &RawLatencyWriter{file: f, csv: csv.NewWriter(f)}
+	if err := w.csv.Write(rawLatencyHeader); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write raw latency file: %w", err)
+	}
+	return w, nil
+}
+
+// Record дописывает замер rtt, полученный в момент at
+func (w *RawLatencyWriter) Record(at time.Time, rtt time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return
+	}
+	ms := float64(rtt.Nanoseconds()) / 1e6
+	if err := w.csv.Write([]string{at.UTC().Format(time.RFC3339Nano), strconv.FormatFloat(ms, 'f', -1, 64)}); err != nil {
+		w.err = err
+		return
+	}
+	w.rows++
+}
+
+// Rows возвращает число записанных замеров без заголовка
+func (w *RawLatencyWriter) Rows() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rows
+}
+
+// Close сбрасывает буфер на диск и закрывает файл; возвращает первую
+// ошибку записи
+func (w *RawLatencyWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.csv.Flush()
+	if w.err == nil {
+		w.err = w.csv.Error()
+	}
+	if err := w.file.Close(); w.err == nil {
+		w.err = err
+	}
+	if w.err != nil {
+		return fmt.Errorf("failed to write raw latency file: %w", w.err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRawLatencyWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rtt.csv")
+	w, err := NewRawLatencyWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		w.Record(start.Add(time.Duration(i)*time.Millisecond), time.Duration(i)*time.Microsecond)
+	}
+	if w.Rows() != 1000 {
+		t.Errorf("Expected 1000 rows, got %d", w.Rows())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1001 {
+		t.Fatalf("Expected a header and 1000 rows, got %d rows", len(rows))
+	}
+	if rows[0][0] != "timestamp" || rows[0][1] != "rtt_ms" {
+		t.Errorf("Unexpected header %v", rows[0])
+	}
+	if rows[2][0] != "2026-01-02T03:04:05.001Z" || rows[2][1] != "0.001" {
+		t.Errorf("Unexpected sample row %v", rows[2])
+	}
+}
@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// NewRegistry создает отдельный registry с метриками Go runtime и процесса.
+// Каждый экспортер регистрирует свои метрики в переданном registry, поэтому
+// несколько экспортеров в одном процессе (тесты, несколько серверов) не
+// конфликтуют из-за повторной регистрации в prometheus.DefaultRegisterer.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
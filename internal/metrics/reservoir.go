@@ -0,0 +1,50 @@
+package metrics
+
+import "math/rand"
+
+// Reservoir хранит равномерную случайную выборку фиксированного размера из
+// потока значений (алгоритм R). Память ограничена size, а перцентили по
+// выборке приближают перцентили всего потока. При size <= 0 хранятся все
+// значения. Не потокобезопасен: вызывающий код держит свою блокировку.
+type Reservoir struct {
+	size   int
+	seen   int64
+	values []float64
+	rnd    *rand.Rand
+}
+
+// NewReservoir создает выборку размера size; seed задает генератор замен
+func NewReservoir(size int, seed int64) *Reservoir {
+	return &Reservoir{
+		size: size,
+		rnd:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Add учитывает значение: пока выборка не заполнена, значение добавляется,
+// затем замещает случайный элемент с вероятностью size/seen
+func (r *Reservoir) Add(v float64) {
+	r.seen++
+	if r.size <= 0 || len(r.values) < r.size {
+		r.values = append(r.values, v)
+		return
+	}
+	if j := r.rnd.Int63n(r.seen); j < int64(r.size) {
+		r.values[j] = v
+	}
+}
+
+// Values возвращает текущую выборку (без копирования)
+func (r *Reservoir) Values() []float64 {
+	return r.values
+}
+
+// Count возвращает число значений, прошедших через выборку
+func (r *Reservoir) Count() int64 {
+	return r.seen
+}
+
+// Sampled сообщает, были ли значения отброшены, т.е. перцентили приближенные
+func (r *Reservoir) Sampled() bool {
+	return r.seen > int64(len(r.values))
+}
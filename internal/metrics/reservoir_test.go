@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestReservoirPercentiles(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	all := make([]float64, 200000)
+	r := NewReservoir(10000, 42)
+	for i := range all {
+		// Задержки с длинным хвостом: 5 мс база + экспоненциальная часть
+		all[i] = 5 + rnd.ExpFloat64()*20
+		r.Add(all[i])
+	}
+
+	if got := len(r.Values()); got != 10000 {
+		t.Fatalf("Expected 10000 retained samples, got %d", got)
+	}
+	if r.Count() != int64(len(all)) || !r.Sampled() {
+		t.Errorf("Expected count %d and sampled=true, got %d and %v", len(all), r.Count(), r.Sampled())
+	}
+
+	sample := append([]float64(nil), r.Values()...)
+	sort.Float64s(all)
+	sort.Float64s(sample)
+	for _, p := range []float64{50, 95, 99} {
+		exact := all[int(float64(len(all))*p/100)]
+		estimated := sample[int(float64(len(sample))*p/100)]
+		if diff := math.Abs(estimated-exact) / exact; diff > 0.05 {
+			t.Errorf("p%.0f: estimated %.2f, exact %.2f (%.1f%% off)", p, estimated, exact, diff*100)
+		}
+	}
+}
+
+func TestReservoirUnbounded(t *testing.T) {
+	r := NewReservoir(0, 1)
+	for i := 0; i < 1000; i++ {
+		r.Add(float64(i))
+	}
+	if len(r.Values()) != 1000 || r.Sampled() {
+		t.Errorf("Expected all 1000 values kept, got %d (sampled=%v)", len(r.Values()), r.Sampled())
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// defaultSizeBucketWidth — ширина корзины гистограммы размеров (байт)
+const defaultSizeBucketWidth = 128
+
+// SizeBucket — число пакетов с размером в диапазоне [From, To]
+type SizeBucket struct {
+	From  int   `json:"from"`
+	To    int   `json:"to"`
+	Count int64 `json:"count"`
+}
+
+// SizeSummary описывает распределение фактически отправленных размеров
+type SizeSummary struct {
+	Count   int64        `json:"count"`
+	Min     int          `json:"min"`
+	Max     int          `json:"max"`
+	Mean    float64      `json:"mean"`
+	StdDev  float64      `json:"stddev"`
+	Buckets []SizeBucket `json:"buckets,omitempty"`
+}
+
+// SizeHistogram собирает статистику размеров пакетов без хранения
+// отдельных значений: счетчики по корзинам, сумма и сумма квадратов
+type SizeHistogram struct {
+	mu          sync.Mutex
+	bucketWidth int
+	buckets     map[int]int64
+	count       int64
+	min, max    int
+	sum, sumSq  float64
+}
+
+// NewSizeHistogram создает гистограмму; bucketWidth <= 0 заменяется умолчанием
+func NewSizeHistogram(bucketWidth int) *SizeHistogram {
+	if bucketWidth <= 0 {
+		bucketWidth = defaultSizeBucketWidth
+	}
+	return &SizeHistogram{
+		bucketWidth: bucketWidth,
+		buckets:     make(map[int]int64),
+	}
+}
+
+// Record учитывает один пакет размером size байт
+func (h *SizeHistogram) Record(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 || size < h.min {
+		h.min = size
+	}
+	if size > h.max {
+		h.max = size
+	}
+	h.count++
+	h.sum += float64(size)
+	h.sumSq += float64(size) * float64(size)
+	h.buckets[size/h.bucketWidth]++
+}
+
+// Summary возвращает сводку по распределению; корзины упорядочены по размеру
+func (h *SizeHistogram) Summary() SizeSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := SizeSummary{Count: h.count, Min: h.min, Max: h.max}
+	if h.count == 0 {
+		return s
+	}
+	s.Mean = h.sum / float64(h.count)
+	if variance := h.sumSq/float64(h.count) - s.Mean*s.Mean; variance > 0 {
+		s.StdDev = math.Sqrt(variance)
+	}
+
+	for idx, count := range h.buckets {
+		s.Buckets = append(s.Buckets, SizeBucket{
+			From:  idx * h.bucketWidth,
+			To:    (idx+1)*h.bucketWidth - 1,
+			Count: count,
+		})
+	}
+	sort.Slice(s.Buckets, func(i, j int) bool { return s.Buckets[i].From < s.Buckets[j].From })
+	return s
+}
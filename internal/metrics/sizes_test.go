@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSizeHistogramSummary(t *testing.T) {
+	h := NewSizeHistogram(0)
+	for i := 0; i < 30; i++ {
+		h.Record(64)
+	}
+	for i := 0; i < 70; i++ {
+		h.Record(1400)
+	}
+
+	s := h.Summary()
+	if s.Count != 100 || s.Min != 64 || s.Max != 1400 {
+		t.Fatalf("Expected 100 sizes in [64, 1400], got %+v", s)
+	}
+	wantMean := 0.3*64 + 0.7*1400
+	if math.Abs(s.Mean-wantMean) > 1e-9 {
+		t.Errorf("Expected mean %.1f, got %.1f", wantMean, s.Mean)
+	}
+	// Для двух значений stddev = |a-b| * sqrt(p*(1-p))
+	wantStdDev := (1400 - 64) * math.Sqrt(0.3*0.7)
+	if math.Abs(s.StdDev-wantStdDev) > 1e-6 {
+		t.Errorf("Expected stddev %.1f, got %.1f", wantStdDev, s.StdDev)
+	}
+
+	if len(s.Buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %+v", s.Buckets)
+	}
+	if s.Buckets[0].From != 0 || s.Buckets[0].Count != 30 {
+		t.Errorf("Unexpected first bucket %+v", s.Buckets[0])
+	}
+	if s.Buckets[1].From != 1280 || s.Buckets[1].To != 1407 || s.Buckets[1].Count != 70 {
+		t.Errorf("Unexpected second bucket %+v", s.Buckets[1])
+	}
+}
+
+func TestSizeHistogramEmpty(t *testing.T) {
+	s := NewSizeHistogram(64).Summary()
+	if s.Count != 0 || s.Mean != 0 || len(s.Buckets) != 0 {
+		t.Errorf("Expected empty summary, got %+v", s)
+	}
+}
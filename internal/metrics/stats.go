@@ -0,0 +1,59 @@
+package metrics
+
+import "math"
+
+// SampleStats — описательная статистика выборки задержек
+type SampleStats struct {
+	Count  int64   `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+}
+
+// RunningStats накапливает min/max/среднее/стандартное отклонение за один
+// проход без хранения значений (алгоритм Уэлфорда). Нулевое значение готово
+// к использованию; синхронизация — на вызывающей стороне.
+type RunningStats struct {
+	count    int64
+	min, max float64
+	mean, m2 float64
+}
+
+// Add добавляет значение в выборку
+func (s *RunningStats) Add(x float64) {
+	s.count++
+	if s.count == 1 {
+		s.min, s.max = x, x
+	} else {
+		s.min = math.Min(s.min, x)
+		s.max = math.Max(s.max, x)
+	}
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+}
+
+// Stats возвращает текущую статистику; StdDev — стандартное отклонение
+// генеральной совокупности, как у jitter в отчетах
+func (s *RunningStats) Stats() SampleStats {
+	if s.count == 0 {
+		return SampleStats{}
+	}
+	return SampleStats{
+		Count:  s.count,
+		Min:    s.min,
+		Max:    s.max,
+		Mean:   s.mean,
+		StdDev: math.Sqrt(s.m2 / float64(s.count)),
+	}
+}
+
+// Summarize вычисляет статистику готовой выборки за один проход
+func Summarize(values []float64) SampleStats {
+	var s RunningStats
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s.Stats()
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarizeKnownInputs(t *testing.T) {
+	// Классический пример: среднее 5, stddev генеральной совокупности 2
+	s := Summarize([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if s.Count != 8 || s.Min != 2 || s.Max != 9 {
+		t.Fatalf("Expected 8 values in [2, 9], got %+v", s)
+	}
+	if math.Abs(s.Mean-5) > 1e-12 || math.Abs(s.StdDev-2) > 1e-12 {
+		t.Errorf("Expected mean 5 and stddev 2, got %+v", s)
+	}
+
+	if s := Summarize([]float64{42}); s.Min != 42 || s.Max != 42 || s.Mean != 42 || s.StdDev != 0 {
+		t.Errorf("Expected a single value without deviation, got %+v", s)
+	}
+	if s := Summarize(nil); s != (SampleStats{}) {
+		t.Errorf("Expected zero stats for an empty sample, got %+v", s)
+	}
+}
+
+func TestRunningStatsNegativeAndLargeOffset(t *testing.T) {
+	// Большое смещение ломает наивную формулу через сумму квадратов,
+	// но не алгоритм Уэлфорда
+	var s RunningStats
+	for _, v := range []float64{-1, 1, -1, 1} {
+		s.Add(1e9 + v)
+	}
+	got := s.Stats()
+	if got.Min != 1e9-1 || got.Max != 1e9+1 {
+		t.Errorf("Expected range [1e9-1, 1e9+1], got [%f, %f]", got.Min, got.Max)
+	}
+	if math.Abs(got.Mean-1e9) > 1e-6 || math.Abs(got.StdDev-1) > 1e-6 {
+		t.Errorf("Expected mean 1e9 and stddev 1, got %+v", got)
+	}
+
+	// Нулевое значение не должно попадать в min при отрицательных данных
+	var neg RunningStats
+	neg.Add(-3)
+	neg.Add(-5)
+	if st := neg.Stats(); st.Min != -5 || st.Max != -3 || st.StdDev != 1 {
+		t.Errorf("Expected [-5, -3] with stddev 1, got %+v", st)
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import "sync"
+
+// Коды предупреждений прогона
+const (
+	WarningShortDuration     = "short_duration"     // тест короче времени выхода на режим
+	WarningUDPBuffer         = "udp_buffer"         // буфер сокета не установлен или урезан ОС
+	WarningUDPRecvDrops      = "udp_recv_drops"     // ядро отбрасывало датаграммы до QUIC
+	WarningCongestionControl = "congestion_control" // алгоритм недоступен, используется стандартный
+	WarningEmulation         = "emulation"          // эмуляция работает у своих пределов
+	WarningNoEchoReplies     = "no_echo_replies"    // сервер не ответил ни на один пакет --echo
+)
+
+// Warning — некритичное обстоятельство прогона, которое влияет на трактовку
+// результатов
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Count   int64  `json:"count"` // сколько раз возникло (например, по соединениям)
+}
+
+// Warnings собирает предупреждения прогона в порядке появления; одинаковые
+// предупреждения (код и текст) объединяются. Методы безопасны для nil
+type Warnings struct {
+	mu    sync.Mutex
+	list  []Warning
+	index map[Warning]int
+}
+
+// Add учитывает предупреждение и возвращает true, если оно новое, чтобы
+// вызывающий выводил его в лог один раз
+func (w *Warnings) Add(code, message string) bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := Warning{Code: code, Message: message}
+	if i, ok := w.index[key]; ok {
+		w.list[i].Count++
+		return false
+	}
+	if w.index == nil {
+		w.index = make(map[Warning]int)
+	}
+	w.index[key] = len(w.list)
+	key.Count = 1
+	w.list = append(w.list, key)
+	return true
+}
+
+// List возвращает копию собранных предупреждений
+func (w *Warnings) List() []Warning {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.list...)
+}
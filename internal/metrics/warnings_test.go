@@ -0,0 +1,30 @@
+package metrics
+
+import "testing"
+
+func TestWarningsMergeDuplicates(t *testing.T) {
+	var w Warnings
+	if !w.Add(WarningUDPBuffer, "clamped") {
+		t.Error("Expected the first warning to be new")
+	}
+	w.Add(WarningShortDuration, "short")
+	if w.Add(WarningUDPBuffer, "clamped") {
+		t.Error("Expected a repeated warning to be merged")
+	}
+
+	list := w.List()
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 warnings, got %+v", list)
+	}
+	if list[0].Code != WarningUDPBuffer || list[0].Count != 2 {
+		t.Errorf("Expected the buffer warning first with count 2, got %+v", list[0])
+	}
+	if list[1].Code != WarningShortDuration || list[1].Count != 1 {
+		t.Errorf("Expected the short-duration warning with count 1, got %+v", list[1])
+	}
+
+	var none *Warnings
+	if none.Add(WarningEmulation, "x") || none.List() != nil {
+		t.Error("Expected a nil collector to ignore warnings")
+	}
+}
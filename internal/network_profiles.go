@@ -230,6 +230,7 @@ func PrintNetworkProfile(profile *NetworkProfile) {
 func ApplyNetworkProfile(cfg *TestConfig, profile *NetworkProfile) {
 	cfg.EmulateLoss = profile.Loss
 	cfg.EmulateLatency = profile.Latency
+	cfg.EmulateJitter = profile.Jitter
 	cfg.EmulateDup = profile.Duplication
 	
 	// Адаптируем параметры теста под профиль
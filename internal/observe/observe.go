@@ -0,0 +1,285 @@
+// Package observe implements synthetic monitoring of an external QUIC
+// server: lightweight periodic probes instead of a full load test.
+package observe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	quic "github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// DefaultInterval is used when Config.Interval is not set
+const DefaultInterval = 10 * time.Second
+
+// probePayload is written on the probe stream. It fits into a single packet
+// so the server's acknowledgement gives one clean RTT sample.
+var probePayload = []byte("quic-test observe probe")
+
+// Config configures an Observer
+type Config struct {
+	Addr      string
+	Interval  time.Duration
+	Timeout   time.Duration // per probe; defaults to Interval
+	TLSConfig *tls.Config   // defaults to verifying against the system roots with the quic-test ALPN
+}
+
+// ProbeResult is the outcome of a single probe
+type ProbeResult struct {
+	Time          time.Time
+	HandshakeTime time.Duration
+	StreamRTT     time.Duration
+	Err           error
+}
+
+// Metrics summarizes all probes run so far
+type Metrics struct {
+	Probes          int64     `json:"probes"`
+	Failures        int64     `json:"failures"`
+	LastProbe       time.Time `json:"last_probe"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastHandshakeMs float64   `json:"last_handshake_ms"`
+	LastStreamRTTMs float64   `json:"last_stream_rtt_ms"`
+	AvgHandshakeMs  float64   `json:"avg_handshake_ms"`
+	AvgStreamRTTMs  float64   `json:"avg_stream_rtt_ms"`
+}
+
+// Observer periodically probes a QUIC server and exports the results
+type Observer struct {
+	cfg Config
+
+	mu      sync.Mutex
+	metrics Metrics
+
+	probes    *prometheus.CounterVec
+	handshake prometheus.Gauge
+	streamRTT prometheus.Gauge
+	up        prometheus.Gauge
+}
+
+// NewObserver creates an observer and registers its metrics with reg
+func NewObserver(cfg Config, reg prometheus.Registerer) *Observer {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = cfg.Interval
+	}
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = defaultTLSConfig()
+	}
+
+	factory := promauto.With(reg)
+	return &Observer{
+		cfg: cfg,
+		probes: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "quic_observe_probes_total",
+			Help: "Probes run against the observed server",
+		}, []string{"result"}),
+		handshake: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "quic_observe_handshake_seconds",
+			Help: "Handshake duration of the last successful probe",
+		}),
+		streamRTT: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "quic_observe_stream_rtt_seconds",
+			Help: "Single-stream RTT of the last successful probe",
+		}),
+		up: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "quic_observe_up",
+			Help: "1 if the last probe succeeded, 0 otherwise",
+		}),
+	}
+}
+
+// defaultTLSConfig is used when no TLS config is given. It verifies the
+// server certificate; for a self-signed test server pass a config with its
+// CA in RootCAs or, explicitly, InsecureSkipVerify.
+func defaultTLSConfig() *tls.Config {
+	return &tls.Config{
+		NextProtos: []string{"quic-test"},
+	}
+}
+
+// Run probes the server immediately and then every Interval until ctx is done.
+// onProbe, if not nil, is called after every probe.
+func (o *Observer) Run(ctx context.Context, onProbe func(ProbeResult)) {
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		result := o.Probe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if onProbe != nil {
+			onProbe(result)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe runs one probe: a handshake followed by a single small stream write
+// whose acknowledgement gives the RTT. The result is recorded in the metrics
+// unless ctx was cancelled while probing.
+func (o *Observer) Probe(ctx context.Context) ProbeResult {
+	probeCtx, cancel := context.WithTimeout(ctx, o.cfg.Timeout)
+	defer cancel()
+
+	result := ProbeResult{Time: time.Now()}
+	result.HandshakeTime, result.StreamRTT, result.Err = o.probe(probeCtx)
+	if ctx.Err() == nil {
+		o.record(result)
+	}
+	return result
+}
+
+func (o *Observer) probe(ctx context.Context) (time.Duration, time.Duration, error) {
+	tracker := newAckTracker()
+	quicConf := &quic.Config{
+		HandshakeIdleTimeout: o.cfg.Timeout,
+		Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+			return tracker.tracer()
+		},
+	}
+
+	start := time.Now()
+	conn, err := quic.DialAddr(ctx, o.cfg.Addr, o.cfg.TLSConfig, quicConf)
+	if err != nil {
+		return 0, 0, fmt.Errorf("handshake: %w", err)
+	}
+	handshake := time.Since(start)
+	defer conn.CloseWithError(0, "probe done")
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return handshake, 0, fmt.Errorf("open stream: %w", err)
+	}
+	tracker.watch(stream.StreamID())
+	if _, err := stream.Write(probePayload); err != nil {
+		return handshake, 0, fmt.Errorf("stream write: %w", err)
+	}
+	stream.Close()
+
+	select {
+	case rtt := <-tracker.rtt:
+		return handshake, rtt, nil
+	case <-ctx.Done():
+		return handshake, 0, fmt.Errorf("stream ack: %w", ctx.Err())
+	}
+}
+
+func (o *Observer) record(result ProbeResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.metrics.Probes++
+	o.metrics.LastProbe = result.Time
+	if result.Err != nil {
+		o.metrics.Failures++
+		o.metrics.LastError = result.Err.Error()
+		o.probes.WithLabelValues("failure").Inc()
+		o.up.Set(0)
+		return
+	}
+
+	handshakeMs := float64(result.HandshakeTime.Microseconds()) / 1000
+	rttMs := float64(result.StreamRTT.Microseconds()) / 1000
+	succeeded := float64(o.metrics.Probes - o.metrics.Failures)
+	o.metrics.LastError = ""
+	o.metrics.LastHandshakeMs = handshakeMs
+	o.metrics.LastStreamRTTMs = rttMs
+	o.metrics.AvgHandshakeMs += (handshakeMs - o.metrics.AvgHandshakeMs) / succeeded
+	o.metrics.AvgStreamRTTMs += (rttMs - o.metrics.AvgStreamRTTMs) / succeeded
+
+	o.probes.WithLabelValues("success").Inc()
+	o.handshake.Set(result.HandshakeTime.Seconds())
+	o.streamRTT.Set(result.StreamRTT.Seconds())
+	o.up.Set(1)
+}
+
+// GetMetrics returns a snapshot of the probe metrics
+func (o *Observer) GetMetrics() Metrics {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.metrics
+}
+
+// ackTracker measures the RTT of the probe stream from the tracer: the time
+// between sending the first packet carrying the stream and receiving the ACK
+// for it, minus the ACK delay reported by the peer. The server does not need
+// to answer on the stream, so any quic-test compatible server can be probed.
+type ackTracker struct {
+	mu       sync.Mutex
+	watching bool
+	streamID quic.StreamID
+	sent     bool
+	pn       logging.PacketNumber
+	sentAt   time.Time
+
+	rtt chan time.Duration
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{rtt: make(chan time.Duration, 1)}
+}
+
+func (t *ackTracker) watch(id quic.StreamID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.watching = true
+	t.streamID = id
+}
+
+func (t *ackTracker) tracer() *logging.ConnectionTracer {
+	return &logging.ConnectionTracer{
+		SentShortHeaderPacket: func(hdr *logging.ShortHeader, _ logging.ByteCount, _ logging.ECN, _ *logging.AckFrame, frames []logging.Frame) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.watching || t.sent {
+				return
+			}
+			for _, f := range frames {
+				if sf, ok := f.(*logging.StreamFrame); ok && sf.StreamID == t.streamID {
+					t.sent = true
+					t.pn = hdr.PacketNumber
+					t.sentAt = time.Now()
+					return
+				}
+			}
+		},
+		ReceivedShortHeaderPacket: func(_ *logging.ShortHeader, _ logging.ByteCount, _ logging.ECN, frames []logging.Frame) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.sent {
+				return
+			}
+			for _, f := range frames {
+				ack, ok := f.(*logging.AckFrame)
+				if !ok || !ack.AcksPacket(t.pn) {
+					continue
+				}
+				rtt := time.Since(t.sentAt)
+				if rtt > ack.DelayTime {
+					rtt -= ack.DelayTime
+				}
+				t.sent, t.watching = false, false
+				select {
+				case t.rtt <- rtt:
+				default:
+				}
+				return
+			}
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package observe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	quic "github.com/quic-go/quic-go"
+)
+
+// startSink starts a QUIC listener that, like the quic-test server, reads
+// streams without answering on them. It returns the listener address and a
+// client TLS config that trusts the listener's self-signed certificate.
+func startSink(t *testing.T) (string, *tls.Config) {
+	t.Helper()
+	certPEM, keyPEM := internal.GenerateSelfSignedTLS()
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"quic-test"}}, nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					str, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go io.Copy(io.Discard, str)
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), &tls.Config{RootCAs: roots, NextProtos: []string{"quic-test"}}
+}
+
+func TestProbePopulatesMetrics(t *testing.T) {
+	addr, tlsConf := startSink(t)
+	reg := prometheus.NewRegistry()
+	observer := NewObserver(Config{Addr: addr, Interval: 50 * time.Millisecond, Timeout: 2 * time.Second, TLSConfig: tlsConf}, reg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var results []ProbeResult
+	observer.Run(ctx, func(r ProbeResult) {
+		results = append(results, r)
+		if len(results) == 3 {
+			cancel()
+		}
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 probes, got %d", len(results))
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("probe %d failed: %v", i, r.Err)
+		}
+		if r.HandshakeTime <= 0 || r.StreamRTT <= 0 {
+			t.Errorf("probe %d: expected positive timings, got handshake %v rtt %v", i, r.HandshakeTime, r.StreamRTT)
+		}
+	}
+
+	m := observer.GetMetrics()
+	if m.Probes != 3 || m.Failures != 0 {
+		t.Errorf("Expected 3 probes without failures, got %+v", m)
+	}
+	if m.AvgHandshakeMs <= 0 || m.AvgStreamRTTMs <= 0 {
+		t.Errorf("Expected averages to be populated, got %+v", m)
+	}
+	if got := testutil.ToFloat64(observer.probes.WithLabelValues("success")); got != 3 {
+		t.Errorf("Expected 3 successful probes exported, got %f", got)
+	}
+	if testutil.ToFloat64(observer.up) != 1 || testutil.ToFloat64(observer.handshake) <= 0 {
+		t.Error("Expected up and handshake gauges to be set")
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	// A bound UDP socket that never answers
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	observer := NewObserver(Config{Addr: conn.LocalAddr().String(), Timeout: 200 * time.Millisecond}, prometheus.NewRegistry())
+	result := observer.Probe(context.Background())
+	if result.Err == nil {
+		t.Fatal("Expected probe against a silent address to fail")
+	}
+
+	m := observer.GetMetrics()
+	if m.Probes != 1 || m.Failures != 1 || m.LastError == "" {
+		t.Errorf("Expected one recorded failure, got %+v", m)
+	}
+	if testutil.ToFloat64(observer.up) != 0 {
+		t.Error("Expected up gauge to be 0 after a failed probe")
+	}
+}
+
+func TestRecordMeasuresLoopback(t *testing.T) {
+	addr, tlsConf := startSink(t)
+	rec, err := Record(context.Background(), RecordConfig{Addr: addr, Duration: 300 * time.Millisecond, Rate: 100, TLSConfig: tlsConf})
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if rec.PacketsSent < 10 {
+		t.Errorf("Expected a packet per write, got %d packets", rec.PacketsSent)
+	}
+	if rec.PacketsLost != 0 {
+		t.Errorf("Expected no loss on loopback, got %d lost", rec.PacketsLost)
+	}
+	if len(rec.RTTs) == 0 {
+		t.Fatal("Expected RTT samples")
+	}
+	for _, rtt := range rec.RTTs {
+		if rtt <= 0 || rtt > time.Second {
+			t.Errorf("Expected a loopback rtt, got %v", rtt)
+		}
+	}
+}
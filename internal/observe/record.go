@@ -0,0 +1,195 @@
+package observe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	quic "github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// DefaultRecordRate is the number of writes per second used when
+// RecordConfig.Rate is not set
+const DefaultRecordRate = 50
+
+// recordAckWait bounds how long Record waits for outstanding packets to be
+// acknowledged or declared lost after the last write
+const recordAckWait = time.Second
+
+// RecordConfig configures a Record run
+type RecordConfig struct {
+	Addr      string
+	Duration  time.Duration
+	Rate      int         // writes per second; defaults to DefaultRecordRate
+	TLSConfig *tls.Config // defaults to verifying against the system roots with the quic-test ALPN
+}
+
+// Recording holds the raw link measurements taken by Record
+type Recording struct {
+	RTTs        []time.Duration // one sample per ACK, ACK delay subtracted
+	PacketsSent int             // 1-RTT packets carrying stream data
+	PacketsLost int             // of PacketsSent, declared lost by loss detection
+}
+
+// Record measures the link to a quic-test compatible server: it keeps one
+// connection open for cfg.Duration, writes a small payload on one stream
+// cfg.Rate times per second, and takes RTT and loss from the connection's
+// loss recovery. Like Probe, it needs no answer from the server.
+func Record(ctx context.Context, cfg RecordConfig) (Recording, error) {
+	if cfg.Rate <= 0 {
+		cfg.Rate = DefaultRecordRate
+	}
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = defaultTLSConfig()
+	}
+
+	rec := newLossRecorder()
+	quicConf := &quic.Config{
+		Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+			return rec.tracer()
+		},
+	}
+	conn, err := quic.DialAddr(ctx, cfg.Addr, cfg.TLSConfig, quicConf)
+	if err != nil {
+		return Recording{}, fmt.Errorf("handshake: %w", err)
+	}
+	defer conn.CloseWithError(0, "record done")
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return Recording{}, fmt.Errorf("open stream: %w", err)
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(cfg.Rate))
+	defer ticker.Stop()
+	deadline := time.After(cfg.Duration)
+writing:
+	for {
+		// Each write is flushed into its own packet before the next tick
+		if _, err := stream.Write(probePayload); err != nil {
+			return Recording{}, fmt.Errorf("stream write: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return Recording{}, ctx.Err()
+		case <-deadline:
+			break writing
+		case <-ticker.C:
+		}
+	}
+	stream.Close()
+
+	wait := time.NewTimer(recordAckWait)
+	defer wait.Stop()
+	select {
+	case <-rec.drained():
+	case <-wait.C:
+	case <-ctx.Done():
+		return Recording{}, ctx.Err()
+	}
+	return rec.recording(), nil
+}
+
+// lossRecorder follows every 1-RTT packet carrying stream data until it is
+// acknowledged or declared lost. An RTT sample is taken when an ACK newly
+// acknowledges its largest packet, as in RFC 9002 section 5.1.
+type lossRecorder struct {
+	mu          sync.Mutex
+	outstanding map[logging.PacketNumber]time.Time
+	rtts        []time.Duration
+	sent, lost  int
+	closing     bool
+	done        chan struct{}
+}
+
+func newLossRecorder() *lossRecorder {
+	return &lossRecorder{
+		outstanding: make(map[logging.PacketNumber]time.Time),
+		done:        make(chan struct{}),
+	}
+}
+
+func (r *lossRecorder) tracer() *logging.ConnectionTracer {
+	return &logging.ConnectionTracer{
+		SentShortHeaderPacket: func(hdr *logging.ShortHeader, _ logging.ByteCount, _ logging.ECN, _ *logging.AckFrame, frames []logging.Frame) {
+			for _, f := range frames {
+				if _, ok := f.(*logging.StreamFrame); ok {
+					r.mu.Lock()
+					r.outstanding[hdr.PacketNumber] = time.Now()
+					r.sent++
+					r.mu.Unlock()
+					return
+				}
+			}
+		},
+		ReceivedShortHeaderPacket: func(_ *logging.ShortHeader, _ logging.ByteCount, _ logging.ECN, frames []logging.Frame) {
+			now := time.Now()
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			for _, f := range frames {
+				ack, ok := f.(*logging.AckFrame)
+				if !ok {
+					continue
+				}
+				if sentAt, ok := r.outstanding[ack.LargestAcked()]; ok {
+					rtt := now.Sub(sentAt)
+					if rtt > ack.DelayTime {
+						rtt -= ack.DelayTime
+					}
+					r.rtts = append(r.rtts, rtt)
+				}
+				for pn := range r.outstanding {
+					if ack.AcksPacket(pn) {
+						delete(r.outstanding, pn)
+					}
+				}
+			}
+			r.checkDrainedLocked()
+		},
+		LostPacket: func(level logging.EncryptionLevel, pn logging.PacketNumber, _ logging.PacketLossReason) {
+			if level != logging.Encryption1RTT {
+				return
+			}
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if _, ok := r.outstanding[pn]; ok {
+				delete(r.outstanding, pn)
+				r.lost++
+			}
+			r.checkDrainedLocked()
+		},
+	}
+}
+
+// drained returns a channel closed once no packet sent so far is outstanding
+func (r *lossRecorder) drained() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closing = true
+	r.checkDrainedLocked()
+	return r.done
+}
+
+func (r *lossRecorder) checkDrainedLocked() {
+	if !r.closing || len(r.outstanding) > 0 {
+		return
+	}
+	select {
+	case <-r.done:
+	default:
+		close(r.done)
+	}
+}
+
+func (r *lossRecorder) recording() Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Recording{
+		RTTs:        append([]time.Duration(nil), r.rtts...),
+		PacketsSent: r.sent,
+		PacketsLost: r.lost,
+	}
+}
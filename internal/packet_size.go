@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Виды распределения размеров пакетов для --packet-size
+const (
+	PacketSizeFixed   = "fixed"   // fixed:1200 (или просто 1200)
+	PacketSizeUniform = "uniform" // uniform:64-1400 — равномерно в диапазоне
+	PacketSizeBimodal = "bimodal" // bimodal:64:1400:0.3 — 30% пакетов по 64 байта, остальные по 1400
+)
+
+// PacketSizeSpec описывает распределение размеров отправляемых пакетов.
+// Для fixed используется Min, для uniform — диапазон [Min, Max],
+// для bimodal Min выбирается с вероятностью Ratio, иначе Max.
+type PacketSizeSpec struct {
+	Kind  string
+	Min   int
+	Max   int
+	Ratio float64
+}
+
+// FixedPacketSize возвращает распределение из одного размера
+func FixedPacketSize(size int) PacketSizeSpec {
+	return PacketSizeSpec{Kind: PacketSizeFixed, Min: size, Max: size}
+}
+
+// ParsePacketSizeSpec разбирает значение --packet-size. Число без префикса
+// означает фиксированный размер, как и раньше.
+func ParsePacketSizeSpec(value string) (PacketSizeSpec, error) {
+	value = strings.TrimSpace(value)
+	kind, args, found := strings.Cut(value, ":")
+	if !found {
+		kind, args = PacketSizeFixed, value
+	}
+
+	var spec PacketSizeSpec
+	var err error
+	switch kind {
+	case PacketSizeFixed:
+		var size int
+		size, err = parseSize(args)
+		spec = FixedPacketSize(size)
+	case PacketSizeUniform:
+		lo, hi, ok := strings.Cut(args, "-")
+		if !ok {
+			return spec, fmt.Errorf("packet size %q: expected uniform:MIN-MAX", value)
+		}
+		spec.Kind = PacketSizeUniform
+		if spec.Min, err = parseSize(lo); err == nil {
+			spec.Max, err = parseSize(hi)
+		}
+	case PacketSizeBimodal:
+		parts := strings.Split(args, ":")
+		if len(parts) != 3 {
+			return spec, fmt.Errorf("packet size %q: expected bimodal:SMALL:LARGE:RATIO", value)
+		}
+		spec.Kind = PacketSizeBimodal
+		if spec.Min, err = parseSize(parts[0]); err == nil {
+			if spec.Max, err = parseSize(parts[1]); err == nil {
+				spec.Ratio, err = strconv.ParseFloat(parts[2], 64)
+			}
+		}
+	default:
+		return spec, fmt.Errorf("packet size %q: unknown distribution %q (fixed, uniform, bimodal)", value, kind)
+	}
+	if err != nil {
+		return spec, fmt.Errorf("packet size %q: %w", value, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return spec, fmt.Errorf("packet size %q: %w", value, err)
+	}
+	return spec, nil
+}
+
+func parseSize(s string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(s))
+}
+
+// Validate проверяет корректность распределения
+func (s PacketSizeSpec) Validate() error {
+	switch s.Kind {
+	case PacketSizeFixed, PacketSizeUniform, PacketSizeBimodal:
+	default:
+		return fmt.Errorf("unknown packet size distribution %q", s.Kind)
+	}
+	if s.Min <= 0 || s.Max <= 0 {
+		return errors.New("packet sizes must be positive")
+	}
+	if s.Min > s.Max {
+		return errors.New("minimum packet size exceeds maximum")
+	}
+	if s.Kind == PacketSizeBimodal && (s.Ratio < 0 || s.Ratio > 1) {
+		return errors.New("bimodal ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Sample выбирает размер очередного пакета; rnd возвращает число из [0, 1)
+func (s PacketSizeSpec) Sample(rnd func() float64) int {
+	switch s.Kind {
+	case PacketSizeUniform:
+		size := s.Min + int(rnd()*float64(s.Max-s.Min+1))
+		if size > s.Max {
+			size = s.Max
+		}
+		return size
+	case PacketSizeBimodal:
+		if rnd() < s.Ratio {
+			return s.Min
+		}
+		return s.Max
+	default:
+		return s.Min
+	}
+}
+
+// Mean возвращает ожидаемый средний размер пакета
+func (s PacketSizeSpec) Mean() float64 {
+	switch s.Kind {
+	case PacketSizeUniform:
+		return float64(s.Min+s.Max) / 2
+	case PacketSizeBimodal:
+		return s.Ratio*float64(s.Min) + (1-s.Ratio)*float64(s.Max)
+	default:
+		return float64(s.Min)
+	}
+}
+
+// String возвращает распределение в формате --packet-size
+// (пустую строку, если распределение не задано)
+func (s PacketSizeSpec) String() string {
+	switch s.Kind {
+	case "":
+		return ""
+	case PacketSizeUniform:
+		return fmt.Sprintf("%s:%d-%d", s.Kind, s.Min, s.Max)
+	case PacketSizeBimodal:
+		return fmt.Sprintf("%s:%d:%d:%s", s.Kind, s.Min, s.Max, strconv.FormatFloat(s.Ratio, 'f', -1, 64))
+	default:
+		return strconv.Itoa(s.Min)
+	}
+}
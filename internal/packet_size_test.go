@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParsePacketSizeSpec(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PacketSizeSpec
+		wantErr bool
+	}{
+		{value: "1200", want: PacketSizeSpec{Kind: PacketSizeFixed, Min: 1200, Max: 1200}},
+		{value: "fixed:512", want: PacketSizeSpec{Kind: PacketSizeFixed, Min: 512, Max: 512}},
+		{value: "uniform:64-1400", want: PacketSizeSpec{Kind: PacketSizeUniform, Min: 64, Max: 1400}},
+		{value: "bimodal:64:1400:0.3", want: PacketSizeSpec{Kind: PacketSizeBimodal, Min: 64, Max: 1400, Ratio: 0.3}},
+		{value: "0", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "uniform:1400-64", wantErr: true},
+		{value: "uniform:64", wantErr: true},
+		{value: "bimodal:64:1400", wantErr: true},
+		{value: "bimodal:64:1400:1.5", wantErr: true},
+		{value: "normal:1200", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePacketSizeSpec(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePacketSizeSpec(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParsePacketSizeSpec(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPacketSizeSpecString(t *testing.T) {
+	for _, value := range []string{"1200", "uniform:64-1400", "bimodal:64:1400:0.3"} {
+		spec, err := ParsePacketSizeSpec(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spec.String() != value {
+			t.Errorf("String() = %q, want %q", spec.String(), value)
+		}
+	}
+}
+
+// sampleStats возвращает min, max, среднее и долю значений, равных spec.Min
+func sampleStats(spec PacketSizeSpec, n int) (min, max int, mean, minShare float64) {
+	rnd := rand.New(rand.NewSource(42))
+	min, max = math.MaxInt, 0
+	var sum float64
+	var atMin int
+	for i := 0; i < n; i++ {
+		size := spec.Sample(rnd.Float64)
+		if size < min {
+			min = size
+		}
+		if size > max {
+			max = size
+		}
+		if size == spec.Min {
+			atMin++
+		}
+		sum += float64(size)
+	}
+	return min, max, sum / float64(n), float64(atMin) / float64(n)
+}
+
+func TestPacketSizeSampleFixed(t *testing.T) {
+	min, max, mean, _ := sampleStats(FixedPacketSize(1200), 1000)
+	if min != 1200 || max != 1200 || mean != 1200 {
+		t.Errorf("fixed: got min %d max %d mean %.1f, want 1200", min, max, mean)
+	}
+}
+
+func TestPacketSizeSampleUniform(t *testing.T) {
+	spec := PacketSizeSpec{Kind: PacketSizeUniform, Min: 64, Max: 1400}
+	min, max, mean, _ := sampleStats(spec, 100000)
+
+	if min < 64 || max > 1400 {
+		t.Errorf("uniform: sizes out of range [%d, %d]", min, max)
+	}
+	// На 100k выборок крайние значения должны встретиться
+	if min != 64 || max != 1400 {
+		t.Errorf("uniform: expected bounds to be reached, got [%d, %d]", min, max)
+	}
+	if math.Abs(mean-spec.Mean()) > 10 {
+		t.Errorf("uniform: mean %.1f, want about %.1f", mean, spec.Mean())
+	}
+}
+
+func TestPacketSizeSampleBimodal(t *testing.T) {
+	spec := PacketSizeSpec{Kind: PacketSizeBimodal, Min: 64, Max: 1400, Ratio: 0.3}
+	min, max, mean, smallShare := sampleStats(spec, 100000)
+
+	if min != 64 || max != 1400 {
+		t.Errorf("bimodal: got sizes in [%d, %d], want only 64 and 1400", min, max)
+	}
+	if math.Abs(smallShare-0.3) > 0.01 {
+		t.Errorf("bimodal: small share %.3f, want about 0.3", smallShare)
+	}
+	if math.Abs(mean-spec.Mean()) > 15 {
+		t.Errorf("bimodal: mean %.1f, want about %.1f", mean, spec.Mean())
+	}
+}
+
+func TestPacketSizeDistributionDefaultsToFixed(t *testing.T) {
+	cfg := TestConfig{PacketSize: 800}
+	if got := cfg.PacketSizeDistribution(); got != FixedPacketSize(800) {
+		t.Errorf("PacketSizeDistribution() = %+v, want fixed 800", got)
+	}
+
+	cfg.PacketSizes = PacketSizeSpec{Kind: PacketSizeUniform, Min: 100, Max: 50}
+	cfg.Connections, cfg.Streams, cfg.Duration, cfg.Rate = 1, 1, 1, 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted an invalid packet size distribution")
+	}
+}
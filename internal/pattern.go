@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Проверка шаблона данных (--pattern zeroes | increment) на сервере.
+//
+// Поток теста с проверяемым шаблоном начинается с управляющего заголовка,
+// по которому сервер узнает, что ожидать, а пакеты в нем идут с длиной:
+//
+//	"PATTERN1" | шаблон (1 байт) | (длина пакета (uint32, big endian) | пакет)...
+//
+// Пакет — номер (uint64, little endian) и байты шаблона: нули или i%256 для
+// смещения i в пакете. Без заголовка (--pattern random) поток остается
+// прежним. Пакеты теста начинаются с номера 1, а загрузка — с "QTUPLOAD",
+// поэтому заголовок ни с тем, ни с другим не путается.
+const patternMagic = "PATTERN1"
+
+const (
+	patternHeaderSize = len(patternMagic) + 1
+	// PatternFrameOverhead — байты длины перед каждым пакетом
+	PatternFrameOverhead = 4
+	// maxPatternPacket ограничивает длину пакета, чтобы испорченная длина не
+	// приводила к огромному выделению памяти
+	maxPatternPacket = 1 << 20
+	patternSeqSize   = 8
+)
+
+// Коды шаблонов в заголовке
+var patternCodes = map[string]byte{"zeroes": 1, "increment": 2}
+
+// ErrPatternFraming — длина пакета испорчена, дальше поток проверить нельзя
+var ErrPatternFraming = errors.New("corrupted pattern packet length")
+
+// PatternVerifiable сообщает, может ли сервер проверить шаблон
+func PatternVerifiable(pattern string) bool {
+	_, ok := patternCodes[pattern]
+	return ok
+}
+
+// IsPatternPrefix сообщает, что начало потока совпадает с заголовком
+// проверяемого шаблона (prefix может быть короче заголовка)
+func IsPatternPrefix(prefix []byte) bool {
+	if len(prefix) == 0 {
+		return false
+	}
+	if len(prefix) > len(patternMagic) {
+		prefix = prefix[:len(patternMagic)]
+	}
+	return bytes.HasPrefix([]byte(patternMagic), prefix)
+}
+
+// PatternHeader возвращает заголовок потока с шаблоном pattern
+func PatternHeader(pattern string) ([]byte, error) {
+	code, ok := patternCodes[pattern]
+	if !ok {
+		return nil, fmt.Errorf("pattern %q cannot be verified", pattern)
+	}
+	return append([]byte(patternMagic), code), nil
+}
+
+// ReadPatternHeader читает заголовок из r и возвращает шаблон потока
+func ReadPatternHeader(r io.Reader) (string, error) {
+	header := make([]byte, patternHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", fmt.Errorf("failed to read pattern header: %w", err)
+	}
+	if string(header[:len(patternMagic)]) != patternMagic {
+		return "", errors.New("not a pattern stream")
+	}
+	for pattern, code := range patternCodes {
+		if code == header[len(patternMagic)] {
+			return pattern, nil
+		}
+	}
+	return "", fmt.Errorf("unknown pattern code %d", header[len(patternMagic)])
+}
+
+// FramePatternPacket возвращает пакет с длиной для потока с заголовком
+func FramePatternPacket(packet []byte) []byte {
+	frame := make([]byte, PatternFrameOverhead+len(packet))
+	binary.BigEndian.PutUint32(frame, uint32(len(packet)))
+	copy(frame[PatternFrameOverhead:], packet)
+	return frame
+}
+
+// ReadPatternPacket читает следующий пакет из потока с заголовком. buf
+// используется повторно, если в него помещается пакет.
+func ReadPatternPacket(r io.Reader, buf []byte) ([]byte, error) {
+	var length [PatternFrameOverhead]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n > maxPatternPacket {
+		return nil, fmt.Errorf("%w: %d bytes", ErrPatternFraming, n)
+	}
+	if int(n) > cap(buf) {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// PatternResult — итог проверки одного пакета
+type PatternResult int
+
+const (
+	PatternOK        PatternResult = iota // содержимое и номер в порядке
+	PatternCorrupted                      // байты не совпали с шаблоном
+	PatternReordered                      // номер меньше уже принятого
+	PatternDuplicate                      // повтор уже принятого номера (например, --emulate-dup)
+)
+
+// PatternStats — итог проверки пакетов одного или нескольких потоков
+type PatternStats struct {
+	Packets    int64 `json:"packets"`    // проверенные пакеты
+	Corrupted  int64 `json:"corrupted"`  // пакеты, байты которых не совпали с шаблоном
+	Reordered  int64 `json:"reordered"`  // пакеты с номером меньше уже принятого
+	Duplicates int64 `json:"duplicates"` // повторы уже принятого номера
+}
+
+// Record учитывает итог проверки пакета
+func (s *PatternStats) Record(result PatternResult) {
+	s.Packets++
+	switch result {
+	case PatternCorrupted:
+		s.Corrupted++
+	case PatternReordered:
+		s.Reordered++
+	case PatternDuplicate:
+		s.Duplicates++
+	}
+}
+
+// PatternVerifier проверяет пакеты одного потока в порядке приема
+type PatternVerifier struct {
+	pattern string
+	lastSeq uint64
+	stats   PatternStats
+}
+
+// NewPatternVerifier создает проверку потока с шаблоном pattern
+func NewPatternVerifier(pattern string) *PatternVerifier {
+	return &PatternVerifier{pattern: pattern}
+}
+
+// Check проверяет пакет: сначала байты шаблона, затем порядок номеров
+func (v *PatternVerifier) Check(packet []byte) PatternResult {
+	result := v.check(packet)
+	v.stats.Record(result)
+	return result
+}
+
+func (v *PatternVerifier) check(packet []byte) PatternResult {
+	// Номер пишется только в пакеты длиной не меньше его самого
+	start := patternSeqSize
+	if len(packet) < patternSeqSize {
+		start = 0
+	}
+	for i := start; i < len(packet); i++ {
+		if packet[i] != v.expected(i) {
+			// Номер испорченного пакета тоже может быть испорчен
+			return PatternCorrupted
+		}
+	}
+	if len(packet) < patternSeqSize {
+		return PatternOK
+	}
+	seq := binary.LittleEndian.Uint64(packet)
+	switch {
+	case seq == v.lastSeq:
+		return PatternDuplicate
+	case seq < v.lastSeq:
+		return PatternReordered
+	}
+	v.lastSeq = seq
+	return PatternOK
+}
+
+// Stats возвращает итог проверки
+func (v *PatternVerifier) Stats() PatternStats {
+	return v.stats
+}
+
+func (v *PatternVerifier) expected(offset int) byte {
+	if v.pattern == "increment" {
+		return byte(offset % 256)
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// patternPacket собирает пакет так же, как клиент: шаблон и номер в начале
+func patternPacket(pattern string, seq uint64, size int) []byte {
+	packet := make([]byte, size)
+	if pattern == "increment" {
+		for i := range packet {
+			packet[i] = byte(i % 256)
+		}
+	}
+	if size >= 8 {
+		binary.LittleEndian.PutUint64(packet, seq)
+	}
+	return packet
+}
+
+func TestPatternVerifier(t *testing.T) {
+	for _, pattern := range []string{"zeroes", "increment"} {
+		v := NewPatternVerifier(pattern)
+		for seq, size := range []int{1200, 600, 8, 3000} {
+			if got := v.Check(patternPacket(pattern, uint64(seq+1), size)); got != PatternOK {
+				t.Errorf("%s: packet %d of %d bytes: expected ok, got %d", pattern, seq+1, size, got)
+			}
+		}
+
+		corrupted := patternPacket(pattern, 5, 1200)
+		corrupted[700] ^= 0x01
+		if got := v.Check(corrupted); got != PatternCorrupted {
+			t.Errorf("%s: expected a flipped bit to be detected, got %d", pattern, got)
+		}
+		if got := v.Check(patternPacket(pattern, 5, 1200)); got != PatternOK {
+			t.Errorf("%s: expected the resent packet to pass, got %d", pattern, got)
+		}
+		if got := v.Check(patternPacket(pattern, 5, 1200)); got != PatternDuplicate {
+			t.Errorf("%s: expected a duplicate, got %d", pattern, got)
+		}
+		if got := v.Check(patternPacket(pattern, 3, 1200)); got != PatternReordered {
+			t.Errorf("%s: expected an out-of-order packet, got %d", pattern, got)
+		}
+
+		want := PatternStats{Packets: 8, Corrupted: 1, Reordered: 1, Duplicates: 1}
+		if got := v.Stats(); got != want {
+			t.Errorf("%s: expected %+v, got %+v", pattern, want, got)
+		}
+	}
+}
+
+func TestPatternShortPacketHasNoSeq(t *testing.T) {
+	// Пакет короче номера целиком состоит из шаблона
+	v := NewPatternVerifier("increment")
+	if got := v.Check([]byte{0, 1, 2, 3}); got != PatternOK {
+		t.Errorf("Expected a short packet to pass, got %d", got)
+	}
+	if got := v.Check([]byte{0, 1, 9, 3}); got != PatternCorrupted {
+		t.Errorf("Expected a corrupted short packet to be detected, got %d", got)
+	}
+}
+
+func TestPatternFraming(t *testing.T) {
+	header, err := PatternHeader("increment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsUploadPrefix(header) || !IsPatternPrefix(header[:1]) {
+		t.Error("Expected the pattern header to be told apart from an upload")
+	}
+	if IsPatternPrefix(patternPacket("increment", 1, 1200)) {
+		t.Error("Expected a plain test packet not to look like the pattern header")
+	}
+	if _, err := PatternHeader("random"); err == nil {
+		t.Error("Expected random data to have no pattern header")
+	}
+
+	var stream bytes.Buffer
+	stream.Write(header)
+	for seq, size := range []int{1200, 10} {
+		stream.Write(FramePatternPacket(patternPacket("increment", uint64(seq+1), size)))
+	}
+	pattern, err := ReadPatternHeader(&stream)
+	if err != nil || pattern != "increment" {
+		t.Fatalf("Expected the increment pattern, got %q, %v", pattern, err)
+	}
+	var buf []byte
+	for _, size := range []int{1200, 10} {
+		packet, err := ReadPatternPacket(&stream, buf)
+		if err != nil || len(packet) != size {
+			t.Fatalf("Expected a %d-byte packet, got %d bytes, %v", size, len(packet), err)
+		}
+		buf = packet
+	}
+	if _, err := ReadPatternPacket(&stream, buf); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end of the stream, got %v", err)
+	}
+
+	broken := bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0})
+	if _, err := ReadPatternPacket(broken, nil); !errors.Is(err, ErrPatternFraming) {
+		t.Errorf("Expected a corrupted length to be rejected, got %v", err)
+	}
+}
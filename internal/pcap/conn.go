@@ -0,0 +1,110 @@
+package pcap
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// maxCachedPeers bounds the route lookup cache on busy servers
+const maxCachedPeers = 1024
+
+// PacketConn wraps a net.PacketConn and records every datagram it sends
+// and receives. It can be handed to quic.Transport in place of the socket.
+type PacketConn struct {
+	net.PacketConn
+	writer *Writer
+
+	mu         sync.Mutex
+	localAddrs map[string]*net.UDPAddr // peer -> source address chosen by routing
+}
+
+// WrapPacketConn returns conn with capture enabled. A nil writer returns conn unchanged.
+func WrapPacketConn(conn net.PacketConn, writer *Writer) net.PacketConn {
+	if writer == nil {
+		return conn
+	}
+	return &PacketConn{
+		PacketConn: conn,
+		writer:     writer,
+		localAddrs: make(map[string]*net.UDPAddr),
+	}
+}
+
+// ReadFrom reads a datagram and records it as peer -> local
+func (c *PacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(p)
+	if err == nil {
+		c.record(addr, c.localAddrFor(addr), p[:n])
+	}
+	return n, addr, err
+}
+
+// WriteTo sends a datagram and records it as local -> peer
+func (c *PacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(p, addr)
+	if err == nil {
+		c.record(c.localAddrFor(addr), addr, p[:n])
+	}
+	return n, err
+}
+
+// SetReadBuffer forwards to the underlying socket so quic-go can still tune it
+func (c *PacketConn) SetReadBuffer(bytes int) error {
+	if conn, ok := c.PacketConn.(interface{ SetReadBuffer(int) error }); ok {
+		return conn.SetReadBuffer(bytes)
+	}
+	return nil
+}
+
+// SetWriteBuffer forwards to the underlying socket so quic-go can still tune it
+func (c *PacketConn) SetWriteBuffer(bytes int) error {
+	if conn, ok := c.PacketConn.(interface{ SetWriteBuffer(int) error }); ok {
+		return conn.SetWriteBuffer(bytes)
+	}
+	return nil
+}
+
+func (c *PacketConn) record(src, dst net.Addr, payload []byte) {
+	srcUDP, ok1 := src.(*net.UDPAddr)
+	dstUDP, ok2 := dst.(*net.UDPAddr)
+	if !ok1 || !ok2 {
+		return
+	}
+	// Capture failures (including the size cap) must never affect traffic
+	_ = c.writer.WritePacket(time.Now(), srcUDP, dstUDP, payload)
+}
+
+// localAddrFor resolves the source address the kernel uses towards peer when
+// the socket is bound to a wildcard address, so captures show real endpoints
+func (c *PacketConn) localAddrFor(peer net.Addr) net.Addr {
+	local, ok := c.LocalAddr().(*net.UDPAddr)
+	if !ok || !local.IP.IsUnspecified() {
+		return c.LocalAddr()
+	}
+	peerUDP, ok := peer.(*net.UDPAddr)
+	if !ok {
+		return local
+	}
+
+	key := peerUDP.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if addr, ok := c.localAddrs[key]; ok {
+		return addr
+	}
+	if len(c.localAddrs) >= maxCachedPeers {
+		return local
+	}
+
+	addr := local
+	// Connecting a UDP socket sends nothing; it only performs the route lookup
+	if probe, err := net.DialUDP("udp", nil, peerUDP); err == nil {
+		if routed, ok := probe.LocalAddr().(*net.UDPAddr); ok {
+			addr = &net.UDPAddr{IP: routed.IP, Port: local.Port, Zone: routed.Zone}
+		}
+		probe.Close()
+	}
+	c.localAddrs[key] = addr
+	return addr
+}
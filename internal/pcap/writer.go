@@ -0,0 +1,234 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	versionMajor      = 2
+	versionMinor      = 4
+	snapLen           = 65535
+
+	// LinkTypeRaw carries bare IPv4/IPv6 packets without a link-layer header
+	LinkTypeRaw = 101
+
+	globalHeaderLen = 24
+	recordHeaderLen = 16
+	ipv4HeaderLen   = 20
+	ipv6HeaderLen   = 40
+	udpHeaderLen    = 8
+)
+
+// ErrSizeLimit is returned when a packet does not fit under the file size cap
+var ErrSizeLimit = errors.New("pcap size limit reached")
+
+// Writer writes UDP datagrams to a pcap stream, synthesizing IP and UDP
+// headers from the socket addresses so that capture tools can decode them.
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closer   io.Closer
+	maxBytes int64
+	written  int64
+	packets  int64
+	dropped  int64
+}
+
+// NewWriter writes the pcap global header to w. maxBytes caps the total
+// stream size (0 means unlimited); packets beyond the cap are dropped.
+func NewWriter(w io.Writer, maxBytes int64) (*Writer, error) {
+	header := make([]byte, globalHeaderLen)
+	binary.LittleEndian.PutUint32(header[0:4], magicMicroseconds)
+	binary.LittleEndian.PutUint16(header[4:6], versionMajor)
+	binary.LittleEndian.PutUint16(header[6:8], versionMinor)
+	// thiszone and sigfigs stay zero
+	binary.LittleEndian.PutUint32(header[16:20], snapLen)
+	binary.LittleEndian.PutUint32(header[20:24], LinkTypeRaw)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write pcap header: %w", err)
+	}
+
+	return &Writer{
+		w:        w,
+		maxBytes: maxBytes,
+		written:  globalHeaderLen,
+	}, nil
+}
+
+// Create opens a pcap file at path, truncating any existing file
+func Create(path string, maxBytes int64) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pcap file: %w", err)
+	}
+
+	w, err := NewWriter(f, maxBytes)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// WritePacket records one UDP datagram sent from src to dst at ts
+func (w *Writer) WritePacket(ts time.Time, src, dst *net.UDPAddr, payload []byte) error {
+	packet, err := buildIPPacket(src, dst, payload)
+	if err != nil {
+		return err
+	}
+
+	captured := packet
+	if len(captured) > snapLen {
+		captured = captured[:snapLen]
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	size := int64(recordHeaderLen + len(captured))
+	if w.maxBytes > 0 && w.written+size > w.maxBytes {
+		w.dropped++
+		return ErrSizeLimit
+	}
+
+	record := make([]byte, recordHeaderLen, size)
+	binary.LittleEndian.PutUint32(record[0:4], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(record[4:8], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:12], uint32(len(captured)))
+	binary.LittleEndian.PutUint32(record[12:16], uint32(len(packet)))
+	record = append(record, captured...)
+
+	if _, err := w.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write pcap record: %w", err)
+	}
+	w.written += size
+	w.packets++
+	return nil
+}
+
+// Packets returns the number of packets written
+func (w *Writer) Packets() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.packets
+}
+
+// Dropped returns the number of packets skipped because of the size cap
+func (w *Writer) Dropped() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Close closes the underlying file when the writer was created by Create
+func (w *Writer) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// buildIPPacket wraps payload into UDP over IPv4 or IPv6 depending on the addresses
+func buildIPPacket(src, dst *net.UDPAddr, payload []byte) ([]byte, error) {
+	srcIP, dstIP := normalizeIP(src.IP), normalizeIP(dst.IP)
+
+	if src4, dst4 := srcIP.To4(), dstIP.To4(); src4 != nil && dst4 != nil {
+		return buildIPv4(src4, dst4, src.Port, dst.Port, payload), nil
+	}
+
+	src16, dst16 := srcIP.To16(), dstIP.To16()
+	if src16 == nil || dst16 == nil {
+		return nil, fmt.Errorf("invalid addresses %v -> %v", src, dst)
+	}
+	return buildIPv6(src16, dst16, src.Port, dst.Port, payload), nil
+}
+
+// normalizeIP maps a nil address (e.g. an unbound socket) to the IPv4 wildcard
+func normalizeIP(ip net.IP) net.IP {
+	if ip == nil {
+		return net.IPv4zero
+	}
+	return ip
+}
+
+func buildIPv4(src, dst net.IP, srcPort, dstPort int, payload []byte) []byte {
+	total := ipv4HeaderLen + udpHeaderLen + len(payload)
+	packet := make([]byte, total)
+
+	packet[0] = 0x45 // version 4, IHL 5
+	binary.BigEndian.PutUint16(packet[2:4], uint16(total))
+	packet[8] = 64 // TTL
+	packet[9] = 17 // UDP
+	copy(packet[12:16], src)
+	copy(packet[16:20], dst)
+	binary.BigEndian.PutUint16(packet[10:12], checksum(packet[:ipv4HeaderLen], 0))
+
+	// The UDP checksum is optional over IPv4 and left zero
+	writeUDPHeader(packet[ipv4HeaderLen:], srcPort, dstPort, payload)
+	return packet
+}
+
+func buildIPv6(src, dst net.IP, srcPort, dstPort int, payload []byte) []byte {
+	udpLen := udpHeaderLen + len(payload)
+	packet := make([]byte, ipv6HeaderLen+udpLen)
+
+	packet[0] = 0x60 // version 6
+	binary.BigEndian.PutUint16(packet[4:6], uint16(udpLen))
+	packet[6] = 17 // next header: UDP
+	packet[7] = 64 // hop limit
+	copy(packet[8:24], src)
+	copy(packet[24:40], dst)
+
+	udp := packet[ipv6HeaderLen:]
+	writeUDPHeader(udp, srcPort, dstPort, payload)
+
+	// The UDP checksum is mandatory over IPv6
+	pseudo := make([]byte, 40)
+	copy(pseudo[0:16], src)
+	copy(pseudo[16:32], dst)
+	binary.BigEndian.PutUint32(pseudo[32:36], uint32(udpLen))
+	pseudo[39] = 17
+	sum := checksum(udp, sumWords(pseudo))
+	if sum == 0 {
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(udp[6:8], sum)
+	return packet
+}
+
+func writeUDPHeader(b []byte, srcPort, dstPort int, payload []byte) {
+	binary.BigEndian.PutUint16(b[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(b[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint16(b[4:6], uint16(udpHeaderLen+len(payload)))
+	copy(b[udpHeaderLen:], payload)
+}
+
+func sumWords(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+// checksum computes the internet checksum of b on top of an initial partial sum
+func checksum(b []byte, initial uint32) uint16 {
+	sum := initial + sumWords(b)
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,178 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type pcapRecord struct {
+	ts     time.Time
+	packet []byte
+}
+
+// readPcap parses a pcap file and fails the test if it is malformed
+func readPcap(t *testing.T, path string) []pcapRecord {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read pcap: %v", err)
+	}
+	if len(data) < globalHeaderLen {
+		t.Fatalf("pcap too short: %d bytes", len(data))
+	}
+	if magic := binary.LittleEndian.Uint32(data[0:4]); magic != magicMicroseconds {
+		t.Fatalf("Unexpected magic 0x%x", magic)
+	}
+	if link := binary.LittleEndian.Uint32(data[20:24]); link != LinkTypeRaw {
+		t.Fatalf("Unexpected link type %d", link)
+	}
+
+	var records []pcapRecord
+	rest := data[globalHeaderLen:]
+	for len(rest) > 0 {
+		if len(rest) < recordHeaderLen {
+			t.Fatalf("Truncated record header")
+		}
+		sec := binary.LittleEndian.Uint32(rest[0:4])
+		usec := binary.LittleEndian.Uint32(rest[4:8])
+		inclLen := binary.LittleEndian.Uint32(rest[8:12])
+		origLen := binary.LittleEndian.Uint32(rest[12:16])
+		if inclLen > origLen {
+			t.Fatalf("Captured length %d exceeds original %d", inclLen, origLen)
+		}
+		rest = rest[recordHeaderLen:]
+		if uint32(len(rest)) < inclLen {
+			t.Fatalf("Truncated record body")
+		}
+		records = append(records, pcapRecord{
+			ts:     time.Unix(int64(sec), int64(usec)*1000),
+			packet: rest[:inclLen],
+		})
+		rest = rest[inclLen:]
+	}
+	return records
+}
+
+func TestPacketConnCapture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	writer, err := Create(path, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	local, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+
+	conn := WrapPacketConn(local, writer)
+	before := time.Now().Truncate(time.Microsecond)
+
+	const sent, received = 3, 2
+	for i := 0; i < sent; i++ {
+		if _, err := conn.WriteTo([]byte("ping"), peer.LocalAddr()); err != nil {
+			t.Fatalf("WriteTo failed: %v", err)
+		}
+	}
+	for i := 0; i < received; i++ {
+		if _, err := peer.WriteTo([]byte("pong!"), local.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 1500)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := conn.ReadFrom(buf); err != nil {
+			t.Fatalf("ReadFrom failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readPcap(t, path)
+	if len(records) != sent+received {
+		t.Fatalf("Expected %d packets, got %d", sent+received, len(records))
+	}
+
+	localPort := local.LocalAddr().(*net.UDPAddr).Port
+	peerPort := peer.LocalAddr().(*net.UDPAddr).Port
+	for i, r := range records {
+		if r.ts.Before(before) || r.ts.After(time.Now()) {
+			t.Errorf("Packet %d has timestamp %v outside of the test window", i, r.ts)
+		}
+		p := r.packet
+		if p[0]>>4 != 4 || p[9] != 17 {
+			t.Fatalf("Packet %d is not IPv4/UDP", i)
+		}
+		if checksum(p[:ipv4HeaderLen], 0) != 0 {
+			t.Errorf("Packet %d has an invalid IPv4 header checksum", i)
+		}
+		srcPort := int(binary.BigEndian.Uint16(p[20:22]))
+		dstPort := int(binary.BigEndian.Uint16(p[22:24]))
+		wantSrc, wantDst := localPort, peerPort
+		if i >= sent {
+			wantSrc, wantDst = peerPort, localPort
+		}
+		if srcPort != wantSrc || dstPort != wantDst {
+			t.Errorf("Packet %d: expected %d -> %d, got %d -> %d", i, wantSrc, wantDst, srcPort, dstPort)
+		}
+	}
+}
+
+func TestWriterSizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capped.pcap")
+	packetSize := int64(recordHeaderLen + ipv4HeaderLen + udpHeaderLen + 100)
+	writer, err := Create(path, globalHeaderLen+2*packetSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1000}
+	dst := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 2000}
+	for i := 0; i < 5; i++ {
+		writer.WritePacket(time.Now(), src, dst, make([]byte, 100))
+	}
+	writer.Close()
+
+	if writer.Packets() != 2 || writer.Dropped() != 3 {
+		t.Errorf("Expected 2 written and 3 dropped, got %d and %d", writer.Packets(), writer.Dropped())
+	}
+	if records := readPcap(t, path); len(records) != 2 {
+		t.Errorf("Expected 2 packets in file, got %d", len(records))
+	}
+	if info, _ := os.Stat(path); info.Size() > globalHeaderLen+2*packetSize {
+		t.Errorf("File size %d exceeds cap", info.Size())
+	}
+}
+
+func TestWriterIPv6Checksum(t *testing.T) {
+	src := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443}
+	dst := &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 50000}
+	packet, err := buildIPPacket(src, dst, []byte("quic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet[0]>>4 != 6 {
+		t.Fatalf("Expected IPv6 packet")
+	}
+
+	udp := packet[ipv6HeaderLen:]
+	pseudo := make([]byte, 40)
+	copy(pseudo[0:16], packet[8:24])
+	copy(pseudo[16:32], packet[24:40])
+	binary.BigEndian.PutUint32(pseudo[32:36], uint32(len(udp)))
+	pseudo[39] = 17
+	if checksum(udp, sumWords(pseudo)) != 0 {
+		t.Error("Invalid UDP checksum over IPv6")
+	}
+}
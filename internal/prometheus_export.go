@@ -3,6 +3,7 @@ package internal
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -69,19 +70,22 @@ func ExportPrometheusMetrics(cfg TestConfig, metrics map[string]interface{}, fil
 	packetLoss := getFloat64FromSchema(metrics, "PacketLoss") * 100
 	retransmissionRate := getFloat64FromSchema(metrics, "RetransmissionRate") * 100
 
+	// Метки всех серий: алгоритм CC и метки прогона --label
+	labels := promLabels(cfg)
+
 	// Записываем метрики
-	file.WriteString(fmt.Sprintf("quic_test_duration_seconds{cc=\"%s\"} %.2f\n", cfg.CongestionControl, durationSec))
-	file.WriteString(fmt.Sprintf("quic_test_connections_total{cc=\"%s\"} %d\n", cfg.CongestionControl, cfg.Connections))
-	file.WriteString(fmt.Sprintf("quic_test_bytes_sent_total{cc=\"%s\"} %d\n", cfg.CongestionControl, bytesSent))
-	file.WriteString(fmt.Sprintf("quic_test_packets_sent_total{cc=\"%s\"} %d\n", cfg.CongestionControl, success))
-	file.WriteString(fmt.Sprintf("quic_test_errors_total{cc=\"%s\"} %d\n", cfg.CongestionControl, errors))
-	file.WriteString(fmt.Sprintf("quic_test_latency_p50_ms{cc=\"%s\"} %.3f\n", cfg.CongestionControl, rttP50))
-	file.WriteString(fmt.Sprintf("quic_test_latency_p95_ms{cc=\"%s\"} %.3f\n", cfg.CongestionControl, rttP95))
-	file.WriteString(fmt.Sprintf("quic_test_latency_p99_ms{cc=\"%s\"} %.3f\n", cfg.CongestionControl, rttP99))
-	file.WriteString(fmt.Sprintf("quic_test_jitter_ms{cc=\"%s\"} %.3f\n", cfg.CongestionControl, jitter))
-	file.WriteString(fmt.Sprintf("quic_test_throughput_mbps{cc=\"%s\"} %.3f\n", cfg.CongestionControl, throughputMbps))
-	file.WriteString(fmt.Sprintf("quic_test_packet_loss_percent{cc=\"%s\"} %.3f\n", cfg.CongestionControl, packetLoss))
-	file.WriteString(fmt.Sprintf("quic_test_retransmission_rate_percent{cc=\"%s\"} %.3f\n", cfg.CongestionControl, retransmissionRate))
+	file.WriteString(fmt.Sprintf("quic_test_duration_seconds{%s} %.2f\n", labels, durationSec))
+	file.WriteString(fmt.Sprintf("quic_test_connections_total{%s} %d\n", labels, cfg.Connections))
+	file.WriteString(fmt.Sprintf("quic_test_bytes_sent_total{%s} %d\n", labels, bytesSent))
+	file.WriteString(fmt.Sprintf("quic_test_packets_sent_total{%s} %d\n", labels, success))
+	file.WriteString(fmt.Sprintf("quic_test_errors_total{%s} %d\n", labels, errors))
+	file.WriteString(fmt.Sprintf("quic_test_latency_p50_ms{%s} %.3f\n", labels, rttP50))
+	file.WriteString(fmt.Sprintf("quic_test_latency_p95_ms{%s} %.3f\n", labels, rttP95))
+	file.WriteString(fmt.Sprintf("quic_test_latency_p99_ms{%s} %.3f\n", labels, rttP99))
+	file.WriteString(fmt.Sprintf("quic_test_jitter_ms{%s} %.3f\n", labels, jitter))
+	file.WriteString(fmt.Sprintf("quic_test_throughput_mbps{%s} %.3f\n", labels, throughputMbps))
+	file.WriteString(fmt.Sprintf("quic_test_packet_loss_percent{%s} %.3f\n", labels, packetLoss))
+	file.WriteString(fmt.Sprintf("quic_test_retransmission_rate_percent{%s} %.3f\n", labels, retransmissionRate))
 
 	// BBRv3 специфичные метрики
 	if bbrv3Metrics, ok := metrics["BBRv3Metrics"].(map[string]interface{}); ok {
@@ -118,12 +122,12 @@ func ExportPrometheusMetrics(cfg TestConfig, metrics map[string]interface{}, fil
 		headroomUsage := getFloat64FromSchema(bbrv3Metrics, "headroom_usage") * 100
 		pacingGain := getFloat64FromSchema(bbrv3Metrics, "pacing_gain")
 
-		file.WriteString(fmt.Sprintf("quic_bbrv3_phase_current{cc=\"%s\"} %.0f\n", cfg.CongestionControl, phaseValue))
-		file.WriteString(fmt.Sprintf("quic_bbrv3_bandwidth_fast_bps{cc=\"%s\"} %.0f\n", cfg.CongestionControl, bwFast))
-		file.WriteString(fmt.Sprintf("quic_bbrv3_bandwidth_slow_bps{cc=\"%s\"} %.0f\n", cfg.CongestionControl, bwSlow))
-		file.WriteString(fmt.Sprintf("quic_bbrv3_loss_rate_round_percent{cc=\"%s\"} %.4f\n", cfg.CongestionControl, lossRateRound))
-		file.WriteString(fmt.Sprintf("quic_bbrv3_headroom_usage_percent{cc=\"%s\"} %.2f\n", cfg.CongestionControl, headroomUsage))
-		file.WriteString(fmt.Sprintf("quic_bbrv3_pacing_gain{cc=\"%s\"} %.2f\n", cfg.CongestionControl, pacingGain))
+		file.WriteString(fmt.Sprintf("quic_bbrv3_phase_current{%s} %.0f\n", labels, phaseValue))
+		file.WriteString(fmt.Sprintf("quic_bbrv3_bandwidth_fast_bps{%s} %.0f\n", labels, bwFast))
+		file.WriteString(fmt.Sprintf("quic_bbrv3_bandwidth_slow_bps{%s} %.0f\n", labels, bwSlow))
+		file.WriteString(fmt.Sprintf("quic_bbrv3_loss_rate_round_percent{%s} %.4f\n", labels, lossRateRound))
+		file.WriteString(fmt.Sprintf("quic_bbrv3_headroom_usage_percent{%s} %.2f\n", labels, headroomUsage))
+		file.WriteString(fmt.Sprintf("quic_bbrv3_pacing_gain{%s} %.2f\n", labels, pacingGain))
 	}
 
 	file.WriteString(fmt.Sprintf("\n# Timestamp: %s\n", time.Now().Format(time.RFC3339)))
@@ -131,3 +135,20 @@ func ExportPrometheusMetrics(cfg TestConfig, metrics map[string]interface{}, fil
 	return nil
 }
 
+// promLabelEscaper экранирует значение метки для текстового формата Prometheus
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabels возвращает метки серий файла .prom: cc и метки --label в
+// порядке имен. Метка cc не переопределяется меткой прогона.
+func promLabels(cfg TestConfig) string {
+	labels := map[string]string{}
+	for name, value := range cfg.Labels {
+		labels[name] = value
+	}
+	labels["cc"] = cfg.CongestionControl
+	pairs := make([]string, 0, len(labels))
+	for _, name := range sortedLabelNames(labels) {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, promLabelEscaper.Replace(labels[name])))
+	}
+	return strings.Join(pairs, ",")
+}
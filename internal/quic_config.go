@@ -7,6 +7,19 @@ import (
 	"github.com/quic-go/quic-go"
 )
 
+// StreamDataLimitErrorCode — код ошибки приложения, с которым сервер
+// сбрасывает поток, переславший больше --max-stream-data байт
+const StreamDataLimitErrorCode = 0x10
+
+// AcceptQueueFullErrorCode — код ошибки приложения, с которым сервер
+// закрывает соединение, не поместившееся в очередь приема (--accept-backlog)
+const AcceptQueueFullErrorCode = 0x11
+
+// StreamStalledErrorCode — код ошибки приложения, с которым сервер
+// сбрасывает поток, простоявший дольше --stream-read-timeout или
+// --stream-write-timeout
+const StreamStalledErrorCode = 0x12
+
 // CreateQUICConfig создает QUIC конфигурацию на основе параметров теста
 func CreateQUICConfig(cfg TestConfig) *quic.Config {
 	config := &quic.Config{
@@ -73,6 +86,11 @@ func CreateQUICConfig(cfg TestConfig) *quic.Config {
 	config.DisablePathMTUDiscovery = false
 	// DisableVersionNegotiationPackets не поддерживается в текущей версии
 	
+	// --transport-param применяются последними и переопределяют флаги выше
+	if err := ApplyTransportParams(config, cfg.TransportParams); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	
 	return config
 }
 
@@ -98,6 +116,17 @@ func CreateClientQUICConfig(cfg TestConfig) *quic.Config {
 	return config
 }
 
+// CongestionControlWarning возвращает предупреждение, если quic-go не дает
+// выбрать алгоритм --cc и соединения работают со встроенным cubic; "" —
+// алгоритм применяется (bbrv2 и bbrv3 — через экспериментальную интеграцию)
+func CongestionControlWarning(name string) string {
+	switch name {
+	case "", "cubic", "bbrv2", "bbrv3":
+		return ""
+	}
+	return fmt.Sprintf("congestion control %q is not selectable in quic-go, falling back to its built-in cubic", name)
+}
+
 // PrintQUICConfig выводит информацию о настроенных QUIC параметрах
 func PrintQUICConfig(cfg TestConfig) {
 	hasQUICConfig := cfg.CongestionControl != "" || 
@@ -110,7 +139,8 @@ func PrintQUICConfig(cfg TestConfig) {
 		cfg.EnableKeyUpdate || 
 		cfg.EnableDatagrams || 
 		cfg.MaxIncomingStreams > 0 || 
-		cfg.MaxIncomingUniStreams > 0
+		cfg.MaxIncomingUniStreams > 0 ||
+		len(cfg.TransportParams) > 0
 	
 	if hasQUICConfig {
 		fmt.Printf("🔧 QUIC Configuration:\n")
@@ -148,6 +178,9 @@ func PrintQUICConfig(cfg TestConfig) {
 		if cfg.MaxIncomingUniStreams > 0 {
 			fmt.Printf("  - Max Incoming Uni Streams: %d\n", cfg.MaxIncomingUniStreams)
 		}
+		if len(cfg.TransportParams) > 0 {
+			fmt.Printf("  - Transport Parameters (effective): %s\n", FormatTransportParams(EffectiveTransportParams(CreateQUICConfig(cfg))))
+		}
 		
 		fmt.Println()
 	}
@@ -2,13 +2,16 @@ package internal
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"math"
 	"os"
 	"sort"
 	"strings"
+	"time"
+
+	"quic-test/internal/metrics"
 
 	"github.com/fatih/color"
 	"github.com/guptarohit/asciigraph"
@@ -26,6 +29,21 @@ func SaveReport(cfg TestConfig, metrics any) error {
 		filename = fmt.Sprintf("report.%s", format)
 	}
 
+	if format == ReportFormatJSONL {
+		// Снимки пишет ReportStream во время теста, здесь добавляется итог
+		report := makeReportJSON(cfg, metrics)
+		line := ReportLine{Time: time.Now(), Final: true}
+		if schema, ok := report.(ReportSchema); ok {
+			line.Metrics = schema.Metrics
+			line.Report = &schema
+		}
+		if err := appendReportLine(filename, line); err != nil {
+			return fmt.Errorf("ошибка сохранения отчета: %w", err)
+		}
+		color.Green("\n✓ Отчет сохранен: %s", filename)
+		return nil
+	}
+
 	var data []byte
 	var err error
 
@@ -33,14 +51,18 @@ func SaveReport(cfg TestConfig, metrics any) error {
 	case "json":
 		data, err = json.MarshalIndent(makeReportJSON(cfg, metrics), "", "  ")
 	case "csv":
-		return saveCSV(filename, makeReportCSV(cfg, metrics))
+		data, err = makeCSV(makeReportCSV(cfg, metrics))
 	case "md":
 		data = []byte(makeReportMarkdown(cfg, metrics))
 	default:
 		data = []byte(makeReportMarkdown(cfg, metrics))
 	}
 
-	if format != "csv" {
+	if err == nil && cfg.ReportCompress {
+		filename = CompressedReportPath(filename)
+		data, err = gzipBytes(data)
+	}
+	if err == nil {
 		err = os.WriteFile(filename, data, 0600) // Более безопасные права доступа
 	}
 	if err != nil {
@@ -70,20 +92,41 @@ func makeReportJSON(cfg TestConfig, metrics any) any {
 
 func makeReportCSV(cfg TestConfig, metrics any) [][]string {
 	// TODO: реализовать сериализацию в CSV
-	return [][]string{{"param", "value"}, {"mode", cfg.Mode}}
+	metricsMap, _ := metrics.(map[string]interface{})
+	v := ReportVersions(metricsMap)
+	return [][]string{
+		{"param", "value"},
+		{"mode", cfg.Mode},
+		{"suite_version", v.Suite},
+		{"quic_go_version", v.QUICGo},
+		{"quic_version", v.QUICVersion},
+		{"tls_version", v.TLSVersion},
+	}
 }
 
-func saveCSV(filename string, rows [][]string) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
+// CompressedReportPath возвращает имя сжатого отчета: с суффиксом .gz
+func CompressedReportPath(filename string) string {
+	if strings.HasSuffix(filename, ".gz") {
+		return filename
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Printf("Warning: failed to close file %s: %v\n", filename, err)
-		}
-	}()
-	
+	return filename + ".gz"
+}
+
+// gzipBytes сжимает data в формат gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// makeCSV выводит строки таблицей в консоль и возвращает их в формате CSV
+func makeCSV(rows [][]string) ([]byte, error) {
 	// Используем tablewriter для форматированного вывода в консоль
 	table := tablewriter.NewWriter(os.Stdout)
 	if len(rows) > 0 {
@@ -111,21 +154,21 @@ func saveCSV(filename string, rows [][]string) error {
 		}
 	}
 	
-	// Сохраняем в CSV файл
-	w := csv.NewWriter(f)
-	defer w.Flush()
-	return w.WriteAll(rows)
+	var buf bytes.Buffer
+	if err := csv.NewWriter(&buf).WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
-func makeReportMarkdown(cfg TestConfig, metrics any) string {
-	m, ok := metrics.(map[string]interface{})
+func makeReportMarkdown(cfg TestConfig, data any) string {
+	m, ok := data.(map[string]interface{})
 	if !ok {
-		return fmt.Sprintf("# 2GC CloudBridge QUIC testing\n\n**Параметры:** \"%+v\"\n\n**Метрики:** \"%+v\"\n", cfg, metrics)
+		return fmt.Sprintf("# 2GC CloudBridge QUIC testing\n\n**Параметры:** \"%+v\"\n\n**Метрики:** \"%+v\"\n", cfg, data)
 	}
 	latencies, _ := m["Latencies"].([]float64)
 	p50, p95, p99 := calcPercentiles(latencies)
-	jitter := calcJitter(latencies)
-	avg := avgLatency(latencies)
+	stats := metrics.Summarize(latencies)
 
 	tsLatency, _ := m["TimeSeriesLatency"].([]interface{})
 	tsThroughput, _ := m["TimeSeriesThroughput"].([]interface{})
@@ -134,7 +177,43 @@ func makeReportMarkdown(cfg TestConfig, metrics any) string {
 	tsHandshakeTime, _ := m["TimeSeriesHandshakeTime"].([]interface{})
 
 	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf(`# 2GC CloudBridge QUIC testing\n\n**Параметры:** "%+v"\n\n**Метрики:**\n\n- Success: %v\n- Errors: %v\n- BytesSent: %v\n- Avg Latency: %.2f ms\n- p50: %.2f ms\n- p95: %.2f ms\n- p99: %.2f ms\n- Jitter: %.2f ms\n- PacketLoss: %v %%\n- Retransmits: %v\n- TLSVersion: %v\n- CipherSuite: %v\n- SessionResumptionCount: %v\n- 0-RTT: %v\n- 1-RTT: %v\n- OutOfOrder: %v\n- FlowControlEvents: %v\n- KeyUpdateEvents: %v\n- ErrorTypeCounts: %v\n`, cfg, m["Success"], m["Errors"], m["BytesSent"], avg, p50, p95, p99, jitter, m["PacketLoss"], m["Retransmits"], m["TLSVersion"], m["CipherSuite"], m["SessionResumptionCount"], m["ZeroRTTCount"], m["OneRTTCount"], m["OutOfOrderCount"], m["FlowControlEvents"], m["KeyUpdateEvents"], m["ErrorTypeCounts"]))
+	buf.WriteString(fmt.Sprintf(`# 2GC CloudBridge QUIC testing\n\n**Параметры:** "%+v"\n\n**Метрики:**\n\n- Success: %v\n- Errors: %v\n- BytesSent: %v\n- Avg Latency: %.2f ms\n- Min: %.2f ms\n- Max: %.2f ms\n- StdDev: %.2f ms\n- p50: %.2f ms\n- p95: %.2f ms\n- p99: %.2f ms\n- Jitter: %.2f ms\n- PacketLoss: %v %%\n- Retransmits: %v\n- TLSVersion: %v\n- CipherSuite: %v\n- SessionResumptionCount: %v\n- 0-RTT: %v\n- 1-RTT: %v\n- OutOfOrder: %v\n- FlowControlEvents: %v\n- KeyUpdateEvents: %v\n- ErrorTypeCounts: %v\n`, cfg, m["Success"], m["Errors"], m["BytesSent"], stats.Mean, stats.Min, stats.Max, stats.StdDev, p50, p95, p99, stats.StdDev, m["PacketLoss"], m["Retransmits"], m["TLSVersion"], m["CipherSuite"], m["SessionResumptionCount"], m["ZeroRTTCount"], m["OneRTTCount"], m["OutOfOrderCount"], m["FlowControlEvents"], m["KeyUpdateEvents"], m["ErrorTypeCounts"]))
+
+	if len(cfg.Labels) > 0 {
+		buf.WriteString(fmt.Sprintf("- Labels: %s\n", FormatRunLabels(cfg.Labels)))
+	}
+	if recv, send := getInt(m, "UDPRecvBuffer"), getInt(m, "UDPSendBuffer"); recv > 0 || send > 0 {
+		buf.WriteString(fmt.Sprintf("- UDP Socket Buffers: recv %d bytes, send %d bytes\n", recv, send))
+	}
+	if drops := getInt64(m, "UDPRecvDrops"); drops > 0 {
+		buf.WriteString(fmt.Sprintf("- UDP Receive Drops (kernel, before QUIC): %d\n", drops))
+	}
+	if cfg.Echo {
+		buf.WriteString(fmt.Sprintf("- RTT: measured from server echoes (%d packets)\n", getInt64(m, "EchoReplies")))
+	}
+	if h := extractHandshakeMetrics(m); h != nil {
+		buf.WriteString(fmt.Sprintf("- Handshake (%d connections): p50 %.2f ms, p95 %.2f ms, p99 %.2f ms, max %.2f ms", h.Count, h.P50Ms, h.P95Ms, h.P99Ms, h.MaxMs))
+		if cfg.MaxConnectionsPerSecond > 0 {
+			buf.WriteString(fmt.Sprintf(", dial rate %.4g/s", cfg.MaxConnectionsPerSecond))
+		}
+		buf.WriteString("\n")
+	}
+	if len(cfg.TransportParams) > 0 {
+		buf.WriteString(fmt.Sprintf("- Transport Parameters: %s\n", FormatTransportParams(EffectiveTransportParams(CreateQUICConfig(cfg)))))
+	}
+	if cfg.LocalAddr != "" {
+		buf.WriteString(fmt.Sprintf("- Local Addresses: %s\n", strings.Join(getStrings(m, "LocalAddrs"), ", ")))
+	}
+	buf.WriteString(fmt.Sprintf("- Stability: %s\n", stabilityFromMetrics(cfg, m)))
+	buf.WriteString(fmt.Sprintf("- Versions: %s\n", ReportVersions(m)))
+	writeTopErrorsMarkdown(&buf, getErrorSummaries(m, "TopErrors"))
+	writePacketSizesMarkdown(&buf, cfg, getSizeSummary(m, "PacketSizes"))
+	writeEmulationMarkdown(&buf, getEmulationSummary(m, "Emulation"))
+	writeConnectionFairnessMarkdown(&buf, getConnectionFairness(m, "ConnectionFairness"))
+	writeCloseReasonsMarkdown(&buf, getCloseReasons(m, "CloseReasons"))
+	writePhasesMarkdown(&buf, getPhaseStats(m, "Phases"))
+	writeUploadMarkdown(&buf, getUploadResult(m, "Upload"))
+	writeBlastMarkdown(&buf, getBlastResult(m, "Blast"))
 
 	buf.WriteString("\n## Временные ряды (Time Series)\n")
 	buf.WriteString("\n### Latency (ms)\n")
@@ -256,7 +335,7 @@ func asciigraphPlot(data []float64, caption string) string {
 	return graph
 }
 
-// calcPercentiles и calcJitter (дублируем для отчета)
+// calcPercentiles (дублируем для отчета)
 func calcPercentiles(latencies []float64) (p50, p95, p99 float64) {
 	if len(latencies) == 0 {
 		return 0, 0, 0
@@ -290,31 +369,134 @@ func calcPercentilesExtended(latencies []float64) (p50, p95, p99, p999 float64)
 	p999 = copyLat[idx(0.999)]
 	return
 }
-func calcJitter(latencies []float64) float64 {
-	if len(latencies) == 0 {
-		return 0
+
+// writeTopErrorsMarkdown выводит агрегированные ошибки вместо полного списка
+func writeTopErrorsMarkdown(buf *bytes.Buffer, summaries []metrics.ErrorSummary) {
+	if len(summaries) == 0 {
+		return
 	}
-	mean := 0.0
-	for _, l := range latencies {
-		mean += l
+	buf.WriteString("\n## Ошибки (top по количеству)\n")
+	buf.WriteString("| Тип | Количество | Первая | Последняя | Пример |\n|---|---|---|---|---|\n")
+	for _, s := range summaries {
+		sample := ""
+		if len(s.Samples) > 0 {
+			sample = strings.ReplaceAll(s.Samples[0], "|", "\\|")
+		}
+		buf.WriteString(fmt.Sprintf("| %s | %d | %s | %s | %s |\n", s.Type, s.Count,
+			s.FirstSeen.Format(time.RFC3339), s.LastSeen.Format(time.RFC3339), sample))
 	}
-	mean /= float64(len(latencies))
-	var sum float64
-	for _, l := range latencies {
-		d := l - mean
-		sum += d * d
+}
+
+// writePhasesMarkdown добавляет метрики по фазам теста
+func writePhasesMarkdown(buf *bytes.Buffer, phases []metrics.PhaseStats) {
+	if len(phases) == 0 {
+		return
+	}
+	buf.WriteString("\n## Фазы теста\n")
+	buf.WriteString("| Фаза | Время (s) | Пакетов | Ошибок | Байт | Avg RTT (ms) | p95 RTT (ms) | Throughput (KB/s) |\n|---|---|---|---|---|---|---|---|\n")
+	for _, p := range phases {
+		buf.WriteString(fmt.Sprintf("| %s | %.1f-%.1f | %d | %d | %d | %.2f | %.2f | %.2f |\n",
+			p.Phase, p.Start, p.End, p.Packets, p.Errors, p.BytesSent, p.AvgRTTMs, p.P95RTTMs, p.ThroughputKBps))
 	}
-	variance := sum / float64(len(latencies))
-	// Извлекаем квадратный корень для получения стандартного отклонения
-	return math.Sqrt(variance)
 }
-func avgLatency(latencies []float64) float64 {
-	if len(latencies) == 0 {
-		return 0
+
+// writeUploadMarkdown добавляет итог передачи файла (--upload-file)
+func writeUploadMarkdown(buf *bytes.Buffer, u *UploadResult) {
+	if u == nil {
+		return
+	}
+	integrity := "OK"
+	if !u.Verified {
+		integrity = "FAILED"
+	}
+	buf.WriteString("\n## Передача файла\n")
+	buf.WriteString(fmt.Sprintf("- Файл: %s\n", u.Path))
+	buf.WriteString(fmt.Sprintf("- Передано: %d байт за %.2f ms (%.2f Mbps)\n", u.Bytes, u.DurationMs, u.ThroughputMbps))
+	buf.WriteString(fmt.Sprintf("- SHA-256: %s\n", u.Checksum))
+	buf.WriteString(fmt.Sprintf("- Целостность: %s\n", integrity))
+	if u.Error != "" {
+		buf.WriteString(fmt.Sprintf("- Ошибка: %s\n", u.Error))
+	}
+}
+
+// writeBlastMarkdown добавляет итог режима --blast
+func writeBlastMarkdown(buf *bytes.Buffer, b *BlastResult) {
+	if b == nil {
+		return
+	}
+	plateau := "не достигнуто"
+	if b.Plateaued {
+		plateau = fmt.Sprintf("с %.0f s", b.PlateauAtSeconds)
+	}
+	buf.WriteString("\n## Blast (максимальная пропускная способность)\n")
+	buf.WriteString(fmt.Sprintf("- Максимум: %.2f Mbps\n", b.MaxThroughputMbps))
+	buf.WriteString(fmt.Sprintf("- Устойчиво: %.2f Mbps\n", b.SustainedThroughputMbps))
+	buf.WriteString(fmt.Sprintf("- Плато: %s\n", plateau))
+	buf.WriteString(fmt.Sprintf("- CPU: %.2f s за %.2f s (%.2f из %.0f ядер)\n", b.CPUSeconds, b.WallSeconds, b.CPUCores, b.CPUCoreLimit))
+	buf.WriteString(fmt.Sprintf("- Ограничение: %s\n", b.Bound))
+}
+
+// writeConnectionFairnessMarkdown выводит доли полосы соединений и индекс
+// справедливости между ними
+func writeConnectionFairnessMarkdown(buf *bytes.Buffer, f *metrics.ConnectionFairness) {
+	if f == nil {
+		return
+	}
+	buf.WriteString(fmt.Sprintf("\n## Справедливость между соединениями\n- Jain's index: %.3f (1 — поровну, %.3f — полосу занимает одно соединение)\n",
+		f.Index, 1/float64(len(f.Connections))))
+	buf.WriteString("\n| Соединение | Байт | Mbps | Доля |\n|---|---|---|---|\n")
+	for _, c := range f.Connections {
+		buf.WriteString(fmt.Sprintf("| %d | %d | %.2f | %.1f%% |\n", c.ID, c.Bytes, c.Mbps, c.Share*100))
+	}
+}
+
+// writeCloseReasonsMarkdown выводит распределение причин закрытия соединений
+func writeCloseReasonsMarkdown(buf *bytes.Buffer, reasons []metrics.CloseReasonSummary) {
+	if len(reasons) == 0 {
+		return
+	}
+	buf.WriteString("\n## Причины закрытия соединений\n| Причина | Соединений | Закрыто сервером | Коды |\n|---|---|---|---|\n")
+	for _, r := range reasons {
+		codes := make([]string, 0, len(r.Codes))
+		for code, n := range r.Codes {
+			codes = append(codes, fmt.Sprintf("%s×%d", code, n))
+		}
+		sort.Strings(codes)
+		buf.WriteString(fmt.Sprintf("| %s | %d | %d | %s |\n", r.Reason, r.Count, r.Remote, strings.Join(codes, ", ")))
+	}
+}
+
+// writeEmulationMarkdown сравнивает заданные --emulate-* с фактически
+// примененными значениями
+func writeEmulationMarkdown(buf *bytes.Buffer, summary *metrics.EmulationSummary) {
+	if summary == nil {
+		return
+	}
+	buf.WriteString("\n## Эмуляция сети (задано / применено)\n")
+	buf.WriteString("| Параметр | Задано | Применено | События |\n|---|---|---|---|\n")
+	buf.WriteString(fmt.Sprintf("| Потери | %.2f%% | %.2f%% | %d из %d |\n",
+		summary.ConfiguredLoss*100, summary.AppliedLoss*100, summary.Drops, summary.Packets))
+	buf.WriteString(fmt.Sprintf("| Дубликаты | %.2f%% | %.2f%% | %d |\n",
+		summary.ConfiguredDup*100, summary.AppliedDup*100, summary.Dups))
+	buf.WriteString(fmt.Sprintf("| Перестановки | %.2f%% | %.2f%% | %d |\n",
+		summary.ConfiguredReorder*100, summary.AppliedReorder*100, summary.Reorders))
+	buf.WriteString(fmt.Sprintf("| Задержка | %.2f ms | %.2f ms | %.3f s всего |\n",
+		summary.ConfiguredLatencyMs, summary.AppliedLatencyMs, summary.DelaySecondsSum))
+	buf.WriteString(fmt.Sprintf("| Jitter | ±%.2f ms | %.2f ms в среднем | |\n",
+		summary.ConfiguredJitterMs, summary.AppliedJitterMs))
+}
+
+// writePacketSizesMarkdown добавляет распределение отправленных размеров пакетов
+func writePacketSizesMarkdown(buf *bytes.Buffer, cfg TestConfig, summary *metrics.SizeSummary) {
+	if summary == nil {
+		return
 	}
-	sum := 0.0
-	for _, l := range latencies {
-		sum += l
+	buf.WriteString(fmt.Sprintf("\n## Размеры пакетов (%s)\n", cfg.PacketSizeDistribution()))
+	buf.WriteString(fmt.Sprintf("- Отправлено: %d, min %d, max %d, среднее %.1f, stddev %.1f байт\n",
+		summary.Count, summary.Min, summary.Max, summary.Mean, summary.StdDev))
+	buf.WriteString("\n| Размер (байт) | Пакетов | Доля |\n|---|---|---|\n")
+	for _, b := range summary.Buckets {
+		buf.WriteString(fmt.Sprintf("| %d-%d | %d | %.1f%% |\n", b.From, b.To, b.Count,
+			float64(b.Count)/float64(summary.Count)*100))
 	}
-	return sum / float64(len(latencies))
 }
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// ReportFormatJSONL — формат отчета, который пишется во время теста:
+// одна строка JSON со снимком метрик за интервал и итоговая строка
+const ReportFormatJSONL = "jsonl"
+
+// IsStreamingReport сообщает, пишется ли отчет во время теста
+func IsStreamingReport(format string) bool {
+	return strings.EqualFold(format, ReportFormatJSONL)
+}
+
+// ReportLine — строка отчета JSON Lines
+type ReportLine struct {
+	Time           time.Time     `json:"time"`
+	ElapsedSeconds float64       `json:"elapsed_seconds,omitempty"`
+	Final          bool          `json:"final,omitempty"`  // итоговая строка прогона
+	Metrics        MetricsSchema `json:"metrics"`          // накопленные с начала теста
+	Report         *ReportSchema `json:"report,omitempty"` // полный отчет в итоговой строке
+}
+
+// ReportStream дописывает строки отчета JSON Lines в файл --report, пока
+// идет тест. Каждая строка сразу попадает в файл, поэтому отчет можно
+// читать через tail и он не теряется, если процесс завершится аварийно.
+type ReportStream struct {
+	mu    sync.Mutex
+	name  string
+	file  *os.File // nil после Close
+	start time.Time
+}
+
+// OpenReportStream создает файл отчета заново и начинает отсчет времени
+func OpenReportStream(cfg TestConfig) (*ReportStream, error) {
+	filename := cfg.ReportPath
+	if filename == "" {
+		filename = "report." + ReportFormatJSONL
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания отчета: %w", err)
+	}
+	return &ReportStream{name: filename, file: f, start: time.Now()}, nil
+}
+
+// WriteSnapshot дописывает снимок накопленных метрик
+func (s *ReportStream) WriteSnapshot(metrics map[string]interface{}) error {
+	now := time.Now()
+	return s.write(ReportLine{
+		Time:           now,
+		ElapsedSeconds: now.Sub(s.start).Seconds(),
+		Metrics:        extractMetrics(metrics),
+	})
+}
+
+// Finish дописывает итоговую строку с полным отчетом и закрывает файл
+func (s *ReportStream) Finish(cfg TestConfig, metrics map[string]interface{}) error {
+	now := time.Now()
+	report := CreateReportSchema(cfg, metrics)
+	err := s.write(ReportLine{
+		Time:           now,
+		ElapsedSeconds: now.Sub(s.start).Seconds(),
+		Final:          true,
+		Metrics:        report.Metrics,
+		Report:         &report,
+	})
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения отчета: %w", err)
+	}
+	color.Green("\n✓ Отчет сохранен: %s", s.name)
+	return nil
+}
+
+// Close закрывает файл без итоговой строки; снимки, пришедшие позже,
+// отбрасываются
+func (s *ReportStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *ReportStream) write(line ReportLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// appendReportLine дописывает строку в отчет JSON Lines; SaveReport так
+// добавляет итог к отчету, снимки которого записаны во время теста
+func appendReportLine(filename string, line ReportLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReportStreamWritesLines(t *testing.T) {
+	cfg := TestConfig{
+		Mode:         "client",
+		Connections:  1,
+		Streams:      1,
+		ReportPath:   filepath.Join(t.TempDir(), "report.jsonl"),
+		ReportFormat: ReportFormatJSONL,
+	}
+	stream, err := OpenReportStream(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sent := range []int{1200, 2400} {
+		if err := stream.WriteSnapshot(map[string]interface{}{"BytesSent": sent}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Снимки уже в файле до конца теста
+	if lines := readReportLines(t, cfg.ReportPath); len(lines) != 2 {
+		t.Fatalf("Expected 2 lines while the test runs, got %d", len(lines))
+	}
+	if err := stream.Finish(cfg, map[string]interface{}{"BytesSent": 3600}); err != nil {
+		t.Fatal(err)
+	}
+	// Поздние снимки после завершения отбрасываются
+	if err := stream.WriteSnapshot(map[string]interface{}{"BytesSent": 4800}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := readReportLines(t, cfg.ReportPath)
+	if len(lines) != 3 {
+		t.Fatalf("Expected 2 snapshots and a final line, got %d lines", len(lines))
+	}
+	for i, want := range []int64{1200, 2400, 3600} {
+		line := lines[i]
+		if int64(line.Metrics.BytesSent) != want {
+			t.Errorf("Line %d: expected %d bytes, got %d", i, want, line.Metrics.BytesSent)
+		}
+		if line.Time.IsZero() || line.ElapsedSeconds <= 0 {
+			t.Errorf("Line %d: expected a timestamp and elapsed time, got %+v", i, line)
+		}
+		if final := i == len(lines)-1; line.Final != final || (line.Report != nil) != final {
+			t.Errorf("Line %d: expected only the last line to be final with the report", i)
+		}
+	}
+	if report := lines[2].Report; report == nil || report.TestConfig.Mode != "client" {
+		t.Errorf("Expected the full report in the final line, got %+v", report)
+	}
+}
+
+func TestSaveReportAppendsJSONLSummary(t *testing.T) {
+	cfg := TestConfig{
+		Mode:         "client",
+		ReportPath:   filepath.Join(t.TempDir(), "report.jsonl"),
+		ReportFormat: "JSONL",
+	}
+	if err := os.WriteFile(cfg.ReportPath, []byte(`{"metrics":{"bytes_sent":1200}}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveReport(cfg, map[string]interface{}{"BytesSent": 2400}); err != nil {
+		t.Fatal(err)
+	}
+	lines := readReportLines(t, cfg.ReportPath)
+	if len(lines) != 2 || !lines[1].Final || lines[1].Metrics.BytesSent != 2400 {
+		t.Errorf("Expected the summary appended after the snapshot, got %+v", lines)
+	}
+}
+
+func TestValidateRejectsCompressedJSONL(t *testing.T) {
+	cfg := TestConfig{
+		Mode:           "client",
+		Addr:           ":9000",
+		Connections:    1,
+		Streams:        1,
+		PacketSize:     1024,
+		Rate:           100,
+		ReportFormat:   ReportFormatJSONL,
+		ReportCompress: true,
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected an error for a compressed jsonl report")
+	}
+}
+
+// readReportLines разбирает отчет JSON Lines построчно
+func readReportLines(t *testing.T, path string) []ReportLine {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []ReportLine
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var line ReportLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Line %d is not JSON: %v", len(lines)+1, err)
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveReportCompressed(t *testing.T) {
+	dir := t.TempDir()
+	cfg := TestConfig{
+		Mode:           "client",
+		Connections:    1,
+		Streams:        1,
+		ReportPath:     filepath.Join(dir, "report.json"),
+		ReportFormat:   "json",
+		ReportCompress: true,
+	}
+	metrics := map[string]interface{}{"Success": 10, "BytesSent": 12000}
+	if err := SaveReport(cfg, metrics); err != nil {
+		t.Fatalf("SaveReport failed: %v", err)
+	}
+
+	if _, err := os.Stat(cfg.ReportPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no uncompressed report, stat returned %v", err)
+	}
+	f, err := os.Open(cfg.ReportPath + ".gz")
+	if err != nil {
+		t.Fatalf("Compressed report not written: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Report is not gzip: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to decompress the report: %v", err)
+	}
+
+	// Совпадает с несжатым отчетом
+	var report ReportSchema
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Decompressed report is not JSON: %v", err)
+	}
+	if report.Metrics.BytesSent != 12000 || report.TestConfig.Mode != "client" {
+		t.Errorf("Unexpected report contents: %+v", report)
+	}
+	cfg.ReportCompress = false
+	if err := SaveReport(cfg, metrics); err != nil {
+		t.Fatal(err)
+	}
+	plain, err := os.ReadFile(cfg.ReportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plainReport ReportSchema
+	if err := json.Unmarshal(plain, &plainReport); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plainReport.Metrics, report.Metrics) {
+		t.Error("Compressed and plain reports carry different metrics")
+	}
+}
+
+func TestCompressedReportPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"report.json":    "report.json.gz",
+		"report.md":      "report.md.gz",
+		"report.json.gz": "report.json.gz",
+	} {
+		if got := CompressedReportPath(path); got != want {
+			t.Errorf("CompressedReportPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestReportIncludesVersions(t *testing.T) {
+	dir := t.TempDir()
+	cfg := TestConfig{
+		Mode:         "client",
+		ReportPath:   filepath.Join(dir, "report.json"),
+		ReportFormat: "json",
+	}
+	metrics := map[string]interface{}{
+		"Success":     1,
+		"QUICVersion": "v1",
+		"TLSVersion":  "TLS 1.3",
+	}
+	if err := SaveReport(cfg, metrics); err != nil {
+		t.Fatalf("SaveReport failed: %v", err)
+	}
+	data, err := os.ReadFile(cfg.ReportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report ReportSchema
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	v := report.Versions
+	if v.Suite == "" || v.Suite == "unknown" {
+		t.Errorf("Suite version not populated: %q", v.Suite)
+	}
+	// Версия библиотеки берется из build info тестового бинарника
+	if !strings.HasPrefix(v.QUICGo, "v") {
+		t.Errorf("quic-go version not populated from build info: %q", v.QUICGo)
+	}
+	if v.QUICVersion != "v1" || v.TLSVersion != "TLS 1.3" {
+		t.Errorf("Negotiated versions not carried into the report: %+v", v)
+	}
+	if report.Metadata["quic_version"] != v.QUICGo {
+		t.Errorf("Metadata quic_version = %v, want %s", report.Metadata["quic_version"], v.QUICGo)
+	}
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"quic-test/internal/metrics"
+)
+
+// LinkConditions — условия канала, измеренные при записи сценария
+type LinkConditions struct {
+	RTTMs       float64 `json:"rtt_ms"` // медиана RTT
+	RTTP95Ms    float64 `json:"rtt_p95_ms"`
+	MinRTTMs    float64 `json:"min_rtt_ms"`
+	JitterMs    float64 `json:"jitter_ms"` // стандартное отклонение RTT
+	LossRatio   float64 `json:"loss_ratio"`
+	PacketsSent int     `json:"packets_sent"`
+	PacketsLost int     `json:"packets_lost"`
+	Samples     int     `json:"samples"` // замеров RTT
+}
+
+// MeasureLinkConditions сводит замеры RTT и счетчики пакетов в условия канала
+func MeasureLinkConditions(rtts []time.Duration, sent, lost int) LinkConditions {
+	values := make([]float64, len(rtts))
+	for i, rtt := range rtts {
+		values[i] = float64(rtt.Microseconds()) / 1000
+	}
+	stats := metrics.Summarize(values)
+	p50, p95, _ := calcPercentiles(values)
+	cond := LinkConditions{
+		RTTMs:       p50,
+		RTTP95Ms:    p95,
+		MinRTTMs:    stats.Min,
+		JitterMs:    stats.StdDev,
+		PacketsSent: sent,
+		PacketsLost: lost,
+		Samples:     len(rtts),
+	}
+	if sent > 0 {
+		cond.LossRatio = float64(lost) / float64(sent)
+	}
+	return cond
+}
+
+// ScenarioEmulation — параметры эмуляции сценария из файла
+type ScenarioEmulation struct {
+	Latency string  `json:"latency"` // длительность, например "42ms"
+	Loss    float64 `json:"loss"`    // 0..1
+	Dup     float64 `json:"dup"`     // 0..1
+}
+
+// ScenarioLoad — нагрузка, с которой сценарий воспроизводится
+type ScenarioLoad struct {
+	Connections int    `json:"connections"`
+	Streams     int    `json:"streams"`
+	Duration    string `json:"duration"`
+	PacketSize  int    `json:"packet_size"`
+	Rate        int    `json:"rate"`
+}
+
+// ScenarioFile — сценарий в файле: записанный --mode record или
+// написанный вручную. Загружается через --scenario <путь>.
+type ScenarioFile struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	RecordedAt  time.Time         `json:"recorded_at,omitempty"`
+	Source      string            `json:"source,omitempty"`   // адрес измеренного сервера
+	Observed    *LinkConditions   `json:"observed,omitempty"` // нет у сценариев, написанных вручную
+	Emulation   ScenarioEmulation `json:"emulation"`
+	Load        ScenarioLoad      `json:"load"`
+}
+
+// defaultScenarioLoad — нагрузка записанного сценария, как у встроенных
+var defaultScenarioLoad = ScenarioLoad{
+	Connections: 1,
+	Streams:     1,
+	Duration:    "30s",
+	PacketSize:  1200,
+	Rate:        100,
+}
+
+// RecordedScenario строит сценарий, эмулирующий измеренные условия.
+// Клиент добавляет EmulateLatency к каждому пакету и считает его RTT
+// пакета, поэтому задержкой эмуляции становится медиана RTT. Потери
+// переносятся как есть. Джиттер и дублирование не эмулируются и
+// остаются только в Observed.
+func RecordedScenario(name, source string, cond LinkConditions, recordedAt time.Time) ScenarioFile {
+	latency := time.Duration(cond.RTTMs * float64(time.Millisecond)).Round(100 * time.Microsecond)
+	return ScenarioFile{
+		Name:        name,
+		Description: fmt.Sprintf("Записан с %s: RTT %.1f ms, джиттер %.1f ms, потери %.2f%%", source, cond.RTTMs, cond.JitterMs, cond.LossRatio*100),
+		RecordedAt:  recordedAt,
+		Source:      source,
+		Observed:    &cond,
+		Emulation: ScenarioEmulation{
+			Latency: latency.String(),
+			Loss:    math.Round(cond.LossRatio*1e4) / 1e4,
+		},
+		Load: defaultScenarioLoad,
+	}
+}
+
+// SaveScenarioFile записывает сценарий в JSON
+func SaveScenarioFile(path string, sf ScenarioFile) error {
+	data, err := json.MarshalIndent(sf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadScenarioFile читает сценарий из JSON и проверяет получившуюся конфигурацию
+func LoadScenarioFile(path string) (*TestScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sf ScenarioFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("сценарий %s: %w", path, err)
+	}
+	scenario, err := sf.Scenario()
+	if err != nil {
+		return nil, fmt.Errorf("сценарий %s: %w", path, err)
+	}
+	return scenario, nil
+}
+
+// Scenario преобразует файл в сценарий. Ожидаемые метрики выводятся из
+// измеренных условий с запасом; без них проверяются только ошибки.
+func (sf ScenarioFile) Scenario() (*TestScenario, error) {
+	var latency time.Duration
+	if sf.Emulation.Latency != "" {
+		var err error
+		if latency, err = time.ParseDuration(sf.Emulation.Latency); err != nil {
+			return nil, fmt.Errorf("emulation latency: %w", err)
+		}
+		if latency < 0 {
+			return nil, fmt.Errorf("emulation latency must not be negative")
+		}
+	}
+	duration, err := time.ParseDuration(sf.Load.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("load duration: %w", err)
+	}
+	name := sf.Name
+	if name == "" {
+		name = "Recorded scenario"
+	}
+
+	scenario := &TestScenario{
+		Name:        name,
+		Description: sf.Description,
+		Config: TestConfig{
+			Mode:           "test",
+			Addr:           ":9000",
+			Connections:    sf.Load.Connections,
+			Streams:        sf.Load.Streams,
+			Duration:       duration,
+			PacketSize:     sf.Load.PacketSize,
+			Rate:           sf.Load.Rate,
+			EmulateLoss:    sf.Emulation.Loss,
+			EmulateLatency: latency,
+			EmulateDup:     sf.Emulation.Dup,
+		},
+		Expected: ExpectedMetrics{
+			MaxErrors: 10,
+		},
+	}
+	if err := scenario.Config.Validate(); err != nil {
+		return nil, err
+	}
+	if sf.Observed != nil {
+		scenario.Expected.MaxRTT = time.Duration(sf.Observed.RTTP95Ms * 1.25 * float64(time.Millisecond)).Round(100 * time.Microsecond)
+		scenario.Expected.MaxLoss = math.Max(2*sf.Observed.LossRatio, 0.01)
+	}
+	return scenario, nil
+}
+
+// LoadScenario возвращает встроенный сценарий по имени или загружает его из файла
+func LoadScenario(nameOrPath string) (*TestScenario, error) {
+	if scenario, err := GetScenario(nameOrPath); err == nil {
+		return scenario, nil
+	}
+	if _, err := os.Stat(nameOrPath); err != nil {
+		return nil, fmt.Errorf("сценарий '%s' не найден: это не встроенный сценарий и не файл", nameOrPath)
+	}
+	return LoadScenarioFile(nameOrPath)
+}
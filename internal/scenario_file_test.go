@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordedScenarioRoundTrip(t *testing.T) {
+	// Синтетический канал: RTT 42 ms ± 3 ms, потеряно 15 пакетов из 1000
+	var rtts []time.Duration
+	for i := 0; i < 100; i++ {
+		jitter := time.Duration(i%7-3) * time.Millisecond
+		rtts = append(rtts, 42*time.Millisecond+jitter)
+	}
+	cond := MeasureLinkConditions(rtts, 1000, 15)
+	if cond.RTTMs != 42 {
+		t.Errorf("Expected median rtt 42 ms, got %.2f", cond.RTTMs)
+	}
+	if cond.MinRTTMs != 39 {
+		t.Errorf("Expected min rtt 39 ms, got %.2f", cond.MinRTTMs)
+	}
+	if cond.JitterMs < 1.5 || cond.JitterMs > 2.5 {
+		t.Errorf("Expected jitter about 2 ms, got %.2f", cond.JitterMs)
+	}
+	if cond.LossRatio != 0.015 {
+		t.Errorf("Expected loss ratio 0.015, got %f", cond.LossRatio)
+	}
+
+	path := filepath.Join(t.TempDir(), "office.json")
+	recordedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := SaveScenarioFile(path, RecordedScenario("office", "10.0.0.1:9000", cond, recordedAt)); err != nil {
+		t.Fatalf("SaveScenarioFile failed: %v", err)
+	}
+
+	scenario, err := LoadScenario(path)
+	if err != nil {
+		t.Fatalf("LoadScenario failed: %v", err)
+	}
+	if scenario.Name != "office" {
+		t.Errorf("Expected name 'office', got '%s'", scenario.Name)
+	}
+	if scenario.Config.EmulateLatency != 42*time.Millisecond {
+		t.Errorf("Expected emulated latency 42ms, got %v", scenario.Config.EmulateLatency)
+	}
+	if scenario.Config.EmulateLoss != 0.015 {
+		t.Errorf("Expected emulated loss 0.015, got %f", scenario.Config.EmulateLoss)
+	}
+	if scenario.Config.EmulateDup != 0 {
+		t.Errorf("Expected no emulated duplication, got %f", scenario.Config.EmulateDup)
+	}
+	if err := scenario.Config.Validate(); err != nil {
+		t.Errorf("Expected the loaded config to validate, got %v", err)
+	}
+	// Ожидания оставляют запас над измеренным
+	wantMaxRTT := time.Duration(math.Round(cond.RTTP95Ms*12.5)) * 100 * time.Microsecond
+	if scenario.Expected.MaxRTT != wantMaxRTT {
+		t.Errorf("Expected max rtt %v, got %v", wantMaxRTT, scenario.Expected.MaxRTT)
+	}
+	if scenario.Expected.MaxLoss != 0.03 {
+		t.Errorf("Expected max loss 0.03, got %f", scenario.Expected.MaxLoss)
+	}
+}
+
+func TestLoadScenarioBuiltinAndInvalid(t *testing.T) {
+	scenario, err := LoadScenario("wifi")
+	if err != nil {
+		t.Fatalf("LoadScenario(wifi) failed: %v", err)
+	}
+	if scenario.Name != "WiFi Network" {
+		t.Errorf("Expected the built-in wifi scenario, got '%s'", scenario.Name)
+	}
+
+	if _, err := LoadScenario("nonexistent"); err == nil {
+		t.Error("Expected error for an unknown scenario name")
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"malformed.json":    `{"name": `,
+		"bad-latency.json":  `{"emulation": {"latency": "fast"}, "load": {"connections": 1, "streams": 1, "duration": "1s", "packet_size": 1200, "rate": 100}}`,
+		"bad-loss.json":     `{"emulation": {"loss": 2}, "load": {"connections": 1, "streams": 1, "duration": "1s", "packet_size": 1200, "rate": 100}}`,
+		"missing-load.json": `{"emulation": {"latency": "10ms"}}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScenarioFile(path); err == nil {
+			t.Errorf("Expected %s to fail to load", name)
+		}
+	}
+}
@@ -169,7 +169,9 @@ func GetScenario(name string) (*TestScenario, error) {
 				Rate:          100,
 				EmulateLoss:   0.02, // 2%
 				EmulateLatency: 15 * time.Millisecond,
+				EmulateJitter: 5 * time.Millisecond,
 				EmulateDup:    0.1, // 10% - высокое дублирование
+				EmulateReorder: 0.1, // 10%
 			},
 			Expected: ExpectedMetrics{
 				MinThroughput: 30.0,  // KB/s
@@ -214,6 +216,10 @@ func PrintScenarioInfo(scenario *TestScenario) {
 	fmt.Printf("  - Loss: %.2f%%\n", scenario.Config.EmulateLoss*100)
 	fmt.Printf("  - Latency: %v\n", scenario.Config.EmulateLatency)
 	fmt.Printf("  - Duplication: %.2f%%\n", scenario.Config.EmulateDup*100)
+	if scenario.Config.EmulateReorder > 0 || scenario.Config.EmulateJitter > 0 {
+		fmt.Printf("  - Reordering: %.2f%%\n", scenario.Config.EmulateReorder*100)
+		fmt.Printf("  - Jitter: ±%v\n", scenario.Config.EmulateJitter)
+	}
 	fmt.Printf("Expected Metrics:\n")
 	fmt.Printf("  - Min Throughput: %.1f KB/s\n", scenario.Expected.MinThroughput)
 	fmt.Printf("  - Max RTT: %v\n", scenario.Expected.MaxRTT)
@@ -2,49 +2,98 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"runtime"
 	"time"
+
+	"quic-test/internal/metrics"
 )
 
 // ReportSchema определяет JSON-схему для отчетов
 type ReportSchema struct {
 	Version     string                 `json:"version"`
 	Timestamp   time.Time             `json:"timestamp"`
+	Versions    VersionsSchema        `json:"versions"`
 	TestConfig  TestConfigSchema      `json:"test_config"`
 	Metrics     MetricsSchema         `json:"metrics"`
 	TimeSeries  TimeSeriesSchema      `json:"time_series"`
 	SLA         SLASchema             `json:"sla,omitempty"`
+	Stability   StabilityVerdict      `json:"stability"`
+	Upload      *UploadResult         `json:"upload,omitempty"` // Итог --upload-file
+	Blast       *BlastResult          `json:"blast,omitempty"`  // Итог --blast
+	Emulation   *metrics.EmulationSummary `json:"emulation,omitempty"` // Заданная и фактическая эмуляция
+	Warnings    []metrics.Warning     `json:"warnings,omitempty"`  // Некритичные обстоятельства прогона
 	BBRv3Metrics map[string]interface{} `json:"BBRv3Metrics,omitempty"` // BBRv3 specific metrics
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// VersionsSchema фиксирует версии сборки и протоколов для воспроизводимости
+type VersionsSchema struct {
+	Suite       string `json:"suite"`        // Версия сборки quic-test
+	QUICGo      string `json:"quic_go"`      // Версия quic-go из build info
+	QUICVersion string `json:"quic_version"` // Согласованная версия QUIC ("v1", "v2")
+	TLSVersion  string `json:"tls_version"`  // Согласованная версия TLS
+}
+
+// ReportVersions собирает версии для отчета; версии QUIC и TLS берутся из
+// метрик, так как их согласовывает рукопожатие
+func ReportVersions(metrics map[string]interface{}) VersionsSchema {
+	return VersionsSchema{
+		Suite:       SuiteVersion(),
+		QUICGo:      QUICGoVersion(),
+		QUICVersion: getString(metrics, "QUICVersion"),
+		TLSVersion:  getString(metrics, "TLSVersion"),
+	}
+}
+
+// String возвращает версии одной строкой для итогов прогона; версии,
+// которые не удалось согласовать (нет соединений), выводятся как "-"
+func (v VersionsSchema) String() string {
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	return fmt.Sprintf("quic-test %s, quic-go %s, QUIC %s, %s",
+		orDash(v.Suite), orDash(v.QUICGo), orDash(v.QUICVersion), orDash(v.TLSVersion))
+}
+
 // TestConfigSchema описывает конфигурацию теста
 type TestConfigSchema struct {
 	Mode         string        `json:"mode"`
 	Address      string        `json:"address"`
+	LocalAddress string        `json:"local_address,omitempty"`
 	Connections  int           `json:"connections"`
+	MaxConnectionsPerSecond float64 `json:"max_connections_per_second,omitempty"`
 	Streams      int           `json:"streams"`
 	Duration     time.Duration `json:"duration"`
 	PacketSize   int           `json:"packet_size"`
+	PacketSizeDistribution string `json:"packet_size_distribution,omitempty"`
 	Rate         int           `json:"rate"`
+	Blast        bool          `json:"blast,omitempty"`
 	Pattern      string        `json:"pattern"`
+	Echo         bool          `json:"echo,omitempty"` // RTT измерен по эхо сервера
 	NoTLS        bool          `json:"no_tls"`
 	Prometheus   bool          `json:"prometheus"`
 	EmulateLoss  float64       `json:"emulate_loss"`
 	EmulateLatency time.Duration `json:"emulate_latency"`
 	EmulateDup   float64       `json:"emulate_dup"`
 	PprofAddr    string        `json:"pprof_addr,omitempty"`
+	TransportParams map[string]string `json:"transport_params,omitempty"` // Действующие значения параметров --transport-param
 }
 
 // MetricsSchema описывает основные метрики
 type MetricsSchema struct {
 	Success              bool                    `json:"success"`
 	Errors               int                     `json:"errors"`
-	BytesSent            int64                   `json:"bytes_sent"`
-	BytesReceived        int64                   `json:"bytes_received"`
+	BytesSent            metrics.ByteCount       `json:"bytes_sent"`
+	BytesReceived        metrics.ByteCount       `json:"bytes_received"`
 	PacketsSent          int64                   `json:"packets_sent"`
 	PacketsReceived      int64                   `json:"packets_received"`
 	Latency              LatencyMetrics         `json:"latency"`
+	Handshake            *HandshakeMetrics       `json:"handshake,omitempty"`   // Распределение времени handshake
 	Throughput           ThroughputMetrics      `json:"throughput"`
 	ThroughputMbps       float64                 `json:"throughput_mbps"`       // Throughput in Mbps (calculated correctly)
 	GoodputMbps          float64                 `json:"goodput_mbps"`          // Goodput in Mbps (excluding retransmits)
@@ -52,8 +101,10 @@ type MetricsSchema struct {
 	Retransmits          int64                   `json:"retransmits"`
 	BufferbloatFactor    float64                 `json:"bufferbloat_factor"`    // (avg_rtt / min_rtt) - 1
 	FairnessIndex        float64                 `json:"fairness_index"`         // Jain's fairness index
+	ConnectionFairness   *metrics.ConnectionFairness `json:"connection_fairness,omitempty"` // Разделение полосы между соединениями
+	CloseReasons         []metrics.CloseReasonSummary `json:"close_reasons,omitempty"`      // Почему закрывались соединения
 	FECPacketsSent       int64                   `json:"fec_packets_sent"`      // Количество отправленных FEC пакетов
-	FECRedundancyBytes   int64                   `json:"fec_redundancy_bytes"` // Байты FEC redundancy
+	FECRedundancyBytes   metrics.ByteCount       `json:"fec_redundancy_bytes"` // Байты FEC redundancy
 	FECRepairPacketsSent int64                   `json:"fec_repair_sent"`      // Redundancy packets sent (repair packets)
 	FECRecovered         int64                   `json:"fec_recovered"`        // Packets recovered via FEC
 	FECRecoveryEvents    int64                   `json:"fec_recovery_events"`  // События восстановления через FEC
@@ -66,6 +117,14 @@ type MetricsSchema struct {
 	FlowControlEvents    int64                   `json:"flow_control_events"`
 	KeyUpdateEvents      int64                   `json:"key_update_events"`
 	ErrorTypeCounts      map[string]int64        `json:"error_type_counts"`
+	TopErrors            []metrics.ErrorSummary  `json:"top_errors,omitempty"`  // Наиболее частые ошибки с примерами
+	PacketSizes          *metrics.SizeSummary    `json:"packet_sizes,omitempty"` // Фактическое распределение размеров пакетов
+	Phases               []metrics.PhaseStats    `json:"phases,omitempty"`       // Метрики по фазам теста
+	UDPRecvBuffer        int                     `json:"udp_recv_buffer,omitempty"` // Фактический SO_RCVBUF, байт
+	UDPSendBuffer        int                     `json:"udp_send_buffer,omitempty"` // Фактический SO_SNDBUF, байт
+	UDPRecvDrops         int64                   `json:"udp_recv_drops,omitempty"`  // Датаграммы, отброшенные ядром до QUIC
+	EchoReplies          int64                   `json:"echo_replies,omitempty"`    // Пакеты, RTT которых измерен по эхо сервера (--echo)
+	LocalAddrs           []string                `json:"local_addrs,omitempty"`     // Фактические локальные адреса сокетов клиента
 	ConnectionMetrics    []ConnectionMetrics     `json:"connection_metrics,omitempty"`
 	StreamMetrics        []StreamMetrics         `json:"stream_metrics,omitempty"`
 }
@@ -78,6 +137,7 @@ type LatencyMetrics struct {
 	P99     float64   `json:"p99"`
 	P999    float64   `json:"p999"`
 	Jitter  float64   `json:"jitter"`
+	StdDev  float64   `json:"stddev"`
 	Min     float64   `json:"min"`
 	Max     float64   `json:"max"`
 	Values  []float64 `json:"values,omitempty"`
@@ -91,12 +151,21 @@ type ThroughputMetrics struct {
 	Current float64 `json:"current"`
 }
 
+// HandshakeMetrics описывает распределение времени handshake соединений
+type HandshakeMetrics struct {
+	Count int     `json:"count"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
 // ConnectionMetrics описывает метрики соединения
 type ConnectionMetrics struct {
 	ConnectionID    int           `json:"connection_id"`
 	HandshakeTime   time.Duration `json:"handshake_time"`
-	BytesSent       int64         `json:"bytes_sent"`
-	BytesReceived   int64         `json:"bytes_received"`
+	BytesSent       metrics.ByteCount `json:"bytes_sent"`
+	BytesReceived   metrics.ByteCount `json:"bytes_received"`
 	PacketsSent     int64         `json:"packets_sent"`
 	PacketsReceived int64         `json:"packets_received"`
 	Retransmits     int64         `json:"retransmits"`
@@ -108,8 +177,8 @@ type ConnectionMetrics struct {
 type StreamMetrics struct {
 	ConnectionID int   `json:"connection_id"`
 	StreamID     int   `json:"stream_id"`
-	BytesSent    int64 `json:"bytes_sent"`
-	BytesReceived int64 `json:"bytes_received"`
+	BytesSent    metrics.ByteCount `json:"bytes_sent"`
+	BytesReceived metrics.ByteCount `json:"bytes_received"`
 	Retransmits  int64 `json:"retransmits"`
 	Errors       int64 `json:"errors"`
 }
@@ -122,6 +191,7 @@ type TimeSeriesSchema struct {
 	Retransmits  []TimeSeriesPoint `json:"retransmits"`
 	HandshakeTime []TimeSeriesPoint `json:"handshake_time"`
 	Errors       []TimeSeriesPoint `json:"errors"`
+	Phases       []metrics.PhaseBoundary `json:"phases,omitempty"` // Начала фаз теста для разметки графиков
 }
 
 // TimeSeriesPoint представляет точку временного ряда
@@ -156,32 +226,49 @@ func CreateReportSchema(cfg TestConfig, metrics map[string]interface{}) ReportSc
 	schema := ReportSchema{
 		Version:   "1.0.0",
 		Timestamp: time.Now(),
+		Versions:  ReportVersions(metrics),
 		TestConfig: TestConfigSchema{
 			Mode:          cfg.Mode,
 			Address:       cfg.Addr,
+			LocalAddress:  cfg.LocalAddr,
 			Connections:   cfg.Connections,
+			MaxConnectionsPerSecond: cfg.MaxConnectionsPerSecond,
 			Streams:       cfg.Streams,
 			Duration:      cfg.Duration,
 			PacketSize:    cfg.PacketSize,
+			PacketSizeDistribution: cfg.PacketSizes.String(),
 			Rate:          cfg.Rate,
+			Blast:         cfg.Blast,
 			Pattern:       cfg.Pattern,
+			Echo:          cfg.Echo,
 			NoTLS:         cfg.NoTLS,
 			Prometheus:    cfg.Prometheus,
 			EmulateLoss:   cfg.EmulateLoss,
 			EmulateLatency: cfg.EmulateLatency,
 			EmulateDup:    cfg.EmulateDup,
 			PprofAddr:     cfg.PprofAddr,
+			TransportParams: EffectiveTransportParams(CreateQUICConfig(cfg)),
 		},
 		Metrics:    extractMetrics(metrics),
 		TimeSeries: extractTimeSeries(metrics),
 		SLA:        extractSLA(cfg, metrics),
+		Stability:  stabilityFromMetrics(cfg, metrics),
+		Upload:     getUploadResult(metrics, "Upload"),
+		Blast:      getBlastResult(metrics, "Blast"),
+		Emulation:  getEmulationSummary(metrics, "Emulation"),
+		Warnings:   getWarnings(metrics, "Warnings"),
 		Metadata: map[string]interface{}{
-			"go_version": "1.21",
-			"quic_version": "0.40.0",
+			"go_version": runtime.Version(),
+			"quic_version": QUICGoVersion(),
 			"build_time": time.Now().Format(time.RFC3339),
 		},
 	}
 	
+	// Метки --label отличают этот прогон от других в общем хранилище отчетов
+	if len(cfg.Labels) > 0 {
+		schema.Metadata["labels"] = cfg.Labels
+	}
+	
 	// Extract BBRv3 metrics if available
 	if bbrv3Metrics, ok := metrics["BBRv3Metrics"].(map[string]interface{}); ok {
 		schema.BBRv3Metrics = bbrv3Metrics
@@ -253,11 +340,12 @@ func extractMetrics(metrics map[string]interface{}) MetricsSchema {
 	return MetricsSchema{
 		Success:           getBool(metrics, "Success"),
 		Errors:            getInt(metrics, "Errors"),
-		BytesSent:         getInt64(metrics, "BytesSent"),
-		BytesReceived:     getInt64(metrics, "BytesReceived"),
+		BytesSent:         getByteCount(metrics, "BytesSent"),
+		BytesReceived:     getByteCount(metrics, "BytesReceived"),
 		PacketsSent:       getInt64(metrics, "PacketsSent"),
 		PacketsReceived:   getInt64(metrics, "PacketsReceived"),
 		Latency:           extractLatencyMetrics(latencies),
+		Handshake:         extractHandshakeMetrics(metrics),
 		Throughput:        extractThroughputMetrics(metrics),
 		ThroughputMbps:    throughputMbps,
 		GoodputMbps:       goodputMbps,
@@ -265,8 +353,10 @@ func extractMetrics(metrics map[string]interface{}) MetricsSchema {
 		Retransmits:       getInt64(metrics, "Retransmits"),
 		BufferbloatFactor: getFloat64FromSchema(metrics, "BufferbloatFactor"),
 		FairnessIndex:     getFloat64FromSchema(metrics, "FairnessIndex"),
+		ConnectionFairness: getConnectionFairness(metrics, "ConnectionFairness"),
+		CloseReasons:      getCloseReasons(metrics, "CloseReasons"),
 		FECPacketsSent:    getInt64(metrics, "FECPacketsSent"),
-		FECRedundancyBytes: getInt64(metrics, "FECRedundancyBytes"),
+		FECRedundancyBytes: getByteCount(metrics, "FECRedundancyBytes"),
 		FECRepairPacketsSent: getInt64(metrics, "FECRepairPacketsSent"),
 		FECRecovered:      getInt64(metrics, "FECRecovered"),
 		FECRecoveryEvents:  getInt64(metrics, "FECRecoveryEvents"),
@@ -279,6 +369,14 @@ func extractMetrics(metrics map[string]interface{}) MetricsSchema {
 		FlowControlEvents: getInt64(metrics, "FlowControlEvents"),
 		KeyUpdateEvents:   getInt64(metrics, "KeyUpdateEvents"),
 		ErrorTypeCounts:   getStringInt64Map(metrics, "ErrorTypeCounts"),
+		TopErrors:         getErrorSummaries(metrics, "TopErrors"),
+		PacketSizes:       getSizeSummary(metrics, "PacketSizes"),
+		Phases:            getPhaseStats(metrics, "Phases"),
+		UDPRecvBuffer:     getInt(metrics, "UDPRecvBuffer"),
+		UDPSendBuffer:     getInt(metrics, "UDPSendBuffer"),
+		UDPRecvDrops:      getInt64(metrics, "UDPRecvDrops"),
+		EchoReplies:       getInt64(metrics, "EchoReplies"),
+		LocalAddrs:        getStrings(metrics, "LocalAddrs"),
 	}
 }
 
@@ -302,28 +400,18 @@ func extractLatencyMetrics(latencies []float64) LatencyMetrics {
 	}
 	
 	p50, p95, p99, p999 := calcPercentilesExtended(filteredLatencies)
-	jitter := calcJitter(filteredLatencies)
-	avg := avgLatency(filteredLatencies)
-	
-	min, max := filteredLatencies[0], filteredLatencies[0]
-	for _, l := range filteredLatencies {
-		if l < min {
-			min = l
-		}
-		if l > max {
-			max = l
-		}
-	}
+	stats := metrics.Summarize(filteredLatencies)
 	
 	return LatencyMetrics{
-		Average: avg,
+		Average: stats.Mean,
 		P50:     p50,
 		P95:     p95,
 		P99:     p99,
 		P999:    p999,
-		Jitter:  jitter,
-		Min:     min,
-		Max:     max,
+		Jitter:  stats.StdDev,
+		StdDev:  stats.StdDev,
+		Min:     stats.Min,
+		Max:     stats.Max,
 	}
 }
 
@@ -346,6 +434,7 @@ func extractTimeSeries(metrics map[string]interface{}) TimeSeriesSchema {
 		Retransmits:   extractTimeSeriesPoints(metrics, "TimeSeriesRetransmits"),
 		HandshakeTime: extractTimeSeriesPoints(metrics, "TimeSeriesHandshakeTime"),
 		Errors:        extractTimeSeriesPoints(metrics, "TimeSeriesErrors"),
+		Phases:        getPhaseBoundaries(metrics, "PhaseBoundaries"),
 	}
 }
 
@@ -494,6 +583,10 @@ func getInt64(m map[string]interface{}, key string) int64 {
 	if v, ok := m[key].(int64); ok {
 		return v
 	}
+	// Try byte count
+	if v, ok := m[key].(metrics.ByteCount); ok {
+		return int64(v)
+	}
 	// Try int
 	if v, ok := m[key].(int); ok {
 		return int64(v)
@@ -505,6 +598,10 @@ func getInt64(m map[string]interface{}, key string) int64 {
 	return 0
 }
 
+func getByteCount(m map[string]interface{}, key string) metrics.ByteCount {
+	return metrics.ByteCount(getInt64(m, key))
+}
+
 func getFloat64FromSchema(m map[string]interface{}, key string) float64 {
 	if v, ok := m[key].(float64); ok {
 		return v
@@ -526,6 +623,97 @@ func getStringInt64Map(m map[string]interface{}, key string) map[string]int64 {
 	return make(map[string]int64)
 }
 
+func getStrings(m map[string]interface{}, key string) []string {
+	if v, ok := m[key].([]string); ok {
+		return v
+	}
+	return nil
+}
+
+func getErrorSummaries(m map[string]interface{}, key string) []metrics.ErrorSummary {
+	if v, ok := m[key].([]metrics.ErrorSummary); ok {
+		return v
+	}
+	return nil
+}
+
+func getSizeSummary(m map[string]interface{}, key string) *metrics.SizeSummary {
+	if v, ok := m[key].(metrics.SizeSummary); ok && v.Count > 0 {
+		return &v
+	}
+	return nil
+}
+
+func getPhaseStats(m map[string]interface{}, key string) []metrics.PhaseStats {
+	if v, ok := m[key].([]metrics.PhaseStats); ok {
+		return v
+	}
+	return nil
+}
+
+func getConnectionFairness(m map[string]interface{}, key string) *metrics.ConnectionFairness {
+	if v, ok := m[key].(metrics.ConnectionFairness); ok {
+		return &v
+	}
+	return nil
+}
+
+// extractHandshakeMetrics возвращает распределение handshake или nil, если
+// ни одно соединение не открывалось
+func extractHandshakeMetrics(m map[string]interface{}) *HandshakeMetrics {
+	count := getInt(m, "HandshakeCount")
+	if count == 0 {
+		return nil
+	}
+	return &HandshakeMetrics{
+		Count: count,
+		P50Ms: getFloat64FromSchema(m, "HandshakeP50Ms"),
+		P95Ms: getFloat64FromSchema(m, "HandshakeP95Ms"),
+		P99Ms: getFloat64FromSchema(m, "HandshakeP99Ms"),
+		MaxMs: getFloat64FromSchema(m, "HandshakeMaxMs"),
+	}
+}
+
+func getCloseReasons(m map[string]interface{}, key string) []metrics.CloseReasonSummary {
+	if v, ok := m[key].([]metrics.CloseReasonSummary); ok && len(v) > 0 {
+		return v
+	}
+	return nil
+}
+
+func getEmulationSummary(m map[string]interface{}, key string) *metrics.EmulationSummary {
+	if v, ok := m[key].(metrics.EmulationSummary); ok {
+		return &v
+	}
+	return nil
+}
+
+func getWarnings(m map[string]interface{}, key string) []metrics.Warning {
+	v, _ := m[key].([]metrics.Warning)
+	return v
+}
+
+func getUploadResult(m map[string]interface{}, key string) *UploadResult {
+	if v, ok := m[key].(UploadResult); ok {
+		return &v
+	}
+	return nil
+}
+
+func getBlastResult(m map[string]interface{}, key string) *BlastResult {
+	if v, ok := m[key].(BlastResult); ok {
+		return &v
+	}
+	return nil
+}
+
+func getPhaseBoundaries(m map[string]interface{}, key string) []metrics.PhaseBoundary {
+	if v, ok := m[key].([]metrics.PhaseBoundary); ok {
+		return v
+	}
+	return nil
+}
+
 func getFloat64FromMap(m map[string]interface{}, key string) float64 {
 	if v, ok := m[key].(float64); ok {
 		return v
@@ -1,8 +1,11 @@
 package internal
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
+
+	"quic-test/internal/metrics"
 )
 
 func TestCreateReportSchema(t *testing.T) {
@@ -170,3 +173,37 @@ func TestExtractLatencyMetricsEmpty(t *testing.T) {
 		t.Errorf("Expected zero average for empty latencies, got %f", metrics.Average)
 	}
 }
+
+func TestReportSchemaWarnings(t *testing.T) {
+	var warnings metrics.Warnings
+	warnings.Add(metrics.WarningUDPBuffer, "UDP receive buffer clamped by the OS to 425984 bytes (requested 7500000)")
+	warnings.Add(metrics.WarningUDPBuffer, "UDP receive buffer clamped by the OS to 425984 bytes (requested 7500000)")
+
+	schema := CreateReportSchema(TestConfig{Mode: "client"}, map[string]interface{}{
+		"Warnings": warnings.List(),
+	})
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Warnings []metrics.Warning `json:"warnings"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Warnings) != 1 {
+		t.Fatalf("Expected one warning in the report, got %+v", report.Warnings)
+	}
+	if w := report.Warnings[0]; w.Code != metrics.WarningUDPBuffer || w.Count != 2 {
+		t.Errorf("Expected the buffer warning seen twice, got %+v", w)
+	}
+
+	// Без предупреждений поле в отчет не попадает
+	data, _ = json.Marshal(CreateReportSchema(TestConfig{Mode: "client"}, map[string]interface{}{}))
+	var raw map[string]interface{}
+	json.Unmarshal(data, &raw)
+	if _, ok := raw["warnings"]; ok {
+		t.Error("Expected no warnings field in a clean report")
+	}
+}
@@ -0,0 +1,43 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// lineSink writes every event as one JSON line
+type lineSink struct {
+	w      io.Writer
+	closer io.Closer // nil for standard output, which is not closed
+}
+
+func newStdoutSink() *lineSink {
+	return &lineSink{w: os.Stdout}
+}
+
+// newFileSink appends to path, so several runs can share one file
+func newFileSink(path string) (*lineSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sink file: %w", err)
+	}
+	return &lineSink{w: f, closer: f}, nil
+}
+
+func (s *lineSink) deliver(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+func (s *lineSink) close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
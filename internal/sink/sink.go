@@ -0,0 +1,222 @@
+// Package sink fans test results out to external systems. A run publishes
+// periodic metrics and a final summary as events; every configured sink
+// delivers them from its own bounded buffer, so a slow or unreachable
+// destination never stalls the test.
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventMetrics = "metrics" // periodic snapshot of a running test
+	EventSummary = "summary" // result of a finished run
+)
+
+// DefaultBufferSize is the number of events a sink holds while its
+// destination catches up; further events are dropped
+const DefaultBufferSize = 256
+
+// closeTimeout bounds how long Close waits for a sink to deliver the
+// events left in its buffer
+const closeTimeout = 10 * time.Second
+
+// Event is one result published to the sinks
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// ResultSink receives the events of a run
+type ResultSink interface {
+	// Publish queues an event without blocking. An event that does not fit
+	// into the buffer is dropped.
+	Publish(Event)
+	// Close delivers the queued events and releases the destination. The
+	// error reports events that were dropped or could not be delivered.
+	Close() error
+}
+
+// deliverer writes events to a destination, one at a time
+type deliverer interface {
+	deliver(Event) error
+	close() error
+}
+
+// buffered is a ResultSink that hands events to a deliverer from a bounded
+// queue in its own goroutine
+type buffered struct {
+	name   string
+	queue  chan Event
+	dest   deliverer
+	done   chan struct{}
+	closed sync.Once
+
+	mu        sync.Mutex
+	dropped   int
+	failed    int
+	lastError error
+}
+
+func newBuffered(name string, dest deliverer, size int) *buffered {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	b := &buffered{
+		name:  name,
+		queue: make(chan Event, size),
+		dest:  dest,
+		done:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *buffered) run() {
+	defer close(b.done)
+	for event := range b.queue {
+		if err := b.dest.deliver(event); err != nil {
+			b.mu.Lock()
+			b.failed++
+			b.lastError = err
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *buffered) Publish(event Event) {
+	select {
+	case b.queue <- event:
+	default:
+		b.mu.Lock()
+		b.dropped++
+		b.mu.Unlock()
+	}
+}
+
+func (b *buffered) Close() error {
+	var err error
+	b.closed.Do(func() {
+		close(b.queue)
+		select {
+		case <-b.done:
+		case <-time.After(closeTimeout):
+			err = fmt.Errorf("sink %s: events not delivered within %v", b.name, closeTimeout)
+		}
+		if closeErr := b.dest.close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("sink %s: %w", b.name, closeErr)
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if err == nil && b.dropped > 0 {
+			err = fmt.Errorf("sink %s: %d events dropped on a full buffer", b.name, b.dropped)
+		}
+		if err == nil && b.failed > 0 {
+			err = fmt.Errorf("sink %s: %d events failed, last: %w", b.name, b.failed, b.lastError)
+		}
+	})
+	return err
+}
+
+// Open creates a sink from a spec:
+//
+//	stdout             JSON lines on standard output
+//	file:PATH          JSON lines appended to PATH
+//	http://HOST/PATH   one JSON POST per event (also https://, or http:URL)
+func Open(spec string) (ResultSink, error) {
+	kind, target, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	var dest deliverer
+	switch kind {
+	case "stdout":
+		dest = newStdoutSink()
+	case "file":
+		dest, err = newFileSink(target)
+	case "http":
+		dest = newHTTPSink(target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newBuffered(spec, dest, DefaultBufferSize), nil
+}
+
+// Validate checks a spec without opening its destination
+func Validate(spec string) error {
+	_, _, err := parseSpec(spec)
+	return err
+}
+
+// parseSpec splits a spec into the sink type and its target: the file path
+// or the URL
+func parseSpec(spec string) (kind, target string, err error) {
+	kind, target, _ = strings.Cut(spec, ":")
+	switch kind {
+	case "stdout":
+		return kind, "", nil
+	case "file":
+		if target == "" {
+			return "", "", fmt.Errorf("sink %q: file path required", spec)
+		}
+		return kind, target, nil
+	case "http", "https":
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			target = spec
+		}
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" {
+			return "", "", fmt.Errorf("sink %q: invalid URL", spec)
+		}
+		return "http", target, nil
+	default:
+		return "", "", fmt.Errorf("sink %q: unknown type %q, expected stdout, file or http", spec, kind)
+	}
+}
+
+// Multi fans events out to several sinks
+type Multi []ResultSink
+
+// Publish queues the event in every sink
+func (m Multi) Publish(event Event) {
+	for _, s := range m {
+		s.Publish(event)
+	}
+}
+
+// Close closes all sinks and joins their errors
+func (m Multi) Close() error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Flags collects repeated --sink flags and rejects invalid specs when they
+// are parsed
+type Flags []string
+
+func (f *Flags) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *Flags) Set(value string) error {
+	if err := Validate(value); err != nil {
+		return err
+	}
+	*f = append(*f, value)
+	return nil
+}
@@ -0,0 +1,149 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPSinkReceivesSummary(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("Failed to decode event: %v", err)
+		}
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	s, err := Open(srv.URL + "/results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Publish(Event{Type: EventMetrics, Time: time.Now(), Data: map[string]any{"latency_ms": 12.5}})
+	s.Publish(Event{Type: EventSummary, Time: time.Now(), Data: map[string]any{"success": 100}})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Expected all events to be delivered, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[1].Type != EventSummary {
+		t.Fatalf("Expected the metrics and then the summary, got %+v", received)
+	}
+	if data, _ := received[1].Data.(map[string]any); data["success"] != 100.0 {
+		t.Errorf("Expected the summary data, got %+v", received[1].Data)
+	}
+}
+
+func TestHTTPSinkReportsFailedDeliveries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s, err := Open("http:" + srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Publish(Event{Type: EventSummary})
+	if err := s.Close(); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the failed delivery to be reported, got %v", err)
+	}
+}
+
+// blockingSink holds every delivery until release is closed
+type blockingSink struct {
+	release   chan struct{}
+	delivered int
+}
+
+func (s *blockingSink) deliver(Event) error {
+	<-s.release
+	s.delivered++
+	return nil
+}
+
+func (s *blockingSink) close() error { return nil }
+
+func TestPublishDoesNotBlockOnSlowSink(t *testing.T) {
+	dest := &blockingSink{release: make(chan struct{})}
+	s := newBuffered("slow", dest, 4)
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		s.Publish(Event{Type: EventMetrics})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Publish not to wait for the destination, took %v", elapsed)
+	}
+
+	close(dest.release)
+	err := s.Close()
+	if err == nil || !strings.Contains(err.Error(), "dropped") {
+		t.Errorf("Expected the overflow to be reported, got %v", err)
+	}
+	// The buffer and the event being delivered when it filled up
+	if dest.delivered < 4 || dest.delivered > 5 {
+		t.Errorf("Expected the buffered events to be delivered, got %d", dest.delivered)
+	}
+}
+
+func TestFileSinkAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	for run := 0; run < 2; run++ {
+		s, err := Open("file:" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Publish(Event{Type: EventSummary, Data: run})
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var runs []any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", scanner.Text(), err)
+		}
+		runs = append(runs, event.Data)
+	}
+	if len(runs) != 2 || runs[0] != 0.0 || runs[1] != 1.0 {
+		t.Errorf("Expected one line per run, got %v", runs)
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	for _, spec := range []string{"stdout", "file:out.jsonl", "http://collector:8080/results", "https://collector/results", "http:http://collector/results"} {
+		if err := Validate(spec); err != nil {
+			t.Errorf("%s: expected a valid spec, got %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "file:", "kafka:broker:9092", "http:collector", "http://"} {
+		if err := Validate(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"quic-test/internal/metrics"
+)
+
+// Значения вердикта стабильности канала
+const (
+	StabilityStable   = "stable"
+	StabilityUnstable = "unstable"
+	StabilityUnknown  = "unknown" // недостаточно данных для оценки
+)
+
+// Пороги коэффициента вариации (stddev / mean) по умолчанию
+const (
+	DefaultStabilityRTTCoV        = 0.30
+	DefaultStabilityThroughputCoV = 0.25
+)
+
+// stabilityMinSamples — минимальное число значений ряда для оценки
+const stabilityMinSamples = 3
+
+// StabilityThresholds — максимальные коэффициенты вариации, при которых
+// канал еще считается стабильным; 0 — значение по умолчанию
+type StabilityThresholds struct {
+	MaxRTTCoV        float64 `json:"max_rtt_cov"`
+	MaxThroughputCoV float64 `json:"max_throughput_cov"`
+}
+
+// withDefaults подставляет пороги по умолчанию вместо незаданных
+func (t StabilityThresholds) withDefaults() StabilityThresholds {
+	if t.MaxRTTCoV <= 0 {
+		t.MaxRTTCoV = DefaultStabilityRTTCoV
+	}
+	if t.MaxThroughputCoV <= 0 {
+		t.MaxThroughputCoV = DefaultStabilityThroughputCoV
+	}
+	return t
+}
+
+// StabilityVerdict — итоговая оценка стабильности канала за прогон
+type StabilityVerdict struct {
+	Verdict       string              `json:"verdict"`
+	RTTCoV        *float64            `json:"rtt_cov,omitempty"`        // nil — недостаточно данных RTT
+	ThroughputCoV *float64            `json:"throughput_cov,omitempty"` // nil — недостаточно данных throughput
+	Thresholds    StabilityThresholds `json:"thresholds"`
+	Reasons       []string            `json:"reasons,omitempty"` // какие пороги превышены
+}
+
+// coefficientOfVariation возвращает stddev/mean ряда или nil, если оценить нельзя
+func coefficientOfVariation(values []float64) *float64 {
+	if len(values) < stabilityMinSamples {
+		return nil
+	}
+	stats := metrics.Summarize(values)
+	if stats.Mean <= 0 {
+		return nil
+	}
+	cov := stats.StdDev / stats.Mean
+	return &cov
+}
+
+// EvaluateStability оценивает стабильность по рядам RTT и throughput.
+// Канал нестабилен, если коэффициент вариации хотя бы одного ряда превышает
+// порог; если ни один ряд оценить нельзя, вердикт — unknown.
+func EvaluateStability(rtt, throughput []float64, thresholds StabilityThresholds) StabilityVerdict {
+	thresholds = thresholds.withDefaults()
+	v := StabilityVerdict{
+		Verdict:       StabilityUnknown,
+		RTTCoV:        coefficientOfVariation(rtt),
+		ThroughputCoV: coefficientOfVariation(throughput),
+		Thresholds:    thresholds,
+	}
+	if v.RTTCoV == nil && v.ThroughputCoV == nil {
+		return v
+	}
+
+	if v.RTTCoV != nil && *v.RTTCoV > thresholds.MaxRTTCoV {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("RTT CoV %.2f > %.2f", *v.RTTCoV, thresholds.MaxRTTCoV))
+	}
+	if v.ThroughputCoV != nil && *v.ThroughputCoV > thresholds.MaxThroughputCoV {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("throughput CoV %.2f > %.2f", *v.ThroughputCoV, thresholds.MaxThroughputCoV))
+	}
+	if len(v.Reasons) > 0 {
+		v.Verdict = StabilityUnstable
+	} else {
+		v.Verdict = StabilityStable
+	}
+	return v
+}
+
+// stabilityFromMetrics оценивает стабильность по метрикам клиента:
+// RTT — по всем измеренным задержкам, throughput — по посекундному ряду
+func stabilityFromMetrics(cfg TestConfig, m map[string]interface{}) StabilityVerdict {
+	latencies, _ := m["Latencies"].([]float64)
+	points := extractTimeSeriesPoints(m, "TimeSeriesThroughput")
+	throughput := make([]float64, 0, len(points))
+	for _, p := range points {
+		throughput = append(throughput, p.Value)
+	}
+	return EvaluateStability(latencies, throughput, cfg.StabilityThresholds())
+}
+
+// String возвращает краткую строку для отчета
+func (v StabilityVerdict) String() string {
+	var details []string
+	if v.RTTCoV != nil {
+		details = append(details, fmt.Sprintf("RTT CoV %.2f (max %.2f)", *v.RTTCoV, v.Thresholds.MaxRTTCoV))
+	}
+	if v.ThroughputCoV != nil {
+		details = append(details, fmt.Sprintf("throughput CoV %.2f (max %.2f)", *v.ThroughputCoV, v.Thresholds.MaxThroughputCoV))
+	}
+	if len(details) == 0 {
+		return v.Verdict
+	}
+	return v.Verdict + " — " + strings.Join(details, ", ")
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// syntheticSeries строит ряд вокруг base с периодическим отклонением ±swing
+func syntheticSeries(n int, base, swing float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = base + swing*math.Sin(float64(i))
+	}
+	return values
+}
+
+func TestEvaluateStability(t *testing.T) {
+	stableRTT := syntheticSeries(200, 20, 1)
+	stableThroughput := syntheticSeries(30, 100, 3)
+	jitteryRTT := syntheticSeries(200, 20, 18)
+	jitteryThroughput := syntheticSeries(30, 100, 70)
+
+	for _, tc := range []struct {
+		name       string
+		rtt        []float64
+		throughput []float64
+		thresholds StabilityThresholds
+		want       string
+		reasons    int
+	}{
+		{"stable link", stableRTT, stableThroughput, StabilityThresholds{}, StabilityStable, 0},
+		{"jittery RTT", jitteryRTT, stableThroughput, StabilityThresholds{}, StabilityUnstable, 1},
+		{"jittery throughput", stableRTT, jitteryThroughput, StabilityThresholds{}, StabilityUnstable, 1},
+		{"jittery link", jitteryRTT, jitteryThroughput, StabilityThresholds{}, StabilityUnstable, 2},
+		{"loose thresholds", jitteryRTT, jitteryThroughput, StabilityThresholds{MaxRTTCoV: 1, MaxThroughputCoV: 1}, StabilityStable, 0},
+		{"strict thresholds", stableRTT, stableThroughput, StabilityThresholds{MaxRTTCoV: 0.01, MaxThroughputCoV: 0.5}, StabilityUnstable, 1},
+		{"RTT only", jitteryRTT, nil, StabilityThresholds{}, StabilityUnstable, 1},
+		{"not enough samples", []float64{10, 20}, []float64{0, 0, 0}, StabilityThresholds{}, StabilityUnknown, 0},
+	} {
+		v := EvaluateStability(tc.rtt, tc.throughput, tc.thresholds)
+		if v.Verdict != tc.want || len(v.Reasons) != tc.reasons {
+			t.Errorf("%s: expected %s with %d reasons, got %s %v", tc.name, tc.want, tc.reasons, v.Verdict, v.Reasons)
+		}
+	}
+}
+
+func TestEvaluateStabilityCoV(t *testing.T) {
+	// Среднее 5, stddev 2 — CoV 0.4
+	v := EvaluateStability([]float64{2, 4, 4, 4, 5, 5, 7, 9}, nil, StabilityThresholds{})
+	if v.RTTCoV == nil || math.Abs(*v.RTTCoV-0.4) > 1e-12 {
+		t.Fatalf("Expected RTT CoV 0.4, got %v", v.RTTCoV)
+	}
+	if v.ThroughputCoV != nil {
+		t.Errorf("Expected no throughput CoV without samples, got %f", *v.ThroughputCoV)
+	}
+	if v.Thresholds.MaxRTTCoV != DefaultStabilityRTTCoV || v.Thresholds.MaxThroughputCoV != DefaultStabilityThroughputCoV {
+		t.Errorf("Expected default thresholds, got %+v", v.Thresholds)
+	}
+}
+
+func TestStabilityInReport(t *testing.T) {
+	throughput := make([]interface{}, 0, 10)
+	for i, value := range syntheticSeries(10, 100, 90) {
+		throughput = append(throughput, map[string]interface{}{"Time": float64(i), "Value": value})
+	}
+	m := map[string]interface{}{
+		"Latencies":            syntheticSeries(50, 20, 0.5),
+		"TimeSeriesThroughput": throughput,
+	}
+
+	md := makeReportMarkdown(TestConfig{}, m)
+	if !strings.Contains(md, "- Stability: unstable — RTT CoV") {
+		t.Errorf("Expected an unstable verdict in the report summary, got:\n%s", md)
+	}
+
+	cfg := TestConfig{StabilityThroughputCoV: 2}
+	if schema := CreateReportSchema(cfg, m); schema.Stability.Verdict != StabilityStable {
+		t.Errorf("Expected the configured threshold to make the link stable, got %+v", schema.Stability)
+	}
+}
@@ -0,0 +1,314 @@
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"quic-test/internal"
+)
+
+// CCRunFunc выполняет один прогон сценария с заданным алгоритмом управления перегрузкой
+type CCRunFunc func(ctx context.Context, algorithm string) (*TestResult, error)
+
+// CCCompareEntry описывает результат одного алгоритма в сравнении
+type CCCompareEntry struct {
+	Algorithm string      `json:"algorithm"`
+	Rank      int         `json:"rank,omitempty"`
+	Score     float64     `json:"score"` // goodput (Mbps) на мс p95 RTT
+	Skipped   bool        `json:"skipped,omitempty"`
+	Note      string      `json:"note,omitempty"`
+	Result    *TestResult `json:"result,omitempty"`
+}
+
+// CCCompareSummary содержит ранжированные результаты сравнения алгоритмов
+type CCCompareSummary struct {
+	GeneratedAt    time.Time        `json:"generated_at"`
+	Seed           int64            `json:"seed"`
+	Conditions     string           `json:"conditions,omitempty"` // сетевой профиль или сценарий и эмуляция прогонов
+	Entries        []CCCompareEntry `json:"entries"`
+	Recommendation string           `json:"recommendation"`
+}
+
+// ParseCCList разбирает список алгоритмов из флага --compare-cc
+func ParseCCList(list string) []string {
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		algorithms = append(algorithms, name)
+	}
+	return algorithms
+}
+
+// RunCCComparison прогоняет сценарий для каждого алгоритма последовательно и ранжирует результаты.
+// Неподдерживаемые алгоритмы и неудачные прогоны пропускаются с пояснением.
+func RunCCComparison(ctx context.Context, algorithms []string, seed int64, run CCRunFunc) *CCCompareSummary {
+	summary := &CCCompareSummary{
+		GeneratedAt: time.Now(),
+		Seed:        seed,
+	}
+
+	for _, algorithm := range algorithms {
+		entry := CCCompareEntry{Algorithm: algorithm}
+
+		if !internal.IsValidCongestionControl(algorithm) {
+			entry.Skipped = true
+			entry.Note = "unsupported congestion control algorithm"
+			summary.Entries = append(summary.Entries, entry)
+			continue
+		}
+
+		if ctx.Err() != nil {
+			entry.Skipped = true
+			entry.Note = "comparison interrupted"
+			summary.Entries = append(summary.Entries, entry)
+			continue
+		}
+
+		fmt.Printf("🔄 Running %s...\n", algorithm)
+		result, err := run(ctx, algorithm)
+		if err != nil {
+			entry.Skipped = true
+			entry.Note = err.Error()
+		} else {
+			entry.Result = result
+			entry.Score = ccScore(result)
+		}
+		summary.Entries = append(summary.Entries, entry)
+	}
+
+	summary.rank()
+	return summary
+}
+
+// ccScore оценивает компромисс пропускной способности и задержки (power = goodput / p95 RTT)
+func ccScore(result *TestResult) float64 {
+	if result == nil {
+		return 0
+	}
+	latency := result.LatencyP95Ms
+	if latency < 1 {
+		latency = 1
+	}
+	return result.GoodputMbps / latency
+}
+
+// rank упорядочивает записи по score и формирует рекомендацию
+func (s *CCCompareSummary) rank() {
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		a, b := s.Entries[i], s.Entries[j]
+		if a.Skipped != b.Skipped {
+			return !a.Skipped
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Algorithm < b.Algorithm
+	})
+
+	var ranked []*CCCompareEntry
+	for i := range s.Entries {
+		if s.Entries[i].Skipped {
+			continue
+		}
+		s.Entries[i].Rank = len(ranked) + 1
+		ranked = append(ranked, &s.Entries[i])
+	}
+
+	if len(ranked) == 0 {
+		s.Recommendation = "no algorithm completed successfully"
+		return
+	}
+
+	best := ranked[0]
+	s.Recommendation = fmt.Sprintf("%s offers the best throughput/latency tradeoff (goodput %.2f Mbps, p95 RTT %.2f ms)",
+		best.Algorithm, best.Result.GoodputMbps, best.Result.LatencyP95Ms)
+
+	// Отдельно отмечаем, если лидер по пропускной способности другой
+	fastest := best
+	for _, e := range ranked {
+		if e.Result.GoodputMbps > fastest.Result.GoodputMbps {
+			fastest = e
+		}
+	}
+	if fastest != best {
+		s.Recommendation += fmt.Sprintf("; %s maximizes raw goodput (%.2f Mbps) at higher latency",
+			fastest.Algorithm, fastest.Result.GoodputMbps)
+	}
+}
+
+// PrintTable выводит ранжированную таблицу в человекочитаемом виде
+func (s *CCCompareSummary) PrintTable(w io.Writer) {
+	fmt.Fprintf(w, "\nCongestion Control Comparison (seed %d)\n", s.Seed)
+	fmt.Fprintf(w, "=======================================\n")
+	if s.Conditions != "" {
+		fmt.Fprintf(w, "Conditions: %s\n", s.Conditions)
+	}
+	fmt.Fprintf(w, "%-4s %-8s %12s %12s %10s %10s %9s %8s\n",
+		"Rank", "CC", "Goodput", "Throughput", "p95 RTT", "Loss", "Fairness", "Score")
+	for _, e := range s.Entries {
+		if e.Skipped {
+			fmt.Fprintf(w, "%-4s %-8s skipped: %s\n", "-", e.Algorithm, e.Note)
+			continue
+		}
+		r := e.Result
+		fmt.Fprintf(w, "%-4d %-8s %7.2f Mbps %7.2f Mbps %7.2f ms %9.2f%% %9.3f %8.3f\n",
+			e.Rank, e.Algorithm, r.GoodputMbps, r.ThroughputMbps, r.LatencyP95Ms, r.LossRatePercent, r.FairnessIndex, e.Score)
+	}
+	fmt.Fprintf(w, "\nRecommendation: %s\n", s.Recommendation)
+}
+
+// Markdown возвращает ранжированную таблицу в формате Markdown
+func (s *CCCompareSummary) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Congestion Control Comparison\n\n")
+	if s.Conditions != "" {
+		fmt.Fprintf(&b, "**Conditions:** %s  \n", s.Conditions)
+	}
+	fmt.Fprintf(&b, "**Seed:** %d\n\n", s.Seed)
+	fmt.Fprintf(&b, "| Rank | CC | Goodput (Mbps) | Throughput (Mbps) | p95 RTT (ms) | Loss (%%) | Fairness | Score |\n")
+	fmt.Fprintf(&b, "|---|---|---|---|---|---|---|---|\n")
+	for _, e := range s.Entries {
+		if e.Skipped {
+			fmt.Fprintf(&b, "| - | %s | skipped: %s | | | | | |\n", e.Algorithm, strings.ReplaceAll(e.Note, "|", "\\|"))
+			continue
+		}
+		r := e.Result
+		fmt.Fprintf(&b, "| %d | %s | %.2f | %.2f | %.2f | %.2f | %.3f | %.3f |\n",
+			e.Rank, e.Algorithm, r.GoodputMbps, r.ThroughputMbps, r.LatencyP95Ms, r.LossRatePercent, r.FairnessIndex, e.Score)
+	}
+	fmt.Fprintf(&b, "\n**Recommendation:** %s\n", s.Recommendation)
+	return b.String()
+}
+
+// SaveJSON сохраняет сводку сравнения в JSON
+func (s *CCCompareSummary) SaveJSON(path string) error {
+	return saveSummaryJSON(path, s)
+}
+
+// SaveMarkdown сохраняет таблицу сравнения в Markdown
+func (s *CCCompareSummary) SaveMarkdown(path string) error {
+	if err := os.WriteFile(path, []byte(s.Markdown()), 0600); err != nil {
+		return fmt.Errorf("failed to write comparison summary: %w", err)
+	}
+	return nil
+}
+
+// saveSummaryJSON сохраняет сводку любого сравнения в JSON
+func saveSummaryJSON(path string, summary interface{}) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal comparison summary: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write comparison summary: %w", err)
+	}
+	return nil
+}
+
+// NewSubprocessCCRun создает CCRunFunc, который запускает бинарник в режиме test
+// с указанными аргументами и читает JSON-отчет прогона
+func NewSubprocessCCRun(binary string, baseArgs []string, outputDir string, verbose bool) CCRunFunc {
+	return func(ctx context.Context, algorithm string) (*TestResult, error) {
+		reportPath := filepath.Join(outputDir, fmt.Sprintf("cc-%s.json", algorithm))
+		args := append(append([]string{}, baseArgs...), "--cc", algorithm)
+
+		result, _, err := runReportSubprocess(ctx, binary, args, reportPath, verbose)
+		if err != nil {
+			return nil, err
+		}
+		result.ScenarioID = "cc-" + algorithm
+		result.CCState = algorithm
+		return result, nil
+	}
+}
+
+// runReportSubprocess запускает бинарник с JSON-отчетом в reportPath и
+// возвращает результат прогона и потребленные процессом ресурсы
+func runReportSubprocess(ctx context.Context, binary string, args []string, reportPath string, verbose bool) (*TestResult, ResourceUsage, error) {
+	args = append(append([]string{}, args...),
+		"--report", reportPath,
+		"--report-format", "json",
+	)
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	if verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		return nil, ResourceUsage{}, fmt.Errorf("run failed: %w", err)
+	}
+	usage := processUsage(cmd.ProcessState)
+
+	result, err := LoadReportResult(reportPath)
+	if err != nil {
+		return nil, usage, err
+	}
+	result.StartTime = start
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(start)
+	return result, usage, nil
+}
+
+// LoadReportResult читает JSON-отчет клиента и преобразует его в TestResult
+func LoadReportResult(path string) (*TestResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var report internal.ReportSchema
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+
+	m := report.Metrics
+	if m.BytesSent == 0 {
+		reason := fmt.Sprintf("no data was sent (%d errors)", m.Errors)
+		if len(m.TopErrors) > 0 && len(m.TopErrors[0].Samples) > 0 {
+			reason += ": " + m.TopErrors[0].Samples[0]
+		}
+		return nil, fmt.Errorf("%s", reason)
+	}
+
+	result := &TestResult{
+		GoodputMbps:     m.GoodputMbps,
+		ThroughputMbps:  m.ThroughputMbps,
+		LatencyMinMs:    m.Latency.Min,
+		LatencyMaxMs:    m.Latency.Max,
+		LatencyMeanMs:   m.Latency.Average,
+		LatencyP95Ms:    m.Latency.P95,
+		LatencyP99Ms:    m.Latency.P99,
+		LossRatePercent: m.PacketLoss,
+		FairnessIndex:   m.FairnessIndex,
+		PacketsSent:     m.PacketsSent,
+		PacketsReceived: m.PacketsReceived,
+		Passed:          m.Errors == 0,
+	}
+	for _, p := range report.TimeSeries.HandshakeTime {
+		result.Handshakes++
+		result.HandshakeTotalMs += p.Value
+	}
+	if result.Handshakes > 0 {
+		result.HandshakeMeanMs = result.HandshakeTotalMs / float64(result.Handshakes)
+	}
+	if m.Errors > 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("%d errors during run", m.Errors))
+	}
+	return result, nil
+}
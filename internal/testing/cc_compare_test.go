@@ -0,0 +1,74 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	gotesting "testing"
+)
+
+func TestRunCCComparisonRanking(t *gotesting.T) {
+	results := map[string]*TestResult{
+		"cubic": {GoodputMbps: 40, LatencyP95Ms: 80},
+		"bbr":   {GoodputMbps: 35, LatencyP95Ms: 20},
+		"bbrv3": {GoodputMbps: 30, LatencyP95Ms: 25},
+	}
+	run := func(ctx context.Context, algorithm string) (*TestResult, error) {
+		if algorithm == "reno" {
+			return nil, errors.New("run failed")
+		}
+		return results[algorithm], nil
+	}
+
+	summary := RunCCComparison(context.Background(), ParseCCList("cubic, bbr,bbrv3,vegas,reno,bbr"), 42, run)
+
+	if len(summary.Entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d", len(summary.Entries))
+	}
+
+	wantOrder := []string{"bbr", "bbrv3", "cubic"}
+	for i, name := range wantOrder {
+		e := summary.Entries[i]
+		if e.Algorithm != name || e.Rank != i+1 || e.Skipped {
+			t.Errorf("Entry %d: expected %s rank %d, got %s rank %d (skipped=%v)", i, name, i+1, e.Algorithm, e.Rank, e.Skipped)
+		}
+	}
+
+	for _, e := range summary.Entries[3:] {
+		if !e.Skipped || e.Note == "" {
+			t.Errorf("Expected %s to be skipped with a note, got %+v", e.Algorithm, e)
+		}
+	}
+
+	if summary.Seed != 42 {
+		t.Errorf("Expected seed 42, got %d", summary.Seed)
+	}
+	if summary.Recommendation == "" {
+		t.Error("Expected a recommendation")
+	}
+}
+
+func TestCCComparisonMarkdown(t *gotesting.T) {
+	run := func(ctx context.Context, algorithm string) (*TestResult, error) {
+		if algorithm == "reno" {
+			return nil, errors.New("exit | status 1")
+		}
+		return &TestResult{GoodputMbps: 12.5, ThroughputMbps: 13, LatencyP95Ms: 50, LossRatePercent: 1}, nil
+	}
+
+	summary := RunCCComparison(context.Background(), []string{"cubic", "reno"}, 7, run)
+	summary.Conditions = "network profile mobile"
+	md := summary.Markdown()
+
+	for _, want := range []string{
+		"**Conditions:** network profile mobile",
+		"| Rank | CC | Goodput (Mbps) |",
+		"| 1 | cubic | 12.50 | 13.00 | 50.00 | 1.00 |",
+		"| - | reno | skipped: exit \\| status 1 |",
+		"**Recommendation:** cubic",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, md)
+		}
+	}
+}
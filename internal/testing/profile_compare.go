@@ -0,0 +1,198 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"quic-test/internal"
+)
+
+// ProfileRunFunc выполняет один прогон сценария под эмуляцией сетевого профиля
+type ProfileRunFunc func(ctx context.Context, profile string) (*TestResult, error)
+
+// ProfileCompareEntry описывает результат одного сетевого профиля
+type ProfileCompareEntry struct {
+	Profile string                   `json:"profile"`
+	Network *internal.NetworkProfile `json:"network,omitempty"`
+	Score   float64                  `json:"score"` // goodput (Mbps) на мс p95 RTT, как в сравнении CC
+	// GoodputShare — goodput относительно лучшего профиля (0..1)
+	GoodputShare    float64     `json:"goodput_share"`
+	Skipped         bool        `json:"skipped,omitempty"`
+	Note            string      `json:"note,omitempty"`
+	Result          *TestResult `json:"result,omitempty"`
+	Recommendations []string    `json:"recommendations,omitempty"`
+}
+
+// ProfileCompareSummary содержит результаты сравнения сетевых профилей
+type ProfileCompareSummary struct {
+	GeneratedAt    time.Time             `json:"generated_at"`
+	Seed           int64                 `json:"seed"`
+	Entries        []ProfileCompareEntry `json:"entries"`
+	Recommendation string                `json:"recommendation"`
+}
+
+// ParseProfileList разбирает список профилей из флага --compare-profiles;
+// "all" означает все известные профили
+func ParseProfileList(list string) []string {
+	if strings.TrimSpace(strings.ToLower(list)) == "all" {
+		return internal.ListNetworkProfiles()
+	}
+	return ParseCCList(list)
+}
+
+// RunProfileComparison прогоняет сценарий для каждого профиля последовательно,
+// в заданном порядке. Неизвестные профили и неудачные прогоны пропускаются
+// с пояснением.
+func RunProfileComparison(ctx context.Context, profiles []string, seed int64, run ProfileRunFunc) *ProfileCompareSummary {
+	summary := &ProfileCompareSummary{
+		GeneratedAt: time.Now(),
+		Seed:        seed,
+	}
+
+	for _, name := range profiles {
+		entry := ProfileCompareEntry{Profile: name}
+
+		network, err := internal.GetNetworkProfile(name)
+		if err != nil {
+			entry.Skipped = true
+			entry.Note = "unknown network profile"
+			summary.Entries = append(summary.Entries, entry)
+			continue
+		}
+		entry.Network = network
+		entry.Recommendations = internal.GetProfileRecommendations(network)
+
+		if ctx.Err() != nil {
+			entry.Skipped = true
+			entry.Note = "comparison interrupted"
+			summary.Entries = append(summary.Entries, entry)
+			continue
+		}
+
+		fmt.Printf("🔄 Running %s (%s)...\n", name, network.Name)
+		result, err := run(ctx, name)
+		if err != nil {
+			entry.Skipped = true
+			entry.Note = err.Error()
+		} else {
+			entry.Result = result
+			entry.Score = ccScore(result)
+		}
+		summary.Entries = append(summary.Entries, entry)
+	}
+
+	summary.recommend()
+	return summary
+}
+
+// recommend сравнивает goodput профилей с лучшим и отмечает профиль с
+// наибольшей деградацией
+func (s *ProfileCompareSummary) recommend() {
+	var best, worst *ProfileCompareEntry
+	for i := range s.Entries {
+		e := &s.Entries[i]
+		if e.Skipped {
+			continue
+		}
+		if best == nil || e.Result.GoodputMbps > best.Result.GoodputMbps {
+			best = e
+		}
+		if worst == nil || e.Result.GoodputMbps < worst.Result.GoodputMbps {
+			worst = e
+		}
+	}
+	if best == nil {
+		s.Recommendation = "no profile completed successfully"
+		return
+	}
+
+	for i := range s.Entries {
+		e := &s.Entries[i]
+		if !e.Skipped && best.Result.GoodputMbps > 0 {
+			e.GoodputShare = e.Result.GoodputMbps / best.Result.GoodputMbps
+		}
+	}
+
+	s.Recommendation = fmt.Sprintf("%s delivers the highest goodput (%.2f Mbps, p95 RTT %.2f ms)",
+		best.Profile, best.Result.GoodputMbps, best.Result.LatencyP95Ms)
+	if worst != best {
+		s.Recommendation += fmt.Sprintf("; %s is hit hardest (%.0f%% of that goodput, p95 RTT %.2f ms, loss %.2f%%)",
+			worst.Profile, worst.GoodputShare*100, worst.Result.LatencyP95Ms, worst.Result.LossRatePercent)
+	}
+}
+
+// PrintTable выводит результаты профилей и рекомендации в человекочитаемом виде
+func (s *ProfileCompareSummary) PrintTable(w io.Writer) {
+	fmt.Fprintf(w, "\nNetwork Profile Comparison (seed %d)\n", s.Seed)
+	fmt.Fprintf(w, "====================================\n")
+	fmt.Fprintf(w, "%-14s %10s %8s %12s %8s %10s %10s %8s\n",
+		"Profile", "Delay", "Loss", "Goodput", "vs best", "p95 RTT", "Loss", "Score")
+	for _, e := range s.Entries {
+		if e.Skipped {
+			fmt.Fprintf(w, "%-14s skipped: %s\n", e.Profile, e.Note)
+			continue
+		}
+		r, n := e.Result, e.Network
+		fmt.Fprintf(w, "%-14s %10v %7.2f%% %7.2f Mbps %7.0f%% %7.2f ms %9.2f%% %8.3f\n",
+			e.Profile, n.Latency, n.Loss*100, r.GoodputMbps, e.GoodputShare*100, r.LatencyP95Ms, r.LossRatePercent, e.Score)
+	}
+	fmt.Fprintf(w, "\nDelay and the first loss column are the emulated profile; the rest is measured.\n")
+
+	for _, e := range s.Entries {
+		if e.Skipped || len(e.Recommendations) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", e.Profile)
+		for _, rec := range e.Recommendations {
+			fmt.Fprintf(w, "  - %s\n", rec)
+		}
+	}
+	fmt.Fprintf(w, "\nRecommendation: %s\n", s.Recommendation)
+}
+
+// SaveJSON сохраняет сводку сравнения в JSON
+func (s *ProfileCompareSummary) SaveJSON(path string) error {
+	return saveSummaryJSON(path, s)
+}
+
+// ProfileEmulationArgs возвращает флаги эмуляции сетевого профиля; нагрузка
+// (соединения, частота, размер пакетов) остается из базовой конфигурации,
+// чтобы профили различались только сетью. Переупорядочивание профили не
+// задают, поэтому оно сбрасывается.
+func ProfileEmulationArgs(profile *internal.NetworkProfile) []string {
+	var cfg internal.TestConfig
+	internal.ApplyNetworkProfile(&cfg, profile)
+	return []string{
+		"--emulate-loss", strconv.FormatFloat(cfg.EmulateLoss, 'f', -1, 64),
+		"--emulate-latency", cfg.EmulateLatency.String(),
+		"--emulate-jitter", cfg.EmulateJitter.String(),
+		"--emulate-dup", strconv.FormatFloat(cfg.EmulateDup, 'f', -1, 64),
+		"--emulate-reorder", strconv.FormatFloat(cfg.EmulateReorder, 'f', -1, 64),
+	}
+}
+
+// NewSubprocessProfileRun создает ProfileRunFunc, который запускает бинарник
+// в режиме test с эмуляцией профиля поверх указанных аргументов и читает JSON-отчет
+func NewSubprocessProfileRun(binary string, baseArgs []string, outputDir string, verbose bool) ProfileRunFunc {
+	return func(ctx context.Context, name string) (*TestResult, error) {
+		profile, err := internal.GetNetworkProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		reportPath := filepath.Join(outputDir, fmt.Sprintf("profile-%s.json", name))
+		// Флаги эмуляции идут последними и переопределяют базовые
+		args := append(append([]string{}, baseArgs...), ProfileEmulationArgs(profile)...)
+
+		result, _, err := runReportSubprocess(ctx, binary, args, reportPath, verbose)
+		if err != nil {
+			return nil, err
+		}
+		result.ScenarioID = "profile-" + name
+		return result, nil
+	}
+}
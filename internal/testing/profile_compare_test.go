@@ -0,0 +1,66 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	gotesting "testing"
+
+	"quic-test/internal"
+)
+
+func TestRunProfileComparison(t *gotesting.T) {
+	results := map[string]*TestResult{
+		"fiber":     {GoodputMbps: 50, LatencyP95Ms: 5},
+		"lte":       {GoodputMbps: 25, LatencyP95Ms: 70, LossRatePercent: 5},
+		"satellite": {GoodputMbps: 10, LatencyP95Ms: 600, LossRatePercent: 1},
+	}
+	run := func(ctx context.Context, profile string) (*TestResult, error) {
+		if profile == "edge" {
+			return nil, errors.New("run failed")
+		}
+		return results[profile], nil
+	}
+
+	summary := RunProfileComparison(context.Background(), ParseProfileList("lte,fiber, satellite,edge,dialup,lte"), 9, run)
+
+	if summary.Seed != 9 || len(summary.Entries) != 5 {
+		t.Fatalf("Unexpected summary: %+v", summary)
+	}
+	// Порядок профилей сохраняется, а не ранжируется
+	for i, name := range []string{"lte", "fiber", "satellite"} {
+		e := summary.Entries[i]
+		if e.Profile != name || e.Skipped || e.Network == nil || len(e.Recommendations) == 0 {
+			t.Errorf("Entry %d: expected completed %s with recommendations, got %+v", i, name, e)
+		}
+	}
+	if share := summary.Entries[0].GoodputShare; share != 0.5 {
+		t.Errorf("Expected lte at 50%% of the fiber goodput, got %v", share)
+	}
+	for _, e := range summary.Entries[3:] {
+		if !e.Skipped || e.Note == "" {
+			t.Errorf("Expected %s to be skipped with a note, got %+v", e.Profile, e)
+		}
+	}
+	if !strings.HasPrefix(summary.Recommendation, "fiber") || !strings.Contains(summary.Recommendation, "satellite is hit hardest (20%") {
+		t.Errorf("Unexpected recommendation: %q", summary.Recommendation)
+	}
+}
+
+func TestParseProfileListAll(t *gotesting.T) {
+	if got := ParseProfileList("all"); len(got) != len(internal.ListNetworkProfiles()) {
+		t.Errorf("Expected all profiles, got %v", got)
+	}
+}
+
+func TestProfileEmulationArgs(t *gotesting.T) {
+	profile, err := internal.GetNetworkProfile("lte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(ProfileEmulationArgs(profile), " ")
+	want := "--emulate-loss 0.05 --emulate-latency 30ms --emulate-jitter 15ms --emulate-dup 0.02 --emulate-reorder 0"
+	if args != want {
+		t.Errorf("Expected %q, got %q", want, args)
+	}
+}
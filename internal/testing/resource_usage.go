@@ -0,0 +1,23 @@
+package testing
+
+import "os"
+
+// ResourceUsage — ресурсы, потребленные процессом одного прогона.
+// В режиме test сервер и клиент работают в одном процессе, поэтому
+// значения включают обе стороны.
+type ResourceUsage struct {
+	CPUSeconds  float64 `json:"cpu_seconds"`             // user + system
+	MaxRSSBytes int64   `json:"max_rss_bytes,omitempty"` // 0 — недоступно на этой платформе
+	UDPSockets  int     `json:"udp_sockets"`             // UDP-сокеты клиента, по одному на соединение
+}
+
+// processUsage собирает ресурсы завершившегося процесса
+func processUsage(state *os.ProcessState) ResourceUsage {
+	if state == nil {
+		return ResourceUsage{}
+	}
+	return ResourceUsage{
+		CPUSeconds:  (state.UserTime() + state.SystemTime()).Seconds(),
+		MaxRSSBytes: maxRSS(state),
+	}
+}
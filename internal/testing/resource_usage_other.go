@@ -0,0 +1,10 @@
+//go:build !unix
+
+package testing
+
+import "os"
+
+// maxRSS не поддерживается на этой платформе: размер неизвестен
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package testing
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSS возвращает пиковый резидентный размер процесса в байтах
+func maxRSS(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// macOS возвращает байты, остальные системы — килобайты
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(rusage.Maxrss)
+	}
+	return int64(rusage.Maxrss) * 1024
+}
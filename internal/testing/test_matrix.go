@@ -82,6 +82,14 @@ type TestResult struct {
 	ACKDelayMs       float64 `json:"ack_delay_ms"`
 	ACKFrequency     int     `json:"ack_frequency"`
 	
+	// Метрики handshake (по одному на соединение)
+	Handshakes       int     `json:"handshakes"`
+	HandshakeMeanMs  float64 `json:"handshake_mean_ms"`
+	HandshakeTotalMs float64 `json:"handshake_total_ms"`
+	
+	// Справедливость распределения полосы (Jain's index)
+	FairnessIndex    float64 `json:"fairness_index"`
+	
 	// Метрики FEC
 	FECRedundancy    float64 `json:"fec_redundancy"`
 	FECRecoveryRate  float64 `json:"fec_recovery_rate"`
@@ -0,0 +1,176 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Предустановленные топологии сравнения
+const (
+	TopologyReuse          = "reuse"           // одно соединение, все потоки в нем
+	TopologyNewConnections = "new-connections" // отдельное соединение на каждый поток
+)
+
+// Topology описывает распределение потоков по соединениям
+type Topology struct {
+	Name        string `json:"name"`
+	Connections int    `json:"connections"`
+	Streams     int    `json:"streams"` // потоков на соединение
+}
+
+// TotalStreams возвращает общее число потоков топологии
+func (t Topology) TotalStreams() int {
+	return t.Connections * t.Streams
+}
+
+// TopologyPresets возвращает две топологии с одинаковым общим числом потоков:
+// повторное использование одного соединения и соединение на каждый поток
+func TopologyPresets(totalStreams int) []Topology {
+	return []Topology{
+		{Name: TopologyReuse, Connections: 1, Streams: totalStreams},
+		{Name: TopologyNewConnections, Connections: totalStreams, Streams: 1},
+	}
+}
+
+// TopologyRunFunc выполняет один прогон сценария с заданной топологией
+type TopologyRunFunc func(ctx context.Context, topology Topology) (*TestResult, ResourceUsage, error)
+
+// TopologyCompareEntry описывает результат одной топологии в сравнении
+type TopologyCompareEntry struct {
+	Topology  Topology       `json:"topology"`
+	Score     float64        `json:"score"` // goodput (Mbps) на мс p95 RTT, как в сравнении CC
+	Skipped   bool           `json:"skipped,omitempty"`
+	Note      string         `json:"note,omitempty"`
+	Result    *TestResult    `json:"result,omitempty"`
+	Resources *ResourceUsage `json:"resources,omitempty"`
+}
+
+// TopologyCompareSummary содержит результаты сравнения топологий
+type TopologyCompareSummary struct {
+	GeneratedAt    time.Time              `json:"generated_at"`
+	Seed           int64                  `json:"seed"`
+	TotalStreams   int                    `json:"total_streams"`
+	Entries        []TopologyCompareEntry `json:"entries"`
+	Recommendation string                 `json:"recommendation"`
+}
+
+// RunTopologyComparison прогоняет сценарий для каждой топологии последовательно.
+// Неудачные прогоны пропускаются с пояснением.
+func RunTopologyComparison(ctx context.Context, topologies []Topology, seed int64, run TopologyRunFunc) *TopologyCompareSummary {
+	summary := &TopologyCompareSummary{
+		GeneratedAt: time.Now(),
+		Seed:        seed,
+	}
+	if len(topologies) > 0 {
+		summary.TotalStreams = topologies[0].TotalStreams()
+	}
+
+	for _, topology := range topologies {
+		entry := TopologyCompareEntry{Topology: topology}
+
+		if ctx.Err() != nil {
+			entry.Skipped = true
+			entry.Note = "comparison interrupted"
+			summary.Entries = append(summary.Entries, entry)
+			continue
+		}
+
+		fmt.Printf("🔄 Running %s (%d connections × %d streams)...\n", topology.Name, topology.Connections, topology.Streams)
+		result, usage, err := run(ctx, topology)
+		if err != nil {
+			entry.Skipped = true
+			entry.Note = err.Error()
+		} else {
+			usage.UDPSockets = topology.Connections
+			entry.Result = result
+			entry.Resources = &usage
+			entry.Score = ccScore(result)
+		}
+		summary.Entries = append(summary.Entries, entry)
+	}
+
+	summary.recommend()
+	return summary
+}
+
+// recommend выбирает топологию с лучшим компромиссом и отмечает цену handshake
+func (s *TopologyCompareSummary) recommend() {
+	var best *TopologyCompareEntry
+	for i := range s.Entries {
+		e := &s.Entries[i]
+		if e.Skipped {
+			continue
+		}
+		if best == nil || e.Score > best.Score {
+			best = e
+		}
+	}
+	if best == nil {
+		s.Recommendation = "no topology completed successfully"
+		return
+	}
+
+	s.Recommendation = fmt.Sprintf("%s offers the best throughput/latency tradeoff (goodput %.2f Mbps, p95 RTT %.2f ms)",
+		best.Topology.Name, best.Result.GoodputMbps, best.Result.LatencyP95Ms)
+	for _, e := range s.Entries {
+		if e.Skipped || e.Topology.Name != TopologyNewConnections || e.Result.Handshakes == 0 {
+			continue
+		}
+		s.Recommendation += fmt.Sprintf("; %d connections spent %.2f ms in handshakes in total",
+			e.Result.Handshakes, e.Result.HandshakeTotalMs)
+	}
+}
+
+// PrintTable выводит результаты топологий в человекочитаемом виде
+func (s *TopologyCompareSummary) PrintTable(w io.Writer) {
+	fmt.Fprintf(w, "\nConnection Reuse vs New Connections (%d streams, seed %d)\n", s.TotalStreams, s.Seed)
+	fmt.Fprintf(w, "=========================================================\n")
+	fmt.Fprintf(w, "%-16s %9s %12s %12s %10s %12s %12s %8s %10s\n",
+		"Topology", "Conns×Str", "Goodput", "Throughput", "p95 RTT", "Handshakes", "Handshake ms", "CPU s", "Max RSS")
+	for _, e := range s.Entries {
+		shape := fmt.Sprintf("%d×%d", e.Topology.Connections, e.Topology.Streams)
+		if e.Skipped {
+			fmt.Fprintf(w, "%-16s %9s skipped: %s\n", e.Topology.Name, shape, e.Note)
+			continue
+		}
+		r, u := e.Result, e.Resources
+		rss := "n/a"
+		if u.MaxRSSBytes > 0 {
+			rss = fmt.Sprintf("%d MiB", u.MaxRSSBytes>>20)
+		}
+		fmt.Fprintf(w, "%-16s %9s %7.2f Mbps %7.2f Mbps %7.2f ms %12d %12.2f %8.2f %10s\n",
+			e.Topology.Name, shape, r.GoodputMbps, r.ThroughputMbps, r.LatencyP95Ms,
+			r.Handshakes, r.HandshakeTotalMs, u.CPUSeconds, rss)
+	}
+	fmt.Fprintf(w, "\nResources cover the whole test-mode process (server and client).\n")
+	fmt.Fprintf(w, "Recommendation: %s\n", s.Recommendation)
+}
+
+// SaveJSON сохраняет сводку сравнения в JSON
+func (s *TopologyCompareSummary) SaveJSON(path string) error {
+	return saveSummaryJSON(path, s)
+}
+
+// NewSubprocessTopologyRun создает TopologyRunFunc, который запускает бинарник
+// в режиме test с топологией поверх указанных аргументов и читает JSON-отчет
+func NewSubprocessTopologyRun(binary string, baseArgs []string, outputDir string, verbose bool) TopologyRunFunc {
+	return func(ctx context.Context, topology Topology) (*TestResult, ResourceUsage, error) {
+		reportPath := filepath.Join(outputDir, fmt.Sprintf("topology-%s.json", topology.Name))
+		// Флаги топологии идут последними и переопределяют базовые
+		args := append(append([]string{}, baseArgs...),
+			"--connections", strconv.Itoa(topology.Connections),
+			"--streams", strconv.Itoa(topology.Streams),
+		)
+
+		result, usage, err := runReportSubprocess(ctx, binary, args, reportPath, verbose)
+		if err != nil {
+			return nil, usage, err
+		}
+		result.ScenarioID = "topology-" + topology.Name
+		return result, usage, nil
+	}
+}
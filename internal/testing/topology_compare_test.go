@@ -0,0 +1,101 @@
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	gotesting "testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+func TestTopologyPresetsKeepTotalStreams(t *gotesting.T) {
+	presets := TopologyPresets(8)
+	if len(presets) != 2 || presets[0].Name != TopologyReuse || presets[1].Name != TopologyNewConnections {
+		t.Fatalf("Expected reuse and new-connections presets, got %+v", presets)
+	}
+	for _, p := range presets {
+		if p.TotalStreams() != 8 {
+			t.Errorf("Expected 8 streams in %s, got %d", p.Name, p.TotalStreams())
+		}
+	}
+	if presets[0].Connections != 1 || presets[1].Streams != 1 {
+		t.Errorf("Unexpected topology shapes: %+v", presets)
+	}
+}
+
+func TestRunTopologyComparison(t *gotesting.T) {
+	run := func(ctx context.Context, topology Topology) (*TestResult, ResourceUsage, error) {
+		if topology.Name == TopologyReuse {
+			return &TestResult{GoodputMbps: 40, LatencyP95Ms: 20, Handshakes: 1, HandshakeTotalMs: 5},
+				ResourceUsage{CPUSeconds: 1}, nil
+		}
+		return &TestResult{GoodputMbps: 42, LatencyP95Ms: 30, Handshakes: topology.Connections, HandshakeTotalMs: 40},
+			ResourceUsage{CPUSeconds: 2}, nil
+	}
+
+	summary := RunTopologyComparison(context.Background(), TopologyPresets(8), 7, run)
+
+	if summary.TotalStreams != 8 || summary.Seed != 7 || len(summary.Entries) != 2 {
+		t.Fatalf("Unexpected summary: %+v", summary)
+	}
+	for _, e := range summary.Entries {
+		if e.Skipped || e.Resources == nil || e.Resources.UDPSockets != e.Topology.Connections {
+			t.Errorf("Expected resources with %d UDP sockets for %s, got %+v", e.Topology.Connections, e.Topology.Name, e)
+		}
+	}
+	if !strings.HasPrefix(summary.Recommendation, TopologyReuse) {
+		t.Errorf("Expected reuse to win on goodput per p95 RTT, got %q", summary.Recommendation)
+	}
+	if !strings.Contains(summary.Recommendation, "8 connections spent 40.00 ms in handshakes") {
+		t.Errorf("Expected the handshake overhead in the recommendation, got %q", summary.Recommendation)
+	}
+}
+
+func TestRunTopologyComparisonSkipsFailedRuns(t *gotesting.T) {
+	run := func(ctx context.Context, topology Topology) (*TestResult, ResourceUsage, error) {
+		return nil, ResourceUsage{}, errors.New("run failed")
+	}
+	summary := RunTopologyComparison(context.Background(), TopologyPresets(4), 1, run)
+	for _, e := range summary.Entries {
+		if !e.Skipped || e.Note != "run failed" || e.Resources != nil {
+			t.Errorf("Expected %s to be skipped with a note, got %+v", e.Topology.Name, e)
+		}
+	}
+	if summary.Recommendation != "no topology completed successfully" {
+		t.Errorf("Unexpected recommendation: %q", summary.Recommendation)
+	}
+}
+
+func TestLoadReportResultHandshakes(t *gotesting.T) {
+	report := internal.ReportSchema{
+		Metrics: internal.MetricsSchema{BytesSent: 1000},
+		TimeSeries: internal.TimeSeriesSchema{
+			HandshakeTime: []internal.TimeSeriesPoint{
+				{Timestamp: time.Now(), Value: 10},
+				{Timestamp: time.Now(), Value: 30},
+			},
+		},
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := LoadReportResult(path)
+	if err != nil {
+		t.Fatalf("LoadReportResult failed: %v", err)
+	}
+	if result.Handshakes != 2 || result.HandshakeTotalMs != 40 || result.HandshakeMeanMs != 20 {
+		t.Errorf("Expected 2 handshakes of 40 ms in total, got %d, %.2f ms (mean %.2f)",
+			result.Handshakes, result.HandshakeTotalMs, result.HandshakeMeanMs)
+	}
+}
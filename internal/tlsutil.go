@@ -1,17 +1,92 @@
 package internal
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"math/big"
 	"net"
+	"os"
+	"strings"
 	"time"
 )
 
+// DefaultCertHosts — SAN самоподписанного сертификата, если --cert-hosts не задан
+var DefaultCertHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// DefaultCertValidity — срок самоподписанного сертификата по умолчанию.
+// Браузеры принимают serverCertificateHashes WebTransport только для
+// сертификатов ECDSA со сроком не больше двух недель.
+const DefaultCertValidity = 14 * 24 * time.Hour
+
+// GenerateSelfSignedCert создает самоподписанный сертификат ECDSA P-256 с
+// SAN из hosts: IP-адреса попадают в IP SAN, остальное — в DNS SAN. Пустой
+// hosts заменяется DefaultCertHosts, validFor <= 0 — DefaultCertValidity.
+func GenerateSelfSignedCert(hosts []string, validFor time.Duration) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = DefaultCertHosts
+	}
+	if validFor <= 0 {
+		validFor = DefaultCertValidity
+	}
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate serial number: %w", err)
+	}
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"quic-test"}, CommonName: hosts[0]},
+		NotBefore:             now,
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			return tls.Certificate{}, errors.New("empty certificate host")
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parse certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv, Leaf: leaf}, nil
+}
+
+// CertificateHash возвращает SHA-256 первого сертификата цепочки в hex —
+// значение для serverCertificateHashes WebTransport
+func CertificateHash(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	return fmt.Sprintf("%x", sum)
+}
+
 // GenerateSelfSignedTLS генерирует self-signed сертификат и ключ для TLS
 func GenerateSelfSignedTLS() (certPEM, keyPEM []byte) {
 	priv, _ := rsa.GenerateKey(rand.Reader, 2048)
@@ -74,4 +149,64 @@ func GenerateTLSConfig(noTLS bool) *tls.Config {
 		NextProtos:   []string{"quic-test"},
 		MinVersion:   tls.VersionTLS12,
 	}
-} 
\ No newline at end of file
+} 
+// InsecureSkipVerifyWarning выводится, когда клиент не проверяет сертификат сервера
+const InsecureSkipVerifyWarning = "⚠️  WARNING: server certificate verification is disabled (--insecure / --no-tls). The server is not authenticated; any host on the path can answer in its place."
+
+// ClientTLSConfig создает TLS-конфигурацию клиента. Сертификат сервера
+// проверяется по системным корневым сертификатам, а с caCertPath — только по
+// указанному CA (например, для самоподписанного сервера). insecure отключает
+// проверку полностью.
+func ClientTLSConfig(insecure bool, caCertPath string, nextProtos []string) (*tls.Config, error) {
+	conf := &tls.Config{
+		InsecureSkipVerify: insecure,
+		NextProtos:         nextProtos,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if caCertPath != "" {
+		pool, err := LoadCertPool(caCertPath)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+	return conf, nil
+}
+
+// PinCertificate заставляет conf принимать только сертификат cert, независимо
+// от имени сервера и корневых сертификатов. Проверка выполняется в
+// VerifyConnection, поэтому действует и для возобновленных сессий.
+func PinCertificate(conf *tls.Config, cert *x509.Certificate) {
+	conf.InsecureSkipVerify = true
+	conf.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 || !state.PeerCertificates[0].Equal(cert) {
+			return errors.New("server certificate is not the one generated for this test")
+		}
+		return nil
+	}
+}
+
+// LoadCertPool читает PEM-файл с одним или несколькими сертификатами CA
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// SplitCertHosts разбирает значение --cert-hosts: хосты через запятую,
+// пробелы вокруг них отбрасываются
+func SplitCertHosts(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
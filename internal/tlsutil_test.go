@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// startSelfSignedServer запускает QUIC-сервер с самоподписанным сертификатом
+// и возвращает его адрес и PEM сертификата
+func startSelfSignedServer(t *testing.T) (string, []byte) {
+	t.Helper()
+	certPEM, keyPEM := GenerateSelfSignedTLS()
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"quic-test"}}, nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			conn.CloseWithError(0, "")
+		}
+	}()
+	return listener.Addr().String(), certPEM
+}
+
+func dialWith(t *testing.T, addr string, tlsConf *tls.Config) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, addr, tlsConf, nil)
+	if err == nil {
+		conn.CloseWithError(0, "")
+	}
+	return err
+}
+
+func TestClientTLSConfigVerification(t *testing.T) {
+	addr, certPEM := startSelfSignedServer(t)
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caPath, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// По умолчанию сертификат проверяется и самоподписанный отвергается
+	verified, err := ClientTLSConfig(false, "", []string{"quic-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dialWith(t, addr, verified); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Expected an untrusted certificate to be rejected, got %v", err)
+	}
+
+	insecure, err := ClientTLSConfig(true, "", []string{"quic-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dialWith(t, addr, insecure); err != nil {
+		t.Errorf("Expected --insecure to accept the certificate, got %v", err)
+	}
+
+	pinned, err := ClientTLSConfig(false, caPath, []string{"quic-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dialWith(t, addr, pinned); err != nil {
+		t.Errorf("Expected the certificate from --ca-cert to be trusted, got %v", err)
+	}
+}
+
+func TestClientTLSConfigBadCACert(t *testing.T) {
+	if _, err := ClientTLSConfig(false, filepath.Join(t.TempDir(), "missing.pem"), nil); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ClientTLSConfig(false, notPEM, nil); err == nil {
+		t.Error("Expected an error for a file without certificates")
+	}
+}
+
+func TestValidateInsecureWithCACert(t *testing.T) {
+	cfg := TestConfig{Connections: 1, Streams: 1, PacketSize: 100, Rate: 1, InsecureSkipVerify: true, CACertPath: "ca.pem"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected --insecure and --ca-cert to be rejected together")
+	}
+}
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	cert, err := GenerateSelfSignedCert([]string{"test.example", "192.0.2.10", "::1"}, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := cert.Leaf
+	if _, ok := leaf.PublicKey.(*ecdsa.PublicKey); !ok {
+		t.Errorf("Expected an ECDSA key, got %T", leaf.PublicKey)
+	}
+	if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "test.example" {
+		t.Errorf("Expected DNS SAN test.example, got %v", leaf.DNSNames)
+	}
+	if len(leaf.IPAddresses) != 2 || !leaf.IPAddresses[0].Equal(net.ParseIP("192.0.2.10")) || !leaf.IPAddresses[1].Equal(net.IPv6loopback) {
+		t.Errorf("Expected IP SANs 192.0.2.10 and ::1, got %v", leaf.IPAddresses)
+	}
+	if validity := leaf.NotAfter.Sub(leaf.NotBefore); validity != 72*time.Hour {
+		t.Errorf("Expected validity 72h, got %v", validity)
+	}
+
+	// Сертификат проверяется для каждого из своих хостов
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	for _, host := range []string{"test.example", "192.0.2.10", "::1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: pool}); err != nil {
+			t.Errorf("Expected the certificate to verify for %s: %v", host, err)
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "other.example", Roots: pool}); err == nil {
+		t.Error("Expected the certificate not to verify for a host outside its SANs")
+	}
+	if hash := CertificateHash(cert); len(hash) != 64 {
+		t.Errorf("Expected a hex SHA-256 hash, got %q", hash)
+	}
+}
+
+func TestGenerateSelfSignedCertDefaults(t *testing.T) {
+	cert, err := GenerateSelfSignedCert(nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validity := cert.Leaf.NotAfter.Sub(cert.Leaf.NotBefore); validity != DefaultCertValidity {
+		t.Errorf("Expected the default validity %v, got %v", DefaultCertValidity, validity)
+	}
+	if len(cert.Leaf.DNSNames) != 1 || cert.Leaf.DNSNames[0] != "localhost" || len(cert.Leaf.IPAddresses) != 2 {
+		t.Errorf("Expected the default hosts, got %v %v", cert.Leaf.DNSNames, cert.Leaf.IPAddresses)
+	}
+	if _, err := GenerateSelfSignedCert([]string{"localhost", " "}, time.Hour); err == nil {
+		t.Error("Expected an error for an empty host")
+	}
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// Значения quic-go v0.40 для незаданных полей quic.Config (populateConfig)
+const (
+	defaultMaxIdleTimeout                 = 30 * time.Second
+	defaultHandshakeIdleTimeout           = 5 * time.Second
+	defaultInitialStreamReceiveWindow     = 512 << 10
+	defaultMaxStreamReceiveWindow         = 6 << 20
+	defaultInitialConnectionReceiveWindow = 768 << 10
+	defaultMaxConnectionReceiveWindow     = 15 << 20
+	defaultMaxIncomingStreams             = 100
+)
+
+// transportParam связывает имя из --transport-param с полем quic.Config
+type transportParam struct {
+	set       func(conf *quic.Config, value string) error
+	effective func(conf *quic.Config) string
+}
+
+// transportParams — allow-list параметров --transport-param. Имена транспортных
+// параметров из RFC 9000 используются там, где quic-go передает их пиру;
+// остальные — настройки quic-go без отдельного флага
+var transportParams = map[string]transportParam{
+	"max_idle_timeout": durationParam(
+		func(c *quic.Config) *time.Duration { return &c.MaxIdleTimeout }, defaultMaxIdleTimeout),
+	"handshake_idle_timeout": durationParam(
+		func(c *quic.Config) *time.Duration { return &c.HandshakeIdleTimeout }, defaultHandshakeIdleTimeout),
+	"keep_alive_period": durationParam(
+		func(c *quic.Config) *time.Duration { return &c.KeepAlivePeriod }, 0),
+	"initial_max_data": windowParam(
+		func(c *quic.Config) *uint64 { return &c.InitialConnectionReceiveWindow }, defaultInitialConnectionReceiveWindow),
+	"initial_max_stream_data": windowParam(
+		func(c *quic.Config) *uint64 { return &c.InitialStreamReceiveWindow }, defaultInitialStreamReceiveWindow),
+	"max_connection_receive_window": windowParam(
+		func(c *quic.Config) *uint64 { return &c.MaxConnectionReceiveWindow }, defaultMaxConnectionReceiveWindow),
+	"max_stream_receive_window": windowParam(
+		func(c *quic.Config) *uint64 { return &c.MaxStreamReceiveWindow }, defaultMaxStreamReceiveWindow),
+	"initial_max_streams_bidi": streamsParam(
+		func(c *quic.Config) *int64 { return &c.MaxIncomingStreams }),
+	"initial_max_streams_uni": streamsParam(
+		func(c *quic.Config) *int64 { return &c.MaxIncomingUniStreams }),
+}
+
+// unsupportedTransportParams — параметры RFC 9000, которые quic-go не дает
+// настроить, с объяснением для сообщения об ошибке
+var unsupportedTransportParams = map[string]string{
+	"max_ack_delay":                       "quic-go always uses 25ms",
+	"ack_delay_exponent":                  "quic-go always uses 3",
+	"active_connection_id_limit":          "quic-go always uses 4",
+	"max_udp_payload_size":                "quic-go derives it from path MTU discovery",
+	"max_datagram_frame_size":             "use --enable-datagrams",
+	"disable_active_migration":            "quic-go does not support disabling it",
+	"initial_max_stream_data_bidi_local":  "quic-go uses one window for all streams, set initial_max_stream_data",
+	"initial_max_stream_data_bidi_remote": "quic-go uses one window for all streams, set initial_max_stream_data",
+	"initial_max_stream_data_uni":         "quic-go uses one window for all streams, set initial_max_stream_data",
+	"preferred_address":                   "quic-go does not support it",
+}
+
+// TransportParamNames возвращает отсортированный allow-list --transport-param
+func TransportParamNames() []string {
+	names := make([]string, 0, len(transportParams))
+	for name := range transportParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyTransportParams задает в conf параметры вида key=value из
+// --transport-param; неизвестные и не поддерживаемые quic-go отвергаются
+func ApplyTransportParams(conf *quic.Config, params []string) error {
+	for _, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return fmt.Errorf("transport parameter %q must be key=value", param)
+		}
+		p, known := transportParams[name]
+		if !known {
+			if reason, found := unsupportedTransportParams[name]; found {
+				return fmt.Errorf("transport parameter %q is not supported: %s", name, reason)
+			}
+			return fmt.Errorf("unknown transport parameter %q (supported: %s)", name, strings.Join(TransportParamNames(), ", "))
+		}
+		if err := p.set(conf, value); err != nil {
+			return fmt.Errorf("transport parameter %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateTransportParams проверяет параметры --transport-param, не меняя конфигурацию
+func ValidateTransportParams(params []string) error {
+	return ApplyTransportParams(&quic.Config{}, params)
+}
+
+// TransportParamFlags собирает повторяющиеся флаги --transport-param key=value
+// и отвергает ошибочные сразу при разборе
+type TransportParamFlags []string
+
+func (f *TransportParamFlags) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *TransportParamFlags) Set(value string) error {
+	if err := ValidateTransportParams([]string{value}); err != nil {
+		return err
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// EffectiveTransportParams возвращает значения всех параметров allow-list,
+// с которыми quic-go будет работать при конфигурации conf
+func EffectiveTransportParams(conf *quic.Config) map[string]string {
+	result := make(map[string]string, len(transportParams))
+	for name, p := range transportParams {
+		result[name] = p.effective(conf)
+	}
+	return result
+}
+
+// FormatTransportParams выводит параметры как key=value через запятую в порядке имен
+func FormatTransportParams(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + params[name]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// durationParam — длительность Go (30s) или число миллисекунд, как в RFC 9000
+func durationParam(field func(*quic.Config) *time.Duration, def time.Duration) transportParam {
+	return transportParam{
+		set: func(conf *quic.Config, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				ms, msErr := strconv.ParseUint(value, 10, 32)
+				if msErr != nil {
+					return fmt.Errorf("invalid duration %q", value)
+				}
+				d = time.Duration(ms) * time.Millisecond
+			}
+			if d <= 0 {
+				return fmt.Errorf("duration must be positive, got %q", value)
+			}
+			*field(conf) = d
+			return nil
+		},
+		effective: func(conf *quic.Config) string {
+			if d := *field(conf); d > 0 {
+				return d.String()
+			}
+			return def.String()
+		},
+	}
+}
+
+// windowParam — окно управления потоком в байтах
+func windowParam(field func(*quic.Config) *uint64, def uint64) transportParam {
+	return transportParam{
+		set: func(conf *quic.Config, value string) error {
+			n, err := strconv.ParseUint(value, 10, 62)
+			if err != nil || n == 0 {
+				return fmt.Errorf("invalid byte count %q", value)
+			}
+			*field(conf) = n
+			return nil
+		},
+		effective: func(conf *quic.Config) string {
+			if n := *field(conf); n > 0 {
+				return strconv.FormatUint(n, 10)
+			}
+			return strconv.FormatUint(def, 10)
+		},
+	}
+}
+
+// streamsParam — число потоков, которые может открыть пир; 0 запрещает их
+// (в quic.Config это отрицательное значение)
+func streamsParam(field func(*quic.Config) *int64) transportParam {
+	return transportParam{
+		set: func(conf *quic.Config, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid stream count %q", value)
+			}
+			if n == 0 {
+				n = -1
+			}
+			*field(conf) = n
+			return nil
+		},
+		effective: func(conf *quic.Config) string {
+			switch n := *field(conf); {
+			case n < 0:
+				return "0"
+			case n == 0:
+				return strconv.Itoa(defaultMaxIncomingStreams)
+			default:
+				return strconv.FormatInt(n, 10)
+			}
+		},
+	}
+}
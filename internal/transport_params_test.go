@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+func TestApplyTransportParams(t *testing.T) {
+	conf := &quic.Config{}
+	err := ApplyTransportParams(conf, []string{
+		"max_idle_timeout=10s",
+		"keep_alive_period=2500",
+		"initial_max_stream_data=1048576",
+		"initial_max_streams_uni=0",
+	})
+	if err != nil {
+		t.Fatalf("ApplyTransportParams: %v", err)
+	}
+	if conf.MaxIdleTimeout != 10*time.Second {
+		t.Errorf("Expected MaxIdleTimeout 10s, got %v", conf.MaxIdleTimeout)
+	}
+	// Число без единиц — миллисекунды, как в RFC 9000
+	if conf.KeepAlivePeriod != 2500*time.Millisecond {
+		t.Errorf("Expected KeepAlivePeriod 2.5s, got %v", conf.KeepAlivePeriod)
+	}
+	if conf.InitialStreamReceiveWindow != 1<<20 {
+		t.Errorf("Expected InitialStreamReceiveWindow 1MiB, got %d", conf.InitialStreamReceiveWindow)
+	}
+	// 0 потоков в quic-go задается отрицательным значением
+	if conf.MaxIncomingUniStreams != -1 {
+		t.Errorf("Expected MaxIncomingUniStreams -1, got %d", conf.MaxIncomingUniStreams)
+	}
+
+	effective := EffectiveTransportParams(conf)
+	for name, want := range map[string]string{
+		"max_idle_timeout":         "10s",
+		"initial_max_stream_data":  "1048576",
+		"initial_max_streams_uni":  "0",
+		"initial_max_streams_bidi": "100",
+		"handshake_idle_timeout":   "5s",
+	} {
+		if effective[name] != want {
+			t.Errorf("Expected effective %s=%s, got %q", name, want, effective[name])
+		}
+	}
+}
+
+func TestApplyTransportParamsOverridesFlags(t *testing.T) {
+	cfg := TestConfig{MaxIdleTimeout: time.Minute, TransportParams: []string{"max_idle_timeout=5s"}}
+	if got := CreateQUICConfig(cfg).MaxIdleTimeout; got != 5*time.Second {
+		t.Errorf("Expected --transport-param to override --max-idle-timeout, got %v", got)
+	}
+}
+
+func TestApplyTransportParamsRejects(t *testing.T) {
+	for _, tc := range []struct {
+		param string
+		want  string
+	}{
+		{"no_such_param=1", "unknown transport parameter"},
+		{"max_ack_delay=10ms", "not supported"},
+		{"active_connection_id_limit=8", "not supported"},
+		{"max_idle_timeout", "key=value"},
+		{"max_idle_timeout=soon", "invalid duration"},
+		{"initial_max_data=-1", "invalid byte count"},
+	} {
+		err := ApplyTransportParams(&quic.Config{}, []string{tc.param})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.param, tc.want, err)
+		}
+		var flags TransportParamFlags
+		if flags.Set(tc.param) == nil {
+			t.Errorf("%s: expected the flag to be rejected", tc.param)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// UDPBufferSizes — фактические размеры буферов UDP-сокета в байтах
+// (0 — неизвестно). На Linux ядро удваивает запрошенное значение под
+// служебные данные; здесь оно приведено к масштабу запроса.
+type UDPBufferSizes struct {
+	Recv int
+	Send int
+}
+
+// ApplyUDPBuffers устанавливает SO_RCVBUF/SO_SNDBUF сокета (0 — оставить
+// системное значение) и возвращает фактические размеры. Если ОС урезала
+// буфер ниже запрошенного, возвращается предупреждение с подсказкой.
+func ApplyUDPBuffers(conn *net.UDPConn, recv, send int) (UDPBufferSizes, []string) {
+	var warnings []string
+	if recv > 0 {
+		if err := conn.SetReadBuffer(recv); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to set UDP receive buffer to %d bytes: %v", recv, err))
+		}
+	}
+	if send > 0 {
+		if err := conn.SetWriteBuffer(send); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to set UDP send buffer to %d bytes: %v", send, err))
+		}
+	}
+
+	sizes, err := ReadUDPBuffers(conn)
+	if err != nil {
+		return sizes, append(warnings, fmt.Sprintf("failed to read UDP buffer sizes: %v", err))
+	}
+	if recv > 0 && sizes.Recv > 0 && sizes.Recv < recv {
+		warnings = append(warnings, fmt.Sprintf("UDP receive buffer clamped by the OS to %d bytes (requested %d); %s",
+			sizes.Recv, recv, udpBufferHint("rmem_max", recv)))
+	}
+	if send > 0 && sizes.Send > 0 && sizes.Send < send {
+		warnings = append(warnings, fmt.Sprintf("UDP send buffer clamped by the OS to %d bytes (requested %d); %s",
+			sizes.Send, send, udpBufferHint("wmem_max", send)))
+	}
+	return sizes, warnings
+}
+
+// udpBufferHint подсказывает, как поднять системный лимит буфера
+func udpBufferHint(linuxLimit string, size int) string {
+	switch runtime.GOOS {
+	case "linux":
+		return fmt.Sprintf("raise the limit with: sysctl -w net.core.%s=%d", linuxLimit, size)
+	case "darwin", "freebsd", "openbsd", "netbsd":
+		return fmt.Sprintf("raise the limit with: sysctl -w kern.ipc.maxsockbuf=%d", size*2)
+	default:
+		return "raise the OS socket buffer limit"
+	}
+}
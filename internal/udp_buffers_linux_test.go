@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func listenTestUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readSysctl(t *testing.T, name string) int {
+	t.Helper()
+	data, err := os.ReadFile("/proc/sys/net/core/" + name)
+	if err != nil {
+		t.Skipf("cannot read %s: %v", name, err)
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("unexpected %s value %q", name, data)
+	}
+	return value
+}
+
+func TestApplyUDPBuffersLinux(t *testing.T) {
+	// Размер заведомо ниже системных лимитов применяется как есть
+	const size = 64 * 1024
+	if readSysctl(t, "rmem_max") < size || readSysctl(t, "wmem_max") < size {
+		t.Skip("socket buffer limits are below the test size")
+	}
+
+	sizes, warnings := ApplyUDPBuffers(listenTestUDP(t), size, size)
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+	if sizes.Recv != size || sizes.Send != size {
+		t.Errorf("Expected %d byte buffers, got receive %d, send %d", size, sizes.Recv, sizes.Send)
+	}
+}
+
+func TestApplyUDPBuffersClampedLinux(t *testing.T) {
+	rmemMax := readSysctl(t, "rmem_max")
+	requested := rmemMax * 4
+
+	sizes, warnings := ApplyUDPBuffers(listenTestUDP(t), requested, 0)
+	if sizes.Recv >= requested {
+		t.Skipf("receive buffer was not clamped (got %d bytes)", sizes.Recv)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "net.core.rmem_max") {
+		t.Errorf("Expected a clamping warning with the sysctl hint, got %v", warnings)
+	}
+}
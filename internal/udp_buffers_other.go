@@ -0,0 +1,10 @@
+//go:build !unix
+
+package internal
+
+import "net"
+
+// ReadUDPBuffers не поддерживается на этой платформе: размеры неизвестны
+func ReadUDPBuffers(conn *net.UDPConn) (UDPBufferSizes, error) {
+	return UDPBufferSizes{}, nil
+}
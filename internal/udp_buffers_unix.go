@@ -0,0 +1,34 @@
+//go:build unix
+
+package internal
+
+import (
+	"net"
+	"runtime"
+	"syscall"
+)
+
+// ReadUDPBuffers возвращает текущие размеры буферов UDP-сокета
+func ReadUDPBuffers(conn *net.UDPConn) (UDPBufferSizes, error) {
+	var sizes UDPBufferSizes
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return sizes, err
+	}
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		if sizes.Recv, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF); sockErr != nil {
+			return
+		}
+		sizes.Send, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+	})
+	if err == nil {
+		err = sockErr
+	}
+	if runtime.GOOS == "linux" {
+		// Linux возвращает удвоенное значение (см. socket(7))
+		sizes.Recv /= 2
+		sizes.Send /= 2
+	}
+	return sizes, err
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrUDPDropsUnsupported — счетчик отброшенных ядром датаграмм недоступен на этой платформе
+var ErrUDPDropsUnsupported = errors.New("UDP receive drop counters are not supported on this platform")
+
+// WatchUDPDrops раз в interval передает в onDrops прирост счетчика
+// отброшенных ядром датаграмм сокета (см. UDPReceiveDrops). Возвращаемая
+// функция останавливает наблюдение, сделав последний замер; ее нужно вызвать
+// до закрытия сокета. Если счетчик недоступен, наблюдение не запускается.
+func WatchUDPDrops(conn *net.UDPConn, interval time.Duration, onDrops func(delta uint64)) (stop func()) {
+	last, err := UDPReceiveDrops(conn)
+	if err != nil {
+		return func() {}
+	}
+
+	sample := func() {
+		drops, err := UDPReceiveDrops(conn)
+		if err != nil || drops <= last {
+			return
+		}
+		onDrops(drops - last)
+		last = drops
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sample()
+			case <-done:
+				sample()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// UDPReceiveDrops возвращает число датаграмм, которые ядро отбросило для
+// сокета conn до того, как их прочитал QUIC (в основном из-за переполнения
+// буфера приема). Счетчик берется из колонки drops в /proc/net/udp{,6};
+// сокет находится по номеру inode.
+func UDPReceiveDrops(conn *net.UDPConn) (uint64, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var stat syscall.Stat_t
+	var statErr error
+	if err := raw.Control(func(fd uintptr) {
+		statErr = syscall.Fstat(int(fd), &stat)
+	}); err != nil {
+		return 0, err
+	}
+	if statErr != nil {
+		return 0, statErr
+	}
+	inode := strconv.FormatUint(uint64(stat.Ino), 10)
+
+	for _, path := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		drops, found, err := procUDPDrops(path, inode)
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		if found {
+			return drops, nil
+		}
+	}
+	return 0, fmt.Errorf("socket inode %s not found in /proc/net/udp", inode)
+}
+
+// procUDPDrops ищет сокет с указанным inode в таблице /proc/net/udp
+func procUDPDrops(path, inode string) (uint64, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // заголовок
+	for scanner.Scan() {
+		// sl local rem st tx:rx tr:when retrnsmt uid timeout inode ref pointer drops
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 || fields[9] != inode {
+			continue
+		}
+		drops, err := strconv.ParseUint(fields[12], 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("%s: bad drops value %q", path, fields[12])
+		}
+		return drops, true, nil
+	}
+	return 0, false, scanner.Err()
+}
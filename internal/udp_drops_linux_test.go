@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPReceiveDropsCountsOverflow(t *testing.T) {
+	receiver := listenTestUDP(t)
+	// Минимальный буфер переполняется за несколько датаграмм
+	if err := receiver.SetReadBuffer(1); err != nil {
+		t.Fatalf("SetReadBuffer failed: %v", err)
+	}
+
+	before, err := UDPReceiveDrops(receiver)
+	if err != nil {
+		t.Fatalf("UDPReceiveDrops failed: %v", err)
+	}
+
+	sender, err := net.DialUDP("udp", nil, receiver.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP failed: %v", err)
+	}
+	defer sender.Close()
+
+	// Никто не читает receiver, поэтому ядро начинает отбрасывать датаграммы
+	payload := make([]byte, 1200)
+	for i := 0; i < 200; i++ {
+		if _, err := sender.Write(payload); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		after, err := UDPReceiveDrops(receiver)
+		if err != nil {
+			t.Fatalf("UDPReceiveDrops failed: %v", err)
+		}
+		if after > before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the drop counter to grow after overflowing the buffer, still %d", after)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchUDPDropsReportsDeltas(t *testing.T) {
+	receiver := listenTestUDP(t)
+	if err := receiver.SetReadBuffer(1); err != nil {
+		t.Fatalf("SetReadBuffer failed: %v", err)
+	}
+
+	var total uint64
+	stop := WatchUDPDrops(receiver, time.Hour, func(delta uint64) { total += delta })
+
+	sender, err := net.DialUDP("udp", nil, receiver.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP failed: %v", err)
+	}
+	defer sender.Close()
+	for i := 0; i < 200; i++ {
+		sender.Write(make([]byte, 1200))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Интервал больше теста: прирост попадает в последний замер при остановке
+	stop()
+	drops, err := UDPReceiveDrops(receiver)
+	if err != nil {
+		t.Fatalf("UDPReceiveDrops failed: %v", err)
+	}
+	if total == 0 || total != drops {
+		t.Errorf("Expected the watcher to report all %d drops, got %d", drops, total)
+	}
+}
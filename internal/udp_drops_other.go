@@ -0,0 +1,10 @@
+//go:build !linux
+
+package internal
+
+import "net"
+
+// UDPReceiveDrops на этой платформе недоступен
+func UDPReceiveDrops(conn *net.UDPConn) (uint64, error) {
+	return 0, ErrUDPDropsUnsupported
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"quic-test/internal/metrics"
+)
+
+// Протокол загрузки файла по одному двунаправленному потоку QUIC:
+//
+//	клиент → сервер: "QTUPLOAD" | размер (uint64, big endian) | содержимое | SHA-256 содержимого
+//	сервер → клиент: статус (1 — контрольная сумма совпала) | SHA-256 принятого содержимого
+//
+// Пакеты обычного теста начинаются с номера (little endian, с 1), поэтому
+// заголовок загрузки с ними не путается.
+const uploadMagic = "QTUPLOAD"
+
+const (
+	uploadHeaderSize = len(uploadMagic) + 8
+	uploadAckSize    = 1 + sha256.Size
+	uploadBufferSize = 64 << 10 // файл передается частями, а не читается целиком
+)
+
+// ErrUploadTruncated — источник закончился раньше заявленного размера
+var ErrUploadTruncated = errors.New("upload source ended before the announced size")
+
+// UploadResult — итог передачи файла
+type UploadResult struct {
+	Path           string            `json:"path"`
+	Bytes          metrics.ByteCount `json:"bytes"`
+	DurationMs     float64           `json:"duration_ms"`
+	ThroughputMbps float64           `json:"throughput_mbps"`
+	Checksum       string            `json:"sha256"`
+	Verified       bool              `json:"verified"` // сервер подтвердил совпадение SHA-256
+	Error          string            `json:"error,omitempty"`
+}
+
+// IsUploadPrefix сообщает, что начало потока совпадает с заголовком
+// загрузки (prefix может быть короче заголовка)
+func IsUploadPrefix(prefix []byte) bool {
+	if len(prefix) == 0 {
+		return false
+	}
+	if len(prefix) > len(uploadMagic) {
+		prefix = prefix[:len(uploadMagic)]
+	}
+	return bytes.HasPrefix([]byte(uploadMagic), prefix)
+}
+
+// SendUpload передает size байт из content в поток и ждет подтверждения
+// сервера. Close потока должен закрывать только направление отправки, как
+// у quic.Stream. Возвращает SHA-256 отправленного содержимого и результат
+// проверки на стороне сервера.
+func SendUpload(stream io.ReadWriteCloser, content io.Reader, size int64) (sum [sha256.Size]byte, verified bool, err error) {
+	header := make([]byte, uploadHeaderSize)
+	copy(header, uploadMagic)
+	binary.BigEndian.PutUint64(header[len(uploadMagic):], uint64(size))
+	if _, err := stream.Write(header); err != nil {
+		return sum, false, fmt.Errorf("failed to send upload header: %w", err)
+	}
+
+	hash := sha256.New()
+	n, err := io.CopyBuffer(stream, io.TeeReader(io.LimitReader(content, size), hash), make([]byte, uploadBufferSize))
+	if err != nil {
+		return sum, false, fmt.Errorf("failed to send upload content: %w", err)
+	}
+	if n != size {
+		return sum, false, ErrUploadTruncated
+	}
+	copy(sum[:], hash.Sum(nil))
+	if _, err := stream.Write(sum[:]); err != nil {
+		return sum, false, fmt.Errorf("failed to send upload checksum: %w", err)
+	}
+	if err := stream.Close(); err != nil {
+		return sum, false, fmt.Errorf("failed to finish upload stream: %w", err)
+	}
+
+	ack := make([]byte, uploadAckSize)
+	if _, err := io.ReadFull(stream, ack); err != nil {
+		return sum, false, fmt.Errorf("failed to read upload acknowledgement: %w", err)
+	}
+	return sum, ack[0] == 1 && bytes.Equal(ack[1:], sum[:]), nil
+}
+
+// ReceiveUpload принимает загрузку из потока, начало которого (prefix) уже
+// прочитано, пишет содержимое в dst и отвечает клиенту результатом проверки.
+// Возвращает число принятых байт и совпадение контрольной суммы.
+func ReceiveUpload(stream io.ReadWriteCloser, prefix []byte, dst io.Writer) (int64, bool, error) {
+	r := io.MultiReader(bytes.NewReader(prefix), stream)
+
+	header := make([]byte, uploadHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, false, fmt.Errorf("failed to read upload header: %w", err)
+	}
+	if string(header[:len(uploadMagic)]) != uploadMagic {
+		return 0, false, errors.New("not an upload stream")
+	}
+	size := int64(binary.BigEndian.Uint64(header[len(uploadMagic):]))
+
+	hash := sha256.New()
+	n, err := io.CopyBuffer(io.MultiWriter(dst, hash), io.LimitReader(r, size), make([]byte, uploadBufferSize))
+	if err != nil {
+		return n, false, fmt.Errorf("failed to receive upload content: %w", err)
+	}
+	if n != size {
+		return n, false, ErrUploadTruncated
+	}
+
+	expected := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(r, expected); err != nil {
+		return n, false, fmt.Errorf("failed to read upload checksum: %w", err)
+	}
+	received := hash.Sum(nil)
+	verified := bytes.Equal(expected, received)
+
+	ack := make([]byte, 0, uploadAckSize)
+	if verified {
+		ack = append(ack, 1)
+	} else {
+		ack = append(ack, 0)
+	}
+	ack = append(ack, received...)
+	if _, err := stream.Write(ack); err != nil {
+		return n, verified, fmt.Errorf("failed to acknowledge upload: %w", err)
+	}
+	return n, verified, stream.Close()
+}
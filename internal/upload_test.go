@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// pipeStream — одно направление двунаправленного потока; Close закрывает
+// только отправку, как у quic.Stream
+type pipeStream struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func (s pipeStream) Read(p []byte) (int, error)  { return s.r.Read(p) }
+func (s pipeStream) Write(p []byte) (int, error) { return s.w.Write(p) }
+func (s pipeStream) Close() error                { return s.w.Close() }
+
+func newStreamPair() (client, server pipeStream) {
+	toServer, fromClient := io.Pipe()
+	toClient, fromServer := io.Pipe()
+	return pipeStream{r: toClient, w: fromClient}, pipeStream{r: toServer, w: fromServer}
+}
+
+// receive принимает загрузку так же, как сервер: после первого чтения
+func receive(t *testing.T, stream pipeStream, dst io.Writer) (int64, bool, error) {
+	t.Helper()
+	prefix := make([]byte, 4)
+	n, err := io.ReadFull(stream, prefix)
+	if err != nil || !IsUploadPrefix(prefix[:n]) {
+		t.Errorf("Expected an upload prefix, got %q (%v)", prefix[:n], err)
+	}
+	return ReceiveUpload(stream, prefix[:n], dst)
+}
+
+func TestUploadRoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("quic-test upload "), 10000)
+	client, server := newStreamPair()
+
+	var received bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		_, verified, err := receive(t, server, &received)
+		if err == nil && !verified {
+			err = errors.New("checksum mismatch")
+		}
+		done <- err
+	}()
+
+	_, verified, err := SendUpload(client, bytes.NewReader(content), int64(len(content)))
+	if err != nil || !verified {
+		t.Fatalf("Expected a verified upload, got %v, %v", verified, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("ReceiveUpload failed: %v", err)
+	}
+	if !bytes.Equal(received.Bytes(), content) {
+		t.Errorf("Received %d bytes that differ from the %d sent", received.Len(), len(content))
+	}
+}
+
+func TestUploadDetectsCorruption(t *testing.T) {
+	client, server := newStreamPair()
+	go func() {
+		// Заголовок, содержимое и контрольная сумма другого содержимого
+		header := []byte(uploadMagic + "\x00\x00\x00\x00\x00\x00\x00\x05")
+		client.Write(header)
+		client.Write([]byte("hello"))
+		client.Write(make([]byte, 32))
+		client.Close()
+		io.Copy(io.Discard, client)
+	}()
+
+	n, verified, err := receive(t, server, io.Discard)
+	if err != nil || verified || n != 5 {
+		t.Errorf("Expected 5 unverified bytes, got %d, %v, %v", n, verified, err)
+	}
+}
+
+func TestUploadTruncatedSource(t *testing.T) {
+	client, server := newStreamPair()
+	go io.Copy(io.Discard, server)
+
+	_, _, err := SendUpload(client, strings.NewReader("short"), 100)
+	if !errors.Is(err, ErrUploadTruncated) {
+		t.Errorf("Expected ErrUploadTruncated, got %v", err)
+	}
+}
+
+func TestIsUploadPrefix(t *testing.T) {
+	for prefix, want := range map[string]bool{
+		"":                       false,
+		"QT":                     true,
+		uploadMagic:              true,
+		uploadMagic + "\x00\x01": true,
+		"\x01\x00\x00":           false, // первый пакет теста с номером 1
+		"QX":                     false,
+	} {
+		if got := IsUploadPrefix([]byte(prefix)); got != want {
+			t.Errorf("IsUploadPrefix(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+}
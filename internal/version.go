@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 )
 
+// quicGoModule — путь модуля библиотеки QUIC в build info
+const quicGoModule = "github.com/quic-go/quic-go"
+
 // GetVersion читает версию из файла tag.txt
 func GetVersion() (string, error) {
 	// Ищем файл tag.txt в текущей директории и в родительских директориях
@@ -65,3 +69,42 @@ func GetVersionInfo() string {
 func PrintVersion() {
 	fmt.Println(GetVersionInfo())
 }
+
+// SuiteVersion возвращает версию сборки: из tag.txt, а если он не найден —
+// версию главного модуля из build info
+func SuiteVersion() string {
+	if version, err := GetVersion(); err == nil && version != "unknown" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
+
+// QUICGoVersion возвращает версию quic-go, с которой собран бинарник,
+// с учетом replace-директив go.mod
+func QUICGoVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	return moduleVersion(info, quicGoModule)
+}
+
+// moduleVersion ищет версию зависимости path в build info
+func moduleVersion(info *debug.BuildInfo, path string) string {
+	for _, dep := range info.Deps {
+		if dep.Path != path {
+			continue
+		}
+		if dep.Replace != nil {
+			if dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Replace.Path
+		}
+		return dep.Version
+	}
+	return "unknown"
+}
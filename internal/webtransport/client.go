@@ -3,33 +3,83 @@ package webtransport
 import (
 	"context"
 	"crypto/tls"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
+	"quic-test/internal/metrics"
+
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
 )
 
+const (
+	datagramSize     = 512                    // payload size of test datagrams
+	datagramInterval = 50 * time.Millisecond  // 20 datagrams per second
+	datagramDrain    = 500 * time.Millisecond // how long to wait for late echoes
+	
+	streamChunkSize   = 1024                   // bytes written per stream round trip
+	streamInterval    = 100 * time.Millisecond // pause between stream round trips
+	streamEchoTimeout = 5 * time.Second        // max wait for a chunk to come back
+	
+	// maxLatencySamples bounds the round trips kept for percentiles; beyond
+	// it they are reservoir-sampled
+	maxLatencySamples = 10000
+	
+	defaultConnectTimeout = 30 * time.Second
+)
+
+// ErrConnectTimeout is reported when a session could not be established
+// within the connect timeout or the caller's deadline
+var ErrConnectTimeout = errors.New("webtransport: connect timed out")
+
 // Client represents a WebTransport client
 type Client struct {
 	config   *Config
-	session  *Session
+	sessions []*Session
 	metrics  *Metrics
 	mu       sync.RWMutex
+	
+	connectSlots chan struct{} // bounds concurrent session establishment
+	
+	loopback     *Server // in-process server with Simulate
+	loopbackConn net.PacketConn
 }
 
 // Config holds WebTransport client configuration
 type Config struct {
 	URL             string            `json:"url"`
 	Duration        time.Duration     `json:"duration"`
-	Streams         int               `json:"streams"`
+	Streams         int               `json:"streams"`     // streams per session
+	
+	// RequestsPerStream is the number of request/response round trips made on
+	// each stream, which stays open between them. The session ends once all
+	// streams are done or Duration elapses, whichever comes first. Zero runs
+	// round trips until Duration.
+	RequestsPerStream int             `json:"requests_per_stream,omitempty"`
+	Sessions        int               `json:"sessions"`    // sessions opened in parallel (default 1)
+	Concurrency     int               `json:"concurrency"` // max sessions connecting at once (0 - all)
+	
+	// ConnectTimeout bounds each connect attempt (default 30s); the caller's
+	// context deadline applies as well. With Synchronous, Connect waits until
+	// every session is connected or failed and returns the first failure.
+	ConnectTimeout  time.Duration     `json:"connect_timeout,omitempty"`
+	Synchronous     bool              `json:"synchronous,omitempty"`
 	Datagrams       bool              `json:"datagrams"`
 	CertificateHash string            `json:"certificate_hash,omitempty"`
 	ALPN            []string          `json:"alpn,omitempty"`
 	Headers         map[string]string `json:"headers,omitempty"`
 	TLSConfig       *tls.Config       `json:"-"`
+	
+	// Simulate runs the sessions against an in-process loopback server
+	// instead of URL, for environments without a WebTransport server
+	Simulate        bool              `json:"simulate,omitempty"`
 }
 
 // Session represents an active WebTransport session
@@ -44,10 +94,15 @@ type Session struct {
 	Error       string                 `json:"error,omitempty"`
 	
 	// Internal fields
-	quicSession quic.Connection
-	httpClient  *http.Client
-	streams     map[string]*StreamInfo
-	mu          sync.RWMutex
+	quicSession   quic.Connection
+	connectStream http3.Stream // closing it ends the session
+	httpClient    *http.Client
+	streams       map[string]*StreamInfo
+	mu            sync.RWMutex
+	
+	ready      chan struct{} // closed once the session is connected or failed
+	readyOnce  sync.Once
+	connectErr error
 }
 
 // StreamInfo holds information about a WebTransport stream
@@ -62,83 +117,153 @@ type StreamInfo struct {
 
 // Metrics holds WebTransport performance metrics
 type Metrics struct {
-	StreamsOpened      int64   `json:"streams_opened"`
-	StreamsClosed      int64   `json:"streams_closed"`
-	DatagramsSent      int64   `json:"datagrams_sent"`
-	DatagramsReceived  int64   `json:"datagrams_received"`
-	BytesSent          int64   `json:"bytes_sent"`
-	BytesReceived      int64   `json:"bytes_received"`
-	ConnectionTime     float64 `json:"connection_time_ms"`
-	AvgStreamLatency   float64 `json:"avg_stream_latency_ms"`
-	DatagramLossRate   float64 `json:"datagram_loss_rate"`
-	ErrorCount         int64   `json:"error_count"`
-	LastError          string  `json:"last_error,omitempty"`
-	
-	mu sync.RWMutex
+	StreamsOpened       int64   `json:"streams_opened"`
+	StreamsClosed       int64   `json:"streams_closed"`
+	DatagramsSent       int64   `json:"datagrams_sent"`
+	DatagramsReceived   int64   `json:"datagrams_received"`
+	BytesSent           int64   `json:"bytes_sent"`
+	BytesReceived       int64   `json:"bytes_received"`
+	SessionsOpened      int64   `json:"sessions_opened"`
+	SessionsFailed      int64   `json:"sessions_failed"`
+	ConnectTimeouts     int64   `json:"connect_timeouts"`
+	ConnectionTime      float64 `json:"connection_time_ms"` // average over opened sessions
+	AvgStreamLatency    float64 `json:"avg_stream_latency_ms"`
+	MinStreamLatency    float64 `json:"min_stream_latency_ms"`
+	MaxStreamLatency    float64 `json:"max_stream_latency_ms"`
+	StdDevStreamLatency float64 `json:"stddev_stream_latency_ms"`
+	Requests            int64   `json:"requests"` // completed stream round trips
+	P50StreamLatency    float64 `json:"p50_stream_latency_ms"`
+	P95StreamLatency    float64 `json:"p95_stream_latency_ms"`
+	P99StreamLatency    float64 `json:"p99_stream_latency_ms"`
+	DatagramLossRate    float64 `json:"datagram_loss_rate"`
+	AvgDatagramRTT      float64 `json:"avg_datagram_rtt_ms"`
+	ErrorCount          int64   `json:"error_count"`
+	LastError           string  `json:"last_error,omitempty"`
+
+	streamLatency  metrics.RunningStats // samples behind the stream latency stats
+	latencySamples *metrics.Reservoir   // round trips behind the percentiles
+	mu             sync.RWMutex
 }
 
 // NewClient creates a new WebTransport client
 func NewClient(config *Config) *Client {
-	return &Client{
+	c := &Client{
 		config: config,
 		metrics: &Metrics{},
 	}
+	if config.Concurrency > 0 {
+		c.connectSlots = make(chan struct{}, config.Concurrency)
+	}
+	return c
 }
 
-// Connect establishes a WebTransport connection
+// Connect establishes the configured number of WebTransport sessions and
+// returns the first one; all of them are available via GetSessions
 func (c *Client) Connect(ctx context.Context) (*Session, error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	
-	sessionID := fmt.Sprintf("wt_session_%d", time.Now().Unix())
+	if c.config.Simulate && c.loopback == nil {
+		if err := c.startLoopback(); err != nil {
+			c.mu.Unlock()
+			return nil, err
+		}
+	}
 	
-	session := &Session{
-		ID:        sessionID,
-		Status:    "connecting",
-		CreatedAt: time.Now(),
-		Config:    c.config,
-		Metrics:   make(map[string]interface{}),
-		streams:   make(map[string]*StreamInfo),
+	count := c.config.Sessions
+	if count <= 0 {
+		count = 1
+	}
+	
+	now := time.Now()
+	for i := 0; i < count; i++ {
+		session := &Session{
+			ID:        fmt.Sprintf("wt_session_%d_%d", now.Unix(), i),
+			Status:    "connecting",
+			CreatedAt: now,
+			Config:    c.config,
+			Metrics:   make(map[string]interface{}),
+			streams:   make(map[string]*StreamInfo),
+			ready:     make(chan struct{}),
+		}
+		c.sessions = append(c.sessions, session)
+		
+		// Start connection in background
+		go c.establishConnection(ctx, session)
 	}
+	started := c.sessions[len(c.sessions)-count:]
+	c.mu.Unlock()
 	
-	c.session = session
+	if !c.config.Synchronous {
+		return started[0], nil
+	}
 	
-	// Start connection in background
-	go c.establishConnection(ctx, session)
+	for _, session := range started {
+		<-session.ready
+	}
+	for _, session := range started {
+		if session.connectErr != nil {
+			return started[0], fmt.Errorf("session %s: %w", session.ID, session.connectErr)
+		}
+	}
+	return started[0], nil
+}
+
+// acquireConnectSlot waits for a free connect slot when Concurrency is set;
+// the returned function releases it and is safe to call more than once
+func (c *Client) acquireConnectSlot(ctx context.Context) (func(), error) {
+	if c.connectSlots == nil {
+		return func() {}, nil
+	}
+	
+	select {
+	case c.connectSlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 	
-	return session, nil
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-c.connectSlots })
+	}, nil
 }
 
 // establishConnection handles the actual WebTransport connection establishment
 func (c *Client) establishConnection(ctx context.Context, session *Session) {
-	startTime := time.Now()
-	
 	defer func() {
 		if r := recover(); r != nil {
-			session.mu.Lock()
-			session.Status = "failed"
-			session.Error = fmt.Sprintf("Connection panic: %v", r)
-			now := time.Now()
-			session.ClosedAt = &now
-			session.mu.Unlock()
-			
-			c.metrics.mu.Lock()
-			c.metrics.ErrorCount++
-			c.metrics.LastError = session.Error
-			c.metrics.mu.Unlock()
+			c.failSession(session, "Connection panic", fmt.Errorf("%v", r))
 		}
 	}()
 	
+	releaseSlot, err := c.acquireConnectSlot(ctx)
+	if err != nil {
+		c.failSession(session, "Connection cancelled", err)
+		return
+	}
+	defer releaseSlot()
+	
+	startTime := time.Now()
+	
+	// Bound the connect attempt; the caller's deadline wins if earlier
+	connectTimeout := c.config.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	connectCtx, cancelConnect := context.WithTimeout(ctx, connectTimeout)
+	defer cancelConnect()
+	
 	// Configure TLS
 	tlsConfig := c.config.TLSConfig
 	if tlsConfig == nil {
+		// The server certificate is verified unless the caller's config
+		// says otherwise
 		tlsConfig = &tls.Config{
-			InsecureSkipVerify: true, // For testing purposes
-			NextProtos:         c.config.ALPN,
+			NextProtos: c.config.ALPN,
 		}
 		
+		// WebTransport runs inside an HTTP/3 connection
 		if len(c.config.ALPN) == 0 {
-			tlsConfig.NextProtos = []string{"wt"}
+			tlsConfig.NextProtos = []string{http3.NextProtoH3}
 		}
 	}
 	
@@ -150,33 +275,27 @@ func (c *Client) establishConnection(ctx context.Context, session *Session) {
 	roundTripper := &http3.RoundTripper{
 		TLSClientConfig: tlsConfig,
 		QuicConfig:      quicConfig,
+		EnableDatagrams: c.config.Datagrams,
 	}
 	defer roundTripper.Close()
 	
 	httpClient := &http.Client{
 		Transport: roundTripper,
-		Timeout:   30 * time.Second,
 	}
 	
 	session.mu.Lock()
 	session.httpClient = httpClient
 	session.mu.Unlock()
 	
-	// Attempt WebTransport connection
-	req, err := http.NewRequestWithContext(ctx, "CONNECT", c.config.URL, nil)
+	// Attempt WebTransport connection (extended CONNECT)
+	req, err := http.NewRequestWithContext(connectCtx, http.MethodConnect, c.config.URL, nil)
 	if err != nil {
-		session.mu.Lock()
-		session.Status = "failed"
-		session.Error = fmt.Sprintf("Failed to create request: %v", err)
-		now := time.Now()
-		session.ClosedAt = &now
-		session.mu.Unlock()
+		c.failSession(session, "Failed to create request", err)
 		return
 	}
 	
 	// Set WebTransport headers
-	req.Header.Set("Connection", "Upgrade")
-	req.Header.Set("Upgrade", "webtransport")
+	req.Proto = protocolWebTransport
 	req.Header.Set("Sec-WebTransport-Http3-Draft", "draft02")
 	
 	// Add custom headers
@@ -184,35 +303,17 @@ func (c *Client) establishConnection(ctx context.Context, session *Session) {
 		req.Header.Set(key, value)
 	}
 	
-	resp, err := httpClient.Do(req)
+	// The CONNECT stream must stay open for the lifetime of the session
+	resp, err := roundTripper.RoundTripOpt(req, http3.RoundTripOpt{DontCloseRequestStream: true})
+	releaseSlot()
 	if err != nil {
-		session.mu.Lock()
-		session.Status = "failed"
-		session.Error = fmt.Sprintf("Connection failed: %v", err)
-		now := time.Now()
-		session.ClosedAt = &now
-		session.mu.Unlock()
-		
-		c.metrics.mu.Lock()
-		c.metrics.ErrorCount++
-		c.metrics.LastError = session.Error
-		c.metrics.mu.Unlock()
+		c.failSession(session, "Connection failed", err)
 		return
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		session.mu.Lock()
-		session.Status = "failed"
-		session.Error = fmt.Sprintf("HTTP error: %d %s", resp.StatusCode, resp.Status)
-		now := time.Now()
-		session.ClosedAt = &now
-		session.mu.Unlock()
-		
-		c.metrics.mu.Lock()
-		c.metrics.ErrorCount++
-		c.metrics.LastError = session.Error
-		c.metrics.mu.Unlock()
+		c.failSession(session, "HTTP error", fmt.Errorf("%s", resp.Status))
 		return
 	}
 	
@@ -223,44 +324,152 @@ func (c *Client) establishConnection(ctx context.Context, session *Session) {
 	session.mu.Lock()
 	session.Status = "connected"
 	session.ConnectedAt = &now
+	if streamer, ok := resp.Body.(http3.HTTPStreamer); ok {
+		session.connectStream = streamer.HTTPStream()
+	}
+	if hijacker, ok := resp.Body.(http3.Hijacker); ok {
+		session.quicSession, _ = hijacker.StreamCreator().(quic.Connection)
+	}
 	session.mu.Unlock()
+	session.markReady()
 	
 	c.metrics.mu.Lock()
-	c.metrics.ConnectionTime = float64(connectionTime.Nanoseconds()) / 1e6
+	c.metrics.SessionsOpened++
+	ms := float64(connectionTime.Nanoseconds()) / 1e6
+	c.metrics.ConnectionTime += (ms - c.metrics.ConnectionTime) / float64(c.metrics.SessionsOpened)
 	c.metrics.mu.Unlock()
 	
 	// Start test operations
 	c.runTestOperations(ctx, session)
 }
 
+// failSession marks a session that could not be established as failed;
+// timeouts are recorded as ErrConnectTimeout
+func (c *Client) failSession(session *Session, reason string, err error) {
+	timedOut := isConnectTimeout(err)
+	if timedOut {
+		reason = "Connection timed out"
+		err = fmt.Errorf("%w: %v", ErrConnectTimeout, err)
+	}
+	
+	session.mu.Lock()
+	session.Status = "failed"
+	session.Error = fmt.Sprintf("%s: %v", reason, err)
+	session.connectErr = err
+	now := time.Now()
+	session.ClosedAt = &now
+	session.mu.Unlock()
+	session.markReady()
+	
+	c.metrics.mu.Lock()
+	c.metrics.SessionsFailed++
+	if timedOut {
+		c.metrics.ConnectTimeouts++
+	}
+	c.metrics.ErrorCount++
+	c.metrics.LastError = session.Error
+	c.metrics.mu.Unlock()
+}
+
+// isConnectTimeout reports whether a connect error is a timeout rather than
+// a refusal or cancellation
+func isConnectTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// markReady signals that the session is no longer connecting
+func (s *Session) markReady() {
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
 // runTestOperations performs WebTransport test operations
 func (c *Client) runTestOperations(ctx context.Context, session *Session) {
+	opsCtx, stopOps := context.WithCancel(ctx)
+	defer stopOps()
+	
 	// Create test streams
+	var wg, streams sync.WaitGroup
 	for i := 0; i < c.config.Streams; i++ {
-		go c.createTestStream(ctx, session, i)
+		streams.Add(1)
+		go func(i int) {
+			defer streams.Done()
+			c.createTestStream(opsCtx, session, i)
+		}(i)
 	}
 	
 	// Send datagrams if enabled
 	if c.config.Datagrams {
-		go c.sendDatagrams(ctx, session)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.sendDatagrams(opsCtx, session)
+		}()
+	}
+	
+	// With a fixed number of requests per stream the session is over once
+	// every stream has made them
+	var streamsDone chan struct{}
+	if c.config.RequestsPerStream > 0 && c.config.Streams > 0 {
+		streamsDone = make(chan struct{})
+		go func() {
+			streams.Wait()
+			close(streamsDone)
+		}()
 	}
 	
 	// Wait for test duration
 	timer := time.NewTimer(c.config.Duration)
 	defer timer.Stop()
 	
+	reason := "completed"
 	select {
 	case <-ctx.Done():
-		c.closeSession(session, "cancelled")
+		reason = "cancelled"
 	case <-timer.C:
-		c.closeSession(session, "completed")
+	case <-streamsDone:
 	}
+	
+	// Let streams finish and in-flight echoes arrive before the session goes away
+	stopOps()
+	streams.Wait()
+	wg.Wait()
+	c.closeSession(session, reason)
 }
 
-// createTestStream creates and tests a WebTransport stream
+// createTestStream opens a bidirectional stream and repeatedly writes a
+// chunk and reads its echo, accounting the bytes that actually moved. With
+// RequestsPerStream set, it stops after that many round trips.
 func (c *Client) createTestStream(ctx context.Context, session *Session, streamIndex int) {
 	streamID := fmt.Sprintf("stream_%d", streamIndex)
 	
+	session.mu.RLock()
+	conn := session.quicSession
+	var sessionID uint64
+	if session.connectStream != nil {
+		sessionID = uint64(session.connectStream.StreamID())
+	}
+	session.mu.RUnlock()
+	if conn == nil {
+		c.recordError("Stream open failed: no connection")
+		return
+	}
+	
+	str, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		c.recordError(fmt.Sprintf("Stream open failed: %v", err))
+		return
+	}
+	if _, err := str.Write(appendStreamHeader(nil, sessionID)); err != nil {
+		str.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+		str.CancelWrite(quic.StreamErrorCode(http3.ErrCodeNoError))
+		c.recordError(fmt.Sprintf("Stream write failed: %v", err))
+		return
+	}
+	
 	streamInfo := &StreamInfo{
 		ID:        streamID,
 		Type:      "bidirectional",
@@ -276,69 +485,205 @@ func (c *Client) createTestStream(ctx context.Context, session *Session, streamI
 	c.metrics.StreamsOpened++
 	c.metrics.mu.Unlock()
 	
-	// Simulate stream operations
-	// In a real implementation, this would use actual WebTransport stream APIs
-	ticker := time.NewTicker(100 * time.Millisecond)
+	defer c.closeStream(session, streamInfo, str)
+	
+	ticker := time.NewTicker(streamInterval)
 	defer ticker.Stop()
 	
-	testData := make([]byte, 1024) // 1KB test data
+	testData := make([]byte, streamChunkSize)
+	echo := make([]byte, streamChunkSize)
 	
-	for {
+	for request := 0; c.config.RequestsPerStream <= 0 || request < c.config.RequestsPerStream; request++ {
 		select {
 		case <-ctx.Done():
-			c.closeStream(session, streamInfo)
 			return
 		case <-ticker.C:
-			// Simulate sending data
-			streamInfo.BytesSent += int64(len(testData))
-			streamInfo.BytesRecv += int64(len(testData)) // Echo response
+			start := time.Now()
+			sent, err := str.Write(testData)
+			c.accountStreamBytes(session, streamInfo, int64(sent), 0)
+			if err != nil {
+				c.recordError(fmt.Sprintf("Stream write failed: %v", err))
+				return
+			}
 			
-			c.metrics.mu.Lock()
-			c.metrics.BytesSent += int64(len(testData))
-			c.metrics.BytesReceived += int64(len(testData))
-			c.metrics.mu.Unlock()
+			str.SetReadDeadline(start.Add(streamEchoTimeout))
+			recv, err := io.ReadFull(str, echo)
+			c.accountStreamBytes(session, streamInfo, 0, int64(recv))
+			if err != nil {
+				c.recordError(fmt.Sprintf("Stream read failed: %v", err))
+				return
+			}
+			
+			c.recordStreamLatency(float64(time.Since(start).Nanoseconds()) / 1e6)
 		}
 	}
 }
 
-// sendDatagrams sends WebTransport datagrams
+// recordStreamLatency adds a stream round trip (ms) to the latency stats
+func (c *Client) recordStreamLatency(latency float64) {
+	c.metrics.mu.Lock()
+	defer c.metrics.mu.Unlock()
+	c.metrics.Requests++
+	if c.metrics.latencySamples == nil {
+		c.metrics.latencySamples = metrics.NewReservoir(maxLatencySamples, time.Now().UnixNano())
+	}
+	c.metrics.latencySamples.Add(latency)
+	c.metrics.streamLatency.Add(latency)
+	stats := c.metrics.streamLatency.Stats()
+	c.metrics.AvgStreamLatency = stats.Mean
+	c.metrics.MinStreamLatency = stats.Min
+	c.metrics.MaxStreamLatency = stats.Max
+	c.metrics.StdDevStreamLatency = stats.StdDev
+}
+
+// accountStreamBytes adds transferred bytes to the stream and the client
+func (c *Client) accountStreamBytes(session *Session, streamInfo *StreamInfo, sent, recv int64) {
+	session.mu.Lock()
+	streamInfo.BytesSent += sent
+	streamInfo.BytesRecv += recv
+	session.mu.Unlock()
+	
+	c.metrics.mu.Lock()
+	c.metrics.BytesSent += sent
+	c.metrics.BytesReceived += recv
+	c.metrics.mu.Unlock()
+}
+
+// sendDatagrams sends numbered, timestamped datagrams that the server echoes
+// back; loss and RTT are computed from the echoes that actually arrive
 func (c *Client) sendDatagrams(ctx context.Context, session *Session) {
-	ticker := time.NewTicker(50 * time.Millisecond) // 20 datagrams per second
-	defer ticker.Stop()
+	session.mu.RLock()
+	conn := session.quicSession
+	var sessionID uint64
+	if session.connectStream != nil {
+		sessionID = uint64(session.connectStream.StreamID())
+	}
+	session.mu.RUnlock()
 	
-	datagramData := make([]byte, 512) // 512 bytes per datagram
-	sentCount := int64(0)
-	receivedCount := int64(0)
+	if conn == nil || !conn.ConnectionState().SupportsDatagrams {
+		c.recordError("Datagrams not supported by the connection")
+		return
+	}
+	
+	// The receiver outlives the sender by datagramDrain to collect late echoes
+	recvCtx, stopRecv := context.WithCancel(context.Background())
+	defer stopRecv()
+	recvDone := make(chan struct{})
+	go func() {
+		defer close(recvDone)
+		c.receiveDatagrams(recvCtx, conn, sessionID)
+	}()
+	
+	ticker := time.NewTicker(datagramInterval)
+	defer ticker.Stop()
 	
+	var seq uint64
 	for {
 		select {
 		case <-ctx.Done():
+			c.drainDatagrams(recvDone)
 			return
 		case <-ticker.C:
-			// Simulate sending datagram
-			sentCount++
+			seq++
+			buf := appendDatagramHeader(make([]byte, 0, datagramSize+8), sessionID)
+			buf = binary.BigEndian.AppendUint64(buf, seq)
+			buf = binary.BigEndian.AppendUint64(buf, uint64(time.Now().UnixNano()))
+			buf = append(buf, make([]byte, datagramSize-16)...)
 			
-			// Simulate 95% delivery rate
-			if sentCount%20 != 0 { // 5% loss
-				receivedCount++
+			if err := conn.SendDatagram(buf); err != nil {
+				c.recordError(fmt.Sprintf("Datagram send failed: %v", err))
+				continue
 			}
 			
 			c.metrics.mu.Lock()
-			c.metrics.DatagramsSent = sentCount
-			c.metrics.DatagramsReceived = receivedCount
-			c.metrics.BytesSent += int64(len(datagramData))
-			c.metrics.BytesReceived += int64(len(datagramData))
-			
-			if sentCount > 0 {
-				c.metrics.DatagramLossRate = float64(sentCount-receivedCount) / float64(sentCount)
-			}
+			c.metrics.DatagramsSent++
+			c.metrics.BytesSent += int64(len(buf))
+			c.updateDatagramLossLocked()
 			c.metrics.mu.Unlock()
 		}
 	}
 }
 
+// drainDatagrams waits until every sent datagram was echoed, the receiver
+// stopped or datagramDrain elapsed
+func (c *Client) drainDatagrams(recvDone <-chan struct{}) {
+	deadline := time.NewTimer(datagramDrain)
+	defer deadline.Stop()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	
+	for {
+		c.metrics.mu.RLock()
+		pending := c.metrics.DatagramsSent > c.metrics.DatagramsReceived
+		c.metrics.mu.RUnlock()
+		if !pending {
+			return
+		}
+		
+		select {
+		case <-recvDone:
+			return
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// receiveDatagrams accounts echoed datagrams of the session
+func (c *Client) receiveDatagrams(ctx context.Context, conn quic.Connection, sessionID uint64) {
+	for {
+		msg, err := conn.ReceiveDatagram(ctx)
+		if err != nil {
+			return
+		}
+		
+		id, payload, err := parseDatagram(msg)
+		if err != nil || id != sessionID || len(payload) < 16 {
+			continue
+		}
+		sentAt := time.Unix(0, int64(binary.BigEndian.Uint64(payload[8:16])))
+		rtt := float64(time.Since(sentAt).Nanoseconds()) / 1e6
+		
+		c.metrics.mu.Lock()
+		c.metrics.DatagramsReceived++
+		c.metrics.BytesReceived += int64(len(msg))
+		c.metrics.AvgDatagramRTT += (rtt - c.metrics.AvgDatagramRTT) / float64(c.metrics.DatagramsReceived)
+		c.updateDatagramLossLocked()
+		c.metrics.mu.Unlock()
+	}
+}
+
+// updateDatagramLossLocked recomputes the loss rate; datagrams still in
+// flight count as lost until their echo arrives
+func (c *Client) updateDatagramLossLocked() {
+	if c.metrics.DatagramsSent > 0 {
+		lost := c.metrics.DatagramsSent - c.metrics.DatagramsReceived
+		if lost < 0 {
+			lost = 0
+		}
+		c.metrics.DatagramLossRate = float64(lost) / float64(c.metrics.DatagramsSent)
+	}
+}
+
+// recordError counts an error in the client metrics
+func (c *Client) recordError(msg string) {
+	c.metrics.mu.Lock()
+	c.metrics.ErrorCount++
+	c.metrics.LastError = msg
+	c.metrics.mu.Unlock()
+}
+
 // closeStream closes a WebTransport stream
-func (c *Client) closeStream(session *Session, streamInfo *StreamInfo) {
+func (c *Client) closeStream(session *Session, streamInfo *StreamInfo, str quic.Stream) {
+	str.Close()
+	str.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+	
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if streamInfo.Status == "closed" {
+		return
+	}
 	streamInfo.Status = "closed"
 	
 	c.metrics.mu.Lock()
@@ -369,6 +714,11 @@ func (c *Client) closeSession(session *Session, reason string) {
 		}
 	}
 	
+	// Closing the CONNECT stream ends the session on the server
+	if session.connectStream != nil {
+		session.connectStream.Close()
+	}
+	
 	// Close HTTP client
 	if session.httpClient != nil {
 		if transport, ok := session.httpClient.Transport.(*http3.RoundTripper); ok {
@@ -377,11 +727,23 @@ func (c *Client) closeSession(session *Session, reason string) {
 	}
 }
 
-// GetSession returns the current session
+// GetSession returns the first session
 func (c *Client) GetSession() *Session {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.session
+	
+	if len(c.sessions) == 0 {
+		return nil
+	}
+	return c.sessions[0]
+}
+
+// GetSessions returns all sessions opened by the client
+func (c *Client) GetSessions() []*Session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	return append([]*Session(nil), c.sessions...)
 }
 
 // GetMetrics returns current metrics
@@ -389,9 +751,50 @@ func (c *Client) GetMetrics() *Metrics {
 	c.metrics.mu.RLock()
 	defer c.metrics.mu.RUnlock()
 	
+	// Percentiles are computed on demand rather than on every round trip
+	var sorted []float64
+	if c.metrics.latencySamples != nil {
+		sorted = append(sorted, c.metrics.latencySamples.Values()...)
+		sort.Float64s(sorted)
+	}
+	
 	// Return a copy
-	metrics := *c.metrics
-	return &metrics
+	return &Metrics{
+		StreamsOpened:       c.metrics.StreamsOpened,
+		StreamsClosed:       c.metrics.StreamsClosed,
+		DatagramsSent:       c.metrics.DatagramsSent,
+		DatagramsReceived:   c.metrics.DatagramsReceived,
+		BytesSent:           c.metrics.BytesSent,
+		BytesReceived:       c.metrics.BytesReceived,
+		SessionsOpened:      c.metrics.SessionsOpened,
+		SessionsFailed:      c.metrics.SessionsFailed,
+		ConnectTimeouts:     c.metrics.ConnectTimeouts,
+		ConnectionTime:      c.metrics.ConnectionTime,
+		AvgStreamLatency:    c.metrics.AvgStreamLatency,
+		MinStreamLatency:    c.metrics.MinStreamLatency,
+		MaxStreamLatency:    c.metrics.MaxStreamLatency,
+		StdDevStreamLatency: c.metrics.StdDevStreamLatency,
+		Requests:            c.metrics.Requests,
+		P50StreamLatency:    percentileOf(sorted, 50),
+		P95StreamLatency:    percentileOf(sorted, 95),
+		P99StreamLatency:    percentileOf(sorted, 99),
+		DatagramLossRate:    c.metrics.DatagramLossRate,
+		AvgDatagramRTT:      c.metrics.AvgDatagramRTT,
+		ErrorCount:          c.metrics.ErrorCount,
+		LastError:           c.metrics.LastError,
+	}
+}
+
+// percentileOf returns the p-th percentile of an ascending slice
+func percentileOf(sorted []float64, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := len(sorted) * p / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
 }
 
 // Close closes the client and cleans up resources
@@ -399,9 +802,10 @@ func (c *Client) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	if c.session != nil {
-		c.closeSession(c.session, "client_closed")
+	for _, session := range c.sessions {
+		c.closeSession(session, "client_closed")
 	}
+	c.stopLoopback()
 	
 	return nil
 }
\ No newline at end of file
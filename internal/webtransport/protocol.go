@@ -0,0 +1,40 @@
+package webtransport
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// WebTransport over HTTP/3 wire constants (draft-ietf-webtrans-http3)
+const (
+	protocolWebTransport       = "webtransport" // :protocol of the extended CONNECT
+	settingsEnableWebTransport = 0x2b603742
+	frameTypeWebTransportBidi  = 0x41 // opens a bidirectional stream of a session
+)
+
+var errShortDatagram = errors.New("webtransport: datagram too short")
+
+// appendStreamHeader prefixes a new bidirectional stream with the signal
+// frame type and the session ID it belongs to
+func appendStreamHeader(b []byte, sessionID uint64) []byte {
+	b = quicvarint.Append(b, frameTypeWebTransportBidi)
+	return quicvarint.Append(b, sessionID)
+}
+
+// appendDatagramHeader prefixes an HTTP datagram with the quarter stream ID
+// of the session's CONNECT stream (RFC 9297)
+func appendDatagramHeader(b []byte, sessionID uint64) []byte {
+	return quicvarint.Append(b, sessionID/4)
+}
+
+// parseDatagram splits an HTTP datagram into the session ID and payload
+func parseDatagram(b []byte) (uint64, []byte, error) {
+	r := bytes.NewReader(b)
+	quarterID, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, nil, errShortDatagram
+	}
+	return quarterID * 4, b[len(b)-r.Len():], nil
+}
@@ -3,12 +3,18 @@ package webtransport
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"time"
 
+	"quic-test/internal"
+
+	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
 )
 
 // Server represents a WebTransport server
@@ -18,6 +24,13 @@ type Server struct {
 	sessions map[string]*ServerSession
 	metrics  *ServerMetrics
 	mu       sync.RWMutex
+	
+	// Sessions by CONNECT stream ID for every QUIC connection, used to
+	// route streams and datagrams; one echo loop runs per connection
+	connSessions map[quic.Connection]map[uint64]*ServerSession
+
+	certHash  string    // SHA-256 of the generated certificate
+	startTime time.Time // when the server started, for the uptime
 }
 
 // ServerConfig holds WebTransport server configuration
@@ -26,6 +39,10 @@ type ServerConfig struct {
 	TLSConfig *tls.Config `json:"-"`
 	CertFile  string      `json:"cert_file,omitempty"`
 	KeyFile   string      `json:"key_file,omitempty"`
+	// CertHosts and CertValidity describe the self-signed certificate used
+	// without CertFile/KeyFile; zero values take the internal defaults
+	CertHosts    []string      `json:"cert_hosts,omitempty"`
+	CertValidity time.Duration `json:"cert_validity,omitempty"`
 }
 
 // ServerSession represents a server-side WebTransport session
@@ -38,6 +55,8 @@ type ServerSession struct {
 	Streams     map[string]*StreamInfo `json:"streams"`
 	Metrics     map[string]interface{} `json:"metrics"`
 	mu          sync.RWMutex
+	
+	streamID uint64 // CONNECT stream, identifies the session on the wire
 }
 
 // ServerMetrics holds server-side WebTransport metrics
@@ -61,17 +80,44 @@ func NewServer(config *ServerConfig) *Server {
 		config:   config,
 		sessions: make(map[string]*ServerSession),
 		metrics:  &ServerMetrics{},
+		
+		connSessions: make(map[quic.Connection]map[uint64]*ServerSession),
 	}
 }
 
 // Start starts the WebTransport server
 func (s *Server) Start(ctx context.Context) error {
+	if err := s.configure(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Starting WebTransport server on %s\n", s.config.Addr)
+
+	// Start server in background
+	go func() {
+		s.recordServeError(s.server.ListenAndServe())
+	}()
+
+	// Wait for context cancellation
+	<-ctx.Done()
+
+	// Graceful shutdown
+	return s.Stop()
+}
+
+// configure sets up TLS and the HTTP/3 server
+func (s *Server) configure() error {
+	s.startTime = time.Now()
+
 	// Configure TLS
 	tlsConfig := s.config.TLSConfig
 	if tlsConfig == nil {
 		if s.config.CertFile == "" || s.config.KeyFile == "" {
 			// Generate self-signed certificate for testing
-			tlsConfig = s.generateSelfSignedTLS()
+			var err error
+			if tlsConfig, err = s.generateSelfSignedTLS(); err != nil {
+				return err
+			}
 		} else {
 			cert, err := tls.LoadX509KeyPair(s.config.CertFile, s.config.KeyFile)
 			if err != nil {
@@ -91,36 +137,32 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/health", s.handleHealth)
 	
 	s.server = &http3.Server{
-		Addr:      s.config.Addr,
-		Handler:   mux,
-		TLSConfig: tlsConfig,
+		Addr:            s.config.Addr,
+		Handler:         mux,
+		TLSConfig:       tlsConfig,
+		EnableDatagrams: true,
+		AdditionalSettings: map[uint64]uint64{
+			settingsEnableWebTransport: 1,
+		},
+		StreamHijacker: s.hijackStream,
+	}
+	return nil
+}
+
+// recordServeError counts the error that ended serving, unless the server
+// was closed
+func (s *Server) recordServeError(err error) {
+	if err != nil && err != http.ErrServerClosed {
+		s.metrics.mu.Lock()
+		s.metrics.ErrorCount++
+		s.metrics.LastError = fmt.Sprintf("Server error: %v", err)
+		s.metrics.mu.Unlock()
 	}
-	
-	fmt.Printf("Starting WebTransport server on %s\n", s.config.Addr)
-	
-	// Start server in background
-	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			s.metrics.mu.Lock()
-			s.metrics.ErrorCount++
-			s.metrics.LastError = fmt.Sprintf("Server error: %v", err)
-			s.metrics.mu.Unlock()
-		}
-	}()
-	
-	// Wait for context cancellation
-	<-ctx.Done()
-	
-	// Graceful shutdown
-	return s.Stop()
 }
 
 // Stop stops the WebTransport server
 func (s *Server) Stop() error {
 	if s.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		
 		return s.server.Close()
 	}
 	return nil
@@ -128,13 +170,29 @@ func (s *Server) Stop() error {
 
 // handleWebTransport handles WebTransport connection requests
 func (s *Server) handleWebTransport(w http.ResponseWriter, r *http.Request) {
-	// Check for WebTransport upgrade
-	if r.Header.Get("Connection") != "Upgrade" || 
-	   r.Header.Get("Upgrade") != "webtransport" {
+	// WebTransport sessions are established with an extended CONNECT
+	if r.Method != http.MethodConnect || r.Proto != protocolWebTransport {
 		http.Error(w, "Not a WebTransport request", http.StatusBadRequest)
 		return
 	}
 	
+	streamer, ok := r.Body.(http3.HTTPStreamer)
+	if !ok {
+		http.Error(w, "WebTransport requires HTTP/3", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http3.Hijacker)
+	if !ok {
+		http.Error(w, "WebTransport requires HTTP/3", http.StatusBadRequest)
+		return
+	}
+	conn, ok := hijacker.StreamCreator().(quic.Connection)
+	if !ok {
+		http.Error(w, "WebTransport requires HTTP/3", http.StatusBadRequest)
+		return
+	}
+	str := streamer.HTTPStream()
+	
 	// Create new session
 	sessionID := fmt.Sprintf("server_session_%d", time.Now().UnixNano())
 	
@@ -146,77 +204,214 @@ func (s *Server) handleWebTransport(w http.ResponseWriter, r *http.Request) {
 		LastActive: time.Now(),
 		Streams:    make(map[string]*StreamInfo),
 		Metrics:    make(map[string]interface{}),
+		streamID:   uint64(str.StreamID()),
 	}
 	
 	s.mu.Lock()
 	s.sessions[sessionID] = session
 	s.metrics.ActiveSessions++
 	s.metrics.TotalSessions++
+	
+	newConn := false
+	if s.connSessions[conn] == nil {
+		s.connSessions[conn] = make(map[uint64]*ServerSession)
+		newConn = true
+	}
+	s.connSessions[conn][session.streamID] = session
 	s.mu.Unlock()
 	
-	// Accept WebTransport connection
+	if newConn {
+		go s.serveConn(conn)
+	}
+	
+	// Accept WebTransport connection; the headers must go out now since
+	// the handler keeps running for the whole session
 	w.Header().Set("Sec-WebTransport-Http3-Draft", "draft02")
 	w.WriteHeader(http.StatusOK)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
 	
 	// Handle session
-	s.handleSession(r.Context(), session)
+	s.handleSession(r.Context(), conn, str, session)
 }
 
-// handleSession handles a WebTransport session
-func (s *Server) handleSession(ctx context.Context, session *ServerSession) {
+// handleSession keeps a WebTransport session open until the client closes
+// its CONNECT stream or the connection goes away
+func (s *Server) handleSession(ctx context.Context, conn quic.Connection, str http3.Stream, session *ServerSession) {
 	defer func() {
 		// Clean up session
 		s.mu.Lock()
 		delete(s.sessions, session.ID)
+		if sessions := s.connSessions[conn]; sessions != nil {
+			delete(sessions, session.streamID)
+		}
 		s.metrics.ActiveSessions--
 		s.mu.Unlock()
 		
 		session.mu.Lock()
 		session.Status = "closed"
 		session.mu.Unlock()
+		
+		str.Close()
 	}()
 	
-	// Simulate session handling
-	// In a real implementation, this would handle actual WebTransport streams and datagrams
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	// The client ends the session by closing the CONNECT stream
+	streamClosed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, str)
+		close(streamClosed)
+	}()
 	
+	select {
+	case <-ctx.Done():
+	case <-conn.Context().Done():
+	case <-streamClosed:
+	}
+}
+
+// serveConn runs the per-connection datagram echo and forgets the
+// connection once it is closed
+func (s *Server) serveConn(conn quic.Connection) {
+	if conn.ConnectionState().SupportsDatagrams {
+		s.echoDatagrams(conn)
+	}
+	<-conn.Context().Done()
+	
+	s.mu.Lock()
+	delete(s.connSessions, conn)
+	s.mu.Unlock()
+}
+
+// sessionFor returns the live session with the given CONNECT stream ID
+func (s *Server) sessionFor(conn quic.Connection, sessionID uint64) *ServerSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.connSessions[conn][sessionID]
+}
+
+// hijackStream takes over bidirectional WebTransport streams from HTTP/3
+func (s *Server) hijackStream(ft http3.FrameType, conn quic.Connection, str quic.Stream, err error) (bool, error) {
+	if err != nil || ft != frameTypeWebTransportBidi {
+		return false, err
+	}
+	go s.echoStream(conn, str)
+	return true, nil
+}
+
+// echoStream writes everything received on a session stream back to the
+// client until it closes its side
+func (s *Server) echoStream(conn quic.Connection, str quic.Stream) {
+	sessionID, err := quicvarint.Read(quicvarint.NewReader(str))
+	session := s.sessionFor(conn, sessionID)
+	if err != nil || session == nil {
+		str.CancelRead(quic.StreamErrorCode(http3.ErrCodeStreamCreationError))
+		str.CancelWrite(quic.StreamErrorCode(http3.ErrCodeStreamCreationError))
+		return
+	}
+	
+	info := &StreamInfo{
+		ID:        fmt.Sprintf("stream_%d", str.StreamID()),
+		Type:      "bidirectional",
+		CreatedAt: time.Now(),
+		Status:    "open",
+	}
+	session.mu.Lock()
+	session.Streams[info.ID] = info
+	session.mu.Unlock()
+	
+	s.metrics.mu.Lock()
+	s.metrics.TotalStreams++
+	s.metrics.mu.Unlock()
+	
+	buf := make([]byte, 16*1024)
 	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
+		n, readErr := str.Read(buf)
+		if n > 0 {
+			if _, err := str.Write(buf[:n]); err != nil {
+				readErr = err
+			}
+			
 			session.mu.Lock()
 			session.LastActive = time.Now()
-			
-			// Simulate receiving data
-			session.Metrics["bytes_received"] = s.metrics.BytesReceived
-			session.Metrics["streams_count"] = len(session.Streams)
+			info.BytesRecv += int64(n)
+			info.BytesSent += int64(n)
 			session.mu.Unlock()
 			
-			// Update server metrics
 			s.metrics.mu.Lock()
-			s.metrics.BytesReceived += 1024 // Simulate 1KB received per second
-			s.metrics.BytesSent += 1024     // Simulate 1KB sent per second
+			s.metrics.BytesReceived += int64(n)
+			s.metrics.BytesSent += int64(n)
+			s.metrics.mu.Unlock()
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	str.Close()
+	
+	session.mu.Lock()
+	info.Status = "closed"
+	session.mu.Unlock()
+}
+
+// echoDatagrams sends every datagram of a live session back to the client
+// unchanged, so the client can measure loss and RTT from its own payload
+func (s *Server) echoDatagrams(conn quic.Connection) {
+	for {
+		msg, err := conn.ReceiveDatagram(conn.Context())
+		if err != nil {
+			return
+		}
+		
+		sessionID, _, err := parseDatagram(msg)
+		if err != nil {
+			continue
+		}
+		session := s.sessionFor(conn, sessionID)
+		if session == nil {
+			continue
+		}
+		
+		if err := conn.SendDatagram(msg); err != nil {
+			s.metrics.mu.Lock()
+			s.metrics.ErrorCount++
+			s.metrics.LastError = fmt.Sprintf("Datagram echo failed: %v", err)
 			s.metrics.mu.Unlock()
+			continue
 		}
+		
+		session.mu.Lock()
+		session.LastActive = time.Now()
+		echoed, _ := session.Metrics["datagrams_echoed"].(int64)
+		session.Metrics["datagrams_echoed"] = echoed + 1
+		session.mu.Unlock()
+		
+		s.metrics.mu.Lock()
+		s.metrics.TotalDatagrams++
+		s.metrics.BytesReceived += int64(len(msg))
+		s.metrics.BytesSent += int64(len(msg))
+		s.metrics.mu.Unlock()
 	}
 }
 
 // handleHealth handles health check requests
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	metrics := s.GetMetrics()
+	uptime := time.Since(s.startTime)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	
-	response := map[string]interface{}{
+	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":          "healthy",
-		"active_sessions": s.metrics.ActiveSessions,
-		"total_sessions":  s.metrics.TotalSessions,
-		"uptime":          time.Since(time.Now()).String(),
-	}
-	
-	fmt.Fprintf(w, `{"status":"healthy","active_sessions":%d,"total_sessions":%d}`,
-		s.metrics.ActiveSessions, s.metrics.TotalSessions)
+		"uptime":          uptime.Round(time.Second).String(),
+		"uptime_seconds":  uptime.Seconds(),
+		"active_sessions": metrics.ActiveSessions,
+		"total_sessions":  metrics.TotalSessions,
+		"total_streams":   metrics.TotalStreams,
+		"total_datagrams": metrics.TotalDatagrams,
+		"bytes_received":  metrics.BytesReceived,
+		"bytes_sent":      metrics.BytesSent,
+	})
 }
 
 // GetSessions returns all active sessions
@@ -243,20 +438,44 @@ func (s *Server) GetSession(sessionID string) *ServerSession {
 
 // GetMetrics returns server metrics
 func (s *Server) GetMetrics() *ServerMetrics {
+	// Session counters are updated under s.mu
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	s.metrics.mu.RLock()
 	defer s.metrics.mu.RUnlock()
 	
 	// Return a copy
-	metrics := *s.metrics
-	return &metrics
+	return &ServerMetrics{
+		ActiveSessions: s.metrics.ActiveSessions,
+		TotalSessions:  s.metrics.TotalSessions,
+		TotalStreams:   s.metrics.TotalStreams,
+		TotalDatagrams: s.metrics.TotalDatagrams,
+		BytesReceived:  s.metrics.BytesReceived,
+		BytesSent:      s.metrics.BytesSent,
+		AvgSessionTime: s.metrics.AvgSessionTime,
+		ErrorCount:     s.metrics.ErrorCount,
+		LastError:      s.metrics.LastError,
+	}
 }
 
-// generateSelfSignedTLS generates a self-signed TLS certificate for testing
-func (s *Server) generateSelfSignedTLS() *tls.Config {
-	// This is a simplified implementation
-	// In production, use proper certificate generation
-	return &tls.Config{
-		InsecureSkipVerify: true,
-		NextProtos:         []string{"wt", "h3"},
+// generateSelfSignedTLS generates a self-signed ECDSA certificate that
+// browsers accept through serverCertificateHashes and prints its hash
+func (s *Server) generateSelfSignedTLS() (*tls.Config, error) {
+	cert, err := internal.GenerateSelfSignedCert(s.config.CertHosts, s.config.CertValidity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TLS certificate: %w", err)
 	}
-}
\ No newline at end of file
+	s.certHash = internal.CertificateHash(cert)
+	fmt.Printf("Self-signed certificate valid until %s, serverCertificateHashes SHA-256: %s\n",
+		cert.Leaf.NotAfter.Format(time.RFC3339), s.certHash)
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"wt", "h3"},
+	}, nil
+}
+
+// CertificateHash returns the SHA-256 of the generated self-signed
+// certificate in hex, or "" when the server uses a configured certificate
+func (s *Server) CertificateHash() string {
+	return s.certHash
+}
@@ -0,0 +1,69 @@
+package webtransport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+
+	"quic-test/internal"
+)
+
+// With Config.Simulate the client needs no WebTransport server of its own:
+// it starts one in-process on a loopback port and runs its sessions against
+// it. Streams and datagrams still cross the wire, so every metric is
+// measured, but the loopback shows no network effects.
+
+// startLoopback starts the in-process server and points a copy of the
+// client config at it. Called with c.mu held.
+func (c *Client) startLoopback() error {
+	cert, err := internal.GenerateSelfSignedCert([]string{"127.0.0.1"}, 0)
+	if err != nil {
+		return fmt.Errorf("failed to generate loopback certificate: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return fmt.Errorf("failed to listen for the loopback server: %w", err)
+	}
+
+	server := NewServer(&ServerConfig{
+		Addr:      conn.LocalAddr().String(),
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	})
+	if err := server.configure(); err != nil {
+		conn.Close()
+		return err
+	}
+	go func() {
+		server.recordServeError(server.server.Serve(conn))
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	config := *c.config
+	config.URL = fmt.Sprintf("https://%s/webtransport", conn.LocalAddr())
+	config.TLSConfig = &tls.Config{RootCAs: roots}
+	config.CertificateHash = ""
+
+	c.config = &config
+	c.loopback = server
+	c.loopbackConn = conn
+	return nil
+}
+
+// LoopbackServer returns the in-process server of a simulated run, or nil
+func (c *Client) LoopbackServer() *Server {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loopback
+}
+
+// stopLoopback stops the in-process server. Called with c.mu held.
+func (c *Client) stopLoopback() {
+	if c.loopback == nil {
+		return
+	}
+	c.loopback.Stop()
+	c.loopbackConn.Close()
+	c.loopback, c.loopbackConn = nil, nil
+}
@@ -0,0 +1,427 @@
+package webtransport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+)
+
+// startTestServer runs a WebTransport server on a free loopback port and
+// returns it together with the session URL and a client TLS config that
+// trusts its self-signed certificate
+func startTestServer(t *testing.T) (*Server, string, *tls.Config) {
+	t.Helper()
+
+	certPEM, keyPEM := internal.GenerateSelfSignedTLS()
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	addr := probe.LocalAddr().String()
+	probe.Close()
+
+	server := NewServer(&ServerConfig{
+		Addr:      addr,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	return server, fmt.Sprintf("https://%s/webtransport", addr), &tls.Config{RootCAs: roots}
+}
+
+// waitForSession waits until the session leaves the connecting state
+func waitForSession(t *testing.T, session *Session, timeout time.Duration) string {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		session.mu.RLock()
+		status, sessionErr := session.Status, session.Error
+		session.mu.RUnlock()
+		if status == "failed" {
+			t.Fatalf("Session failed: %s", sessionErr)
+		}
+		if status != "connecting" {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Session did not connect within %v", timeout)
+	return ""
+}
+
+// waitForClose waits until the client session has been closed
+func waitForClose(t *testing.T, session *Session, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		session.mu.RLock()
+		closed := session.Status == "closed"
+		session.mu.RUnlock()
+		if closed {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Session was not closed within %v", timeout)
+}
+
+func TestDatagramEcho(t *testing.T) {
+	server, url, tlsConf := startTestServer(t)
+
+	client := NewClient(&Config{
+		URL:       url,
+		TLSConfig: tlsConf,
+		Duration:  time.Second,
+		Datagrams: true,
+	})
+	defer client.Close()
+
+	session, err := client.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	waitForSession(t, session, 5*time.Second)
+
+	// Wait for the test duration plus the echo drain
+	waitForClose(t, session, 5*time.Second)
+
+	metrics := client.GetMetrics()
+	if metrics.DatagramsSent < 10 {
+		t.Fatalf("Expected at least 10 datagrams sent, got %d (last error: %s)", metrics.DatagramsSent, metrics.LastError)
+	}
+	if metrics.DatagramLossRate > 0.05 {
+		t.Errorf("Expected near-zero loss on loopback, got %.2f%% (%d/%d echoed)",
+			metrics.DatagramLossRate*100, metrics.DatagramsReceived, metrics.DatagramsSent)
+	}
+	if metrics.AvgDatagramRTT <= 0 || metrics.AvgDatagramRTT > 100 {
+		t.Errorf("Expected a small positive datagram RTT, got %.3f ms", metrics.AvgDatagramRTT)
+	}
+	if echoed := server.GetMetrics().TotalDatagrams; echoed < metrics.DatagramsReceived {
+		t.Errorf("Server echoed %d datagrams but client received %d", echoed, metrics.DatagramsReceived)
+	}
+}
+
+func TestStreamEcho(t *testing.T) {
+	server, url, tlsConf := startTestServer(t)
+
+	client := NewClient(&Config{
+		URL:       url,
+		TLSConfig: tlsConf,
+		Duration:  time.Second,
+		Streams:   3,
+	})
+	defer client.Close()
+
+	session, err := client.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	waitForSession(t, session, 5*time.Second)
+	waitForClose(t, session, 5*time.Second)
+
+	metrics := client.GetMetrics()
+	if metrics.StreamsOpened != 3 || metrics.StreamsClosed != 3 {
+		t.Errorf("Expected 3 streams opened and closed, got %d / %d (last error: %s)",
+			metrics.StreamsOpened, metrics.StreamsClosed, metrics.LastError)
+	}
+	if metrics.BytesSent == 0 || metrics.BytesSent != metrics.BytesReceived {
+		t.Errorf("Expected echoed bytes to match sent bytes, got sent=%d received=%d", metrics.BytesSent, metrics.BytesReceived)
+	}
+	if metrics.BytesSent%streamChunkSize != 0 {
+		t.Errorf("Expected whole chunks to be sent, got %d bytes", metrics.BytesSent)
+	}
+	if metrics.AvgStreamLatency <= 0 {
+		t.Errorf("Expected a positive stream latency, got %f", metrics.AvgStreamLatency)
+	}
+
+	serverMetrics := server.GetMetrics()
+	if serverMetrics.TotalStreams != 3 || serverMetrics.BytesReceived != metrics.BytesSent {
+		t.Errorf("Expected server to echo 3 streams and %d bytes, got %d streams / %d bytes",
+			metrics.BytesSent, serverMetrics.TotalStreams, serverMetrics.BytesReceived)
+	}
+}
+
+func TestRequestsPerStream(t *testing.T) {
+	server, url, tlsConf := startTestServer(t)
+
+	const streams, requests = 2, 5
+	client := NewClient(&Config{
+		URL:               url,
+		TLSConfig:         tlsConf,
+		Duration:          30 * time.Second, // upper bound only
+		Streams:           streams,
+		RequestsPerStream: requests,
+	})
+	defer client.Close()
+
+	start := time.Now()
+	session, err := client.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	waitForSession(t, session, 5*time.Second)
+	waitForClose(t, session, 10*time.Second)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the session to end after its requests, it took %v", elapsed)
+	}
+
+	metrics := client.GetMetrics()
+	if metrics.Requests != streams*requests {
+		t.Fatalf("Expected %d requests, got %d (last error: %s)", streams*requests, metrics.Requests, metrics.LastError)
+	}
+	if metrics.StreamsOpened != streams || metrics.StreamsClosed != streams {
+		t.Errorf("Expected %d streams kept open for all requests, got %d opened / %d closed",
+			streams, metrics.StreamsOpened, metrics.StreamsClosed)
+	}
+	if want := int64(streams * requests * streamChunkSize); metrics.BytesSent != want || metrics.BytesReceived != want {
+		t.Errorf("Expected %d bytes each way, got sent=%d received=%d", want, metrics.BytesSent, metrics.BytesReceived)
+	}
+	if !(metrics.MinStreamLatency > 0 &&
+		metrics.MinStreamLatency <= metrics.P50StreamLatency &&
+		metrics.P50StreamLatency <= metrics.P95StreamLatency &&
+		metrics.P95StreamLatency <= metrics.P99StreamLatency &&
+		metrics.P99StreamLatency <= metrics.MaxStreamLatency) {
+		t.Errorf("Expected ordered positive latencies, got min %.3f p50 %.3f p95 %.3f p99 %.3f max %.3f",
+			metrics.MinStreamLatency, metrics.P50StreamLatency, metrics.P95StreamLatency, metrics.P99StreamLatency, metrics.MaxStreamLatency)
+	}
+	if serverMetrics := server.GetMetrics(); serverMetrics.BytesReceived != metrics.BytesSent {
+		t.Errorf("Expected the server to receive %d bytes, got %d", metrics.BytesSent, serverMetrics.BytesReceived)
+	}
+}
+
+func TestMultipleSessions(t *testing.T) {
+	server, url, tlsConf := startTestServer(t)
+
+	client := NewClient(&Config{
+		URL:         url,
+		TLSConfig:   tlsConf,
+		Duration:    time.Second,
+		Streams:     2,
+		Sessions:    4,
+		Concurrency: 2,
+	})
+	defer client.Close()
+
+	if _, err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	sessions := client.GetSessions()
+	if len(sessions) != 4 {
+		t.Fatalf("Expected 4 sessions, got %d", len(sessions))
+	}
+	for _, session := range sessions {
+		waitForSession(t, session, 5*time.Second)
+	}
+	for _, session := range sessions {
+		waitForClose(t, session, 5*time.Second)
+	}
+
+	metrics := client.GetMetrics()
+	if metrics.SessionsOpened != 4 || metrics.SessionsFailed != 0 {
+		t.Errorf("Expected 4 opened sessions, got %d opened / %d failed (last error: %s)",
+			metrics.SessionsOpened, metrics.SessionsFailed, metrics.LastError)
+	}
+	if metrics.StreamsOpened != 8 || metrics.StreamsClosed != 8 {
+		t.Errorf("Expected 8 streams across sessions, got %d opened / %d closed", metrics.StreamsOpened, metrics.StreamsClosed)
+	}
+	if metrics.BytesSent == 0 || metrics.BytesSent != metrics.BytesReceived {
+		t.Errorf("Expected echoed bytes to match, got sent=%d received=%d", metrics.BytesSent, metrics.BytesReceived)
+	}
+
+	serverMetrics := server.GetMetrics()
+	if serverMetrics.TotalSessions != 4 || serverMetrics.TotalStreams != 8 {
+		t.Errorf("Expected server to see 4 sessions and 8 streams, got %d / %d",
+			serverMetrics.TotalSessions, serverMetrics.TotalStreams)
+	}
+}
+
+// blackholeURL returns a URL whose UDP port accepts packets but never answers
+func blackholeURL(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return fmt.Sprintf("https://%s/webtransport", conn.LocalAddr())
+}
+
+func TestConnectCancel(t *testing.T) {
+	client := NewClient(&Config{
+		URL:         blackholeURL(t),
+		Duration:    time.Second,
+		Synchronous: true,
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.Connect(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Connect took %v to abort after cancellation", elapsed)
+	}
+	if metrics := client.GetMetrics(); metrics.SessionsFailed != 1 || metrics.ConnectTimeouts != 0 {
+		t.Errorf("Expected 1 failed session without timeouts, got %d failed / %d timeouts",
+			metrics.SessionsFailed, metrics.ConnectTimeouts)
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	client := NewClient(&Config{
+		URL:            blackholeURL(t),
+		Duration:       time.Second,
+		ConnectTimeout: 200 * time.Millisecond,
+		Synchronous:    true,
+	})
+	defer client.Close()
+
+	start := time.Now()
+	session, err := client.Connect(context.Background())
+	if !errors.Is(err, ErrConnectTimeout) {
+		t.Fatalf("Expected ErrConnectTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Connect took %v with a 200ms timeout", elapsed)
+	}
+	if session.Status != "failed" || client.GetMetrics().ConnectTimeouts != 1 {
+		t.Errorf("Expected a failed session and one connect timeout, got %q / %d",
+			session.Status, client.GetMetrics().ConnectTimeouts)
+	}
+}
+
+func TestStreamLatencyStats(t *testing.T) {
+	client := NewClient(&Config{URL: "https://localhost/"})
+	defer client.Close()
+
+	for _, latency := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		client.recordStreamLatency(latency)
+	}
+
+	metrics := client.GetMetrics()
+	if metrics.MinStreamLatency != 2 || metrics.MaxStreamLatency != 9 {
+		t.Errorf("Expected min 2 ms and max 9 ms, got %f and %f", metrics.MinStreamLatency, metrics.MaxStreamLatency)
+	}
+	if metrics.AvgStreamLatency != 5 || metrics.StdDevStreamLatency != 2 {
+		t.Errorf("Expected avg 5 ms and stddev 2 ms, got %f and %f", metrics.AvgStreamLatency, metrics.StdDevStreamLatency)
+	}
+	if metrics.Requests != 8 {
+		t.Errorf("Expected 8 requests, got %d", metrics.Requests)
+	}
+	if metrics.P50StreamLatency != 5 || metrics.P95StreamLatency != 9 || metrics.P99StreamLatency != 9 {
+		t.Errorf("Expected p50 5 ms, p95 and p99 9 ms, got %f, %f and %f",
+			metrics.P50StreamLatency, metrics.P95StreamLatency, metrics.P99StreamLatency)
+	}
+}
+
+func TestSimulateRunsAgainstLoopbackServer(t *testing.T) {
+	const requests = 3
+	config := &Config{
+		Duration:          30 * time.Second, // upper bound only
+		Streams:           1,
+		RequestsPerStream: requests,
+		Datagrams:         true,
+		Synchronous:       true,
+		Simulate:          true,
+	}
+	client := NewClient(config)
+	defer client.Close()
+
+	session, err := client.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if config.URL != "" {
+		t.Errorf("Expected the caller's config to stay unchanged, got URL %q", config.URL)
+	}
+	waitForClose(t, session, 10*time.Second)
+
+	metrics := client.GetMetrics()
+	if metrics.Requests != requests || metrics.BytesReceived == 0 {
+		t.Fatalf("Expected %d echoed requests, got %d and %d bytes (last error: %s)",
+			requests, metrics.Requests, metrics.BytesReceived, metrics.LastError)
+	}
+	if metrics.DatagramsSent == 0 || metrics.DatagramsReceived == 0 {
+		t.Errorf("Expected datagrams to be echoed, got %d sent / %d received", metrics.DatagramsSent, metrics.DatagramsReceived)
+	}
+	server := client.LoopbackServer()
+	if server == nil {
+		t.Fatal("Expected a loopback server")
+	}
+	if streams := server.GetMetrics().TotalStreams; streams != 1 {
+		t.Errorf("Expected the loopback server to echo 1 stream, got %d", streams)
+	}
+}
+
+func TestHealthReportsUptimeAndTotals(t *testing.T) {
+	server, url, tlsConf := startTestServer(t)
+
+	client := NewClient(&Config{
+		URL:               url,
+		TLSConfig:         tlsConf,
+		Duration:          30 * time.Second, // upper bound only
+		Streams:           1,
+		RequestsPerStream: 2,
+		Synchronous:       true,
+	})
+	defer client.Close()
+	session, err := client.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	waitForClose(t, session, 10*time.Second)
+
+	recorder := httptest.NewRecorder()
+	server.handleHealth(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var health struct {
+		Status        string  `json:"status"`
+		UptimeSeconds float64 `json:"uptime_seconds"`
+		TotalSessions int64   `json:"total_sessions"`
+		TotalStreams  int64   `json:"total_streams"`
+		BytesReceived int64   `json:"bytes_received"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &health); err != nil {
+		t.Fatalf("Expected a single JSON document, got %q: %v", recorder.Body.String(), err)
+	}
+	if health.Status != "healthy" || health.UptimeSeconds < 0.1 {
+		t.Errorf("Expected a healthy server up for at least the session, got %+v", health)
+	}
+	if want := int64(2 * streamChunkSize); health.TotalSessions != 1 || health.TotalStreams != 1 || health.BytesReceived != want {
+		t.Errorf("Expected 1 session, 1 stream and %d bytes, got %+v", want, health)
+	}
+}
@@ -7,40 +7,74 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"quic-test/client"
 	"quic-test/internal"
+	"quic-test/internal/http3"
+	"quic-test/internal/metrics"
+	"quic-test/internal/observe"
+	"quic-test/internal/sink"
 	"quic-test/server"
 )
 
 func main() {
 	// Add --version flag
 	version := flag.Bool("version", false, "Show program version")
-	
-	fmt.Println("\033[1;36m==========================================\033[0m")
-	fmt.Println("\033[1;36m    2GC Network Protocol Suite\033[0m")
-	fmt.Println("\033[1;36m==========================================\033[0m")
-	fmt.Println("Comprehensive testing of QUIC, MASQUE, ICE/STUN/TURN and other network protocols")
-	mode := flag.String("mode", "test", "Mode: server | client | test")
+	completion := flag.String("completion", "", "Print a shell completion script: bash | zsh | fish")
+	mode := flag.String("mode", "test", "Mode: server | client | test | http3-load | http3-server | masque-server | masque-client | stun | observe | record")
 	addr := flag.String("addr", ":9000", "Address for connection or listening")
+	localAddr := flag.String("local-addr", "", "Client: local IP[:port] to bind the UDP socket to (multi-homed hosts, migration tests); a fixed port allows one connection")
 	streams := flag.Int("streams", 1, "Number of streams per connection")
 	connections := flag.Int("connections", 1, "Number of QUIC connections")
+	maxConnsPerSecond := flag.Float64("max-connections-per-second", 0, "Client: open new connections at most this many per second instead of all at once (0 - no limit)")
 	duration := flag.Duration("duration", 0, "Test duration (0 - until manual termination)")
-	packetSize := flag.Int("packet-size", 1200, "Packet size (bytes)")
+	warmup := flag.Duration("warmup", 0, "Warmup phase after all handshakes complete, reported separately from steady state")
+	drain := flag.Duration("drain", 0, "Drain phase at the end of the test, reported separately (requires --duration)")
+	minDurationFactor := flag.Float64("min-duration-factor", internal.DefaultMinDurationFactor, "Warn when --duration is shorter than this multiple of the estimated handshake + slow-start time")
+	autoWarmup := flag.Bool("auto-warmup", false, "For a short test without --warmup, use the estimated handshake + slow-start time as warmup")
+	packetSize := flag.String("packet-size", "1200", "Packet size (bytes) or distribution: fixed:1200 | uniform:64-1400 | bimodal:64:1400:0.3 (30% small)")
 	rate := flag.Int("rate", 100, "Packet sending rate (per second)")
+	blast := flag.Bool("blast", false, "Send as fast as the connection allows on all streams (no rate limit, emulation or integrity checks) to find the max throughput; saturates the link")
 	reportPath := flag.String("report", "", "Path to report file (optional)")
-	reportFormat := flag.String("report-format", "md", "Report format: csv | md | json")
+	reportFormat := flag.String("report-format", "md", "Report format: csv | md | json | jsonl (client: one JSON line of metrics per second while the test runs)")
+	reportCompress := flag.Bool("report-compress", false, "Gzip the report file (.gz is appended to its name)")
+	rawLatencyOut := flag.String("raw-latency-out", "", "Client: write every RTT sample with its timestamp to this CSV file while the test runs")
+	jsonByteStrings := flag.Bool("json-byte-strings", false, "Write all byte counts in JSON reports as strings; by default only counts above 2^53-1, which JavaScript cannot represent exactly, are strings")
 	certPath := flag.String("cert", "", "Path to TLS certificate (optional)")
 	keyPath := flag.String("key", "", "Path to TLS key (optional)")
+	certHosts := flag.String("cert-hosts", strings.Join(internal.DefaultCertHosts, ","), "Server: comma-separated DNS names and IPs of the self-signed certificate used without --cert/--key")
+	certValidity := flag.Duration("cert-validity", internal.DefaultCertValidity, "Server: validity of the self-signed certificate (browsers accept serverCertificateHashes only up to 14 days)")
 	pattern := flag.String("pattern", "random", "Data pattern: random | zeroes | increment")
-	noTLS := flag.Bool("no-tls", false, "Disable TLS (for testing)")
+	echo := flag.Bool("echo", false, "Server: acknowledge every packet of --echo clients; client: measure RTT from the server's acknowledgements (the server needs --echo too)")
+	noTLS := flag.Bool("no-tls", false, "Disable TLS (for testing); the client does not verify the server certificate")
+	insecure := flag.Bool("insecure", false, "Skip server certificate verification (only for testing against untrusted servers)")
+	flag.BoolVar(insecure, "insecure-skip-verify", false, "Alias for --insecure")
+	caCert := flag.String("ca-cert", "", "PEM file with the CA that signed the server certificate; trusted instead of the system roots")
 	prometheus := flag.Bool("prometheus", false, "Export Prometheus metrics on /metrics")
 	quicBottom := flag.Bool("quic-bottom", false, "Start QUIC Bottom for metrics visualization")
 	emulateLoss := flag.Float64("emulate-loss", 0, "Packet loss probability (0..1)")
 	emulateLatency := flag.Duration("emulate-latency", 0, "Additional latency before packet sending (e.g., 20ms)")
 	emulateDup := flag.Float64("emulate-dup", 0, "Packet duplication probability (0..1)")
+	emulateReorder := flag.Float64("emulate-reorder", 0, "Probability that a packet is held back and sent after the next one (0..1)")
+	emulateJitter := flag.Duration("emulate-jitter", 0, "Random per-packet variation of --emulate-latency, uniform within ± this value (e.g., 5ms)")
+	progressInterval := flag.Duration("progress-interval", 0, "Print a one-line progress summary to stderr every interval (0 - disabled)")
+	quiet := flag.Bool("quiet", false, "Suppress periodic progress output")
+	logFormat := flag.String("log-format", "text", "Progress output format: text | json")
+	var sinks sink.Flags
+	var labels internal.RunLabels
+	flag.Var(&labels, "label", fmt.Sprintf("Run label key=value added to all Prometheus series and the report metadata, repeatable (at most %d labels, values up to %d bytes)", internal.MaxRunLabels, internal.MaxRunLabelValueLength))
+	flag.Var(&sinks, "sink", "Send per-second metrics and the run summary as JSON events to stdout, file:PATH (JSON lines) or http(s)://URL (one POST per event), repeatable")
+	pcapPath := flag.String("pcap", "", "Write sent/received UDP datagrams to a pcap file")
+	pcapMaxSize := flag.Int64("pcap-max-size", 0, "Maximum pcap file size in bytes (0 - unlimited)")
+	udpRecvBuffer := flag.Int("udp-recv-buffer", 0, "UDP socket receive buffer (SO_RCVBUF) in bytes for client and server (0 - OS default)")
+	udpSendBuffer := flag.Int("udp-send-buffer", 0, "UDP socket send buffer (SO_SNDBUF) in bytes for client and server (0 - OS default)")
+	uploadFile := flag.String("upload-file", "", "Client: stream the contents of this file to the server instead of generated packets and verify its SHA-256")
+	outputFile := flag.String("output-file", "", "Server: write files received with --upload-file to this path (discarded when empty)")
+	emulationSeed := flag.Int64("emulation-seed", 0, "Seed for loss/dup emulation (0 - random); equal seeds reproduce the same loss pattern")
 	
 	// FEC flags
 	fecEnabled := flag.Bool("enable-fec", false, "Enable Forward Error Correction")
@@ -58,29 +92,98 @@ func main() {
 	slaLoss := flag.Float64("sla-loss", 0, "SLA: maximum packet loss (0..1, e.g., 0.01 for 1%)")
 	slaThroughput := flag.Float64("sla-throughput", 0, "SLA: minimum throughput (KB/s)")
 	slaErrors := flag.Int64("sla-errors", 0, "SLA: maximum number of errors")
+	stabilityRTTCoV := flag.Float64("stability-rtt-cov", internal.DefaultStabilityRTTCoV, "Stability verdict: maximum coefficient of variation of RTT for a stable link")
+	stabilityThroughputCoV := flag.Float64("stability-throughput-cov", internal.DefaultStabilityThroughputCoV, "Stability verdict: maximum coefficient of variation of throughput for a stable link")
 	
 	// QUIC tuning flags
 	cc := flag.String("cc", "", "Congestion control algorithm: cubic, bbr, bbrv2, bbrv3, reno")
 	maxIdleTimeout := flag.Duration("max-idle-timeout", 0, "Maximum connection idle timeout")
-	handshakeTimeout := flag.Duration("handshake-timeout", 0, "Handshake timeout")
+	handshakeTimeout := flag.Duration("handshake-timeout", 0, "Handshake timeout: a handshake not completed in time is reported as handshake_timeout (0 = quic-go default, 5s)")
 	keepAlive := flag.Duration("keep-alive", 0, "Keep-alive interval")
 	maxStreams := flag.Int64("max-streams", 0, "Maximum number of streams")
-	maxStreamData := flag.Int64("max-stream-data", 0, "Maximum stream data size")
+	maxStreamData := flag.Int64("max-stream-data", 0, "Maximum stream data size in bytes; the server resets streams that send more")
 	enable0RTT := flag.Bool("enable-0rtt", false, "Enable 0-RTT")
 	enableKeyUpdate := flag.Bool("enable-key-update", false, "Enable key update")
 	enableDatagrams := flag.Bool("enable-datagrams", false, "Enable datagrams")
 	maxIncomingStreams := flag.Int64("max-incoming-streams", 0, "Maximum number of incoming streams")
 	maxIncomingUniStreams := flag.Int64("max-incoming-uni-streams", 0, "Maximum number of incoming unidirectional streams")
+	acceptBacklog := flag.Int("accept-backlog", internal.DefaultAcceptBacklog, "Server: accepted connections that may wait for a handler; connections beyond it are closed")
+	streamReadTimeout := flag.Duration("stream-read-timeout", 0, "Server: reset streams that send no data for this long (0 = no limit)")
+	streamWriteTimeout := flag.Duration("stream-write-timeout", 0, "Server: reset streams whose replies cannot be written within this time (0 = no limit)")
+	maxActiveConnections := flag.Int("max-active-connections", 0, "Server: connections handled at once, the rest wait in the accept queue (0 = unlimited)")
+	var transportParams internal.TransportParamFlags
+	flag.Var(&transportParams, "transport-param", "QUIC transport parameter key=value applied over the other QUIC flags, repeatable; supported: "+strings.Join(internal.TransportParamNames(), ", "))
 	
 	// Test scenarios
-	scenario := flag.String("scenario", "", "Predefined scenario: wifi, lte, sat, dc-eu, ru-eu, loss-burst, reorder")
+	scenario := flag.String("scenario", "", "Predefined scenario (wifi, lte, sat, dc-eu, ru-eu, loss-burst, reorder) or a scenario file written by --mode record")
 	listScenarios := flag.Bool("list-scenarios", false, "Show list of available scenarios")
 	
 	// Network profiles
 	networkProfile := flag.String("network-profile", "", "Network profile: wifi, lte, 5g, satellite, ethernet, fiber, datacenter")
 	listProfiles := flag.Bool("list-profiles", false, "Show list of available network profiles")
+	estimate := flag.Bool("estimate", false, "Print projected bandwidth, total bytes and resource footprint of the test without running it")
+	smoke := flag.Bool("smoke", false, "Self-test for CI: run an in-process server and client on loopback for 2s and exit 0 if traffic flowed without errors, 1 otherwise")
+	
+	// HTTP/3 load test (--mode http3-load)
+	loadURL := flag.String("url", "", "http3-load: target URL (comma-separated for multiple targets)")
+	loadMethod := flag.String("method", "GET", "http3-load: request method")
+	loadBodySize := flag.Int("body-size", 0, "http3-load: request body size (bytes)")
+	loadRequests := flag.Int("requests", 100, "http3-load: requests per connection")
+	loadPattern := flag.String("request-pattern", "sequential", "http3-load: sequential | parallel | burst")
+	loadProtocol := flag.String("protocol", "h3", "http3-load: h3 | h2 (TCP baseline)")
+	loadThinkTime := flag.Duration("think-time", 0, "http3-load: pause between requests on a connection")
+	loadRampStep := flag.Int("ramp-step", 0, "http3-load: connections added per ramp step (0 - start all at once)")
+	loadRampInterval := flag.Duration("ramp-interval", 0, "http3-load: interval between ramp steps")
+	loadMaxSamples := flag.Int("max-samples", 100000, "http3-load: response times kept for percentiles; reservoir-sampled beyond (0 - keep all)")
+	loadReconnect := flag.Bool("reconnect-on-close", false, "http3-load: retry a request on a new connection after GOAWAY/server close")
+	loadSetupBudget := flag.Float64("establishment-budget", 0, "http3-load: abort the ramp when more than this fraction of connection setups fail (0..1, 0 - disabled)")
+	var loadHeaders headerFlags
+	flag.Var(&loadHeaders, "header", "http3-load: extra request header \"Name: value\", repeatable ({{token}} is replaced with the login token)")
+	loadBearer := flag.String("auth-bearer", "", "http3-load: static bearer token")
+	loadBasic := flag.String("auth-basic", "", "http3-load: basic auth credentials user:password")
+	loadLoginURL := flag.String("auth-login-url", "", "http3-load: URL of a login request made before the test; its token is sent as a bearer token (or via {{token}} in --header) and refreshed on 401")
+	loadLoginBody := flag.String("auth-login-body", "", "http3-load: body of the login request (JSON)")
+	loadTokenField := flag.String("auth-token-field", "token", "http3-load: JSON field of the login response holding the token (dots for nested fields)")
+	loadResumption := flag.Bool("session-resumption", false, "http3-load: share a TLS session cache so reconnects resume")
+	loadSharedTransport := flag.Bool("shared-transport", false, "http3-load: send all connection workers through one transport, multiplexed over one connection per host (default - a connection per worker)")
+	loadPreWarm := flag.Bool("pre-warm", false, "http3-load: establish the connections before the measured phase, so request latency excludes the handshake")
+	var h3Responses http3.SyntheticResponseFlags
+	flag.Var(&h3Responses, "response", "http3-server: synthetic response status=CODE,size=BYTES,delay=DURATION,weight=N, repeatable; each request gets one at random by weight (default status=200,size=1024)")
+	masqueTarget := flag.String("masque-target", "", "masque-client: UDP target host:port reached through the CONNECT-UDP proxy at --addr; it must echo datagrams")
+	masqueBaseline := flag.Bool("masque-baseline", false, "masque-client: measure direct UDP to --masque-target first to show the overhead of the tunnel (needs --duration)")
+	masqueEcho := flag.String("masque-echo", "", "masque-server: also run a UDP echo target on this address (e.g. :9001)")
+	masqueAllow := flag.String("masque-allow", "", "masque-server: comma-separated targets the proxy may reach: public, an address or CIDR network, each optionally with :port (e.g. 10.0.0.0/8,[::1]:9001); default public, so loopback and private networks are refused")
+	stunServer := flag.String("stun-server", "", "stun: STUN server host:port (e.g. stun.l.google.com:19302)")
+	stunCount := flag.Int("stun-count", 10, "stun: number of Binding Requests to send")
+	stunInterval := flag.Duration("stun-interval", 500*time.Millisecond, "stun: pause between Binding Requests")
+	slaErrorRate := flag.Float64("sla-error-rate", 0, "SLA: maximum request error rate (0..1, http3-load)")
+	slaMinRPS := flag.Float64("sla-min-rps", 0, "SLA: minimum requests per second (http3-load)")
+	
+	// Observe mode (synthetic monitoring of an external server)
+	probeInterval := flag.Duration("probe-interval", observe.DefaultInterval, "observe: interval between probes of --addr")
+	scenarioOut := flag.String("scenario-out", "recorded-scenario.json", "record: file the scenario measured from --addr is written to")
+	
+	// Congestion control comparison
+	compareCC := flag.String("compare-cc", "", "Compare congestion control algorithms under identical emulation (e.g. cubic,bbr,bbrv3)")
+	compareProfiles := flag.String("compare-profiles", "", "Compare network profiles under emulation with the same load and seed (e.g. wifi,lte,5g,satellite or all)")
+	compareTopology := flag.Bool("compare-topology", false, "Compare one reused connection with one connection per stream at equal total streams (--connections × --streams)")
 	
 	flag.Parse()
+	metrics.SetByteCountsAsStrings(*jsonByteStrings)
+
+	// The completion script goes to stdout alone so that it can be sourced
+	if *completion != "" {
+		if err := writeCompletion(os.Stdout, *completion, filepath.Base(os.Args[0]), flag.CommandLine); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	fmt.Println("\033[1;36m==========================================\033[0m")
+	fmt.Println("\033[1;36m    2GC Network Protocol Suite\033[0m")
+	fmt.Println("\033[1;36m==========================================\033[0m")
+	fmt.Println("Comprehensive testing of QUIC, MASQUE, ICE/STUN/TURN and other network protocols")
 
 	// Handle --version flag
 	if *version {
@@ -88,28 +191,69 @@ func main() {
 		os.Exit(0)
 	}
 
+	// The smoke test ignores all other flags
+	if *smoke {
+		os.Exit(runSmoke())
+	}
+
+	packetSizes, err := internal.ParsePacketSizeSpec(*packetSize)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	cfg := internal.TestConfig{
 		Mode:           *mode,
 		Addr:           *addr,
+		LocalAddr:      *localAddr,
 		Streams:        *streams,
 		Connections:    *connections,
+		MaxConnectionsPerSecond: *maxConnsPerSecond,
 		Duration:       *duration,
-		PacketSize:     *packetSize,
+		Warmup:         *warmup,
+		Drain:          *drain,
+		MinDurationFactor: *minDurationFactor,
+		AutoWarmup:     *autoWarmup,
+		PacketSize:     packetSizes.Max,
 		Rate:           *rate,
 		ReportPath:     *reportPath,
 		ReportFormat:   *reportFormat,
+		ReportCompress: *reportCompress,
+		RawLatencyOut:  *rawLatencyOut,
 		CertPath:       *certPath,
 		KeyPath:        *keyPath,
+		CertHosts:      internal.SplitCertHosts(*certHosts),
+		CertValidity:   *certValidity,
 		Pattern:        *pattern,
+		Echo:           *echo,
 		NoTLS:          *noTLS,
+		InsecureSkipVerify: *insecure,
+		CACertPath:     *caCert,
 		Prometheus:     *prometheus,
 		EmulateLoss:    *emulateLoss,
 		EmulateLatency: *emulateLatency,
 		EmulateDup:     *emulateDup,
+		EmulateReorder: *emulateReorder,
+		EmulateJitter:  *emulateJitter,
+		EmulationSeed:  *emulationSeed,
+		ProgressInterval: *progressInterval,
+		Quiet:          *quiet,
+		LogFormat:      *logFormat,
+		Sinks:          sinks,
+		Labels:         labels,
+		PcapPath:       *pcapPath,
+		PcapMaxBytes:   *pcapMaxSize,
+		UDPRecvBuffer:  *udpRecvBuffer,
+		UDPSendBuffer:  *udpSendBuffer,
+		Blast:          *blast,
+		UploadFile:     *uploadFile,
+		OutputFile:     *outputFile,
 		SlaRttP95:      *slaRttP95,
 		SlaLoss:        *slaLoss,
 		SlaThroughput:  *slaThroughput,
 		SlaErrors:      *slaErrors,
+		StabilityRTTCoV:        *stabilityRTTCoV,
+		StabilityThroughputCoV: *stabilityThroughputCoV,
 		CongestionControl: *cc,
 		MaxIdleTimeout:    *maxIdleTimeout,
 		HandshakeTimeout:  *handshakeTimeout,
@@ -121,6 +265,11 @@ func main() {
 		EnableDatagrams:   *enableDatagrams,
 		MaxIncomingStreams: *maxIncomingStreams,
 		MaxIncomingUniStreams: *maxIncomingUniStreams,
+		AcceptBacklog:     *acceptBacklog,
+		MaxActiveConnections: *maxActiveConnections,
+		StreamReadTimeout:    *streamReadTimeout,
+		StreamWriteTimeout:   *streamWriteTimeout,
+		TransportParams:   transportParams,
 		FECEnabled:       *fecEnabled || *fecEnabledAlias,
 		FECRedundancy:    func() float64 {
 			if *fecEnabled || *fecEnabledAlias {
@@ -134,9 +283,37 @@ func main() {
 		PQCEnabled:       *pqcEnabled,
 		PQCAlgorithm:     *pqcAlgorithm,
 	}
+	if cfg.LogFormat != internal.LogFormatText && cfg.LogFormat != internal.LogFormatJSON {
+		fmt.Printf("❌ Error: unknown --log-format %q (text | json)\n", cfg.LogFormat)
+		os.Exit(1)
+	}
+	if cfg.UDPRecvBuffer < 0 || cfg.UDPSendBuffer < 0 {
+		fmt.Println("❌ Error: --udp-recv-buffer and --udp-send-buffer must be non-negative")
+		os.Exit(1)
+	}
+	if cfg.StabilityRTTCoV < 0 || cfg.StabilityThroughputCoV < 0 {
+		fmt.Println("❌ Error: --stability-rtt-cov and --stability-throughput-cov must be non-negative")
+		os.Exit(1)
+	}
+	if cfg.MinDurationFactor < 0 {
+		fmt.Println("❌ Error: --min-duration-factor must be non-negative")
+		os.Exit(1)
+	}
+	if cfg.InsecureSkipVerify && cfg.CACertPath != "" {
+		fmt.Println("❌ Error: --insecure and --ca-cert are mutually exclusive")
+		os.Exit(1)
+	}
+	if cfg.MaxConnectionsPerSecond < 0 {
+		fmt.Println("❌ Error: --max-connections-per-second must be non-negative")
+		os.Exit(1)
+	}
+	// A fixed size stays in PacketSize alone so network profiles can still adjust it
+	if packetSizes.Kind != internal.PacketSizeFixed {
+		cfg.PacketSizes = packetSizes
+	}
 
-	fmt.Printf("mode=%s, addr=%s, connections=%d, streams=%d, duration=%s, packet-size=%d, rate=%d, report=%s, report-format=%s, cert=%s, key=%s, pattern=%s, no-tls=%v, prometheus=%v\n",
-		cfg.Mode, cfg.Addr, cfg.Connections, cfg.Streams, cfg.Duration.String(), cfg.PacketSize, cfg.Rate, cfg.ReportPath, cfg.ReportFormat, cfg.CertPath, cfg.KeyPath, cfg.Pattern, cfg.NoTLS, cfg.Prometheus)
+	fmt.Printf("mode=%s, addr=%s, connections=%d, streams=%d, duration=%s, packet-size=%s, rate=%d, report=%s, report-format=%s, cert=%s, key=%s, pattern=%s, no-tls=%v, prometheus=%v\n",
+		cfg.Mode, cfg.Addr, cfg.Connections, cfg.Streams, cfg.Duration.String(), cfg.PacketSizeDistribution(), cfg.Rate, cfg.ReportPath, cfg.ReportFormat, cfg.CertPath, cfg.KeyPath, cfg.Pattern, cfg.NoTLS, cfg.Prometheus)
 	
 	// Print SLA configuration if set
 	internal.PrintSLAConfig(cfg)
@@ -184,7 +361,7 @@ func main() {
 	}
 	
 	if *scenario != "" {
-		scenarioConfig, err := internal.GetScenario(*scenario)
+		scenarioConfig, err := internal.LoadScenario(*scenario)
 		if err != nil {
 			fmt.Printf("❌ Error: %v\n", err)
 			os.Exit(1)
@@ -192,6 +369,16 @@ func main() {
 		
 		// Apply scenario configuration
 		cfg = scenarioConfig.Config
+		cfg.MinDurationFactor = *minDurationFactor
+		cfg.AutoWarmup = *autoWarmup
+		cfg.InsecureSkipVerify = *insecure
+		cfg.CACertPath = *caCert
+		if *maxConnsPerSecond > 0 {
+			cfg.MaxConnectionsPerSecond = *maxConnsPerSecond
+		}
+		if len(transportParams) > 0 {
+			cfg.TransportParams = transportParams
+		}
 		fmt.Printf("Running scenario: %s\n", scenarioConfig.Name)
 	}
 	
@@ -208,15 +395,58 @@ func main() {
 		internal.PrintProfileRecommendations(profile)
 	}
 
-	// Initialize QUIC Bottom (use 127.0.0.1 instead of localhost to avoid IPv6 issues)
-	internal.InitBottomBridge("http://127.0.0.1:8080", 100*time.Millisecond)
+	// Validate --addr early instead of failing deep inside quic-go
+	normalizedAddr, err := internal.NormalizeAddr(cfg.Addr)
+	if err != nil {
+		fmt.Printf("❌ Error: --addr: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Addr = normalizedAddr
+
+	if cfg.LocalAddr != "" && cfg.Mode != "server" {
+		local, err := internal.ParseLocalAddr(cfg.LocalAddr)
+		if err != nil {
+			fmt.Printf("❌ Error: --local-addr: %v\n", err)
+			os.Exit(1)
+		}
+		if local.Port != 0 && cfg.Connections > 1 {
+			fmt.Printf("❌ Error: --local-addr with port %d allows only --connections 1\n", local.Port)
+			os.Exit(1)
+		}
+	}
+
+	// The flags above are checked one by one; Validate reports all the
+	// remaining problems of the resulting config at once
+	if err := cfg.Validate(); err != nil {
+		fmt.Println("❌ Error: invalid configuration:")
+		for _, problem := range internal.ValidationErrors(err) {
+			fmt.Printf("  - %v\n", problem)
+		}
+		os.Exit(1)
+	}
+
+	if cfg.Blast && cfg.Mode != "server" {
+		if cfg.UploadFile != "" {
+			fmt.Println("❌ Error: --blast cannot be combined with --upload-file")
+			os.Exit(1)
+		}
+		fmt.Println("⚠️  Blast mode: traffic is sent without any rate limit and will saturate the link to the server")
+	}
+
+	if *estimate {
+		os.Exit(runEstimate(cfg))
+	}
+
+	// Initialize QUIC Bottom; the bridge tries localhost over both IPv6 and IPv4
+	internal.InitBottomBridge("http://localhost:8080", 100*time.Millisecond)
 	internal.EnableBottomBridge()
 
-	// Handle signals for graceful shutdown
+	// Handle signals for graceful shutdown; the server stops when ctx is
+	// cancelled, the client handles the signals itself to save its report
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
-	_, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	go func(cancelFunc context.CancelFunc) {
@@ -225,16 +455,81 @@ func main() {
 		cancelFunc() // Correct termination
 	}(cancel)
 
+	if *compareCC != "" {
+		var conditions []string
+		if *scenario != "" {
+			conditions = append(conditions, "scenario "+*scenario)
+		}
+		if *networkProfile != "" {
+			conditions = append(conditions, "network profile "+*networkProfile)
+		}
+		os.Exit(runCompareCC(cfg, *compareCC, strings.Join(conditions, ", ")))
+	}
+	if *compareTopology {
+		os.Exit(runCompareTopology(cfg))
+	}
+	if *compareProfiles != "" {
+		os.Exit(runCompareProfiles(cfg, *compareProfiles))
+	}
+
 	switch cfg.Mode {
 	case "server":
 		fmt.Println("Starting in server mode...")
-		server.Run(cfg)
+		if err := server.Run(ctx, cfg); err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			os.Exit(1)
+		}
 	case "client":
 		fmt.Println("Starting in client mode...")
-		client.Run(cfg)
+		client.RunAndReport(cfg)
 	case "test":
 		fmt.Println("Starting in test mode (server+client)...")
 		runTestMode(cfg)
+	case "http3-load":
+		fmt.Println("Starting in HTTP/3 load test mode...")
+		os.Exit(runHTTP3Load(cfg, http3LoadOptions{
+			URL:             *loadURL,
+			Method:          *loadMethod,
+			BodySize:        *loadBodySize,
+			Requests:        *loadRequests,
+			RequestPattern:  *loadPattern,
+			Protocol:        *loadProtocol,
+			ThinkTime:       *loadThinkTime,
+			RampStep:        *loadRampStep,
+			RampInterval:    *loadRampInterval,
+			Resumption:      *loadResumption,
+			PreWarm:         *loadPreWarm,
+			SharedTransport: *loadSharedTransport,
+			MaxSamples:      *loadMaxSamples,
+			Reconnect:       *loadReconnect,
+			SetupBudget:     *loadSetupBudget,
+			Headers:         loadHeaders,
+			BearerToken:     *loadBearer,
+			BasicAuth:       *loadBasic,
+			LoginURL:        *loadLoginURL,
+			LoginBody:       *loadLoginBody,
+			TokenField:      *loadTokenField,
+			SLAErrorRate:    *slaErrorRate,
+			SLAMinRPS:       *slaMinRPS,
+		}))
+	case "http3-server":
+		fmt.Println("Starting in HTTP/3 test server mode...")
+		os.Exit(runHTTP3Server(ctx, cfg, h3Responses))
+	case "masque-server":
+		fmt.Println("Starting in MASQUE CONNECT-UDP proxy mode...")
+		os.Exit(runMASQUEServer(ctx, cfg, masqueOptions{EchoAddr: *masqueEcho, Allow: *masqueAllow}))
+	case "masque-client":
+		fmt.Println("Starting in MASQUE CONNECT-UDP client mode...")
+		os.Exit(runMASQUEClient(ctx, cfg, masqueOptions{Target: *masqueTarget, Baseline: *masqueBaseline}))
+	case "stun":
+		fmt.Println("Starting in STUN check mode...")
+		os.Exit(runSTUN(ctx, stunOptions{Server: *stunServer, Count: *stunCount, Interval: *stunInterval}))
+	case "observe":
+		fmt.Println("Starting in observe mode...")
+		os.Exit(runObserve(cfg, *probeInterval))
+	case "record":
+		fmt.Println("Starting in record mode...")
+		os.Exit(runRecord(cfg, *scenarioOut))
 	default:
 		fmt.Println("Unknown mode", cfg.Mode)
 		os.Exit(1)
@@ -243,20 +538,37 @@ func main() {
 
 // runTestMode starts server and client for testing
 func runTestMode(cfg internal.TestConfig) {
+	// The client verifies the in-process server by the certificate made for it
+	if err := cfg.PrepareLoopbackTLS(); err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Each side gets its own role-specific config derived from the shared one
+	serverCfg := cfg.ServerConfig()
+	clientCfg := cfg.ClientConfig()
+
 	// Start server in goroutine
-	serverDone := make(chan struct{})
+	serverCtx, stopServer := context.WithCancel(context.Background())
+	defer stopServer()
+	serverDone := make(chan error, 1)
 	go func() {
-		defer close(serverDone)
-		server.Run(cfg)
+		serverDone <- server.Run(serverCtx, serverCfg)
 	}()
 
-	// Wait for server to start
-	time.Sleep(3 * time.Second)
+	// Wait for server to start; a listen failure ends the test right away
+	select {
+	case err := <-serverDone:
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	case <-time.After(3 * time.Second):
+	}
 
 	// Start client
-	client.Run(cfg)
+	client.RunAndReport(clientCfg)
 
 	// Give server time to shutdown gracefully (maximum 5 seconds)
+	stopServer()
 	serverTimeout := time.NewTimer(5 * time.Second)
 	select {
 	case <-serverDone:
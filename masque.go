@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/masque"
+	"quic-test/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// masqueEchoWait is how long the client waits for the echoes of the last
+// datagrams once sending stops
+const masqueEchoWait = time.Second
+
+// masqueMetricsAddr is where --mode masque-server --prometheus serves /metrics
+const masqueMetricsAddr = ":2115"
+
+// masqueSetupTimeout bounds the handshake with the proxy and its answer to
+// the CONNECT-UDP request
+const masqueSetupTimeout = 10 * time.Second
+
+// masqueOptions are the flags of --mode masque-server and masque-client
+type masqueOptions struct {
+	Target   string // client: UDP target behind the proxy
+	Baseline bool   // client: measure direct UDP to the target as well
+	EchoAddr string // server: also run a UDP echo target on this address
+	Allow    string // server: --masque-allow, comma-separated target rules
+}
+
+// runMASQUEServer proxies CONNECT-UDP requests on --addr until ctx is
+// cancelled and returns the process exit code
+func runMASQUEServer(ctx context.Context, cfg internal.TestConfig, opts masqueOptions) int {
+	var tlsConf *tls.Config
+	if cfg.CertPath != "" && cfg.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+		if err != nil {
+			fmt.Printf("❌ Error: failed to load certificate: %v\n", err)
+			return int(internal.ExitCodeCriticalFailure)
+		}
+		tlsConf = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	} else {
+		cert, err := internal.GenerateSelfSignedCert(cfg.CertHosts, cfg.CertValidity)
+		if err != nil {
+			fmt.Printf("❌ Error: failed to generate certificate: %v\n", err)
+			return int(internal.ExitCodeCriticalFailure)
+		}
+		tlsConf = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	allow, err := masque.ParseTargetRules(opts.Allow)
+	if err != nil {
+		fmt.Printf("❌ Error: --masque-allow: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	var echo net.PacketConn
+	if opts.EchoAddr != "" {
+		echo, err = net.ListenPacket("udp", opts.EchoAddr)
+		if err != nil {
+			fmt.Printf("❌ Error: UDP echo: %v\n", err)
+			return int(internal.ExitCodeCriticalFailure)
+		}
+		// The echo target is meant to be reached through the proxy, even
+		// though it listens on this host
+		allow = append(allow, echoTargetRules(echo.LocalAddr())...)
+	}
+
+	proxy, err := masque.ListenProxy(masque.ProxyConfig{
+		Addr:       cfg.Addr,
+		TLSConfig:  tlsConf,
+		QUICConfig: internal.CreateQUICConfig(cfg),
+		Allow:      allow,
+	})
+	if err != nil {
+		if echo != nil {
+			echo.Close()
+		}
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	fmt.Printf("Proxying CONNECT-UDP on https://%s%s{host}/{port}/\n", proxy.Addr(), masque.ConnectUDPPathPrefix)
+	fmt.Printf("Allowed targets: %s\n", formatTargetRules(allow))
+
+	if cfg.Prometheus {
+		registry := metrics.NewRegistry()
+		registry.MustRegister(proxy.Collectors()...)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(metrics.WithLabels(registry, cfg.Labels), promhttp.HandlerOpts{}))
+		srv := &http.Server{Addr: masqueMetricsAddr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Failed to start Prometheus server: %v", err)
+			}
+		}()
+		defer srv.Close()
+		fmt.Printf("Prometheus endpoint available at %s/metrics\n", masqueMetricsAddr)
+	}
+
+	echoDone := make(chan error, 1)
+	if echo != nil {
+		fmt.Printf("UDP echo target on %s\n", echo.LocalAddr())
+		go func() { echoDone <- masque.ServeUDPEcho(ctx, echo) }()
+	} else {
+		echoDone <- nil
+	}
+
+	err = proxy.Serve(ctx)
+	if echoErr := <-echoDone; err == nil {
+		err = echoErr
+	}
+	fmt.Printf("Proxied %s\n", proxy.Stats())
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	return int(internal.ExitCodeSuccess)
+}
+
+// echoTargetRules allow the port of the --masque-echo target on loopback
+// and on the address it listens on
+func echoTargetRules(addr net.Addr) []masque.TargetRule {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	port := uint16(udpAddr.Port)
+	rules := []masque.TargetRule{
+		{Prefix: netip.MustParsePrefix("127.0.0.0/8"), Port: port},
+		{Prefix: netip.MustParsePrefix("::1/128"), Port: port},
+	}
+	if ip, ok := netip.AddrFromSlice(udpAddr.IP); ok && !ip.IsUnspecified() && !ip.IsLoopback() {
+		ip = ip.Unmap()
+		rules = append(rules, masque.TargetRule{Prefix: netip.PrefixFrom(ip, ip.BitLen()), Port: port})
+	}
+	return rules
+}
+
+// formatTargetRules lists the rules for the startup banner
+func formatTargetRules(rules []masque.TargetRule) string {
+	list := make([]string, len(rules))
+	for i, rule := range rules {
+		list[i] = rule.String()
+	}
+	return strings.Join(list, ", ")
+}
+
+// runMASQUEClient sends datagrams to --masque-target through the
+// CONNECT-UDP proxy at --addr and reports RTT and loss of their echoes;
+// with --masque-baseline the same run over direct UDP comes first, so the
+// overhead of the tunnel can be read off. Returns the process exit code.
+func runMASQUEClient(ctx context.Context, cfg internal.TestConfig, opts masqueOptions) int {
+	if opts.Target == "" {
+		fmt.Println("❌ Error: --masque-target is required for masque-client mode")
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	if cfg.PacketSize > masque.MaxTunnelPayload {
+		fmt.Printf("❌ Error: --packet-size %d does not fit into one HTTP datagram, use at most %d\n", cfg.PacketSize, masque.MaxTunnelPayload)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	if opts.Baseline && cfg.Duration <= 0 {
+		fmt.Println("❌ Error: --masque-baseline requires --duration")
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	echoConfig := masque.EchoTestConfig{
+		PacketSize: cfg.PacketSize,
+		Rate:       cfg.Rate,
+		Duration:   cfg.Duration,
+		Wait:       masqueEchoWait,
+	}
+
+	var baseline *masque.EchoTestResult
+	if opts.Baseline {
+		conn, err := masque.DialUDP(opts.Target)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return int(internal.ExitCodeCriticalFailure)
+		}
+		fmt.Printf("Measuring direct UDP to %s for %v...\n", opts.Target, cfg.Duration)
+		result, err := masque.RunEchoTest(ctx, conn, echoConfig)
+		conn.Close()
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			return int(internal.ExitCodeCriticalFailure)
+		}
+		printEchoResult("Direct UDP", result)
+		baseline = &result
+	}
+
+	tlsConf, err := clientTLSConfig(cfg, nil)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, masqueSetupTimeout)
+	tunnel, err := masque.DialUDPTunnel(dialCtx, masque.UDPTunnelConfig{
+		ProxyAddr:  cfg.Addr,
+		Target:     opts.Target,
+		TLSConfig:  tlsConf,
+		QUICConfig: internal.CreateClientQUICConfig(cfg),
+	})
+	cancel()
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	defer tunnel.Close()
+	fmt.Printf("CONNECT-UDP tunnel to %s via %s set up in %.2f ms\n", opts.Target, cfg.Addr, float64(tunnel.SetupTime.Microseconds())/1000)
+
+	result, err := masque.RunEchoTest(ctx, tunnel, echoConfig)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	printEchoResult("CONNECT-UDP", result)
+	if baseline != nil && len(baseline.RTTs) > 0 && len(result.RTTs) > 0 {
+		fmt.Printf("MASQUE overhead: RTT mean %+.3f ms, p95 %+.3f ms, loss %+.2f%%\n",
+			result.RTT().Mean-baseline.RTT().Mean,
+			result.RTT().P95-baseline.RTT().P95,
+			(result.LossRatio()-baseline.LossRatio())*100)
+	}
+	if result.Received == 0 {
+		if result.LastError != nil {
+			fmt.Printf("❌ Error: %v\n", result.LastError)
+		} else {
+			fmt.Println("❌ Error: no datagram came back; the target must echo UDP (see --masque-echo)")
+		}
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	return int(internal.ExitCodeSuccess)
+}
+
+// printEchoResult prints one measurement of runMASQUEClient
+func printEchoResult(name string, r masque.EchoTestResult) {
+	rtt := r.RTT()
+	fmt.Printf("%s: %d sent, %d echoed (loss %.2f%%), %d/%d bytes, RTT mean %.3f ms, p50 %.3f ms, p95 %.3f ms, p99 %.3f ms\n",
+		name, r.Sent, r.Received, r.LossRatio()*100, r.BytesSent, r.BytesReceived,
+		rtt.Mean, rtt.P50, rtt.P95, rtt.P99)
+	if r.SendErrors > 0 {
+		fmt.Printf("%s: %d send errors, last: %v\n", name, r.SendErrors, r.LastError)
+	}
+}
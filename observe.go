@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/metrics"
+	"quic-test/internal/observe"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// observeMetricsAddr is where --mode observe --prometheus serves /metrics
+const observeMetricsAddr = ":2114"
+
+// runObserve probes the server at cfg.Addr every interval until interrupted
+// (or until --duration elapses) and returns the process exit code
+func runObserve(cfg internal.TestConfig, interval time.Duration) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	tlsConf, err := clientTLSConfig(cfg, []string{"quic-test"})
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	registry := metrics.NewRegistry()
+	observer := observe.NewObserver(observe.Config{Addr: cfg.Addr, Interval: interval, TLSConfig: tlsConf}, registry)
+
+	if cfg.Prometheus {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(metrics.WithLabels(registry, cfg.Labels), promhttp.HandlerOpts{}))
+		srv := &http.Server{Addr: observeMetricsAddr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Failed to start Prometheus server: %v", err)
+			}
+		}()
+		defer srv.Close()
+		fmt.Printf("Prometheus endpoint available at %s/metrics\n", observeMetricsAddr)
+	}
+
+	fmt.Printf("Observing %s every %v\n", cfg.Addr, interval)
+	observer.Run(ctx, func(r observe.ProbeResult) {
+		if r.Err != nil {
+			fmt.Printf("%s ❌ probe failed: %v\n", r.Time.Format(time.RFC3339), r.Err)
+			return
+		}
+		fmt.Printf("%s ✅ handshake %.2f ms, stream rtt %.2f ms\n", r.Time.Format(time.RFC3339),
+			float64(r.HandshakeTime.Microseconds())/1000, float64(r.StreamRTT.Microseconds())/1000)
+	})
+
+	m := observer.GetMetrics()
+	fmt.Printf("\nProbes: %d total, %d failed; avg handshake %.2f ms, avg stream rtt %.2f ms\n",
+		m.Probes, m.Failures, m.AvgHandshakeMs, m.AvgStreamRTTMs)
+	if m.Probes > 0 && m.Failures == m.Probes {
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	return int(internal.ExitCodeSuccess)
+}
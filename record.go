@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/observe"
+)
+
+// defaultRecordDuration is how long --mode record measures when --duration is not set
+const defaultRecordDuration = 10 * time.Second
+
+// runRecord measures the link to the server at cfg.Addr, writes a scenario
+// emulating the observed conditions to out, and checks that the written file
+// loads back as a valid scenario. It returns the process exit code.
+func runRecord(cfg internal.TestConfig, out string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	duration := cfg.Duration
+	if duration <= 0 {
+		duration = defaultRecordDuration
+	}
+	tlsConf, err := clientTLSConfig(cfg, []string{"quic-test"})
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	fmt.Printf("Recording link conditions to %s for %v\n", cfg.Addr, duration)
+	rec, err := observe.Record(ctx, observe.RecordConfig{Addr: cfg.Addr, Duration: duration, TLSConfig: tlsConf})
+	if err != nil {
+		fmt.Printf("❌ Error: record: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	if len(rec.RTTs) == 0 {
+		fmt.Println("❌ Error: record: no packet was acknowledged, RTT is unknown")
+		return int(internal.ExitCodeCriticalFailure)
+	}
+
+	cond := internal.MeasureLinkConditions(rec.RTTs, rec.PacketsSent, rec.PacketsLost)
+	fmt.Printf("Observed: rtt %.2f ms (p95 %.2f ms, min %.2f ms), jitter %.2f ms, loss %.2f%% (%d/%d packets)\n",
+		cond.RTTMs, cond.RTTP95Ms, cond.MinRTTMs, cond.JitterMs, cond.LossRatio*100, cond.PacketsLost, cond.PacketsSent)
+
+	name := strings.TrimSuffix(filepath.Base(out), filepath.Ext(out))
+	if err := internal.SaveScenarioFile(out, internal.RecordedScenario(name, cfg.Addr, cond, time.Now())); err != nil {
+		fmt.Printf("❌ Error: write scenario: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	scenario, err := internal.LoadScenarioFile(out)
+	if err != nil {
+		fmt.Printf("❌ Error: recorded scenario does not load: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	fmt.Printf("✅ Scenario written to %s, replay it with --scenario %s\n", out, out)
+	internal.PrintScenarioInfo(scenario)
+	return int(internal.ExitCodeSuccess)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"quic-test/internal"
+
+	"github.com/prometheus/client_golang/prometheus"
+	quic "github.com/quic-go/quic-go"
+)
+
+// acceptQueue holds accepted connections until a handler takes them. With
+// maxActive set, a connection waits in the queue while that many are being
+// handled; a connection that finds the queue full is closed right away with
+// internal.AcceptQueueFullErrorCode. Depth, peak depth, rejections and the
+// time spent in the queue are counted in metrics.
+type acceptQueue struct {
+	conns   chan queuedConn
+	slots   chan struct{} // one per active handler; nil - no limit
+	metrics *serverMetrics
+}
+
+// queuedConn is a connection waiting for a handler
+type queuedConn struct {
+	conn   quic.Connection
+	queued time.Time
+}
+
+func newAcceptQueue(backlog, maxActive int, metrics *serverMetrics) *acceptQueue {
+	if backlog <= 0 {
+		backlog = internal.DefaultAcceptBacklog
+	}
+	q := &acceptQueue{
+		conns:   make(chan queuedConn, backlog),
+		metrics: metrics,
+	}
+	if maxActive > 0 {
+		q.slots = make(chan struct{}, maxActive)
+	}
+	return q
+}
+
+// push queues an accepted connection and reports whether it fit
+func (q *acceptQueue) push(conn quic.Connection) bool {
+	// Counted before the send so that the handler never sees a negative depth
+	depth := q.metrics.AcceptQueueDepth.Add(1)
+	select {
+	case q.conns <- queuedConn{conn: conn, queued: time.Now()}:
+		for {
+			peak := q.metrics.AcceptQueuePeak.Load()
+			if depth <= peak || q.metrics.AcceptQueuePeak.CompareAndSwap(peak, depth) {
+				return true
+			}
+		}
+	default:
+		q.metrics.AcceptQueueDepth.Add(-1)
+		q.metrics.AcceptRejected.Add(1)
+		if err := conn.CloseWithError(internal.AcceptQueueFullErrorCode, "accept queue full"); err != nil {
+			log.Printf("Warning: failed to close rejected connection: %v\n", err)
+		}
+		return false
+	}
+}
+
+// run hands queued connections to handle, each in its own goroutine, until
+// ctx is cancelled. start is called before a handler starts and done after
+// it returns, so that the caller can wait for the handlers.
+func (q *acceptQueue) run(ctx context.Context, handle func(quic.Connection), start, done func()) {
+	for {
+		// A connection stays in the queue, and counted there, until a
+		// handler is free to take it
+		if q.slots != nil {
+			select {
+			case q.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case qc := <-q.conns:
+			q.metrics.AcceptQueueDepth.Add(-1)
+			q.metrics.observeAcceptWait(time.Since(qc.queued))
+			start()
+			go func() {
+				defer done()
+				defer q.release()
+				handle(qc.conn)
+			}()
+		case <-ctx.Done():
+			q.release()
+			return
+		}
+	}
+}
+
+// release frees the handler slot taken in run
+func (q *acceptQueue) release() {
+	if q.slots != nil {
+		<-q.slots
+	}
+}
+
+// drain closes the connections left in the queue when the server stops.
+// It must be called after the accept loop and run have returned.
+func (q *acceptQueue) drain() {
+	for {
+		select {
+		case qc := <-q.conns:
+			q.metrics.AcceptQueueDepth.Add(-1)
+			if err := qc.conn.CloseWithError(0, "server stopping"); err != nil {
+				log.Printf("Warning: failed to close queued connection: %v\n", err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// newAcceptWaitHistogram creates the histogram of the time connections spend
+// in the accept queue
+func newAcceptWaitHistogram() prometheus.Histogram {
+	return prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "quic_server_accept_queue_wait_seconds",
+		Help:    "Time accepted connections wait in the accept queue before a handler starts",
+		Buckets: []float64{0.0001, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0},
+	})
+}
+
+// observeAcceptWait records how long a connection waited in the accept queue
+func (m *serverMetrics) observeAcceptWait(wait time.Duration) {
+	if m.acceptWait != nil {
+		m.acceptWait.Observe(wait.Seconds())
+	}
+}
+
+// logAcceptQueue warns about connections rejected by a full accept queue
+func logAcceptQueue(stats Stats, backlog int) {
+	if stats.AcceptRejected == 0 {
+		return
+	}
+	if backlog <= 0 {
+		backlog = internal.DefaultAcceptBacklog
+	}
+	log.Printf("Warning: %d connections rejected by the full accept queue (peak depth %d of %d); consider --accept-backlog or --max-active-connections",
+		stats.AcceptRejected, stats.AcceptQueuePeak, backlog)
+}
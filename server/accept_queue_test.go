@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestAcceptQueueRejectsBurstBeyondBacklog(t *testing.T) {
+	free, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.LocalAddr().String()
+	free.Close()
+
+	// One connection is handled, two wait and the rest are rejected
+	const backlog, burst = 2, 6
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	var last Stats
+	go func() {
+		done <- RunWithStats(ctx, internal.TestConfig{
+			Addr:                 addr,
+			NoTLS:                true,
+			AcceptBacklog:        backlog,
+			MaxActiveConnections: 1,
+		}, func(s Stats) { last = s })
+	}()
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer dialCancel()
+	var conns []quic.Connection
+	defer func() {
+		for _, conn := range conns {
+			conn.CloseWithError(0, "")
+		}
+	}()
+	for len(conns) < burst {
+		conn, err := quic.DialAddr(dialCtx, addr, internal.GenerateTLSConfig(true), nil)
+		if err != nil {
+			if dialCtx.Err() != nil {
+				t.Fatalf("Failed to connect to the server: %v", err)
+			}
+			continue
+		}
+		conns = append(conns, conn)
+	}
+
+	// Rejected connections are closed by the server with a distinct code
+	var appErr *quic.ApplicationError
+	select {
+	case <-conns[burst-1].Context().Done():
+		if err := context.Cause(conns[burst-1].Context()); !errors.As(err, &appErr) || appErr.ErrorCode != internal.AcceptQueueFullErrorCode {
+			t.Errorf("Expected the last connection to be rejected with code %#x, got %v", internal.AcceptQueueFullErrorCode, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the server to reject the last connection")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean stop, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return after the context was cancelled")
+	}
+	if last.AcceptQueuePeak != backlog {
+		t.Errorf("Expected the accept queue to peak at %d, got %d", backlog, last.AcceptQueuePeak)
+	}
+	if last.AcceptRejected != burst-1-backlog {
+		t.Errorf("Expected %d rejected connections, got %d", burst-1-backlog, last.AcceptRejected)
+	}
+	if last.Connections+int(last.AcceptRejected) != burst {
+		t.Errorf("Expected every connection to be admitted or rejected, got %+v", last)
+	}
+	if last.AcceptQueueDepth != 0 {
+		t.Errorf("Expected the queue to be drained on stop, got depth %d", last.AcceptQueueDepth)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// echoStream handles a stream sent with --echo, whose first bytes (prefix)
+// were already read. Its packets are counted like on a plain stream and
+// checked against the pattern named in the header, if any. With echo set,
+// every packet but FEC repair packets is acknowledged on the same stream
+// with its sequence number and the server time, from which the client
+// computes the RTT; otherwise the stream is only counted.
+func echoStream(stream quic.Stream, packets *streamPackets, prefix []byte, echo bool) {
+	r := io.MultiReader(bytes.NewReader(prefix), stream)
+	pattern, err := internal.ReadEchoHeader(r)
+	if err != nil {
+		packets.end(err)
+		return
+	}
+	if !echo {
+		readFramedPackets(stream, r, packets, pattern, nil)
+		return
+	}
+
+	// The client closes its side when it is done and then waits for the
+	// last acknowledgements, which end with our side closed
+	defer stream.Close()
+	var ack []byte
+	readFramedPackets(stream, r, packets, pattern, func(packet []byte) {
+		if !echo {
+			return
+		}
+		ack = internal.AppendEchoAck(ack[:0], packet, time.Now())
+		if _, err := stream.Write(ack); err != nil {
+			// The client stopped reading; the rest of the stream is only counted
+			log.Printf("Echo on connection %s stopped: %v", packets.connID, err)
+			echo = false
+			return
+		}
+		packets.metrics.EchoReplies.Add(1)
+	})
+}
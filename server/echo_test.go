@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// testTLSConfig returns the server TLS config for cfg and fails t if it
+// cannot be made
+func testTLSConfig(t *testing.T, cfg internal.TestConfig) *tls.Config {
+	t.Helper()
+	conf, err := makeTLSConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conf
+}
+
+// dialEchoStream starts a server connection handler with opts and opens a
+// stream to it that carries the echo header
+func dialEchoStream(t *testing.T, ctx context.Context, metrics *serverMetrics, opts streamOptions) quic.Stream {
+	t.Helper()
+	cfg := internal.TestConfig{NoTLS: true}
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, cfg), serverQUICConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		handleConn(ctx, conn, metrics, opts)
+	}()
+
+	conn, err := quic.DialAddr(ctx, listener.Addr().String(), internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.CloseWithError(0, "") })
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Write(internal.EchoHeader("increment")); err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+// sendIncrementPackets writes n framed increment packets numbered from 1
+func sendIncrementPackets(t *testing.T, stream quic.Stream, n int) {
+	t.Helper()
+	for seq := uint64(1); seq <= uint64(n); seq++ {
+		packet := make([]byte, 1200)
+		for i := range packet {
+			packet[i] = byte(i % 256)
+		}
+		binary.LittleEndian.PutUint64(packet, seq)
+		if _, err := stream.Write(internal.FramePatternPacket(packet)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEchoAcknowledgesPackets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	metrics := &serverMetrics{Start: time.Now()}
+	stream := dialEchoStream(t, ctx, metrics, streamOptions{echo: true})
+
+	const packets = 5
+	start := time.Now()
+	sendIncrementPackets(t, stream, packets)
+	stream.Close()
+
+	for seq := uint64(1); seq <= packets; seq++ {
+		ack, err := internal.ReadEchoAck(stream)
+		if err != nil {
+			t.Fatalf("Expected an acknowledgement of packet %d: %v", seq, err)
+		}
+		if ack.Seq != seq {
+			t.Errorf("Expected packet %d to be acknowledged, got %d", seq, ack.Seq)
+		}
+		if ack.ServerTime.Before(start.Add(-time.Second)) || ack.ServerTime.After(time.Now().Add(time.Second)) {
+			t.Errorf("Expected the server time of packet %d around now, got %v", seq, ack.ServerTime)
+		}
+	}
+	// The server closes its side once the client's side has ended
+	if _, err := internal.ReadEchoAck(stream); err != io.EOF {
+		t.Errorf("Expected the server to close the stream, got %v", err)
+	}
+
+	pattern := metrics.pattern.load()
+	if replies := metrics.EchoReplies.Load(); replies != packets {
+		t.Errorf("Expected %d echo replies, got %d", packets, replies)
+	}
+	if pattern.Packets != packets || pattern.Corrupted != 0 {
+		t.Errorf("Expected the pattern named in the echo header to be verified, got %+v", pattern)
+	}
+}
+
+func TestEchoStreamWithoutEchoIsOnlyCounted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	metrics := &serverMetrics{Start: time.Now()}
+	stream := dialEchoStream(t, ctx, metrics, streamOptions{})
+
+	const packets = 5
+	sendIncrementPackets(t, stream, packets)
+	stream.Close()
+
+	want := int64(packets * (internal.PatternFrameOverhead + 1200))
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.Bytes.Load() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d bytes, got %d", want, metrics.Bytes.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if replies := metrics.EchoReplies.Load(); replies != 0 {
+		t.Errorf("Expected no echo replies without --echo, got %d", replies)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"time"
+
+	"quic-test/internal/fec"
+)
+
+const (
+	// fecGroupSize matches the group of 10 packets used by the client encoder
+	fecGroupSize = 10
+	// fecCleanupInterval is how often expired FEC groups are dropped
+	fecCleanupInterval = time.Second
+)
+
+// streamFEC is the FEC receive state of a single stream. The client encodes
+// every stream separately and numbers its groups from zero, so a decoder must
+// not be shared between streams or connections: a shared decoder mixes
+// unrelated packets into the same groups and corrupts recovery. The state is
+// owned by the stream handler and released when the stream ends, including
+// when the connection is closed.
+type streamFEC struct {
+	decoder     *fec.FECDecoder
+	packetID    uint64
+	groupID     uint64
+	lastCleanup time.Time
+	// Open groups and buffered bytes last added to the server-wide gauges
+	reportedGroups int64
+	reportedBytes  int64
+}
+
+func newStreamFEC() *streamFEC {
+	return &streamFEC{
+		decoder:     fec.NewFECDecoder(),
+		lastCleanup: time.Now(),
+	}
+}
+
+// isRepairPacket reports whether data is a FEC repair packet (0xFE 0xC0 marker)
+func isRepairPacket(data []byte) bool {
+	return len(data) >= 11 && data[0] == 0xFE && data[1] == 0xC0
+}
+
+// addRepair feeds a repair packet and returns the packets it recovered
+func (s *streamFEC) addRepair(data []byte) []fec.Recovered {
+	recovered, list := s.decoder.AddRedundancyPacket(data)
+	if !recovered {
+		return nil
+	}
+	return list
+}
+
+// addData feeds a regular packet, numbering packets in groups like the encoder
+func (s *streamFEC) addData(data []byte) {
+	s.decoder.AddPacket(data, s.packetID, s.groupID)
+	s.packetID++
+	if s.packetID >= fecGroupSize {
+		s.packetID = 0
+		s.groupID++
+	}
+}
+
+// maybeCleanup drops expired groups, at most once per fecCleanupInterval,
+// and reports what is left to the server-wide gauges
+func (s *streamFEC) maybeCleanup(now time.Time, metrics *serverMetrics) {
+	if now.Sub(s.lastCleanup) < fecCleanupInterval {
+		return
+	}
+	s.lastCleanup = now
+	s.decoder.CleanupGroups()
+	s.report(metrics)
+}
+
+// report updates the server-wide FEC gauges with the change in this
+// stream's open groups and buffered bytes since the last report
+func (s *streamFEC) report(metrics *serverMetrics) {
+	groups, bytes := s.decoder.GetMetrics().GroupsActive, s.decoder.BufferedBytes()
+	metrics.FECOpenGroups.Add(groups - s.reportedGroups)
+	metrics.FECBufferedBytes.Add(bytes - s.reportedBytes)
+	s.reportedGroups, s.reportedBytes = groups, bytes
+}
+
+// release takes this stream out of the server-wide gauges when it ends
+func (s *streamFEC) release(metrics *serverMetrics) {
+	metrics.FECOpenGroups.Add(-s.reportedGroups)
+	metrics.FECBufferedBytes.Add(-s.reportedBytes)
+	s.reportedGroups, s.reportedBytes = 0, 0
+}
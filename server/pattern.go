@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"sync/atomic"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// patternErrorCode resets a pattern stream whose packet lengths are corrupted
+const patternErrorCode quic.StreamErrorCode = 0x50
+
+// verifyPatternStream handles a stream sent with --pattern zeroes or
+// increment, whose first bytes (prefix) were already read. The header names
+// the pattern; every packet is counted like on a plain stream and checked
+// against it, FEC repair packets excepted.
+func verifyPatternStream(stream quic.ReceiveStream, packets *streamPackets, prefix []byte) {
+	r := io.MultiReader(bytes.NewReader(prefix), stream)
+	pattern, err := internal.ReadPatternHeader(r)
+	if err != nil {
+		packets.end(err)
+		return
+	}
+	readFramedPackets(stream, r, packets, pattern, nil)
+}
+
+// readFramedPackets counts the length-prefixed packets read from r until the
+// stream ends. With a pattern, every packet but FEC repair packets is
+// checked against it and then passed to onPacket, if not nil.
+func readFramedPackets(stream quic.ReceiveStream, r io.Reader, packets *streamPackets, pattern string, onPacket func(packet []byte)) {
+	metrics := packets.metrics
+	var verifier *internal.PatternVerifier
+	if pattern != "" {
+		verifier = internal.NewPatternVerifier(pattern)
+	}
+	var buf []byte
+	for {
+		packet, err := internal.ReadPatternPacket(r, buf)
+		if errors.Is(err, internal.ErrPatternFraming) {
+			// Packet boundaries are lost, so nothing after this can be checked
+			log.Printf("Stream on connection %s: %v, resetting the stream", packets.connID, err)
+			stream.CancelRead(patternErrorCode)
+			if verifier != nil {
+				metrics.pattern.record(internal.PatternCorrupted)
+			}
+			metrics.Errors.Add(1)
+			return
+		}
+		if err != nil {
+			packets.end(err)
+			return
+		}
+		buf = packet
+
+		if packets.add(packet, internal.PatternFrameOverhead+len(packet)) == requestFECRepair {
+			continue
+		}
+		if verifier != nil {
+			result := verifier.Check(packet)
+			metrics.pattern.record(result)
+			// One warning per stream; the counters keep the rest
+			if result == internal.PatternCorrupted && verifier.Stats().Corrupted == 1 {
+				log.Printf("Pattern %s on connection %s: packet %d does not match the pattern", pattern, packets.connID, verifier.Stats().Packets)
+			}
+		}
+		if onPacket != nil {
+			onPacket(packet)
+		}
+	}
+}
+
+// patternCounters count the packets of all pattern streams by the outcome
+// of their verification. Every packet updates them, so they are atomic like
+// the other server counters instead of taking a lock shared by all streams.
+type patternCounters struct {
+	packets, corrupted, reordered, duplicates atomic.Int64
+}
+
+// record counts one verified packet. Packets is updated first, so a reader
+// that loads it last never sees more failed than verified packets.
+func (c *patternCounters) record(result internal.PatternResult) {
+	c.packets.Add(1)
+	switch result {
+	case internal.PatternCorrupted:
+		c.corrupted.Add(1)
+	case internal.PatternReordered:
+		c.reordered.Add(1)
+	case internal.PatternDuplicate:
+		c.duplicates.Add(1)
+	}
+}
+
+// load returns the current counts
+func (c *patternCounters) load() internal.PatternStats {
+	stats := internal.PatternStats{
+		Corrupted:  c.corrupted.Load(),
+		Reordered:  c.reordered.Load(),
+		Duplicates: c.duplicates.Load(),
+	}
+	stats.Packets = c.packets.Load()
+	return stats
+}
+
+// logPatternStats logs the verification totals of pattern streams, if any
+func logPatternStats(stats internal.PatternStats) {
+	if stats.Packets == 0 {
+		return
+	}
+	log.Printf("Pattern verification: %d packets, %d corrupted, %d reordered, %d duplicates",
+		stats.Packets, stats.Corrupted, stats.Reordered, stats.Duplicates)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestPatternCorruptionDetected(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg := internal.TestConfig{NoTLS: true}
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, cfg), serverQUICConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	metrics := &serverMetrics{Start: time.Now()}
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		handleConn(ctx, conn, metrics, streamOptions{})
+	}()
+
+	conn, err := quic.DialAddr(ctx, listener.Addr().String(), internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _ := internal.PatternHeader("increment")
+	if _, err := stream.Write(header); err != nil {
+		t.Fatal(err)
+	}
+	const packets, corruptSeq = 10, 4
+	var wire int64
+	for seq := uint64(1); seq <= packets; seq++ {
+		packet := make([]byte, 1200)
+		for i := range packet {
+			packet[i] = byte(i % 256)
+		}
+		binary.LittleEndian.PutUint64(packet, seq)
+		if seq == corruptSeq {
+			packet[500]++
+		}
+		frame := internal.FramePatternPacket(packet)
+		wire += int64(len(frame))
+		if _, err := stream.Write(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := metrics.pattern.load()
+		bytes, errs := metrics.Bytes.Load(), metrics.Errors.Load()
+		if stats.Packets == packets {
+			if stats.Corrupted != 1 || stats.Reordered != 0 || stats.Duplicates != 0 {
+				t.Errorf("Expected exactly the one corrupted packet, got %+v", stats)
+			}
+			if bytes != wire || errs != 0 {
+				t.Errorf("Expected %d bytes and no errors, got %d bytes and %d errors", wire, bytes, errs)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d verified packets, got %+v", packets, stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPatternCountersConcurrentStreams(t *testing.T) {
+	var counters patternCounters
+	results := []internal.PatternResult{internal.PatternOK, internal.PatternCorrupted, internal.PatternReordered, internal.PatternDuplicate}
+	const streams, perStream = 8, 1000
+
+	var wg sync.WaitGroup
+	for s := 0; s < streams; s++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perStream; i++ {
+				counters.record(results[i%len(results)])
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := internal.PatternStats{
+		Packets:    streams * perStream,
+		Corrupted:  streams * perStream / 4,
+		Reordered:  streams * perStream / 4,
+		Duplicates: streams * perStream / 4,
+	}
+	if got := counters.load(); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
@@ -48,31 +48,33 @@ type ServerMetrics struct {
 }
 
 // NewAdvancedPrometheusExporter creates a new metrics exporter for the server
-func NewAdvancedPrometheusExporter(serverAddr string) *AdvancedPrometheusExporter {
+// and registers its metrics with reg
+func NewAdvancedPrometheusExporter(serverAddr string, reg prometheus.Registerer) *AdvancedPrometheusExporter {
+	factory := promauto.With(reg)
 	return &AdvancedPrometheusExporter{
-		metrics: metrics.NewPrometheusMetrics(prometheus.DefaultRegisterer),
+		metrics: metrics.NewPrometheusMetrics(reg),
 		serverMetrics: &ServerMetrics{
 			ServerAddr: serverAddr,
 			StartTime:  time.Now(),
 		},
-		requestTypeCounters: promauto.NewCounterVec(prometheus.CounterOpts{
+		requestTypeCounters: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "quic_server_request_type_total",
 			Help: "Total requests by type",
 		}, []string{"request_type", "connection_id", "stream_id", "result"}),
-		requestProcessingHistograms: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		requestProcessingHistograms: factory.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "quic_server_request_processing_duration_seconds",
 			Help:    "Request processing duration",
 			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0},
 		}, []string{"request_type", "connection_id", "result"}),
-		connectionMetrics: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		connectionMetrics: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "quic_server_connection_info",
 			Help: "Server connection information",
 		}, []string{"connection_id", "remote_addr", "tls_version", "cipher_suite", "state"}),
-		streamMetrics: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		streamMetrics: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "quic_server_stream_info",
 			Help: "Server stream information",
 		}, []string{"stream_id", "connection_id", "stream_type", "state", "direction"}),
-		dataProcessingMetrics: promauto.NewCounterVec(prometheus.CounterOpts{
+		dataProcessingMetrics: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "quic_server_data_processing_total",
 			Help: "Data processing metrics",
 		}, []string{"operation", "connection_id", "stream_id", "data_type"}),
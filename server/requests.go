@@ -0,0 +1,51 @@
+package server
+
+import (
+	"strconv"
+	"time"
+)
+
+// Request types of the client protocol, used as the request_type label of
+// the exporter's request metrics
+const (
+	requestHandshake = "handshake"  // connection accepted after its handshake
+	requestControl   = "control"    // stream opened or finished by the peer
+	requestPing      = "ping"       // packet carrying only the sequence number
+	requestData      = "data"       // regular data packet
+	requestFECRepair = "fec-repair" // FEC repair packet, only with FEC enabled
+	requestUpload    = "upload"     // whole file uploaded with --upload-file
+)
+
+// pingPacketSize is the size of the sequence number that starts every packet;
+// a packet of at most this size carries no payload
+const pingPacketSize = 8
+
+// classifyPacket returns the request type of a packet read from a stream
+func classifyPacket(data []byte, fecEnabled bool) string {
+	switch {
+	case fecEnabled && isRepairPacket(data):
+		return requestFECRepair
+	case len(data) <= pingPacketSize:
+		return requestPing
+	default:
+		return requestData
+	}
+}
+
+// newConnectionID numbers accepted connections for the connection_id label
+func (m *serverMetrics) newConnectionID() string {
+	return strconv.FormatInt(m.lastConnID.Add(1), 10)
+}
+
+// recordRequest reports a request handled since start to the exporter.
+// Without an exporter (Prometheus disabled) nothing is recorded.
+func (m *serverMetrics) recordRequest(requestType, connID string, start time.Time, failed bool) {
+	if m.exporter == nil {
+		return
+	}
+	result := "ok"
+	if failed {
+		result = "error"
+	}
+	m.exporter.RecordRequestProcessing(requestType, connID, time.Since(start), result)
+}
@@ -5,229 +5,760 @@ import (
 	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
+	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 
 	"quic-test/internal"
-	"quic-test/internal/fec"
+	"quic-test/internal/metrics"
+	"quic-test/internal/pcap"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	quic "github.com/quic-go/quic-go"
 )
 
-// serverMetrics stores server metrics
+// serverMetrics stores server metrics. The counters are updated by every
+// stream on every packet, so they are atomic and streams never wait for
+// each other to count. A snapshot loads the counters one by one, so they
+// may be a few packets apart.
 type serverMetrics struct {
-	mu          sync.Mutex
-	Connections int
-	Streams     int
-	Bytes       int64
+	Connections          atomic.Int64
+	Streams              atomic.Int64 // all streams, bidirectional and unidirectional
+	Bytes                atomic.Int64 // received on all streams
+	UniStreams           atomic.Int64 // unidirectional streams among Streams
+	UniBytes             atomic.Int64 // received on unidirectional streams, part of Bytes
+	Errors               atomic.Int64
+	FECRecovered         atomic.Int64 // packets recovered by the per-stream FEC decoders
+	UDPRecvDrops         atomic.Int64 // datagrams dropped by the kernel before quic-go read them
+	StreamDataViolations atomic.Int64 // streams reset for exceeding --max-stream-data
+	StreamResets         atomic.Int64 // streams reset by the peer
+	StreamsInterrupted   atomic.Int64 // streams cut off by the connection closing
+	StreamStalls         atomic.Int64 // streams reset for exceeding --stream-read-timeout or --stream-write-timeout
+	EchoReplies          atomic.Int64 // packets acknowledged with --echo
+	AcceptQueueDepth     atomic.Int64 // connections waiting in the accept queue
+	AcceptQueuePeak      atomic.Int64 // highest AcceptQueueDepth so far
+	AcceptRejected       atomic.Int64 // connections closed because the accept queue was full
+	FECOpenGroups        atomic.Int64 // groups held by the FEC decoders of open streams
+	FECBufferedBytes     atomic.Int64 // data held by those groups
+
+	Start time.Time
+
+	// pattern counts the packets of --pattern zeroes/increment streams by
+	// the outcome of their verification
+	pattern patternCounters
+
+	// CloseReasons counts closed connections by why they closed
+	CloseReasons *metrics.CloseReasons
+
+	// acceptWait observes the time connections spend in the accept queue
+	acceptWait prometheus.Histogram
+
+	// exporter receives per-request metrics; nil when Prometheus is disabled
+	exporter   *AdvancedPrometheusExporter
+	lastConnID atomic.Int64
+}
+
+// Stats is a snapshot of a running server's metrics
+type Stats struct {
+	Uptime      time.Duration
+	Connections int   // accepted so far
+	Streams     int   // opened so far, bidirectional and unidirectional
+	Bytes       int64 // received on all streams
 	Errors      int
-	Start       time.Time
-	FECDecoder  *fec.FECDecoder // FEC decoder for packet recovery
+	Pattern     internal.PatternStats
+
+	AcceptQueueDepth int64 // connections waiting for a handler
+	AcceptQueuePeak  int64 // highest queue depth so far
+	AcceptRejected   int64 // connections rejected by the full queue
+
+	FECOpenGroups    int64 // FEC groups the open streams are still decoding
+	FECBufferedBytes int64 // data held by those groups
+}
+
+// statsInterval is how often RunWithStats reports a snapshot
+const statsInterval = time.Second
+
+const (
+	// prometheusAddr is where --prometheus serves the server metrics
+	prometheusAddr = ":2113"
+	// prometheusShutdownTimeout bounds how long a stopping server waits
+	// for scrapes in progress
+	prometheusShutdownTimeout = 2 * time.Second
+)
+
+// Run starts the server with parameters from TestConfig and serves until ctx
+// is cancelled or the listener fails. Signals are not handled here: the
+// caller cancels ctx, so the server can be embedded and stopped
+// programmatically. A listen or accept failure is returned instead of
+// exiting the process.
+func Run(ctx context.Context, cfg internal.TestConfig) error {
+	return RunWithStats(ctx, cfg, nil)
+}
+
+// RunWithStats serves like Run. onStats, if not nil, receives a snapshot
+// every second and a last one after the listener is closed.
+func RunWithStats(ctx context.Context, cfg internal.TestConfig, onStats func(Stats)) error {
+	return RunWithHooks(ctx, cfg, Hooks{Stats: onStats})
+}
+
+// Hooks are the optional callbacks of RunWithHooks
+type Hooks struct {
+	// Ready is called once with the bound address when the server accepts
+	// connections. It is not called when the server fails to start.
+	Ready func(addr net.Addr)
+	// Stats receives a snapshot every second and a last one after the
+	// listener is closed
+	Stats func(Stats)
 }
 
-// Run starts the server with parameters from TestConfig
-func Run(cfg internal.TestConfig) {
+// RunWithHooks serves like Run and reports to hooks. When it returns, the
+// accept loop, all connection handlers and the Prometheus exporter have
+// stopped.
+func RunWithHooks(ctx context.Context, cfg internal.TestConfig, hooks Hooks) error {
+	onStats := hooks.Stats
+	// A listener failure stops the connection handlers like a cancelled ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Own registry instead of the global one so that several servers
+	// can run in one process without duplicate registration panics
+	registry := metrics.NewRegistry()
+	gatherer := metrics.WithLabels(registry, cfg.Labels)
 	metrics := &serverMetrics{
-		Start:      time.Now(),
-		FECDecoder: fec.NewFECDecoder(), // Initialize FEC decoder if needed
+		Start:        time.Now(),
+		CloseReasons: metrics.NewCloseReasons(),
+		acceptWait:   newAcceptWaitHistogram(),
 	}
-	
-	// Periodic cleanup of old FEC groups
-	go func() {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-		for range ticker.C {
-			if metrics.FECDecoder != nil {
-				metrics.FECDecoder.CleanupGroups()
-			}
-		}
-	}()
 
-	if cfg.Prometheus {
-		go startPrometheusExporter(metrics)
+	if err := internal.ValidateTransportParams(cfg.TransportParams); err != nil {
+		return err
 	}
-
-	tlsConf := makeTLSConfig(cfg)
-	listener, err := quic.ListenAddr(cfg.Addr, tlsConf, &quic.Config{})
+	tlsConf, err := makeTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	listener, socket, err := listen(cfg, tlsConf)
 	if err != nil {
-		log.Fatalf("Failed to start QUIC server: %v", err)
+		return fmt.Errorf("failed to start QUIC server: %w", err)
 	}
+	defer socket.Close()
 	log.Printf("QUIC server listening on %s", cfg.Addr)
+	if cfg.PcapPath != "" {
+		log.Printf("Capturing packets to %s", cfg.PcapPath)
+	}
 
-	done := make(chan struct{})
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-		<-c
-		log.Println("Stopping server...")
-		if err := listener.Close(); err != nil {
-			log.Printf("Warning: failed to close listener: %v\n", err)
+	// The exporter serves until ctx is done and is waited for like the
+	// accept loop, so the next server in the process can bind its port
+	exporterDone := make(chan struct{})
+	if cfg.Prometheus {
+		metrics.exporter = NewAdvancedPrometheusExporter(cfg.Addr, registry)
+		go func() {
+			defer close(exporterDone)
+			startPrometheusExporter(ctx, metrics, registry, gatherer)
+		}()
+	} else {
+		close(exporterDone)
+	}
+
+	// Datagrams dropped by the kernel before quic-go could read them
+	stopDropWatch := internal.WatchUDPDrops(socket.conn, time.Second, func(delta uint64) {
+		metrics.UDPRecvDrops.Add(int64(delta))
+	})
+	defer func() {
+		stopDropWatch()
+		if drops := metrics.UDPRecvDrops.Load(); drops > 0 {
+			log.Printf("Warning: the kernel dropped %d datagrams on receive (socket buffer overflow); consider --udp-recv-buffer", drops)
 		}
-		close(done)
 	}()
 
+	statsDone := make(chan struct{})
+	if onStats == nil {
+		close(statsDone)
+	} else {
+		go func() {
+			defer close(statsDone)
+			ticker := time.NewTicker(statsInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					onStats(metrics.snapshot())
+				}
+			}
+		}()
+	}
+
+	// Accepted connections wait in the queue until a handler takes them
+	var conns sync.WaitGroup
+	queue := newAcceptQueue(cfg.AcceptBacklog, cfg.MaxActiveConnections, metrics)
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		queue.run(ctx, func(conn quic.Connection) {
+			handleConn(ctx, conn, metrics, streamOptions{
+				fecEnabled:    cfg.FECEnabled,
+				outputFile:    cfg.OutputFile,
+				maxStreamData: cfg.MaxStreamData,
+				readTimeout:   cfg.StreamReadTimeout,
+				writeTimeout:  cfg.StreamWriteTimeout,
+				echo:          cfg.Echo,
+			})
+		}, func() { conns.Add(1) }, conns.Done)
+	}()
+
+	// The accept loop reports how it ended: nil when ctx was cancelled
+	acceptDone := make(chan error, 1)
 	go func() {
 		for {
-			conn, err := listener.Accept(context.Background())
+			conn, err := listener.Accept(ctx)
 			if err != nil {
-				metrics.mu.Lock()
-				metrics.Errors++
-				metrics.mu.Unlock()
-				break
+				if ctx.Err() != nil {
+					err = nil
+				} else {
+					metrics.Errors.Add(1)
+				}
+				acceptDone <- err
+				return
+			}
+			if queue.push(conn) {
+				metrics.Connections.Add(1)
 			}
-			metrics.mu.Lock()
-			metrics.Connections++
-			metrics.mu.Unlock()
-			go handleConn(conn, metrics)
 		}
 	}()
+	if hooks.Ready != nil {
+		hooks.Ready(listener.Addr())
+	}
 
 	// Wait for completion
-	<-done
+	var runErr error
+	select {
+	case <-ctx.Done():
+		runErr = <-acceptDone
+	case err := <-acceptDone:
+		runErr = fmt.Errorf("QUIC listener failed: %w", err)
+	}
+	log.Println("Stopping server...")
+	cancel()
+	if err := listener.Close(); err != nil {
+		log.Printf("Warning: failed to close listener: %v\n", err)
+	}
+	// Connections are closed while the socket can still send CONNECTION_CLOSE
+	<-queueDone
+	queue.drain()
+	conns.Wait()
+	logCloseReasons(metrics.CloseReasons)
+	// The last snapshot comes after all periodic ones
+	<-statsDone
+	<-exporterDone
+	stats := metrics.snapshot()
+	logPatternStats(stats.Pattern)
+	logAcceptQueue(stats, cfg.AcceptBacklog)
+	if onStats != nil {
+		onStats(stats)
+	}
+	return runErr
+}
+
+// snapshot returns the current metrics as Stats
+func (m *serverMetrics) snapshot() Stats {
+	pattern := m.pattern.load()
+	return Stats{
+		Uptime:      time.Since(m.Start),
+		Connections: int(m.Connections.Load()),
+		Streams:     int(m.Streams.Load()),
+		Bytes:       m.Bytes.Load(),
+		Errors:      int(m.Errors.Load()),
+		Pattern:     pattern,
+
+		AcceptQueueDepth: m.AcceptQueueDepth.Load(),
+		AcceptQueuePeak:  m.AcceptQueuePeak.Load(),
+		AcceptRejected:   m.AcceptRejected.Load(),
+
+		FECOpenGroups:    m.FECOpenGroups.Load(),
+		FECBufferedBytes: m.FECBufferedBytes.Load(),
+	}
+}
+
+// listen starts the QUIC listener on its own UDP socket, so that the socket
+// can be tuned and watched. With cfg.PcapPath set, the socket is wrapped so
+// that all datagrams are written to a pcap file; with UDP buffer sizes set,
+// they are applied before quic-go takes the socket over. The returned socket
+// must be closed after the listener.
+func listen(cfg internal.TestConfig, tlsConf *tls.Config) (*quic.Listener, *serverSocket, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", cfg.Addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.UDPRecvBuffer > 0 || cfg.UDPSendBuffer > 0 {
+		_, warnings := internal.ApplyUDPBuffers(udpConn, cfg.UDPRecvBuffer, cfg.UDPSendBuffer)
+		for _, warning := range warnings {
+			log.Printf("Warning: %s", warning)
+		}
+	}
+
+	socket := &serverSocket{conn: udpConn}
+	var packetConn net.PacketConn = udpConn
+	if cfg.PcapPath != "" {
+		writer, err := pcap.Create(cfg.PcapPath, cfg.PcapMaxBytes)
+		if err != nil {
+			udpConn.Close()
+			return nil, nil, err
+		}
+		socket.writer = writer
+		packetConn = pcap.WrapPacketConn(udpConn, writer)
+	}
+
+	listener, err := quic.Listen(packetConn, tlsConf, serverQUICConfig(cfg))
+	if err != nil {
+		socket.Close()
+		return nil, nil, err
+	}
+	if cfg.UDPRecvBuffer > 0 || cfg.UDPSendBuffer > 0 {
+		// quic-go may grow the buffers further, so report them once it is set up
+		if sizes, err := internal.ReadUDPBuffers(udpConn); err == nil {
+			log.Printf("UDP socket buffers: receive %d bytes, send %d bytes", sizes.Recv, sizes.Send)
+		}
+	}
+	return listener, socket, nil
+}
+
+// serverQUICConfig returns the QUIC configuration of the listener. With
+// --max-stream-data the stream flow control window never grows past it,
+// so a peer cannot have more than that in flight on one stream.
+// --max-incoming-streams and --max-incoming-uni-streams limit the streams
+// a peer may have open at once; quic-go defaults apply when they are unset.
+// --transport-param values, validated by Run, override all of these.
+func serverQUICConfig(cfg internal.TestConfig) *quic.Config {
+	conf := &quic.Config{}
+	if cfg.MaxStreamData > 0 {
+		conf.InitialStreamReceiveWindow = uint64(cfg.MaxStreamData)
+		conf.MaxStreamReceiveWindow = uint64(cfg.MaxStreamData)
+	}
+	if cfg.MaxIncomingStreams > 0 {
+		conf.MaxIncomingStreams = cfg.MaxIncomingStreams
+	}
+	if cfg.MaxIncomingUniStreams > 0 {
+		conf.MaxIncomingUniStreams = cfg.MaxIncomingUniStreams
+	}
+	if err := internal.ApplyTransportParams(conf, cfg.TransportParams); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return conf
+}
+
+// serverSocket is the UDP socket of the listener and its optional capture
+type serverSocket struct {
+	conn   *net.UDPConn
+	writer *pcap.Writer // nil without --pcap
 }
 
-func handleConn(conn quic.Connection, metrics *serverMetrics) {
+func (s *serverSocket) Close() error {
+	s.conn.Close()
+	if s.writer == nil {
+		return nil
+	}
+	log.Printf("pcap: %d packets written, %d dropped by size limit", s.writer.Packets(), s.writer.Dropped())
+	return s.writer.Close()
+}
+
+// streamOptions configures how the streams of accepted connections are handled
+type streamOptions struct {
+	fecEnabled    bool          // decode FEC repair packets; otherwise every packet is plain data
+	outputFile    string        // where file uploads are written; discarded when empty
+	maxStreamData int64         // streams sending more bytes are reset; 0 - no limit
+	readTimeout   time.Duration // streams idle for longer are reset; 0 - no limit
+	writeTimeout  time.Duration // streams not taking a reply within it are reset; 0 - no limit
+	echo          bool          // acknowledge the packets of --echo streams
+}
+
+// handleConn accepts the bidirectional and unidirectional streams of a
+// connection and handles them with opts until the connection or ctx ends
+func handleConn(ctx context.Context, conn quic.Connection, metrics *serverMetrics, opts streamOptions) {
+	// quic-go completes the handshake before Accept returns, so only the
+	// server-side setup of the connection is timed
+	start := time.Now()
+	connID := metrics.newConnectionID()
+	metrics.recordRequest(requestHandshake, connID, start, false)
+	
 	defer func() {
 		if err := conn.CloseWithError(0, "bye"); err != nil {
 			log.Printf("Warning: failed to close connection: %v\n", err)
 		}
+		// If the client or a timeout closed the connection first, that is
+		// the cause recorded rather than our own close
+		metrics.CloseReasons.Record(context.Cause(conn.Context()))
 	}()
+	// Closing the connection above also ends the unidirectional loop
+	go acceptUniStreams(conn, metrics, connID, opts)
+	for {
+		stream, err := conn.AcceptStream(ctx)
+		if err != nil {
+			// A server that is stopping closes its connections on purpose
+			if ctx.Err() == nil {
+				metrics.Errors.Add(1)
+			}
+			return
+		}
+		start := time.Now()
+		metrics.Streams.Add(1)
+		metrics.recordRequest(requestControl, connID, start, false)
+		stream = deadlineStream(stream, opts.readTimeout, opts.writeTimeout, connID, metrics)
+		go handleStream(limitStream(stream, opts.maxStreamData, connID, metrics), metrics, connID, opts)
+	}
+}
+
+// acceptUniStreams accepts the unidirectional streams of a connection until
+// it is closed. They carry test packets like bidirectional ones but cannot
+// carry uploads, which need a reply.
+func acceptUniStreams(conn quic.Connection, metrics *serverMetrics, connID string, opts streamOptions) {
 	for {
-		stream, err := conn.AcceptStream(context.Background())
+		stream, err := conn.AcceptUniStream(context.Background())
 		if err != nil {
-			metrics.mu.Lock()
-			metrics.Errors++
-			metrics.mu.Unlock()
+			// The closed connection is counted by the bidirectional loop
 			return
 		}
-		metrics.mu.Lock()
-		metrics.Streams++
-		metrics.mu.Unlock()
-		go handleStream(stream, metrics)
+		start := time.Now()
+		metrics.Streams.Add(1)
+		metrics.UniStreams.Add(1)
+		metrics.recordRequest(requestControl, connID, start, false)
+		stream = deadlineUniStream(stream, opts.readTimeout, connID, metrics)
+		go handleStream(limitUniStream(stream, opts.maxStreamData, connID, metrics), metrics, connID, opts)
 	}
 }
 
-func handleStream(stream quic.Stream, metrics *serverMetrics) {
+// handleStream counts the packets of a test stream. A bidirectional stream
+// that starts with the upload header carries a file instead and is handed to
+// receiveUpload, and one that starts with the echo header is answered by
+// echoStream; a stream that starts with the pattern header has its packets
+// verified by verifyPatternStream.
+func handleStream(stream quic.ReceiveStream, metrics *serverMetrics, connID string, opts streamOptions) {
+	bidi, isBidi := stream.(quic.Stream)
+	packets := newStreamPackets(metrics, connID, !isBidi, opts.fecEnabled)
 	buf := make([]byte, 4096)
-	packetID := uint64(0)
-	groupID := uint64(0)
 	
+	first := true
 	for {
 		n, err := stream.Read(buf)
 		if n > 0 {
-			// Check if this is a FEC repair packet (starts with 0xFE 0xC0)
-			if n >= 11 && buf[0] == 0xFE && buf[1] == 0xC0 {
-				// This is a FEC repair packet
-				if metrics.FECDecoder != nil {
-					recovered, recoveredList := metrics.FECDecoder.AddRedundancyPacket(buf[:n])
-					if recovered && len(recoveredList) > 0 {
-						// Successfully recovered packets
-						for _, rec := range recoveredList {
-							metrics.mu.Lock()
-							metrics.Bytes += int64(len(rec.Data))
-							metrics.mu.Unlock()
-						}
-					}
-				}
-			} else {
-				// Regular packet
-				metrics.mu.Lock()
-				metrics.Bytes += int64(n)
-				metrics.mu.Unlock()
-				
-				// Add to FEC decoder for possible recovery
-				if metrics.FECDecoder != nil {
-					metrics.FECDecoder.AddPacket(buf[:n], packetID, groupID)
-					packetID++
-					if packetID >= 10 {
-						packetID = 0
-						groupID++
-					}
-				}
+			if first && isBidi && internal.IsUploadPrefix(buf[:n]) {
+				receiveUpload(bidi, metrics, connID, buf[:n], opts.outputFile)
+				return
+			}
+			if first && isBidi && internal.IsEchoPrefix(buf[:n]) {
+				echoStream(bidi, packets, buf[:n], opts.echo)
+				return
+			}
+			if first && internal.IsPatternPrefix(buf[:n]) {
+				verifyPatternStream(stream, packets, buf[:n])
+				return
 			}
+			first = false
+			packets.add(buf[:n], n)
 		}
 		if err != nil {
-			if err.Error() != "EOF" {
-				metrics.mu.Lock()
-				metrics.Errors++
-				metrics.mu.Unlock()
-			}
+			packets.end(err)
 			return
 		}
 	}
 }
 
-func makeTLSConfig(cfg internal.TestConfig) *tls.Config {
+// streamPackets counts the packets of one stream and feeds its FEC decoder
+type streamPackets struct {
+	metrics    *serverMetrics
+	connID     string
+	uni        bool
+	fecEnabled bool
+	// FEC state of this stream only; dropped when the handler returns.
+	// Without FEC there is nothing to decode and no packet is inspected.
+	fec *streamFEC
+}
+
+func newStreamPackets(metrics *serverMetrics, connID string, uni, fecEnabled bool) *streamPackets {
+	p := &streamPackets{metrics: metrics, connID: connID, uni: uni, fecEnabled: fecEnabled}
+	if fecEnabled {
+		p.fec = newStreamFEC()
+	}
+	return p
+}
+
+// add counts a packet that took wireBytes on the stream and returns its
+// request type
+func (p *streamPackets) add(data []byte, wireBytes int) string {
+	metrics := p.metrics
+	start := time.Now()
+	requestType := classifyPacket(data, p.fecEnabled)
+	if requestType == requestFECRepair {
+		// Successfully recovered packets count as received data
+		recovered := p.fec.addRepair(data)
+		if len(recovered) > 0 {
+			metrics.FECRecovered.Add(int64(len(recovered)))
+			for _, rec := range recovered {
+				metrics.addBytes(int64(len(rec.Data)), p.uni)
+			}
+		}
+	} else {
+		// Regular packet
+		metrics.addBytes(int64(wireBytes), p.uni)
+		
+		// Add to FEC decoder for possible recovery
+		if p.fec != nil {
+			p.fec.addData(data)
+			p.fec.maybeCleanup(time.Now(), metrics)
+		}
+	}
+	metrics.recordRequest(requestType, p.connID, start, false)
+	return requestType
+}
+
+// end counts the end of the stream, a control request of its own
+func (p *streamPackets) end(err error) {
+	start := time.Now()
+	failed := p.metrics.recordStreamEnd(classifyStreamEnd(err))
+	p.metrics.recordRequest(requestControl, p.connID, start, failed)
+	if p.fec != nil {
+		p.fec.release(p.metrics)
+	}
+}
+
+// addBytes counts received stream data. Bytes is updated before UniBytes,
+// so a reader that loads UniBytes first never sees more uni than total bytes.
+func (m *serverMetrics) addBytes(n int64, uni bool) {
+	m.Bytes.Add(n)
+	if uni {
+		m.UniBytes.Add(n)
+	}
+}
+
+func makeTLSConfig(cfg internal.TestConfig) (*tls.Config, error) {
 	if cfg.CertPath != "" && cfg.KeyPath != "" {
 		cert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
 		if err != nil {
-			log.Fatalf("Certificate loading error: %v", err)
+			return nil, fmt.Errorf("certificate loading error: %w", err)
 		}
 		return &tls.Config{
 			Certificates: []tls.Certificate{cert},
 			NextProtos:   []string{"quic-test"},
 			MinVersion:   tls.VersionTLS12,
-		}
+		}, nil
 	}
 	
-	// Use unified function for TLS configuration generation
-	return internal.GenerateTLSConfig(cfg.NoTLS)
+	if cfg.NoTLS {
+		return internal.GenerateTLSConfig(true), nil
+	}
+	// Test mode shares its certificate with the client of the pair
+	if cfg.LoopbackCert != nil {
+		return &tls.Config{
+			Certificates: []tls.Certificate{*cfg.LoopbackCert},
+			NextProtos:   []string{"quic-test"},
+			MinVersion:   tls.VersionTLS12,
+		}, nil
+	}
+	// A throwaway certificate with the SANs clients will verify
+	cert, err := internal.GenerateSelfSignedCert(cfg.CertHosts, cfg.CertValidity)
+	if err != nil {
+		return nil, fmt.Errorf("certificate generation error: %w", err)
+	}
+	hosts := cfg.CertHosts
+	if len(hosts) == 0 {
+		hosts = internal.DefaultCertHosts
+	}
+	log.Printf("Self-signed certificate for %s, valid until %s, SHA-256 %s",
+		strings.Join(hosts, ", "), cert.Leaf.NotAfter.Format(time.RFC3339), internal.CertificateHash(cert))
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"quic-test"},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
 }
 
 // printServerMetrics removed - no longer used
 
-func startPrometheusExporter(metrics *serverMetrics) {
+// logCloseReasons logs how many connections closed for each reason
+func logCloseReasons(reasons *metrics.CloseReasons) {
+	for _, r := range reasons.Summary() {
+		log.Printf("Connections closed by %s: %d (%d by the client) %v", r.Reason, r.Count, r.Remote, r.Codes)
+	}
+}
+
+// closeReasonGauges exports the close reason distribution, one series per reason
+func closeReasonGauges(reasons *metrics.CloseReasons) []prometheus.Collector {
+	gauges := make([]prometheus.Collector, 0, len(metrics.CloseReasonNames))
+	for _, reason := range metrics.CloseReasonNames {
+		gauges = append(gauges, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "quic_server_connection_close_total",
+			Help:        "Closed connections by close reason",
+			ConstLabels: prometheus.Labels{"reason": reason},
+		}, func() float64 {
+			return float64(reasons.Count(reason))
+		}))
+	}
+	return gauges
+}
+
+// startPrometheusExporter serves the server metrics on prometheusAddr until
+// ctx is done
+func startPrometheusExporter(ctx context.Context, metrics *serverMetrics, reg *prometheus.Registry, gatherer prometheus.Gatherer) {
 	connections := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "quic_server_connections_total",
 		Help: "Total connections",
 	}, func() float64 {
-		metrics.mu.Lock()
-		defer metrics.mu.Unlock()
-		return float64(metrics.Connections)
+		return float64(metrics.Connections.Load())
 	})
 	streams := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "quic_server_streams_total",
 		Help: "Total streams",
 	}, func() float64 {
-		metrics.mu.Lock()
-		defer metrics.mu.Unlock()
-		return float64(metrics.Streams)
+		return float64(metrics.Streams.Load())
 	})
 	bytes := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "quic_server_bytes_total",
 		Help: "Total bytes received",
 	}, func() float64 {
-		metrics.mu.Lock()
-		defer metrics.mu.Unlock()
-		return float64(metrics.Bytes)
+		return float64(metrics.Bytes.Load())
 	})
 	errors := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "quic_server_errors_total",
 		Help: "Total errors",
 	}, func() float64 {
-		metrics.mu.Lock()
-		defer metrics.mu.Unlock()
-		return float64(metrics.Errors)
+		return float64(metrics.Errors.Load())
+	})
+	udpDrops := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_udp_receive_drops_total",
+		Help: "Datagrams dropped by the kernel before QUIC read them (receive buffer overflow)",
+	}, func() float64 {
+		return float64(metrics.UDPRecvDrops.Load())
+	})
+	violations := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_stream_data_violations_total",
+		Help: "Streams reset for sending more than --max-stream-data bytes",
+	}, func() float64 {
+		return float64(metrics.StreamDataViolations.Load())
+	})
+	resets := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_stream_resets_total",
+		Help: "Streams reset by the peer",
+	}, func() float64 {
+		return float64(metrics.StreamResets.Load())
+	})
+	stalls := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_stream_stalls_total",
+		Help: "Streams reset for exceeding --stream-read-timeout or --stream-write-timeout",
+	}, func() float64 {
+		return float64(metrics.StreamStalls.Load())
+	})
+	interrupted := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_streams_interrupted_total",
+		Help: "Streams cut off by their connection closing before they finished",
+	}, func() float64 {
+		return float64(metrics.StreamsInterrupted.Load())
+	})
+	echoReplies := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_echo_replies_total",
+		Help: "Packets acknowledged to the client with --echo",
+	}, func() float64 {
+		return float64(metrics.EchoReplies.Load())
 	})
+	acceptQueueDepth := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_accept_queue_depth",
+		Help: "Accepted connections waiting for a handler",
+	}, func() float64 {
+		return float64(metrics.AcceptQueueDepth.Load())
+	})
+	acceptQueuePeak := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_accept_queue_peak_depth",
+		Help: "Highest accept queue depth since the server started",
+	}, func() float64 {
+		return float64(metrics.AcceptQueuePeak.Load())
+	})
+	acceptRejected := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "quic_server_accept_queue_rejected_total",
+		Help: "Connections closed because the accept queue was full",
+	}, func() float64 {
+		return float64(metrics.AcceptRejected.Load())
+	})
+	// Bidirectional counts are the totals without the unidirectional ones
+	streamsByType := func(streamType string, count func() float64) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "quic_server_streams_by_type_total",
+			Help:        "Total streams by stream type (bidi, uni)",
+			ConstLabels: prometheus.Labels{"stream_type": streamType},
+		}, count)
+	}
+	bytesByType := func(streamType string, count func() float64) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "quic_server_bytes_by_type_total",
+			Help:        "Total bytes received by stream type (bidi, uni)",
+			ConstLabels: prometheus.Labels{"stream_type": streamType},
+		}, count)
+	}
+	// The uni counter is loaded first: it is updated after the total, so the
+	// difference cannot go negative
+	bidiStreams := streamsByType("bidi", func() float64 {
+		uni := metrics.UniStreams.Load()
+		return float64(metrics.Streams.Load() - uni)
+	})
+	uniStreams := streamsByType("uni", func() float64 { return float64(metrics.UniStreams.Load()) })
+	bidiBytes := bytesByType("bidi", func() float64 {
+		uni := metrics.UniBytes.Load()
+		return float64(metrics.Bytes.Load() - uni)
+	})
+	uniBytes := bytesByType("uni", func() float64 { return float64(metrics.UniBytes.Load()) })
+	// Packets of --pattern zeroes/increment streams by verification result
+	patternPackets := func(result string, count func() float64) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "quic_server_pattern_packets_total",
+			Help:        "Packets of pattern-verified streams by result (ok, corrupted, reordered, duplicate)",
+			ConstLabels: prometheus.Labels{"result": result},
+		}, count)
+	}
+	patternOK := patternPackets("ok", func() float64 {
+		p := metrics.pattern.load()
+		return float64(p.Packets - p.Corrupted - p.Reordered - p.Duplicates)
+	})
+	patternCorrupted := patternPackets("corrupted", func() float64 { return float64(metrics.pattern.corrupted.Load()) })
+	patternReordered := patternPackets("reordered", func() float64 { return float64(metrics.pattern.reordered.Load()) })
+	patternDuplicates := patternPackets("duplicate", func() float64 { return float64(metrics.pattern.duplicates.Load()) })
 	uptime := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Name: "quic_server_uptime_seconds",
 		Help: "Server uptime in seconds",
 	}, func() float64 {
-		metrics.mu.Lock()
-		defer metrics.mu.Unlock()
 		return time.Since(metrics.Start).Seconds()
 	})
 
-	prometheus.MustRegister(connections, streams, bytes, errors, uptime)
-	http.Handle("/metrics", promhttp.Handler())
-	fmt.Println("Prometheus server endpoint available at :2113/metrics")
-	if err := http.ListenAndServe(":2113", nil); err != nil {
+	reg.MustRegister(connections, streams, bytes, errors, udpDrops, violations, resets, stalls, interrupted, echoReplies, uptime,
+		bidiStreams, uniStreams, bidiBytes, uniBytes,
+		patternOK, patternCorrupted, patternReordered, patternDuplicates)
+	reg.MustRegister(acceptQueueDepth, acceptQueuePeak, acceptRejected)
+	if metrics.acceptWait != nil {
+		reg.MustRegister(metrics.acceptWait)
+	}
+	reg.MustRegister(closeReasonGauges(metrics.CloseReasons)...)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: prometheusAddr, Handler: mux}
+	serveDone := make(chan error, 1)
+	go func() { serveDone <- srv.ListenAndServe() }()
+	fmt.Printf("Prometheus server endpoint available at %s/metrics\n", prometheusAddr)
+	select {
+	case err := <-serveDone:
 		log.Printf("Failed to start Prometheus server: %v", err)
+		return
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), prometheusShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: Prometheus server shutdown: %v", err)
 	}
+	<-serveDone
 }
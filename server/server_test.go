@@ -0,0 +1,505 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/fec"
+
+	"github.com/prometheus/client_golang/prometheus"
+	quic "github.com/quic-go/quic-go"
+)
+
+// fakeStream delivers one packet per Read, like a stream whose writes are
+// never coalesced, and then ends with end (io.EOF when nil)
+type fakeStream struct {
+	quic.Stream
+	packets [][]byte
+	end     error
+}
+
+func (s *fakeStream) Read(p []byte) (int, error) {
+	if len(s.packets) == 0 {
+		if s.end != nil {
+			return 0, s.end
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, s.packets[0])
+	s.packets = s.packets[1:]
+	return n, nil
+}
+
+// fakeConn hands out its streams and then reports the connection as closed
+type fakeConn struct {
+	quic.Connection
+	streams chan quic.Stream
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newFakeConn(streams ...*fakeStream) *fakeConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &fakeConn{streams: make(chan quic.Stream, len(streams)), ctx: ctx, cancel: cancel}
+	for _, s := range streams {
+		c.streams <- s
+	}
+	close(c.streams)
+	return c
+}
+
+func (c *fakeConn) AcceptStream(ctx context.Context) (quic.Stream, error) {
+	if s, ok := <-c.streams; ok {
+		return s, nil
+	}
+	return nil, errors.New("connection closed")
+}
+
+// AcceptUniStream blocks until the connection is closed: fake connections
+// carry bidirectional streams only
+func (c *fakeConn) AcceptUniStream(ctx context.Context) (quic.ReceiveStream, error) {
+	<-c.ctx.Done()
+	return nil, errors.New("connection closed")
+}
+
+func (c *fakeConn) CloseWithError(quic.ApplicationErrorCode, string) error {
+	c.cancel()
+	return nil
+}
+
+func (c *fakeConn) Context() context.Context { return c.ctx }
+
+// fecTraffic encodes one group of packets filled with fill and drops the last
+// one, so that the server can only get it back from the repair packet
+func fecTraffic(t *testing.T, fill byte) (sent [][]byte, lost []byte) {
+	t.Helper()
+	encoder := fec.NewFECEncoder(0.10)
+	var repair []byte
+	for i := 0; i < fecGroupSize; i++ {
+		packet := bytes.Repeat([]byte{fill + byte(i)}, 100)
+		ok, redundancy, err := encoder.AddPacket(packet, uint64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < fecGroupSize-1 {
+			sent = append(sent, packet)
+		} else {
+			lost = packet
+		}
+		if ok {
+			repair = redundancy
+		}
+	}
+	if repair == nil {
+		t.Fatal("Encoder produced no repair packet")
+	}
+	return append(sent, repair), lost
+}
+
+func TestFECRecoveryIsPerConnection(t *testing.T) {
+	metrics := &serverMetrics{}
+
+	// Both connections number their FEC groups from zero; a shared decoder
+	// would put their packets into the same groups and recover nothing
+	trafficA, _ := fecTraffic(t, 0x10)
+	trafficB, _ := fecTraffic(t, 0x80)
+
+	var wg sync.WaitGroup
+	for _, traffic := range [][][]byte{trafficA, trafficB} {
+		wg.Add(1)
+		go func(traffic [][]byte) {
+			defer wg.Done()
+			handleConn(context.Background(), newFakeConn(&fakeStream{packets: traffic}), metrics, streamOptions{fecEnabled: true})
+		}(traffic)
+	}
+	wg.Wait()
+
+	// Streams are handled in their own goroutines and may still be running
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		recovered, received := metrics.FECRecovered.Load(), metrics.Bytes.Load()
+		if recovered == 2 && received == 2*fecGroupSize*100 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 recovered packets and %d bytes, got %d and %d",
+				2*fecGroupSize*100, recovered, received)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamFECRecoversOwnPackets(t *testing.T) {
+	trafficA, lostA := fecTraffic(t, 0x10)
+	trafficB, lostB := fecTraffic(t, 0x80)
+	stateA, stateB := newStreamFEC(), newStreamFEC()
+
+	// Interleave the two streams as concurrent connections would
+	var recoveredA, recoveredB []fec.Recovered
+	for i := range trafficA {
+		for _, s := range []struct {
+			state     *streamFEC
+			packet    []byte
+			recovered *[]fec.Recovered
+		}{{stateA, trafficA[i], &recoveredA}, {stateB, trafficB[i], &recoveredB}} {
+			if isRepairPacket(s.packet) {
+				*s.recovered = append(*s.recovered, s.state.addRepair(s.packet)...)
+			} else {
+				s.state.addData(s.packet)
+			}
+		}
+	}
+
+	if len(recoveredA) != 1 || !bytes.Equal(recoveredA[0].Data, lostA) {
+		t.Errorf("Stream A did not recover its own lost packet: %+v", recoveredA)
+	}
+	if len(recoveredB) != 1 || !bytes.Equal(recoveredB[0].Data, lostB) {
+		t.Errorf("Stream B did not recover its own lost packet: %+v", recoveredB)
+	}
+}
+
+func TestFECGaugesFollowOpenStreams(t *testing.T) {
+	metrics := &serverMetrics{}
+	packets := newStreamPackets(metrics, "1", false, true)
+	packet := bytes.Repeat([]byte{0x42}, 100)
+
+	// A group that never completes stays open in the decoder
+	packets.add(packet, len(packet))
+	packets.fec.lastCleanup = time.Time{}
+	packets.add(packet, len(packet))
+	stats := metrics.snapshot()
+	if stats.FECOpenGroups != 1 || stats.FECBufferedBytes != 2*int64(len(packet)) {
+		t.Errorf("Expected 1 open group holding %d bytes, got %d and %d",
+			2*len(packet), stats.FECOpenGroups, stats.FECBufferedBytes)
+	}
+
+	packets.end(io.EOF)
+	stats = metrics.snapshot()
+	if stats.FECOpenGroups != 0 || stats.FECBufferedBytes != 0 {
+		t.Errorf("Expected the ended stream to leave the gauges, got %d groups and %d bytes",
+			stats.FECOpenGroups, stats.FECBufferedBytes)
+	}
+}
+
+func TestFECDisabledCountsAllPacketsAsData(t *testing.T) {
+	metrics := &serverMetrics{}
+	traffic, _ := fecTraffic(t, 0x10)
+
+	handleStream(&fakeStream{packets: traffic}, metrics, "1", streamOptions{})
+
+	var want int64
+	for _, packet := range traffic {
+		want += int64(len(packet))
+	}
+	if metrics.FECRecovered.Load() != 0 || metrics.Bytes.Load() != want {
+		t.Errorf("Expected %d plain bytes and no recovery, got %d bytes, %d recovered",
+			want, metrics.Bytes.Load(), metrics.FECRecovered.Load())
+	}
+}
+
+// requestCounts sums the request counters and histogram samples by request type
+func requestCounts(t *testing.T, reg *prometheus.Registry) (counters, observations map[string]float64) {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counters, observations = make(map[string]float64), make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var requestType string
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "request_type" {
+					requestType = label.GetValue()
+				}
+			}
+			switch family.GetName() {
+			case "quic_server_request_type_total":
+				counters[requestType] += metric.GetCounter().GetValue()
+			case "quic_server_request_processing_duration_seconds":
+				observations[requestType] += float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return counters, observations
+}
+
+func TestRequestTypesRecordedPerRun(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := &serverMetrics{exporter: NewAdvancedPrometheusExporter("test", reg)}
+
+	// One FEC stream (9 data packets and a repair packet) and one stream
+	// with a ping followed by a data packet
+	traffic, _ := fecTraffic(t, 0x10)
+	ping := bytes.Repeat([]byte{0x01}, pingPacketSize)
+	handleConn(context.Background(), newFakeConn(
+		&fakeStream{packets: traffic},
+		&fakeStream{packets: [][]byte{ping, bytes.Repeat([]byte{0x02}, 100)}},
+	), metrics, streamOptions{fecEnabled: true})
+
+	want := map[string]float64{
+		requestHandshake: 1,
+		requestControl:   4, // 2 streams opened and finished
+		requestData:      fecGroupSize,
+		requestFECRepair: 1,
+		requestPing:      1,
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		counters, observations := requestCounts(t, reg)
+		done := true
+		for requestType, n := range want {
+			if counters[requestType] != n || observations[requestType] != n {
+				done = false
+			}
+		}
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected requests %v, got counters %v, histogram samples %v", want, counters, observations)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClassifyPacket(t *testing.T) {
+	repair := append([]byte{0xFE, 0xC0}, make([]byte, 20)...)
+	for _, tc := range []struct {
+		data       []byte
+		fecEnabled bool
+		want       string
+	}{
+		{repair, true, requestFECRepair},
+		{repair, false, requestData},
+		{make([]byte, pingPacketSize), true, requestPing},
+		{make([]byte, 1200), true, requestData},
+	} {
+		if got := classifyPacket(tc.data, tc.fecEnabled); got != tc.want {
+			t.Errorf("classifyPacket(%d bytes, fec=%v) = %s, want %s", len(tc.data), tc.fecEnabled, got, tc.want)
+		}
+	}
+}
+
+// BenchmarkHandleStream measures the per-packet cost of the stream handler
+// with and without FEC decoding
+func BenchmarkHandleStream(b *testing.B) {
+	packet := bytes.Repeat([]byte{0x42}, 1200)
+	packets := make([][]byte, 1000)
+	for i := range packets {
+		packets[i] = packet
+	}
+
+	for _, fecEnabled := range []bool{false, true} {
+		name := "fec_off"
+		if fecEnabled {
+			name = "fec_on"
+		}
+		b.Run(name, func(b *testing.B) {
+			metrics := &serverMetrics{}
+			b.SetBytes(int64(len(packet) * len(packets)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				handleStream(&fakeStream{packets: packets}, metrics, "1", streamOptions{fecEnabled: fecEnabled})
+			}
+		})
+	}
+}
+
+// BenchmarkConcurrentStreams measures packet accounting with many streams
+// counting at once. The mutex case takes one lock shared by all streams
+// around every packet, as the counters needed before they became atomic,
+// for comparison with the lock-free path.
+func BenchmarkConcurrentStreams(b *testing.B) {
+	packet := bytes.Repeat([]byte{0x42}, 1200)
+	for _, shared := range []bool{false, true} {
+		name := "atomic"
+		if shared {
+			name = "mutex"
+		}
+		b.Run(name, func(b *testing.B) {
+			metrics := &serverMetrics{}
+			var mu sync.Mutex
+			b.SetBytes(int64(len(packet)))
+			// Several streams per CPU, each with its own goroutine
+			b.SetParallelism(8)
+			b.RunParallel(func(pb *testing.PB) {
+				packets := newStreamPackets(metrics, "1", false, false)
+				for pb.Next() {
+					if shared {
+						mu.Lock()
+					}
+					packets.add(packet, len(packet))
+					if shared {
+						mu.Unlock()
+					}
+				}
+			})
+			if metrics.Bytes.Load() != int64(b.N*len(packet)) {
+				b.Fatalf("Expected %d bytes, got %d", b.N*len(packet), metrics.Bytes.Load())
+			}
+		})
+	}
+}
+
+func TestRunReturnsListenError(t *testing.T) {
+	taken, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	// Run would previously exit the process here
+	err = Run(context.Background(), internal.TestConfig{Addr: taken.LocalAddr().String(), NoTLS: true})
+	if err == nil {
+		t.Fatal("Expected an error for an address in use")
+	}
+	if !strings.Contains(err.Error(), "failed to start QUIC server") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRunReturnsCertificateError(t *testing.T) {
+	dir := t.TempDir()
+	cfg := internal.TestConfig{
+		Addr:     "127.0.0.1:0",
+		CertPath: filepath.Join(dir, "missing.crt"),
+		KeyPath:  filepath.Join(dir, "missing.key"),
+	}
+
+	// Run would previously exit the process here too
+	err := Run(context.Background(), cfg)
+	if err == nil {
+		t.Fatal("Expected an error for a missing certificate")
+	}
+	if !strings.Contains(err.Error(), "certificate loading error") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, internal.TestConfig{Addr: "127.0.0.1:0", NoTLS: true}) }()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean stop, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return after the context was cancelled")
+	}
+}
+
+// TestRunReleasesPrometheusPort: the exporter stops with the server, so a
+// later server in the same process can serve its metrics on the same port
+func TestRunReleasesPrometheusPort(t *testing.T) {
+	probe, err := net.Listen("tcp", prometheusAddr)
+	if err != nil {
+		t.Skipf("Prometheus port is busy: %v", err)
+	}
+	probe.Close()
+
+	for run := 0; run < 2; run++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- Run(ctx, internal.TestConfig{Addr: "127.0.0.1:0", NoTLS: true, Prometheus: true})
+		}()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			resp, err := http.Get("http://127.0.0.1" + prometheusAddr + "/metrics")
+			if err == nil {
+				resp.Body.Close()
+				break
+			}
+			if time.Now().After(deadline) {
+				cancel()
+				t.Fatalf("Run %d: metrics not served: %v", run, err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Run %d: expected a clean stop, got %v", run, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Run %d: expected Run to return after the context was cancelled", run)
+		}
+	}
+	probe, err = net.Listen("tcp", prometheusAddr)
+	if err != nil {
+		t.Fatalf("Expected the Prometheus port to be free after Run returned: %v", err)
+	}
+	probe.Close()
+}
+
+func TestRunClosesConnectionsOnCancel(t *testing.T) {
+	free, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.LocalAddr().String()
+	free.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	var last Stats
+	go func() {
+		done <- RunWithStats(ctx, internal.TestConfig{Addr: addr, NoTLS: true}, func(s Stats) { last = s })
+	}()
+
+	dialCtx, dialCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer dialCancel()
+	var conn quic.Connection
+	for conn == nil {
+		conn, err = quic.DialAddr(dialCtx, addr, internal.GenerateTLSConfig(true), nil)
+		if err != nil && dialCtx.Err() != nil {
+			t.Fatalf("Failed to connect to the server: %v", err)
+		}
+	}
+	defer conn.CloseWithError(0, "")
+	stream, err := conn.OpenStreamSync(dialCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Write(make([]byte, 1200)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean stop, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return after the context was cancelled")
+	}
+	// The open connection is closed by the server, not left to time out
+	select {
+	case <-conn.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the server to close the client connection")
+	}
+	if last.Connections != 1 || last.Errors != 0 {
+		t.Errorf("Expected one connection and no errors from stopping, got %+v", last)
+	}
+}
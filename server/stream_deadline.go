@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// errStreamStalled is returned by a deadline stream once its peer has sent
+// nothing, or not taken a reply, within the configured timeout
+var errStreamStalled = errors.New("stream stalled")
+
+// timedStream resets a stream whose peer sends nothing for readTimeout or
+// does not take a reply within writeTimeout, with
+// internal.StreamStalledErrorCode as the application error
+type timedStream struct {
+	quic.Stream
+	timer streamTimer
+}
+
+// timedUniStream is timedStream for an incoming unidirectional stream
+type timedUniStream struct {
+	quic.ReceiveStream
+	timer streamTimer
+}
+
+// streamTimer applies the deadlines of one stream and counts its stall once
+type streamTimer struct {
+	read, write time.Duration
+	connID      string
+	metrics     *serverMetrics
+	stall       sync.Once
+}
+
+// deadlineStream enforces the timeouts on stream; with both timeouts
+// non-positive the stream is returned as is
+func deadlineStream(stream quic.Stream, readTimeout, writeTimeout time.Duration, connID string, metrics *serverMetrics) quic.Stream {
+	if readTimeout <= 0 && writeTimeout <= 0 {
+		return stream
+	}
+	return &timedStream{Stream: stream, timer: streamTimer{read: readTimeout, write: writeTimeout, connID: connID, metrics: metrics}}
+}
+
+// deadlineUniStream enforces readTimeout on a unidirectional stream
+func deadlineUniStream(stream quic.ReceiveStream, readTimeout time.Duration, connID string, metrics *serverMetrics) quic.ReceiveStream {
+	if readTimeout <= 0 {
+		return stream
+	}
+	return &timedUniStream{ReceiveStream: stream, timer: streamTimer{read: readTimeout, connID: connID, metrics: metrics}}
+}
+
+func (s *timedStream) Read(p []byte) (int, error) {
+	if s.timer.read > 0 {
+		s.Stream.SetReadDeadline(time.Now().Add(s.timer.read))
+	}
+	n, err := s.Stream.Read(p)
+	return n, s.timer.check(err, s.Stream, s.Stream.CancelWrite, "sent nothing", s.timer.read)
+}
+
+func (s *timedStream) Write(p []byte) (int, error) {
+	if s.timer.write > 0 {
+		s.Stream.SetWriteDeadline(time.Now().Add(s.timer.write))
+	}
+	n, err := s.Stream.Write(p)
+	return n, s.timer.check(err, s.Stream, s.Stream.CancelWrite, "took no reply", s.timer.write)
+}
+
+func (s *timedUniStream) Read(p []byte) (int, error) {
+	s.ReceiveStream.SetReadDeadline(time.Now().Add(s.timer.read))
+	n, err := s.ReceiveStream.Read(p)
+	// The server has no send side on a unidirectional stream
+	return n, s.timer.check(err, s.ReceiveStream, nil, "sent nothing", s.timer.read)
+}
+
+// check resets the stream when err is an expired deadline and returns
+// errStreamStalled instead. cancelWrite resets the send side too; nil when
+// there is none.
+func (t *streamTimer) check(err error, stream quic.ReceiveStream, cancelWrite func(quic.StreamErrorCode), what string, timeout time.Duration) error {
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	t.stall.Do(func() {
+		code := quic.StreamErrorCode(internal.StreamStalledErrorCode)
+		stream.CancelRead(code)
+		if cancelWrite != nil {
+			cancelWrite(code)
+		}
+		t.metrics.StreamStalls.Add(1)
+		log.Printf("Stream %d of connection %s %s for %v, reset", stream.StreamID(), t.connID, what, timeout)
+	})
+	return errStreamStalled
+}
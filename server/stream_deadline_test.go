@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestSilentStreamIsResetAfterReadTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg := internal.TestConfig{NoTLS: true}
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, cfg), serverQUICConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	metrics := &serverMetrics{Start: time.Now()}
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		handleConn(ctx, conn, metrics, streamOptions{readTimeout: 200 * time.Millisecond})
+	}()
+
+	conn, err := quic.DialAddr(ctx, listener.Addr().String(), internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	// One byte makes the stream visible to the server, then the client goes quiet
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Write([]byte{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, readErr := stream.Read(make([]byte, 1))
+	var se *quic.StreamError
+	if !errors.As(readErr, &se) {
+		t.Fatalf("Expected the server to reset the stream, read error: %v", readErr)
+	}
+	if !se.Remote || se.ErrorCode != quic.StreamErrorCode(internal.StreamStalledErrorCode) {
+		t.Errorf("Expected a remote reset with code %#x, got %v", internal.StreamStalledErrorCode, se)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.StreamStalls.Load() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected one stall, got %d", metrics.StreamStalls.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if errs := metrics.Errors.Load(); errs != 0 {
+		t.Errorf("Expected a stall not to count as a server error, got %d errors", errs)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"io"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// streamEnd is how a stream handled by the server ended
+type streamEnd int
+
+const (
+	streamEndClean      streamEnd = iota // the peer finished the stream
+	streamEndReset                       // the peer reset the stream
+	streamEndLimit                       // reset by the server for exceeding --max-stream-data
+	streamEndStalled                     // reset by the server for exceeding a stream timeout
+	streamEndConnClosed                  // the connection closed before the stream finished
+	streamEndError                       // any other read error
+)
+
+// classifyStreamEnd classifies the error that ended a stream read. Wrapped
+// errors are unwrapped, so io.EOF returned by a stream wrapper still counts
+// as a clean end.
+func classifyStreamEnd(err error) streamEnd {
+	var (
+		streamErr      *quic.StreamError
+		appErr         *quic.ApplicationError
+		transportErr   *quic.TransportError
+		idleErr        *quic.IdleTimeoutError
+		statelessReset *quic.StatelessResetError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return streamEndClean
+	case errors.Is(err, errStreamDataLimit):
+		return streamEndLimit
+	case errors.Is(err, errStreamStalled):
+		return streamEndStalled
+	case errors.As(err, &streamErr) && streamErr.Remote:
+		return streamEndReset
+	case errors.As(err, &appErr), errors.As(err, &transportErr),
+		errors.As(err, &idleErr), errors.As(err, &statelessReset):
+		return streamEndConnClosed
+	}
+	return streamEndError
+}
+
+// recordStreamEnd counts a stream end in metrics and reports whether the
+// stream failed. Only unexpected read errors count as server errors: peers
+// resetting streams or closing connections mid-stream are counted
+// separately, and streams over the --max-stream-data limit or a stream
+// timeout are counted as violations or stalls when they are reset.
+func (m *serverMetrics) recordStreamEnd(end streamEnd) (failed bool) {
+	switch end {
+	case streamEndClean:
+		return false
+	case streamEndReset:
+		m.StreamResets.Add(1)
+	case streamEndConnClosed:
+		m.StreamsInterrupted.Add(1)
+	case streamEndError:
+		m.Errors.Add(1)
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestStreamEndAttribution(t *testing.T) {
+	for _, tc := range []struct {
+		name                      string
+		end                       error
+		errors, resets, interrupt int
+	}{
+		{"clean EOF", io.EOF, 0, 0, 0},
+		{"wrapped EOF", fmt.Errorf("read: %w", io.EOF), 0, 0, 0},
+		{"reset by peer", &quic.StreamError{StreamID: 4, ErrorCode: 7, Remote: true}, 0, 1, 0},
+		{"connection closed by peer", &quic.ApplicationError{ErrorCode: 0, Remote: true}, 0, 0, 1},
+		{"idle timeout", &quic.IdleTimeoutError{}, 0, 0, 1},
+		{"data limit", errStreamDataLimit, 0, 0, 0},
+		{"unexpected error", errors.New("boom"), 1, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics := &serverMetrics{}
+			handleStream(&fakeStream{packets: [][]byte{make([]byte, 100)}, end: tc.end}, metrics, "1", streamOptions{})
+
+			if metrics.Bytes.Load() != 100 {
+				t.Errorf("Expected the data before the end to be counted, got %d bytes", metrics.Bytes.Load())
+			}
+			errs, resets, interrupted := metrics.Errors.Load(), metrics.StreamResets.Load(), metrics.StreamsInterrupted.Load()
+			if errs != int64(tc.errors) || resets != int64(tc.resets) || interrupted != int64(tc.interrupt) {
+				t.Errorf("Expected %d errors, %d resets, %d interrupted; got %d, %d, %d",
+					tc.errors, tc.resets, tc.interrupt, errs, resets, interrupted)
+			}
+		})
+	}
+}
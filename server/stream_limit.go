@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"log"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// errStreamDataLimit is returned by a limitedStream once the peer has sent
+// more than the configured maximum
+var errStreamDataLimit = errors.New("stream data limit exceeded")
+
+// limitedStream resets a stream whose peer sends more than limit bytes in
+// total, with internal.StreamDataLimitErrorCode as the application error
+type limitedStream struct {
+	quic.Stream
+	limiter streamLimiter
+}
+
+// limitedUniStream is limitedStream for an incoming unidirectional stream
+type limitedUniStream struct {
+	quic.ReceiveStream
+	limiter streamLimiter
+}
+
+// streamLimiter counts the bytes read from one stream
+type streamLimiter struct {
+	limit   int64
+	read    int64
+	connID  string
+	metrics *serverMetrics
+}
+
+// limitStream enforces limit on stream; a non-positive limit disables it
+func limitStream(stream quic.Stream, limit int64, connID string, metrics *serverMetrics) quic.Stream {
+	if limit <= 0 {
+		return stream
+	}
+	return &limitedStream{Stream: stream, limiter: streamLimiter{limit: limit, connID: connID, metrics: metrics}}
+}
+
+// limitUniStream enforces limit on a unidirectional stream
+func limitUniStream(stream quic.ReceiveStream, limit int64, connID string, metrics *serverMetrics) quic.ReceiveStream {
+	if limit <= 0 {
+		return stream
+	}
+	return &limitedUniStream{ReceiveStream: stream, limiter: streamLimiter{limit: limit, connID: connID, metrics: metrics}}
+}
+
+func (s *limitedStream) Read(p []byte) (int, error) {
+	return s.limiter.readFrom(s.Stream, p, s.Stream.CancelWrite)
+}
+
+func (s *limitedUniStream) Read(p []byte) (int, error) {
+	// The server has no send side on a unidirectional stream
+	return s.limiter.readFrom(s.ReceiveStream, p, nil)
+}
+
+// readFrom reads from stream into p and resets the stream once the limit is
+// exceeded. cancelWrite resets the send side too; nil when there is none.
+func (l *streamLimiter) readFrom(stream quic.ReceiveStream, p []byte, cancelWrite func(quic.StreamErrorCode)) (int, error) {
+	if l.read > l.limit {
+		return 0, errStreamDataLimit
+	}
+	n, err := stream.Read(p)
+	l.read += int64(n)
+	if l.read <= l.limit {
+		return n, err
+	}
+
+	// Only the bytes within the limit are handed to the caller
+	code := quic.StreamErrorCode(internal.StreamDataLimitErrorCode)
+	stream.CancelRead(code)
+	if cancelWrite != nil {
+		cancelWrite(code)
+	}
+	l.metrics.StreamDataViolations.Add(1)
+	log.Printf("Stream %d of connection %s sent more than %d bytes (--max-stream-data), reset",
+		stream.StreamID(), l.connID, l.limit)
+	return n - int(l.read-l.limit), errStreamDataLimit
+}
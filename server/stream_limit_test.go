@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestStreamExceedingMaxStreamDataIsReset(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const limit = 64 * 1024
+	cfg := internal.TestConfig{NoTLS: true, MaxStreamData: limit}
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, cfg), serverQUICConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	metrics := &serverMetrics{Start: time.Now()}
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		handleConn(ctx, conn, metrics, streamOptions{maxStreamData: limit})
+	}()
+
+	conn, err := quic.DialAddr(ctx, listener.Addr().String(), internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	// A stream within the limit is read to the end
+	within, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := within.Write(make([]byte, limit)); err != nil {
+		t.Fatalf("Write within the limit failed: %v", err)
+	}
+	within.Close()
+
+	// The client keeps writing past the limit until the server resets the stream
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := make([]byte, 4096)
+	var writeErr error
+	for written := 0; written < 100*limit && writeErr == nil; written += len(chunk) {
+		_, writeErr = stream.Write(chunk)
+	}
+	var se *quic.StreamError
+	if !errors.As(writeErr, &se) {
+		t.Fatalf("Expected the server to reset the stream, write error: %v", writeErr)
+	}
+	if !se.Remote || se.ErrorCode != quic.StreamErrorCode(internal.StreamDataLimitErrorCode) {
+		t.Errorf("Expected a remote reset with code %#x, got %v", internal.StreamDataLimitErrorCode, se)
+	}
+
+	// Only data within the limit is counted: the first stream and the
+	// accepted part of the second one
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		violations, bytes := metrics.StreamDataViolations.Load(), metrics.Bytes.Load()
+		if violations == 1 && bytes == 2*limit {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected one violation and %d bytes, got %d violations and %d bytes", 2*limit, violations, bytes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestUniStreamsAcceptedAndCounted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg := internal.TestConfig{NoTLS: true, MaxIncomingUniStreams: 1}
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, cfg), serverQUICConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	metrics := &serverMetrics{Start: time.Now()}
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		handleConn(ctx, conn, metrics, streamOptions{})
+	}()
+
+	conn, err := quic.DialAddr(ctx, listener.Addr().String(), internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	const uniSize, bidiSize = 10000, 3000
+	uni, err := conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// --max-incoming-uni-streams 1: no second stream while the first is open
+	if _, err := conn.OpenUniStream(); err == nil {
+		t.Error("Expected the server to allow only one open unidirectional stream")
+	}
+	if _, err := uni.Write(make([]byte, uniSize)); err != nil {
+		t.Fatalf("Write on the unidirectional stream failed: %v", err)
+	}
+	uni.Close()
+
+	bidi, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bidi.Write(make([]byte, bidiSize)); err != nil {
+		t.Fatal(err)
+	}
+	bidi.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		streams, uniStreams, bytes, uniBytes := metrics.Streams.Load(), metrics.UniStreams.Load(), metrics.Bytes.Load(), metrics.UniBytes.Load()
+		if streams == 2 && uniStreams == 1 && bytes == uniSize+bidiSize && uniBytes == uniSize {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 streams (1 uni) and %d bytes (%d uni), got %d streams (%d uni) and %d bytes (%d uni)",
+				uniSize+bidiSize, uniSize, streams, uniStreams, bytes, uniBytes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Once the first stream is read to the end, the peer may open another
+	next, err := conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		t.Fatalf("Expected a new unidirectional stream after the first one finished: %v", err)
+	}
+	next.Close()
+}
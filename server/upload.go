@@ -0,0 +1,79 @@
+package server
+
+import (
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+// uploadFileMu serializes uploads written to the same --output-file
+var uploadFileMu sync.Mutex
+
+// receiveUpload receives a file uploaded with --upload-file whose first bytes
+// (prefix) were already read from the stream. The content is written to
+// outputFile, or discarded when it is empty, and its checksum is verified
+// against the one sent by the client.
+func receiveUpload(stream quic.Stream, metrics *serverMetrics, connID string, prefix []byte, outputFile string) {
+	start := time.Now()
+
+	var dst io.Writer = io.Discard
+	if outputFile != "" {
+		uploadFileMu.Lock()
+		defer uploadFileMu.Unlock()
+		f, err := os.Create(outputFile)
+		if err != nil {
+			log.Printf("Upload: failed to create output file: %v", err)
+			stream.CancelRead(0)
+			stream.CancelWrite(0)
+			metrics.Errors.Add(1)
+			metrics.recordRequest(requestUpload, connID, start, true)
+			return
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Printf("Warning: failed to close output file: %v", err)
+			}
+		}()
+		dst = f
+	}
+
+	// Bytes are counted as they arrive so that metrics reflect a running upload
+	n, verified, err := internal.ReceiveUpload(stream, prefix, &countingWriter{w: dst, metrics: metrics})
+	elapsed := time.Since(start)
+	failed := err != nil || !verified
+	if failed {
+		metrics.Errors.Add(1)
+	}
+	metrics.recordRequest(requestUpload, connID, start, failed)
+
+	switch {
+	case err != nil:
+		log.Printf("Upload failed after %d bytes: %v", n, err)
+	case !verified:
+		log.Printf("Upload of %d bytes received in %v: checksum mismatch", n, elapsed)
+	default:
+		mbps := 0.0
+		if elapsed > 0 {
+			mbps = float64(n*8) / elapsed.Seconds() / 1e6
+		}
+		log.Printf("Upload of %d bytes received in %v (%.2f Mbps): checksum ok", n, elapsed, mbps)
+	}
+}
+
+// countingWriter adds the bytes written through it to the server metrics
+type countingWriter struct {
+	w       io.Writer
+	metrics *serverMetrics
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.metrics.Bytes.Add(int64(n))
+	return n, err
+}
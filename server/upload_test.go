@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"quic-test/internal"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+func TestUploadRoundTripIntegrity(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	content := make([]byte, 3<<20+123) // several MiB, not a multiple of the copy buffer
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "src.bin")
+	if err := os.WriteFile(src, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.bin")
+
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, internal.TestConfig{NoTLS: true}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	metrics := &serverMetrics{Start: time.Now()}
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		handleConn(ctx, conn, metrics, streamOptions{outputFile: out})
+	}()
+
+	conn, err := quic.DialAddr(ctx, listener.Addr().String(), internal.GenerateTLSConfig(true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, verified, err := internal.SendUpload(stream, f, int64(len(content)))
+	if err != nil {
+		t.Fatalf("SendUpload failed: %v", err)
+	}
+	if !verified {
+		t.Fatal("Expected the server to verify the checksum")
+	}
+	received, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, content) {
+		t.Errorf("Output file differs from the upload: %d of %d bytes", len(received), len(content))
+	}
+	if bytes, errs := metrics.Bytes.Load(), metrics.Errors.Load(); bytes != int64(len(content)) || errs != 0 {
+		t.Errorf("Expected %d bytes without errors, got %d bytes, %d errors", len(content), bytes, errs)
+	}
+}
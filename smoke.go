@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"quic-test/client"
+	"quic-test/internal"
+	"quic-test/server"
+
+	quic "github.com/quic-go/quic-go"
+)
+
+const (
+	// smokeDuration is how long the smoke test sends traffic
+	smokeDuration = 2 * time.Second
+	// smokeServerWait bounds how long the smoke test waits for the server to answer
+	smokeServerWait = 5 * time.Second
+)
+
+// runSmoke runs the smoke self-test and returns the process exit code
+func runSmoke() int {
+	fmt.Println("Running smoke test (in-process server and client on loopback)...")
+	if err := smokeTest(); err != nil {
+		fmt.Printf("❌ Smoke test failed: %v\n", err)
+		return 1
+	}
+	fmt.Println("✅ Smoke test passed")
+	return 0
+}
+
+// smokeTest starts a server and a client in this process on an ephemeral
+// loopback port with a self-signed certificate, sends traffic for
+// smokeDuration and checks the client's result: the handshake completed,
+// bytes were sent and no errors were recorded.
+func smokeTest() error {
+	port, err := freeLoopbackPort()
+	if err != nil {
+		return fmt.Errorf("no free port: %w", err)
+	}
+
+	cfg := internal.TestConfig{
+		Mode:        "test",
+		Addr:        net.JoinHostPort("127.0.0.1", fmt.Sprint(port)),
+		Connections: 1,
+		Streams:     1,
+		Duration:    smokeDuration,
+		PacketSize:  1200,
+		Rate:        100,
+		Pattern:     "random",
+		NoTLS:       true, // self-signed certificate
+		Quiet:       true,
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	// The server runs until the smoke test is done
+	ctx, stopServer := context.WithCancel(context.Background())
+	defer stopServer()
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.Run(ctx, cfg.ServerConfig()) }()
+	if err := waitForServer(cfg.Addr, smokeServerWait); err != nil {
+		// A failed listen explains the missing server better than the timeout
+		select {
+		case listenErr := <-serverErr:
+			if listenErr != nil {
+				return listenErr
+			}
+		default:
+		}
+		return err
+	}
+	result, err := client.RunContext(ctx, cfg.ClientConfig())
+	if err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+	// The same metrics the client writes to its report
+	return checkSmokeReport(internal.CreateReportSchema(result.Config, result.Metrics).Metrics)
+}
+
+// checkSmokeReport checks the client metrics of a smoke run
+func checkSmokeReport(m internal.MetricsSchema) error {
+	var problems []error
+	if m.TLSVersion == "" || m.OneRTT+m.ZeroRTT == 0 {
+		problems = append(problems, errors.New("handshake did not complete"))
+	}
+	if m.BytesSent == 0 {
+		problems = append(problems, errors.New("no bytes were sent"))
+	}
+	if m.Errors > 0 {
+		problems = append(problems, fmt.Errorf("%d errors recorded: %v", m.Errors, m.ErrorTypeCounts))
+	}
+	return errors.Join(problems...)
+}
+
+// freeLoopbackPort returns a UDP port on 127.0.0.1 that was free a moment ago
+func freeLoopbackPort() (int, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port, nil
+}
+
+// waitForServer retries a handshake until the server at addr answers
+func waitForServer(addr string, timeout time.Duration) error {
+	tlsConf := internal.GenerateTLSConfig(true)
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		conn, err := quic.DialAddr(ctx, addr, tlsConf, nil)
+		cancel()
+		if err == nil {
+			return conn.CloseWithError(0, "smoke probe")
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("server at %s did not answer within %v: %w", addr, timeout, err)
+		}
+	}
+}
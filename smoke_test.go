@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"quic-test/internal"
+)
+
+// TestSmoke runs the --smoke self-test as an end-to-end integration test
+func TestSmoke(t *testing.T) {
+	if testing.Short() {
+		t.Skip("smoke test sends traffic for 2s")
+	}
+	if err := smokeTest(); err != nil {
+		t.Fatalf("smoke test failed: %v", err)
+	}
+}
+
+func TestCheckSmokeReport(t *testing.T) {
+	ok := internal.MetricsSchema{TLSVersion: "TLS 1.3", OneRTT: 1, BytesSent: 12000}
+	if err := checkSmokeReport(ok); err != nil {
+		t.Errorf("Expected a passing report, got %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		metrics internal.MetricsSchema
+		want    string
+	}{
+		"no handshake": {internal.MetricsSchema{BytesSent: 1}, "handshake"},
+		"no bytes":     {internal.MetricsSchema{TLSVersion: "TLS 1.3", OneRTT: 1}, "no bytes"},
+		"errors": {internal.MetricsSchema{TLSVersion: "TLS 1.3", OneRTT: 1, BytesSent: 1, Errors: 2,
+			ErrorTypeCounts: map[string]int64{"stream_write": 2}}, "2 errors"},
+	} {
+		err := checkSmokeReport(tc.metrics)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", name, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"quic-test/internal"
+	"quic-test/internal/ice"
+)
+
+// stunOptions are the flags of --mode stun
+type stunOptions struct {
+	Server   string
+	Count    int
+	Interval time.Duration
+}
+
+// runSTUN sends --stun-count Binding Requests to --stun-server and reports
+// the server-reflexive candidate, the success rate and the RTT distribution.
+// Returns the process exit code.
+func runSTUN(ctx context.Context, opts stunOptions) int {
+	if opts.Server == "" {
+		fmt.Println("❌ Error: --stun-server is required for stun mode")
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	fmt.Printf("Sending %d Binding Requests to %s...\n", opts.Count, opts.Server)
+	result, err := ice.RunSTUNCheck(ctx, ice.STUNCheckConfig{
+		Server:   opts.Server,
+		Count:    opts.Count,
+		Interval: opts.Interval,
+	})
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return int(internal.ExitCodeCriticalFailure)
+	}
+
+	fmt.Printf("STUN: %d/%d answered (success rate %.1f%%)\n", result.Successes, result.Attempts, result.SuccessRate()*100)
+	if result.Successes > 0 {
+		rtt := result.RTT()
+		fmt.Printf("STUN RTT: min %.3f ms, mean %.3f ms, p50 %.3f ms, p95 %.3f ms, p99 %.3f ms, max %.3f ms\n",
+			rtt.Min, rtt.Mean, rtt.P50, rtt.P95, rtt.P99, rtt.Max)
+		fmt.Printf("Candidate: %s\n", result.Last.Candidate())
+		if result.Last.BehindNAT() {
+			fmt.Println("NAT: the server sees a different address than the local socket")
+		} else {
+			fmt.Println("NAT: none detected, the local socket address is public")
+		}
+		if len(result.Mapped) > 1 {
+			fmt.Printf("⚠️  The mapped address changed during the run: %s\n", strings.Join(result.Mapped, " -> "))
+		}
+	}
+	if len(result.Errors) > 0 {
+		errs := make([]string, 0, len(result.Errors))
+		for err := range result.Errors {
+			errs = append(errs, err)
+		}
+		sort.Strings(errs)
+		for _, err := range errs {
+			fmt.Printf("STUN error (%d×): %s\n", result.Errors[err], err)
+		}
+	}
+	if result.Successes == 0 {
+		fmt.Println("❌ Error: the STUN server did not answer")
+		return int(internal.ExitCodeCriticalFailure)
+	}
+	return int(internal.ExitCodeSuccess)
+}
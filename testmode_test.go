@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"quic-test/internal"
+)
+
+// runMainEnv makes the test binary run main() instead of the tests, so a
+// test can start the CLI exactly as a user would
+const runMainEnv = "QUIC_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		os.Args = append([]string{os.Args[0]}, flagArgs(os.Args[1:])...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// flagArgs drops the arguments before "--", which belong to the test binary
+func flagArgs(args []string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[i+1:]
+		}
+	}
+	return args
+}
+
+// TestTestModeDefaultFlags runs --mode test with the default TLS settings:
+// the client has to verify the in-process server's self-signed certificate
+// without --insecure
+func TestTestModeDefaultFlags(t *testing.T) {
+	if testing.Short() {
+		t.Skip("test mode waits for the server and sends traffic for 2s")
+	}
+	port, err := freeLoopbackPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$", "--",
+		"--mode", "test",
+		"--addr", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)),
+		"--duration", "2s",
+		"--report", reportPath,
+		"--report-format", "json",
+	)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("test mode failed: %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("no report: %v\n%s", err, out)
+	}
+	var report internal.ReportSchema
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSmokeReport(report.Metrics); err != nil {
+		t.Errorf("test mode with default flags: %v\n%s", err, out)
+	}
+}